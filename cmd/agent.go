@@ -6,6 +6,7 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"strings"
@@ -15,12 +16,22 @@ import (
 	"miniclaw/pkg/logger"
 	"miniclaw/pkg/provider"
 	providerfantasy "miniclaw/pkg/provider/fantasy"
+	"miniclaw/pkg/provider/mock"
+	"miniclaw/pkg/retrieval"
+	"miniclaw/pkg/store"
+	fstools "miniclaw/pkg/tools/fs"
+	"miniclaw/pkg/tools/variables"
+	"miniclaw/pkg/transcript"
 	"miniclaw/pkg/ui/chat"
+	"miniclaw/pkg/workspace"
 
 	"github.com/spf13/cobra"
 )
 
 var promptText string
+var captureFixturePath string
+var resumeSession bool
+var agentName string
 
 var newFantasyProviderClient = func(cfg *config.Config) (provider.Client, error) {
 	return providerfantasy.New(cfg)
@@ -34,6 +45,8 @@ const (
 	agentTypeGeneric  = "generic-agent"
 	agentTypeOpenCode = "opencode-agent"
 	agentTypeFantasy  = "fantasy-agent"
+
+	providerMock = "mock"
 )
 
 // agentCmd represents the agent command
@@ -43,12 +56,22 @@ var agentCmd = &cobra.Command{
 	Long:  "Loads MiniClaw configuration, connects to the configured provider, and sends one prompt or starts an interactive chat.",
 	Run: func(cmd *cobra.Command, args []string) {
 		prompt := resolvePrompt(args)
+		if strings.TrimSpace(captureFixturePath) != "" && prompt == "" {
+			fmt.Println("--capture-fixture requires a prompt (one-shot mode)")
+			return
+		}
 
 		cfg, err := config.LoadConfig()
 		if err != nil {
 			fmt.Printf("failed to load config: %v\n", err)
 			return
 		}
+		if name := strings.TrimSpace(agentName); name != "" {
+			if cfg, err = cfg.WithNamedAgent(name); err != nil {
+				fmt.Printf("failed to select agent: %v\n", err)
+				return
+			}
+		}
 
 		agentType, err := resolveAgentType(cfg.Agents.Defaults.Type)
 		if err != nil {
@@ -95,10 +118,18 @@ func runOpenCodeAgent(prompt string, cfg *config.Config, log *slog.Logger) error
 }
 
 func runFantasyAgent(prompt string, cfg *config.Config, log *slog.Logger) error {
+	// Fixture replay stands in for the model, so it bypasses the fantasy client.
+	if strings.EqualFold(strings.TrimSpace(cfg.Agents.Defaults.Provider), providerMock) {
+		return runLocalAgentRuntime(prompt, cfg, log, agentTypeFantasy)
+	}
+
 	client, err := newFantasyProviderClient(cfg)
 	if err != nil {
 		return fmt.Errorf("initialize fantasy provider: %w", err)
 	}
+	if closer, ok := client.(io.Closer); ok {
+		defer closer.Close()
+	}
 
 	return runLocalAgentRuntimeWithClientFn(prompt, cfg, log, client, agentTypeFantasy)
 }
@@ -113,12 +144,42 @@ func runLocalAgentRuntime(prompt string, cfg *config.Config, log *slog.Logger, a
 }
 
 func runLocalAgentRuntimeWithClient(prompt string, cfg *config.Config, log *slog.Logger, client provider.Client, agentType string) error {
+	if path := strings.TrimSpace(captureFixturePath); path != "" && prompt != "" {
+		recorder := mock.NewRecorder(client, cfg.Agents.Defaults.Provider, cfg.Agents.Defaults.Model)
+		client = recorder
+		defer func() {
+			if err := recorder.Save(path, mock.NewSanitizer(cfg)); err != nil {
+				log.Error("Failed to save fixture", "path", path, "error", err)
+				return
+			}
+			fmt.Fprintf(os.Stderr, "Fixture saved to %s\n", path)
+		}()
+	}
+
+	index, err := retrieval.Open(cfg)
+	if err != nil {
+		return err
+	}
+	var middleware []agentruntime.Middleware
+	if index != nil {
+		middleware = append(middleware, agentruntime.PrePrompt(index.Augment))
+	}
+
 	ctx := context.Background()
-	session, err := agentruntime.StartLocalSession(ctx, cfg, log, client, shouldShowRuntimeLogs(cfg.Logging.Level))
+	session, err := agentruntime.StartLocalSessionWithOptions(ctx, cfg, log, client, agentruntime.LocalSessionOptions{
+		ObserveEvents: shouldShowRuntimeLogs(cfg.Logging.Level),
+		Resume:        resumeSession,
+		AgentName:     strings.TrimSpace(agentName),
+		Agent:         providerAgent(cfg, agentName),
+		Middleware:    middleware,
+	})
 	if err != nil {
 		return err
 	}
 	defer session.Close()
+	if resumeSession {
+		reportResume(os.Stderr, session, cfg.Storage.Backend)
+	}
 
 	if shouldShowRuntimeLogs(cfg.Logging.Level) {
 		log.Info("Session started")
@@ -129,14 +190,139 @@ func runLocalAgentRuntimeWithClient(prompt string, cfg *config.Config, log *slog
 		return nil
 	}
 
-	runInteractiveFn(ctx, session.Prompt, chat.RuntimeInfo{
+	info := chat.RuntimeInfo{
 		AgentType: agentType,
 		Provider:  strings.TrimSpace(cfg.Agents.Defaults.Provider),
 		Model:     strings.TrimSpace(cfg.Agents.Defaults.Model),
-	})
+		UndoTurn:  session.RollbackLastTurn,
+		UndoFiles: undoFilesFunc(cfg, agentType),
+		Export:    exportFunc(session, agentType),
+		Reindex:   reindexFunc(index),
+	}
+	info.SetVariable, info.Variables = variableFuncs(client, session)
+	runInteractiveFn(ctx, session.Prompt, info)
 	return nil
 }
 
+// providerAgent returns the provider-side agent the named profile selects, if any.
+func providerAgent(cfg *config.Config, name string) string {
+	named, ok := cfg.Agents.NamedAgent(name)
+	if !ok {
+		return ""
+	}
+
+	return strings.TrimSpace(named.Agent)
+}
+
+// reportResume tells the user whether --resume found a conversation to continue.
+func reportResume(out io.Writer, session *agentruntime.LocalSession, backend string) {
+	if session.Resumed() {
+		fmt.Fprintf(out, "Resumed conversation %s\n", session.SessionID())
+		return
+	}
+
+	backend = strings.ToLower(strings.TrimSpace(backend))
+	if backend == "" || backend == store.BackendMemory {
+		fmt.Fprintln(out, "No saved conversation to resume (set storage.backend to jsonl or sqlite); starting a new one")
+		return
+	}
+	fmt.Fprintln(out, "No saved conversation to resume; starting a new one")
+}
+
+// undoFilesFunc backs /undo-files with the workspace file snapshots, or
+// returns nil when fantasy-agent tools are not snapshotting.
+func undoFilesFunc(cfg *config.Config, agentType string) func(context.Context) (string, error) {
+	if agentType != agentTypeFantasy || !cfg.Tools.Filesystem.Snapshots {
+		return nil
+	}
+
+	return func(context.Context) (string, error) {
+		defaults := cfg.Agents.Defaults
+		guard, err := workspace.NewGuardWithOptions(defaults.Workspace, workspace.GuardOptions{
+			RestrictToWorkspace: defaults.RestrictToWorkspace,
+			AllowedPaths:        defaults.AllowedPaths,
+			SymlinkPolicy:       defaults.SymlinkPolicy,
+		})
+		if err != nil {
+			return "", err
+		}
+		result, err := fstools.OpenSnapshots(guard).UndoLast()
+		if err != nil {
+			return "", err
+		}
+
+		return "Undid " + fstools.DescribeRestore(result), nil
+	}
+}
+
+// exportFunc backs /export by writing the session to miniclaw-<session>.<format>
+// in the working directory, or returns nil unless fantasy-agent stores it.
+func exportFunc(session *agentruntime.LocalSession, agentType string) func(context.Context, string) (string, error) {
+	if agentType != agentTypeFantasy {
+		return nil
+	}
+
+	return func(ctx context.Context, format string) (string, error) {
+		format, err := transcript.ParseFormat(format)
+		if err != nil {
+			return "", err
+		}
+		exported, err := session.ExportSession(ctx)
+		if err != nil {
+			return "", err
+		}
+		path := "miniclaw-" + exported.SessionID + "." + format
+		if err := writeTranscript(path, exported, format); err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("Exported %d messages to %s", len(exported.Messages), path), nil
+	}
+}
+
+// variableHolder is implemented by provider clients with session variables (fantasy-agent).
+type variableHolder interface {
+	Variables(sessionID string) *variables.Store
+}
+
+// variableFuncs backs /set and /vars with the session's variables, or returns
+// nils when the client has none or tools.variables is off.
+func variableFuncs(client provider.Client, session *agentruntime.LocalSession) (func(string, string) (string, error), func() string) {
+	holder, ok := client.(variableHolder)
+	if !ok {
+		return nil, nil
+	}
+	vars := holder.Variables(session.SessionID())
+	if vars == nil {
+		return nil, nil
+	}
+
+	set := func(name string, value string) (string, error) {
+		if _, err := vars.Set(name, value); err != nil {
+			return "", err
+		}
+		return vars.Render(), nil
+	}
+	return set, vars.Render
+}
+
+// reindexFunc backs /index with the workspace index, or returns nil when
+// agents.defaults.retrieval is off.
+func reindexFunc(index *retrieval.Index) func(context.Context) (string, error) {
+	if index == nil {
+		return nil
+	}
+
+	return func(ctx context.Context) (string, error) {
+		stats, err := index.Sync(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("Indexed %d files (%d snippets); %d updated, %d removed", stats.Files, stats.Chunks, stats.Updated, stats.Removed), nil
+	}
+}
+
 func logStartupConfiguration(log *slog.Logger, cfg *config.Config, prompt string) {
 	promptMode := "interactive"
 	if strings.TrimSpace(prompt) != "" {
@@ -189,6 +375,9 @@ func resolveAgentType(input string) (string, error) {
 func init() {
 	rootCmd.AddCommand(agentCmd)
 	agentCmd.Flags().StringVarP(&promptText, "prompt", "p", "", "prompt text to send")
+	agentCmd.Flags().StringVar(&agentName, "agent", "", "run as the named agent profile from agents.named")
+	agentCmd.Flags().BoolVar(&resumeSession, "resume", false, "continue the most recent saved conversation (needs a jsonl or sqlite storage backend)")
+	agentCmd.Flags().StringVar(&captureFixturePath, "capture-fixture", "", "record a one-shot run into a sanitized mock-provider fixture at this path")
 }
 
 func resolvePrompt(args []string) string {
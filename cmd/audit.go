@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"miniclaw/pkg/config"
+	fstools "miniclaw/pkg/tools/fs"
+	"miniclaw/pkg/workspace"
+
+	"github.com/spf13/cobra"
+)
+
+var auditTailLines int
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Inspect the file tool audit log",
+	Long:  "Works with the append-only log of file tool writes and deletes (tools.filesystem.audit, .miniclaw/audit.jsonl in the workspace).",
+}
+
+var auditTailCmd = &cobra.Command{
+	Use:   "tail",
+	Short: "Print the most recent audit entries, oldest first",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_ = args
+
+		if auditTailLines <= 0 {
+			return errors.New("--lines must be a positive number of entries")
+		}
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		guard, err := workspace.NewGuardWithOptions(cfg.Agents.Defaults.Workspace, workspace.GuardOptions{RestrictToWorkspace: true, SymlinkPolicy: cfg.Agents.Defaults.SymlinkPolicy})
+		if err != nil {
+			return fmt.Errorf("initialize workspace guard: %w", err)
+		}
+
+		return printAudit(cmd.OutOrStdout(), fstools.OpenAudit(guard), auditTailLines)
+	},
+}
+
+func init() {
+	auditTailCmd.Flags().IntVarP(&auditTailLines, "lines", "n", 20, "number of entries to print")
+	auditCmd.AddCommand(auditTailCmd)
+	rootCmd.AddCommand(auditCmd)
+}
+
+// printAudit writes the last n entries of log, one per line.
+func printAudit(out io.Writer, log *fstools.AuditLog, n int) error {
+	entries, err := log.Tail(n)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Fprintf(out, "No audit entries in %s\n", log.Path())
+		return nil
+	}
+
+	for _, entry := range entries {
+		session := entry.Session
+		if session == "" {
+			session = "-"
+		}
+		change := fmt.Sprintf("%d -> %d bytes", entry.BytesBefore, entry.BytesAfter)
+		if entry.Deleted {
+			change = fmt.Sprintf("deleted %d bytes", entry.BytesBefore)
+		}
+		fmt.Fprintf(out, "%s  %s  %s  %s  %s\n", entry.At.UTC().Format("2006-01-02 15:04:05"), session, entry.Tool, entry.Path, change)
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"miniclaw/pkg/autostart"
+	"miniclaw/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+var autostartDryRun bool
+
+var gatewayAutostartCmd = &cobra.Command{
+	Use:   "autostart",
+	Short: "Start the gateway at login on macOS or Windows",
+	Long:  "Installs or removes a launchd agent (macOS) or Task Scheduler logon task (Windows) that runs the gateway with the current config path.",
+}
+
+var gatewayAutostartEnableCmd = &cobra.Command{
+	Use:   "enable",
+	Short: "Install the gateway login item",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_ = args
+
+		spec, err := autostartSpec()
+		if err != nil {
+			return err
+		}
+		manager, err := autostart.NewManager(runtime.GOOS)
+		if err != nil {
+			return err
+		}
+
+		result, err := manager.Enable(context.Background(), spec, autostartDryRun)
+		if err != nil {
+			return err
+		}
+
+		printAutostartResult(cmd, result, "Installed gateway autostart")
+		return nil
+	},
+}
+
+var gatewayAutostartDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Remove the gateway login item",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_ = args
+
+		manager, err := autostart.NewManager(runtime.GOOS)
+		if err != nil {
+			return err
+		}
+
+		result, err := manager.Disable(context.Background())
+		if err != nil {
+			return err
+		}
+
+		printAutostartResult(cmd, result, "Removed gateway autostart")
+		return nil
+	},
+}
+
+func init() {
+	gatewayAutostartEnableCmd.Flags().BoolVar(&autostartDryRun, "dry-run", false, "print the planned file and commands without changing anything")
+	gatewayAutostartCmd.AddCommand(gatewayAutostartEnableCmd, gatewayAutostartDisableCmd)
+	gatewayCmd.AddCommand(gatewayAutostartCmd)
+}
+
+// autostartSpec captures the binary, config path, and working directory of this invocation.
+func autostartSpec() (autostart.Spec, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return autostart.Spec{}, fmt.Errorf("resolve executable: %w", err)
+	}
+	configPath, err := config.Path()
+	if err != nil {
+		return autostart.Spec{}, err
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return autostart.Spec{}, fmt.Errorf("resolve home directory: %w", err)
+	}
+
+	return autostart.Spec{
+		Executable: autostart.StableExecutable(executable),
+		ConfigPath: configPath,
+		WorkDir:    filepath.Dir(configPath),
+		LogDir:     filepath.Join(home, ".miniclaw", "logs"),
+	}, nil
+}
+
+func printAutostartResult(cmd *cobra.Command, result autostart.Result, action string) {
+	out := cmd.OutOrStdout()
+	if autostartDryRun {
+		action = "Would install gateway autostart"
+	}
+	fmt.Fprintf(out, "%s: %s\n", action, result.Path)
+	for _, command := range result.Commands {
+		fmt.Fprintf(out, "  %s\n", strings.Join(command, " "))
+	}
+}
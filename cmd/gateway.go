@@ -11,7 +11,9 @@ import (
 	"syscall"
 
 	"miniclaw/pkg/channel"
+	"miniclaw/pkg/channel/httpapi"
 	"miniclaw/pkg/channel/telegram"
+	"miniclaw/pkg/channel/websocket"
 	"miniclaw/pkg/config"
 	"miniclaw/pkg/gateway"
 	"miniclaw/pkg/logger"
@@ -19,12 +21,16 @@ import (
 	"github.com/spf13/cobra"
 )
 
-const telegramChannelName = "telegram"
+const (
+	telegramChannelName  = "telegram"
+	httpChannelName      = "http"
+	websocketChannelName = "websocket"
+)
 
 var gatewayCmd = &cobra.Command{
 	Use:   "gateway",
 	Short: "Run channel gateway mode",
-	Long:  "Runs MiniClaw as a channel gateway with health and readiness endpoints.",
+	Long:  "Runs MiniClaw as a channel gateway with health and readiness endpoints.\n\nSend SIGHUP to reload config.json: channel allowlists, enabled channels, runtime session limits, and the log level change without dropping sessions.",
 	Run: func(cmd *cobra.Command, args []string) {
 		_ = args
 
@@ -57,6 +63,20 @@ var gatewayCmd = &cobra.Command{
 			return
 		}
 
+		reloads := make(chan os.Signal, 1)
+		signal.Notify(reloads, syscall.SIGHUP)
+		defer signal.Stop(reloads)
+		go func() {
+			for {
+				select {
+				case <-runCtx.Done():
+					return
+				case <-reloads:
+					reloadGateway(svc, log)
+				}
+			}
+		}()
+
 		log.Info("Gateway started", "channels", enabledChannelNames(adapters), "provider", cfg.Agents.Defaults.Provider, "model", cfg.Agents.Defaults.Model)
 		if err := svc.Run(runCtx); err != nil {
 			if errors.Is(err, context.Canceled) {
@@ -71,8 +91,36 @@ func init() {
 	rootCmd.AddCommand(gatewayCmd)
 }
 
+// reloadGateway rereads config.json and applies its safe changes to svc; an
+// invalid config is logged and leaves the gateway unchanged.
+func reloadGateway(svc *gateway.Service, log *slog.Logger) {
+	log.Info("Reloading config")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Error("Config reload failed", "error", err)
+		return
+	}
+	adapters, err := enabledAdapters(cfg, log)
+	if err != nil {
+		log.Error("Config reload failed", "error", err)
+		return
+	}
+	if err := svc.Reload(cfg, adapters); err != nil {
+		log.Error("Config reload failed", "error", err)
+		return
+	}
+	if err := logger.SetLevel(slog.Default(), cfg.Logging.Level); err != nil {
+		log.Error("Log level reload failed", "error", err)
+	}
+}
+
 func enabledAdapters(cfg *config.Config, log *slog.Logger) ([]channel.Adapter, error) {
-	adapters := make([]channel.Adapter, 0, 1)
+	adapters := make([]channel.Adapter, 0, 2)
+	auth, err := channel.NewAuth(cfg.Gateway.AuthConfig())
+	if err != nil {
+		return nil, err
+	}
 
 	if cfg.Channels.Telegram.Enabled {
 		adapter, err := telegram.NewAdapter(cfg.Channels.Telegram, log)
@@ -82,7 +130,25 @@ func enabledAdapters(cfg *config.Config, log *slog.Logger) ([]channel.Adapter, e
 		adapters = append(adapters, adapter)
 	}
 
-	if len(adapters) == 0 {
+	if cfg.Channels.HTTP.Enabled {
+		adapter, err := httpapi.NewAdapter(cfg.Channels.HTTP, auth, log)
+		if err != nil {
+			return nil, fmt.Errorf("configure %s channel: %w", httpChannelName, err)
+		}
+		adapters = append(adapters, adapter)
+	}
+
+	if cfg.Channels.WebSocket.Enabled {
+		adapter, err := websocket.NewAdapter(cfg.Channels.WebSocket, auth, log)
+		if err != nil {
+			return nil, fmt.Errorf("configure %s channel: %w", websocketChannelName, err)
+		}
+		adapters = append(adapters, adapter)
+	}
+
+	// A cluster instance may run without channels and only answer the
+	// sessions it owns.
+	if len(adapters) == 0 && !cfg.Gateway.Cluster.Enabled {
 		return nil, errors.New("no channels are enabled")
 	}
 
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+
+	"miniclaw/pkg/config"
+	"miniclaw/pkg/logger"
+	fantasytools "miniclaw/pkg/tools/fantasy"
+	"miniclaw/pkg/tools/mcp"
+
+	core "charm.land/fantasy"
+	"github.com/spf13/cobra"
+)
+
+var mcpServeCmd = &cobra.Command{
+	Use:   "mcp-serve",
+	Short: "Serve the workspace tools over MCP on stdio",
+	Long: `Runs an MCP server on stdin/stdout that exposes MiniClaw's workspace tools
+(filesystem, plus run_command when tools.exec is enabled) to other agents and editors.
+Tools keep the workspace guard, tools.approval, and tools.quotas settings; calls that
+need approval are denied because there is no one to ask. The server exits when stdin
+closes; logs go to stderr.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_ = args
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+
+		appLogger, err := logger.New(cfg.Logging)
+		if err != nil {
+			return fmt.Errorf("initialize logger: %w", err)
+		}
+		slog.SetDefault(appLogger)
+		log := slog.Default().With("component", "cmd.mcp_serve")
+
+		tools, err := servedTools(cfg)
+		if err != nil {
+			return err
+		}
+
+		// One meter covers the whole connection, so tools.quotas apply per served session.
+		runCtx := fantasytools.WithToolMeter(context.Background(), &fantasytools.ToolMeter{})
+
+		log.Info("MCP server started", "tools", len(tools), "workspace", cfg.Agents.Defaults.Workspace)
+		return mcp.NewServer(fantasytools.MCPServerTools(tools)).Serve(runCtx, os.Stdin, os.Stdout)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mcpServeCmd)
+}
+
+// servedTools builds the workspace tools with the configured approval and quota wrappers.
+// send_file is left out: MCP clients have no chat to deliver files to.
+func servedTools(cfg *config.Config) ([]core.AgentTool, error) {
+	tools, err := fantasytools.BuildWorkspaceTools(cfg)
+	if err != nil {
+		return nil, err
+	}
+	tools = slices.DeleteFunc(tools, func(tool core.AgentTool) bool { return tool.Info().Name == fantasytools.SendFileTool })
+	approval, err := fantasytools.NewApprovalPolicy(cfg.Tools.Approval)
+	if err != nil {
+		return nil, err
+	}
+	quota, err := fantasytools.NewToolQuota(cfg.Tools.Quotas)
+	if err != nil {
+		return nil, err
+	}
+
+	return fantasytools.WrapWithMetering(fantasytools.WrapWithApproval(tools, approval), quota), nil
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"miniclaw/pkg/config"
+	memorytools "miniclaw/pkg/tools/memory"
+	"miniclaw/pkg/workspace"
+
+	"github.com/spf13/cobra"
+)
+
+var memoryCmd = &cobra.Command{
+	Use:   "memory",
+	Short: "Inspect the agent's long-term memory",
+	Long:  "Works with the workspace memory file the remember and recall tools use (tools.memory.path, MEMORY.md by default).",
+}
+
+var memoryShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print remembered facts, oldest first",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_ = args
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		defaults := cfg.Agents.Defaults
+		guard, err := workspace.NewGuardWithOptions(defaults.Workspace, workspace.GuardOptions{
+			RestrictToWorkspace: defaults.RestrictToWorkspace,
+			AllowedPaths:        defaults.AllowedPaths,
+			SymlinkPolicy:       defaults.SymlinkPolicy,
+		})
+		if err != nil {
+			return fmt.Errorf("initialize workspace guard: %w", err)
+		}
+		store, err := memorytools.NewStore(guard, cfg.Tools.Memory)
+		if err != nil {
+			return err
+		}
+
+		return printMemory(cmd.OutOrStdout(), store)
+	},
+}
+
+func init() {
+	memoryCmd.AddCommand(memoryShowCmd)
+	rootCmd.AddCommand(memoryCmd)
+}
+
+// printMemory writes every entry in store with the file it came from.
+func printMemory(out io.Writer, store *memorytools.Store) error {
+	entries, err := store.Entries()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Fprintf(out, "No remembered facts in %s\n", store.Path())
+		return nil
+	}
+
+	fmt.Fprintf(out, "%d remembered facts in %s\n", len(entries), store.Path())
+	for _, entry := range entries {
+		fmt.Fprintf(out, "- %s\n", entry)
+	}
+
+	return nil
+}
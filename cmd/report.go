@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"miniclaw/pkg/config"
+	"miniclaw/pkg/report"
+	"miniclaw/pkg/store"
+
+	"github.com/spf13/cobra"
+)
+
+var reportSince time.Duration
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Summarize recent agent activity",
+	Long: `Prints sessions, prompts, tools used, files changed, and cost over a recent window,
+read from the persisted session store (storage.backend jsonl or sqlite).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_ = args
+
+		if reportSince <= 0 {
+			return errors.New("--since must be a positive duration such as 24h")
+		}
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		backend := strings.ToLower(strings.TrimSpace(cfg.Storage.Backend))
+		if backend == "" || backend == store.BackendMemory {
+			return errors.New("report reads stored sessions; set storage.backend to jsonl or sqlite")
+		}
+		sessions, err := store.Open(cfg.Storage)
+		if err != nil {
+			return fmt.Errorf("open session store: %w", err)
+		}
+		defer sessions.Close()
+
+		until := time.Now()
+		activity, err := report.Generate(cmd.Context(), cfg, sessions, until.Add(-reportSince), until)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(cmd.OutOrStdout(), activity.String())
+		return nil
+	},
+}
+
+func init() {
+	reportCmd.Flags().DurationVar(&reportSince, "since", report.DefaultWindow, "how far back to report, for example 24h or 168h")
+	rootCmd.AddCommand(reportCmd)
+}
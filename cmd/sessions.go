@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"miniclaw/pkg/config"
+	"miniclaw/pkg/store"
+	"miniclaw/pkg/transcript"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	sessionsExportFormat string
+	sessionsExportOutput string
+	sessionsImportTitle  string
+)
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "List, export, and import stored conversations",
+	Long:  "Works with the persisted session store (storage.backend jsonl or sqlite).",
+}
+
+var sessionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Print stored sessions, oldest first",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_ = args
+
+		sessions, err := openSessionStore()
+		if err != nil {
+			return err
+		}
+		defer sessions.Close()
+
+		summaries, err := sessions.List(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("list sessions: %w", err)
+		}
+
+		return printSessions(cmd.OutOrStdout(), summaries)
+	},
+}
+
+var sessionsExportCmd = &cobra.Command{
+	Use:   "export <session-id>",
+	Short: "Write a stored session as JSON or Markdown",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := transcript.ParseFormat(sessionsExportFormat)
+		if err != nil {
+			return err
+		}
+		sessions, err := openSessionStore()
+		if err != nil {
+			return err
+		}
+		defer sessions.Close()
+
+		exported, err := transcript.Export(cmd.Context(), sessions, args[0])
+		if err != nil {
+			return fmt.Errorf("export session %s: %w", args[0], err)
+		}
+		if path := strings.TrimSpace(sessionsExportOutput); path != "" && path != "-" {
+			if err := writeTranscript(path, exported, format); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Exported %d messages to %s\n", len(exported.Messages), path)
+			return nil
+		}
+
+		return transcript.Encode(cmd.OutOrStdout(), exported, format)
+	},
+}
+
+var sessionsImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Store a JSON export as a new session",
+	Long: `Stores a JSON export as a new session and prints its ID. With the default
+--title, "miniclaw agent --resume" continues the imported conversation; use
+"miniclaw:<session-key>" to hand it to a gateway chat instead.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("open transcript: %w", err)
+		}
+		defer file.Close()
+		exported, err := transcript.Decode(file)
+		if err != nil {
+			return err
+		}
+
+		sessions, err := openSessionStore()
+		if err != nil {
+			return err
+		}
+		defer sessions.Close()
+
+		id, err := transcript.Import(cmd.Context(), sessions, exported, sessionsImportTitle)
+		if err != nil {
+			return fmt.Errorf("import transcript: %w", err)
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "Imported %d messages as session %s\n", len(exported.Messages), id)
+		return nil
+	},
+}
+
+func init() {
+	sessionsExportCmd.Flags().StringVar(&sessionsExportFormat, "format", transcript.FormatMarkdown, "export format: md or json (only json can be imported)")
+	sessionsExportCmd.Flags().StringVarP(&sessionsExportOutput, "output", "o", "", "write to this file instead of stdout")
+	sessionsImportCmd.Flags().StringVar(&sessionsImportTitle, "title", "miniclaw", "title of the new session, which selects who resumes it")
+	sessionsCmd.AddCommand(sessionsListCmd, sessionsExportCmd, sessionsImportCmd)
+	rootCmd.AddCommand(sessionsCmd)
+}
+
+// openSessionStore opens the configured persistent session store.
+func openSessionStore() (store.SessionStore, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	backend := strings.ToLower(strings.TrimSpace(cfg.Storage.Backend))
+	if backend == "" || backend == store.BackendMemory {
+		return nil, errors.New("sessions reads stored sessions; set storage.backend to jsonl or sqlite")
+	}
+	sessions, err := store.Open(cfg.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("open session store: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// printSessions writes one line per stored session.
+func printSessions(out io.Writer, summaries []store.Summary) error {
+	if len(summaries) == 0 {
+		fmt.Fprintln(out, "No stored sessions")
+		return nil
+	}
+
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tTITLE\tNAME\tTURNS\tUPDATED")
+	for _, summary := range summaries {
+		name := summary.Name
+		if name == "" {
+			name = "-"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%d\t%s\n", summary.ID, summary.Title, name, summary.TurnCount, summary.UpdatedAt.Local().Format("2006-01-02 15:04"))
+	}
+
+	return writer.Flush()
+}
+
+// writeTranscript writes exported to path in format.
+func writeTranscript(path string, exported transcript.Transcript, format string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("create export file: %w", err)
+	}
+	if err := transcript.Encode(file, exported, format); err != nil {
+		file.Close()
+		return fmt.Errorf("write export file: %w", err)
+	}
+
+	return file.Close()
+}
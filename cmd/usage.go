@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"miniclaw/pkg/accounting"
+	"miniclaw/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	usageFrom       string
+	usageTo         string
+	usageGroupBy    string
+	usageChannel    string
+	usageSessionKey string
+	usageJSON       bool
+)
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Report gateway token usage and cost",
+	Long: `Prints token usage and estimated cost per day, channel, or session, read from
+the gateway usage store (gateway.usage). Days are UTC and both bounds are inclusive.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_ = args
+
+		filter := accounting.Filter{GroupBy: usageGroupBy, Channel: usageChannel, SessionKey: usageSessionKey}
+		for _, bound := range []struct {
+			flag  string
+			value string
+			day   *time.Time
+		}{{"--from", usageFrom, &filter.From}, {"--to", usageTo, &filter.To}} {
+			if bound.value == "" {
+				continue
+			}
+			parsed, err := time.Parse(time.DateOnly, bound.value)
+			if err != nil {
+				return fmt.Errorf("%s must be a date such as 2026-10-16", bound.flag)
+			}
+			*bound.day = parsed
+		}
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		store, err := accounting.Load(cfg.Gateway.Usage)
+		if errors.Is(err, accounting.ErrNoStore) && !cfg.Gateway.Usage.Enabled {
+			return errors.New("usage reads the gateway usage store; set gateway.usage.enabled")
+		}
+		if err != nil {
+			return err
+		}
+
+		report, err := store.Query(filter)
+		if err != nil {
+			return err
+		}
+		if usageJSON {
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(report)
+		}
+
+		return printUsage(cmd.OutOrStdout(), report)
+	},
+}
+
+func init() {
+	usageCmd.Flags().StringVar(&usageFrom, "from", "", "first UTC day to report, for example 2026-10-01")
+	usageCmd.Flags().StringVar(&usageTo, "to", "", "last UTC day to report, for example 2026-10-16")
+	usageCmd.Flags().StringVar(&usageGroupBy, "by", accounting.GroupByDay, "group rows by day, channel, or session")
+	usageCmd.Flags().StringVar(&usageChannel, "channel", "", "only report this channel, for example telegram")
+	usageCmd.Flags().StringVar(&usageSessionKey, "session", "", "only report this session key, for example telegram:100")
+	usageCmd.Flags().BoolVar(&usageJSON, "json", false, "print the report as JSON")
+	rootCmd.AddCommand(usageCmd)
+}
+
+// printUsage writes one line per report row and a total.
+func printUsage(out io.Writer, report accounting.Report) error {
+	if len(report.Rows) == 0 {
+		fmt.Fprintln(out, "No usage recorded in this range")
+		return nil
+	}
+
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	switch report.GroupBy {
+	case accounting.GroupByChannel:
+		fmt.Fprint(writer, "CHANNEL")
+	case accounting.GroupBySession:
+		fmt.Fprint(writer, "SESSION")
+	default:
+		fmt.Fprint(writer, "DAY")
+	}
+	fmt.Fprintln(writer, "\tTURNS\tINPUT\tOUTPUT\tTOTAL\tCOST (USD)")
+	for _, row := range report.Rows {
+		label := row.Day
+		switch report.GroupBy {
+		case accounting.GroupByChannel:
+			label = row.Channel
+		case accounting.GroupBySession:
+			label = row.SessionKey
+		}
+		printUsageRow(writer, label, row)
+	}
+	printUsageRow(writer, "TOTAL", report.Total)
+
+	return writer.Flush()
+}
+
+func printUsageRow(writer io.Writer, label string, row accounting.Row) {
+	fmt.Fprintf(writer, "%s\t%d\t%d\t%d\t%d\t%.4f\n", label, row.Turns, row.InputTokens, row.OutputTokens, row.TotalTokens, row.CostUSD)
+}
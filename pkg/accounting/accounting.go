@@ -0,0 +1,368 @@
+// Package accounting keeps token usage and estimated cost per UTC day,
+// channel, and session in a small JSON file, for the gateway's usage report
+// endpoint and `miniclaw usage`.
+package accounting
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+	"miniclaw/pkg/workspace"
+)
+
+const (
+	// DefaultPath is the store file when gateway.usage.path is unset.
+	DefaultPath = "~/.miniclaw/gateway-usage.json"
+	// DefaultRetentionDays is how many UTC days the store keeps when
+	// gateway.usage.retention_days is unset.
+	DefaultRetentionDays = 90
+)
+
+// fileVersion is the format version written to the store file. Loading
+// refuses newer versions, so an older gateway never overwrites a store it
+// cannot read.
+const fileVersion = 1
+
+// Grouping names accepted by Query.
+const (
+	GroupByDay     = "day"
+	GroupByChannel = "channel"
+	GroupBySession = "session"
+)
+
+// ErrNoStore reports that the store file does not exist yet.
+var ErrNoStore = errors.New("no usage recorded yet")
+
+// Prices converts token counts into an estimated cost.
+type Prices struct {
+	InputUSDPerMillion  float64
+	OutputUSDPerMillion float64
+}
+
+// Cost returns the estimated USD cost of input and output tokens.
+func (p Prices) Cost(input, output int64) float64 {
+	return (float64(input)*p.InputUSDPerMillion + float64(output)*p.OutputUSDPerMillion) / 1e6
+}
+
+// Row is the usage of one UTC day, channel, and session key, or an
+// aggregate of such rows. Query leaves the fields it grouped away empty.
+type Row struct {
+	Day          string  `json:"day,omitempty"`
+	Channel      string  `json:"channel,omitempty"`
+	SessionKey   string  `json:"session_key,omitempty"`
+	Turns        int64   `json:"turns"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	TotalTokens  int64   `json:"total_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+// add sums other's counters into r.
+func (r *Row) add(other Row) {
+	r.Turns += other.Turns
+	r.InputTokens += other.InputTokens
+	r.OutputTokens += other.OutputTokens
+	r.TotalTokens += other.TotalTokens
+	r.CostUSD += other.CostUSD
+}
+
+// Filter selects the rows Query reports. Zero values match everything.
+type Filter struct {
+	// From and To bound the UTC days reported, inclusive.
+	From, To time.Time
+	// Channel and SessionKey select one channel or session key.
+	Channel    string
+	SessionKey string
+	// GroupBy is GroupByDay (the default), GroupByChannel, or GroupBySession.
+	GroupBy string
+}
+
+// Report is the result of Query.
+type Report struct {
+	From    string `json:"from,omitempty"`
+	To      string `json:"to,omitempty"`
+	GroupBy string `json:"group_by"`
+	Rows    []Row  `json:"rows"`
+	Total   Row    `json:"total"`
+}
+
+// storeFile is the on-disk format of the store.
+type storeFile struct {
+	Version int   `json:"version"`
+	Rows    []Row `json:"rows"`
+}
+
+type rowKey struct {
+	day        string
+	sessionKey string
+}
+
+// Store aggregates usage in memory and writes the whole store to its file
+// after each change.
+type Store struct {
+	path      string
+	retention int
+	prices    Prices
+
+	mu   sync.Mutex
+	rows map[rowKey]Row
+}
+
+// Open loads the store configured by cfg; a missing file starts it empty.
+// prices estimate the cost of the usage recorded from now on.
+func Open(cfg config.GatewayUsageConfig, prices Prices) (*Store, error) {
+	path, err := Path(cfg)
+	if err != nil {
+		return nil, err
+	}
+	retention := cfg.RetentionDays
+	if retention <= 0 {
+		retention = DefaultRetentionDays
+	}
+
+	store := &Store{path: path, retention: retention, prices: prices, rows: map[rowKey]Row{}}
+	if err := store.load(); err != nil && !errors.Is(err, ErrNoStore) {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Load reads the store at the path cfg configures for reporting. It returns
+// ErrNoStore when nothing has been recorded there yet.
+func Load(cfg config.GatewayUsageConfig) (*Store, error) {
+	path, err := Path(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &Store{path: path, rows: map[rowKey]Row{}}
+	if err := store.load(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Path resolves the store file path, expanding a leading "~/".
+func Path(cfg config.GatewayUsageConfig) (string, error) {
+	path := strings.TrimSpace(cfg.Path)
+	if path == "" {
+		path = DefaultPath
+	}
+
+	return workspace.ExpandHome(path)
+}
+
+func (s *Store) load() error {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNoStore
+	}
+	if err != nil {
+		return fmt.Errorf("read usage store: %w", err)
+	}
+
+	var file storeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("decode usage store %s: %w", s.path, err)
+	}
+	if file.Version != fileVersion {
+		return fmt.Errorf("usage store %s: unsupported format version %d (want %d)", s.path, file.Version, fileVersion)
+	}
+	for _, row := range file.Rows {
+		s.rows[rowKey{day: row.Day, sessionKey: row.SessionKey}] = row
+	}
+
+	return nil
+}
+
+// Record adds one turn's usage to the row of its UTC day and session key,
+// drops days past retention, and writes the store.
+func (s *Store) Record(sessionKey string, usage providertypes.TokenUsage, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := rowKey{day: at.UTC().Format(time.DateOnly), sessionKey: sessionKey}
+	row := s.rows[key]
+	row.Day = key.day
+	row.Channel = ChannelOf(sessionKey)
+	row.SessionKey = sessionKey
+	row.add(Row{
+		Turns:        1,
+		InputTokens:  usage.InputTokens,
+		OutputTokens: usage.OutputTokens,
+		TotalTokens:  usage.TotalTokens,
+		CostUSD:      s.prices.Cost(usage.InputTokens, usage.OutputTokens),
+	})
+	s.rows[key] = row
+
+	oldest := at.UTC().AddDate(0, 0, -(s.retention - 1)).Format(time.DateOnly)
+	for existing := range s.rows {
+		if existing.day < oldest {
+			delete(s.rows, existing)
+		}
+	}
+
+	return s.writeLocked()
+}
+
+// Query aggregates the rows filter selects, in day, channel, and session
+// key order.
+func (s *Store) Query(filter Filter) (Report, error) {
+	groupBy := cmp.Or(strings.ToLower(strings.TrimSpace(filter.GroupBy)), GroupByDay)
+	if groupBy != GroupByDay && groupBy != GroupByChannel && groupBy != GroupBySession {
+		return Report{}, fmt.Errorf("unknown grouping %q (want %s, %s, or %s)", filter.GroupBy, GroupByDay, GroupByChannel, GroupBySession)
+	}
+	report := Report{GroupBy: groupBy, Rows: []Row{}}
+	if !filter.From.IsZero() {
+		report.From = filter.From.UTC().Format(time.DateOnly)
+	}
+	if !filter.To.IsZero() {
+		report.To = filter.To.UTC().Format(time.DateOnly)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	groups := map[Row]*Row{}
+	for _, row := range s.rows {
+		switch {
+		case report.From != "" && row.Day < report.From,
+			report.To != "" && row.Day > report.To,
+			filter.Channel != "" && row.Channel != filter.Channel,
+			filter.SessionKey != "" && row.SessionKey != filter.SessionKey:
+			continue
+		}
+
+		var group Row
+		switch groupBy {
+		case GroupByDay:
+			group = Row{Day: row.Day}
+		case GroupByChannel:
+			group = Row{Channel: row.Channel}
+		case GroupBySession:
+			group = Row{Channel: row.Channel, SessionKey: row.SessionKey}
+		}
+		total, ok := groups[group]
+		if !ok {
+			total = &Row{Day: group.Day, Channel: group.Channel, SessionKey: group.SessionKey}
+			groups[group] = total
+		}
+		total.add(row)
+		report.Total.add(row)
+	}
+
+	for _, row := range groups {
+		report.Rows = append(report.Rows, *row)
+	}
+	slices.SortFunc(report.Rows, func(a, b Row) int {
+		return cmp.Or(cmp.Compare(a.Day, b.Day), cmp.Compare(a.Channel, b.Channel), cmp.Compare(a.SessionKey, b.SessionKey))
+	})
+
+	return report, nil
+}
+
+// Compact merges the rows of UTC days before cutoff into one row per day and
+// channel, whose session key is CompactedSessionKey of the channel, and
+// writes the store when that changed anything. Day and channel reports keep
+// their totals; session reports show the merged days as one session per
+// channel. It returns the number of rows before and after.
+func (s *Store) Compact(cutoff time.Time) (int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := len(s.rows)
+	day := cutoff.UTC().Format(time.DateOnly)
+	merged := map[rowKey]Row{}
+	for key, row := range s.rows {
+		compacted := rowKey{day: key.day, sessionKey: CompactedSessionKey(row.Channel)}
+		if key.day >= day || key == compacted {
+			continue
+		}
+		delete(s.rows, key)
+		total := merged[compacted]
+		total.add(row)
+		merged[compacted] = total
+	}
+	if len(merged) == 0 {
+		return before, before, nil
+	}
+	for key, total := range merged {
+		row := s.rows[key]
+		row.Day = key.day
+		row.Channel = ChannelOf(key.sessionKey)
+		row.SessionKey = key.sessionKey
+		row.add(total)
+		s.rows[key] = row
+	}
+
+	return before, len(s.rows), s.writeLocked()
+}
+
+// CompactedSessionKey is the session key Compact gives the merged rows of
+// channel, such as "telegram:*".
+func CompactedSessionKey(channel string) string {
+	return channel + ":*"
+}
+
+// ChannelOf returns the channel a gateway session key belongs to, such as
+// "telegram" for "telegram:100" or "cron" for "cron:nightly".
+func ChannelOf(sessionKey string) string {
+	channel, _, ok := strings.Cut(sessionKey, ":")
+	if !ok {
+		return ""
+	}
+
+	return channel
+}
+
+// writeLocked replaces the store file through a temporary file, so a crash
+// mid-write leaves the previous store intact; the caller holds s.mu.
+func (s *Store) writeLocked() error {
+	rows := make([]Row, 0, len(s.rows))
+	for _, row := range s.rows {
+		rows = append(rows, row)
+	}
+	slices.SortFunc(rows, func(a, b Row) int {
+		return cmp.Or(cmp.Compare(a.Day, b.Day), cmp.Compare(a.SessionKey, b.SessionKey))
+	})
+	data, err := json.MarshalIndent(storeFile{Version: fileVersion, Rows: rows}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode usage store: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("create usage store directory: %w", err)
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("write usage store: %w", err)
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(append(data, '\n')); err != nil {
+		_ = temp.Close()
+		return fmt.Errorf("write usage store: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("write usage store: %w", err)
+	}
+	if err := os.Chmod(temp.Name(), 0o644); err != nil {
+		return fmt.Errorf("write usage store: %w", err)
+	}
+	if err := os.Rename(temp.Name(), s.path); err != nil {
+		return fmt.Errorf("write usage store: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package accounting
+
+import (
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+func TestStoreRecordsPersistsAndGroupsUsage(t *testing.T) {
+	cfg := config.GatewayUsageConfig{Enabled: true, Path: filepath.Join(t.TempDir(), "usage.json"), RetentionDays: 7}
+	if _, err := Load(cfg); !errors.Is(err, ErrNoStore) {
+		t.Fatalf("Load before recording = %v, want ErrNoStore", err)
+	}
+
+	store, err := Open(cfg, Prices{InputUSDPerMillion: 2, OutputUSDPerMillion: 8})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	day := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	record := func(sessionKey string, input, output int64, at time.Time) {
+		t.Helper()
+		usage := providertypes.TokenUsage{InputTokens: input, OutputTokens: output, TotalTokens: input + output}
+		if err := store.Record(sessionKey, usage, at); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	record("telegram:100", 1000, 500, day.AddDate(0, 0, -10))
+	record("telegram:100", 1_000_000, 250_000, day)
+	record("telegram:100", 500_000, 0, day)
+	record("http:ci", 100, 50, day.AddDate(0, 0, 1))
+	record("cron:nightly", 10, 10, day.AddDate(0, 0, 1))
+
+	// A reopened store reads what the first one wrote; the 10-day-old row
+	// fell out of the 7-day retention.
+	reloaded, err := Load(cfg)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	byDay, err := reloaded.Query(Filter{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(byDay.Rows) != 2 || byDay.Rows[0].Day != "2026-10-15" || byDay.Rows[1].Day != "2026-10-16" {
+		t.Fatalf("rows by day = %+v", byDay.Rows)
+	}
+	first := byDay.Rows[0]
+	if first.Turns != 2 || first.InputTokens != 1_500_000 || first.OutputTokens != 250_000 || math.Abs(first.CostUSD-5) > 1e-9 {
+		t.Fatalf("2026-10-15 = %+v, want 2 turns costing $5", first)
+	}
+	if byDay.Total.Turns != 4 || byDay.GroupBy != GroupByDay {
+		t.Fatalf("total = %+v", byDay)
+	}
+
+	byChannel, err := reloaded.Query(Filter{From: day.AddDate(0, 0, 1), GroupBy: "channel"})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(byChannel.Rows) != 2 || byChannel.Rows[0].Channel != "cron" || byChannel.Rows[1].Channel != "http" || byChannel.Rows[0].Day != "" {
+		t.Fatalf("rows by channel from 2026-10-16 = %+v", byChannel.Rows)
+	}
+
+	bySession, err := reloaded.Query(Filter{To: day, Channel: "telegram", GroupBy: "session"})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(bySession.Rows) != 1 || bySession.Rows[0].SessionKey != "telegram:100" || bySession.Rows[0].Turns != 2 {
+		t.Fatalf("telegram sessions to 2026-10-15 = %+v", bySession.Rows)
+	}
+
+	if _, err := reloaded.Query(Filter{GroupBy: "model"}); err == nil {
+		t.Fatal("Query with an unknown grouping should fail")
+	}
+}
+
+func TestStoreCompactMergesOldSessionsPerChannel(t *testing.T) {
+	cfg := config.GatewayUsageConfig{Enabled: true, Path: filepath.Join(t.TempDir(), "usage.json")}
+	store, err := Open(cfg, Prices{})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	day := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	for _, turn := range []struct {
+		sessionKey string
+		at         time.Time
+	}{
+		{"telegram:100", day.AddDate(0, 0, -3)},
+		{"telegram:200", day.AddDate(0, 0, -3)},
+		{"http:ci", day.AddDate(0, 0, -3)},
+		{"telegram:100", day},
+	} {
+		usage := providertypes.TokenUsage{InputTokens: 10, OutputTokens: 5, TotalTokens: 15}
+		if err := store.Record(turn.sessionKey, usage, turn.at); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	before, after, err := store.Compact(day)
+	if err != nil || before != 4 || after != 3 {
+		t.Fatalf("Compact = %d -> %d, %v; want 4 -> 3", before, after, err)
+	}
+	if before, after, err := store.Compact(day); err != nil || before != after {
+		t.Fatalf("second Compact = %d -> %d, %v; want no change", before, after, err)
+	}
+
+	reloaded, err := Load(cfg)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	bySession, err := reloaded.Query(Filter{GroupBy: GroupBySession})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	var keys []string
+	for _, row := range bySession.Rows {
+		keys = append(keys, row.SessionKey)
+	}
+	if len(keys) != 3 || keys[0] != "http:*" || keys[1] != "telegram:*" || keys[2] != "telegram:100" {
+		t.Fatalf("sessions after compaction = %v", keys)
+	}
+	if bySession.Rows[1].Turns != 2 || bySession.Total.Turns != 4 || bySession.Total.TotalTokens != 60 {
+		t.Fatalf("compacted rows = %+v, total %+v", bySession.Rows, bySession.Total)
+	}
+}
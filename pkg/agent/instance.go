@@ -5,10 +5,13 @@ import (
 	"errors"
 	"strings"
 	"sync"
+	"time"
 
 	"miniclaw/pkg/config"
 	"miniclaw/pkg/provider"
 	providertypes "miniclaw/pkg/provider/types"
+	"miniclaw/pkg/store"
+	"miniclaw/pkg/transcript"
 )
 
 type Instance struct {
@@ -20,16 +23,24 @@ type Instance struct {
 	memory    *Memory
 	// queueWake is a coalescing signal channel: one token means "queue has work".
 	queueWake chan struct{}
+	// stepBackoff and maxStepFailures tune heartbeat error recovery in Run.
+	stepBackoff     time.Duration
+	maxStepFailures int
+	// onHeartbeat runs on every heartbeat tick of Run; nil when unset.
+	onHeartbeat func()
 
 	mu        sync.RWMutex
 	sessionID string
 	queue     []queuedPrompt
+	// loopErr is set once Run gives up so new prompts fail fast instead of waiting forever.
+	loopErr error
 }
 
 type queuedPrompt struct {
-	prompt   string
-	resultCh chan promptResult
-	ctx      context.Context
+	prompt     string
+	resultCh   chan promptResult
+	ctx        context.Context
+	enqueuedAt time.Time
 }
 
 type promptResult struct {
@@ -46,6 +57,9 @@ func New(client provider.Client, model string, heartbeat config.HeartbeatConfig,
 		heartbeat: heartbeat,
 		memory:    NewMemory(),
 		queueWake: make(chan struct{}, 1),
+
+		stepBackoff:     defaultStepBackoff,
+		maxStepFailures: defaultMaxStepFailures,
 	}
 }
 
@@ -66,6 +80,53 @@ func (i *Instance) StartSession(ctx context.Context, title string) error {
 	return nil
 }
 
+// Reset starts a new provider session titled title and clears the
+// transcript, so the next prompt continues no earlier conversation.
+func (i *Instance) Reset(ctx context.Context, title string) error {
+	if err := i.StartSession(ctx, title); err != nil {
+		return err
+	}
+	i.memory.Clear()
+
+	return nil
+}
+
+// UseSession continues the provider session sessionID, for example one whose
+// ID was saved before a restart. Call it instead of StartSession.
+func (i *Instance) UseSession(sessionID string) {
+	i.mu.Lock()
+	i.sessionID = sessionID
+	i.mu.Unlock()
+}
+
+// ResumeSession continues the most recent provider session started with
+// title, so a restarted process keeps its conversation. When the client
+// cannot resume sessions or has none with title, it starts a new session and
+// reports false.
+func (i *Instance) ResumeSession(ctx context.Context, title string) (bool, error) {
+	resumer, ok := i.client.(provider.SessionResumer)
+	if !ok {
+		return false, i.StartSession(ctx, title)
+	}
+	if err := i.client.Health(ctx); err != nil {
+		return false, err
+	}
+
+	sessionID, found, err := resumer.LatestSession(ctx, title)
+	if err != nil {
+		return false, err
+	}
+	if !found {
+		return false, i.StartSession(ctx, title)
+	}
+
+	i.mu.Lock()
+	i.sessionID = sessionID
+	i.mu.Unlock()
+
+	return true, nil
+}
+
 func (i *Instance) Prompt(ctx context.Context, prompt string) (providertypes.PromptResult, error) {
 	prompt = strings.TrimSpace(prompt)
 	if prompt == "" {
@@ -77,10 +138,12 @@ func (i *Instance) Prompt(ctx context.Context, prompt string) (providertypes.Pro
 		return providertypes.PromptResult{}, errors.New("session is not started")
 	}
 
+	startedAt := time.Now()
 	result, err := i.client.Prompt(ctx, sessionID, prompt, i.model, i.agent, i.system)
 	if err != nil {
 		return providertypes.PromptResult{}, err
 	}
+	recordProviderTiming(&result, time.Since(startedAt))
 
 	i.memory.Append("user", prompt)
 	i.memory.Append("assistant", result.Text)
@@ -88,6 +151,66 @@ func (i *Instance) Prompt(ctx context.Context, prompt string) (providertypes.Pro
 	return result, nil
 }
 
+// recordProviderTiming attributes a provider call's wall-clock time, less any
+// tool time the provider reported, to provider latency.
+func recordProviderTiming(result *providertypes.PromptResult, elapsed time.Duration) {
+	timing := result.Metadata.EnsureTiming()
+	timing.Provider = max(elapsed-timing.Tools, 0)
+	timing.Total = elapsed
+}
+
+// ExportSession copies the current provider session out of the client's
+// session store, when the client supports it.
+func (i *Instance) ExportSession(ctx context.Context) (transcript.Transcript, error) {
+	exporter, ok := i.client.(provider.SessionExporter)
+	if !ok {
+		return transcript.Transcript{}, errors.New("provider does not support session export")
+	}
+	sessionID := i.SessionID()
+	if sessionID == "" {
+		return transcript.Transcript{}, errors.New("session is not started")
+	}
+
+	return exporter.ExportSession(ctx, sessionID)
+}
+
+// SessionName returns the provider session's display name. It is empty when
+// the session is not started, has no name yet, or the client does not name
+// sessions (provider.SessionNamer).
+func (i *Instance) SessionName(ctx context.Context) (string, error) {
+	namer, ok := i.client.(provider.SessionNamer)
+	sessionID := i.SessionID()
+	if !ok || sessionID == "" {
+		return "", nil
+	}
+
+	return namer.SessionName(ctx, sessionID)
+}
+
+// RollbackLastTurn removes the last prompt and its reply from memory and, when
+// the client keeps session history (provider.TurnRollbacker), from the
+// provider session too, so the prompt can be retried cleanly. It returns
+// providertypes.ErrNoTurnToUndo when there is nothing to remove.
+func (i *Instance) RollbackLastTurn(ctx context.Context) error {
+	sessionID := i.SessionID()
+	if sessionID == "" {
+		return errors.New("session is not started")
+	}
+
+	if rollbacker, ok := i.client.(provider.TurnRollbacker); ok {
+		if err := rollbacker.RollbackLastTurn(ctx, sessionID); err != nil {
+			return err
+		}
+		i.memory.DropLastTurn()
+		return nil
+	}
+	if !i.memory.DropLastTurn() {
+		return providertypes.ErrNoTurnToUndo
+	}
+
+	return nil
+}
+
 func (i *Instance) SessionID() string {
 	i.mu.RLock()
 	defer i.mu.RUnlock()
@@ -95,6 +218,20 @@ func (i *Instance) SessionID() string {
 	return i.sessionID
 }
 
+// UseStore switches the instance transcript to one persisted under id.
+//
+// Existing entries for id are loaded, so a restarted process resumes the same
+// transcript. Call it before the first prompt.
+func (i *Instance) UseStore(ctx context.Context, sessionStore store.SessionStore, id string) error {
+	memory, err := NewPersistentMemory(ctx, sessionStore, id)
+	if err != nil {
+		return err
+	}
+
+	i.memory = memory
+	return nil
+}
+
 func (i *Instance) MemorySnapshot() []MemoryEntry {
 	return i.memory.List()
 }
@@ -103,6 +240,14 @@ func (i *Instance) HeartbeatEnabled() bool {
 	return i.heartbeat.Enabled
 }
 
+// OnHeartbeat sets fn to run on every heartbeat tick of Run, before queued
+// prompts are drained. Call it before Run.
+func (i *Instance) OnHeartbeat(fn func()) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.onHeartbeat = fn
+}
+
 func (i *Instance) EnqueuePrompt(prompt string) {
 	i.enqueuePrompt(context.Background(), prompt, nil)
 }
@@ -133,7 +278,10 @@ func (i *Instance) enqueuePrompt(promptCtx context.Context, prompt string, resul
 	i.mu.Lock()
 	defer i.mu.Unlock()
 
-	i.queue = append(i.queue, queuedPrompt{prompt: prompt, resultCh: resultCh, ctx: promptCtx})
+	if i.loopErr != nil {
+		return i.loopErr
+	}
+	i.queue = append(i.queue, queuedPrompt{prompt: prompt, resultCh: resultCh, ctx: promptCtx, enqueuedAt: time.Now()})
 	if i.heartbeat.Enabled {
 		select {
 		case i.queueWake <- struct{}{}:
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"sync"
 	"testing"
+	"time"
 
 	"miniclaw/pkg/config"
 	providertypes "miniclaw/pkg/provider/types"
@@ -170,3 +171,70 @@ func TestPromptPassesAgentAndSystemProfile(t *testing.T) {
 		t.Fatalf("system prompt = %q, want %q", client.lastSystem, "system profile")
 	}
 }
+
+func TestPromptRecordsProviderTiming(t *testing.T) {
+	client := &fakeProviderClient{createSessionID: "session-1", promptResponse: "ok"}
+	instance := New(client, "model", config.HeartbeatConfig{}, "", "")
+	if err := instance.StartSession(context.Background(), "test"); err != nil {
+		t.Fatalf("StartSession error: %v", err)
+	}
+
+	result, err := instance.Prompt(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Prompt error: %v", err)
+	}
+	timing := result.Metadata.Timing
+	if timing == nil || timing.Total != timing.Provider || timing.QueueWait != 0 || timing.Tools != 0 {
+		t.Fatalf("timing = %+v, want provider-only timing", timing)
+	}
+}
+
+func TestRecordProviderTimingSubtractsToolTime(t *testing.T) {
+	result := providertypes.PromptResult{Metadata: providertypes.PromptMetadata{Timing: &providertypes.TurnTiming{Tools: 300 * time.Millisecond}}}
+	recordProviderTiming(&result, time.Second)
+
+	if got := result.Metadata.Timing; got.Provider != 700*time.Millisecond || got.Total != time.Second {
+		t.Fatalf("timing = %+v, want provider 700ms of 1s total", got)
+	}
+}
+
+// resumingProviderClient is a fakeProviderClient that also resumes sessions.
+type resumingProviderClient struct {
+	fakeProviderClient
+	latest map[string]string
+}
+
+func (r *resumingProviderClient) LatestSession(ctx context.Context, title string) (string, bool, error) {
+	sessionID, ok := r.latest[title]
+	return sessionID, ok, nil
+}
+
+func TestResumeSession(t *testing.T) {
+	client := &resumingProviderClient{
+		fakeProviderClient: fakeProviderClient{createSessionID: "session-new"},
+		latest:             map[string]string{"miniclaw": "session-old"},
+	}
+
+	inst := New(client, "openai/gpt-5.2", config.HeartbeatConfig{}, "", "")
+	resumed, err := inst.ResumeSession(context.Background(), "miniclaw")
+	if err != nil || !resumed {
+		t.Fatalf("ResumeSession = %v, %v; want resumed", resumed, err)
+	}
+	if got := inst.SessionID(); got != "session-old" || client.createCalls != 0 {
+		t.Fatalf("SessionID = %q with %d creates, want session-old and none", got, client.createCalls)
+	}
+
+	fresh := New(client, "openai/gpt-5.2", config.HeartbeatConfig{}, "", "")
+	resumed, err = fresh.ResumeSession(context.Background(), "miniclaw:telegram:1")
+	if err != nil || resumed {
+		t.Fatalf("ResumeSession without stored session = %v, %v; want new session", resumed, err)
+	}
+	if got := fresh.SessionID(); got != "session-new" {
+		t.Fatalf("SessionID = %q, want session-new", got)
+	}
+
+	plain := New(&fakeProviderClient{createSessionID: "session-plain"}, "openai/gpt-5.2", config.HeartbeatConfig{}, "", "")
+	if resumed, err := plain.ResumeSession(context.Background(), "miniclaw"); err != nil || resumed || plain.SessionID() != "session-plain" {
+		t.Fatalf("ResumeSession on a non-resuming client = %v, %v, %q", resumed, err, plain.SessionID())
+	}
+}
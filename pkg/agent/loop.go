@@ -3,9 +3,27 @@ package agent
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
 	"time"
 )
 
+const (
+	defaultStepBackoff     = time.Second
+	maxStepBackoff         = 30 * time.Second
+	defaultMaxStepFailures = 5
+)
+
+// ErrHeartbeatCircuitOpen is returned by Run after too many consecutive step failures.
+var ErrHeartbeatCircuitOpen = errors.New("heartbeat circuit open")
+
+// Run drains queued prompts on wake signals and on every heartbeat tick.
+//
+// Step errors are treated as transient: they are logged and retried with
+// exponential backoff so one provider hiccup does not stop background
+// processing for the session. After maxStepFailures consecutive failures the
+// loop gives up, fails any still-queued (and future) prompts, and returns
+// ErrHeartbeatCircuitOpen so callers can surface the persistent problem.
 func (i *Instance) Run(ctx context.Context) error {
 	if !i.heartbeat.Enabled {
 		return nil
@@ -19,19 +37,50 @@ func (i *Instance) Run(ctx context.Context) error {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	failures := 0
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
 		case <-i.queueWakeChannel():
 			// Process immediately when new work arrives.
-			if err := i.processQueuedPrompts(ctx); err != nil {
-				return err
-			}
 		case <-ticker.C:
 			// Periodic draining is a safety net in case no wake signal is observed.
-			if err := i.processQueuedPrompts(ctx); err != nil {
-				return err
+			i.mu.RLock()
+			onHeartbeat := i.onHeartbeat
+			i.mu.RUnlock()
+			if onHeartbeat != nil {
+				onHeartbeat()
+			}
+		}
+
+		for {
+			err := i.processQueuedPrompts(ctx)
+			if err == nil || ctx.Err() != nil {
+				failures = 0
+				break
+			}
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				// The caller gave up on that prompt; this says nothing about provider health.
+				continue
+			}
+
+			failures++
+			if failures >= i.maxStepFailures {
+				circuitErr := fmt.Errorf("%w after %d consecutive failures: %w", ErrHeartbeatCircuitOpen, failures, err)
+				i.mu.Lock()
+				i.loopErr = circuitErr
+				i.mu.Unlock()
+				i.failQueuedPrompts(circuitErr)
+				return circuitErr
+			}
+
+			backoff := i.stepBackoffFor(failures)
+			slog.Default().Warn("Heartbeat step failed; retrying", "component", "agent", "error", err, "consecutive_failures", failures, "backoff_ms", backoff.Milliseconds())
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(backoff):
 			}
 		}
 	}
@@ -49,7 +98,13 @@ func (i *Instance) processQueuedPrompts(ctx context.Context) error {
 			promptCtx = ctx
 		}
 
+		queueWait := time.Since(item.enqueuedAt)
 		result, err := i.Prompt(promptCtx, item.prompt)
+		if err == nil {
+			timing := result.Metadata.EnsureTiming()
+			timing.QueueWait += queueWait
+			timing.Total += queueWait
+		}
 		if item.resultCh != nil {
 			item.resultCh <- promptResult{result: result, err: err}
 		}
@@ -62,3 +117,29 @@ func (i *Instance) processQueuedPrompts(ctx context.Context) error {
 func (i *Instance) Step(ctx context.Context) error {
 	return i.processQueuedPrompts(ctx)
 }
+
+// stepBackoffFor doubles the base backoff per consecutive failure, capped at maxStepBackoff.
+func (i *Instance) stepBackoffFor(failures int) time.Duration {
+	backoff := i.stepBackoff
+	for range failures - 1 {
+		backoff *= 2
+		if backoff >= maxStepBackoff {
+			return maxStepBackoff
+		}
+	}
+
+	return backoff
+}
+
+// failQueuedPrompts drains the queue and reports err to every waiting caller.
+func (i *Instance) failQueuedPrompts(err error) {
+	for {
+		item, ok := i.dequeuePrompt()
+		if !ok {
+			return
+		}
+		if item.resultCh != nil {
+			item.resultCh <- promptResult{err: err}
+		}
+	}
+}
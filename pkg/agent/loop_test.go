@@ -146,3 +146,95 @@ func TestEnqueueAndWaitRespectsContextCancel(t *testing.T) {
 		t.Fatalf("error = %v, want %v", err, context.Canceled)
 	}
 }
+
+func TestRunRecoversFromTransientStepErrors(t *testing.T) {
+	client := &fakeProviderClient{createSessionID: "session-1", promptResponse: "pong", promptErr: errors.New("provider unavailable")}
+	inst := New(client, "openai/gpt-5.2", config.HeartbeatConfig{Enabled: true, Interval: 60}, "", "")
+	inst.stepBackoff = time.Millisecond
+
+	if err := inst.StartSession(context.Background(), "miniclaw"); err != nil {
+		t.Fatalf("StartSession error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- inst.Run(ctx)
+	}()
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), time.Second)
+	defer waitCancel()
+	if _, err := inst.EnqueueAndWait(waitCtx, "first"); err == nil {
+		t.Fatal("expected first prompt to fail")
+	}
+
+	client.mu.Lock()
+	client.promptErr = nil
+	client.mu.Unlock()
+
+	result, err := inst.EnqueueAndWait(waitCtx, "second")
+	if err != nil {
+		t.Fatalf("EnqueueAndWait error after recovery: %v", err)
+	}
+	if result.Text != "pong" {
+		t.Fatalf("result text = %q, want %q", result.Text, "pong")
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+}
+
+func TestRunOpensCircuitAfterConsecutiveFailures(t *testing.T) {
+	client := &fakeProviderClient{createSessionID: "session-1", promptErr: errors.New("provider unavailable")}
+	inst := New(client, "openai/gpt-5.2", config.HeartbeatConfig{Enabled: true, Interval: 60}, "", "")
+	inst.stepBackoff = time.Millisecond
+	inst.maxStepFailures = 2
+
+	if err := inst.StartSession(context.Background(), "miniclaw"); err != nil {
+		t.Fatalf("StartSession error: %v", err)
+	}
+
+	inst.EnqueuePrompt("one")
+	inst.EnqueuePrompt("two")
+	resultCh := make(chan promptResult, 1)
+	if err := inst.enqueuePrompt(context.Background(), "three", resultCh); err != nil {
+		t.Fatalf("enqueuePrompt error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err := inst.Run(ctx)
+	if !errors.Is(err, ErrHeartbeatCircuitOpen) {
+		t.Fatalf("Run error = %v, want ErrHeartbeatCircuitOpen", err)
+	}
+	if got := client.promptCallCount(); got != 2 {
+		t.Fatalf("prompt calls = %d, want 2", got)
+	}
+
+	queued := <-resultCh
+	if !errors.Is(queued.err, ErrHeartbeatCircuitOpen) {
+		t.Fatalf("queued prompt error = %v, want ErrHeartbeatCircuitOpen", queued.err)
+	}
+	if _, err := inst.EnqueueAndWait(context.Background(), "four"); !errors.Is(err, ErrHeartbeatCircuitOpen) {
+		t.Fatalf("EnqueueAndWait error = %v, want ErrHeartbeatCircuitOpen", err)
+	}
+}
+
+func TestStepBackoffIsCapped(t *testing.T) {
+	inst := New(&fakeProviderClient{}, "", config.HeartbeatConfig{}, "", "")
+
+	if got := inst.stepBackoffFor(1); got != defaultStepBackoff {
+		t.Fatalf("backoff(1) = %v, want %v", got, defaultStepBackoff)
+	}
+	if got := inst.stepBackoffFor(3); got != 4*defaultStepBackoff {
+		t.Fatalf("backoff(3) = %v, want %v", got, 4*defaultStepBackoff)
+	}
+	if got := inst.stepBackoffFor(20); got != maxStepBackoff {
+		t.Fatalf("backoff(20) = %v, want %v", got, maxStepBackoff)
+	}
+}
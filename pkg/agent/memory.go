@@ -1,9 +1,17 @@
 package agent
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"strings"
 	"sync"
 	"time"
+
+	"miniclaw/pkg/store"
+	"miniclaw/pkg/transcript"
 )
 
 type MemoryEntry struct {
@@ -15,12 +23,44 @@ type MemoryEntry struct {
 type Memory struct {
 	mu      sync.RWMutex
 	entries []MemoryEntry
+
+	// store and storeID are set for memories that write through to a SessionStore.
+	store   store.SessionStore
+	storeID string
 }
 
 func NewMemory() *Memory {
 	return &Memory{}
 }
 
+// NewPersistentMemory loads (or creates) a transcript backed by a session store.
+//
+// Appends are written through to the store so the transcript survives restarts.
+func NewPersistentMemory(ctx context.Context, sessionStore store.SessionStore, id string) (*Memory, error) {
+	session, err := sessionStore.Load(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		session, err = sessionStore.Create(ctx, id, "")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	memory := &Memory{store: sessionStore, storeID: id}
+	for _, turn := range session.Turns {
+		if turn.Role == transcript.RollbackRole {
+			var rollback transcript.Rollback
+			if err := json.Unmarshal(turn.Payload, &rollback); err != nil {
+				return nil, fmt.Errorf("decode rollback: %w", err)
+			}
+			memory.entries = memory.entries[:max(len(memory.entries)-rollback.Messages, 0)]
+			continue
+		}
+		memory.entries = append(memory.entries, MemoryEntry{Role: turn.Role, Content: turn.Content, At: turn.At})
+	}
+
+	return memory, nil
+}
+
 func (m *Memory) Append(role string, content string) {
 	role = strings.TrimSpace(role)
 	content = strings.TrimSpace(content)
@@ -28,14 +68,22 @@ func (m *Memory) Append(role string, content string) {
 		return
 	}
 
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	m.entries = append(m.entries, MemoryEntry{
+	entry := MemoryEntry{
 		Role:    role,
 		Content: content,
 		At:      time.Now().UTC(),
-	})
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries = append(m.entries, entry)
+	if m.store != nil {
+		// Persistence is best-effort: the in-memory transcript stays authoritative for this process.
+		if err := m.store.AppendTurn(context.Background(), m.storeID, store.Turn{Role: entry.Role, Content: entry.Content, At: entry.At}); err != nil {
+			slog.Default().Warn("Failed to persist memory entry", "component", "agent.memory", "session_id", m.storeID, "error", err)
+		}
+	}
 }
 
 func (m *Memory) List() []MemoryEntry {
@@ -51,9 +99,52 @@ func (m *Memory) List() []MemoryEntry {
 	return out
 }
 
+// DropLastTurn removes the last user entry and every entry after it, and
+// reports whether there was one. A persisted memory records the removal as a
+// rollback turn, so a restart does not bring the entries back.
+func (m *Memory) DropLastTurn() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	last := -1
+	for index, entry := range m.entries {
+		if entry.Role == "user" {
+			last = index
+		}
+	}
+	if last < 0 {
+		return false
+	}
+
+	dropped := len(m.entries) - last
+	m.entries = m.entries[:last]
+	if m.store != nil {
+		payload, err := json.Marshal(transcript.Rollback{Messages: dropped})
+		if err == nil {
+			err = m.store.AppendTurn(context.Background(), m.storeID, store.Turn{Role: transcript.RollbackRole, Payload: payload})
+		}
+		if err != nil {
+			slog.Default().Warn("Failed to persist memory rollback", "component", "agent.memory", "session_id", m.storeID, "error", err)
+		}
+	}
+
+	return true
+}
+
 func (m *Memory) Clear() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	m.entries = nil
+	if m.store != nil {
+		// Recreate the stored session so a restart does not resurrect cleared entries.
+		ctx := context.Background()
+		if err := m.store.Delete(ctx, m.storeID); err != nil && !errors.Is(err, store.ErrNotFound) {
+			slog.Default().Warn("Failed to clear persisted memory", "component", "agent.memory", "session_id", m.storeID, "error", err)
+			return
+		}
+		if _, err := m.store.Create(ctx, m.storeID, ""); err != nil {
+			slog.Default().Warn("Failed to clear persisted memory", "component", "agent.memory", "session_id", m.storeID, "error", err)
+		}
+	}
 }
@@ -1,8 +1,12 @@
 package agent
 
 import (
+	"context"
+	"strings"
 	"sync"
 	"testing"
+
+	"miniclaw/pkg/store"
 )
 
 func TestMemoryAppendListClear(t *testing.T) {
@@ -47,3 +51,68 @@ func TestMemoryConcurrentAppend(t *testing.T) {
 		t.Fatalf("len(entries) = %d, want %d", got, n)
 	}
 }
+
+func TestPersistentMemoryReloadsFromStore(t *testing.T) {
+	sessionStore, err := store.NewJSONLStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONLStore error: %v", err)
+	}
+
+	first, err := NewPersistentMemory(context.Background(), sessionStore, "gateway:telegram:1")
+	if err != nil {
+		t.Fatalf("NewPersistentMemory error: %v", err)
+	}
+	first.Append("user", "hello")
+	first.Append("assistant", "hi")
+
+	second, err := NewPersistentMemory(context.Background(), sessionStore, "gateway:telegram:1")
+	if err != nil {
+		t.Fatalf("NewPersistentMemory reload error: %v", err)
+	}
+	entries := second.List()
+	if len(entries) != 2 || entries[0].Content != "hello" || entries[1].Role != "assistant" {
+		t.Fatalf("reloaded entries = %#v, want persisted transcript", entries)
+	}
+
+	second.Clear()
+	third, err := NewPersistentMemory(context.Background(), sessionStore, "gateway:telegram:1")
+	if err != nil {
+		t.Fatalf("NewPersistentMemory after clear error: %v", err)
+	}
+	if got := len(third.List()); got != 0 {
+		t.Fatalf("len(entries) after clear = %d, want 0", got)
+	}
+}
+
+func TestPersistentMemoryDropLastTurnSurvivesReload(t *testing.T) {
+	sessionStore := store.NewMemoryStore()
+	first, err := NewPersistentMemory(context.Background(), sessionStore, "gateway:telegram:1")
+	if err != nil {
+		t.Fatalf("NewPersistentMemory error: %v", err)
+	}
+	if first.DropLastTurn() {
+		t.Fatal("DropLastTurn on an empty memory = true, want false")
+	}
+	first.Append("user", "hello")
+	first.Append("assistant", "hi")
+	first.Append("user", "bad prompt")
+	first.Append("assistant", "bad reply")
+
+	if !first.DropLastTurn() {
+		t.Fatal("DropLastTurn = false, want true")
+	}
+	first.Append("user", "better prompt")
+	first.Append("assistant", "better reply")
+
+	second, err := NewPersistentMemory(context.Background(), sessionStore, "gateway:telegram:1")
+	if err != nil {
+		t.Fatalf("NewPersistentMemory reload error: %v", err)
+	}
+	var contents []string
+	for _, entry := range second.List() {
+		contents = append(contents, entry.Content)
+	}
+	if got := strings.Join(contents, "|"); got != "hello|hi|better prompt|better reply" {
+		t.Fatalf("reloaded entries = %q, want the undone turn gone", got)
+	}
+}
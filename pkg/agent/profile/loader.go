@@ -30,6 +30,16 @@ func ResolveSystemProfile(provider string) (string, error) {
 	return profile, nil
 }
 
+// WithInstructions appends instructions to a system profile.
+func WithInstructions(profile string, instructions string) string {
+	instructions = strings.TrimSpace(instructions)
+	if instructions == "" {
+		return profile
+	}
+
+	return strings.TrimSpace(profile + "\n\n" + instructions)
+}
+
 func templatePath(templateName string) string {
 	return "templates/" + strings.TrimSpace(templateName) + ".md"
 }
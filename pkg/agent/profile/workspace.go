@@ -0,0 +1,63 @@
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strings"
+
+	"miniclaw/pkg/config"
+	"miniclaw/pkg/workspace"
+)
+
+// maxWorkspaceFileBytes bounds each system prompt file read from the workspace.
+const maxWorkspaceFileBytes = 64 << 10
+
+// WorkspacePrompt reads agents.defaults.system_files from the workspace and
+// returns them as one block, each file under a "# <path>" heading, to be
+// merged into the system profile with WithInstructions.
+//
+// Files are read through the workspace guard, so they obey the same
+// containment and symlink policy as the file tools. Missing files are
+// skipped, and files over 64 KiB are cut with a warning. Callers read the
+// files at session start, so edits apply to the next session.
+func WorkspacePrompt(defaults config.AgentDefaults) (string, error) {
+	if len(defaults.SystemFiles) == 0 {
+		return "", nil
+	}
+
+	guard, err := workspace.NewGuardWithOptions(defaults.Workspace, workspace.GuardOptions{
+		RestrictToWorkspace: defaults.RestrictToWorkspace,
+		AllowedPaths:        defaults.AllowedPaths,
+		SymlinkPolicy:       defaults.SymlinkPolicy,
+	})
+	if err != nil {
+		return "", fmt.Errorf("initialize workspace guard: %w", err)
+	}
+
+	sections := make([]string, 0, len(defaults.SystemFiles))
+	for _, name := range defaults.SystemFiles {
+		path, err := guard.ResolvePath(name)
+		if err != nil {
+			return "", fmt.Errorf("system file %s: %w", name, err)
+		}
+		content, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("read system file %s: %w", name, err)
+		}
+		if len(content) > maxWorkspaceFileBytes {
+			slog.Default().Warn("System file truncated", "component", "agent.profile", "path", guard.RelPath(path), "max_bytes", maxWorkspaceFileBytes)
+			content = content[:maxWorkspaceFileBytes]
+		}
+		if text := strings.TrimSpace(strings.ToValidUTF8(string(content), "")); text != "" {
+			sections = append(sections, "# "+guard.RelPath(path)+"\n\n"+text)
+		}
+	}
+
+	return strings.Join(sections, "\n\n"), nil
+}
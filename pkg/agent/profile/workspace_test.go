@@ -0,0 +1,49 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"miniclaw/pkg/config"
+)
+
+func TestWorkspacePrompt(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "AGENTS.md"), []byte("  Run go test before committing.\n"), 0o644); err != nil {
+		t.Fatalf("write AGENTS.md: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "SOUL.md"), []byte("Be terse."), 0o644); err != nil {
+		t.Fatalf("write SOUL.md: %v", err)
+	}
+	defaults := config.AgentDefaults{
+		Workspace:           root,
+		RestrictToWorkspace: true,
+		SystemFiles:         []string{"AGENTS.md", "MISSING.md", "SOUL.md"},
+	}
+
+	got, err := WorkspacePrompt(defaults)
+	if err != nil {
+		t.Fatalf("WorkspacePrompt error: %v", err)
+	}
+	want := "# AGENTS.md\n\nRun go test before committing.\n\n# SOUL.md\n\nBe terse."
+	if got != want {
+		t.Fatalf("WorkspacePrompt = %q, want %q", got, want)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "SOUL.md"), []byte("Be friendly."), 0o644); err != nil {
+		t.Fatalf("rewrite SOUL.md: %v", err)
+	}
+	got, err = WorkspacePrompt(defaults)
+	if err != nil {
+		t.Fatalf("WorkspacePrompt error after edit: %v", err)
+	}
+	if want := "# AGENTS.md\n\nRun go test before committing.\n\n# SOUL.md\n\nBe friendly."; got != want {
+		t.Fatalf("WorkspacePrompt after edit = %q, want %q", got, want)
+	}
+
+	defaults.SystemFiles = []string{"../outside.md"}
+	if _, err := WorkspacePrompt(defaults); err == nil {
+		t.Fatal("expected error for a system file outside the workspace")
+	}
+}
@@ -0,0 +1,152 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+// ErrCircuitOpen is wrapped by the error a CircuitBreaker returns while it
+// fails prompts fast.
+var ErrCircuitOpen = errors.New("provider circuit breaker is open")
+
+// CircuitBreaker fails a session's prompts fast while its provider is down,
+// so a chat gets an answer at once instead of waiting on every timeout.
+//
+// It opens after a run of consecutive outage failures (provider_down,
+// timeout, or rate_limit) and half-opens after the cooldown, letting one
+// trial prompt through: success closes it, another failure reopens it.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	log       *slog.Logger
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	trial     bool
+	provider  string
+}
+
+// NewCircuitBreaker returns a breaker for one session, or nil when
+// cfg.Enabled is false. A nil breaker passes every prompt through.
+func NewCircuitBreaker(cfg config.CircuitBreakerConfig, log *slog.Logger) *CircuitBreaker {
+	if !cfg.Enabled {
+		return nil
+	}
+	if log == nil {
+		log = slog.Default()
+	}
+
+	threshold := defaultBreakerThreshold
+	if cfg.FailureThreshold > 0 {
+		threshold = cfg.FailureThreshold
+	}
+	cooldown := defaultBreakerCooldown
+	if cfg.CooldownSeconds > 0 {
+		cooldown = time.Duration(cfg.CooldownSeconds) * time.Second
+	}
+
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		log:       log,
+		now:       time.Now,
+	}
+}
+
+// Middleware returns the breaker as prompt middleware. Place it innermost so
+// only provider failures count. A nil breaker returns nil, which Chain skips.
+func (b *CircuitBreaker) Middleware() Middleware {
+	if b == nil {
+		return nil
+	}
+
+	return func(next PromptHandler) PromptHandler {
+		return func(ctx context.Context, prompt string) (providertypes.PromptResult, error) {
+			trial, err := b.allow()
+			if err != nil {
+				return providertypes.PromptResult{}, err
+			}
+
+			result, err := next(ctx, prompt)
+			b.record(trial, err)
+			return result, err
+		}
+	}
+}
+
+// allow admits a prompt or returns a circuit_open error. Once the cooldown
+// has passed it admits a single trial prompt at a time and reports it as such.
+func (b *CircuitBreaker) allow() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return false, nil
+	}
+	now := b.now()
+	if !b.trial && !now.Before(b.openUntil) {
+		b.trial = true
+		return true, nil
+	}
+
+	return false, &providertypes.PromptError{
+		Category:   providertypes.ErrorCircuitOpen,
+		Provider:   b.provider,
+		RetryAfter: max(b.openUntil.Sub(now), time.Second),
+		Err:        fmt.Errorf("%w after %d consecutive failures", ErrCircuitOpen, b.failures),
+	}
+}
+
+// record updates the failure run with the outcome of an admitted prompt.
+// Failures that do not point at the provider, such as a rejected prompt or a
+// cancel, neither count nor reset the run.
+func (b *CircuitBreaker) record(trial bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if trial {
+		b.trial = false
+	}
+	switch {
+	case err == nil:
+		if b.failures >= b.threshold {
+			b.log.Info("Circuit breaker closed", "provider", b.provider)
+		}
+		b.failures = 0
+	case isOutage(err):
+		b.failures++
+		var promptErr *providertypes.PromptError
+		if errors.As(err, &promptErr) && promptErr.Provider != "" {
+			b.provider = promptErr.Provider
+		}
+		if b.failures >= b.threshold {
+			b.openUntil = b.now().Add(b.cooldown)
+			b.log.Warn("Circuit breaker opened", "provider", b.provider, "failures", b.failures, "cooldown", b.cooldown, "error", err)
+		}
+	}
+}
+
+// isOutage reports whether err suggests the provider itself is failing, as
+// opposed to a problem with this prompt or a user cancel.
+func isOutage(err error) bool {
+	switch providertypes.ErrorCategoryOf(err) {
+	case providertypes.ErrorProviderDown, providertypes.ErrorTimeout, providertypes.ErrorRateLimit:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,163 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+// ErrBudgetExhausted is wrapped by the error a Budget returns once the
+// session has spent one of its caps.
+var ErrBudgetExhausted = errors.New("session budget exhausted")
+
+// Budget refuses a session's prompts once it has spent the token or estimated
+// USD caps of runtime.budget, over its lifetime or the current UTC day.
+//
+// Spending is counted in memory from each reply's token usage, so it starts
+// over when the process restarts. A prompt that starts under the cap runs to
+// completion even if it ends above it.
+type Budget struct {
+	cfg config.BudgetConfig
+	log *slog.Logger
+	now func() time.Time
+
+	mu       sync.Mutex
+	session  spend
+	today    spend
+	day      time.Time
+	exceeded bool
+}
+
+// spend is the usage counted against one cap period.
+type spend struct {
+	tokens int64
+	usd    float64
+}
+
+// NewBudget returns a budget for one session, or nil when cfg is disabled or
+// sets no cap. A nil budget passes every prompt through. USD caps need at
+// least one token price.
+func NewBudget(cfg config.BudgetConfig, log *slog.Logger) (*Budget, error) {
+	if !cfg.Enabled || (cfg.MaxTokensPerSession <= 0 && cfg.MaxTokensPerDay <= 0 && cfg.MaxUSDPerSession <= 0 && cfg.MaxUSDPerDay <= 0) {
+		return nil, nil
+	}
+	if (cfg.MaxUSDPerSession > 0 || cfg.MaxUSDPerDay > 0) && cfg.InputUSDPerMillion <= 0 && cfg.OutputUSDPerMillion <= 0 {
+		return nil, errors.New("runtime.budget: USD caps need input_usd_per_million or output_usd_per_million")
+	}
+	if log == nil {
+		log = slog.Default()
+	}
+
+	return &Budget{cfg: cfg, log: log, now: time.Now}, nil
+}
+
+// Middleware returns the budget as prompt middleware. Place it outside the
+// circuit breaker so refused prompts never reach the provider. A nil budget
+// returns nil, which Chain skips.
+func (b *Budget) Middleware() Middleware {
+	if b == nil {
+		return nil
+	}
+
+	return func(next PromptHandler) PromptHandler {
+		return func(ctx context.Context, prompt string) (providertypes.PromptResult, error) {
+			if err := b.check(); err != nil {
+				return providertypes.PromptResult{}, err
+			}
+
+			result, err := next(ctx, prompt)
+			if err == nil && result.Metadata.Usage != nil {
+				b.record(*result.Metadata.Usage)
+			}
+			return result, err
+		}
+	}
+}
+
+// check returns a budget_exhausted error when a cap is spent. Day caps report
+// the time until midnight UTC as RetryAfter.
+func (b *Budget) check() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.rollDayLocked(now)
+
+	reason := ""
+	switch {
+	case b.cfg.MaxTokensPerSession > 0 && b.session.tokens >= b.cfg.MaxTokensPerSession:
+		reason = fmt.Sprintf("%d of %d tokens used in this session", b.session.tokens, b.cfg.MaxTokensPerSession)
+	case b.cfg.MaxUSDPerSession > 0 && b.session.usd >= b.cfg.MaxUSDPerSession:
+		reason = fmt.Sprintf("$%.2f of $%.2f spent in this session", b.session.usd, b.cfg.MaxUSDPerSession)
+	}
+	if reason != "" {
+		return &providertypes.PromptError{
+			Category: providertypes.ErrorBudgetExhausted,
+			Err:      fmt.Errorf("%w: %s", ErrBudgetExhausted, reason),
+		}
+	}
+
+	switch {
+	case b.cfg.MaxTokensPerDay > 0 && b.today.tokens >= b.cfg.MaxTokensPerDay:
+		reason = fmt.Sprintf("%d of %d tokens used today", b.today.tokens, b.cfg.MaxTokensPerDay)
+	case b.cfg.MaxUSDPerDay > 0 && b.today.usd >= b.cfg.MaxUSDPerDay:
+		reason = fmt.Sprintf("$%.2f of $%.2f spent today", b.today.usd, b.cfg.MaxUSDPerDay)
+	}
+	if reason != "" {
+		return &providertypes.PromptError{
+			Category:   providertypes.ErrorBudgetExhausted,
+			RetryAfter: b.day.AddDate(0, 0, 1).Sub(now),
+			Err:        fmt.Errorf("%w: %s", ErrBudgetExhausted, reason),
+		}
+	}
+
+	return nil
+}
+
+// record adds the usage of a finished prompt to both periods.
+func (b *Budget) record(usage providertypes.TokenUsage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.rollDayLocked(b.now())
+	tokens := usage.TotalTokens
+	if tokens == 0 {
+		tokens = usage.InputTokens + usage.OutputTokens
+	}
+	usd := (float64(usage.InputTokens)*b.cfg.InputUSDPerMillion + float64(usage.OutputTokens)*b.cfg.OutputUSDPerMillion) / 1e6
+	b.session.tokens += tokens
+	b.session.usd += usd
+	b.today.tokens += tokens
+	b.today.usd += usd
+
+	if !b.exceeded && b.exhaustedLocked() {
+		b.exceeded = true
+		b.log.Warn("Session budget exhausted", "session_tokens", b.session.tokens, "session_usd", b.session.usd, "day_tokens", b.today.tokens, "day_usd", b.today.usd)
+	}
+}
+
+// exhaustedLocked reports whether any cap is spent.
+func (b *Budget) exhaustedLocked() bool {
+	return (b.cfg.MaxTokensPerSession > 0 && b.session.tokens >= b.cfg.MaxTokensPerSession) ||
+		(b.cfg.MaxUSDPerSession > 0 && b.session.usd >= b.cfg.MaxUSDPerSession) ||
+		(b.cfg.MaxTokensPerDay > 0 && b.today.tokens >= b.cfg.MaxTokensPerDay) ||
+		(b.cfg.MaxUSDPerDay > 0 && b.today.usd >= b.cfg.MaxUSDPerDay)
+}
+
+// rollDayLocked starts a new day period once now passes midnight UTC.
+func (b *Budget) rollDayLocked(now time.Time) {
+	day := now.UTC().Truncate(24 * time.Hour)
+	if day.Equal(b.day) {
+		return
+	}
+
+	b.day = day
+	b.today = spend{}
+	b.exceeded = b.exhaustedLocked()
+}
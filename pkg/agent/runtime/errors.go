@@ -0,0 +1,64 @@
+package runtime
+
+import (
+	"errors"
+	"strconv"
+
+	"miniclaw/pkg/bus"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+// PromptErrorMetadata serializes the error category of err into outbound
+// metadata stamped with the schema version.
+//
+// The bus only carries error text, so the category travels alongside it and
+// PromptErrorFromOutbound rebuilds a typed error on the receiving side.
+func PromptErrorMetadata(err error) map[string]string {
+	if err == nil {
+		return nil
+	}
+
+	metadata := StampSchemaVersion(nil)
+	metadata[ErrorCategoryKey] = string(providertypes.ErrorCategoryOf(err))
+
+	var promptErr *providertypes.PromptError
+	if errors.As(err, &promptErr) {
+		if promptErr.Provider != "" {
+			metadata[ErrorProviderKey] = promptErr.Provider
+		}
+		if promptErr.StatusCode != 0 {
+			metadata[ErrorStatusCodeKey] = strconv.Itoa(promptErr.StatusCode)
+		}
+		if promptErr.RetryAfter > 0 {
+			metadata[ErrorRetryAfterMsKey] = strconv.FormatInt(promptErr.RetryAfter.Milliseconds(), 10)
+		}
+	}
+
+	return metadata
+}
+
+// PromptErrorFromOutbound reconstructs a categorized error from a failed outbound message.
+//
+// The request ID is attached so the UI can list failures by request. It
+// returns nil when the message carries no error.
+func PromptErrorFromOutbound(outbound bus.OutboundMessage) error {
+	if outbound.Error == "" {
+		return nil
+	}
+
+	err := errors.New(outbound.Error)
+	metadata := ReadMetadata(outbound)
+	category := metadata.ErrorCategory()
+	if category == "" {
+		category = providertypes.ErrorCategoryOf(err)
+	}
+
+	return &providertypes.PromptError{
+		Category:   category,
+		Provider:   metadata.String(ErrorProviderKey),
+		StatusCode: int(metadata.int64(ErrorStatusCodeKey)),
+		RetryAfter: parseMillis(metadata.String(ErrorRetryAfterMsKey)),
+		RequestID:  metadata.RequestID(),
+		Err:        err,
+	}
+}
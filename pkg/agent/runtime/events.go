@@ -3,15 +3,23 @@ package runtime
 import (
 	"context"
 	"log/slog"
+	"sync"
 
 	"miniclaw/pkg/bus"
+	providertypes "miniclaw/pkg/provider/types"
 )
 
+// observeAgentEvents logs bus events until ctx ends. It subscribes before
+// returning, so events published afterwards are never missed.
 func observeAgentEvents(ctx context.Context, messageBus *bus.MessageBus) {
 	// Subscribe to a buffered event stream so runtime workers never block on
 	// logging. Slow consumers may drop events by design in the bus layer.
 	log := slog.Default().With("component", "bus.events")
 	events, unsubscribe := messageBus.SubscribeEvents(ctx, 32)
+	go logEvents(ctx, log, events, unsubscribe)
+}
+
+func logEvents(ctx context.Context, log *slog.Logger, events <-chan bus.Event, unsubscribe func()) {
 	defer unsubscribe()
 
 	for {
@@ -35,12 +43,13 @@ func logEvent(log *slog.Logger, event bus.Event) {
 	attrs := []any{
 		"event_type", event.Type,
 		"request_id", event.RequestID,
+		"trace_id", event.TraceID,
 		"channel", event.Channel,
 		"chat_id", event.ChatID,
 		"session_key", event.SessionKey,
 		"timestamp", event.At.UTC().Format("2006-01-02T15:04:05.999999999Z07:00"),
 	}
-	if len(event.Payload) > 0 {
+	if event.Payload != nil {
 		attrs = append(attrs, "payload", event.Payload)
 	}
 
@@ -54,7 +63,90 @@ func logEvent(log *slog.Logger, event bus.Event) {
 		log.Info("Prompt event", attrs...)
 	case bus.EventPromptCompleted:
 		log.Info("Prompt event", attrs...)
+	case bus.EventWorkspaceChanged:
+		log.Info("Workspace event", attrs...)
+	case bus.EventSessionCreated, bus.EventSessionEvicted:
+		log.Info("Session event", attrs...)
+	case bus.EventToolStarted, bus.EventToolFinished:
+		log.Debug("Tool event", attrs...)
+	case bus.EventToolFailed:
+		log.Warn("Tool event", append(attrs, "error", event.Error)...)
+	case bus.EventHeartbeatTick:
+		log.Debug("Heartbeat event", attrs...)
+	case bus.EventChannelConnected:
+		log.Info("Channel event", attrs...)
+	case bus.EventChannelDisconnected:
+		if event.Error != "" {
+			log.Warn("Channel event", append(attrs, "error", event.Error)...)
+			break
+		}
+		log.Info("Channel event", attrs...)
 	default:
 		log.Debug("Prompt event", attrs...)
 	}
 }
+
+// WithToolEventBus returns ctx with a tool event handler that publishes each
+// tool call to messageBus as a tool_started event and each result as
+// tool_finished or tool_failed, identified like template, then passes the
+// event on to the handler ctx already carried, if any.
+//
+// Providers stop collecting PromptMetadata.ToolEvents once a handler is
+// attached, so pass the prompt result to the returned func: when ctx had no
+// handler of its own, it puts the events back there.
+func WithToolEventBus(ctx context.Context, messageBus *bus.MessageBus, template bus.Event) (context.Context, func(*providertypes.PromptResult)) {
+	next, hasNext := providertypes.ToolEventHandlerFromContext(ctx)
+
+	var (
+		mu        sync.Mutex
+		collected []providertypes.ToolEvent
+	)
+	handler := func(toolEvent providertypes.ToolEvent) {
+		event := template
+		payload := bus.ToolEventPayload{Tool: toolEvent.Tool}
+		switch {
+		case toolEvent.Kind == "call":
+			event.Type = bus.EventToolStarted
+		case toolEvent.Failed:
+			event.Type = bus.EventToolFailed
+			event.Error = toolEvent.Payload
+		default:
+			event.Type = bus.EventToolFinished
+		}
+		if event.Type != bus.EventToolStarted {
+			payload.DurationMs = toolEvent.DurationMs
+		}
+		event.Payload = payload
+		_ = messageBus.PublishEvent(ctx, event)
+
+		if hasNext {
+			next(toolEvent)
+			return
+		}
+		mu.Lock()
+		collected = append(collected, toolEvent)
+		mu.Unlock()
+	}
+
+	restore := func(result *providertypes.PromptResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !hasNext && result.Metadata.ToolEvents == nil && len(collected) > 0 {
+			result.Metadata.ToolEvents = collected
+		}
+	}
+
+	return providertypes.WithToolEventHandler(ctx, handler), restore
+}
+
+// LogTurnTiming writes one debug line splitting a turn into its timing stages.
+//
+// attrs identify the turn, for example its request ID or session key.
+func LogTurnTiming(log *slog.Logger, timing providertypes.TurnTiming, attrs ...any) {
+	log.Debug("Turn timing", append(attrs,
+		"queue_wait_ms", timing.QueueWait.Milliseconds(),
+		"provider_ms", timing.Provider.Milliseconds(),
+		"tools_ms", timing.Tools.Milliseconds(),
+		"total_ms", timing.Total.Milliseconds(),
+	)...)
+}
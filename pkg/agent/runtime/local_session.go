@@ -5,9 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"miniclaw/pkg/agent"
 	agentprofile "miniclaw/pkg/agent/profile"
@@ -15,21 +18,41 @@ import (
 	"miniclaw/pkg/config"
 	"miniclaw/pkg/provider"
 	providertypes "miniclaw/pkg/provider/types"
+	"miniclaw/pkg/transcript"
 )
 
 const (
 	cliChannelName = "cli"
 	cliChatID      = "local"
 	cliSessionKey  = "local"
+	// cliSessionTitle names CLI provider sessions; --resume looks it up.
+	cliSessionTitle = "miniclaw"
 )
 
+// LocalSessionOptions tunes StartLocalSessionWithOptions.
+type LocalSessionOptions struct {
+	// ObserveEvents logs agent bus events.
+	ObserveEvents bool
+	// Resume continues the most recent stored CLI conversation instead of
+	// starting a new one, when the provider can resume sessions.
+	Resume bool
+	// AgentName is the agents.named profile the session runs as; it keeps
+	// that profile's --resume history apart from the default agent's.
+	AgentName string
+	// Agent selects a provider-side agent (OpenCode), as agents.named[].agent does.
+	Agent string
+	// Middleware wraps every prompt the bus workers run, outermost first.
+	Middleware []Middleware
+}
+
 // LocalSession coordinates a single local CLI session.
 //
 // It owns:
 //   - one agent instance,
 //   - one in-process message bus,
-//   - one bus worker goroutine,
-//   - and (optionally) one heartbeat loop goroutine.
+//   - a pool of bus workers (runtime.workers, default one) keyed by session,
+//   - (optionally) one heartbeat loop goroutine,
+//   - and (optionally, with the heartbeat) one workspace watcher.
 //
 // Prompt requests are routed through the bus so UI code and runtime execution
 // share the same transport semantics.
@@ -37,18 +60,42 @@ type LocalSession struct {
 	runtime    *agent.Instance
 	messageBus *bus.MessageBus
 	log        *slog.Logger
+	// topic is the bus topic of this session's prompts: "cli", or
+	// "cli.<agent>" for a named agent.
+	topic string
 
 	cancelLoop   context.CancelFunc
 	loopErrCh    chan error
 	cancelWorker context.CancelFunc
 
 	requestCounter atomic.Uint64
+	// promptsInFlight and lastPromptDone (unix nanoseconds) tell the
+	// workspace watcher when file changes may be the agent's own.
+	promptsInFlight atomic.Int64
+	lastPromptDone  atomic.Int64
+
+	hooksMu      sync.Mutex
+	requestHooks map[string]requestHooks
+
+	// cancels holds the cancel func of each request a worker is running;
+	// stopped marks requests canceled before a worker picked them up.
+	cancelsMu sync.Mutex
+	cancels   map[string]context.CancelFunc
+	stopped   map[string]bool
+
+	// waiting holds the requests whose callers are waiting for a reply.
+	waitingMu sync.Mutex
+	waiting   map[string]bool
 
-	handlersMu        sync.Mutex
-	toolEventHandlers map[string]providertypes.ToolEventHandler
+	resumed bool
 }
 
 func StartLocalSession(ctx context.Context, cfg *config.Config, log *slog.Logger, client provider.Client, observeEvents bool) (*LocalSession, error) {
+	return StartLocalSessionWithOptions(ctx, cfg, log, client, LocalSessionOptions{ObserveEvents: observeEvents})
+}
+
+// StartLocalSessionWithOptions starts a local session; see LocalSessionOptions.
+func StartLocalSessionWithOptions(ctx context.Context, cfg *config.Config, log *slog.Logger, client provider.Client, opts LocalSessionOptions) (*LocalSession, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -62,41 +109,97 @@ func StartLocalSession(ctx context.Context, cfg *config.Config, log *slog.Logger
 		log = slog.Default()
 	}
 
+	budget, err := NewBudget(cfg.Runtime.Budget, log)
+	if err != nil {
+		return nil, err
+	}
+
 	systemProfile, err := agentprofile.ResolveSystemProfile(cfg.Agents.Defaults.Provider)
 	if err != nil {
 		return nil, fmt.Errorf("resolve agent profile: %w", err)
 	}
 
-	runtime := agent.New(client, cfg.Agents.Defaults.Model, cfg.Heartbeat, "", systemProfile)
-	if err := runtime.StartSession(ctx, "miniclaw"); err != nil {
+	systemProfile = agentprofile.WithInstructions(systemProfile, cfg.Agents.Defaults.Instructions)
+	workspacePrompt, err := agentprofile.WorkspacePrompt(cfg.Agents.Defaults)
+	if err != nil {
+		return nil, fmt.Errorf("load system files: %w", err)
+	}
+	systemProfile = agentprofile.WithInstructions(systemProfile, workspacePrompt)
+
+	runtime := agent.New(client, cfg.Agents.Defaults.Model, cfg.Heartbeat, opts.Agent, systemProfile)
+	title := cliSessionTitle
+	if opts.AgentName != "" {
+		title += "@" + strings.ToLower(opts.AgentName)
+	}
+	resumed := false
+	if opts.Resume {
+		resumed, err = runtime.ResumeSession(ctx, title)
+	} else {
+		err = runtime.StartSession(ctx, title)
+	}
+	if err != nil {
 		return nil, fmt.Errorf("start session: %w", err)
 	}
 
+	messageBus, err := bus.Open(cfg.Bus)
+	if err != nil {
+		return nil, fmt.Errorf("open message bus: %w", err)
+	}
+
 	session := &LocalSession{
-		runtime:           runtime,
-		messageBus:        bus.NewMessageBus(),
-		log:               log,
-		cancelLoop:        func() {},
-		loopErrCh:         make(chan error, 1),
-		cancelWorker:      func() {},
-		toolEventHandlers: make(map[string]providertypes.ToolEventHandler),
+		runtime:      runtime,
+		messageBus:   messageBus,
+		log:          log,
+		topic:        bus.Topic(cliChannelName, strings.ToLower(opts.AgentName)),
+		cancelLoop:   func() {},
+		loopErrCh:    make(chan error, 1),
+		cancelWorker: func() {},
+		requestHooks: make(map[string]requestHooks),
+		cancels:      make(map[string]context.CancelFunc),
+		stopped:      make(map[string]bool),
+		waiting:      make(map[string]bool),
+		resumed:      resumed,
 	}
 
 	workerCtx, cancelWorker := context.WithCancel(ctx)
 	session.cancelWorker = cancelWorker
-	go runAgentBusWorker(workerCtx, runtime, session.messageBus, session.toolEventHandler, session.clearToolEventHandler)
+	// The circuit breaker goes innermost so only provider failures trip it;
+	// the budget sits just outside it so refused prompts never count.
+	breaker := NewCircuitBreaker(cfg.Runtime.CircuitBreaker, log)
+	handler := Chain(func(ctx context.Context, prompt string) (providertypes.PromptResult, error) {
+		return executePrompt(ctx, runtime, prompt)
+	}, slices.Concat(opts.Middleware, []Middleware{budget.Middleware(), breaker.Middleware()})...)
+	go runAgentBusWorker(workerCtx, cfg.Runtime, handler, session.messageBus, log, session.hooksFor, session.startRequest, session.finishRequest)
 
 	if runtime.HeartbeatEnabled() {
+		runtime.OnHeartbeat(func() {
+			_ = messageBus.PublishEvent(workerCtx, bus.Event{
+				Type:       bus.EventHeartbeatTick,
+				Channel:    cliChannelName,
+				ChatID:     cliChatID,
+				SessionKey: cliSessionKey,
+			})
+		})
 		loopCtx, cancelLoop := context.WithCancel(ctx)
 		session.cancelLoop = cancelLoop
 		go func() {
 			session.loopErrCh <- runtime.Run(loopCtx)
 		}()
+		if cfg.Heartbeat.Watch.Enabled {
+			session.startWorkspaceWatch(workerCtx, cfg.Heartbeat.Watch, cfg.Agents.Defaults.Workspace)
+		}
 	}
 
-	if observeEvents {
-		go observeAgentEvents(workerCtx, session.messageBus)
+	if opts.ObserveEvents {
+		observeAgentEvents(workerCtx, session.messageBus)
 	}
+	_ = session.messageBus.PublishEvent(ctx, bus.Event{
+		Type:       bus.EventSessionCreated,
+		Channel:    cliChannelName,
+		ChatID:     cliChatID,
+		SessionKey: cliSessionKey,
+		Payload:    bus.SessionPayload{Resumed: resumed, Agent: strings.ToLower(opts.AgentName)},
+	})
 
 	return session, nil
 }
@@ -109,6 +212,38 @@ func (s *LocalSession) Prompt(ctx context.Context, prompt string) (providertypes
 	return s.executePromptViaBus(ctx, prompt)
 }
 
+// Resumed reports whether the session continued a stored conversation.
+func (s *LocalSession) Resumed() bool {
+	return s != nil && s.resumed
+}
+
+// SessionID returns the provider session the local session prompts.
+func (s *LocalSession) SessionID() string {
+	if s == nil {
+		return ""
+	}
+
+	return s.runtime.SessionID()
+}
+
+// ExportSession copies the session's stored conversation, for /export.
+func (s *LocalSession) ExportSession(ctx context.Context) (transcript.Transcript, error) {
+	if s == nil {
+		return transcript.Transcript{}, errors.New("local session is nil")
+	}
+
+	return s.runtime.ExportSession(ctx)
+}
+
+// RollbackLastTurn removes the session's last prompt and reply, for /undo.
+func (s *LocalSession) RollbackLastTurn(ctx context.Context) error {
+	if s == nil {
+		return errors.New("local session is nil")
+	}
+
+	return s.runtime.RollbackLastTurn(ctx)
+}
+
 // Close shuts down worker and heartbeat resources owned by the session.
 //
 // Shutdown is best-effort and non-blocking for heartbeat completion to avoid
@@ -139,71 +274,129 @@ func executePrompt(ctx context.Context, runtime *agent.Instance, prompt string)
 	return runtime.Prompt(ctx, prompt)
 }
 
-func runAgentBusWorker(ctx context.Context, runtime *agent.Instance, messageBus *bus.MessageBus, toolEventHandler func(requestID string) (providertypes.ToolEventHandler, bool), clearToolEventHandler func(requestID string)) {
-	var sessionUsageIn int64
-	var sessionUsageOut int64
-	var sessionUsageTotal int64
+// requestHooks are caller callbacks carried across the bus for one request.
+//
+// Context values do not survive the hop to a bus worker, so callers register
+// them by request ID and the worker re-attaches them to its prompt context.
+type requestHooks struct {
+	toolEvents providertypes.ToolEventHandler
+	approver   providertypes.ToolApprover
+	textDeltas providertypes.TextDeltaHandler
+}
 
-	for {
-		inbound, ok := messageBus.ConsumeInbound(ctx)
-		if !ok {
-			return
-		}
+// hooksFromContext captures the callbacks a caller attached to ctx.
+func hooksFromContext(ctx context.Context) (requestHooks, bool) {
+	var hooks requestHooks
+	hooks.toolEvents, _ = providertypes.ToolEventHandlerFromContext(ctx)
+	hooks.approver, _ = providertypes.ToolApproverFromContext(ctx)
+	hooks.textDeltas, _ = providertypes.TextDeltaHandlerFromContext(ctx)
 
-		requestID := inbound.Metadata["request_id"]
-		_ = messageBus.PublishEvent(ctx, bus.Event{
-			Type:       bus.EventPromptReceived,
-			Channel:    inbound.Channel,
-			ChatID:     inbound.ChatID,
-			SessionKey: inbound.SessionKey,
-			RequestID:  requestID,
-			Payload: map[string]string{
-				"prompt_length": strconv.Itoa(len(inbound.Content)),
-			},
-		})
+	return hooks, hooks.toolEvents != nil || hooks.approver != nil || hooks.textDeltas != nil
+}
 
-		callCtx := ctx
-		if handler, ok := toolEventHandler(requestID); ok {
-			callCtx = providertypes.WithToolEventHandler(ctx, handler)
-		}
+// apply attaches the callbacks to a worker prompt context.
+func (h requestHooks) apply(ctx context.Context) context.Context {
+	ctx = providertypes.WithToolEventHandler(ctx, h.toolEvents)
+	ctx = providertypes.WithTextDeltaHandler(ctx, h.textDeltas)
+	return providertypes.WithToolApprover(ctx, h.approver)
+}
 
-		result, err := executePrompt(callCtx, runtime, inbound.Content)
-		if requestID != "" {
-			clearToolEventHandler(requestID)
-		}
-		outbound := bus.OutboundMessage{
+// sessionUsage accumulates token and tool usage for one session key.
+type sessionUsage struct {
+	input  int64
+	output int64
+	total  int64
+	tools  providertypes.ToolUsage
+}
+
+// sessionUsageTracker keeps running usage totals shared by all bus workers.
+type sessionUsageTracker struct {
+	mu     sync.Mutex
+	totals map[string]sessionUsage
+}
+
+// add records one prompt's usage and returns the updated session totals.
+func (t *sessionUsageTracker) add(sessionKey string, usage *providertypes.TokenUsage) sessionUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.totals == nil {
+		t.totals = make(map[string]sessionUsage)
+	}
+	totals := t.totals[sessionKey]
+	totals.input += usage.InputTokens
+	totals.output += usage.OutputTokens
+	totals.total += usage.TotalTokens
+	t.totals[sessionKey] = totals
+	return totals
+}
+
+// addTools records one prompt's tool usage and returns the updated session totals.
+func (t *sessionUsageTracker) addTools(sessionKey string, usage providertypes.ToolUsage) providertypes.ToolUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.totals == nil {
+		t.totals = make(map[string]sessionUsage)
+	}
+	totals := t.totals[sessionKey]
+	totals.tools.Add(usage)
+	t.totals[sessionKey] = totals
+	return totals.tools
+}
+
+func runAgentBusWorker(ctx context.Context, cfg config.RuntimeConfig, handler PromptHandler, messageBus *bus.MessageBus, log *slog.Logger, hooksFor func(requestID string) (requestHooks, bool), startRequest func(requestID string, cancel context.CancelFunc), finishRequest func(requestID string)) {
+	usageTracker := &sessionUsageTracker{}
+
+	// reply publishes the outcome of one prompt as events and an outbound message.
+	reply := func(ctx context.Context, inbound bus.InboundMessage, result providertypes.PromptResult, err error) bool {
+		requestID := inbound.Metadata[RequestIDKey]
+		outbound := bus.CarryTrace(inbound, bus.OutboundMessage{
 			Channel:    inbound.Channel,
 			ChatID:     inbound.ChatID,
 			SessionKey: inbound.SessionKey,
 			Content:    result.Text,
 			Metadata:   PromptResultMetadata(result),
+		})
+		if requestID != "" {
+			outbound.Metadata[RequestIDKey] = requestID
 		}
 		if err != nil {
 			outbound.Error = err.Error()
+			if outbound.Metadata == nil {
+				outbound.Metadata = map[string]string{}
+			}
+			for key, value := range PromptErrorMetadata(err) {
+				outbound.Metadata[key] = value
+			}
 			_ = messageBus.PublishEvent(ctx, bus.Event{
 				Type:       bus.EventPromptFailed,
 				Channel:    inbound.Channel,
 				ChatID:     inbound.ChatID,
 				SessionKey: inbound.SessionKey,
 				RequestID:  requestID,
-				Error:      err.Error(),
+				Payload: bus.PromptFailedPayload{
+					Timing:        tracePromptTiming(outbound.Metadata),
+					ErrorCategory: outbound.Metadata[ErrorCategoryKey],
+				},
+				Error: err.Error(),
 			})
 		} else {
-			usagePayload := map[string]string{
-				"response_length": strconv.Itoa(len(result.Text)),
+			completed := bus.PromptCompletedPayload{
+				Timing:         tracePromptTiming(outbound.Metadata),
+				ResponseLength: len(result.Text),
 			}
 			if result.Metadata.Usage != nil {
 				usage := result.Metadata.Usage
-				sessionUsageIn += usage.InputTokens
-				sessionUsageOut += usage.OutputTokens
-				sessionUsageTotal += usage.TotalTokens
-
-				usagePayload[UsageInputTokensKey] = strconv.FormatInt(usage.InputTokens, 10)
-				usagePayload[UsageOutputTokensKey] = strconv.FormatInt(usage.OutputTokens, 10)
-				usagePayload[UsageTotalTokensKey] = strconv.FormatInt(usage.TotalTokens, 10)
-				usagePayload["session_usage_input_tokens"] = strconv.FormatInt(sessionUsageIn, 10)
-				usagePayload["session_usage_output_tokens"] = strconv.FormatInt(sessionUsageOut, 10)
-				usagePayload["session_usage_total_tokens"] = strconv.FormatInt(sessionUsageTotal, 10)
+				totals := usageTracker.add(inbound.SessionKey, usage)
+
+				completed.Usage = &bus.TokenUsage{InputTokens: usage.InputTokens, OutputTokens: usage.OutputTokens, TotalTokens: usage.TotalTokens}
+				completed.SessionUsage = &bus.TokenUsage{InputTokens: totals.input, OutputTokens: totals.output, TotalTokens: totals.total}
+			}
+			if toolUsage := result.Metadata.ToolUsage; toolUsage != nil {
+				totals := usageTracker.addTools(inbound.SessionKey, *toolUsage)
+				completed.Tools = eventToolUsage(*toolUsage)
+				completed.SessionTools = eventToolUsage(totals)
 			}
 			_ = messageBus.PublishEvent(ctx, bus.Event{
 				Type:       bus.EventPromptCompleted,
@@ -211,85 +404,232 @@ func runAgentBusWorker(ctx context.Context, runtime *agent.Instance, messageBus
 				ChatID:     inbound.ChatID,
 				SessionKey: inbound.SessionKey,
 				RequestID:  requestID,
-				Payload:    usagePayload,
+				Payload:    completed,
 			})
 		}
 
-		if ok := messageBus.PublishOutbound(ctx, outbound); !ok {
-			return
+		if !messageBus.PublishOutbound(ctx, outbound) {
+			return false
+		}
+		// The requester has its reply, a failed one included, so a shared
+		// transport must not hand the prompt to another process to run again.
+		if err := messageBus.Ack(ctx, inbound); err != nil {
+			log.Warn("Failed to acknowledge bus message", "request_id", requestID, "error", err)
 		}
+		return true
+	}
+
+	dispatchByKey(ctx, messageBus, cfg, func(ctx context.Context, inbound bus.InboundMessage) bool {
+		requestID := inbound.Metadata[RequestIDKey]
+		_ = messageBus.PublishEvent(ctx, bus.Event{
+			Type:       bus.EventPromptReceived,
+			Channel:    inbound.Channel,
+			ChatID:     inbound.ChatID,
+			SessionKey: inbound.SessionKey,
+			RequestID:  requestID,
+			Payload:    bus.PromptReceivedPayload{PromptLength: len(inbound.Content)},
+		})
+
+		callCtx, cancel := context.WithCancel(ctx)
+		if hooks, ok := hooksFor(requestID); ok {
+			callCtx = hooks.apply(callCtx)
+		}
+		callCtx, restoreToolEvents := WithToolEventBus(callCtx, messageBus, bus.Event{
+			Channel:    inbound.Channel,
+			ChatID:     inbound.ChatID,
+			SessionKey: inbound.SessionKey,
+			RequestID:  requestID,
+		})
+		if requestID != "" {
+			startRequest(requestID, cancel)
+		}
+
+		result, err := handler(callCtx, inbound.Content)
+		restoreToolEvents(&result)
+		cancel()
+		if requestID != "" {
+			finishRequest(requestID)
+		}
+
+		return reply(ctx, inbound, result, err)
+	}, func(ctx context.Context, inbound bus.InboundMessage, err error) bool {
+		if requestID := inbound.Metadata[RequestIDKey]; requestID != "" {
+			finishRequest(requestID)
+		}
+		return reply(ctx, inbound, providertypes.PromptResult{}, err)
+	})
+}
+
+// tracePromptTiming converts bus hop timestamps into the event timing, or
+// nil when the trace is incomplete.
+//
+// Splitting queue wait from processing time makes it obvious whether slow
+// replies come from a backed-up queue or from the provider itself.
+func tracePromptTiming(metadata map[string]string) *bus.PromptTiming {
+	timings, ok := bus.TimingsFromMetadata(metadata)
+	if !ok {
+		return nil
+	}
+
+	return &bus.PromptTiming{
+		QueueWaitMs:  timings.QueueWait.Milliseconds(),
+		ProcessingMs: timings.Processing.Milliseconds(),
 	}
 }
 
 func (s *LocalSession) executePromptViaBus(ctx context.Context, prompt string) (providertypes.PromptResult, error) {
+	startedAt := time.Now()
+	s.promptsInFlight.Add(1)
+	defer func() {
+		s.lastPromptDone.Store(time.Now().UnixNano())
+		s.promptsInFlight.Add(-1)
+	}()
 	requestID := strconv.FormatUint(s.requestCounter.Add(1), 10)
-	if handler, ok := providertypes.ToolEventHandlerFromContext(ctx); ok {
-		s.setToolEventHandler(requestID, handler)
-		defer s.clearToolEventHandler(requestID)
+	if hooks, ok := hooksFromContext(ctx); ok {
+		s.setHooks(requestID, hooks)
+		defer s.clearHooks(requestID)
 	}
 
+	s.setWaiting(requestID, true)
+	defer s.setWaiting(requestID, false)
+
 	inbound := bus.InboundMessage{
+		Topic:      s.topic,
 		Channel:    cliChannelName,
 		ChatID:     cliChatID,
 		SessionKey: cliSessionKey,
 		Content:    prompt,
 		Metadata: map[string]string{
-			"request_id": requestID,
+			RequestIDKey: requestID,
 		},
 	}
 
-	if ok := s.messageBus.PublishInbound(ctx, inbound); !ok {
+	// The bus routes the reply back to this request even when several
+	// workers finish out of order.
+	pending, ok := s.messageBus.Request(ctx, inbound)
+	if !ok {
 		if err := ctx.Err(); err != nil {
 			return providertypes.PromptResult{}, err
 		}
 		return providertypes.PromptResult{}, errors.New("unable to enqueue prompt")
 	}
+	defer pending.Close()
 
-	outbound, ok := s.messageBus.SubscribeOutbound(ctx)
-	if !ok {
-		if err := ctx.Err(); err != nil {
-			return providertypes.PromptResult{}, err
+	outbound, err := pending.Wait(ctx)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			// Stop the provider call too, not just the wait for its reply.
+			s.Cancel(requestID)
+			return providertypes.PromptResult{}, ctxErr
 		}
 		return providertypes.PromptResult{}, errors.New("unable to receive prompt result")
 	}
 
-	if outbound.Error != "" {
-		return providertypes.PromptResult{}, errors.New(outbound.Error)
+	if err := PromptErrorFromOutbound(outbound); err != nil {
+		return providertypes.PromptResult{}, err
+	}
+
+	result := PromptResultFromOutbound(outbound)
+	timing := result.Metadata.EnsureTiming()
+	if hops, ok := bus.TimingsFromMetadata(outbound.Metadata); ok {
+		timing.QueueWait += hops.QueueWait
 	}
+	timing.Total = time.Since(startedAt)
+	LogTurnTiming(s.log, *timing, "request_id", requestID)
+
+	return result, nil
+}
+
+// setWaiting records whether the caller of requestID is waiting for its reply.
+func (s *LocalSession) setWaiting(requestID string, waiting bool) {
+	s.waitingMu.Lock()
+	defer s.waitingMu.Unlock()
 
-	return PromptResultFromOutbound(outbound), nil
+	if waiting {
+		s.waiting[requestID] = true
+	} else {
+		delete(s.waiting, requestID)
+	}
 }
 
-func (s *LocalSession) setToolEventHandler(requestID string, handler providertypes.ToolEventHandler) {
+// Cancel stops the prompt with requestID, whether a worker is running it or it
+// is still queued on the bus, and reports whether the request was in flight.
+// The prompt's caller receives context.Canceled.
+func (s *LocalSession) Cancel(requestID string) bool {
+	if s == nil {
+		return false
+	}
+
+	s.waitingMu.Lock()
+	waiting := s.waiting[requestID]
+	s.waitingMu.Unlock()
+
+	s.cancelsMu.Lock()
+	defer s.cancelsMu.Unlock()
+	if cancel, ok := s.cancels[requestID]; ok {
+		cancel()
+		return true
+	}
+	if waiting {
+		s.stopped[requestID] = true
+	}
+
+	return waiting
+}
+
+// startRequest records the cancel func of a request a worker picked up,
+// canceling it at once when Cancel arrived first.
+func (s *LocalSession) startRequest(requestID string, cancel context.CancelFunc) {
+	s.cancelsMu.Lock()
+	defer s.cancelsMu.Unlock()
+
+	if s.stopped[requestID] {
+		delete(s.stopped, requestID)
+		cancel()
+	}
+	s.cancels[requestID] = cancel
+}
+
+// finishRequest drops the per-request state once a worker is done with it.
+func (s *LocalSession) finishRequest(requestID string) {
+	s.clearHooks(requestID)
+
+	s.cancelsMu.Lock()
+	defer s.cancelsMu.Unlock()
+	delete(s.cancels, requestID)
+	delete(s.stopped, requestID)
+}
+
+func (s *LocalSession) setHooks(requestID string, hooks requestHooks) {
 	if s == nil {
 		return
 	}
 	requestID = strconv.FormatInt(parseRequestID(requestID), 10)
-	if requestID == "0" || handler == nil {
+	if requestID == "0" {
 		return
 	}
 
-	s.handlersMu.Lock()
-	defer s.handlersMu.Unlock()
-	s.toolEventHandlers[requestID] = handler
+	s.hooksMu.Lock()
+	defer s.hooksMu.Unlock()
+	s.requestHooks[requestID] = hooks
 }
 
-func (s *LocalSession) toolEventHandler(requestID string) (providertypes.ToolEventHandler, bool) {
+func (s *LocalSession) hooksFor(requestID string) (requestHooks, bool) {
 	if s == nil {
-		return nil, false
+		return requestHooks{}, false
 	}
 	requestID = strconv.FormatInt(parseRequestID(requestID), 10)
 	if requestID == "0" {
-		return nil, false
+		return requestHooks{}, false
 	}
 
-	s.handlersMu.Lock()
-	defer s.handlersMu.Unlock()
-	handler, ok := s.toolEventHandlers[requestID]
-	return handler, ok
+	s.hooksMu.Lock()
+	defer s.hooksMu.Unlock()
+	hooks, ok := s.requestHooks[requestID]
+	return hooks, ok
 }
 
-func (s *LocalSession) clearToolEventHandler(requestID string) {
+func (s *LocalSession) clearHooks(requestID string) {
 	if s == nil {
 		return
 	}
@@ -298,9 +638,9 @@ func (s *LocalSession) clearToolEventHandler(requestID string) {
 		return
 	}
 
-	s.handlersMu.Lock()
-	defer s.handlersMu.Unlock()
-	delete(s.toolEventHandlers, requestID)
+	s.hooksMu.Lock()
+	defer s.hooksMu.Unlock()
+	delete(s.requestHooks, requestID)
 }
 
 func parseRequestID(value string) int64 {
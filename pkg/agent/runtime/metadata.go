@@ -0,0 +1,206 @@
+package runtime
+
+import (
+	"strconv"
+	"strings"
+
+	"miniclaw/pkg/bus"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+// Outbound metadata schema.
+//
+// Every outbound reply built by this package carries SchemaVersionKey. Adding
+// a key is backwards compatible and keeps the version; renaming or removing
+// one bumps MetadataSchemaVersion and records the old name in renamedKeys so
+// OutboundMetadata keeps reading replies written by older versions.
+const (
+	// SchemaVersionKey holds the schema revision of an outbound metadata map.
+	SchemaVersionKey = "schema_version"
+	// MetadataSchemaVersion is the revision this package writes.
+	MetadataSchemaVersion = 1
+)
+
+// Outbound metadata keys, schema version 1.
+const (
+	// RequestIDKey correlates a reply with the local caller waiting on it.
+	RequestIDKey = "request_id"
+	// AgentKey names the gateway agent that handled the message.
+	AgentKey = "agent"
+	// TraceIDKey carries the trace ID a channel assigned to the inbound
+	// message; the reply repeats it.
+	TraceIDKey = "trace_id"
+
+	UsageInputTokensKey       = "usage_input_tokens"
+	UsageOutputTokensKey      = "usage_output_tokens"
+	UsageTotalTokensKey       = "usage_total_tokens"
+	UsageReasoningTokensKey   = "usage_reasoning_tokens"
+	UsageCacheCreateTokensKey = "usage_cache_creation_tokens"
+	UsageCacheReadTokensKey   = "usage_cache_read_tokens"
+	// ToolEventsJSONKey holds a JSON array of providertypes.ToolEvent.
+	ToolEventsJSONKey    = "tool_events_json"
+	ErrorCategoryKey     = "error_category"
+	ErrorProviderKey     = "error_provider"
+	ErrorStatusCodeKey   = "error_status_code"
+	ErrorRetryAfterMsKey = "error_retry_after_ms"
+	TimingQueueWaitMsKey = "timing_queue_wait_ms"
+	TimingProviderMsKey  = "timing_provider_ms"
+	TimingToolsMsKey     = "timing_tools_ms"
+	TimingTotalMsKey     = "timing_total_ms"
+	ToolCallsKey         = "tool_calls"
+	ToolQuotaDeniedKey   = "tool_quota_denied"
+	ToolBytesReadKey     = "tool_bytes_read"
+	ToolBytesWrittenKey  = "tool_bytes_written"
+	ToolDurationMsKey    = "tool_duration_ms"
+	// Context window fill after the turn; see providertypes.ContextUsage.
+	ContextUsedTokensKey   = "context_used_tokens"
+	ContextWindowTokensKey = "context_window_tokens"
+	ContextEstimatedKey    = "context_estimated"
+	ContextNearlyFullKey   = "context_nearly_full"
+)
+
+// renamedKeys maps a current key to the names earlier schema versions used for it.
+var renamedKeys = map[string][]string{}
+
+// OutboundMetadata is a typed, version-aware view of bus.OutboundMessage metadata.
+//
+// Accessors resolve renamed keys, so channel adapters and external consumers
+// should read through them instead of indexing the map directly.
+type OutboundMetadata struct {
+	values map[string]string
+}
+
+// ReadMetadata wraps the metadata of outbound.
+func ReadMetadata(outbound bus.OutboundMessage) OutboundMetadata {
+	return OutboundMetadata{values: outbound.Metadata}
+}
+
+// StampSchemaVersion records MetadataSchemaVersion in metadata, allocating it when nil.
+func StampSchemaVersion(metadata map[string]string) map[string]string {
+	if metadata == nil {
+		metadata = make(map[string]string, 1)
+	}
+	metadata[SchemaVersionKey] = strconv.Itoa(MetadataSchemaVersion)
+
+	return metadata
+}
+
+// SchemaVersion returns the schema revision the metadata was written with, or
+// 0 for metadata written before versioning.
+func (m OutboundMetadata) SchemaVersion() int {
+	return int(parseInt64(m.values[SchemaVersionKey]))
+}
+
+// Get returns the value for key, falling back to the key's earlier names.
+func (m OutboundMetadata) Get(key string) (string, bool) {
+	if value, ok := m.values[key]; ok {
+		return value, true
+	}
+	for _, old := range renamedKeys[key] {
+		if value, ok := m.values[old]; ok {
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+// String returns the value for key, or "" when it is absent.
+func (m OutboundMetadata) String(key string) string {
+	value, _ := m.Get(key)
+	return value
+}
+
+// RequestID returns the local request ID, if any.
+func (m OutboundMetadata) RequestID() string {
+	return m.String(RequestIDKey)
+}
+
+// TraceID returns the trace ID of the request the message answers, if any.
+func (m OutboundMetadata) TraceID() string {
+	return m.String(TraceIDKey)
+}
+
+// Agent returns the gateway agent that handled the message, if any.
+func (m OutboundMetadata) Agent() string {
+	return m.String(AgentKey)
+}
+
+// Usage returns token usage, or nil when none was reported.
+func (m OutboundMetadata) Usage() *providertypes.TokenUsage {
+	usage := &providertypes.TokenUsage{
+		InputTokens:         m.int64(UsageInputTokensKey),
+		OutputTokens:        m.int64(UsageOutputTokensKey),
+		TotalTokens:         m.int64(UsageTotalTokensKey),
+		ReasoningTokens:     m.int64(UsageReasoningTokensKey),
+		CacheCreationTokens: m.int64(UsageCacheCreateTokensKey),
+		CacheReadTokens:     m.int64(UsageCacheReadTokensKey),
+	}
+	if usage.IsZero() {
+		return nil
+	}
+
+	return usage
+}
+
+// ToolEvents returns the tool events of the turn, or nil when none were recorded.
+func (m OutboundMetadata) ToolEvents() []providertypes.ToolEvent {
+	return parseToolEvents(m.String(ToolEventsJSONKey))
+}
+
+// Timing returns the turn timing breakdown, or nil when it was not recorded.
+func (m OutboundMetadata) Timing() *providertypes.TurnTiming {
+	if _, ok := m.Get(TimingTotalMsKey); !ok {
+		return nil
+	}
+
+	return &providertypes.TurnTiming{
+		QueueWait: parseMillis(m.String(TimingQueueWaitMsKey)),
+		Provider:  parseMillis(m.String(TimingProviderMsKey)),
+		Tools:     parseMillis(m.String(TimingToolsMsKey)),
+		Total:     parseMillis(m.String(TimingTotalMsKey)),
+	}
+}
+
+// ToolUsage returns the turn's tool usage, or nil when no tools were metered.
+func (m OutboundMetadata) ToolUsage() *providertypes.ToolUsage {
+	if _, ok := m.Get(ToolCallsKey); !ok {
+		return nil
+	}
+
+	return &providertypes.ToolUsage{
+		Calls:        m.int64(ToolCallsKey),
+		QuotaDenied:  m.int64(ToolQuotaDeniedKey),
+		BytesRead:    m.int64(ToolBytesReadKey),
+		BytesWritten: m.int64(ToolBytesWrittenKey),
+		Duration:     parseMillis(m.String(ToolDurationMsKey)),
+	}
+}
+
+// Context returns how full the context window was after the turn, or nil when it was not reported.
+func (m OutboundMetadata) Context() *providertypes.ContextUsage {
+	if _, ok := m.Get(ContextWindowTokensKey); !ok {
+		return nil
+	}
+
+	return &providertypes.ContextUsage{
+		UsedTokens:   m.int64(ContextUsedTokensKey),
+		WindowTokens: m.int64(ContextWindowTokensKey),
+		Estimated:    m.bool(ContextEstimatedKey),
+		NearlyFull:   m.bool(ContextNearlyFullKey),
+	}
+}
+
+// ErrorCategory returns the recorded provider error category, or "" when none was recorded.
+func (m OutboundMetadata) ErrorCategory() providertypes.ErrorCategory {
+	return providertypes.ErrorCategory(strings.TrimSpace(m.String(ErrorCategoryKey)))
+}
+
+func (m OutboundMetadata) int64(key string) int64 {
+	return parseInt64(m.String(key))
+}
+
+func (m OutboundMetadata) bool(key string) bool {
+	value, _ := strconv.ParseBool(strings.TrimSpace(m.String(key)))
+	return value
+}
@@ -0,0 +1,60 @@
+package runtime
+
+import (
+	"context"
+
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+// PromptHandler runs one prompt against an agent and returns its result.
+type PromptHandler func(ctx context.Context, prompt string) (providertypes.PromptResult, error)
+
+// Middleware wraps a PromptHandler with behavior that runs around every
+// prompt, such as redaction, guardrails, templating, or logging. It may
+// rewrite the prompt, return early without calling next, or change the
+// result next returns.
+type Middleware func(next PromptHandler) PromptHandler
+
+// Chain wraps handler with middleware. The first middleware is the outermost:
+// it sees the prompt first and the result last. Nil entries are skipped.
+func Chain(handler PromptHandler, middleware ...Middleware) PromptHandler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		if middleware[i] != nil {
+			handler = middleware[i](handler)
+		}
+	}
+
+	return handler
+}
+
+// PrePrompt returns middleware that passes each prompt through rewrite before
+// the agent sees it. An error from rewrite fails the prompt without calling
+// the agent.
+func PrePrompt(rewrite func(ctx context.Context, prompt string) (string, error)) Middleware {
+	return func(next PromptHandler) PromptHandler {
+		return func(ctx context.Context, prompt string) (providertypes.PromptResult, error) {
+			prompt, err := rewrite(ctx, prompt)
+			if err != nil {
+				return providertypes.PromptResult{}, err
+			}
+
+			return next(ctx, prompt)
+		}
+	}
+}
+
+// PostResponse returns middleware that passes each successful result through
+// rewrite before it reaches the caller, along with the prompt as this
+// middleware passed it on. Failed prompts are returned unchanged.
+func PostResponse(rewrite func(ctx context.Context, prompt string, result providertypes.PromptResult) (providertypes.PromptResult, error)) Middleware {
+	return func(next PromptHandler) PromptHandler {
+		return func(ctx context.Context, prompt string) (providertypes.PromptResult, error) {
+			result, err := next(ctx, prompt)
+			if err != nil {
+				return result, err
+			}
+
+			return rewrite(ctx, prompt, result)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+// ErrQueueFull is wrapped by the error a PromptQueue returns when the session
+// already has runtime.max_queued_per_session prompts waiting.
+var ErrQueueFull = errors.New("session prompt queue is full")
+
+// PromptQueue admits the prompts of one session: up to SessionConcurrency run
+// at once and the rest wait in arrival order, up to MaxQueuedPerSession of
+// them. The zero value runs one prompt at a time with no waiting limit.
+type PromptQueue struct {
+	concurrency int
+	maxQueued   int
+
+	mu      sync.Mutex
+	running int
+	waiting []chan struct{}
+}
+
+// NewPromptQueue returns a queue applying the session limits of cfg.
+func NewPromptQueue(cfg config.RuntimeConfig) *PromptQueue {
+	return &PromptQueue{concurrency: cfg.SessionConcurrency, maxQueued: cfg.MaxQueuedPerSession}
+}
+
+// SetLimits applies new session limits to a live queue. Raising the
+// concurrency starts waiting prompts at once; lowering a limit never cancels
+// prompts already admitted.
+func (q *PromptQueue) SetLimits(cfg config.RuntimeConfig) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.concurrency = cfg.SessionConcurrency
+	q.maxQueued = cfg.MaxQueuedPerSession
+	for len(q.waiting) > 0 && q.running < max(q.concurrency, 1) {
+		close(q.waiting[0])
+		q.waiting = q.waiting[1:]
+		q.running++
+	}
+}
+
+// PromptTicket is one admitted prompt's place in a PromptQueue.
+type PromptTicket struct {
+	queue *PromptQueue
+	ready chan struct{}
+}
+
+// Enqueue takes a place for one prompt without blocking. It fails with a
+// queue_full PromptError wrapping ErrQueueFull when the waiting limit is
+// reached. Callers Wait on the ticket and call Done once the prompt ends.
+func (q *PromptQueue) Enqueue() (*PromptTicket, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	ready := make(chan struct{})
+	if q.running < max(q.concurrency, 1) && len(q.waiting) == 0 {
+		q.running++
+		close(ready)
+		return &PromptTicket{queue: q, ready: ready}, nil
+	}
+	if q.maxQueued > 0 && len(q.waiting) >= q.maxQueued {
+		return nil, &providertypes.PromptError{
+			Category: providertypes.ErrorQueueFull,
+			Err:      fmt.Errorf("%w: %d prompts already waiting", ErrQueueFull, len(q.waiting)),
+		}
+	}
+	q.waiting = append(q.waiting, ready)
+
+	return &PromptTicket{queue: q, ready: ready}, nil
+}
+
+// Acquire enqueues a prompt and waits for its turn. The returned release must
+// be called once the prompt ends.
+func (q *PromptQueue) Acquire(ctx context.Context) (func(), error) {
+	ticket, err := q.Enqueue()
+	if err != nil {
+		return nil, err
+	}
+	if err := ticket.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	return ticket.Done, nil
+}
+
+// Wait blocks until the prompt may run. When ctx ends first the ticket leaves
+// the queue and Done must not be called.
+func (t *PromptTicket) Wait(ctx context.Context) error {
+	select {
+	case <-t.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	q := t.queue
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if index := slices.Index(q.waiting, t.ready); index >= 0 {
+		q.waiting = slices.Delete(q.waiting, index, index+1)
+	} else {
+		// Promoted just as ctx ended; pass the slot on.
+		q.releaseLocked()
+	}
+
+	return ctx.Err()
+}
+
+// Done frees the ticket's slot for the next waiting prompt.
+func (t *PromptTicket) Done() {
+	t.queue.mu.Lock()
+	defer t.queue.mu.Unlock()
+	t.queue.releaseLocked()
+}
+
+// idle reports whether no prompt is running or waiting.
+func (q *PromptQueue) idle() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.running == 0 && len(q.waiting) == 0
+}
+
+func (q *PromptQueue) releaseLocked() {
+	q.running--
+	if len(q.waiting) > 0 {
+		close(q.waiting[0])
+		q.waiting = q.waiting[1:]
+		q.running++
+	}
+}
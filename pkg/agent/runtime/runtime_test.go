@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -251,3 +253,705 @@ func (h *recordingHandler) LastLevel() slog.Level {
 	}
 	return h.records[len(h.records)-1].Level
 }
+
+type echoProviderClient struct{}
+
+func (echoProviderClient) Health(ctx context.Context) error {
+	return nil
+}
+
+func (echoProviderClient) CreateSession(ctx context.Context, title string) (string, error) {
+	return "session-echo", nil
+}
+
+func (echoProviderClient) Prompt(ctx context.Context, sessionID string, prompt string, model string, agentName string, systemPrompt string) (providertypes.PromptResult, error) {
+	return providertypes.PromptResult{Text: "echo: " + prompt}, nil
+}
+
+func TestDispatchByKeyPreservesPerSessionOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messageBus := bus.NewMessageBus()
+	defer messageBus.Close()
+
+	const perSession = 20
+	sessions := []string{"alpha", "beta", "gamma"}
+
+	var mu sync.Mutex
+	seen := map[string][]string{}
+	done := make(chan struct{})
+	handled := 0
+	go dispatchByKey(ctx, messageBus, config.RuntimeConfig{Workers: 3}, func(ctx context.Context, inbound bus.InboundMessage) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		seen[inbound.SessionKey] = append(seen[inbound.SessionKey], inbound.Content)
+		handled++
+		if handled == perSession*len(sessions) {
+			close(done)
+		}
+		return true
+	}, func(context.Context, bus.InboundMessage, error) bool {
+		t.Error("unexpected rejection without a queue limit")
+		return true
+	})
+
+	for index := range perSession {
+		for _, session := range sessions {
+			messageBus.PublishInbound(ctx, bus.InboundMessage{SessionKey: session, Content: strconv.Itoa(index)})
+		}
+	}
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for dispatched messages")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, session := range sessions {
+		got := seen[session]
+		if len(got) != perSession {
+			t.Fatalf("session %s handled %d messages, want %d", session, len(got), perSession)
+		}
+		for index, content := range got {
+			if content != strconv.Itoa(index) {
+				t.Fatalf("session %s message %d = %q, want %q", session, index, content, strconv.Itoa(index))
+			}
+		}
+	}
+}
+
+func TestResolveWorkerCountDefaultsToOne(t *testing.T) {
+	for configured, want := range map[int]int{-1: 1, 0: 1, 1: 1, 4: 4} {
+		if got := resolveWorkerCount(configured); got != want {
+			t.Fatalf("resolveWorkerCount(%d) = %d, want %d", configured, got, want)
+		}
+	}
+}
+
+func TestLocalSessionConcurrentPromptsReceiveOwnReplies(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Agents.Defaults.Provider = "openai"
+	cfg.Runtime.Workers = 4
+
+	session, err := StartLocalSession(context.Background(), cfg, slog.Default(), echoProviderClient{}, false)
+	if err != nil {
+		t.Fatalf("StartLocalSession error: %v", err)
+	}
+	defer session.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for index := range 10 {
+		wg.Go(func() {
+			prompt := "prompt-" + strconv.Itoa(index)
+			result, err := session.Prompt(context.Background(), prompt)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if result.Text != "echo: "+prompt {
+				errs <- errors.New("got reply " + result.Text + " for " + prompt)
+			}
+		})
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
+
+func TestPromptErrorMetadataRoundTrip(t *testing.T) {
+	original := &providertypes.PromptError{
+		Category:   providertypes.ErrorRateLimit,
+		Provider:   "openai",
+		StatusCode: 429,
+		RetryAfter: 3 * time.Second,
+		Err:        errors.New("prompt failed: 429 Too Many Requests"),
+	}
+
+	metadata := PromptErrorMetadata(original)
+	metadata[RequestIDKey] = "7"
+	err := PromptErrorFromOutbound(bus.OutboundMessage{
+		Error:    original.Error(),
+		Metadata: metadata,
+	})
+
+	var promptErr *providertypes.PromptError
+	if !errors.As(err, &promptErr) {
+		t.Fatalf("expected PromptError, got %v", err)
+	}
+	if promptErr.Category != original.Category || promptErr.Provider != original.Provider || promptErr.StatusCode != 429 || promptErr.RetryAfter != 3*time.Second {
+		t.Fatalf("round trip = %+v, want %+v", promptErr, original)
+	}
+	if promptErr.Error() != original.Error() {
+		t.Fatalf("message = %q, want %q", promptErr.Error(), original.Error())
+	}
+	if promptErr.RequestID != "7" {
+		t.Fatalf("request id = %q, want 7", promptErr.RequestID)
+	}
+}
+
+// approvingProviderClient asks the context-carried approver before replying, like a guarded tool would.
+type approvingProviderClient struct {
+	echoProviderClient
+}
+
+func (approvingProviderClient) Prompt(ctx context.Context, sessionID string, prompt string, model string, agentName string, systemPrompt string) (providertypes.PromptResult, error) {
+	approver, ok := providertypes.ToolApproverFromContext(ctx)
+	if !ok {
+		return providertypes.PromptResult{Text: "no approver"}, nil
+	}
+	approved, err := approver(ctx, providertypes.ToolApprovalRequest{Tool: "write_file", Input: prompt})
+	if err != nil {
+		return providertypes.PromptResult{}, err
+	}
+
+	return providertypes.PromptResult{Text: "approved=" + strconv.FormatBool(approved)}, nil
+}
+
+func TestLocalSessionCarriesToolApproverAcrossBus(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Agents.Defaults.Provider = "openai"
+
+	session, err := StartLocalSession(context.Background(), cfg, slog.Default(), approvingProviderClient{}, false)
+	if err != nil {
+		t.Fatalf("StartLocalSession error: %v", err)
+	}
+	defer session.Close()
+
+	var asked providertypes.ToolApprovalRequest
+	ctx := providertypes.WithToolApprover(context.Background(), func(_ context.Context, request providertypes.ToolApprovalRequest) (bool, error) {
+		asked = request
+		return true, nil
+	})
+	result, err := session.Prompt(ctx, "notes.md")
+	if err != nil {
+		t.Fatalf("Prompt error: %v", err)
+	}
+	if result.Text != "approved=true" || asked.Tool != "write_file" || asked.Input != "notes.md" {
+		t.Fatalf("result = %q, asked = %+v", result.Text, asked)
+	}
+
+	result, err = session.Prompt(context.Background(), "other.md")
+	if err != nil {
+		t.Fatalf("Prompt error: %v", err)
+	}
+	if result.Text != "no approver" {
+		t.Fatalf("result = %q, want no approver without a caller approver", result.Text)
+	}
+}
+
+// toolProviderClient reports one failed tool call per prompt through the context handler.
+type toolProviderClient struct {
+	echoProviderClient
+}
+
+func (toolProviderClient) Prompt(ctx context.Context, sessionID string, prompt string, model string, agentName string, systemPrompt string) (providertypes.PromptResult, error) {
+	providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "call", Tool: "read_file", Payload: prompt})
+	providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "read_file", Payload: "no such file", Failed: true, DurationMs: 3})
+	return providertypes.PromptResult{Text: "done"}, nil
+}
+
+func TestLocalSessionPublishesToolEvents(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Agents.Defaults.Provider = "openai"
+
+	session, err := StartLocalSession(context.Background(), cfg, slog.Default(), toolProviderClient{}, false)
+	if err != nil {
+		t.Fatalf("StartLocalSession error: %v", err)
+	}
+	defer session.Close()
+
+	events, unsubscribe := session.messageBus.SubscribeEvents(context.Background(), 16)
+	defer unsubscribe()
+
+	result, err := session.Prompt(context.Background(), "notes.md")
+	if err != nil {
+		t.Fatalf("Prompt error: %v", err)
+	}
+	// Without a caller handler the events still reach the result metadata.
+	if len(result.Metadata.ToolEvents) != 2 {
+		t.Fatalf("tool events in result = %+v, want 2", result.Metadata.ToolEvents)
+	}
+
+	var toolEvents []bus.Event
+	for len(toolEvents) < 2 {
+		select {
+		case event := <-events:
+			if event.Type == bus.EventToolStarted || event.Type == bus.EventToolFailed {
+				toolEvents = append(toolEvents, event)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("tool events = %+v, want started and failed", toolEvents)
+		}
+	}
+	started, failed := toolEvents[0], toolEvents[1]
+	if started.Type != bus.EventToolStarted || started.Payload != (bus.ToolEventPayload{Tool: "read_file"}) || started.RequestID == "" {
+		t.Fatalf("started = %+v", started)
+	}
+	if failed.Type != bus.EventToolFailed || failed.Error != "no such file" || failed.Payload != (bus.ToolEventPayload{Tool: "read_file", DurationMs: 3}) {
+		t.Fatalf("failed = %+v", failed)
+	}
+}
+
+// blockingProviderClient holds each prompt until its context ends and reports that on stopped.
+type blockingProviderClient struct {
+	echoProviderClient
+	started chan struct{}
+	stopped chan error
+}
+
+func (c blockingProviderClient) Prompt(ctx context.Context, sessionID string, prompt string, model string, agentName string, systemPrompt string) (providertypes.PromptResult, error) {
+	c.started <- struct{}{}
+	<-ctx.Done()
+	c.stopped <- ctx.Err()
+
+	return providertypes.PromptResult{}, ctx.Err()
+}
+
+func TestLocalSessionCancelStopsProviderCall(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Agents.Defaults.Provider = "openai"
+
+	client := blockingProviderClient{started: make(chan struct{}, 1), stopped: make(chan error, 1)}
+	session, err := StartLocalSession(context.Background(), cfg, slog.Default(), client, false)
+	if err != nil {
+		t.Fatalf("StartLocalSession error: %v", err)
+	}
+	defer session.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := session.Prompt(ctx, "long task")
+		errCh <- err
+	}()
+	<-client.started
+	cancel()
+
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Prompt error = %v, want context.Canceled", err)
+	}
+	select {
+	case err := <-client.stopped:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("provider context error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("provider call was not canceled")
+	}
+}
+
+func TestPromptResultTimingRoundTrip(t *testing.T) {
+	timing := &providertypes.TurnTiming{
+		QueueWait: 5 * time.Millisecond,
+		Provider:  1200 * time.Millisecond,
+		Tools:     300 * time.Millisecond,
+		Total:     1505 * time.Millisecond,
+	}
+	metadata := PromptResultMetadata(providertypes.PromptResult{Text: "answer", Metadata: providertypes.PromptMetadata{Timing: timing}})
+	if metadata[TimingProviderMsKey] != "1200" || metadata[TimingToolsMsKey] != "300" {
+		t.Fatalf("metadata = %v, want timing keys", metadata)
+	}
+
+	result := PromptResultFromOutbound(bus.OutboundMessage{Content: "answer", Metadata: metadata})
+	if result.Metadata.Timing == nil || *result.Metadata.Timing != *timing {
+		t.Fatalf("timing = %+v, want %+v", result.Metadata.Timing, timing)
+	}
+}
+
+func TestPromptResultToolUsageRoundTrip(t *testing.T) {
+	usage := &providertypes.ToolUsage{Calls: 3, QuotaDenied: 1, BytesRead: 2048, BytesWritten: 12, Duration: 40 * time.Millisecond}
+	metadata := PromptResultMetadata(providertypes.PromptResult{Text: "answer", Metadata: providertypes.PromptMetadata{ToolUsage: usage}})
+	if metadata[ToolCallsKey] != "3" || metadata[ToolBytesReadKey] != "2048" {
+		t.Fatalf("metadata = %v, want tool usage keys", metadata)
+	}
+
+	result := PromptResultFromOutbound(bus.OutboundMessage{Content: "answer", Metadata: metadata})
+	if result.Metadata.ToolUsage == nil || *result.Metadata.ToolUsage != *usage {
+		t.Fatalf("tool usage = %+v, want %+v", result.Metadata.ToolUsage, usage)
+	}
+}
+
+func TestPromptResultContextRoundTrip(t *testing.T) {
+	context := &providertypes.ContextUsage{UsedTokens: 170_000, WindowTokens: 200_000, Estimated: true, NearlyFull: true}
+	metadata := PromptResultMetadata(providertypes.PromptResult{Text: "answer", Metadata: providertypes.PromptMetadata{Context: context}})
+	if metadata[ContextWindowTokensKey] != "200000" || metadata[ContextNearlyFullKey] != "true" {
+		t.Fatalf("metadata = %v, want context keys", metadata)
+	}
+
+	result := PromptResultFromOutbound(bus.OutboundMessage{Content: "answer", Metadata: metadata})
+	if result.Metadata.Context == nil || *result.Metadata.Context != *context {
+		t.Fatalf("context = %+v, want %+v", result.Metadata.Context, context)
+	}
+}
+
+func TestOutboundMetadataIsVersioned(t *testing.T) {
+	for name, metadata := range map[string]map[string]string{
+		"result": PromptResultMetadata(providertypes.PromptResult{Text: "answer"}),
+		"error":  PromptErrorMetadata(errors.New("boom")),
+	} {
+		if got := ReadMetadata(bus.OutboundMessage{Metadata: metadata}).SchemaVersion(); got != MetadataSchemaVersion {
+			t.Fatalf("%s schema version = %d, want %d", name, got, MetadataSchemaVersion)
+		}
+	}
+
+	legacy := ReadMetadata(bus.OutboundMessage{Metadata: map[string]string{UsageTotalTokensKey: "7"}})
+	if legacy.SchemaVersion() != 0 || legacy.Usage() == nil || legacy.Usage().TotalTokens != 7 {
+		t.Fatalf("unversioned metadata = version %d usage %+v, want version 0 with usage", legacy.SchemaVersion(), legacy.Usage())
+	}
+}
+
+func TestOutboundMetadataReadsRenamedKeys(t *testing.T) {
+	renamedKeys[UsageInputTokensKey] = []string{"input_tokens"}
+	defer delete(renamedKeys, UsageInputTokensKey)
+
+	metadata := ReadMetadata(bus.OutboundMessage{Metadata: map[string]string{"input_tokens": "12"}})
+	if usage := metadata.Usage(); usage == nil || usage.InputTokens != 12 {
+		t.Fatalf("usage = %+v, want input tokens read from the old key", usage)
+	}
+
+	metadata = ReadMetadata(bus.OutboundMessage{Metadata: map[string]string{"input_tokens": "12", UsageInputTokensKey: "13"}})
+	if got := metadata.String(UsageInputTokensKey); got != "13" {
+		t.Fatalf("input tokens = %q, want the current key to win", got)
+	}
+}
+
+func TestLocalSessionReportsTurnTiming(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Agents.Defaults.Provider = "openai"
+
+	session, err := StartLocalSession(context.Background(), cfg, slog.Default(), echoProviderClient{}, false)
+	if err != nil {
+		t.Fatalf("StartLocalSession error: %v", err)
+	}
+	defer session.Close()
+
+	result, err := session.Prompt(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Prompt error: %v", err)
+	}
+	timing := result.Metadata.Timing
+	if timing == nil {
+		t.Fatal("expected timing metadata on the result")
+	}
+	if timing.Total <= 0 || timing.Total < timing.Provider+timing.QueueWait {
+		t.Fatalf("timing = %+v, want total covering queue wait and provider time", timing)
+	}
+}
+
+func TestChainRunsMiddlewareOutermostFirst(t *testing.T) {
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next PromptHandler) PromptHandler {
+			return func(ctx context.Context, prompt string) (providertypes.PromptResult, error) {
+				order = append(order, name+">")
+				result, err := next(ctx, prompt)
+				order = append(order, "<"+name)
+				return result, err
+			}
+		}
+	}
+	handler := Chain(func(ctx context.Context, prompt string) (providertypes.PromptResult, error) {
+		order = append(order, "agent")
+		return providertypes.PromptResult{Text: prompt}, nil
+	}, trace("a"), nil, trace("b"))
+
+	if _, err := handler(context.Background(), "ping"); err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	if got, want := strings.Join(order, " "), "a> b> agent <b <a"; got != want {
+		t.Fatalf("order = %q, want %q", got, want)
+	}
+}
+
+func TestPrePromptErrorSkipsAgent(t *testing.T) {
+	blocked := errors.New("prompt blocked")
+	called := false
+	handler := Chain(func(ctx context.Context, prompt string) (providertypes.PromptResult, error) {
+		called = true
+		return providertypes.PromptResult{}, nil
+	}, PrePrompt(func(ctx context.Context, prompt string) (string, error) {
+		return "", blocked
+	}))
+
+	if _, err := handler(context.Background(), "ping"); !errors.Is(err, blocked) {
+		t.Fatalf("handler error = %v, want %v", err, blocked)
+	}
+	if called {
+		t.Fatal("expected the agent not to run after a pre-prompt error")
+	}
+}
+
+func TestLocalSessionAppliesMiddleware(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Agents.Defaults.Provider = "openai"
+
+	session, err := StartLocalSessionWithOptions(context.Background(), cfg, slog.Default(), echoProviderClient{}, LocalSessionOptions{
+		Middleware: []Middleware{
+			PrePrompt(func(ctx context.Context, prompt string) (string, error) {
+				return strings.ReplaceAll(prompt, "secret", "[redacted]"), nil
+			}),
+			PostResponse(func(ctx context.Context, prompt string, result providertypes.PromptResult) (providertypes.PromptResult, error) {
+				result.Text = strings.ToUpper(result.Text)
+				return result, nil
+			}),
+		},
+	})
+	if err != nil {
+		t.Fatalf("StartLocalSessionWithOptions error: %v", err)
+	}
+	defer session.Close()
+
+	result, err := session.Prompt(context.Background(), "my secret")
+	if err != nil {
+		t.Fatalf("Prompt error: %v", err)
+	}
+	if result.Text != "ECHO: MY [REDACTED]" {
+		t.Fatalf("result text = %q, want %q", result.Text, "ECHO: MY [REDACTED]")
+	}
+}
+
+func TestPromptQueueAdmitsInOrderAndRejectsWhenFull(t *testing.T) {
+	queue := NewPromptQueue(config.RuntimeConfig{MaxQueuedPerSession: 1})
+
+	first, err := queue.Enqueue()
+	if err != nil {
+		t.Fatalf("first Enqueue error: %v", err)
+	}
+	if err := first.Wait(context.Background()); err != nil {
+		t.Fatalf("first Wait error: %v", err)
+	}
+	second, err := queue.Enqueue()
+	if err != nil {
+		t.Fatalf("second Enqueue error: %v", err)
+	}
+	if _, err := queue.Enqueue(); !errors.Is(err, ErrQueueFull) || providertypes.ErrorCategoryOf(err) != providertypes.ErrorQueueFull {
+		t.Fatalf("third Enqueue error = %v, want a queue_full error", err)
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := second.Wait(canceled); !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled Wait error = %v, want context.Canceled", err)
+	}
+
+	// The canceled ticket left the queue, so a new prompt may wait in its place.
+	third, err := queue.Enqueue()
+	if err != nil {
+		t.Fatalf("Enqueue after cancel error: %v", err)
+	}
+	first.Done()
+	ctx, stop := context.WithTimeout(context.Background(), 2*time.Second)
+	defer stop()
+	if err := third.Wait(ctx); err != nil {
+		t.Fatalf("Wait after Done error: %v", err)
+	}
+	third.Done()
+	if !queue.idle() {
+		t.Fatal("queue should be idle once every ticket is done")
+	}
+}
+
+func TestPromptQueueSetLimitsAdmitsWaitingPrompts(t *testing.T) {
+	queue := NewPromptQueue(config.RuntimeConfig{})
+
+	first, err := queue.Enqueue()
+	if err != nil {
+		t.Fatalf("first Enqueue error: %v", err)
+	}
+	second, err := queue.Enqueue()
+	if err != nil {
+		t.Fatalf("second Enqueue error: %v", err)
+	}
+
+	queue.SetLimits(config.RuntimeConfig{SessionConcurrency: 2, MaxQueuedPerSession: 1})
+	ctx, stop := context.WithTimeout(context.Background(), 2*time.Second)
+	defer stop()
+	if err := second.Wait(ctx); err != nil {
+		t.Fatalf("Wait after raising the concurrency error: %v", err)
+	}
+	if _, err := queue.Enqueue(); err != nil {
+		t.Fatalf("third Enqueue error: %v", err)
+	}
+	if _, err := queue.Enqueue(); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("fourth Enqueue error = %v, want the new waiting limit", err)
+	}
+	first.Done()
+	second.Done()
+}
+
+func TestDispatchByKeyAppliesSessionLimits(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messageBus := bus.NewMessageBus()
+	defer messageBus.Close()
+
+	started := make(chan string, 4)
+	finish := make(chan struct{})
+	rejected := make(chan string, 4)
+	go dispatchByKey(ctx, messageBus, config.RuntimeConfig{Workers: 4, SessionConcurrency: 2, MaxQueuedPerSession: 1}, func(ctx context.Context, inbound bus.InboundMessage) bool {
+		started <- inbound.Content
+		<-finish
+		return true
+	}, func(ctx context.Context, inbound bus.InboundMessage, err error) bool {
+		if !errors.Is(err, ErrQueueFull) {
+			t.Errorf("reject error = %v, want ErrQueueFull", err)
+		}
+		rejected <- inbound.Content
+		return true
+	})
+
+	for _, content := range []string{"one", "two", "three", "four"} {
+		messageBus.PublishInbound(ctx, bus.InboundMessage{SessionKey: "alpha", Content: content})
+	}
+
+	// Two prompts run at once, one waits, and the fourth is rejected.
+	for range 2 {
+		select {
+		case <-started:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for concurrent prompts to start")
+		}
+	}
+	select {
+	case content := <-rejected:
+		if content != "four" {
+			t.Fatalf("rejected %q, want %q", content, "four")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the rejection")
+	}
+
+	close(finish)
+	select {
+	case content := <-started:
+		if content != "three" {
+			t.Fatalf("queued prompt = %q, want %q", content, "three")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the queued prompt")
+	}
+}
+
+func TestCircuitBreakerOpensAndHalfOpens(t *testing.T) {
+	breaker := NewCircuitBreaker(config.CircuitBreakerConfig{Enabled: true, FailureThreshold: 2, CooldownSeconds: 10}, slog.Default())
+	now := time.Unix(1_700_000_000, 0)
+	breaker.now = func() time.Time { return now }
+
+	calls := 0
+	var providerErr error
+	handler := Chain(func(ctx context.Context, prompt string) (providertypes.PromptResult, error) {
+		calls++
+		return providertypes.PromptResult{Text: "ok"}, providerErr
+	}, breaker.Middleware())
+
+	down := providertypes.ClassifyError("openai", 503, errors.New("service unavailable"))
+	providerErr = down
+	for range 2 {
+		if _, err := handler(context.Background(), "hi"); !errors.Is(err, down) {
+			t.Fatalf("error = %v, want the provider failure", err)
+		}
+	}
+
+	_, err := handler(context.Background(), "hi")
+	var promptErr *providertypes.PromptError
+	if !errors.Is(err, ErrCircuitOpen) || !errors.As(err, &promptErr) || promptErr.Category != providertypes.ErrorCircuitOpen || promptErr.Provider != "openai" || promptErr.RetryAfter != 10*time.Second {
+		t.Fatalf("open breaker error = %#v, want circuit_open for openai retrying in 10s", err)
+	}
+	if calls != 2 {
+		t.Fatalf("provider calls = %d, want 2 (the open breaker fails fast)", calls)
+	}
+
+	// After the cooldown one failing trial reopens the breaker.
+	now = now.Add(10 * time.Second)
+	if _, err := handler(context.Background(), "hi"); !errors.Is(err, down) {
+		t.Fatalf("trial error = %v, want the provider failure", err)
+	}
+	if _, err := handler(context.Background(), "hi"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("error after failed trial = %v, want ErrCircuitOpen", err)
+	}
+
+	// A successful trial closes it.
+	now = now.Add(10 * time.Second)
+	providerErr = nil
+	for range 2 {
+		if _, err := handler(context.Background(), "hi"); err != nil {
+			t.Fatalf("error after recovery = %v", err)
+		}
+	}
+	if calls != 5 {
+		t.Fatalf("provider calls = %d, want 5", calls)
+	}
+}
+
+func TestCircuitBreakerIgnoresPromptErrors(t *testing.T) {
+	if NewCircuitBreaker(config.CircuitBreakerConfig{}, nil).Middleware() != nil {
+		t.Fatal("disabled breaker should not add middleware")
+	}
+
+	breaker := NewCircuitBreaker(config.CircuitBreakerConfig{Enabled: true, FailureThreshold: 1}, nil)
+	handler := Chain(func(ctx context.Context, prompt string) (providertypes.PromptResult, error) {
+		return providertypes.PromptResult{}, context.Canceled
+	}, breaker.Middleware())
+	for range 3 {
+		if _, err := handler(context.Background(), "hi"); !errors.Is(err, context.Canceled) {
+			t.Fatalf("error = %v, want context.Canceled without tripping the breaker", err)
+		}
+	}
+}
+
+func TestBudgetRefusesPromptsOverDailyCap(t *testing.T) {
+	if _, err := NewBudget(config.BudgetConfig{Enabled: true, MaxUSDPerDay: 1}, nil); err == nil {
+		t.Fatal("expected an error for a USD cap without token prices")
+	}
+
+	budget, err := NewBudget(config.BudgetConfig{Enabled: true, MaxTokensPerDay: 100, MaxUSDPerSession: 0.9, InputUSDPerMillion: 2000, OutputUSDPerMillion: 8000}, nil)
+	if err != nil {
+		t.Fatalf("NewBudget error: %v", err)
+	}
+	now := time.Date(2026, 10, 16, 23, 0, 0, 0, time.UTC)
+	budget.now = func() time.Time { return now }
+
+	calls := 0
+	usage := providertypes.TokenUsage{InputTokens: 40, OutputTokens: 20, TotalTokens: 60}
+	handler := Chain(func(ctx context.Context, prompt string) (providertypes.PromptResult, error) {
+		calls++
+		result := providertypes.PromptResult{Text: "ok"}
+		result.Metadata.Usage = &usage
+		return result, nil
+	}, budget.Middleware())
+
+	for range 2 {
+		if _, err := handler(context.Background(), "hi"); err != nil {
+			t.Fatalf("prompt under the cap error = %v", err)
+		}
+	}
+	_, err = handler(context.Background(), "hi")
+	var promptErr *providertypes.PromptError
+	if !errors.Is(err, ErrBudgetExhausted) || !errors.As(err, &promptErr) || promptErr.Category != providertypes.ErrorBudgetExhausted || promptErr.RetryAfter != time.Hour {
+		t.Fatalf("over-cap error = %#v, want budget_exhausted resetting in 1h", err)
+	}
+	if calls != 2 {
+		t.Fatalf("provider calls = %d, want 2", calls)
+	}
+
+	// The next UTC day has a fresh token cap, but each prompt costs
+	// (40 x $2000 + 20 x $8000) / 1M = $0.24, so four spend the session's $0.90.
+	now = now.Add(2 * time.Hour)
+	for range 2 {
+		if _, err := handler(context.Background(), "hi"); err != nil {
+			t.Fatalf("prompt on the next day error = %v", err)
+		}
+	}
+	_, err = handler(context.Background(), "hi")
+	if !errors.As(err, &promptErr) || promptErr.Category != providertypes.ErrorBudgetExhausted || promptErr.RetryAfter != 0 {
+		t.Fatalf("over session cap error = %v, want budget_exhausted without a reset time", err)
+	}
+}
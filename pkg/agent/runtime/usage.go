@@ -2,33 +2,22 @@ package runtime
 
 import (
 	"encoding/json"
+	"maps"
 	"strconv"
 	"strings"
+	"time"
 
 	"miniclaw/pkg/bus"
 	providertypes "miniclaw/pkg/provider/types"
 )
 
-const (
-	UsageInputTokensKey       = "usage_input_tokens"
-	UsageOutputTokensKey      = "usage_output_tokens"
-	UsageTotalTokensKey       = "usage_total_tokens"
-	UsageReasoningTokensKey   = "usage_reasoning_tokens"
-	UsageCacheCreateTokensKey = "usage_cache_creation_tokens"
-	UsageCacheReadTokensKey   = "usage_cache_read_tokens"
-	ToolEventsJSONKey         = "tool_events_json"
-)
-
-// PromptResultMetadata serializes provider usage fields into outbound metadata.
+// PromptResultMetadata serializes provider usage fields into outbound metadata
+// stamped with the schema version.
 //
 // Keeping this logic in one place avoids subtle drift between CLI and gateway
 // response formatting.
 func PromptResultMetadata(result providertypes.PromptResult) map[string]string {
-	if result.Metadata.Usage == nil && len(result.Metadata.ToolEvents) == 0 {
-		return nil
-	}
-
-	metadata := map[string]string{}
+	metadata := StampSchemaVersion(nil)
 	if result.Metadata.Usage != nil {
 		usage := result.Metadata.Usage
 		metadata[UsageInputTokensKey] = strconv.FormatInt(usage.InputTokens, 10)
@@ -46,8 +35,22 @@ func PromptResultMetadata(result providertypes.PromptResult) map[string]string {
 		}
 	}
 
-	if len(metadata) == 0 {
-		return nil
+	if timing := result.Metadata.Timing; timing != nil {
+		metadata[TimingQueueWaitMsKey] = strconv.FormatInt(timing.QueueWait.Milliseconds(), 10)
+		metadata[TimingProviderMsKey] = strconv.FormatInt(timing.Provider.Milliseconds(), 10)
+		metadata[TimingToolsMsKey] = strconv.FormatInt(timing.Tools.Milliseconds(), 10)
+		metadata[TimingTotalMsKey] = strconv.FormatInt(timing.Total.Milliseconds(), 10)
+	}
+
+	if toolUsage := result.Metadata.ToolUsage; toolUsage != nil {
+		maps.Copy(metadata, ToolUsagePayload(*toolUsage))
+	}
+
+	if context := result.Metadata.Context; context != nil {
+		metadata[ContextUsedTokensKey] = strconv.FormatInt(context.UsedTokens, 10)
+		metadata[ContextWindowTokensKey] = strconv.FormatInt(context.WindowTokens, 10)
+		metadata[ContextEstimatedKey] = strconv.FormatBool(context.Estimated)
+		metadata[ContextNearlyFullKey] = strconv.FormatBool(context.NearlyFull)
 	}
 
 	return metadata
@@ -60,25 +63,36 @@ func PromptResultFromOutbound(outbound bus.OutboundMessage) providertypes.Prompt
 		return result
 	}
 
-	usage := &providertypes.TokenUsage{
-		InputTokens:         parseInt64(outbound.Metadata[UsageInputTokensKey]),
-		OutputTokens:        parseInt64(outbound.Metadata[UsageOutputTokensKey]),
-		TotalTokens:         parseInt64(outbound.Metadata[UsageTotalTokensKey]),
-		ReasoningTokens:     parseInt64(outbound.Metadata[UsageReasoningTokensKey]),
-		CacheCreationTokens: parseInt64(outbound.Metadata[UsageCacheCreateTokensKey]),
-		CacheReadTokens:     parseInt64(outbound.Metadata[UsageCacheReadTokensKey]),
-	}
+	metadata := ReadMetadata(outbound)
+	result.Metadata.Usage = metadata.Usage()
+	result.Metadata.ToolEvents = metadata.ToolEvents()
+	result.Metadata.Timing = metadata.Timing()
+	result.Metadata.ToolUsage = metadata.ToolUsage()
+	result.Metadata.Context = metadata.Context()
 
-	if usage.IsZero() {
-		usage = nil
-	}
+	return result
+}
 
-	result.Metadata.Usage = usage
-	if raw, ok := outbound.Metadata[ToolEventsJSONKey]; ok {
-		result.Metadata.ToolEvents = parseToolEvents(raw)
+// ToolUsagePayload encodes tool usage counters as metadata fields.
+func ToolUsagePayload(usage providertypes.ToolUsage) map[string]string {
+	return map[string]string{
+		ToolCallsKey:        strconv.FormatInt(usage.Calls, 10),
+		ToolQuotaDeniedKey:  strconv.FormatInt(usage.QuotaDenied, 10),
+		ToolBytesReadKey:    strconv.FormatInt(usage.BytesRead, 10),
+		ToolBytesWrittenKey: strconv.FormatInt(usage.BytesWritten, 10),
+		ToolDurationMsKey:   strconv.FormatInt(usage.Duration.Milliseconds(), 10),
 	}
+}
 
-	return result
+// eventToolUsage converts tool usage counters to their event payload form.
+func eventToolUsage(usage providertypes.ToolUsage) *bus.ToolUsage {
+	return &bus.ToolUsage{
+		Calls:        usage.Calls,
+		QuotaDenied:  usage.QuotaDenied,
+		BytesRead:    usage.BytesRead,
+		BytesWritten: usage.BytesWritten,
+		DurationMs:   usage.Duration.Milliseconds(),
+	}
 }
 
 func parseToolEvents(raw string) []providertypes.ToolEvent {
@@ -99,6 +113,10 @@ func parseToolEvents(raw string) []providertypes.ToolEvent {
 	return events
 }
 
+func parseMillis(value string) time.Duration {
+	return time.Duration(parseInt64(value)) * time.Millisecond
+}
+
 func parseInt64(value string) int64 {
 	parsed, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
 	if err != nil {
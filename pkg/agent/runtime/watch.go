@@ -0,0 +1,97 @@
+package runtime
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/config"
+	"miniclaw/pkg/watch"
+	"miniclaw/pkg/workspace"
+)
+
+// minWatchSettle is the shortest quiet period after a prompt before workspace
+// changes are attributed to someone other than the agent.
+const minWatchSettle = 2 * time.Second
+
+// startWorkspaceWatch runs the workspace watcher on the session bus and
+// prompts the agent about the changes it reports. A watcher that cannot start
+// is logged and skipped so the session still works without it.
+func (s *LocalSession) startWorkspaceWatch(ctx context.Context, cfg config.WorkspaceWatchConfig, workspacePath string) {
+	root, err := workspace.ResolveRoot(workspacePath)
+	if err != nil {
+		s.log.Warn("Workspace watch disabled", "error", err)
+		return
+	}
+	debounce := time.Duration(cfg.DebounceMS) * time.Millisecond
+	watcher, err := watch.New(root, debounce)
+	if err != nil {
+		s.log.Warn("Workspace watch disabled", "error", err)
+		return
+	}
+
+	events, unsubscribe := s.messageBus.SubscribeEvents(ctx, 256)
+	go watcher.Run(ctx, s.messageBus)
+	go func() {
+		defer unsubscribe()
+		s.reactToWorkspaceChanges(ctx, events, max(2*max(debounce, watch.DefaultDebounce), minWatchSettle))
+	}()
+	s.log.Info("Watching workspace for file changes", "workspace", root)
+}
+
+// reactToWorkspaceChanges collects workspace change events and, once they
+// have been quiet for settle, sends one prompt listing them.
+//
+// Changes seen while a prompt is running, or within settle after it ends, are
+// taken to be the agent's own tool writes and dropped, so the agent does not
+// keep prompting itself about files it just wrote.
+func (s *LocalSession) reactToWorkspaceChanges(ctx context.Context, events <-chan bus.Event, settle time.Duration) {
+	pending := make(map[string]string)
+	timer := time.NewTimer(settle)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			change, ok := watch.ChangeFromEvent(event)
+			if !ok || s.promptActive(settle) {
+				continue
+			}
+			pending[change.Path] = change.Op
+			timer.Reset(settle)
+		case <-timer.C:
+			if len(pending) == 0 || s.promptActive(settle) {
+				clear(pending)
+				continue
+			}
+			changes := make([]watch.Change, 0, len(pending))
+			for _, path := range slices.Sorted(maps.Keys(pending)) {
+				changes = append(changes, watch.Change{Path: path, Op: pending[path]})
+			}
+			clear(pending)
+
+			go func() {
+				if _, err := s.Prompt(ctx, watch.Prompt(changes)); err != nil && ctx.Err() == nil {
+					s.log.Warn("Workspace change prompt failed", "changes", len(changes), "error", err)
+				}
+			}()
+		}
+	}
+}
+
+// promptActive reports whether a prompt is running or ended less than settle ago.
+func (s *LocalSession) promptActive(settle time.Duration) bool {
+	if s.promptsInFlight.Load() > 0 {
+		return true
+	}
+
+	return time.Since(time.Unix(0, s.lastPromptDone.Load())) < settle
+}
@@ -0,0 +1,103 @@
+package runtime
+
+import (
+	"context"
+	"sync"
+
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/config"
+)
+
+// resolveWorkerCount normalizes the configured worker count to at least one.
+func resolveWorkerCount(configured int) int {
+	if configured < 1 {
+		return 1
+	}
+
+	return configured
+}
+
+// dispatchByKey consumes inbound messages and runs them on up to cfg.Workers
+// concurrent workers.
+//
+// Each session key gets a PromptQueue, so prompts for one session run
+// cfg.SessionConcurrency at a time (one by default, keeping their arrival
+// order) while different sessions share the workers. A message arriving while
+// its session already has cfg.MaxQueuedPerSession prompts waiting goes to
+// reject instead of handle. Dispatch stops when either callback returns false.
+func dispatchByKey(ctx context.Context, messageBus *bus.MessageBus, cfg config.RuntimeConfig, handle func(context.Context, bus.InboundMessage) bool, reject func(context.Context, bus.InboundMessage, error) bool) {
+	workerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := make(chan struct{}, resolveWorkerCount(cfg.Workers))
+	queues := &sessionQueues{cfg: cfg, queues: map[string]*PromptQueue{}}
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		inbound, ok := messageBus.ConsumeInbound(workerCtx)
+		if !ok {
+			return
+		}
+
+		// Enqueue here, not in the worker goroutine, so tickets follow arrival order.
+		ticket, err := queues.enqueue(inbound.SessionKey)
+		if err != nil {
+			if !reject(workerCtx, inbound, err) {
+				return
+			}
+			continue
+		}
+
+		wg.Go(func() {
+			defer queues.release(inbound.SessionKey)
+			if ticket.Wait(workerCtx) != nil {
+				return
+			}
+			defer ticket.Done()
+
+			select {
+			case workers <- struct{}{}:
+			case <-workerCtx.Done():
+				return
+			}
+			defer func() { <-workers }()
+
+			if !handle(workerCtx, inbound) {
+				cancel()
+			}
+		})
+	}
+}
+
+// sessionQueues holds the PromptQueue of each session with prompts in flight.
+type sessionQueues struct {
+	cfg config.RuntimeConfig
+
+	mu     sync.Mutex
+	queues map[string]*PromptQueue
+}
+
+// enqueue takes a place in the queue of sessionKey, creating it on first use.
+func (s *sessionQueues) enqueue(sessionKey string) (*PromptTicket, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queue, ok := s.queues[sessionKey]
+	if !ok {
+		queue = NewPromptQueue(s.cfg)
+		s.queues[sessionKey] = queue
+	}
+
+	return queue.Enqueue()
+}
+
+// release drops the queue of sessionKey once nothing runs or waits in it.
+func (s *sessionQueues) release(sessionKey string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if queue, ok := s.queues[sessionKey]; ok && queue.idle() {
+		delete(s.queues, sessionKey)
+	}
+}
@@ -0,0 +1,266 @@
+package autostart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// Label identifies the launchd job and the Windows scheduled task.
+	Label = "com.miniclaw.gateway"
+	// WindowsTaskName is the Task Scheduler entry name.
+	WindowsTaskName = "MiniClaw Gateway"
+
+	configEnvName = "MINICLAW_CONFIG"
+)
+
+// ErrUnsupported is returned on platforms without a login-item backend (for example Linux, which uses systemd).
+var ErrUnsupported = errors.New("autostart is supported on macOS (launchd) and Windows (Task Scheduler); use a systemd user unit on Linux")
+
+// Spec describes the gateway process to start at login.
+type Spec struct {
+	// Executable is the absolute path of the miniclaw binary.
+	Executable string
+	// ConfigPath is exported as MINICLAW_CONFIG so the gateway finds the same config.
+	ConfigPath string
+	// WorkDir is the working directory for the gateway process.
+	WorkDir string
+	// LogDir receives stdout/stderr logs where the platform supports redirection.
+	LogDir string
+}
+
+// Result reports what Enable installed.
+type Result struct {
+	// Path is the installed plist or wrapper script.
+	Path string
+	// Commands are the service-manager commands that were (or would be) run.
+	Commands [][]string
+}
+
+// Runner executes one service-manager command.
+type Runner func(ctx context.Context, name string, args ...string) error
+
+// Manager installs and removes the gateway login item for one platform.
+type Manager struct {
+	goos    string
+	homeDir string
+	run     Runner
+}
+
+// NewManager builds a Manager for goos (normally runtime.GOOS).
+func NewManager(goos string) (*Manager, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("resolve home directory: %w", err)
+	}
+
+	return &Manager{goos: goos, homeDir: home, run: runCommand}, nil
+}
+
+// Enable writes the platform login item for spec and registers it.
+//
+// With dryRun set, nothing is written or run; Result still lists the planned
+// path and commands.
+func (m *Manager) Enable(ctx context.Context, spec Spec, dryRun bool) (Result, error) {
+	if err := spec.validate(); err != nil {
+		return Result{}, err
+	}
+
+	var path, content string
+	var commands [][]string
+	switch m.goos {
+	case "darwin":
+		path = m.launchdPath()
+		content = LaunchdPlist(spec)
+		// Unload first so re-running enable picks up a changed plist.
+		commands = [][]string{
+			{"launchctl", "unload", path},
+			{"launchctl", "load", "-w", path},
+		}
+	case "windows":
+		path = m.windowsScriptPath()
+		content = WindowsScript(spec)
+		commands = [][]string{
+			{"schtasks", "/Create", "/F", "/SC", "ONLOGON", "/RL", "LIMITED", "/TN", WindowsTaskName, "/TR", `"` + path + `"`},
+		}
+	default:
+		return Result{}, ErrUnsupported
+	}
+
+	result := Result{Path: path, Commands: commands}
+	if dryRun {
+		return result, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return Result{}, fmt.Errorf("create autostart directory: %w", err)
+	}
+	if spec.LogDir != "" {
+		if err := os.MkdirAll(spec.LogDir, 0o755); err != nil {
+			return Result{}, fmt.Errorf("create log directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return Result{}, fmt.Errorf("write autostart file: %w", err)
+	}
+
+	for index, command := range commands {
+		err := m.run(ctx, command[0], command[1:]...)
+		// launchctl unload fails when the job was never loaded; that is expected.
+		if err != nil && !(m.goos == "darwin" && index == 0) {
+			return Result{}, fmt.Errorf("run %s: %w", command[0], err)
+		}
+	}
+
+	return result, nil
+}
+
+// Disable unregisters the login item and removes its file.
+func (m *Manager) Disable(ctx context.Context) (Result, error) {
+	var result Result
+	switch m.goos {
+	case "darwin":
+		result.Path = m.launchdPath()
+		result.Commands = [][]string{{"launchctl", "unload", "-w", result.Path}}
+	case "windows":
+		result.Path = m.windowsScriptPath()
+		result.Commands = [][]string{{"schtasks", "/Delete", "/F", "/TN", WindowsTaskName}}
+	default:
+		return Result{}, ErrUnsupported
+	}
+
+	if _, err := os.Stat(result.Path); errors.Is(err, os.ErrNotExist) {
+		return Result{}, fmt.Errorf("autostart is not enabled (%s not found)", result.Path)
+	}
+	for _, command := range result.Commands {
+		if err := m.run(ctx, command[0], command[1:]...); err != nil {
+			return Result{}, fmt.Errorf("run %s: %w", command[0], err)
+		}
+	}
+	if err := os.Remove(result.Path); err != nil {
+		return Result{}, fmt.Errorf("remove autostart file: %w", err)
+	}
+
+	return result, nil
+}
+
+func (m *Manager) launchdPath() string {
+	return filepath.Join(m.homeDir, "Library", "LaunchAgents", Label+".plist")
+}
+
+func (m *Manager) windowsScriptPath() string {
+	base := os.Getenv("LOCALAPPDATA")
+	if base == "" {
+		base = filepath.Join(m.homeDir, "AppData", "Local")
+	}
+
+	return filepath.Join(base, "miniclaw", "gateway-autostart.cmd")
+}
+
+// LaunchdPlist renders a per-user launchd agent that keeps the gateway running.
+func LaunchdPlist(spec Spec) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+`)
+	writePlistString(&b, "Label", Label)
+	b.WriteString("\t<key>ProgramArguments</key>\n\t<array>\n")
+	fmt.Fprintf(&b, "\t\t<string>%s</string>\n", html.EscapeString(spec.Executable))
+	b.WriteString("\t\t<string>gateway</string>\n\t</array>\n")
+	b.WriteString("\t<key>EnvironmentVariables</key>\n\t<dict>\n")
+	fmt.Fprintf(&b, "\t\t<key>%s</key>\n\t\t<string>%s</string>\n", configEnvName, html.EscapeString(spec.ConfigPath))
+	b.WriteString("\t</dict>\n")
+	if spec.WorkDir != "" {
+		writePlistString(&b, "WorkingDirectory", spec.WorkDir)
+	}
+	b.WriteString("\t<key>RunAtLoad</key>\n\t<true/>\n")
+	b.WriteString("\t<key>KeepAlive</key>\n\t<true/>\n")
+	if spec.LogDir != "" {
+		writePlistString(&b, "StandardOutPath", filepath.Join(spec.LogDir, "gateway.log"))
+		writePlistString(&b, "StandardErrorPath", filepath.Join(spec.LogDir, "gateway.err.log"))
+	}
+	b.WriteString("</dict>\n</plist>\n")
+
+	return b.String()
+}
+
+func writePlistString(b *strings.Builder, key string, value string) {
+	fmt.Fprintf(b, "\t<key>%s</key>\n\t<string>%s</string>\n", key, html.EscapeString(value))
+}
+
+// WindowsScript renders the wrapper the scheduled task runs.
+//
+// Task Scheduler cannot set environment variables, so the wrapper exports
+// MINICLAW_CONFIG before starting the gateway.
+func WindowsScript(spec Spec) string {
+	var b strings.Builder
+	b.WriteString("@echo off\r\n")
+	fmt.Fprintf(&b, "set \"%s=%s\"\r\n", configEnvName, spec.ConfigPath)
+	if spec.WorkDir != "" {
+		fmt.Fprintf(&b, "cd /d \"%s\"\r\n", spec.WorkDir)
+	}
+	command := fmt.Sprintf("\"%s\" gateway", spec.Executable)
+	if spec.LogDir != "" {
+		command += fmt.Sprintf(" >> \"%s\" 2>&1", filepath.Join(spec.LogDir, "gateway.log"))
+	}
+	b.WriteString(command + "\r\n")
+
+	return b.String()
+}
+
+// StableExecutable prefers the PATH entry for miniclaw when it resolves to executable.
+//
+// Package managers such as Homebrew install binaries in versioned directories
+// and link them from a stable bin directory; pointing the login item at the
+// link keeps it working across upgrades.
+func StableExecutable(executable string) string {
+	resolved, err := filepath.EvalSymlinks(executable)
+	if err != nil {
+		return executable
+	}
+
+	onPath, err := osexec.LookPath("miniclaw")
+	if err != nil {
+		return executable
+	}
+	onPath, err = filepath.Abs(onPath)
+	if err != nil {
+		return executable
+	}
+	if target, err := filepath.EvalSymlinks(onPath); err == nil && target == resolved {
+		return onPath
+	}
+
+	return executable
+}
+
+func (s Spec) validate() error {
+	if !filepath.IsAbs(s.Executable) {
+		return fmt.Errorf("executable path must be absolute: %q", s.Executable)
+	}
+	if !filepath.IsAbs(s.ConfigPath) {
+		return fmt.Errorf("config path must be absolute: %q", s.ConfigPath)
+	}
+
+	return nil
+}
+
+func runCommand(ctx context.Context, name string, args ...string) error {
+	output, err := osexec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		if trimmed := strings.TrimSpace(string(output)); trimmed != "" {
+			return fmt.Errorf("%w: %s", err, trimmed)
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package autostart
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testSpec(dir string) Spec {
+	return Spec{
+		Executable: filepath.Join(dir, "bin", "miniclaw"),
+		ConfigPath: filepath.Join(dir, "config & more", "config.json"),
+		WorkDir:    dir,
+		LogDir:     filepath.Join(dir, "logs"),
+	}
+}
+
+func TestLaunchdPlist(t *testing.T) {
+	spec := testSpec("/Users/me")
+	plist := LaunchdPlist(spec)
+
+	for _, want := range []string{
+		"<string>" + Label + "</string>",
+		"<string>/Users/me/bin/miniclaw</string>\n\t\t<string>gateway</string>",
+		"<key>MINICLAW_CONFIG</key>\n\t\t<string>/Users/me/config &amp; more/config.json</string>",
+		"<key>RunAtLoad</key>\n\t<true/>",
+		"<string>/Users/me/logs/gateway.log</string>",
+	} {
+		if !strings.Contains(plist, want) {
+			t.Fatalf("expected plist to contain %q, got:\n%s", want, plist)
+		}
+	}
+}
+
+func TestWindowsScript(t *testing.T) {
+	spec := Spec{
+		Executable: `C:\Tools\miniclaw.exe`,
+		ConfigPath: `C:\Users\me\miniclaw\config.json`,
+		WorkDir:    `C:\Users\me\miniclaw`,
+	}
+	script := WindowsScript(spec)
+
+	for _, want := range []string{
+		`set "MINICLAW_CONFIG=C:\Users\me\miniclaw\config.json"`,
+		`cd /d "C:\Users\me\miniclaw"`,
+		`"C:\Tools\miniclaw.exe" gateway`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Fatalf("expected script to contain %q, got:\n%s", want, script)
+		}
+	}
+}
+
+func TestEnableDarwinWritesPlistAndLoadsIt(t *testing.T) {
+	home := t.TempDir()
+	var ran [][]string
+	manager := &Manager{goos: "darwin", homeDir: home, run: func(ctx context.Context, name string, args ...string) error {
+		_ = ctx
+		ran = append(ran, append([]string{name}, args...))
+		if len(args) == 2 && args[0] == "unload" {
+			return errors.New("not loaded")
+		}
+		return nil
+	}}
+
+	result, err := manager.Enable(context.Background(), testSpec(home), false)
+	if err != nil {
+		t.Fatalf("Enable returned error: %v", err)
+	}
+
+	wantPath := filepath.Join(home, "Library", "LaunchAgents", Label+".plist")
+	if result.Path != wantPath {
+		t.Fatalf("expected path %q, got %q", wantPath, result.Path)
+	}
+	data, err := os.ReadFile(wantPath)
+	if err != nil {
+		t.Fatalf("read plist: %v", err)
+	}
+	if string(data) != LaunchdPlist(testSpec(home)) {
+		t.Fatalf("unexpected plist content:\n%s", data)
+	}
+	if len(ran) != 2 || strings.Join(ran[1], " ") != "launchctl load -w "+wantPath {
+		t.Fatalf("unexpected commands: %v", ran)
+	}
+	if _, err := os.Stat(filepath.Join(home, "logs")); err != nil {
+		t.Fatalf("expected log directory to be created: %v", err)
+	}
+
+	if _, err := manager.Disable(context.Background()); err != nil {
+		t.Fatalf("Disable returned error: %v", err)
+	}
+	if _, err := os.Stat(wantPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected plist to be removed, stat error: %v", err)
+	}
+}
+
+func TestEnableDryRunChangesNothing(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("LOCALAPPDATA", filepath.Join(home, "AppData"))
+	manager := &Manager{goos: "windows", homeDir: home, run: func(ctx context.Context, name string, args ...string) error {
+		t.Fatalf("dry run must not run %s", name)
+		return nil
+	}}
+
+	result, err := manager.Enable(context.Background(), testSpec(home), true)
+	if err != nil {
+		t.Fatalf("Enable returned error: %v", err)
+	}
+	if len(result.Commands) != 1 || result.Commands[0][0] != "schtasks" {
+		t.Fatalf("unexpected planned commands: %v", result.Commands)
+	}
+	if _, err := os.Stat(result.Path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected no file at %s, stat error: %v", result.Path, err)
+	}
+}
+
+func TestEnableRejectsUnsupportedPlatformAndRelativePaths(t *testing.T) {
+	manager := &Manager{goos: "linux", homeDir: t.TempDir(), run: runCommand}
+	if _, err := manager.Enable(context.Background(), testSpec("/home/me"), true); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+
+	manager.goos = "darwin"
+	spec := testSpec("/home/me")
+	spec.ConfigPath = "config.json"
+	if _, err := manager.Enable(context.Background(), spec, true); err == nil {
+		t.Fatal("expected relative config path to be rejected")
+	}
+}
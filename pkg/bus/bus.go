@@ -2,22 +2,47 @@ package bus
 
 import (
 	"context"
+	"errors"
+	"maps"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const defaultBufferSize = 100
 
+// ErrClosed is returned by a PendingReply once the bus has been closed.
+var ErrClosed = errors.New("message bus is closed")
+
 // MessageBus is an in-process transport for inbound/outbound messages and runtime events.
 //
 // It is designed for local fan-in/fan-out coordination between runtime components
-// without external dependencies.
+// without external dependencies. Messages are queued per topic (see Topic), so
+// a full queue for one adapter or agent profile never holds up another.
 type MessageBus struct {
-	inbound  chan InboundMessage
-	outbound chan OutboundMessage
-	handlers map[string]MessageHandler
+	// inbound and outbound hold one queue per topic, created on first use.
+	inbound     map[string]chan InboundMessage
+	outbound    map[string]chan OutboundMessage
+	topicBuffer int
+	// topicsAdded is closed and replaced whenever a queue is created.
+	topicsAdded chan struct{}
+	handlers    map[string]MessageHandler
 
 	eventSubscribers      map[uint64]chan Event
 	nextEventSubscriberID uint64
+	// eventLog records published events for replay; nil when not enabled.
+	eventLog *EventLog
+
+	// transport carries inbound messages and replies between processes; nil
+	// for a bus local to this process.
+	transport     Transport
+	stopTransport context.CancelFunc
+
+	// pending holds the reply channel of each request still waiting, by reply_to ID.
+	pending        map[string]chan OutboundMessage
+	messageCounter atomic.Uint64
+	requestCounter atomic.Uint64
 
 	done      chan struct{}
 	closeOnce sync.Once
@@ -25,25 +50,37 @@ type MessageBus struct {
 	mu sync.RWMutex
 }
 
-// NewMessageBus creates a message bus with default buffer sizing.
+// NewMessageBus creates a message bus with default buffer sizing for each
+// topic queue.
 func NewMessageBus() *MessageBus {
 	return &MessageBus{
-		inbound:          make(chan InboundMessage, defaultBufferSize),
-		outbound:         make(chan OutboundMessage, defaultBufferSize),
+		inbound:          make(map[string]chan InboundMessage),
+		outbound:         make(map[string]chan OutboundMessage),
+		topicBuffer:      defaultBufferSize,
+		topicsAdded:      make(chan struct{}),
 		handlers:         make(map[string]MessageHandler),
 		eventSubscribers: make(map[uint64]chan Event),
+		pending:          make(map[string]chan OutboundMessage),
 		done:             make(chan struct{}),
 	}
 }
 
-// PublishInbound queues one inbound message.
+// PublishInbound queues one inbound message on the queue of its topic.
 //
-// It returns false when the context is canceled or when the bus has been closed.
+// The queued copy is stamped with message_id and created_at trace metadata
+// unless the caller already set them. A bus with a transport sends the
+// message over it.
+//
+// It returns false when the context is canceled, when the bus has been
+// closed, or when the transport cannot take the message.
 func (mb *MessageBus) PublishInbound(ctx context.Context, msg InboundMessage) bool {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
+	msg.Metadata = withMetadataDefault(msg.Metadata, MetadataCreatedAt, formatTraceTime(time.Now()))
+	msg.Metadata = withMetadataDefault(msg.Metadata, MetadataMessageID, mb.nextMessageID())
+
 	select {
 	case <-ctx.Done():
 		return false
@@ -53,35 +90,45 @@ func (mb *MessageBus) PublishInbound(ctx context.Context, msg InboundMessage) bo
 		// Preflight before send so callers fail fast after bus shutdown.
 	}
 
+	if mb.transport != nil {
+		return mb.transport.SendInbound(ctx, msg) == nil
+	}
+
 	select {
 	case <-ctx.Done():
 		return false
 	case <-mb.done:
 		return false
-	case mb.inbound <- msg:
+	case mb.inboundQueue(msg.Topic) <- msg:
 		return true
 	}
 }
 
-// ConsumeInbound waits for one inbound message.
+// ConsumeInbound waits for one inbound message on a topic matching topics
+// (see MatchTopic; none means every topic) and stamps consumed_at on it.
 //
 // It returns false when the context is canceled or when the bus has been closed.
-func (mb *MessageBus) ConsumeInbound(ctx context.Context) (InboundMessage, bool) {
+func (mb *MessageBus) ConsumeInbound(ctx context.Context, topics ...string) (InboundMessage, bool) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	select {
-	case <-ctx.Done():
-		return InboundMessage{}, false
-	case <-mb.done:
+	msg, ok := receiveTopic(ctx, mb, mb.inbound, topics)
+	if !ok {
 		return InboundMessage{}, false
-	case msg := <-mb.inbound:
-		return msg, true
 	}
+
+	msg.Metadata = withMetadataDefault(msg.Metadata, MetadataConsumedAt, formatTraceTime(time.Now()))
+	return msg, true
 }
 
-// PublishOutbound queues one outbound message.
+// PublishOutbound queues one outbound message on the queue of its topic.
+//
+// The queued copy is stamped with responded_at unless the caller already set it.
+// A message whose reply_to names a request goes to that request's
+// PendingReply instead of the shared outbound queue; one for a request that
+// stopped waiting is dropped. A reply to a request from another process is
+// sent back over the transport.
 //
 // It returns false when the context is canceled or when the bus has been closed.
 func (mb *MessageBus) PublishOutbound(ctx context.Context, msg OutboundMessage) bool {
@@ -89,6 +136,8 @@ func (mb *MessageBus) PublishOutbound(ctx context.Context, msg OutboundMessage)
 		ctx = context.Background()
 	}
 
+	msg.Metadata = withMetadataDefault(msg.Metadata, MetadataRespondedAt, formatTraceTime(time.Now()))
+
 	select {
 	case <-ctx.Done():
 		return false
@@ -98,32 +147,164 @@ func (mb *MessageBus) PublishOutbound(ctx context.Context, msg OutboundMessage)
 		// Preflight before send so callers fail fast after bus shutdown.
 	}
 
+	if msg.Metadata[MetadataReplyTo] != "" {
+		if inbox := msg.Metadata[MetadataReplyInbox]; mb.transport != nil && inbox != "" && inbox != mb.transport.Inbox() {
+			return mb.transport.SendReply(ctx, inbox, msg) == nil
+		}
+		mb.deliverReply(msg)
+		return true
+	}
+
 	select {
 	case <-ctx.Done():
 		return false
 	case <-mb.done:
 		return false
-	case mb.outbound <- msg:
+	case mb.outboundQueue(msg.Topic) <- msg:
 		return true
 	}
 }
 
-// SubscribeOutbound waits for one outbound message.
+// deliverReply hands msg to the request named by its reply_to, or drops it
+// when that request is no longer waiting.
+func (mb *MessageBus) deliverReply(msg OutboundMessage) {
+	replyTo := msg.Metadata[MetadataReplyTo]
+	mb.mu.Lock()
+	replyCh, ok := mb.pending[replyTo]
+	delete(mb.pending, replyTo)
+	mb.mu.Unlock()
+	if ok {
+		// Buffered for exactly one reply, so this never blocks.
+		replyCh <- msg
+	}
+}
+
+// PendingReply waits for the reply to one request published with Request.
+type PendingReply struct {
+	mb      *MessageBus
+	replyTo string
+	replyCh chan OutboundMessage
+}
+
+// Request publishes msg like PublishInbound and returns a PendingReply that
+// receives the outbound message correlated with it.
+//
+// The queued copy gets a bus-allocated reply_to ID; workers must carry it onto
+// their reply (CarryTrace does). Replies reach only their own request, so any
+// number of callers may have requests in flight at once. Callers must Close
+// the PendingReply once they stop waiting. On a bus with a transport the
+// request also carries this process's reply_inbox, so a worker in another
+// process can send the reply back.
 //
 // It returns false when the context is canceled or when the bus has been closed.
-func (mb *MessageBus) SubscribeOutbound(ctx context.Context) (OutboundMessage, bool) {
+func (mb *MessageBus) Request(ctx context.Context, msg InboundMessage) (*PendingReply, bool) {
+	return mb.request(ctx, msg, mb.PublishInbound)
+}
+
+// RequestTo is Request for the process whose inbox is inbox (see OpenPeer)
+// instead of whichever process takes the message first.
+//
+// It returns false on a bus without a transport, when the context is
+// canceled, or when the bus has been closed.
+func (mb *MessageBus) RequestTo(ctx context.Context, inbox string, msg InboundMessage) (*PendingReply, bool) {
+	if mb.transport == nil {
+		return nil, false
+	}
+
+	return mb.request(ctx, msg, func(ctx context.Context, msg InboundMessage) bool {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		msg.Metadata = withMetadataDefault(msg.Metadata, MetadataCreatedAt, formatTraceTime(time.Now()))
+		msg.Metadata = withMetadataDefault(msg.Metadata, MetadataMessageID, mb.nextMessageID())
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-mb.done:
+			return false
+		default:
+		}
+
+		return mb.transport.SendInboundTo(ctx, inbox, msg) == nil
+	})
+}
+
+// request registers a PendingReply for msg and hands msg to publish.
+func (mb *MessageBus) request(ctx context.Context, msg InboundMessage, publish func(context.Context, InboundMessage) bool) (*PendingReply, bool) {
+	replyTo := "req-" + strconv.FormatUint(mb.requestCounter.Add(1), 10)
+	msg.Metadata = maps.Clone(msg.Metadata)
+	if msg.Metadata == nil {
+		msg.Metadata = make(map[string]string, 4)
+	}
+	msg.Metadata[MetadataReplyTo] = replyTo
+	if mb.transport != nil {
+		msg.Metadata[MetadataReplyInbox] = mb.transport.Inbox()
+	}
+
+	pending := &PendingReply{mb: mb, replyTo: replyTo, replyCh: make(chan OutboundMessage, 1)}
+	mb.mu.Lock()
+	mb.pending[replyTo] = pending.replyCh
+	mb.mu.Unlock()
+
+	if !publish(ctx, msg) {
+		pending.Close()
+		return nil, false
+	}
+
+	return pending, true
+}
+
+// ReplyTo returns the correlation ID stamped on the request.
+func (p *PendingReply) ReplyTo() string {
+	return p.replyTo
+}
+
+// Wait blocks until the reply arrives. It returns ctx.Err() when ctx ends
+// first and ErrClosed when the bus closes first.
+func (p *PendingReply) Wait(ctx context.Context) (OutboundMessage, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
 	select {
+	case msg := <-p.replyCh:
+		return msg, nil
 	case <-ctx.Done():
-		return OutboundMessage{}, false
-	case <-mb.done:
-		return OutboundMessage{}, false
-	case msg := <-mb.outbound:
-		return msg, true
+		return OutboundMessage{}, ctx.Err()
+	case <-p.mb.done:
+		return OutboundMessage{}, ErrClosed
+	}
+}
+
+// Waiting reports whether the request's reply has not been published yet
+// and the request has not been closed.
+func (p *PendingReply) Waiting() bool {
+	p.mb.mu.RLock()
+	defer p.mb.mu.RUnlock()
+	_, ok := p.mb.pending[p.replyTo]
+	return ok
+}
+
+// Close stops waiting; a reply published afterwards is dropped.
+func (p *PendingReply) Close() {
+	p.mb.mu.Lock()
+	defer p.mb.mu.Unlock()
+	delete(p.mb.pending, p.replyTo)
+}
+
+// SubscribeOutbound waits for one outbound message that is not a reply to a
+// Request. Concurrent subscribers each receive a different message, so use
+// Request when a caller needs the reply to its own message. Like
+// ConsumeInbound, it takes messages only from topics matching topics.
+//
+// It returns false when the context is canceled or when the bus has been closed.
+func (mb *MessageBus) SubscribeOutbound(ctx context.Context, topics ...string) (OutboundMessage, bool) {
+	if ctx == nil {
+		ctx = context.Background()
 	}
+
+	return receiveTopic(ctx, mb, mb.outbound, topics)
 }
 
 // RegisterHandler stores a channel handler.
@@ -141,10 +322,18 @@ func (mb *MessageBus) GetHandler(channel string) (MessageHandler, bool) {
 	return handler, ok
 }
 
-// Close shuts down the bus and closes all event subscriptions.
+// Close shuts down the bus, closes all event subscriptions, and closes the
+// bus's transport.
 func (mb *MessageBus) Close() {
 	mb.closeOnce.Do(func() {
 		close(mb.done)
+		if mb.transport != nil {
+			mb.stopTransport()
+			_ = mb.transport.Close()
+		}
+		if mb.eventLog != nil {
+			_ = mb.eventLog.Close()
+		}
 
 		mb.mu.Lock()
 		for id, ch := range mb.eventSubscribers {
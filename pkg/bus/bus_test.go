@@ -2,9 +2,18 @@ package bus
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
+
+	"miniclaw/pkg/config"
 )
 
 func TestInboundRoundTrip(t *testing.T) {
@@ -43,6 +52,170 @@ func TestOutboundRoundTrip(t *testing.T) {
 	}
 }
 
+func TestRequestRoutesRepliesToTheirCaller(t *testing.T) {
+	mb := NewMessageBus()
+	t.Cleanup(mb.Close)
+
+	// A worker that answers in reverse order of arrival.
+	go func() {
+		var inbound []InboundMessage
+		for range 5 {
+			msg, ok := mb.ConsumeInbound(context.Background())
+			if !ok {
+				return
+			}
+			inbound = append(inbound, msg)
+		}
+		for _, msg := range slices.Backward(inbound) {
+			mb.PublishOutbound(context.Background(), CarryTrace(msg, OutboundMessage{Content: "re: " + msg.Content}))
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for index := range 5 {
+		wg.Go(func() {
+			content := strconv.Itoa(index)
+			pending, ok := mb.Request(context.Background(), InboundMessage{Content: content})
+			if !ok {
+				t.Error("expected request to publish")
+				return
+			}
+			defer pending.Close()
+			reply, err := pending.Wait(context.Background())
+			if err != nil || reply.Content != "re: "+content {
+				t.Errorf("reply to %s = %q, %v; want its own reply", content, reply.Content, err)
+			}
+		})
+	}
+	wg.Wait()
+
+	// A reply to a request that stopped waiting is dropped, not queued.
+	pending, _ := mb.Request(context.Background(), InboundMessage{Content: "late"})
+	pending.Close()
+	msg, _ := mb.ConsumeInbound(context.Background())
+	if ok := mb.PublishOutbound(context.Background(), CarryTrace(msg, OutboundMessage{Content: "late reply"})); !ok {
+		t.Fatal("expected the late reply publish to succeed")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if out, ok := mb.SubscribeOutbound(ctx); ok {
+		t.Fatalf("late reply reached the shared queue: %+v", out)
+	}
+
+	waiting, _ := mb.Request(context.Background(), InboundMessage{Content: "never answered"})
+	mb.Close()
+	if _, err := waiting.Wait(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Wait after close error = %v, want ErrClosed", err)
+	}
+}
+
+func TestSQLiteJournalKeepsUnackedMessages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bus.db")
+	ctx := context.Background()
+	journal, err := OpenJournal(config.BusConfig{Backend: "sqlite", Path: path})
+	if err != nil {
+		t.Fatalf("OpenJournal error: %v", err)
+	}
+
+	update := InboundMessage{Content: "from telegram", Metadata: map[string]string{MetadataDedupKey: "telegram:100"}}
+	var ids []string
+	for _, msg := range []InboundMessage{update, {Content: "lost in a crash"}, update} {
+		id, err := journal.Append(ctx, msg)
+		if err != nil {
+			t.Fatalf("Append %q error: %v", msg.Content, err)
+		}
+		ids = append(ids, id)
+	}
+	if ids[0] == "" || ids[1] == "" || ids[2] != "" {
+		t.Fatalf("delivery IDs = %q, want the duplicate dropped", ids)
+	}
+	if err := journal.Ack(ctx, ids[0]); err != nil {
+		t.Fatalf("Ack error: %v", err)
+	}
+	if err := journal.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	// A restart still lists the unacknowledged message and knows the dedup key.
+	journal, err = OpenJournal(config.BusConfig{Backend: "sqlite", Path: path})
+	if err != nil {
+		t.Fatalf("reopen error: %v", err)
+	}
+	t.Cleanup(func() { _ = journal.Close() })
+	if id, err := journal.Append(ctx, update); err != nil || id != "" {
+		t.Fatalf("duplicate Append after restart = %q, %v; want it dropped", id, err)
+	}
+	pending, err := journal.Pending(ctx)
+	if err != nil {
+		t.Fatalf("Pending error: %v", err)
+	}
+	if len(pending) != 1 || pending[0].Content != "lost in a crash" || pending[0].Metadata[MetadataDeliveryID] != ids[1] {
+		t.Fatalf("pending = %+v, want the unacknowledged message", pending)
+	}
+	if err := journal.Ack(ctx, ids[1]); err != nil {
+		t.Fatalf("Ack error: %v", err)
+	}
+	if pending, _ := journal.Pending(ctx); len(pending) != 0 {
+		t.Fatalf("pending after Ack = %+v", pending)
+	}
+
+	if journal, err := OpenJournal(config.BusConfig{}); journal != nil || err != nil {
+		t.Fatalf("OpenJournal for the memory backend = %v, %v; want none", journal, err)
+	}
+}
+
+func TestTopicsRouteWithoutHeadOfLineBlocking(t *testing.T) {
+	mb := NewMessageBus()
+	t.Cleanup(mb.Close)
+	ctx := context.Background()
+
+	// Fill the telegram queue; the cli queue must still take messages.
+	for i := range defaultBufferSize {
+		if !mb.PublishInbound(ctx, InboundMessage{Topic: "telegram", Content: strconv.Itoa(i)}) {
+			t.Fatalf("publish telegram %d failed", i)
+		}
+	}
+	publishCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if !mb.PublishInbound(publishCtx, InboundMessage{Topic: Topic("cli", "coder"), Content: "prompt"}) {
+		t.Fatal("publish to cli.coder blocked behind the full telegram queue")
+	}
+
+	msg, ok := mb.ConsumeInbound(ctx, "cli.*")
+	if !ok || msg.Topic != "cli.coder" || msg.Content != "prompt" {
+		t.Fatalf("consume cli.* = %+v, %v; want the cli.coder prompt", msg, ok)
+	}
+	msg, ok = mb.ConsumeInbound(ctx, "telegram")
+	if !ok || msg.Content != "0" {
+		t.Fatalf("consume telegram = %+v, %v; want the first telegram message", msg, ok)
+	}
+
+	// A consumer waiting before its topic exists still receives from it.
+	received := make(chan OutboundMessage, 1)
+	go func() {
+		if msg, ok := mb.SubscribeOutbound(ctx, "slack"); ok {
+			received <- msg
+		}
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if !mb.PublishOutbound(ctx, OutboundMessage{Topic: "other", Content: "skip"}) ||
+		!mb.PublishOutbound(ctx, OutboundMessage{Topic: "slack", Content: "hi"}) {
+		t.Fatal("outbound publish failed")
+	}
+	select {
+	case msg := <-received:
+		if msg.Content != "hi" {
+			t.Fatalf("slack subscriber got %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("slack subscriber did not receive its message")
+	}
+
+	if reply := CarryTrace(msg, OutboundMessage{}); reply.Topic != "telegram" {
+		t.Fatalf("reply topic = %q, want telegram", reply.Topic)
+	}
+}
+
 func TestCloseStopsBusOperations(t *testing.T) {
 	mb := NewMessageBus()
 	mb.Close()
@@ -239,3 +412,131 @@ func TestSubscribeEventsUnblocksOnClose(t *testing.T) {
 		t.Fatal("event subscription did not unblock after close")
 	}
 }
+
+func TestTraceMetadataStampedAcrossHops(t *testing.T) {
+	mb := NewMessageBus()
+	t.Cleanup(mb.Close)
+
+	callerMetadata := map[string]string{"request_id": "7"}
+	if ok := mb.PublishInbound(context.Background(), InboundMessage{Content: "hello", Metadata: callerMetadata}); !ok {
+		t.Fatal("expected inbound publish to succeed")
+	}
+	if len(callerMetadata) != 1 {
+		t.Fatalf("caller metadata mutated: %v", callerMetadata)
+	}
+
+	inbound, ok := mb.ConsumeInbound(context.Background())
+	if !ok {
+		t.Fatal("expected inbound consume to succeed")
+	}
+	for _, key := range []string{"request_id", MetadataMessageID, MetadataCreatedAt, MetadataConsumedAt} {
+		if inbound.Metadata[key] == "" {
+			t.Fatalf("inbound metadata missing %q: %v", key, inbound.Metadata)
+		}
+	}
+
+	outbound := CarryTrace(inbound, OutboundMessage{Content: "world"})
+	if outbound.Metadata[MetadataMessageID] != inbound.Metadata[MetadataMessageID] {
+		t.Fatalf("message_id = %q, want %q", outbound.Metadata[MetadataMessageID], inbound.Metadata[MetadataMessageID])
+	}
+	if _, ok := TimingsFromMetadata(outbound.Metadata); !ok {
+		t.Fatalf("expected complete timings in %v", outbound.Metadata)
+	}
+}
+
+func TestTimingsFromMetadata(t *testing.T) {
+	metadata := map[string]string{
+		MetadataCreatedAt:   "2026-01-01T10:00:00Z",
+		MetadataConsumedAt:  "2026-01-01T10:00:00.250Z",
+		MetadataRespondedAt: "2026-01-01T10:00:02.250Z",
+	}
+
+	timings, ok := TimingsFromMetadata(metadata)
+	if !ok {
+		t.Fatal("expected timings")
+	}
+	if timings.QueueWait != 250*time.Millisecond {
+		t.Fatalf("queue wait = %s, want 250ms", timings.QueueWait)
+	}
+	if timings.Processing != 2*time.Second {
+		t.Fatalf("processing = %s, want 2s", timings.Processing)
+	}
+
+	delete(metadata, MetadataConsumedAt)
+	if _, ok := TimingsFromMetadata(metadata); ok {
+		t.Fatal("expected incomplete metadata to report no timings")
+	}
+}
+
+func TestEventLogRotatesAndReplaysRange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	mb, err := Open(config.BusConfig{EventLog: config.EventLogConfig{Path: path, MaxFiles: 2}})
+	if err != nil {
+		t.Fatalf("open bus: %v", err)
+	}
+	t.Cleanup(mb.Close)
+	// Shrink the size limit so a few events rotate the file.
+	mb.eventLog.maxBytes = 300
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for index := range 12 {
+		mb.PublishEvent(context.Background(), Event{Type: EventHeartbeatTick, At: start.Add(time.Duration(index) * time.Minute), SessionKey: "s" + strconv.Itoa(index)})
+	}
+	if _, err := os.Stat(path + ".2"); err != nil {
+		t.Fatalf("expected two rotated files: %v", err)
+	}
+	if _, err := os.Stat(path + ".3"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected at most two rotated files, stat .3 = %v", err)
+	}
+
+	events, err := mb.ReplayEvents(context.Background(), start.Add(8*time.Minute), start.Add(10*time.Minute), 0)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	var keys []string
+	for event := range events {
+		keys = append(keys, event.SessionKey)
+	}
+	if !slices.Equal(keys, []string{"s8", "s9", "s10"}) {
+		t.Fatalf("replayed = %v, want s8 through s10", keys)
+	}
+
+	if _, err := NewMessageBus().ReplayEvents(context.Background(), time.Time{}, time.Time{}, 0); !errors.Is(err, ErrNoEventLog) {
+		t.Fatalf("replay without log = %v, want ErrNoEventLog", err)
+	}
+}
+
+func TestEventPayloadRoundTripsThroughJSON(t *testing.T) {
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := []Event{
+		{Type: EventPromptCompleted, At: at, Payload: PromptCompletedPayload{
+			Timing:         &PromptTiming{QueueWaitMs: 4, ProcessingMs: 120},
+			ResponseLength: 42,
+			Usage:          &TokenUsage{InputTokens: 10, OutputTokens: 5, TotalTokens: 15},
+			Tools:          &ToolUsage{Calls: 2, DurationMs: 30},
+		}},
+		{Type: EventToolFailed, At: at, Payload: ToolEventPayload{Tool: "read_file", DurationMs: 3}, Error: "no such file"},
+		{Type: EventSessionEvicted, At: at, Payload: SessionPayload{Reason: "shutdown"}},
+		{Type: EventHeartbeatTick, At: at},
+		{Type: EventMaintenanceRun, At: at, Payload: MaintenancePayload{Task: "snapshot_prune", Result: "pruned 2 changes", DurationMs: 8}},
+	}
+
+	for _, want := range events {
+		encoded, err := json.Marshal(want)
+		if err != nil {
+			t.Fatalf("marshal %s: %v", want.Type, err)
+		}
+		var got Event
+		if err := json.Unmarshal(encoded, &got); err != nil {
+			t.Fatalf("unmarshal %s: %v", encoded, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("round trip = %+v, want %+v", got, want)
+		}
+	}
+
+	var mistyped Event
+	if err := json.Unmarshal([]byte(`{"type":"tool_started","payload":{"tool":7}}`), &mistyped); err == nil {
+		t.Fatal("expected a mistyped payload to fail")
+	}
+}
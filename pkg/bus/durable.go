@@ -0,0 +1,289 @@
+package bus
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"miniclaw/pkg/config"
+	"miniclaw/pkg/workspace"
+)
+
+// Bus backends accepted by Open.
+const (
+	BackendMemory = "memory"
+	BackendSQLite = "sqlite"
+	BackendNATS   = "nats"
+	BackendRedis  = "redis"
+)
+
+// defaultTransportPrefix names the subjects and streams of an external bus
+// transport when bus.prefix is empty.
+const defaultTransportPrefix = "miniclaw"
+
+// dedupWindow is how long a handled message's dedup_key keeps rejecting
+// duplicates.
+const dedupWindow = 24 * time.Hour
+
+// Journal persists inbound messages until they are handled, giving the
+// gateway at-least-once delivery of channel messages across restarts (see
+// OpenJournal).
+type Journal interface {
+	// Append stores msg and returns its delivery ID. It returns "" when msg
+	// carries a dedup_key the journal has already seen.
+	Append(ctx context.Context, msg InboundMessage) (string, error)
+	// Ack marks the message with deliveryID handled.
+	Ack(ctx context.Context, deliveryID string) error
+	// Pending lists the messages not yet acknowledged, oldest first, with
+	// their delivery IDs stamped.
+	Pending(ctx context.Context) ([]InboundMessage, error)
+	Close() error
+}
+
+// Open returns the message bus selected by cfg: in memory by default, or
+// shared with other processes over NATS or Redis Streams. The sqlite backend
+// journals the gateway's channel messages (see OpenJournal), so its local
+// session bus stays in memory. With bus.event_log.path set, the bus also
+// records its events.
+func Open(cfg config.BusConfig) (*MessageBus, error) {
+	mb, err := openBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(cfg.EventLog.Path) == "" {
+		return mb, nil
+	}
+
+	eventLog, err := OpenEventLog(cfg.EventLog)
+	if err != nil {
+		mb.Close()
+		return nil, err
+	}
+	mb.AttachEventLog(eventLog)
+
+	return mb, nil
+}
+
+// openBackend builds the bus of cfg.Backend.
+func openBackend(cfg config.BusConfig) (*MessageBus, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Backend)) {
+	case "", BackendMemory, BackendSQLite:
+		return NewMessageBus(), nil
+	case BackendNATS, BackendRedis:
+		transport, err := openTransport(cfg, "", "")
+		if err != nil {
+			return nil, err
+		}
+		return NewTransportMessageBus(transport), nil
+	default:
+		return nil, fmt.Errorf("unsupported bus backend: %s", cfg.Backend)
+	}
+}
+
+// OpenJournal opens the SQLite journal at bus.path when bus.backend is
+// sqlite, or returns nil for any other backend.
+//
+// The gateway journals each channel message before answering it and marks
+// it handled once answered, so messages that were waiting or running when the
+// process died are answered on the next start through their channel.
+func OpenJournal(cfg config.BusConfig) (Journal, error) {
+	if !strings.EqualFold(strings.TrimSpace(cfg.Backend), BackendSQLite) {
+		return nil, nil
+	}
+	path, err := workspace.ExpandHome(strings.TrimSpace(cfg.Path))
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, errors.New("bus.path is required for the sqlite backend")
+	}
+
+	return NewSQLiteJournal(path)
+}
+
+// OpenPeer returns a bus over the NATS or Redis server of cfg whose inbox is
+// inbox, so other processes can address requests to it with RequestTo.
+//
+// Its subjects and streams sit under <bus.prefix>.peer, apart from the shared
+// work of buses from Open, so a peer only receives what other peers send it.
+// The event log is not attached.
+func OpenPeer(cfg config.BusConfig, inbox string) (*MessageBus, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Backend)) {
+	case BackendNATS, BackendRedis:
+	default:
+		return nil, fmt.Errorf("bus.backend must be %s or %s to reach other processes, got %q", BackendNATS, BackendRedis, cfg.Backend)
+	}
+	if inbox == "" || strings.ContainsAny(inbox, " \t\r\n*>") {
+		return nil, fmt.Errorf("peer inbox %q must be non-empty and must not contain whitespace, '*', or '>'", inbox)
+	}
+
+	transport, err := openTransport(cfg, ".peer", inbox)
+	if err != nil {
+		return nil, err
+	}
+	return NewTransportMessageBus(transport), nil
+}
+
+// openTransport connects the NATS or Redis transport of cfg under its prefix
+// plus suffix. An empty inbox gets a random name.
+func openTransport(cfg config.BusConfig, suffix string, inbox string) (Transport, error) {
+	prefix := strings.TrimSpace(cfg.Prefix)
+	if prefix == "" {
+		prefix = defaultTransportPrefix
+	}
+	if strings.ContainsAny(prefix, " \t\r\n*>") {
+		return nil, fmt.Errorf("bus.prefix %q must not contain whitespace, '*', or '>'", prefix)
+	}
+	prefix += suffix
+	tlsConfig, err := loadTransportTLS(cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(strings.TrimSpace(cfg.Backend), BackendNATS) {
+		return NewNATSTransport(strings.TrimSpace(cfg.URL), tlsConfig, prefix, inbox)
+	}
+	return NewRedisTransport(strings.TrimSpace(cfg.URL), tlsConfig, prefix, inbox)
+}
+
+// Ack marks a consumed inbound message handled, so the bus's transport does
+// not deliver it again. Workers call it once the message's reply is
+// published. It is a no-op on a bus without a transport.
+func (mb *MessageBus) Ack(ctx context.Context, msg InboundMessage) error {
+	if mb.transport == nil {
+		return nil
+	}
+
+	return mb.transport.Ack(ctx, msg)
+}
+
+const sqliteJournalSchema = `
+CREATE TABLE IF NOT EXISTS inbound (
+	seq        INTEGER PRIMARY KEY AUTOINCREMENT,
+	dedup_key  TEXT UNIQUE,
+	message    TEXT NOT NULL,
+	acked      INTEGER NOT NULL DEFAULT 0,
+	created_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS inbound_pending ON inbound (acked, seq);
+`
+
+// SQLiteJournal is a Journal in a SQLite database file (WAL mode).
+//
+// Handled messages are deleted, except that those with a dedup_key are kept
+// for dedupWindow so a redelivered duplicate is still recognized.
+type SQLiteJournal struct {
+	db *sql.DB
+}
+
+// NewSQLiteJournal opens (or creates) a bus journal at path and prunes
+// handled messages older than the dedup window.
+func NewSQLiteJournal(path string) (*SQLiteJournal, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("create bus journal directory: %w", err)
+		}
+	}
+
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open bus journal: %w", err)
+	}
+	if _, err := db.Exec(sqliteJournalSchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("initialize bus journal: %w", err)
+	}
+	cutoff := formatTraceTime(time.Now().Add(-dedupWindow))
+	if _, err := db.Exec(`DELETE FROM inbound WHERE acked = 1 AND created_at < ?`, cutoff); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("prune bus journal: %w", err)
+	}
+
+	return &SQLiteJournal{db: db}, nil
+}
+
+func (j *SQLiteJournal) Append(ctx context.Context, msg InboundMessage) (string, error) {
+	encoded, err := json.Marshal(msg)
+	if err != nil {
+		return "", fmt.Errorf("encode bus message: %w", err)
+	}
+
+	var dedupKey any
+	if key := msg.Metadata[MetadataDedupKey]; key != "" {
+		dedupKey = key
+	}
+	result, err := j.db.ExecContext(ctx, `INSERT INTO inbound (dedup_key, message, created_at) VALUES (?, ?, ?) ON CONFLICT (dedup_key) DO NOTHING`,
+		dedupKey, string(encoded), formatTraceTime(time.Now()))
+	if err != nil {
+		return "", fmt.Errorf("journal bus message: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return "", nil
+	}
+	seq, err := result.LastInsertId()
+	if err != nil {
+		return "", fmt.Errorf("journal bus message: %w", err)
+	}
+
+	return strconv.FormatInt(seq, 10), nil
+}
+
+func (j *SQLiteJournal) Ack(ctx context.Context, deliveryID string) error {
+	seq, err := strconv.ParseInt(deliveryID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid delivery id %q", deliveryID)
+	}
+
+	if _, err := j.db.ExecContext(ctx, `DELETE FROM inbound WHERE seq = ? AND dedup_key IS NULL`, seq); err != nil {
+		return fmt.Errorf("ack bus message: %w", err)
+	}
+	if _, err := j.db.ExecContext(ctx, `UPDATE inbound SET acked = 1 WHERE seq = ?`, seq); err != nil {
+		return fmt.Errorf("ack bus message: %w", err)
+	}
+
+	return nil
+}
+
+func (j *SQLiteJournal) Pending(ctx context.Context) ([]InboundMessage, error) {
+	rows, err := j.db.QueryContext(ctx, `SELECT seq, message FROM inbound WHERE acked = 0 ORDER BY seq`)
+	if err != nil {
+		return nil, fmt.Errorf("list pending bus messages: %w", err)
+	}
+	defer rows.Close()
+
+	var pending []InboundMessage
+	for rows.Next() {
+		var (
+			seq     int64
+			encoded string
+			msg     InboundMessage
+		)
+		if err := rows.Scan(&seq, &encoded); err != nil {
+			return nil, fmt.Errorf("list pending bus messages: %w", err)
+		}
+		if err := json.Unmarshal([]byte(encoded), &msg); err != nil {
+			return nil, fmt.Errorf("decode bus message %d: %w", seq, err)
+		}
+		if msg.Metadata == nil {
+			msg.Metadata = make(map[string]string, 1)
+		}
+		msg.Metadata[MetadataDeliveryID] = strconv.FormatInt(seq, 10)
+		pending = append(pending, msg)
+	}
+
+	return pending, rows.Err()
+}
+
+func (j *SQLiteJournal) Close() error {
+	return j.db.Close()
+}
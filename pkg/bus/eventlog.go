@@ -0,0 +1,271 @@
+package bus
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"miniclaw/pkg/config"
+	"miniclaw/pkg/workspace"
+)
+
+const (
+	defaultEventLogMaxSizeMB = 10
+	defaultEventLogMaxFiles  = 5
+)
+
+// ErrNoEventLog is returned by ReplayEvents on a bus that does not record
+// its events.
+var ErrNoEventLog = errors.New("bus has no event log")
+
+// EventLog appends bus events, one JSON object per line, to a file that is
+// rotated once it reaches its size limit. Rotated files are named <path>.1
+// (newest) through <path>.<max files>; older ones are removed.
+//
+// One process should write a given path: rotation is not coordinated
+// between processes.
+type EventLog struct {
+	path     string
+	maxBytes int64
+	maxFiles int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// OpenEventLog opens the event log configured by cfg, creating its
+// directory and file when missing.
+func OpenEventLog(cfg config.EventLogConfig) (*EventLog, error) {
+	path, err := workspace.ExpandHome(strings.TrimSpace(cfg.Path))
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, errors.New("bus.event_log.path is required")
+	}
+	if cfg.MaxSizeMB < 0 || cfg.MaxFiles < 0 {
+		return nil, errors.New("bus.event_log.max_size_mb and max_files must not be negative")
+	}
+
+	maxSizeMB := cfg.MaxSizeMB
+	if maxSizeMB == 0 {
+		maxSizeMB = defaultEventLogMaxSizeMB
+	}
+	maxFiles := cfg.MaxFiles
+	if maxFiles == 0 {
+		maxFiles = defaultEventLogMaxFiles
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create event log directory: %w", err)
+	}
+	l := &EventLog{path: path, maxBytes: int64(maxSizeMB) << 20, maxFiles: maxFiles}
+	if err := l.openLocked(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Path returns the path of the current event log file.
+func (l *EventLog) Path() string {
+	return l.path
+}
+
+// Append writes event to the end of the log, rotating first when the line
+// would take the current file past its size limit.
+func (l *EventLog) Append(event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return errors.New("event log is closed")
+	}
+	if l.size > 0 && l.size+int64(len(line)) > l.maxBytes {
+		if err := l.rotateLocked(); err != nil {
+			return err
+		}
+	}
+
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("write event log: %w", err)
+	}
+
+	return nil
+}
+
+// Replay calls fn with every logged event whose time is within [from, to],
+// oldest file first, until fn returns false. A zero from or to leaves that
+// end of the range open. Lines that do not decode are skipped.
+func (l *EventLog) Replay(ctx context.Context, from, to time.Time, fn func(Event) bool) error {
+	// Hold the lock only to pick the files; rotation may rename them while
+	// they are read, which costs at most the events of one rotation.
+	l.mu.Lock()
+	paths := make([]string, 0, l.maxFiles+1)
+	for index := l.maxFiles; index >= 1; index-- {
+		paths = append(paths, l.rotatedPath(index))
+	}
+	paths = append(paths, l.path)
+	l.mu.Unlock()
+
+	for _, path := range paths {
+		more, err := replayEventFile(ctx, path, from, to, fn)
+		if err != nil || !more {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replayEventFile replays one log file and reports whether fn wants more.
+func replayEventFile(ctx context.Context, path string, from, to time.Time, fn func(Event) bool) (bool, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("open event log: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		if (!from.IsZero() && event.At.Before(from)) || (!to.IsZero() && event.At.After(to)) {
+			continue
+		}
+		if !fn(event) {
+			return false, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("read event log: %w", err)
+	}
+
+	return true, nil
+}
+
+// Close closes the current log file.
+func (l *EventLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+
+	return err
+}
+
+// openLocked opens the current log file for appending.
+func (l *EventLog) openLocked() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open event log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("open event log: %w", err)
+	}
+	l.file, l.size = file, info.Size()
+
+	return nil
+}
+
+// rotateLocked shifts the rotated files up by one, dropping the oldest, and
+// starts a new current file.
+func (l *EventLog) rotateLocked() error {
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("close event log: %w", err)
+	}
+	l.file = nil
+
+	_ = os.Remove(l.rotatedPath(l.maxFiles))
+	for index := l.maxFiles - 1; index >= 1; index-- {
+		if err := os.Rename(l.rotatedPath(index), l.rotatedPath(index+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("rotate event log: %w", err)
+		}
+	}
+	if err := os.Rename(l.path, l.rotatedPath(1)); err != nil {
+		return fmt.Errorf("rotate event log: %w", err)
+	}
+
+	return l.openLocked()
+}
+
+// rotatedPath returns the path of the index-th newest rotated file.
+func (l *EventLog) rotatedPath(index int) string {
+	return l.path + "." + strconv.Itoa(index)
+}
+
+// AttachEventLog records every event published on the bus to log. The bus
+// closes log when it closes.
+func (mb *MessageBus) AttachEventLog(log *EventLog) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	mb.eventLog = log
+}
+
+// ReplayEvents streams the logged events within [from, to] to the returned
+// channel, oldest first, and closes it once they are all sent or ctx ends.
+// Unlike SubscribeEvents, replay waits for a slow reader instead of
+// dropping events. It fails with ErrNoEventLog when no log is attached.
+func (mb *MessageBus) ReplayEvents(ctx context.Context, from, to time.Time, buffer int) (<-chan Event, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if buffer <= 0 {
+		buffer = defaultBufferSize
+	}
+
+	mb.mu.RLock()
+	log := mb.eventLog
+	mb.mu.RUnlock()
+	if log == nil {
+		return nil, ErrNoEventLog
+	}
+
+	ch := make(chan Event, buffer)
+	go func() {
+		defer close(ch)
+		_ = log.Replay(ctx, from, to, func(event Event) bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case ch <- event:
+				return true
+			}
+		})
+	}()
+
+	return ch, nil
+}
@@ -2,6 +2,7 @@ package bus
 
 import (
 	"context"
+	"log/slog"
 	"sync"
 	"time"
 )
@@ -9,29 +10,75 @@ import (
 type EventType string
 
 const (
-	// EventPromptReceived is emitted when a prompt enters the runtime flow.
+	// EventPromptReceived is emitted when a prompt enters the runtime flow,
+	// with a PromptReceivedPayload.
 	EventPromptReceived EventType = "prompt_received"
-	// EventPromptCompleted is emitted when prompt execution completes successfully.
+	// EventPromptCompleted is emitted when prompt execution completes
+	// successfully, with a PromptCompletedPayload.
 	EventPromptCompleted EventType = "prompt_completed"
-	// EventPromptFailed is emitted when prompt execution ends with an error.
+	// EventPromptFailed is emitted when prompt execution ends with an error,
+	// with a PromptFailedPayload.
 	EventPromptFailed EventType = "prompt_failed"
+	// EventWorkspaceChanged is emitted by the workspace watcher for each file
+	// created, modified, or removed, with a WorkspaceChangedPayload.
+	EventWorkspaceChanged EventType = "workspace_changed"
+
+	// EventSessionCreated is emitted when a session runtime starts, with a
+	// SessionPayload.
+	EventSessionCreated EventType = "session_created"
+	// EventSessionEvicted is emitted when a live session runtime is dropped,
+	// with a SessionPayload giving the reason.
+	EventSessionEvicted EventType = "session_evicted"
+
+	// EventToolStarted is emitted when the agent calls a tool, with a
+	// ToolEventPayload.
+	EventToolStarted EventType = "tool_started"
+	// EventToolFinished is emitted when a tool call returns, with a
+	// ToolEventPayload.
+	EventToolFinished EventType = "tool_finished"
+	// EventToolFailed is emitted when a tool call returns an error, which is
+	// set as the event error, with a ToolEventPayload.
+	EventToolFailed EventType = "tool_failed"
+
+	// EventHeartbeatTick is emitted on every heartbeat tick of a session.
+	EventHeartbeatTick EventType = "heartbeat_tick"
+
+	// EventChannelConnected is emitted when a channel adapter starts running.
+	EventChannelConnected EventType = "channel_connected"
+	// EventChannelDisconnected is emitted when a channel adapter stops; the
+	// event error is set when it stopped on a failure.
+	EventChannelDisconnected EventType = "channel_disconnected"
+
+	// EventProviderDown is emitted when the gateway's provider health check
+	// starts failing; the event error holds the failure.
+	EventProviderDown EventType = "provider_down"
+	// EventProviderRecovered is emitted when a failing provider health check
+	// passes again.
+	EventProviderRecovered EventType = "provider_recovered"
+
+	// EventMaintenanceRun is emitted when a tools.cron.maintenance task
+	// finishes, with a MaintenancePayload; the event error is set when the
+	// run failed.
+	EventMaintenanceRun EventType = "maintenance_run"
 )
 
 // Event is a lightweight runtime signal broadcast to subscribers.
 type Event struct {
-	Type       EventType         `json:"type"`
-	At         time.Time         `json:"at"`
-	Channel    string            `json:"channel,omitempty"`
-	ChatID     string            `json:"chat_id,omitempty"`
-	SessionKey string            `json:"session_key,omitempty"`
-	RequestID  string            `json:"request_id,omitempty"`
-	Payload    map[string]string `json:"payload,omitempty"`
-	Error      string            `json:"error,omitempty"`
+	Type       EventType    `json:"type"`
+	At         time.Time    `json:"at"`
+	Channel    string       `json:"channel,omitempty"`
+	ChatID     string       `json:"chat_id,omitempty"`
+	SessionKey string       `json:"session_key,omitempty"`
+	RequestID  string       `json:"request_id,omitempty"`
+	TraceID    string       `json:"trace_id,omitempty"`
+	Payload    EventPayload `json:"payload,omitempty"`
+	Error      string       `json:"error,omitempty"`
 }
 
 // PublishEvent broadcasts one event to all current subscribers.
 //
 // Delivery is best effort: slow subscribers may miss events instead of blocking publishers.
+// With an event log attached, the event is also appended to it.
 func (mb *MessageBus) PublishEvent(ctx context.Context, event Event) bool {
 	if ctx == nil {
 		ctx = context.Background()
@@ -50,13 +97,23 @@ func (mb *MessageBus) PublishEvent(ctx context.Context, event Event) bool {
 	}
 
 	mb.mu.RLock()
-	subs := make([]chan Event, 0, len(mb.eventSubscribers))
-	for _, ch := range mb.eventSubscribers {
-		subs = append(subs, ch)
-	}
+	eventLog := mb.eventLog
 	mb.mu.RUnlock()
 
-	for _, ch := range subs {
+	if eventLog != nil {
+		if err := eventLog.Append(event); err != nil {
+			slog.Default().With("component", "bus").Warn("Event log write failed",
+				"event", event.Type,
+				"error", err,
+			)
+		}
+	}
+
+	// Sending under the read lock keeps unsubscribe and Close from closing a
+	// subscription mid-send; the sends never block.
+	mb.mu.RLock()
+	defer mb.mu.RUnlock()
+	for _, ch := range mb.eventSubscribers {
 		select {
 		case ch <- event:
 		default:
@@ -0,0 +1,215 @@
+package bus
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	defaultNATSURL     = "nats://127.0.0.1:4222"
+	natsDialTimeout    = 5 * time.Second
+	natsReconnectDelay = time.Second
+)
+
+// NATSTransport is a Transport over core NATS.
+//
+// Inbound messages are published to <prefix>.inbound, which every process
+// subscribes to in the <prefix>-workers queue group so each message reaches
+// one of them; messages for one process go to <prefix>.direct.<inbox> and
+// replies to <prefix>.reply.<inbox>. Core NATS does not store messages: one
+// sent while no process is subscribed is lost, and Ack does nothing. The
+// client reconnects after the connection drops, buffering publishes until it
+// is back.
+type NATSTransport struct {
+	conn     *nats.Conn
+	inbox    string
+	inbound  string
+	direct   string
+	replyTo  string
+	received chan InboundMessage
+	replies  chan OutboundMessage
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewNATSTransport connects to the NATS servers at rawURL (a comma-separated
+// list of nats:// or tls:// URLs with optional user:pass@ or token@, default
+// nats://127.0.0.1:4222) in the background and subscribes under prefix.
+// tlsConfig, when not nil, turns TLS on with its CA and client certificate.
+// An empty inbox gets a random name.
+func NewNATSTransport(rawURL string, tlsConfig *tls.Config, prefix string, inbox string) (*NATSTransport, error) {
+	if rawURL == "" {
+		rawURL = defaultNATSURL
+	}
+	for server := range strings.SplitSeq(rawURL, ",") {
+		parsed, err := url.Parse(strings.TrimSpace(server))
+		if err != nil {
+			return nil, fmt.Errorf("bus.url: %w", err)
+		}
+		if parsed.Scheme != "nats" && parsed.Scheme != "tls" {
+			return nil, fmt.Errorf("bus.url: unsupported nats scheme %q", parsed.Scheme)
+		}
+	}
+
+	options := []nats.Option{
+		nats.Name("miniclaw"),
+		nats.Timeout(natsDialTimeout),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(natsReconnectDelay),
+	}
+	if tlsConfig != nil {
+		options = append(options, nats.Secure(tlsConfig))
+	}
+	conn, err := nats.Connect(rawURL, options...)
+	if err != nil {
+		return nil, fmt.Errorf("nats: %w", err)
+	}
+
+	inbox = newInbox(inbox)
+	t := &NATSTransport{
+		conn:     conn,
+		inbox:    inbox,
+		inbound:  prefix + ".inbound",
+		direct:   prefix + ".direct.",
+		replyTo:  prefix + ".reply.",
+		received: make(chan InboundMessage, defaultBufferSize),
+		replies:  make(chan OutboundMessage, defaultBufferSize),
+		done:     make(chan struct{}),
+	}
+	_, err = conn.QueueSubscribe(t.inbound, prefix+"-workers", t.deliverInbound)
+	if err == nil {
+		_, err = conn.Subscribe(t.replyTo+inbox, t.deliverReply)
+	}
+	if err == nil {
+		_, err = conn.Subscribe(t.direct+inbox, t.deliverInbound)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("nats: subscribe: %w", err)
+	}
+
+	return t, nil
+}
+
+// Inbox implements Transport.
+func (t *NATSTransport) Inbox() string {
+	return t.inbox
+}
+
+// SendInbound implements Transport.
+func (t *NATSTransport) SendInbound(ctx context.Context, msg InboundMessage) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("encode bus message: %w", err)
+	}
+
+	return t.publish(ctx, t.inbound, payload)
+}
+
+// SendInboundTo implements Transport.
+func (t *NATSTransport) SendInboundTo(ctx context.Context, inbox string, msg InboundMessage) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("encode bus message: %w", err)
+	}
+
+	return t.publish(ctx, t.direct+inbox, payload)
+}
+
+// ReceiveInbound implements Transport.
+func (t *NATSTransport) ReceiveInbound(ctx context.Context) (InboundMessage, error) {
+	select {
+	case <-ctx.Done():
+		return InboundMessage{}, ctx.Err()
+	case <-t.done:
+		return InboundMessage{}, errTransportClosed
+	case msg := <-t.received:
+		return msg, nil
+	}
+}
+
+// SendReply implements Transport.
+func (t *NATSTransport) SendReply(ctx context.Context, inbox string, msg OutboundMessage) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("encode bus reply: %w", err)
+	}
+
+	return t.publish(ctx, t.replyTo+inbox, payload)
+}
+
+// ReceiveReply implements Transport.
+func (t *NATSTransport) ReceiveReply(ctx context.Context) (OutboundMessage, error) {
+	select {
+	case <-ctx.Done():
+		return OutboundMessage{}, ctx.Err()
+	case <-t.done:
+		return OutboundMessage{}, errTransportClosed
+	case msg := <-t.replies:
+		return msg, nil
+	}
+}
+
+// Ack implements Transport. Core NATS never redelivers, so there is nothing
+// to acknowledge.
+func (t *NATSTransport) Ack(context.Context, InboundMessage) error {
+	return nil
+}
+
+// Close implements Transport.
+func (t *NATSTransport) Close() error {
+	t.closeOnce.Do(func() {
+		close(t.done)
+		t.conn.Close()
+	})
+
+	return nil
+}
+
+// publish sends payload to subject.
+func (t *NATSTransport) publish(ctx context.Context, subject string, payload []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := t.conn.Publish(subject, payload); err != nil {
+		return fmt.Errorf("nats: publish: %w", err)
+	}
+
+	return nil
+}
+
+// deliverInbound hands a message from the inbound or direct subject to
+// ReceiveInbound. Undecodable payloads are dropped.
+func (t *NATSTransport) deliverInbound(delivered *nats.Msg) {
+	var msg InboundMessage
+	if json.Unmarshal(delivered.Data, &msg) != nil {
+		return
+	}
+	select {
+	case <-t.done:
+	case t.received <- msg:
+	}
+}
+
+// deliverReply hands a message from the reply subject to ReceiveReply.
+// Undecodable payloads are dropped.
+func (t *NATSTransport) deliverReply(delivered *nats.Msg) {
+	var msg OutboundMessage
+	if json.Unmarshal(delivered.Data, &msg) != nil {
+		return
+	}
+	select {
+	case <-t.done:
+	case t.replies <- msg:
+	}
+}
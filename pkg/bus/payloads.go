@@ -0,0 +1,153 @@
+package bus
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// EventPayload is the typed detail of an Event. Each event type carries one
+// payload type, named in its EventType doc comment; event types without
+// details carry none. Consumers type-switch on the concrete type:
+//
+//	if payload, ok := event.Payload.(bus.ToolEventPayload); ok { ... }
+type EventPayload interface {
+	eventPayload()
+}
+
+// PromptReceivedPayload describes a prompt entering the runtime flow.
+type PromptReceivedPayload struct {
+	PromptLength int `json:"prompt_length"`
+}
+
+// PromptTiming splits a prompt's round trip into time spent queued on the
+// bus and time spent handling it.
+type PromptTiming struct {
+	QueueWaitMs  int64 `json:"queue_wait_ms"`
+	ProcessingMs int64 `json:"processing_ms"`
+}
+
+// TokenUsage counts the tokens of one turn or of a whole session.
+type TokenUsage struct {
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+	TotalTokens  int64 `json:"total_tokens"`
+}
+
+// ToolUsage counts the tool activity of one turn or of a whole session.
+type ToolUsage struct {
+	Calls        int64 `json:"calls"`
+	QuotaDenied  int64 `json:"quota_denied"`
+	BytesRead    int64 `json:"bytes_read"`
+	BytesWritten int64 `json:"bytes_written"`
+	DurationMs   int64 `json:"duration_ms"`
+}
+
+// PromptCompletedPayload describes a successful turn. Timing is nil when the
+// bus trace was incomplete; the usage fields are nil when the provider
+// reported no usage or no tools ran. The Session fields are running totals.
+type PromptCompletedPayload struct {
+	Timing         *PromptTiming `json:"timing,omitempty"`
+	ResponseLength int           `json:"response_length"`
+	Usage          *TokenUsage   `json:"usage,omitempty"`
+	SessionUsage   *TokenUsage   `json:"session_usage,omitempty"`
+	Tools          *ToolUsage    `json:"tools,omitempty"`
+	SessionTools   *ToolUsage    `json:"session_tools,omitempty"`
+}
+
+// PromptFailedPayload describes a failed turn; the error itself is the
+// event error.
+type PromptFailedPayload struct {
+	Timing        *PromptTiming `json:"timing,omitempty"`
+	ErrorCategory string        `json:"error_category,omitempty"`
+}
+
+// WorkspaceChangedPayload names a file the workspace watcher saw change. Op
+// is "created", "modified", or "removed".
+type WorkspaceChangedPayload struct {
+	Path string `json:"path"`
+	Op   string `json:"op"`
+}
+
+// SessionPayload describes a session runtime starting or being dropped.
+// Resumed is set when a created session picked up saved history, Agent names
+// its agent profile, and Reason says why an evicted session was dropped.
+type SessionPayload struct {
+	Resumed bool   `json:"resumed,omitempty"`
+	Agent   string `json:"agent,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// ToolEventPayload names the tool of a tool event. DurationMs is unset for
+// tool_started.
+type ToolEventPayload struct {
+	Tool       string `json:"tool"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
+}
+
+// MaintenancePayload describes one run of a maintenance task. Result is the
+// task's one-line summary, empty when the run failed.
+type MaintenancePayload struct {
+	Task       string `json:"task"`
+	Result     string `json:"result,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+func (PromptReceivedPayload) eventPayload()   {}
+func (PromptCompletedPayload) eventPayload()  {}
+func (PromptFailedPayload) eventPayload()     {}
+func (WorkspaceChangedPayload) eventPayload() {}
+func (SessionPayload) eventPayload()          {}
+func (ToolEventPayload) eventPayload()        {}
+func (MaintenancePayload) eventPayload()      {}
+
+// UnmarshalJSON decodes an event, picking the payload type from the event
+// type. The payload of an unknown event type is dropped.
+func (e *Event) UnmarshalJSON(data []byte) error {
+	type plainEvent Event
+	var raw struct {
+		plainEvent
+		Payload json.RawMessage `json:"payload,omitempty"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*e = Event(raw.plainEvent)
+	e.Payload = nil
+	if len(raw.Payload) == 0 || string(raw.Payload) == "null" {
+		return nil
+	}
+
+	var err error
+	switch e.Type {
+	case EventPromptReceived:
+		e.Payload, err = decodePayload[PromptReceivedPayload](raw.Payload)
+	case EventPromptCompleted:
+		e.Payload, err = decodePayload[PromptCompletedPayload](raw.Payload)
+	case EventPromptFailed:
+		e.Payload, err = decodePayload[PromptFailedPayload](raw.Payload)
+	case EventWorkspaceChanged:
+		e.Payload, err = decodePayload[WorkspaceChangedPayload](raw.Payload)
+	case EventSessionCreated, EventSessionEvicted:
+		e.Payload, err = decodePayload[SessionPayload](raw.Payload)
+	case EventToolStarted, EventToolFinished, EventToolFailed:
+		e.Payload, err = decodePayload[ToolEventPayload](raw.Payload)
+	case EventMaintenanceRun:
+		e.Payload, err = decodePayload[MaintenancePayload](raw.Payload)
+	}
+	if err != nil {
+		return fmt.Errorf("decode %s payload: %w", e.Type, err)
+	}
+
+	return nil
+}
+
+// decodePayload decodes raw as a payload of type T.
+func decodePayload[T EventPayload](raw json.RawMessage) (EventPayload, error) {
+	var payload T
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,324 @@
+package bus
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultRedisURL  = "redis://127.0.0.1:6379"
+	redisDialTimeout = 5 * time.Second
+	// redisBlock bounds each blocking read, so a receive notices a canceled
+	// context or a closed transport within about this long.
+	redisBlock = time.Second
+	// redisClaimIdle is how long a message may sit unacknowledged with a
+	// consumer before another process takes it over.
+	redisClaimIdle     = 5 * time.Minute
+	redisClaimInterval = time.Minute
+	redisInboundMaxLen = 10000
+	redisReplyMaxLen   = 1000
+	redisReplyTTL      = time.Hour
+	redisMessageField  = "message"
+	// redisDeliveryStream marks an inbound message read from this process's
+	// direct stream, so Ack acknowledges it there.
+	redisDeliveryStream = "delivery_stream"
+)
+
+// RedisTransport is a Transport over Redis Streams.
+//
+// Inbound messages are appended to the <prefix>:inbound stream and read
+// through the <prefix>-workers consumer group, so each reaches one process
+// and stays pending until it is acknowledged; a message left unacknowledged
+// by a process that died is claimed by another after redisClaimIdle.
+// Messages for one process go to its <prefix>:direct:<inbox> stream, read
+// through the same consumer group but never claimed by another process.
+// Replies go to a <prefix>:reply:<inbox> stream that expires an hour after
+// its last reply.
+type RedisTransport struct {
+	client       *redis.Client
+	inbox        string
+	stream       string
+	group        string
+	directKey    string
+	directPrefix string
+	replyKey     string
+	replyPrefix  string
+
+	// Used only by ReceiveInbound.
+	groupReady bool
+	lastClaim  time.Time
+	claimed    []InboundMessage
+
+	// Used only by ReceiveReply.
+	lastReplyID string
+	received    []OutboundMessage
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewRedisTransport returns a transport for the Redis server at rawURL
+// (redis:// or, for TLS, rediss://[[user]:pass@]host[:port][/db], default
+// redis://127.0.0.1:6379) with keys under prefix. tlsConfig, when not nil,
+// turns TLS on with its CA and client certificate. It connects on first use.
+// An empty inbox gets a random name.
+func NewRedisTransport(rawURL string, tlsConfig *tls.Config, prefix string, inbox string) (*RedisTransport, error) {
+	if rawURL == "" {
+		rawURL = defaultRedisURL
+	}
+	options, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("bus.url: %w", err)
+	}
+	options.DialTimeout = redisDialTimeout
+	if tlsConfig != nil {
+		tlsConfig = tlsConfig.Clone()
+		if options.TLSConfig != nil {
+			tlsConfig.ServerName = options.TLSConfig.ServerName
+		}
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName, _, _ = net.SplitHostPort(options.Addr)
+		}
+		options.TLSConfig = tlsConfig
+	}
+
+	inbox = newInbox(inbox)
+	return &RedisTransport{
+		client:       redis.NewClient(options),
+		inbox:        inbox,
+		stream:       prefix + ":inbound",
+		group:        prefix + "-workers",
+		directKey:    prefix + ":direct:" + inbox,
+		directPrefix: prefix + ":direct:",
+		replyKey:     prefix + ":reply:" + inbox,
+		replyPrefix:  prefix + ":reply:",
+		lastReplyID:  "0",
+		done:         make(chan struct{}),
+	}, nil
+}
+
+// Inbox implements Transport.
+func (t *RedisTransport) Inbox() string {
+	return t.inbox
+}
+
+// SendInbound implements Transport.
+func (t *RedisTransport) SendInbound(ctx context.Context, msg InboundMessage) error {
+	return t.add(ctx, t.stream, redisInboundMaxLen, msg)
+}
+
+// SendInboundTo implements Transport.
+func (t *RedisTransport) SendInboundTo(ctx context.Context, inbox string, msg InboundMessage) error {
+	return t.add(ctx, t.directPrefix+inbox, redisInboundMaxLen, msg)
+}
+
+// ReceiveInbound implements Transport. The delivered message carries its
+// stream entry ID as delivery_id for Ack.
+func (t *RedisTransport) ReceiveInbound(ctx context.Context) (InboundMessage, error) {
+	for {
+		if err := t.alive(ctx); err != nil {
+			return InboundMessage{}, err
+		}
+		if len(t.claimed) > 0 {
+			msg := t.claimed[0]
+			t.claimed = t.claimed[1:]
+			return msg, nil
+		}
+
+		if !t.groupReady {
+			for _, stream := range []string{t.stream, t.directKey} {
+				err := t.client.XGroupCreateMkStream(ctx, stream, t.group, "0").Err()
+				if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+					return InboundMessage{}, fmt.Errorf("redis: create consumer group: %w", err)
+				}
+			}
+			t.groupReady = true
+		}
+
+		if time.Since(t.lastClaim) >= redisClaimInterval {
+			t.lastClaim = time.Now()
+			entries, _, err := t.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+				Stream:   t.stream,
+				Group:    t.group,
+				Consumer: t.inbox,
+				MinIdle:  redisClaimIdle,
+				Start:    "0-0",
+				Count:    10,
+			}).Result()
+			if err != nil {
+				return InboundMessage{}, t.groupError(err)
+			}
+			t.claimed = decodeRedisEntries[InboundMessage](entries)
+			continue
+		}
+
+		streams, err := t.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    t.group,
+			Consumer: t.inbox,
+			Streams:  []string{t.stream, t.directKey, ">", ">"},
+			Count:    1,
+			Block:    redisBlock,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return InboundMessage{}, t.groupError(err)
+		}
+		for _, stream := range streams {
+			entries := decodeRedisEntries[InboundMessage](stream.Messages)
+			if stream.Stream == t.directKey {
+				for _, msg := range entries {
+					msg.Metadata[redisDeliveryStream] = stream.Stream
+				}
+			}
+			t.claimed = append(t.claimed, entries...)
+		}
+	}
+}
+
+// SendReply implements Transport.
+func (t *RedisTransport) SendReply(ctx context.Context, inbox string, msg OutboundMessage) error {
+	key := t.replyPrefix + inbox
+	if err := t.add(ctx, key, redisReplyMaxLen, msg); err != nil {
+		return err
+	}
+	if err := t.client.Expire(ctx, key, redisReplyTTL).Err(); err != nil {
+		return fmt.Errorf("redis: expire reply stream: %w", err)
+	}
+
+	return nil
+}
+
+// ReceiveReply implements Transport.
+func (t *RedisTransport) ReceiveReply(ctx context.Context) (OutboundMessage, error) {
+	for {
+		if err := t.alive(ctx); err != nil {
+			return OutboundMessage{}, err
+		}
+		if len(t.received) > 0 {
+			msg := t.received[0]
+			t.received = t.received[1:]
+			return msg, nil
+		}
+
+		streams, err := t.client.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{t.replyKey, t.lastReplyID},
+			Count:   10,
+			Block:   redisBlock,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return OutboundMessage{}, fmt.Errorf("redis: read replies: %w", err)
+		}
+		for _, stream := range streams {
+			if len(stream.Messages) > 0 {
+				t.lastReplyID = stream.Messages[len(stream.Messages)-1].ID
+			}
+			t.received = append(t.received, decodeRedisEntries[OutboundMessage](stream.Messages)...)
+		}
+	}
+}
+
+// Ack implements Transport.
+func (t *RedisTransport) Ack(ctx context.Context, msg InboundMessage) error {
+	id := msg.Metadata[MetadataDeliveryID]
+	if id == "" {
+		return nil
+	}
+
+	stream := t.stream
+	if key := msg.Metadata[redisDeliveryStream]; key != "" {
+		stream = key
+	}
+	if err := t.client.XAck(ctx, stream, t.group, id).Err(); err != nil {
+		return fmt.Errorf("redis: ack: %w", err)
+	}
+
+	return nil
+}
+
+// Close implements Transport.
+func (t *RedisTransport) Close() error {
+	t.closeOnce.Do(func() {
+		close(t.done)
+		_ = t.client.Close()
+	})
+
+	return nil
+}
+
+// add appends msg to stream, trimming it to about maxLen entries.
+func (t *RedisTransport) add(ctx context.Context, stream string, maxLen int64, msg any) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("encode bus message: %w", err)
+	}
+
+	err = t.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		MaxLen: maxLen,
+		Approx: true,
+		Values: []any{redisMessageField, string(payload)},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("redis: add to %s: %w", stream, err)
+	}
+
+	return nil
+}
+
+// alive returns an error once ctx ends or the transport is closed.
+func (t *RedisTransport) alive(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.done:
+		return errTransportClosed
+	default:
+		return nil
+	}
+}
+
+// groupError notes that the consumer group must be created again when the
+// stream was deleted under it.
+func (t *RedisTransport) groupError(err error) error {
+	if strings.HasPrefix(err.Error(), "NOGROUP") {
+		t.groupReady = false
+	}
+	return fmt.Errorf("redis: read inbound: %w", err)
+}
+
+// decodeRedisEntries decodes the message field of stream entries. Entries
+// without a decodable message field are skipped. Inbound messages get their
+// entry ID as delivery_id.
+func decodeRedisEntries[T InboundMessage | OutboundMessage](entries []redis.XMessage) []T {
+	var decoded []T
+	for _, entry := range entries {
+		payload, _ := entry.Values[redisMessageField].(string)
+		var msg T
+		if json.Unmarshal([]byte(payload), &msg) != nil {
+			continue
+		}
+		if inbound, ok := any(&msg).(*InboundMessage); ok {
+			if inbound.Metadata == nil {
+				inbound.Metadata = make(map[string]string, 1)
+			}
+			inbound.Metadata[MetadataDeliveryID] = entry.ID
+		}
+		decoded = append(decoded, msg)
+	}
+
+	return decoded
+}
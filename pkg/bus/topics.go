@@ -0,0 +1,115 @@
+package bus
+
+import (
+	"context"
+	"reflect"
+	"strings"
+)
+
+// DefaultTopic is the topic of a message published without one.
+const DefaultTopic = ""
+
+// Topic joins the non-empty parts into a topic name, such as "telegram" or
+// "cli.coder" for a channel adapter and agent profile.
+func Topic(parts ...string) string {
+	names := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			names = append(names, part)
+		}
+	}
+
+	return strings.Join(names, ".")
+}
+
+// MatchTopic reports whether topic matches any of patterns. A pattern is a
+// topic name, or a prefix followed by "*" ("telegram.*", or "*" for every
+// topic). No patterns at all match every topic.
+func MatchTopic(patterns []string, topic string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(topic, prefix) {
+				return true
+			}
+			continue
+		}
+		if pattern == topic {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inboundQueue returns the inbound queue of topic, creating it on first use.
+func (mb *MessageBus) inboundQueue(topic string) chan InboundMessage {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	queue, ok := mb.inbound[topic]
+	if !ok {
+		queue = make(chan InboundMessage, mb.topicBuffer)
+		mb.inbound[topic] = queue
+		mb.topicAddedLocked()
+	}
+
+	return queue
+}
+
+// outboundQueue returns the outbound queue of topic, creating it on first use.
+func (mb *MessageBus) outboundQueue(topic string) chan OutboundMessage {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	queue, ok := mb.outbound[topic]
+	if !ok {
+		queue = make(chan OutboundMessage, mb.topicBuffer)
+		mb.outbound[topic] = queue
+		mb.topicAddedLocked()
+	}
+
+	return queue
+}
+
+// topicAddedLocked wakes receivers so they pick up a new queue their
+// patterns may match.
+func (mb *MessageBus) topicAddedLocked() {
+	close(mb.topicsAdded)
+	mb.topicsAdded = make(chan struct{})
+}
+
+// receiveTopic waits for one message from any queue in queues whose topic
+// matches patterns. Queues are chosen at random among those ready, so a busy
+// topic does not starve the others. It returns false when ctx ends or the bus
+// closes.
+func receiveTopic[T any](ctx context.Context, mb *MessageBus, queues map[string]chan T, patterns []string) (T, bool) {
+	var zero T
+	for {
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(mb.done)},
+		}
+		mb.mu.RLock()
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(mb.topicsAdded)})
+		for topic, queue := range queues {
+			if MatchTopic(patterns, topic) {
+				cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(queue)})
+			}
+		}
+		mb.mu.RUnlock()
+
+		chosen, value, _ := reflect.Select(cases)
+		switch chosen {
+		case 0, 1:
+			return zero, false
+		case 2:
+			continue
+		}
+
+		return value.Interface().(T), true
+	}
+}
@@ -0,0 +1,118 @@
+package bus
+
+import (
+	"maps"
+	"strconv"
+	"time"
+)
+
+// Trace metadata keys stamped onto messages as they move through the bus.
+//
+// Timestamps use RFC3339Nano in UTC so they stay sortable and readable in logs.
+const (
+	MetadataMessageID   = "message_id"
+	MetadataCreatedAt   = "created_at"
+	MetadataConsumedAt  = "consumed_at"
+	MetadataRespondedAt = "responded_at"
+	// MetadataReplyTo correlates a reply with the request that is waiting for
+	// it (see MessageBus.Request).
+	MetadataReplyTo = "reply_to"
+	// MetadataReplyInbox names the process waiting for a reply when the bus
+	// has a transport shared by several processes.
+	MetadataReplyInbox = "reply_inbox"
+	// MetadataDedupKey names an inbound message for a Journal, which drops
+	// a later message with the same key (for example a redelivered channel
+	// update).
+	MetadataDedupKey = "dedup_key"
+	// MetadataDeliveryID is the journal entry of an inbound message, or its
+	// entry in a transport's queue; Journal.Ack and MessageBus.Ack use it.
+	MetadataDeliveryID = "delivery_id"
+)
+
+// TraceTimings splits one request/reply round trip into its bus hops.
+type TraceTimings struct {
+	// QueueWait is the time between publish and a worker consuming the message.
+	QueueWait time.Duration
+	// Processing is the time a worker spent producing the reply (mostly provider time).
+	Processing time.Duration
+}
+
+// CarryTrace copies inbound trace metadata, including reply_to and
+// reply_inbox, onto an outbound reply and stamps responded_at. A reply
+// without a topic gets the inbound topic.
+//
+// Workers call this right after prompt execution so the reply carries the full
+// hop timeline back to whoever is waiting for it.
+func CarryTrace(inbound InboundMessage, outbound OutboundMessage) OutboundMessage {
+	metadata := maps.Clone(outbound.Metadata)
+	if metadata == nil {
+		metadata = make(map[string]string, 4)
+	}
+
+	for _, key := range []string{MetadataMessageID, MetadataCreatedAt, MetadataConsumedAt, MetadataReplyTo, MetadataReplyInbox} {
+		if value, ok := inbound.Metadata[key]; ok {
+			metadata[key] = value
+		}
+	}
+	if outbound.Topic == "" {
+		outbound.Topic = inbound.Topic
+	}
+	outbound.Metadata = withMetadataDefault(metadata, MetadataRespondedAt, formatTraceTime(time.Now()))
+	return outbound
+}
+
+// TimingsFromMetadata derives hop durations from trace metadata.
+//
+// It returns false when any of the three timestamps is missing or malformed.
+func TimingsFromMetadata(metadata map[string]string) (TraceTimings, bool) {
+	createdAt, okCreated := parseTraceTime(metadata[MetadataCreatedAt])
+	consumedAt, okConsumed := parseTraceTime(metadata[MetadataConsumedAt])
+	respondedAt, okResponded := parseTraceTime(metadata[MetadataRespondedAt])
+	if !okCreated || !okConsumed || !okResponded {
+		return TraceTimings{}, false
+	}
+
+	return TraceTimings{
+		QueueWait:  consumedAt.Sub(createdAt),
+		Processing: respondedAt.Sub(consumedAt),
+	}, true
+}
+
+// withMetadataDefault returns metadata with key set to value, unless key is already present.
+//
+// The map is copied before writing so callers' maps stay untouched; they may
+// reuse them for other messages.
+func withMetadataDefault(metadata map[string]string, key string, value string) map[string]string {
+	if _, ok := metadata[key]; ok {
+		return metadata
+	}
+
+	stamped := maps.Clone(metadata)
+	if stamped == nil {
+		stamped = make(map[string]string, 4)
+	}
+	stamped[key] = value
+	return stamped
+}
+
+// nextMessageID allocates a bus-scoped message identifier.
+func (mb *MessageBus) nextMessageID() string {
+	return "msg-" + strconv.FormatUint(mb.messageCounter.Add(1), 10)
+}
+
+func formatTraceTime(at time.Time) string {
+	return at.UTC().Format(time.RFC3339Nano)
+}
+
+func parseTraceTime(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return parsed, true
+}
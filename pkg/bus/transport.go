@@ -0,0 +1,176 @@
+package bus
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"miniclaw/pkg/config"
+	"miniclaw/pkg/workspace"
+)
+
+// transportRetryDelay is how long a bus waits before asking a failing
+// transport for messages again.
+const transportRetryDelay = time.Second
+
+// Transport carries a bus's inbound messages and request replies between
+// processes, so several miniclaw processes can share one message fabric.
+//
+// Inbound messages are shared work: each is received by one of the processes
+// on the transport, unless SendInboundTo addresses one by inbox. Replies go
+// back to the process that sent the request, which is named by its inbox.
+// Uncorrelated outbound messages and events stay in the process that
+// published them.
+type Transport interface {
+	// Inbox names this process's reply address on the transport.
+	Inbox() string
+	// SendInbound queues msg for whichever process receives it first.
+	SendInbound(ctx context.Context, msg InboundMessage) error
+	// SendInboundTo queues msg for the process whose inbox is inbox only.
+	SendInboundTo(ctx context.Context, inbox string, msg InboundMessage) error
+	// ReceiveInbound waits for the next inbound message for this process,
+	// shared or sent to its inbox.
+	ReceiveInbound(ctx context.Context) (InboundMessage, error)
+	// SendReply delivers msg to the process whose inbox is inbox.
+	SendReply(ctx context.Context, inbox string, msg OutboundMessage) error
+	// ReceiveReply waits for the next reply sent to Inbox.
+	ReceiveReply(ctx context.Context) (OutboundMessage, error)
+	// Ack marks a received inbound message handled. Transports without
+	// redelivery ignore it.
+	Ack(ctx context.Context, msg InboundMessage) error
+	Close() error
+}
+
+// NewTransportMessageBus returns a bus whose inbound messages and request
+// replies travel over transport. The bus closes transport when it closes.
+func NewTransportMessageBus(transport Transport) *MessageBus {
+	mb := NewMessageBus()
+	mb.transport = transport
+	// Local publishes go over the transport, so topic queues only hand on
+	// messages received from it; keep them unbuffered.
+	mb.topicBuffer = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mb.stopTransport = cancel
+	go mb.pumpInbound(ctx)
+	go mb.pumpReplies(ctx)
+
+	return mb
+}
+
+// pumpInbound hands messages received from the transport to ConsumeInbound.
+// The hand-off is unbuffered, so this process holds at most one message that
+// another process could have taken. Every process on the transport must
+// consume each topic it may receive, or the pump stalls on that topic.
+func (mb *MessageBus) pumpInbound(ctx context.Context) {
+	for {
+		msg, err := mb.transport.ReceiveInbound(ctx)
+		if err != nil {
+			if !sleepContext(ctx, transportRetryDelay) {
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case mb.inboundQueue(msg.Topic) <- msg:
+		}
+	}
+}
+
+// pumpReplies routes replies received from the transport to their requests.
+func (mb *MessageBus) pumpReplies(ctx context.Context) {
+	for {
+		msg, err := mb.transport.ReceiveReply(ctx)
+		if err != nil {
+			if !sleepContext(ctx, transportRetryDelay) {
+				return
+			}
+			continue
+		}
+
+		mb.deliverReply(msg)
+	}
+}
+
+// sleepContext waits for d and reports false when ctx ends first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// newInbox returns inbox, or a random inbox name for one process when it is empty.
+func newInbox(inbox string) string {
+	if inbox != "" {
+		return inbox
+	}
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// errTransportClosed is returned by a transport after Close.
+var errTransportClosed = errors.New("bus transport is closed")
+
+// loadTransportTLS builds the TLS settings of bus.tls, or returns nil when
+// none are set.
+func loadTransportTLS(cfg config.BusTLSConfig) (*tls.Config, error) {
+	caFile := strings.TrimSpace(cfg.CAFile)
+	certFile := strings.TrimSpace(cfg.CertFile)
+	keyFile := strings.TrimSpace(cfg.KeyFile)
+	if caFile == "" && certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("bus.tls.cert_file and bus.tls.key_file must be set together")
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		path, err := workspace.ExpandHome(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read bus.tls.ca_file: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("bus.tls.ca_file %s holds no PEM certificates", caFile)
+		}
+	}
+	if certFile != "" {
+		certFile, err := workspace.ExpandHome(certFile)
+		if err != nil {
+			return nil, err
+		}
+		keyFile, err := workspace.ExpandHome(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load bus.tls certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
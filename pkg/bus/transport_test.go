@@ -0,0 +1,367 @@
+package bus
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"miniclaw/pkg/config"
+)
+
+func TestNATSTransportRoutesRepliesAcrossProcesses(t *testing.T) {
+	server := newFakeNATS(t)
+
+	// The requester subscribes first; the fake server hands queue messages to
+	// the newest subscriber, which is the worker.
+	requester, err := Open(config.BusConfig{Backend: BackendNATS, URL: "nats://" + server.addr})
+	if err != nil {
+		t.Fatalf("open requester bus: %v", err)
+	}
+	t.Cleanup(requester.Close)
+	server.waitForSubscriptions(t, 3)
+
+	worker, err := Open(config.BusConfig{Backend: BackendNATS, URL: "nats://" + server.addr})
+	if err != nil {
+		t.Fatalf("open worker bus: %v", err)
+	}
+	t.Cleanup(worker.Close)
+	server.waitForSubscriptions(t, 6)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pending, ok := requester.Request(ctx, InboundMessage{Channel: "cli", Content: "ping", SessionKey: "s1"})
+	if !ok {
+		t.Fatal("expected request to publish")
+	}
+	defer pending.Close()
+
+	inbound, ok := worker.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("expected worker to receive the request")
+	}
+	if inbound.Content != "ping" || inbound.Metadata[MetadataReplyInbox] == "" {
+		t.Fatalf("inbound = %+v, want ping with reply_inbox", inbound)
+	}
+	if !worker.PublishOutbound(ctx, CarryTrace(inbound, OutboundMessage{Channel: "cli", Content: "pong"})) {
+		t.Fatal("expected reply to publish")
+	}
+
+	reply, err := pending.Wait(ctx)
+	if err != nil {
+		t.Fatalf("wait for reply: %v", err)
+	}
+	if reply.Content != "pong" || reply.Metadata[MetadataReplyTo] != pending.ReplyTo() {
+		t.Fatalf("reply = %+v, want pong for %s", reply, pending.ReplyTo())
+	}
+}
+
+func TestNATSRequestToReachesOnlyThatPeer(t *testing.T) {
+	server := newFakeNATS(t)
+	cfg := config.BusConfig{Backend: BackendNATS, URL: "nats://" + server.addr}
+
+	peers := make(map[string]*MessageBus, 3)
+	for i, inbox := range []string{"gw-a", "gw-b", "gw-c"} {
+		peer, err := OpenPeer(cfg, inbox)
+		if err != nil {
+			t.Fatalf("open peer %s: %v", inbox, err)
+		}
+		t.Cleanup(peer.Close)
+		peers[inbox] = peer
+		server.waitForSubscriptions(t, 3*(i+1))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pending, ok := peers["gw-a"].RequestTo(ctx, "gw-b", InboundMessage{Channel: "telegram", Content: "ping", SessionKey: "telegram:1"})
+	if !ok {
+		t.Fatal("expected request to publish")
+	}
+	defer pending.Close()
+
+	inbound, ok := peers["gw-b"].ConsumeInbound(ctx)
+	if !ok || inbound.Content != "ping" || inbound.Metadata[MetadataReplyInbox] != "gw-a" {
+		t.Fatalf("gw-b inbound = %+v, want ping from gw-a", inbound)
+	}
+	if !peers["gw-b"].PublishOutbound(ctx, CarryTrace(inbound, OutboundMessage{Channel: "telegram", Content: "pong"})) {
+		t.Fatal("expected reply to publish")
+	}
+	reply, err := pending.Wait(ctx)
+	if err != nil || reply.Content != "pong" {
+		t.Fatalf("reply = %+v, %v; want pong", reply, err)
+	}
+
+	idle, stop := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer stop()
+	if msg, ok := peers["gw-c"].ConsumeInbound(idle); ok {
+		t.Fatalf("gw-c received %+v, want nothing", msg)
+	}
+
+	if _, err := OpenPeer(config.BusConfig{Backend: BackendMemory}, "gw-a"); err == nil {
+		t.Fatal("OpenPeer should reject a bus that cannot reach other processes")
+	}
+}
+
+func TestRedisTransportRoutesRepliesAndRequestTo(t *testing.T) {
+	server := miniredis.RunT(t)
+	cfg := config.BusConfig{Backend: BackendRedis, URL: "redis://" + server.Addr()}
+
+	requester, err := OpenPeer(cfg, "gw-a")
+	if err != nil {
+		t.Fatalf("open requester bus: %v", err)
+	}
+	t.Cleanup(requester.Close)
+	worker, err := OpenPeer(cfg, "gw-b")
+	if err != nil {
+		t.Fatalf("open worker bus: %v", err)
+	}
+	t.Cleanup(worker.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pending, ok := requester.RequestTo(ctx, "gw-b", InboundMessage{Channel: "telegram", Content: "ping", SessionKey: "telegram:1"})
+	if !ok {
+		t.Fatal("expected request to publish")
+	}
+	defer pending.Close()
+
+	inbound, ok := worker.ConsumeInbound(ctx)
+	if !ok || inbound.Content != "ping" || inbound.Metadata[MetadataReplyInbox] != "gw-a" || inbound.Metadata[MetadataDeliveryID] == "" {
+		t.Fatalf("gw-b inbound = %+v, want ping from gw-a with a delivery ID", inbound)
+	}
+	if !worker.PublishOutbound(ctx, CarryTrace(inbound, OutboundMessage{Channel: "telegram", Content: "pong"})) {
+		t.Fatal("expected reply to publish")
+	}
+	reply, err := pending.Wait(ctx)
+	if err != nil || reply.Content != "pong" {
+		t.Fatalf("reply = %+v, %v; want pong", reply, err)
+	}
+}
+
+func TestRedisTransportSpeaksTLS(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t, t.TempDir())
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("load certificate: %v", err)
+	}
+	server := miniredis.NewMiniRedis()
+	if err := server.StartTLS(&tls.Config{Certificates: []tls.Certificate{certificate}}); err != nil {
+		t.Fatalf("start TLS redis: %v", err)
+	}
+	t.Cleanup(server.Close)
+
+	mb, err := Open(config.BusConfig{Backend: BackendRedis, URL: "rediss://" + server.Addr(), TLS: config.BusTLSConfig{CAFile: certFile}})
+	if err != nil {
+		t.Fatalf("open bus: %v", err)
+	}
+	t.Cleanup(mb.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if !mb.PublishInbound(ctx, InboundMessage{Channel: "cli", Content: "over tls", SessionKey: "s1"}) {
+		t.Fatal("expected publish over TLS")
+	}
+	if inbound, ok := mb.ConsumeInbound(ctx); !ok || inbound.Content != "over tls" {
+		t.Fatalf("inbound = %+v, %v; want the message sent over TLS", inbound, ok)
+	}
+}
+
+func TestOpenTransportRejectsHalfAClientCertificate(t *testing.T) {
+	cfg := config.BusConfig{Backend: BackendNATS, TLS: config.BusTLSConfig{CertFile: "client.pem"}}
+	if _, err := Open(cfg); err == nil || !strings.Contains(err.Error(), "set together") {
+		t.Fatalf("Open error = %v, want cert_file and key_file together", err)
+	}
+	cfg.TLS = config.BusTLSConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")}
+	if _, err := Open(cfg); err == nil || !strings.Contains(err.Error(), "bus.tls.ca_file") {
+		t.Fatalf("Open error = %v, want a ca_file error", err)
+	}
+}
+
+// writeTestCertificate writes a self-signed PEM certificate for 127.0.0.1 and
+// its key to dir.
+func writeTestCertificate(t *testing.T, dir string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "127.0.0.1"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		t.Fatalf("write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+
+	return certFile, keyFile
+}
+
+// fakeNATS is a minimal NATS server: it routes PUB to SUB by exact subject
+// and hands queue-group messages to the newest queue subscriber.
+type fakeNATS struct {
+	addr string
+
+	mu    sync.Mutex
+	subs  []fakeNATSSub
+	conns []net.Conn
+}
+
+type fakeNATSSub struct {
+	subject string
+	queue   string
+	sid     string
+	conn    *fakeNATSConn
+}
+
+type fakeNATSConn struct {
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func (c *fakeNATSConn) write(format string, args ...any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(c.conn, format, args...)
+}
+
+func newFakeNATS(t *testing.T) *fakeNATS {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	server := &fakeNATS{addr: listener.Addr().String()}
+	t.Cleanup(func() {
+		_ = listener.Close()
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		for _, conn := range server.conns {
+			_ = conn.Close()
+		}
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			server.mu.Lock()
+			server.conns = append(server.conns, conn)
+			server.mu.Unlock()
+			go server.serve(&fakeNATSConn{conn: conn})
+		}
+	}()
+
+	return server
+}
+
+func (s *fakeNATS) serve(conn *fakeNATSConn) {
+	conn.write("INFO {\"server_id\":\"fake\",\"max_payload\":1048576}\r\n")
+	reader := bufio.NewReader(conn.conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "PING":
+			conn.write("PONG\r\n")
+		case "SUB":
+			sub := fakeNATSSub{subject: fields[1], sid: fields[len(fields)-1], conn: conn}
+			if len(fields) == 4 {
+				sub.queue = fields[2]
+			}
+			s.mu.Lock()
+			s.subs = append(s.subs, sub)
+			s.mu.Unlock()
+		case "PUB":
+			size, _ := strconv.Atoi(fields[len(fields)-1])
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+			s.route(fields[1], payload[:size])
+		}
+	}
+}
+
+func (s *fakeNATS) route(subject string, payload []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queued := false
+	for i := len(s.subs) - 1; i >= 0; i-- {
+		sub := s.subs[i]
+		if sub.subject != subject || (sub.queue != "" && queued) {
+			continue
+		}
+		queued = queued || sub.queue != ""
+		sub.conn.write("MSG %s %s %d\r\n%s\r\n", subject, sub.sid, len(payload), payload)
+	}
+}
+
+func (s *fakeNATS) waitForSubscriptions(t *testing.T, want int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		got := len(s.subs)
+		s.mu.Unlock()
+		if got >= want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d subscriptions", want)
+}
@@ -2,6 +2,8 @@ package bus
 
 // InboundMessage is a normalized user/system message entering runtime processing.
 type InboundMessage struct {
+	// Topic selects the bus queue; empty is DefaultTopic.
+	Topic      string            `json:"topic,omitempty"`
 	Channel    string            `json:"channel"`
 	SenderID   string            `json:"sender_id"`
 	ChatID     string            `json:"chat_id"`
@@ -13,6 +15,8 @@ type InboundMessage struct {
 
 // OutboundMessage is a normalized message produced by runtime processing.
 type OutboundMessage struct {
+	// Topic selects the bus queue; empty is DefaultTopic.
+	Topic      string            `json:"topic,omitempty"`
 	Channel    string            `json:"channel"`
 	ChatID     string            `json:"chat_id"`
 	SessionKey string            `json:"session_key,omitempty"`
@@ -0,0 +1,164 @@
+package channel
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"miniclaw/pkg/config"
+)
+
+// OperatorCaller is the caller name of requests carrying the gateway.auth
+// bearer token.
+const OperatorCaller = "operator"
+
+// clientNamePattern limits gateway.auth.clients names to characters that are
+// safe in session keys.
+var clientNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Caller is who an authenticated request comes from.
+type Caller struct {
+	// Name identifies the caller in session keys: OperatorCaller, the basic
+	// auth username, or a gateway.auth.clients name. It is empty while
+	// gateway.auth is off.
+	Name string
+	// Operator is set for the gateway.auth token and basic credentials, which
+	// also open the status server, and for every request while gateway.auth
+	// is off.
+	Operator bool
+}
+
+// Auth checks the gateway.auth credentials of HTTP requests. The gateway's
+// status server and the channels that listen for HTTP share it, so one set of
+// credentials opens all of them.
+type Auth struct {
+	token    string
+	username string
+	password string
+	// clients maps gateway.auth.clients tokens to their names.
+	clients map[string]string
+}
+
+// NewAuth validates gateway.auth and builds its checker. Callers pass
+// config.GatewayConfig.AuthConfig so the deprecated gateway.approvals.token
+// still applies.
+func NewAuth(cfg config.GatewayAuthConfig) (*Auth, error) {
+	username := strings.TrimSpace(cfg.Username)
+	if (username == "") != (cfg.Password == "") {
+		return nil, errors.New("gateway.auth.username and gateway.auth.password must be set together")
+	}
+
+	auth := &Auth{token: strings.TrimSpace(cfg.Token), username: username, password: cfg.Password}
+	names := make(map[string]bool, len(cfg.Clients))
+	for index, client := range cfg.Clients {
+		name := strings.TrimSpace(client.Name)
+		token := strings.TrimSpace(client.Token)
+		switch {
+		case !clientNamePattern.MatchString(name):
+			return nil, fmt.Errorf("gateway.auth.clients[%d].name %q must be 1-64 letters, digits, \"_\", or \"-\"", index, client.Name)
+		case name == OperatorCaller || name == username || names[name]:
+			return nil, fmt.Errorf("gateway.auth.clients[%d].name %q is already in use", index, name)
+		case token == "":
+			return nil, fmt.Errorf("gateway.auth.clients[%d].token is required", index)
+		case token == auth.token || auth.clients[token] != "":
+			return nil, fmt.Errorf("gateway.auth.clients[%d].token is already in use", index)
+		}
+		if auth.clients == nil {
+			auth.clients = make(map[string]string, len(cfg.Clients))
+		}
+		auth.clients[token] = name
+		names[name] = true
+	}
+
+	return auth, nil
+}
+
+// Enabled reports whether gateway.auth sets any credential.
+func (a *Auth) Enabled() bool {
+	return a.token != "" || a.username != "" || len(a.clients) > 0
+}
+
+// Authenticate returns the caller r carries credentials for, and reports false
+// when they are missing or wrong. Every request is an unnamed operator while
+// gateway.auth is off.
+func (a *Auth) Authenticate(r *http.Request) (Caller, bool) {
+	if !a.Enabled() {
+		return Caller{Operator: true}, true
+	}
+	header := r.Header.Get("Authorization")
+	if a.token != "" && subtle.ConstantTimeCompare([]byte(header), []byte("Bearer "+a.token)) == 1 {
+		return Caller{Name: OperatorCaller, Operator: true}, true
+	}
+	if gotUser, gotPassword, ok := r.BasicAuth(); ok && a.username != "" {
+		userOK := subtle.ConstantTimeCompare([]byte(gotUser), []byte(a.username)) == 1
+		passwordOK := subtle.ConstantTimeCompare([]byte(gotPassword), []byte(a.password)) == 1
+		if userOK && passwordOK {
+			return Caller{Name: a.username, Operator: true}, true
+		}
+	}
+	// Compare against every client token so the time taken does not tell
+	// which one matched.
+	var caller Caller
+	for token, name := range a.clients {
+		if subtle.ConstantTimeCompare([]byte(header), []byte("Bearer "+token)) == 1 {
+			caller = Caller{Name: name}
+		}
+	}
+
+	return caller, caller.Name != ""
+}
+
+// Require passes operator requests to next and answers the rest with 401 and
+// a challenge for the configured scheme; gateway.auth.clients tokens do not
+// open it.
+func (a *Auth) Require(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if caller, ok := a.Authenticate(r); !ok || !caller.Operator {
+			a.Deny(w, http.StatusUnauthorized, "missing or invalid credentials")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Deny answers a request with statusCode and a JSON error body, adding a
+// challenge for the configured scheme to 401 replies.
+func (a *Auth) Deny(w http.ResponseWriter, statusCode int, message string) {
+	if statusCode == http.StatusUnauthorized {
+		challenge := `Bearer realm="miniclaw"`
+		if a.username != "" {
+			challenge = `Basic realm="miniclaw"`
+		}
+		w.Header().Set("WWW-Authenticate", challenge)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{message})
+}
+
+// ScopeChat returns the chat ID a caller's client-chosen key maps to: the key
+// itself while gateway.auth is off, and "<caller>:<key>" otherwise, so callers
+// cannot reach each other's sessions.
+func ScopeChat(caller Caller, key string) string {
+	if caller.Name == "" {
+		return key
+	}
+
+	return caller.Name + ":" + key
+}
+
+// IsLoopbackHost reports whether host only accepts local connections.
+func IsLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
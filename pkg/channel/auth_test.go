@@ -0,0 +1,70 @@
+package channel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"miniclaw/pkg/config"
+)
+
+func TestNewAuthRejectsHalfBasicCredential(t *testing.T) {
+	if _, err := NewAuth(config.GatewayAuthConfig{Username: "ops"}); err == nil {
+		t.Fatal("username without password must be rejected")
+	}
+	if _, err := NewAuth(config.GatewayAuthConfig{Token: "t"}); err != nil {
+		t.Fatalf("token only: %v", err)
+	}
+	for name, clients := range map[string][]config.GatewayAuthClientConfig{
+		"bad name":       {{Name: "ci bot", Token: "a"}},
+		"operator name":  {{Name: OperatorCaller, Token: "a"}},
+		"missing token":  {{Name: "ci"}},
+		"operator token": {{Name: "ci", Token: "t"}},
+		"duplicate name": {{Name: "ci", Token: "a"}, {Name: "ci", Token: "b"}},
+	} {
+		if _, err := NewAuth(config.GatewayAuthConfig{Token: "t", Clients: clients}); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestAuthAuthenticate(t *testing.T) {
+	auth, err := NewAuth(config.GatewayAuthConfig{
+		Token:    "secret",
+		Username: "ops",
+		Password: "hunter2",
+		Clients:  []config.GatewayAuthClientConfig{{Name: "ci", Token: "ci-token"}},
+	})
+	if err != nil {
+		t.Fatalf("NewAuth error: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if _, ok := auth.Authenticate(req); ok {
+		t.Fatal("request without credentials was accepted")
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	if caller, ok := auth.Authenticate(req); !ok || caller != (Caller{Name: OperatorCaller, Operator: true}) {
+		t.Fatalf("bearer = %+v, %v; want the operator", caller, ok)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth("ops", "hunter2")
+	if caller, ok := auth.Authenticate(req); !ok || caller != (Caller{Name: "ops", Operator: true}) {
+		t.Fatalf("basic = %+v, %v; want operator ops", caller, ok)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer ci-token")
+	if caller, ok := auth.Authenticate(req); !ok || caller != (Caller{Name: "ci"}) {
+		t.Fatalf("client = %+v, %v; want client ci", caller, ok)
+	}
+	recorder := httptest.NewRecorder()
+	auth.Require(http.NotFoundHandler()).ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("Require with a client token = %d, want 401", recorder.Code)
+	}
+
+	off, _ := NewAuth(config.GatewayAuthConfig{})
+	if caller, ok := off.Authenticate(httptest.NewRequest(http.MethodGet, "/", nil)); !ok || caller != (Caller{Operator: true}) {
+		t.Fatalf("auth off = %+v, %v; want every request accepted", caller, ok)
+	}
+}
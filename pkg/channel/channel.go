@@ -2,10 +2,116 @@ package channel
 
 import (
 	"context"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
 
+	agentruntime "miniclaw/pkg/agent/runtime"
 	"miniclaw/pkg/bus"
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+	fstools "miniclaw/pkg/tools/fs"
 )
 
+// StopCommand asks the gateway to cancel the prompts running for the sender's chat.
+const StopCommand = "/stop"
+
+// Command is a chat command the gateway answers itself instead of prompting
+// the agent.
+type Command struct {
+	// Name includes the leading slash, as in "/reset".
+	Name        string
+	Description string
+}
+
+// Commands lists the chat commands every channel supports, in the order
+// /help shows them.
+var Commands = []Command{
+	{Name: "/help", Description: "List commands and agents"},
+	{Name: "/reset", Description: "Start a new conversation"},
+	{Name: "/model", Description: "Show or switch this chat's agent"},
+	{Name: "/usage", Description: "Show token usage for this chat"},
+	{Name: StopCommand, Description: "Cancel the running reply"},
+}
+
+// IsStopCommand reports whether text is StopCommand, optionally addressed to a
+// bot as in "/stop@my_bot".
+func IsStopCommand(text string) bool {
+	name, args, ok := ParseCommand(text)
+	return ok && name == StopCommand && args == ""
+}
+
+// ParseCommand splits a "/name args" message into its lower-cased name and
+// trimmed arguments, dropping a bot address as in "/reset@my_bot". It reports
+// false for text that does not start with a slash.
+func ParseCommand(text string) (name string, args string, ok bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "/") {
+		return "", "", false
+	}
+	name = text
+	if end := strings.IndexFunc(text, unicode.IsSpace); end >= 0 {
+		name, args = text[:end], text[end:]
+	}
+	name, _, _ = strings.Cut(name, "@")
+
+	return strings.ToLower(name), strings.TrimSpace(args), name != "/"
+}
+
+// MetadataAttachments is the inbound metadata key listing the files a message
+// carried, one workspace-relative path per line.
+const MetadataAttachments = "attachments"
+
+// MetadataFiles is the outbound metadata key listing the workspace files to
+// deliver after the reply text, one absolute path per line; see
+// providertypes.FileOutbox.
+const MetadataFiles = "files"
+
+// Attachments returns the workspace-relative paths of the files inbound
+// carried; see MetadataAttachments.
+func Attachments(inbound bus.InboundMessage) []string {
+	var paths []string
+	for path := range strings.Lines(inbound.Metadata[MetadataAttachments]) {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths
+}
+
+// Files returns the absolute paths of the files outbound asks the channel to
+// deliver; see MetadataFiles.
+func Files(outbound bus.OutboundMessage) []string {
+	var paths []string
+	for path := range strings.Lines(outbound.Metadata[MetadataFiles]) {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths
+}
+
+// TraceInbound returns the trace ID of inbound, assigning a new one to its
+// metadata when the channel did not receive one from the client.
+//
+// Adapters call it at ingress so their logs and replies can name the same ID
+// the gateway passes to providers, tools, and events.
+func TraceInbound(inbound *bus.InboundMessage) string {
+	if traceID := strings.TrimSpace(inbound.Metadata[agentruntime.TraceIDKey]); traceID != "" {
+		return traceID
+	}
+	if inbound.Metadata == nil {
+		inbound.Metadata = make(map[string]string, 1)
+	}
+	traceID := providertypes.NewTraceID()
+	inbound.Metadata[agentruntime.TraceIDKey] = traceID
+
+	return traceID
+}
+
 // Handler processes one inbound channel message and returns an outbound reply.
 type Handler func(context.Context, bus.InboundMessage) (bus.OutboundMessage, error)
 
@@ -19,3 +125,72 @@ type Adapter interface {
 	// Run starts the adapter loop and blocks until context cancellation or fatal error.
 	Run(context.Context, Handler) error
 }
+
+// Notifier is implemented by adapters that can deliver messages without an
+// inbound trigger, to the chat named by the message's ChatID.
+//
+// Cron jobs, approval requests, and other runtime tasks use it to push
+// messages to a chat nobody is currently talking in. The gateway Service
+// implements it too, routing each message to the running adapter named by its
+// Channel.
+type Notifier interface {
+	Notify(context.Context, bus.OutboundMessage) error
+}
+
+// Reloader is implemented by adapters that can take changed settings while
+// running, such as sender allowlists.
+//
+// Reload applies what it can and returns an error naming the changed settings
+// that only take effect after a restart.
+type Reloader interface {
+	Reload(config.ChannelsConfig) error
+}
+
+// WorkspaceFunc returns the file service of the workspace of the agent
+// answering sessionKey, creating the workspace if needed. Its writes keep to
+// the workspace containment policy and disk quota the agent's tools use.
+type WorkspaceFunc func(sessionKey string) (*fstools.Service, error)
+
+// WorkspaceUser is implemented by adapters that save files where the agent's
+// tools can read them, such as Telegram photos and documents.
+//
+// The gateway calls UseWorkspaces before Run.
+type WorkspaceUser interface {
+	UseWorkspaces(WorkspaceFunc)
+}
+
+// HealthChecker is implemented by adapters that can probe their transport,
+// such as a Telegram bot token check.
+//
+// CheckHealth returns an error while the adapter cannot receive messages even
+// though Run has not returned, along with the time it last received one (zero
+// before the first).
+type HealthChecker interface {
+	CheckHealth(context.Context) (lastActivity time.Time, err error)
+}
+
+// WebhookReceiver is implemented by adapters that can receive updates on the
+// gateway HTTP server instead of fetching them, such as Telegram in webhook
+// mode.
+//
+// WebhookPath returns the path whose POST requests the gateway routes to
+// ServeHTTP while the adapter runs, or "" when it does not use a webhook. The
+// gateway's status authentication does not apply to these requests, so
+// ServeHTTP must check them itself.
+type WebhookReceiver interface {
+	WebhookPath() string
+	http.Handler
+}
+
+// PageServer is implemented by adapters that serve browser pages on the
+// gateway HTTP server, such as the WebSocket channel's web chat, so the pages
+// share its address and gateway.tls.
+//
+// PagePath returns the path prefix, ending in "/", whose requests the gateway
+// routes to ServeHTTP while the adapter runs, or "" when it serves no pages.
+// Browsers cannot add credentials to every request a page makes, such as a
+// WebSocket upgrade, so ServeHTTP checks gateway.auth itself.
+type PageServer interface {
+	PagePath() string
+	http.Handler
+}
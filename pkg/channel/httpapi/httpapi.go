@@ -0,0 +1,315 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	agentruntime "miniclaw/pkg/agent/runtime"
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/channel"
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+const channelName = "http"
+
+const (
+	defaultHost = "127.0.0.1"
+	defaultPort = 18791
+	// maxRequestBytes bounds the JSON body of one prompt request.
+	maxRequestBytes = 1 << 20
+	// traceHeader carries a client-chosen trace ID in and the request's trace
+	// ID back out.
+	traceHeader = "X-Request-Id"
+	// maxTraceIDLength bounds client trace IDs; longer ones are replaced.
+	maxTraceIDLength = 128
+	shutdownTimeout  = 5 * time.Second
+)
+
+// Adapter serves the HTTP chat API: scripts and other services POST a prompt
+// to /v1/prompt and get the reply back as JSON. Requests need the gateway.auth
+// credentials, and each caller's session keys are its own.
+type Adapter struct {
+	addr string
+	auth *channel.Auth
+	log  *slog.Logger
+}
+
+// promptRequest is the JSON body of POST /v1/prompt.
+type promptRequest struct {
+	// SessionKey names the conversation; requests with the same key share
+	// history.
+	SessionKey string            `json:"session_key"`
+	Prompt     string            `json:"prompt"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+}
+
+// promptResponse is the JSON reply to POST /v1/prompt.
+type promptResponse struct {
+	SessionKey string          `json:"session_key"`
+	TraceID    string          `json:"trace_id"`
+	Text       string          `json:"text"`
+	Agent      string          `json:"agent,omitempty"`
+	Usage      *usageResponse  `json:"usage,omitempty"`
+	ToolEvents []toolEventJSON `json:"tool_events,omitempty"`
+}
+
+type usageResponse struct {
+	InputTokens         int64 `json:"input_tokens"`
+	OutputTokens        int64 `json:"output_tokens"`
+	TotalTokens         int64 `json:"total_tokens"`
+	ReasoningTokens     int64 `json:"reasoning_tokens,omitempty"`
+	CacheCreationTokens int64 `json:"cache_creation_tokens,omitempty"`
+	CacheReadTokens     int64 `json:"cache_read_tokens,omitempty"`
+}
+
+type toolEventJSON struct {
+	Kind       string `json:"kind"`
+	Tool       string `json:"tool"`
+	Payload    string `json:"payload,omitempty"`
+	Failed     bool   `json:"failed,omitempty"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
+}
+
+// errorResponse is the JSON body of a failed request.
+type errorResponse struct {
+	Error         string `json:"error"`
+	ErrorCategory string `json:"error_category,omitempty"`
+	TraceID       string `json:"trace_id,omitempty"`
+}
+
+// NewAdapter validates HTTP channel configuration and constructs an adapter
+// that checks requests with auth. It refuses to listen beyond loopback while
+// auth is off.
+func NewAdapter(cfg config.HTTPChannelConfig, auth *channel.Auth, log *slog.Logger) (*Adapter, error) {
+	if auth == nil {
+		return nil, errors.New("auth is required")
+	}
+	addr, err := listenAddr(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if host, _, _ := net.SplitHostPort(addr); !auth.Enabled() && !channel.IsLoopbackHost(host) {
+		return nil, fmt.Errorf("channels.http.host %s is not loopback; set gateway.auth to accept remote clients", host)
+	}
+
+	if log == nil {
+		log = slog.Default()
+	}
+
+	return &Adapter{
+		addr: addr,
+		auth: auth,
+		log:  log.With("component", "channel.http"),
+	}, nil
+}
+
+// listenAddr resolves the listener address of cfg, applying the defaults.
+func listenAddr(cfg config.HTTPChannelConfig) (string, error) {
+	host := strings.TrimSpace(cfg.Host)
+	if host == "" {
+		host = defaultHost
+	}
+	port := cfg.Port
+	if port == 0 {
+		port = defaultPort
+	}
+	if port < 0 || port > 65535 {
+		return "", fmt.Errorf("channels.http.port %d is out of range", cfg.Port)
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(port)), nil
+}
+
+// Reload reports a changed listener address, which needs a restart; the
+// HTTP channel has no other settings.
+func (a *Adapter) Reload(cfg config.ChannelsConfig) error {
+	addr, err := listenAddr(cfg.HTTP)
+	if err != nil {
+		return err
+	}
+	if addr != a.addr {
+		return errors.New("channels.http.host and port changes need a restart")
+	}
+
+	return nil
+}
+
+// Name returns the channel identifier used in bus metadata and logs.
+func (a *Adapter) Name() string {
+	return channelName
+}
+
+// Run serves the chat API until ctx ends, passing each prompt to handler.
+func (a *Adapter) Run(ctx context.Context, handler channel.Handler) error {
+	if handler == nil {
+		return errors.New("handler is required")
+	}
+
+	listener, err := net.Listen("tcp", a.addr)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", a.addr, err)
+	}
+
+	server := &http.Server{
+		Handler:           a.routes(handler),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	a.log.Info("HTTP channel started", "address", listener.Addr().String())
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serve http channel: %w", err)
+	}
+
+	return nil
+}
+
+// routes returns the chat API handler.
+func (a *Adapter) routes(handler channel.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/prompt", a.handlePrompt(handler))
+	return mux
+}
+
+// handlePrompt runs one prompt request through handler and writes the reply.
+// The client's session key is scoped to the authenticated caller.
+func (a *Adapter) handlePrompt(handler channel.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		caller, ok := a.auth.Authenticate(r)
+		if !ok {
+			a.auth.Deny(w, http.StatusUnauthorized, "missing or invalid credentials")
+			return
+		}
+
+		var request promptRequest
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+		if err := decoder.Decode(&request); err != nil {
+			a.respondJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid JSON body: " + err.Error()})
+			return
+		}
+		key := strings.TrimSpace(request.SessionKey)
+		prompt := strings.TrimSpace(request.Prompt)
+		if key == "" || prompt == "" {
+			a.respondJSON(w, http.StatusBadRequest, errorResponse{Error: "session_key and prompt are required"})
+			return
+		}
+
+		chatID := channel.ScopeChat(caller, key)
+		inbound := bus.InboundMessage{
+			Channel:    channelName,
+			ChatID:     chatID,
+			SenderID:   caller.Name,
+			SessionKey: sessionKey(chatID),
+			Content:    prompt,
+			Metadata:   request.Metadata,
+		}
+		if traceID := strings.TrimSpace(r.Header.Get(traceHeader)); traceID != "" && len(traceID) <= maxTraceIDLength {
+			inbound.Metadata = withTraceID(inbound.Metadata, traceID)
+		}
+		traceID := channel.TraceInbound(&inbound)
+		w.Header().Set(traceHeader, traceID)
+		log := a.log.With("trace_id", traceID)
+		log.Info("Received message", "session_key", inbound.SessionKey, "remote_addr", r.RemoteAddr)
+
+		outbound, err := handler(r.Context(), inbound)
+		if err != nil {
+			category := providertypes.ErrorCategoryOf(err)
+			log.Error("Failed to process inbound message", "session_key", inbound.SessionKey, "error", err)
+			a.respondJSON(w, errorStatus(category), errorResponse{
+				Error:         providertypes.UserMessage(err),
+				ErrorCategory: string(category),
+				TraceID:       traceID,
+			})
+			return
+		}
+
+		response := newPromptResponse(key, outbound)
+		response.TraceID = traceID
+		a.respondJSON(w, http.StatusOK, response)
+	}
+}
+
+// newPromptResponse reads the reply text, usage, and tool events from outbound.
+func newPromptResponse(key string, outbound bus.OutboundMessage) promptResponse {
+	metadata := agentruntime.ReadMetadata(outbound)
+	response := promptResponse{
+		SessionKey: key,
+		Text:       outbound.Content,
+		Agent:      metadata.Agent(),
+	}
+	if usage := metadata.Usage(); usage != nil {
+		response.Usage = &usageResponse{
+			InputTokens:         usage.InputTokens,
+			OutputTokens:        usage.OutputTokens,
+			TotalTokens:         usage.TotalTokens,
+			ReasoningTokens:     usage.ReasoningTokens,
+			CacheCreationTokens: usage.CacheCreationTokens,
+			CacheReadTokens:     usage.CacheReadTokens,
+		}
+	}
+	for _, event := range metadata.ToolEvents() {
+		response.ToolEvents = append(response.ToolEvents, toolEventJSON(event))
+	}
+
+	return response
+}
+
+// withTraceID returns a copy of the client's request metadata with traceID set.
+func withTraceID(metadata map[string]string, traceID string) map[string]string {
+	traced := make(map[string]string, len(metadata)+1)
+	for key, value := range metadata {
+		traced[key] = value
+	}
+	traced[agentruntime.TraceIDKey] = traceID
+
+	return traced
+}
+
+// errorStatus maps a prompt error category to the HTTP status of its reply.
+func errorStatus(category providertypes.ErrorCategory) int {
+	switch category {
+	case providertypes.ErrorRateLimit, providertypes.ErrorQueueFull, providertypes.ErrorBudgetExhausted:
+		return http.StatusTooManyRequests
+	case providertypes.ErrorProviderDown, providertypes.ErrorCircuitOpen:
+		return http.StatusServiceUnavailable
+	case providertypes.ErrorTimeout:
+		return http.StatusGatewayTimeout
+	case providertypes.ErrorRequestTooLarge, providertypes.ErrorContextLength:
+		return http.StatusRequestEntityTooLarge
+	case providertypes.ErrorCanceled:
+		return http.StatusConflict
+	case providertypes.ErrorRejected:
+		return http.StatusForbidden
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+// respondJSON writes payload as the JSON body of a reply.
+func (a *Adapter) respondJSON(w http.ResponseWriter, statusCode int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		a.log.Error("Failed to write API response", "error", err)
+	}
+}
+
+// sessionKey maps one scoped chat ID to one runtime session namespace.
+func sessionKey(key string) string {
+	return channelName + ":" + strings.TrimSpace(key)
+}
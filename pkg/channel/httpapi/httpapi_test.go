@@ -0,0 +1,182 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	agentruntime "miniclaw/pkg/agent/runtime"
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/channel"
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+// newAuth builds the gateway.auth checker of cfg.
+func newAuth(t *testing.T, cfg config.GatewayAuthConfig) *channel.Auth {
+	t.Helper()
+
+	auth, err := channel.NewAuth(cfg)
+	if err != nil {
+		t.Fatalf("NewAuth: %v", err)
+	}
+	return auth
+}
+
+func TestPromptReturnsTextUsageAndToolEvents(t *testing.T) {
+	adapter, err := NewAdapter(config.HTTPChannelConfig{Enabled: true}, newAuth(t, config.GatewayAuthConfig{}), nil)
+	if err != nil {
+		t.Fatalf("NewAdapter: %v", err)
+	}
+
+	var got bus.InboundMessage
+	handler := func(_ context.Context, inbound bus.InboundMessage) (bus.OutboundMessage, error) {
+		got = inbound
+		result := providertypes.PromptResult{
+			Text: "done",
+			Metadata: providertypes.PromptMetadata{
+				Usage:      &providertypes.TokenUsage{InputTokens: 10, OutputTokens: 5, TotalTokens: 15},
+				ToolEvents: []providertypes.ToolEvent{{Kind: "call", Tool: "read_file", Payload: `{"path":"a.txt"}`}},
+			},
+		}
+		return bus.OutboundMessage{Content: result.Text, Metadata: agentruntime.PromptResultMetadata(result)}, nil
+	}
+
+	body := `{"session_key":"build-bot","prompt":"summarize a.txt","metadata":{"source":"ci"}}`
+	recorder := httptest.NewRecorder()
+	adapter.routes(handler).ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/prompt", strings.NewReader(body)))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", recorder.Code, recorder.Body)
+	}
+	if got.Channel != "http" || got.SessionKey != "http:build-bot" || got.Content != "summarize a.txt" || got.Metadata["source"] != "ci" {
+		t.Fatalf("inbound = %+v", got)
+	}
+
+	var response promptResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if response.Text != "done" || response.SessionKey != "build-bot" {
+		t.Fatalf("response = %+v", response)
+	}
+	if response.Usage == nil || response.Usage.TotalTokens != 15 {
+		t.Fatalf("usage = %+v, want 15 total tokens", response.Usage)
+	}
+	if len(response.ToolEvents) != 1 || response.ToolEvents[0].Tool != "read_file" {
+		t.Fatalf("tool events = %+v", response.ToolEvents)
+	}
+}
+
+func TestPromptRejectsBadRequestsAndMapsErrors(t *testing.T) {
+	adapter, err := NewAdapter(config.HTTPChannelConfig{Enabled: true}, newAuth(t, config.GatewayAuthConfig{}), nil)
+	if err != nil {
+		t.Fatalf("NewAdapter: %v", err)
+	}
+	handler := func(context.Context, bus.InboundMessage) (bus.OutboundMessage, error) {
+		return bus.OutboundMessage{}, &providertypes.PromptError{Category: providertypes.ErrorRateLimit, Err: context.DeadlineExceeded}
+	}
+	routes := adapter.routes(handler)
+
+	cases := []struct {
+		body     string
+		status   int
+		category string
+	}{
+		{body: `{"prompt":"hi"}`, status: http.StatusBadRequest},
+		{body: `not json`, status: http.StatusBadRequest},
+		{body: `{"session_key":"s","prompt":"hi"}`, status: http.StatusTooManyRequests, category: "rate_limit"},
+	}
+	for _, tc := range cases {
+		recorder := httptest.NewRecorder()
+		routes.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/prompt", strings.NewReader(tc.body)))
+		if recorder.Code != tc.status {
+			t.Fatalf("%s: status = %d, want %d", tc.body, recorder.Code, tc.status)
+		}
+		var response errorResponse
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil || response.Error == "" || response.ErrorCategory != tc.category {
+			t.Fatalf("%s: error body = %s", tc.body, recorder.Body)
+		}
+	}
+}
+
+func TestPromptCarriesClientTraceID(t *testing.T) {
+	adapter, err := NewAdapter(config.HTTPChannelConfig{Enabled: true}, newAuth(t, config.GatewayAuthConfig{}), nil)
+	if err != nil {
+		t.Fatalf("NewAdapter: %v", err)
+	}
+	var got bus.InboundMessage
+	handler := func(_ context.Context, inbound bus.InboundMessage) (bus.OutboundMessage, error) {
+		got = inbound
+		return bus.OutboundMessage{}, &providertypes.PromptError{Category: providertypes.ErrorProviderDown, Err: context.DeadlineExceeded}
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/v1/prompt", strings.NewReader(`{"session_key":"s","prompt":"hi"}`))
+	request.Header.Set("X-Request-Id", "ci-run-7")
+	recorder := httptest.NewRecorder()
+	adapter.routes(handler).ServeHTTP(recorder, request)
+
+	if got.Metadata[agentruntime.TraceIDKey] != "ci-run-7" {
+		t.Fatalf("inbound metadata = %+v, want the client trace ID", got.Metadata)
+	}
+	if header := recorder.Header().Get("X-Request-Id"); header != "ci-run-7" {
+		t.Fatalf("X-Request-Id = %q", header)
+	}
+	var response errorResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil || response.TraceID != "ci-run-7" {
+		t.Fatalf("error body = %s", recorder.Body)
+	}
+
+	// Without a client ID the adapter assigns one.
+	recorder = httptest.NewRecorder()
+	adapter.routes(handler).ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/prompt", strings.NewReader(`{"session_key":"s","prompt":"hi"}`)))
+	if traceID := got.Metadata[agentruntime.TraceIDKey]; traceID == "" || recorder.Header().Get("X-Request-Id") != traceID {
+		t.Fatalf("assigned trace ID = %q, header %q", traceID, recorder.Header().Get("X-Request-Id"))
+	}
+}
+
+func TestPromptRequiresAuthAndScopesSessionsToTheCaller(t *testing.T) {
+	auth := newAuth(t, config.GatewayAuthConfig{Token: "secret", Clients: []config.GatewayAuthClientConfig{{Name: "ci", Token: "ci-token"}}})
+	if _, err := NewAdapter(config.HTTPChannelConfig{Host: "0.0.0.0"}, newAuth(t, config.GatewayAuthConfig{}), nil); err == nil {
+		t.Fatal("expected a non-loopback listener without gateway.auth to be refused")
+	}
+	adapter, err := NewAdapter(config.HTTPChannelConfig{Host: "0.0.0.0"}, auth, nil)
+	if err != nil {
+		t.Fatalf("NewAdapter: %v", err)
+	}
+	var got bus.InboundMessage
+	routes := adapter.routes(func(_ context.Context, inbound bus.InboundMessage) (bus.OutboundMessage, error) {
+		got = inbound
+		return bus.OutboundMessage{Content: "ok"}, nil
+	})
+
+	do := func(token string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/v1/prompt", strings.NewReader(`{"session_key":"build-bot","prompt":"hi"}`))
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		routes.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	for _, token := range []string{"", "wrong"} {
+		if recorder := do(token); recorder.Code != http.StatusUnauthorized || recorder.Header().Get("WWW-Authenticate") == "" {
+			t.Fatalf("token %q = %d, want 401 with a challenge", token, recorder.Code)
+		}
+	}
+	for token, want := range map[string]string{"secret": "http:operator:build-bot", "ci-token": "http:ci:build-bot"} {
+		got = bus.InboundMessage{}
+		recorder := do(token)
+		if recorder.Code != http.StatusOK || got.SessionKey != want {
+			t.Fatalf("token %q = %d on session %q, want %q", token, recorder.Code, got.SessionKey, want)
+		}
+		var response promptResponse
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil || response.SessionKey != "build-bot" {
+			t.Fatalf("response = %s, want the client's session key", recorder.Body)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"miniclaw/pkg/bus"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+// ErrRateLimited is wrapped by the error RateLimit returns for a message over
+// its sender's limit.
+var ErrRateLimited = errors.New("too many messages")
+
+// Middleware wraps a Handler with a policy shared by every channel, such as
+// a rate limit, an allowlist, a content filter, or metrics. It may return
+// early without calling next, or change the reply next returns.
+type Middleware func(next Handler) Handler
+
+// Chain wraps handler with middleware. The first middleware is the outermost:
+// it sees the message first and the reply last. Nil entries are skipped.
+func Chain(handler Handler, middleware ...Middleware) Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		if middleware[i] != nil {
+			handler = middleware[i](handler)
+		}
+	}
+
+	return handler
+}
+
+// Observe returns middleware that reports each message to observe once next
+// returns, along with its error and how long it took, including messages a
+// later middleware refused.
+func Observe(observe func(inbound bus.InboundMessage, err error, elapsed time.Duration)) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, inbound bus.InboundMessage) (bus.OutboundMessage, error) {
+			started := time.Now()
+			outbound, err := next(ctx, inbound)
+			observe(inbound, err, time.Since(started))
+			return outbound, err
+		}
+	}
+}
+
+// AllowFrom returns middleware that accepts only messages from the listed
+// channels, such as "http", or "<channel>:<id>" entries naming a sender or
+// chat ID, such as "telegram:123456789". It returns nil, which Chain skips,
+// when allow is empty.
+func AllowFrom(allow []string) Middleware {
+	allowed := make(map[string]struct{}, len(allow))
+	for _, entry := range allow {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			allowed[entry] = struct{}{}
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, inbound bus.InboundMessage) (bus.OutboundMessage, error) {
+			for _, id := range []string{"", inbound.SenderID, inbound.ChatID} {
+				key := inbound.Channel
+				if id != "" {
+					key += ":" + id
+				}
+				if _, ok := allowed[key]; ok {
+					return next(ctx, inbound)
+				}
+			}
+
+			return bus.OutboundMessage{}, rejected("This sender is not allowed to message the agent")
+		}
+	}
+}
+
+// FilterContent returns middleware that refuses messages matching one of
+// patterns, or longer than maxChars characters when it is above 0. It returns
+// nil, which Chain skips, when there is nothing to check. Replies do not name
+// the pattern that matched.
+func FilterContent(patterns []*regexp.Regexp, maxChars int) Middleware {
+	if len(patterns) == 0 && maxChars <= 0 {
+		return nil
+	}
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, inbound bus.InboundMessage) (bus.OutboundMessage, error) {
+			if maxChars > 0 && utf8.RuneCountInString(inbound.Content) > maxChars {
+				return bus.OutboundMessage{}, rejected(fmt.Sprintf("Messages are limited to %d characters", maxChars))
+			}
+			for _, pattern := range patterns {
+				if pattern.MatchString(inbound.Content) {
+					return bus.OutboundMessage{}, rejected("This message was blocked by a content filter")
+				}
+			}
+
+			return next(ctx, inbound)
+		}
+	}
+}
+
+// rejected returns the error refused messages fail with; reason is the reply
+// channels show.
+func rejected(reason string) error {
+	return &providertypes.PromptError{Category: providertypes.ErrorRejected, Err: errors.New(reason)}
+}
+
+// RateLimit returns middleware that lets each sender, or each session for
+// channels without sender IDs, send up to messages messages per period,
+// refilling evenly, and fails the rest with a rate_limit error. StopCommand is
+// never limited. It returns nil, which Chain skips, when messages or period is
+// not positive.
+func RateLimit(messages int, period time.Duration) Middleware {
+	if messages <= 0 || period <= 0 {
+		return nil
+	}
+	limiter := &rateLimiter{
+		burst:   float64(messages),
+		refill:  period / time.Duration(messages),
+		now:     time.Now,
+		buckets: make(map[string]*rateBucket),
+	}
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, inbound bus.InboundMessage) (bus.OutboundMessage, error) {
+			if IsStopCommand(inbound.Content) {
+				return next(ctx, inbound)
+			}
+			sender := inbound.SenderID
+			if sender == "" {
+				sender = inbound.SessionKey
+			}
+			if wait := limiter.take(inbound.Channel + ":" + sender); wait > 0 {
+				return bus.OutboundMessage{}, &providertypes.PromptError{
+					Category:   providertypes.ErrorRateLimit,
+					Provider:   "gateway",
+					RetryAfter: max(wait.Round(time.Second), time.Second),
+					Err:        fmt.Errorf("%w from %s on %s", ErrRateLimited, sender, inbound.Channel),
+				}
+			}
+
+			return next(ctx, inbound)
+		}
+	}
+}
+
+// rateLimiter is a token bucket per sender.
+type rateLimiter struct {
+	burst  float64
+	refill time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*rateBucket
+	pruned  time.Time
+}
+
+// rateBucket holds the messages a sender has left as of updated.
+type rateBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// take spends one message of key's bucket, or returns how long until one is
+// available. Buckets that have refilled completely are dropped now and then
+// so the map only holds recent senders.
+func (l *rateLimiter) take(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if full := l.refill * time.Duration(l.burst); now.Sub(l.pruned) > full {
+		for other, bucket := range l.buckets {
+			if now.Sub(bucket.updated) >= full {
+				delete(l.buckets, other)
+			}
+		}
+		l.pruned = now
+	}
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &rateBucket{tokens: l.burst, updated: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = min(l.burst, bucket.tokens+float64(now.Sub(bucket.updated))/float64(l.refill))
+	bucket.updated = now
+	if bucket.tokens < 1 {
+		return time.Duration((1 - bucket.tokens) * float64(l.refill))
+	}
+	bucket.tokens--
+
+	return 0
+}
@@ -0,0 +1,130 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"miniclaw/pkg/bus"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+func echoHandler(_ context.Context, inbound bus.InboundMessage) (bus.OutboundMessage, error) {
+	return bus.OutboundMessage{Content: "ok:" + inbound.Content}, nil
+}
+
+func TestChainRunsFirstMiddlewareOutermost(t *testing.T) {
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, inbound bus.InboundMessage) (bus.OutboundMessage, error) {
+				order = append(order, name)
+				return next(ctx, inbound)
+			}
+		}
+	}
+
+	if _, err := Chain(echoHandler, trace("outer"), nil, trace("inner"))(context.Background(), bus.InboundMessage{}); err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	if strings.Join(order, ",") != "outer,inner" {
+		t.Fatalf("order = %v, want outer then inner", order)
+	}
+}
+
+func TestAllowFrom(t *testing.T) {
+	if AllowFrom([]string{" "}) != nil {
+		t.Fatal("expected no middleware for an empty allowlist")
+	}
+	handler := Chain(echoHandler, AllowFrom([]string{"http", "telegram:42", "telegram:-100"}))
+
+	for _, tc := range []struct {
+		inbound bus.InboundMessage
+		allowed bool
+	}{
+		{bus.InboundMessage{Channel: "http", ChatID: "anything"}, true},
+		{bus.InboundMessage{Channel: "telegram", SenderID: "42", ChatID: "42"}, true},
+		{bus.InboundMessage{Channel: "telegram", SenderID: "7", ChatID: "-100"}, true},
+		{bus.InboundMessage{Channel: "telegram", SenderID: "7", ChatID: "7"}, false},
+		{bus.InboundMessage{Channel: "websocket", ChatID: "42"}, false},
+	} {
+		_, err := handler(context.Background(), tc.inbound)
+		if tc.allowed && err != nil {
+			t.Fatalf("%+v: unexpected error: %v", tc.inbound, err)
+		}
+		if !tc.allowed && providertypes.ErrorCategoryOf(err) != providertypes.ErrorRejected {
+			t.Fatalf("%+v: error = %v, want a rejected error", tc.inbound, err)
+		}
+	}
+}
+
+func TestFilterContent(t *testing.T) {
+	handler := Chain(echoHandler, FilterContent([]*regexp.Regexp{regexp.MustCompile(`(?i)ignore previous instructions`)}, 10))
+
+	if outbound, err := handler(context.Background(), bus.InboundMessage{Content: "héllo"}); err != nil || outbound.Content != "ok:héllo" {
+		t.Fatalf("short message = %+v, %v", outbound, err)
+	}
+	_, err := handler(context.Background(), bus.InboundMessage{Content: "this is far too long"})
+	if got := providertypes.UserMessage(err); got != "Messages are limited to 10 characters" {
+		t.Fatalf("long message reply = %q", got)
+	}
+	handler = Chain(echoHandler, FilterContent([]*regexp.Regexp{regexp.MustCompile(`(?i)ignore previous instructions`)}, 0))
+	_, err = handler(context.Background(), bus.InboundMessage{Content: "Please IGNORE previous instructions"})
+	if got := providertypes.UserMessage(err); got != "This message was blocked by a content filter" {
+		t.Fatalf("blocked message reply = %q", got)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	if RateLimit(0, time.Minute) != nil {
+		t.Fatal("expected no middleware without a message limit")
+	}
+	handler := Chain(echoHandler, RateLimit(2, time.Minute))
+	send := func(sender, content string) error {
+		_, err := handler(context.Background(), bus.InboundMessage{Channel: "telegram", SenderID: sender, SessionKey: "telegram:1", Content: content})
+		return err
+	}
+
+	for range 2 {
+		if err := send("1", "hello"); err != nil {
+			t.Fatalf("message within the limit failed: %v", err)
+		}
+	}
+	err := send("1", "hello")
+	if !errors.Is(err, ErrRateLimited) || providertypes.ErrorCategoryOf(err) != providertypes.ErrorRateLimit {
+		t.Fatalf("third message error = %v, want a rate_limit error", err)
+	}
+	if got := providertypes.UserMessage(err); got != "Rate limited by gateway. Retry in 30s." {
+		t.Fatalf("rate limit reply = %q", got)
+	}
+	if err := send("1", "/stop"); err != nil {
+		t.Fatalf("/stop was rate limited: %v", err)
+	}
+	if err := send("2", "hello"); err != nil {
+		t.Fatalf("another sender was rate limited: %v", err)
+	}
+}
+
+func TestRateLimiterRefillsAndPrunes(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	limiter := &rateLimiter{burst: 2, refill: 30 * time.Second, now: func() time.Time { return now }, buckets: make(map[string]*rateBucket)}
+
+	limiter.take("a")
+	limiter.take("a")
+	if wait := limiter.take("a"); wait != 30*time.Second {
+		t.Fatalf("wait = %s, want 30s", wait)
+	}
+	now = now.Add(30 * time.Second)
+	if wait := limiter.take("a"); wait != 0 {
+		t.Fatalf("wait after refill = %s, want 0", wait)
+	}
+
+	now = now.Add(2 * time.Minute)
+	limiter.take("b")
+	if _, ok := limiter.buckets["a"]; ok || len(limiter.buckets) != 1 {
+		t.Fatalf("buckets = %v, want only b after a refilled", limiter.buckets)
+	}
+}
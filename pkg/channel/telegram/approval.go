@@ -0,0 +1,180 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	providertypes "miniclaw/pkg/provider/types"
+
+	"github.com/mymmrac/telego"
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+const (
+	approveCallbackPrefix = "approve:"
+	denyCallbackPrefix    = "deny:"
+
+	approvalInputPreviewLimit = 600
+)
+
+// approvalRegistry tracks tool approvals waiting for an inline-keyboard answer.
+type approvalRegistry struct {
+	mu      sync.Mutex
+	next    uint64
+	pending map[string]pendingApproval
+}
+
+type pendingApproval struct {
+	chatID int64
+	reply  chan bool
+}
+
+func newApprovalRegistry() *approvalRegistry {
+	return &approvalRegistry{pending: make(map[string]pendingApproval)}
+}
+
+// register opens an approval for chatID and returns its callback ID and answer channel.
+func (r *approvalRegistry) register(chatID int64) (string, <-chan bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.next++
+	id := strconv.FormatUint(r.next, 10)
+	reply := make(chan bool, 1)
+	r.pending[id] = pendingApproval{chatID: chatID, reply: reply}
+
+	return id, reply
+}
+
+func (r *approvalRegistry) remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pending, id)
+}
+
+// resolve delivers one button press.
+//
+// It reports ok=false for malformed data, requests that are no longer
+// pending, and presses from a chat other than the one that was asked.
+func (r *approvalRegistry) resolve(data string, chatID int64) (approved bool, ok bool) {
+	id, approved := strings.CutPrefix(data, approveCallbackPrefix)
+	if !approved {
+		var isDeny bool
+		if id, isDeny = strings.CutPrefix(data, denyCallbackPrefix); !isDeny {
+			return false, false
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pending, found := r.pending[id]
+	if !found || pending.chatID != chatID {
+		return false, false
+	}
+	delete(r.pending, id)
+	pending.reply <- approved
+
+	return approved, true
+}
+
+// toolApprover asks for tool approval in chatID, and forum topic threadID
+// when it is not 0, with Approve/Deny buttons.
+func (a *Adapter) toolApprover(bot *telego.Bot, chatID int64, threadID int) providertypes.ToolApprover {
+	return func(ctx context.Context, request providertypes.ToolApprovalRequest) (bool, error) {
+		id, reply := a.approvals.register(chatID)
+		defer a.approvals.remove(id)
+
+		text := approvalText(request)
+		keyboard := tu.InlineKeyboard(tu.InlineKeyboardRow(
+			tu.InlineKeyboardButton("✅ Approve").WithCallbackData(approveCallbackPrefix+id),
+			tu.InlineKeyboardButton("🚫 Deny").WithCallbackData(denyCallbackPrefix+id),
+		))
+		a.log.Info("Requesting tool approval", "chat_id", chatID, "tool", request.Tool, "approval_id", id)
+		sent, err := bot.SendMessage(ctx, tu.Message(tu.ID(chatID), text).WithReplyMarkup(keyboard).WithMessageThreadID(threadID))
+		if err != nil {
+			return false, fmt.Errorf("send approval request: %w", err)
+		}
+
+		select {
+		case approved := <-reply:
+			return approved, nil
+		case <-ctx.Done():
+			// The request context is gone, so use a short detached one to retire the buttons.
+			editCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			a.closeApprovalMessage(editCtx, bot, chatID, sent.MessageID, text, "⌛ Expired without an answer")
+			return false, ctx.Err()
+		}
+	}
+}
+
+// handleCallback applies an Approve/Deny button press.
+func (a *Adapter) handleCallback(ctx context.Context, bot *telego.Bot, query *telego.CallbackQuery) {
+	answer := "This request is no longer pending."
+	defer func() {
+		if err := bot.AnswerCallbackQuery(ctx, tu.CallbackQuery(query.ID).WithText(answer)); err != nil {
+			a.log.Debug("Failed to answer callback query", "error", err)
+		}
+	}()
+
+	senderID := strconv.FormatInt(query.From.ID, 10)
+	if !a.senderAllowed(senderID) {
+		a.log.Debug("Ignoring callback from unauthorized sender", "sender_id", senderID)
+		answer = "You are not allowed to approve tools."
+		return
+	}
+	if query.Message == nil {
+		return
+	}
+
+	chatID := query.Message.GetChat().ID
+	approved, ok := a.approvals.resolve(query.Data, chatID)
+	if !ok {
+		return
+	}
+
+	outcome := "🚫 Denied"
+	answer = "Denied"
+	if approved {
+		outcome = "✅ Approved"
+		answer = "Approved"
+	}
+	a.log.Info("Tool approval answered", "chat_id", chatID, "sender_id", senderID, "approved", approved)
+
+	text := ""
+	if message := query.Message.Message(); message != nil {
+		text = message.Text
+	}
+	a.closeApprovalMessage(ctx, bot, chatID, query.Message.GetMessageID(), text, outcome)
+}
+
+// closeApprovalMessage replaces the buttons with the final outcome.
+func (a *Adapter) closeApprovalMessage(ctx context.Context, bot *telego.Bot, chatID int64, messageID int, text string, outcome string) {
+	params := &telego.EditMessageTextParams{
+		ChatID:    tu.ID(chatID),
+		MessageID: messageID,
+		Text:      strings.TrimSpace(text + "\n\n" + outcome),
+	}
+	if _, err := bot.EditMessageText(ctx, params); err != nil {
+		a.log.Debug("Failed to update approval message", "chat_id", chatID, "error", err)
+	}
+}
+
+// approvalText renders the question shown above the Approve/Deny buttons.
+func approvalText(request providertypes.ToolApprovalRequest) string {
+	text := fmt.Sprintf("🔐 Allow %s?", request.Tool)
+	input := strings.TrimSpace(request.Input)
+	if runes := []rune(input); len(runes) > approvalInputPreviewLimit {
+		input = string(runes[:approvalInputPreviewLimit]) + "..."
+	}
+	if input != "" {
+		text += "\n\n" + input
+	}
+
+	return text
+}
@@ -0,0 +1,201 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"miniclaw/pkg/channel"
+	fstools "miniclaw/pkg/tools/fs"
+
+	"github.com/mymmrac/telego"
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// defaultMaxFileSizeMB bounds one received file when
+// channels.telegram.files.max_size_mb is unset; the Bot API serves files up
+// to 20 MB.
+const defaultMaxFileSizeMB = 20
+
+// maxUploadSizeMB is the Bot API limit on a file a bot sends.
+const maxUploadSizeMB = 50
+
+// uploadsDir is the workspace directory received files are saved under, one
+// subdirectory per chat.
+const uploadsDir = "uploads"
+
+// attachment is one photo or document of a message.
+type attachment struct {
+	fileID string
+	name   string
+	size   int64
+}
+
+// UseWorkspaces sets how the adapter finds a chat's session workspace, where
+// it saves received files.
+func (a *Adapter) UseWorkspaces(workspaces channel.WorkspaceFunc) {
+	a.workspaceMu.Lock()
+	defer a.workspaceMu.Unlock()
+
+	a.workspaces = workspaces
+}
+
+// messageAttachments returns the largest size of a message's photo and its
+// document, if any.
+func messageAttachments(message *telego.Message) []attachment {
+	var attachments []attachment
+	if len(message.Photo) > 0 {
+		photo := message.Photo[len(message.Photo)-1]
+		attachments = append(attachments, attachment{
+			fileID: photo.FileID,
+			name:   "photo-" + photo.FileUniqueID + ".jpg",
+			size:   int64(photo.FileSize),
+		})
+	}
+	if document := message.Document; document != nil {
+		name := document.FileName
+		if strings.TrimSpace(name) == "" {
+			name = "document-" + document.FileUniqueID
+		}
+		attachments = append(attachments, attachment{fileID: document.FileID, name: name, size: document.FileSize})
+	}
+
+	return attachments
+}
+
+// saveAttachments downloads attachments into uploads/telegram-<chat_id> of
+// the session workspace and returns their workspace-relative paths. Files
+// over channels.telegram.files.max_size_mb are refused before downloading,
+// and the workspace's containment policy and disk quota apply to each save.
+func (a *Adapter) saveAttachments(ctx context.Context, bot *telego.Bot, sessionKey string, chatID int64, attachments []attachment) ([]string, error) {
+	a.workspaceMu.RLock()
+	workspaces := a.workspaces
+	a.workspaceMu.RUnlock()
+	if workspaces == nil {
+		return nil, errors.New("no workspace is available for received files")
+	}
+
+	files, err := workspaces(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(uploadsDir, channelName+"-"+strconv.FormatInt(chatID, 10))
+
+	maxBytes := int64(a.cfg.Files.MaxSizeMB) << 20
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxFileSizeMB << 20
+	}
+
+	paths := make([]string, 0, len(attachments))
+	for _, file := range attachments {
+		if file.size > maxBytes {
+			return paths, fmt.Errorf("%s is %d MB; files are limited to %d MB", file.name, file.size>>20, maxBytes>>20)
+		}
+		// Nanoseconds keep a file sent twice in a row from colliding with
+		// the first copy.
+		path := filepath.Join(dir, fmt.Sprintf("%d-%s", time.Now().UnixNano(), safeFileName(file.name)))
+		if err := downloadFile(ctx, bot, files, file, path, maxBytes); err != nil {
+			return paths, fmt.Errorf("save %s: %w", file.name, err)
+		}
+		paths = append(paths, filepath.ToSlash(path))
+	}
+
+	return paths, nil
+}
+
+// downloadFile saves the Telegram file of file to the workspace path of
+// files, failing once it grows past maxBytes.
+func downloadFile(ctx context.Context, bot *telego.Bot, files *fstools.Service, file attachment, path string, maxBytes int64) error {
+	info, err := bot.GetFile(ctx, &telego.GetFileParams{FileID: file.fileID})
+	if err != nil {
+		return err
+	}
+	if info.FileSize > maxBytes {
+		return fmt.Errorf("file is %d MB; files are limited to %d MB", info.FileSize>>20, maxBytes>>20)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, bot.FileDownloadURL(info.FilePath), nil)
+	if err != nil {
+		return err
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("download: %w", err)
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("download: %s", response.Status)
+	}
+
+	// The Bot API may leave file_size out; the quota then counts the limit.
+	size := info.FileSize
+	if size <= 0 {
+		size = maxBytes
+	}
+	_, err = files.SaveFile(ctx, path, response.Body, size)
+	return err
+}
+
+// safeFileName reduces a sender-chosen file name to one path element without
+// separators or a leading dot.
+func safeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r < ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.TrimLeft(strings.TrimSpace(name), ".")
+	if name == "" {
+		return "file"
+	}
+
+	return name
+}
+
+// sendFiles sends each file of paths to chatID, in forum topic threadID when
+// it is not 0, as a document after the reply text. A file over the Bot API
+// upload limit, or one that fails to send, is reported in the chat instead.
+func (a *Adapter) sendFiles(ctx context.Context, bot *telego.Bot, chatID int64, threadID int, paths []string) error {
+	var errs []error
+	for _, path := range paths {
+		err := sendDocument(ctx, bot, chatID, threadID, path)
+		if err == nil {
+			continue
+		}
+		a.log.Error("Failed to send telegram file", "chat_id", chatID, "path", path, "error", err)
+		errs = append(errs, fmt.Errorf("send %s: %w", filepath.Base(path), err))
+		if sendErr := a.sendText(ctx, bot, chatID, threadID, "Could not send "+filepath.Base(path)+": "+err.Error()); sendErr != nil {
+			errs = append(errs, sendErr)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// sendDocument uploads the file at path to chatID and threadID, refusing
+// files over maxUploadSizeMB before reading them.
+func sendDocument(ctx context.Context, bot *telego.Bot, chatID int64, threadID int, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() > maxUploadSizeMB<<20 {
+		return fmt.Errorf("file is %d MB; bots can send files up to %d MB", info.Size()>>20, maxUploadSizeMB)
+	}
+
+	_, err = bot.SendDocument(ctx, tu.Document(tu.ID(chatID), tu.File(file)).WithMessageThreadID(threadID))
+	return err
+}
@@ -0,0 +1,153 @@
+package telegram
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"miniclaw/pkg/channel"
+	"miniclaw/pkg/config"
+
+	"github.com/mymmrac/telego"
+)
+
+// metadataThreadID is the inbound and outbound metadata key holding the forum
+// topic a message belongs to.
+const metadataThreadID = "message_thread_id"
+
+// isGroup reports whether chat is a group or supergroup.
+func isGroup(chat telego.Chat) bool {
+	return chat.Type == telego.ChatTypeGroup || chat.Type == telego.ChatTypeSupergroup
+}
+
+// messageThreadID returns the forum topic of message, or 0 outside topics.
+// Reply threads in ordinary supergroups also carry a thread ID; they share the
+// chat's session.
+func messageThreadID(message *telego.Message) int {
+	if !message.IsTopicMessage {
+		return 0
+	}
+
+	return message.MessageThreadID
+}
+
+// messageSessionKey maps a chat, or one forum topic of it, to a session. With
+// session_scope "sender", each sender in a group gets their own session
+// within it; a private chat has only one sender and keeps the chat's key.
+func (a *Adapter) messageSessionKey(message *telego.Message) string {
+	key := sessionKey(strconv.FormatInt(message.Chat.ID, 10))
+	if threadID := messageThreadID(message); threadID != 0 {
+		key += ":topic:" + strconv.Itoa(threadID)
+	}
+
+	a.allowMu.RLock()
+	perSender := a.perSender
+	a.allowMu.RUnlock()
+	if perSender && message.From != nil && message.From.ID != message.Chat.ID {
+		key += ":user:" + strconv.FormatInt(message.From.ID, 10)
+	}
+
+	return key
+}
+
+// validateSessionScope checks channels.telegram.session_scope.
+func validateSessionScope(scope string) error {
+	switch strings.TrimSpace(scope) {
+	case "", config.TelegramSessionScopeChat, config.TelegramSessionScopeSender:
+		return nil
+	}
+
+	return fmt.Errorf("channels.telegram.session_scope must be %q or %q", config.TelegramSessionScopeChat, config.TelegramSessionScopeSender)
+}
+
+// perSenderScope reports whether scope gives each sender their own session.
+func perSenderScope(scope string) bool {
+	return strings.TrimSpace(scope) == config.TelegramSessionScopeSender
+}
+
+// chatAllowed checks a message's sender against allow_from and, in groups,
+// its chat against groups.allow_from: a member of a listed group is accepted
+// even when allow_from does not list them.
+func (a *Adapter) chatAllowed(chat telego.Chat, senderID string) bool {
+	if a.senderAllowed(senderID) {
+		return true
+	}
+	if !isGroup(chat) {
+		return false
+	}
+
+	a.allowMu.RLock()
+	defer a.allowMu.RUnlock()
+
+	_, ok := a.groupAllowFrom[strconv.FormatInt(chat.ID, 10)]
+	return ok
+}
+
+// groupMessageText decides whether a group message is for the bot, me: it
+// mentions the bot, replies to one of its messages, or starts with a chat
+// command that is not addressed to another bot. It returns text with the
+// bot's mentions removed. With groups.always_respond every message is for the
+// bot.
+func (a *Adapter) groupMessageText(message *telego.Message, text string, entities []telego.MessageEntity, me *telego.User) (string, bool) {
+	a.allowMu.RLock()
+	alwaysRespond := a.alwaysRespond
+	a.allowMu.RUnlock()
+
+	addressed := alwaysRespond
+	if me == nil {
+		return text, addressed
+	}
+	if reply := message.ReplyToMessage; reply != nil && reply.From != nil && reply.From.ID == me.ID {
+		addressed = true
+	}
+
+	units := utf16.Encode([]rune(text))
+	var mentions [][2]int
+	for _, entity := range entities {
+		if entity.Offset < 0 || entity.Length <= 0 || entity.Offset+entity.Length > len(units) {
+			continue
+		}
+		value := string(utf16.Decode(units[entity.Offset : entity.Offset+entity.Length]))
+		switch entity.Type {
+		case telego.EntityTypeMention:
+			if strings.EqualFold(value, "@"+me.Username) {
+				addressed = true
+				mentions = append(mentions, [2]int{entity.Offset, entity.Offset + entity.Length})
+			}
+		case telego.EntityTypeTextMention:
+			if entity.User != nil && entity.User.ID == me.ID {
+				addressed = true
+			}
+		case telego.EntityTypeBotCommand:
+			if entity.Offset == 0 && commandForBot(value, me.Username) {
+				addressed = true
+			}
+		}
+	}
+
+	// Drop mentions from the end so earlier offsets stay valid, along with a
+	// space they leave doubled.
+	for _, mention := range slices.Backward(mentions) {
+		start, end := mention[0], mention[1]
+		if start > 0 && end < len(units) && units[start-1] == ' ' && units[end] == ' ' {
+			end++
+		}
+		units = append(units[:start], units[end:]...)
+	}
+
+	return strings.TrimSpace(string(utf16.Decode(units))), addressed
+}
+
+// commandForBot reports whether command, such as "/help" or "/help@name_bot",
+// is one of channel.Commands and not addressed to another bot.
+func commandForBot(command string, username string) bool {
+	name, target, addressed := strings.Cut(command, "@")
+	if addressed {
+		return strings.EqualFold(target, username)
+	}
+
+	name = strings.ToLower(name)
+	return name == "/start" || slices.ContainsFunc(channel.Commands, func(c channel.Command) bool { return c.Name == name })
+}
@@ -0,0 +1,190 @@
+package telegram
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	markdownHeading  = regexp.MustCompile(`^#{1,6}\s+(.*)$`)
+	markdownBullet   = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	markdownNumbered = regexp.MustCompile(`^(\s*)(\d+)[.)]\s+(.*)$`)
+	markdownQuote    = regexp.MustCompile(`^>\s?(.*)$`)
+	fenceLanguage    = regexp.MustCompile(`^[A-Za-z0-9_+#.-]+$`)
+)
+
+// markdownV2 converts the Markdown assistants write into Telegram MarkdownV2:
+// code blocks and inline code keep their text, **bold** and *italic* become
+// Telegram entities, headings become bold lines, bullets become "•", and every
+// other reserved character is escaped so it shows as written.
+func markdownV2(text string) string {
+	var out strings.Builder
+	inFence := false
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			out.WriteByte('\n')
+		}
+
+		if isCodeFence(line) {
+			out.WriteString(codeFence)
+			if !inFence {
+				if language := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), codeFence)); fenceLanguage.MatchString(language) {
+					out.WriteString(language)
+				}
+			}
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			out.WriteString(escapeMarkdownV2Code(line))
+			continue
+		}
+
+		switch {
+		case markdownHeading.MatchString(line):
+			heading := markdownHeading.FindStringSubmatch(line)[1]
+			heading = strings.NewReplacer("**", "", "__", "").Replace(strings.TrimRight(heading, "# "))
+			out.WriteString("*" + escapeMarkdownV2(heading) + "*")
+		case markdownBullet.MatchString(line):
+			match := markdownBullet.FindStringSubmatch(line)
+			out.WriteString(match[1] + "• " + inlineMarkdownV2(match[2]))
+		case markdownNumbered.MatchString(line):
+			match := markdownNumbered.FindStringSubmatch(line)
+			out.WriteString(match[1] + match[2] + `\. ` + inlineMarkdownV2(match[3]))
+		case markdownQuote.MatchString(line):
+			out.WriteString(">" + inlineMarkdownV2(markdownQuote.FindStringSubmatch(line)[1]))
+		default:
+			out.WriteString(inlineMarkdownV2(line))
+		}
+	}
+	if inFence {
+		out.WriteString("\n" + codeFence)
+	}
+
+	return out.String()
+}
+
+// inlineMarkdownV2 converts the inline spans of one line: `code`, **bold**,
+// __bold__, *italic*, _italic_, ~~strikethrough~~, and [links](url). Markers
+// without a matching close are escaped and shown as written.
+func inlineMarkdownV2(text string) string {
+	var out strings.Builder
+	for i := 0; i < len(text); {
+		rest := text[i:]
+		switch {
+		case rest[0] == '`':
+			if end := strings.IndexByte(rest[1:], '`'); end > 0 {
+				out.WriteString("`" + escapeMarkdownV2Code(rest[1:1+end]) + "`")
+				i += end + 2
+				continue
+			}
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__"):
+			if rest[0] == '_' && i > 0 && isWordByte(text[i-1]) {
+				break
+			}
+			if inner, ok := delimited(rest, rest[:2]); ok {
+				out.WriteString("*" + inlineMarkdownV2(inner) + "*")
+				i += len(inner) + 4
+				continue
+			}
+		case strings.HasPrefix(rest, "~~"):
+			if inner, ok := delimited(rest, "~~"); ok {
+				out.WriteString("~" + inlineMarkdownV2(inner) + "~")
+				i += len(inner) + 4
+				continue
+			}
+		case rest[0] == '*' || rest[0] == '_':
+			// An underscore inside a word, as in snake_case, is not emphasis.
+			if rest[0] == '_' && i > 0 && isWordByte(text[i-1]) {
+				break
+			}
+			if inner, ok := delimited(rest, rest[:1]); ok && (rest[0] == '*' || !startsWord(text[i+len(inner)+2:])) {
+				out.WriteString("_" + inlineMarkdownV2(inner) + "_")
+				i += len(inner) + 2
+				continue
+			}
+		case rest[0] == '[':
+			if label, url, ok := markdownLink(rest); ok {
+				out.WriteString("[" + inlineMarkdownV2(label) + "](" + escapeMarkdownV2URL(url) + ")")
+				i += len(label) + len(url) + 4
+				continue
+			}
+		}
+
+		r, size := utf8.DecodeRuneInString(rest)
+		out.WriteString(escapeMarkdownV2(string(r)))
+		i += size
+	}
+
+	return out.String()
+}
+
+// delimited returns the text between marker at the start of s and its next
+// occurrence, when that text is non-empty and does not start or end with a
+// space.
+func delimited(s, marker string) (string, bool) {
+	end := strings.Index(s[len(marker):], marker)
+	if end <= 0 {
+		return "", false
+	}
+	inner := s[len(marker) : len(marker)+end]
+	if strings.TrimSpace(inner) != inner {
+		return "", false
+	}
+
+	return inner, true
+}
+
+// markdownLink parses a [label](url) link at the start of s.
+func markdownLink(s string) (label, url string, ok bool) {
+	labelEnd := strings.Index(s, "](")
+	if labelEnd <= 1 {
+		return "", "", false
+	}
+	urlEnd := strings.IndexByte(s[labelEnd+2:], ')')
+	if urlEnd <= 0 {
+		return "", "", false
+	}
+	label, url = s[1:labelEnd], s[labelEnd+2:labelEnd+2+urlEnd]
+	if strings.ContainsAny(label, "[]") || strings.ContainsAny(url, " \n") {
+		return "", "", false
+	}
+
+	return label, url, true
+}
+
+// escapeMarkdownV2 escapes every character MarkdownV2 reserves in plain text.
+func escapeMarkdownV2(text string) string {
+	var out strings.Builder
+	for _, r := range text {
+		if strings.ContainsRune("_*[]()~`>#+-=|{}.!\\", r) {
+			out.WriteByte('\\')
+		}
+		out.WriteRune(r)
+	}
+
+	return out.String()
+}
+
+// escapeMarkdownV2Code escapes the characters MarkdownV2 reserves inside code.
+func escapeMarkdownV2Code(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(text)
+}
+
+// escapeMarkdownV2URL escapes the characters MarkdownV2 reserves inside a
+// link URL.
+func escapeMarkdownV2URL(url string) string {
+	return strings.NewReplacer(`\`, `\\`, ")", `\)`).Replace(url)
+}
+
+// isWordByte reports whether b is an ASCII letter, digit, or underscore.
+func isWordByte(b byte) bool {
+	return b == '_' || b < utf8.RuneSelf && (unicode.IsLetter(rune(b)) || unicode.IsDigit(rune(b)))
+}
+
+// startsWord reports whether s begins with a word character.
+func startsWord(s string) bool {
+	return s != "" && isWordByte(s[0])
+}
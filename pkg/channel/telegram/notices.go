@@ -0,0 +1,176 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+
+	"github.com/mymmrac/telego"
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// toolNoticeInterval spaces tool notices so a burst of calls stays within
+// Telegram's per-chat rate limits; calls in between are not shown.
+const toolNoticeInterval = 2 * time.Second
+
+// toolNoticeQueueSize buffers notices while one is being sent.
+const toolNoticeQueueSize = 16
+
+// toolNoticeArgLimit bounds the argument shown after the tool name, in runes.
+const toolNoticeArgLimit = 60
+
+// toolNoticeArgKeys are the input fields shown after the tool name, in order
+// of preference.
+var toolNoticeArgKeys = []string{"path", "command", "query", "url", "pattern", "source", "name"}
+
+// validateToolNotices checks channels.telegram.tool_notices.
+func validateToolNotices(mode string) error {
+	switch strings.TrimSpace(mode) {
+	case "", config.TelegramToolNoticesOff, config.TelegramToolNoticesMessages, config.TelegramToolNoticesStatus:
+		return nil
+	}
+
+	return fmt.Errorf("channels.telegram.tool_notices must be %q, %q, or %q", config.TelegramToolNoticesOff, config.TelegramToolNoticesMessages, config.TelegramToolNoticesStatus)
+}
+
+// toolNotices posts the tool calls of one turn to its chat, as separate
+// messages or as one status message, on its own goroutine so tools never
+// wait for Telegram.
+type toolNotices struct {
+	adapter  *Adapter
+	bot      *telego.Bot
+	chatID   int64
+	threadID int
+	status   bool
+
+	notices chan string
+	done    chan struct{}
+	// statusID is the status message, once sent; only the worker uses it.
+	statusID int
+}
+
+// startToolNotices starts posting tool calls to chatID, or returns nil when
+// channels.telegram.tool_notices is off.
+func (a *Adapter) startToolNotices(ctx context.Context, bot *telego.Bot, chatID int64, threadID int) *toolNotices {
+	a.allowMu.RLock()
+	mode := strings.TrimSpace(a.toolNotices)
+	a.allowMu.RUnlock()
+	if mode == "" || mode == config.TelegramToolNoticesOff {
+		return nil
+	}
+
+	n := &toolNotices{
+		adapter:  a,
+		bot:      bot,
+		chatID:   chatID,
+		threadID: threadID,
+		status:   mode == config.TelegramToolNoticesStatus,
+		notices:  make(chan string, toolNoticeQueueSize),
+		done:     make(chan struct{}),
+	}
+	go n.run(ctx)
+
+	return n
+}
+
+// handle queues a notice for each tool call event; results are not shown.
+// A notice that does not fit the queue is dropped.
+func (n *toolNotices) handle(event providertypes.ToolEvent) {
+	if event.Kind != "call" {
+		return
+	}
+
+	select {
+	case n.notices <- toolNoticeText(event):
+	default:
+	}
+}
+
+// run sends queued notices, skipping those that arrive within
+// toolNoticeInterval of the last one sent.
+func (n *toolNotices) run(ctx context.Context) {
+	defer close(n.done)
+
+	var last time.Time
+	for text := range n.notices {
+		if !last.IsZero() && time.Since(last) < toolNoticeInterval {
+			continue
+		}
+		last = time.Now()
+
+		var err error
+		switch {
+		case !n.status:
+			_, err = n.bot.SendMessage(ctx, tu.Message(tu.ID(n.chatID), text).WithMessageThreadID(n.threadID).WithDisableNotification())
+		case n.statusID == 0:
+			var sent *telego.Message
+			if sent, err = n.bot.SendMessage(ctx, tu.Message(tu.ID(n.chatID), text).WithMessageThreadID(n.threadID).WithDisableNotification()); err == nil {
+				n.statusID = sent.MessageID
+			}
+		default:
+			_, err = n.bot.EditMessageText(ctx, &telego.EditMessageTextParams{ChatID: tu.ID(n.chatID), MessageID: n.statusID, Text: text})
+		}
+		if err != nil && ctx.Err() == nil {
+			n.adapter.log.Debug("Failed to send tool notice", "chat_id", n.chatID, "error", err)
+		}
+	}
+}
+
+// stop waits for the queued notices and deletes the status message, so the
+// reply follows the notices. It does nothing on a nil toolNotices.
+func (n *toolNotices) stop(ctx context.Context) {
+	if n == nil {
+		return
+	}
+	close(n.notices)
+	<-n.done
+
+	if n.statusID != 0 {
+		if err := n.bot.DeleteMessage(ctx, tu.Delete(tu.ID(n.chatID), n.statusID)); err != nil {
+			n.adapter.log.Debug("Failed to delete tool status message", "chat_id", n.chatID, "error", err)
+		}
+	}
+}
+
+// toolNoticeText renders a tool call as "🔧 running read_file(src/main.go)…",
+// showing the input field of toolNoticeArgKeys it has, or else its first
+// text field.
+func toolNoticeText(event providertypes.ToolEvent) string {
+	var input map[string]any
+	_ = json.Unmarshal([]byte(event.Payload), &input)
+
+	arg := ""
+	for _, key := range toolNoticeArgKeys {
+		if value, ok := input[key].(string); ok && strings.TrimSpace(value) != "" {
+			arg = value
+			break
+		}
+	}
+	if arg == "" {
+		keys := make([]string, 0, len(input))
+		for key := range input {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			if value, ok := input[key].(string); ok && strings.TrimSpace(value) != "" {
+				arg = value
+				break
+			}
+		}
+	}
+
+	arg = strings.Join(strings.Fields(arg), " ")
+	if utf8.RuneCountInString(arg) > toolNoticeArgLimit {
+		arg = string([]rune(arg)[:toolNoticeArgLimit]) + "…"
+	}
+
+	return "🔧 running " + event.Tool + "(" + arg + ")…"
+}
@@ -0,0 +1,164 @@
+package telegram
+
+import (
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// maxMessageLength is Telegram's limit on the text of one message, counted in
+// UTF-16 code units.
+const maxMessageLength = 4096
+
+// codeFence opens and closes a Markdown code block.
+const codeFence = "```"
+
+// splitMessage splits text into messages of at most limit UTF-16 code units.
+//
+// Breaks fall between paragraphs where possible, then between lines, then
+// between words. A code block cut in two is closed at the end of one message
+// and reopened, with its language tag, at the start of the next, so every
+// message renders on its own.
+func splitMessage(text string, limit int) []string {
+	if textLength(text) <= limit {
+		return []string{text}
+	}
+
+	var messages []string
+	current := ""
+	for _, block := range messageBlocks(text) {
+		for _, piece := range splitBlock(block, limit) {
+			switch {
+			case current == "":
+				current = piece
+			case textLength(current)+2+textLength(piece) <= limit:
+				current += "\n\n" + piece
+			default:
+				messages = append(messages, current)
+				current = piece
+			}
+		}
+	}
+	if current != "" {
+		messages = append(messages, current)
+	}
+
+	return messages
+}
+
+// messageBlocks splits text into paragraphs at blank lines outside code
+// blocks, so a code block with blank lines stays in one paragraph.
+func messageBlocks(text string) []string {
+	var blocks []string
+	var lines []string
+	inFence := false
+	for _, line := range strings.Split(text, "\n") {
+		if !inFence && strings.TrimSpace(line) == "" {
+			if len(lines) > 0 {
+				blocks = append(blocks, strings.Join(lines, "\n"))
+				lines = nil
+			}
+			continue
+		}
+		if isCodeFence(line) {
+			inFence = !inFence
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) > 0 {
+		blocks = append(blocks, strings.Join(lines, "\n"))
+	}
+
+	return blocks
+}
+
+// splitBlock splits one paragraph into pieces of at most limit UTF-16 code
+// units at line breaks, cutting lines that are too long on their own.
+func splitBlock(block string, limit int) []string {
+	if textLength(block) <= limit {
+		return []string{block}
+	}
+
+	var pieces []string
+	var lines []string
+	size := 0
+	// opener is the fence line of the code block open after lines, or "".
+	opener := ""
+	for _, line := range strings.Split(block, "\n") {
+		// Leave room to reopen the code block and close it again.
+		width := limit
+		if opener != "" {
+			width = limit - textLength(opener) - 1 - len("\n"+codeFence)
+		}
+		for _, part := range splitLine(line, width) {
+			closing := 0
+			if opener != "" {
+				closing = len("\n" + codeFence)
+			}
+			if len(lines) > 0 && size+1+textLength(part)+closing > limit {
+				piece := strings.Join(lines, "\n")
+				if opener != "" {
+					piece += "\n" + codeFence
+				}
+				pieces = append(pieces, piece)
+				lines, size = nil, 0
+				if opener != "" {
+					lines, size = []string{opener}, textLength(opener)
+				}
+			}
+			if len(lines) > 0 {
+				size++
+			}
+			lines = append(lines, part)
+			size += textLength(part)
+		}
+		if isCodeFence(line) {
+			if opener == "" {
+				opener = strings.TrimSpace(line)
+			} else {
+				opener = ""
+			}
+		}
+	}
+	if len(lines) > 0 {
+		pieces = append(pieces, strings.Join(lines, "\n"))
+	}
+
+	return pieces
+}
+
+// splitLine cuts line into parts of at most width UTF-16 code units, at the
+// last space that keeps a part at least half full, or else mid-word.
+func splitLine(line string, width int) []string {
+	var parts []string
+	for textLength(line) > width {
+		cut, size := 0, 0
+		for i, r := range line {
+			runeSize := len(utf16.Encode([]rune{r}))
+			if size+runeSize > width {
+				break
+			}
+			size += runeSize
+			cut = i + utf8.RuneLen(r)
+		}
+		if space := strings.LastIndexByte(line[:cut], ' '); space > 0 && textLength(line[:space]) >= width/2 {
+			parts = append(parts, line[:space])
+			line = line[space+1:]
+			continue
+		}
+		parts = append(parts, line[:cut])
+		line = line[cut:]
+	}
+
+	return append(parts, line)
+}
+
+// isCodeFence reports whether line opens or closes a Markdown code block.
+func isCodeFence(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), codeFence)
+}
+
+// textLength counts text the way Telegram limits it, in UTF-16 code units.
+func textLength(text string) int {
+	return len(utf16.Encode([]rune(text)))
+}
@@ -7,13 +7,17 @@ import (
 	"log/slog"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"miniclaw/pkg/bus"
 	"miniclaw/pkg/channel"
 	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
 
 	"github.com/mymmrac/telego"
+	ta "github.com/mymmrac/telego/telegoapi"
 	tu "github.com/mymmrac/telego/telegoutil"
 )
 
@@ -21,11 +25,41 @@ const channelName = "telegram"
 const messagePreviewLimit = 240
 const typingRefreshInterval = 4 * time.Second
 
+// messageQueueSize buffers inbound messages while an earlier one is being handled.
+const messageQueueSize = 64
+
 // Adapter bridges Telegram updates into MiniClaw inbound/outbound messages.
 type Adapter struct {
 	cfg       config.TelegramConfig
-	allowFrom map[string]struct{}
 	log       *slog.Logger
+	approvals *approvalRegistry
+
+	// allowMu guards the settings Reload changes.
+	allowMu        sync.RWMutex
+	allowFrom      map[string]struct{}
+	groupAllowFrom map[string]struct{}
+	alwaysRespond  bool
+	perSender      bool
+	toolNotices    string
+
+	botMu sync.Mutex
+	bot   *telego.Bot
+
+	// me is the bot's own user, fetched by Run, which group messages mention.
+	me atomic.Pointer[telego.User]
+
+	// workspaces finds where received files are saved; see UseWorkspaces.
+	workspaceMu sync.RWMutex
+	workspaces  channel.WorkspaceFunc
+
+	// webhookUpdates receives the updates ServeHTTP accepts while Run runs
+	// in webhook mode; webhookDone closes when that Run stops.
+	webhookMu      sync.RWMutex
+	webhookUpdates chan telego.Update
+	webhookDone    <-chan struct{}
+
+	// lastUpdateAt holds the Unix nanoseconds of the last update Run received.
+	lastUpdateAt atomic.Int64
 }
 
 // NewAdapter validates Telegram configuration and constructs an adapter instance.
@@ -34,15 +68,29 @@ func NewAdapter(cfg config.TelegramConfig, log *slog.Logger) (*Adapter, error) {
 	if token == "" {
 		return nil, errors.New("channels.telegram.token is required")
 	}
+	if err := validateMode(cfg); err != nil {
+		return nil, err
+	}
+	if err := validateSessionScope(cfg.SessionScope); err != nil {
+		return nil, err
+	}
+	if err := validateToolNotices(cfg.ToolNotices); err != nil {
+		return nil, err
+	}
 
 	if log == nil {
 		log = slog.Default()
 	}
 
 	return &Adapter{
-		cfg:       cfg,
-		allowFrom: allowFromSet(cfg.AllowFrom),
-		log:       log.With("component", "channel.telegram"),
+		cfg:            cfg,
+		allowFrom:      allowFromSet(cfg.AllowFrom),
+		groupAllowFrom: allowFromSet(cfg.Groups.AllowFrom),
+		alwaysRespond:  cfg.Groups.AlwaysRespond,
+		perSender:      perSenderScope(cfg.SessionScope),
+		toolNotices:    cfg.ToolNotices,
+		log:            log.With("component", "channel.telegram"),
+		approvals:      newApprovalRegistry(),
 	}, nil
 }
 
@@ -51,24 +99,46 @@ func (a *Adapter) Name() string {
 	return channelName
 }
 
-// Run starts Telegram long polling and forwards messages through the shared channel handler.
+// Run receives Telegram updates, by long polling or in webhook mode through
+// ServeHTTP, and forwards messages through the shared channel handler.
 func (a *Adapter) Run(ctx context.Context, handler channel.Handler) error {
 	if handler == nil {
 		return errors.New("handler is required")
 	}
 
-	bot, err := telego.NewBot(strings.TrimSpace(a.cfg.Token))
+	bot, err := a.botClient()
+	if err != nil {
+		return err
+	}
+	me, err := bot.GetMe(ctx)
 	if err != nil {
-		return fmt.Errorf("initialize telegram bot: %w", err)
+		return fmt.Errorf("telegram getMe: %w", err)
 	}
+	a.me.Store(me)
 
-	updates, err := bot.UpdatesViaLongPolling(ctx, nil)
+	updates, err := a.receiveUpdates(ctx, bot)
 	if err != nil {
-		return fmt.Errorf("start long polling: %w", err)
+		return err
 	}
+	defer a.stopWebhook()
 
+	a.registerCommands(ctx, bot)
 	a.log.Info("Telegram channel started")
 
+	// Messages are handled one at a time on a separate goroutine so the
+	// update loop stays free to receive approval button presses for the running prompt.
+	queue := make(chan telego.Update, messageQueueSize)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.processMessages(ctx, bot, handler, queue)
+	}()
+	defer func() {
+		close(queue)
+		wg.Wait()
+	}()
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -80,70 +150,308 @@ func (a *Adapter) Run(ctx context.Context, handler channel.Handler) error {
 				}
 				return errors.New("telegram updates channel closed")
 			}
+			a.lastUpdateAt.Store(time.Now().UnixNano())
 
-			message := update.Message
-			if message == nil {
+			if update.CallbackQuery != nil {
+				a.handleCallback(ctx, bot, update.CallbackQuery)
 				continue
 			}
-
-			content := strings.TrimSpace(message.Text)
-			if content == "" {
-				// Ignore non-text updates for now; runtime currently expects text content.
+			if update.Message == nil {
 				continue
 			}
-			if message.From == nil {
-				a.log.Debug("Ignoring message without sender")
+			if channel.IsStopCommand(update.Message.Text) {
+				// Handled here rather than queued, where it would wait for the prompt it stops.
+				a.handleMessage(ctx, bot, handler, update)
 				continue
 			}
 
-			senderID := strconv.FormatInt(message.From.ID, 10)
-			if !a.senderAllowed(senderID) {
-				a.log.Debug("Ignoring message from unauthorized sender", "sender_id", senderID)
-				continue
+			select {
+			case queue <- update:
+			case <-ctx.Done():
+				return nil
 			}
+		}
+	}
+}
+
+// registerCommands publishes channel.Commands as the bot's command menu. A
+// failure only costs the menu; the commands still work when typed.
+func (a *Adapter) registerCommands(ctx context.Context, bot *telego.Bot) {
+	commands := make([]telego.BotCommand, 0, len(channel.Commands))
+	for _, command := range channel.Commands {
+		commands = append(commands, telego.BotCommand{Command: strings.TrimPrefix(command.Name, "/"), Description: command.Description})
+	}
+	if err := bot.SetMyCommands(ctx, &telego.SetMyCommandsParams{Commands: commands}); err != nil {
+		a.log.Warn("Failed to register bot commands", "error", err)
+	}
+}
 
-			chatID := strconv.FormatInt(message.Chat.ID, 10)
-			inbound := bus.InboundMessage{
-				Channel:    channelName,
-				SenderID:   senderID,
-				ChatID:     chatID,
-				SessionKey: sessionKey(chatID),
-				Content:    content,
-				Metadata: map[string]string{
-					"update_id": strconv.Itoa(update.UpdateID),
-				},
+// processMessages handles queued message updates in arrival order until ctx ends or the queue closes.
+func (a *Adapter) processMessages(ctx context.Context, bot *telego.Bot, handler channel.Handler, queue <-chan telego.Update) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case update, ok := <-queue:
+			if !ok {
+				return
 			}
-			a.log.Info("Received message", "chat_id", chatID, "sender_id", senderID, "session_key", inbound.SessionKey, "content", previewText(content))
+			a.handleMessage(ctx, bot, handler, update)
+		}
+	}
+}
 
-			stopTyping := a.startTypingIndicator(ctx, bot, message.Chat.ID)
+// handleMessage runs one message through handler and sends the reply. With
+// channels.telegram.files enabled, a photo or document is saved to the
+// session workspace first and its caption is the message text. In groups,
+// only messages addressed to the bot are handled, and a forum topic gets its
+// own session and replies.
+func (a *Adapter) handleMessage(ctx context.Context, bot *telego.Bot, handler channel.Handler, update telego.Update) {
+	message := update.Message
+	text, entities := message.Text, message.Entities
+	var attachments []attachment
+	if a.cfg.Files.Enabled {
+		if attachments = messageAttachments(message); len(attachments) > 0 {
+			text, entities = message.Caption, message.CaptionEntities
+		}
+	}
+	content := strings.TrimSpace(text)
+	if content == "" && len(attachments) == 0 {
+		// Other non-text updates are ignored; the runtime expects text content.
+		return
+	}
+	if message.From == nil {
+		a.log.Debug("Ignoring message without sender")
+		return
+	}
 
-			outbound, err := handler(ctx, inbound)
-			stopTyping()
-			if err != nil {
-				a.log.Error("Failed to process inbound message", "error", err)
-				outbound = bus.OutboundMessage{Error: err.Error()}
-			}
+	senderID := strconv.FormatInt(message.From.ID, 10)
+	if !a.chatAllowed(message.Chat, senderID) {
+		a.log.Debug("Ignoring message from unauthorized sender", "sender_id", senderID, "chat_id", message.Chat.ID)
+		return
+	}
+	if isGroup(message.Chat) {
+		var addressed bool
+		if content, addressed = a.groupMessageText(message, text, entities, a.me.Load()); !addressed {
+			a.log.Debug("Ignoring group message not addressed to the bot", "chat_id", message.Chat.ID)
+			return
+		}
+		if content == "" && len(attachments) == 0 {
+			return
+		}
+	}
 
-			responseText := strings.TrimSpace(outbound.Content)
-			if responseText == "" {
-				responseText = strings.TrimSpace(outbound.Error)
-			}
-			if responseText == "" {
-				continue
+	chatID := strconv.FormatInt(message.Chat.ID, 10)
+	threadID := messageThreadID(message)
+	inbound := bus.InboundMessage{
+		Channel:    channelName,
+		SenderID:   senderID,
+		ChatID:     chatID,
+		SessionKey: a.messageSessionKey(message),
+		Content:    content,
+		Metadata: map[string]string{
+			"update_id": strconv.Itoa(update.UpdateID),
+		},
+	}
+	if threadID != 0 {
+		inbound.Metadata[metadataThreadID] = strconv.Itoa(threadID)
+	}
+	traceID := channel.TraceInbound(&inbound)
+	log := a.log.With("trace_id", traceID)
+	log.Info("Received message", "chat_id", chatID, "sender_id", senderID, "session_key", inbound.SessionKey, "content", previewText(content), "attachments", len(attachments))
+
+	if len(attachments) > 0 {
+		paths, err := a.saveAttachments(ctx, bot, inbound.SessionKey, message.Chat.ID, attachments)
+		if err != nil {
+			log.Warn("Failed to save received file", "chat_id", chatID, "error", err)
+			if err := a.sendText(ctx, bot, message.Chat.ID, threadID, "Could not save the file: "+err.Error()); err != nil {
+				log.Error("Failed to send telegram message", "error", err)
 			}
-			a.log.Info("Sending message", "chat_id", chatID, "session_key", inbound.SessionKey, "content", previewText(responseText))
+			return
+		}
+		inbound.Metadata[channel.MetadataAttachments] = strings.Join(paths, "\n")
+		log.Info("Saved received files", "chat_id", chatID, "paths", paths)
+	}
 
-			if _, err := bot.SendMessage(ctx, tu.Message(tu.ID(message.Chat.ID), responseText)); err != nil {
-				a.log.Error("Failed to send telegram message", "error", err)
-			}
+	stopTyping := a.startTypingIndicator(ctx, bot, message.Chat.ID, threadID)
+
+	promptCtx := providertypes.WithToolApprover(ctx, a.toolApprover(bot, message.Chat.ID, threadID))
+	notices := a.startToolNotices(ctx, bot, message.Chat.ID, threadID)
+	if notices != nil {
+		promptCtx = providertypes.WithToolEventHandler(promptCtx, notices.handle)
+	}
+	outbound, err := handler(promptCtx, inbound)
+	stopTyping()
+	notices.stop(ctx)
+	if providertypes.ErrorCategoryOf(err) == providertypes.ErrorCanceled {
+		// The /stop reply already told the chat.
+		log.Info("Prompt stopped", "chat_id", chatID, "session_key", inbound.SessionKey)
+		return
+	}
+	if err != nil {
+		log.Error("Failed to process inbound message", "error", err)
+		outbound = bus.OutboundMessage{Error: providertypes.UserMessage(err)}
+	}
+
+	responseText := strings.TrimSpace(outbound.Content)
+	if responseText == "" && strings.TrimSpace(outbound.Error) != "" {
+		// The trace ID lets the operator find the failed request in the logs.
+		responseText = strings.TrimSpace(outbound.Error) + "\n\nTrace ID: " + traceID
+	}
+	files := channel.Files(outbound)
+	if responseText == "" && len(files) == 0 {
+		return
+	}
+
+	if responseText != "" {
+		log.Info("Sending message", "chat_id", chatID, "session_key", inbound.SessionKey, "content", previewText(responseText))
+		if err := a.sendText(ctx, bot, message.Chat.ID, threadID, responseText); err != nil {
+			log.Error("Failed to send telegram message", "error", err)
+		}
+	}
+	if len(files) > 0 {
+		log.Info("Sending files", "chat_id", chatID, "session_key", inbound.SessionKey, "files", len(files))
+		_ = a.sendFiles(ctx, bot, message.Chat.ID, threadID, files)
+	}
+}
+
+// Notify delivers an unsolicited message to the chat named by outbound.ChatID.
+//
+// Error text is sent when the message carries no content, matching replies.
+// The files of channel.Files(outbound) follow the text as documents. A
+// message_thread_id metadata value sends it to that forum topic.
+func (a *Adapter) Notify(ctx context.Context, outbound bus.OutboundMessage) error {
+	chatID, err := strconv.ParseInt(strings.TrimSpace(outbound.ChatID), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid telegram chat id %q", outbound.ChatID)
+	}
+	threadID := 0
+	if raw := strings.TrimSpace(outbound.Metadata[metadataThreadID]); raw != "" {
+		if threadID, err = strconv.Atoi(raw); err != nil {
+			return fmt.Errorf("invalid telegram message thread id %q", raw)
+		}
+	}
+
+	text := strings.TrimSpace(outbound.Content)
+	if text == "" {
+		text = strings.TrimSpace(outbound.Error)
+	}
+	files := channel.Files(outbound)
+	if text == "" && len(files) == 0 {
+		return nil
+	}
+
+	bot, err := a.botClient()
+	if err != nil {
+		return err
+	}
+
+	if text != "" {
+		a.log.Info("Sending message", "chat_id", chatID, "session_key", outbound.SessionKey, "content", previewText(text))
+		if err := a.sendText(ctx, bot, chatID, threadID, text); err != nil {
+			return err
 		}
 	}
+	return a.sendFiles(ctx, bot, chatID, threadID, files)
+}
+
+// sendText sends text to chatID, in forum topic threadID when it is not 0,
+// formatted as MarkdownV2 and split into as many messages as Telegram's length
+// limit requires. A message Telegram cannot parse is sent again as plain text.
+// It stops at the first message that fails.
+func (a *Adapter) sendText(ctx context.Context, bot *telego.Bot, chatID int64, threadID int, text string) error {
+	for _, part := range splitMessage(text, maxMessageLength) {
+		_, err := bot.SendMessage(ctx, tu.Message(tu.ID(chatID), markdownV2(part)).WithParseMode(telego.ModeMarkdownV2).WithMessageThreadID(threadID))
+		if isParseError(err) {
+			a.log.Warn("Telegram rejected MarkdownV2; sending plain text", "chat_id", chatID, "error", err)
+			_, err = bot.SendMessage(ctx, tu.Message(tu.ID(chatID), part).WithMessageThreadID(threadID))
+		}
+		if err != nil {
+			return fmt.Errorf("send telegram message: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// isParseError reports whether err is Telegram rejecting a message's
+// formatting entities.
+func isParseError(err error) bool {
+	var apiErr *ta.Error
+	return errors.As(err, &apiErr) && apiErr.ErrorCode == 400 && strings.Contains(apiErr.Description, "can't parse entities")
+}
+
+// CheckHealth calls getMe to confirm the bot token and Telegram API are
+// reachable, and reports when Run last received an update.
+func (a *Adapter) CheckHealth(ctx context.Context) (time.Time, error) {
+	var lastUpdate time.Time
+	if nanos := a.lastUpdateAt.Load(); nanos != 0 {
+		lastUpdate = time.Unix(0, nanos).UTC()
+	}
+
+	bot, err := a.botClient()
+	if err != nil {
+		return lastUpdate, err
+	}
+	if _, err := bot.GetMe(ctx); err != nil {
+		return lastUpdate, fmt.Errorf("telegram getMe: %w", err)
+	}
+
+	return lastUpdate, nil
+}
+
+// botClient returns the shared bot client, creating it on first use.
+func (a *Adapter) botClient() (*telego.Bot, error) {
+	a.botMu.Lock()
+	defer a.botMu.Unlock()
+
+	if a.bot != nil {
+		return a.bot, nil
+	}
+
+	bot, err := telego.NewBot(strings.TrimSpace(a.cfg.Token))
+	if err != nil {
+		return nil, fmt.Errorf("initialize telegram bot: %w", err)
+	}
+	a.bot = bot
+
+	return bot, nil
+}
+
+// Reload applies changed allow_from, groups, session_scope, and
+// tool_notices settings to the running adapter. Token, proxy, files, mode,
+// and webhook changes need a restart.
+func (a *Adapter) Reload(cfg config.ChannelsConfig) error {
+	if err := validateSessionScope(cfg.Telegram.SessionScope); err != nil {
+		return err
+	}
+	if err := validateToolNotices(cfg.Telegram.ToolNotices); err != nil {
+		return err
+	}
+
+	a.allowMu.Lock()
+	a.allowFrom = allowFromSet(cfg.Telegram.AllowFrom)
+	a.groupAllowFrom = allowFromSet(cfg.Telegram.Groups.AllowFrom)
+	a.alwaysRespond = cfg.Telegram.Groups.AlwaysRespond
+	a.perSender = perSenderScope(cfg.Telegram.SessionScope)
+	a.toolNotices = cfg.Telegram.ToolNotices
+	a.allowMu.Unlock()
+
+	next := cfg.Telegram
+	if strings.TrimSpace(next.Token) != strings.TrimSpace(a.cfg.Token) || next.Proxy != a.cfg.Proxy || next.Files != a.cfg.Files || next.Mode != a.cfg.Mode || next.Webhook != a.cfg.Webhook {
+		return errors.New("channels.telegram.token, proxy, files, mode, and webhook changes need a restart")
+	}
+
+	return nil
 }
 
 // senderAllowed checks whether a sender is permitted by allow_from config.
 //
 // When no allow list is configured, all senders are accepted.
 func (a *Adapter) senderAllowed(senderID string) bool {
+	a.allowMu.RLock()
+	defer a.allowMu.RUnlock()
+
 	if len(a.allowFrom) == 0 {
 		return true
 	}
@@ -189,13 +497,14 @@ func previewText(text string) string {
 	return trimmed[:messagePreviewLimit] + "..."
 }
 
-// startTypingIndicator sends an initial typing action and refreshes it periodically
-// until the returned cancel function is called.
-func (a *Adapter) startTypingIndicator(ctx context.Context, bot *telego.Bot, chatID int64) context.CancelFunc {
+// startTypingIndicator sends an initial typing action, in forum topic threadID
+// when it is not 0, and refreshes it periodically until the returned cancel
+// function is called.
+func (a *Adapter) startTypingIndicator(ctx context.Context, bot *telego.Bot, chatID int64, threadID int) context.CancelFunc {
 	typingCtx, cancel := context.WithCancel(ctx)
 
 	sendTyping := func() {
-		if err := bot.SendChatAction(typingCtx, tu.ChatAction(tu.ID(chatID), telego.ChatActionTyping)); err != nil && typingCtx.Err() == nil {
+		if err := bot.SendChatAction(typingCtx, tu.ChatAction(tu.ID(chatID), telego.ChatActionTyping).WithMessageThreadID(threadID)); err != nil && typingCtx.Err() == nil {
 			a.log.Debug("Failed to send typing indicator", "chat_id", chatID, "error", err)
 		}
 	}
@@ -1,8 +1,20 @@
 package telegram
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"unicode/utf8"
+
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+	fstools "miniclaw/pkg/tools/fs"
+	"miniclaw/pkg/workspace"
+
+	"github.com/mymmrac/telego"
 )
 
 func TestAllowFromSet(t *testing.T) {
@@ -33,6 +45,29 @@ func TestSenderAllowed(t *testing.T) {
 	}
 }
 
+func TestReloadReplacesAllowList(t *testing.T) {
+	adapter, err := NewAdapter(config.TelegramConfig{Token: "token", AllowFrom: []string{"1"}}, nil)
+	if err != nil {
+		t.Fatalf("NewAdapter error: %v", err)
+	}
+
+	if err := adapter.Reload(config.ChannelsConfig{Telegram: config.TelegramConfig{Token: "token", AllowFrom: []string{"2"}}}); err != nil {
+		t.Fatalf("Reload error: %v", err)
+	}
+	if adapter.senderAllowed("1") || !adapter.senderAllowed("2") {
+		t.Fatal("expected the reloaded allow list to replace the old one")
+	}
+
+	// A token change is reported, but the allow list still applies.
+	err = adapter.Reload(config.ChannelsConfig{Telegram: config.TelegramConfig{Token: "other", AllowFrom: []string{"3"}}})
+	if err == nil || !strings.Contains(err.Error(), "restart") {
+		t.Fatalf("Reload error = %v, want a restart error", err)
+	}
+	if !adapter.senderAllowed("3") {
+		t.Fatal("expected sender 3 to be allowed after reload")
+	}
+}
+
 func TestSessionKey(t *testing.T) {
 	if got := sessionKey(" 42 "); got != "telegram:42" {
 		t.Fatalf("sessionKey = %q, want %q", got, "telegram:42")
@@ -54,3 +89,412 @@ func TestPreviewText(t *testing.T) {
 		t.Fatalf("previewText long = %q, want ellipsis suffix", got)
 	}
 }
+
+func TestApprovalRegistryResolve(t *testing.T) {
+	registry := newApprovalRegistry()
+	id, reply := registry.register(42)
+
+	if _, ok := registry.resolve("approve:"+id, 7); ok {
+		t.Fatal("expected press from another chat to be ignored")
+	}
+	if _, ok := registry.resolve("maybe:"+id, 42); ok {
+		t.Fatal("expected malformed callback data to be ignored")
+	}
+
+	approved, ok := registry.resolve("deny:"+id, 42)
+	if !ok || approved {
+		t.Fatalf("resolve(deny) = %v, %v; want false, true", approved, ok)
+	}
+	if answer := <-reply; answer {
+		t.Fatal("expected deny to be delivered")
+	}
+	if _, ok := registry.resolve("approve:"+id, 42); ok {
+		t.Fatal("expected second press on a resolved request to be ignored")
+	}
+}
+
+func TestApprovalTextTruncatesInput(t *testing.T) {
+	text := approvalText(providertypes.ToolApprovalRequest{Tool: "run_command", Input: strings.Repeat("x", approvalInputPreviewLimit+10)})
+	if !strings.HasPrefix(text, "🔐 Allow run_command?") || !strings.HasSuffix(text, "...") {
+		t.Fatalf("unexpected approval text: %q", text)
+	}
+}
+
+func TestSplitMessageKeepsShortTextWhole(t *testing.T) {
+	parts := splitMessage("hello\n\nworld", 100)
+	if len(parts) != 1 || parts[0] != "hello\n\nworld" {
+		t.Fatalf("splitMessage = %q, want the text unchanged", parts)
+	}
+}
+
+func TestSplitMessagePrefersParagraphs(t *testing.T) {
+	first := strings.Repeat("a", 30)
+	second := strings.Repeat("b", 30)
+	third := strings.Repeat("c", 30)
+	parts := splitMessage(first+"\n\n"+second+"\n\n"+third, 70)
+	if len(parts) != 2 || parts[0] != first+"\n\n"+second || parts[1] != third {
+		t.Fatalf("splitMessage = %q, want paragraph breaks", parts)
+	}
+}
+
+func TestSplitMessageReopensCodeFences(t *testing.T) {
+	var lines []string
+	for range 20 {
+		lines = append(lines, "fmt.Println(1)")
+	}
+	text := "Example:\n\n```go\n" + strings.Join(lines, "\n") + "\n```"
+	parts := splitMessage(text, 100)
+	if len(parts) < 3 {
+		t.Fatalf("splitMessage returned %d parts, want at least 3", len(parts))
+	}
+	if parts[0] != "Example:" {
+		t.Fatalf("first part = %q, want the leading paragraph", parts[0])
+	}
+	joined := 0
+	for _, part := range parts[1:] {
+		if textLength(part) > 100 {
+			t.Fatalf("part is %d long, want at most 100: %q", textLength(part), part)
+		}
+		if !strings.HasPrefix(part, "```go\n") || !strings.HasSuffix(part, "\n```") {
+			t.Fatalf("part %q is not a complete go code block", part)
+		}
+		joined += strings.Count(part, "fmt.Println(1)")
+	}
+	if joined != len(lines) {
+		t.Fatalf("code lines = %d, want %d", joined, len(lines))
+	}
+}
+
+func TestSplitMessageCutsLongLines(t *testing.T) {
+	text := strings.Repeat("word ", 50) + strings.Repeat("é", 120) + strings.Repeat("😀", 30)
+	parts := splitMessage(text, 64)
+	for _, part := range parts {
+		if textLength(part) > 64 {
+			t.Fatalf("part is %d long, want at most 64: %q", textLength(part), part)
+		}
+		if !utf8.ValidString(part) {
+			t.Fatalf("part %q is not valid UTF-8", part)
+		}
+	}
+	if got := strings.Join(parts, ""); strings.ReplaceAll(got, " ", "") != strings.ReplaceAll(text, " ", "") {
+		t.Fatalf("split dropped text: %q", parts)
+	}
+}
+
+func TestMarkdownV2(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "plain text is escaped", in: "Done. Cost: 1+1=2 (approx)!", want: `Done\. Cost: 1\+1\=2 \(approx\)\!`},
+		{name: "bold and italic", in: "**bold** and *italic* and _also_", want: "*bold* and _italic_ and _also_"},
+		{name: "snake_case stays literal", in: "call read_file_now", want: `call read\_file\_now`},
+		{name: "inline code", in: "run `go test ./...` now", want: "run `go test ./...` now"},
+		{name: "unmatched markers", in: "2 * 3 and a ` tick", want: "2 \\* 3 and a \\` tick"},
+		{name: "heading", in: "## Summary (v2)", want: `*Summary \(v2\)*`},
+		{name: "lists", in: "- one.\n  * two\n3. three", want: "• one\\.\n  • two\n3\\. three"},
+		{name: "link", in: "see [the_docs](https://example.com/a_b)", want: `see [the\_docs](https://example.com/a_b)`},
+		{name: "code block", in: "```go\nx := `a\\b`\n```", want: "```go\nx := \\`a\\\\b\\`\n```"},
+		{name: "unclosed code block", in: "```\nfmt.Println(1)", want: "```\nfmt.Println(1)\n```"},
+	}
+	for _, test := range tests {
+		if got := markdownV2(test.in); got != test.want {
+			t.Fatalf("%s: markdownV2(%q) = %q, want %q", test.name, test.in, got, test.want)
+		}
+	}
+}
+
+func TestMessageAttachmentsPicksLargestPhotoAndDocument(t *testing.T) {
+	message := &telego.Message{
+		Photo: []telego.PhotoSize{
+			{FileID: "small", FileUniqueID: "s", FileSize: 10},
+			{FileID: "large", FileUniqueID: "l", FileSize: 1000},
+		},
+		Document: &telego.Document{FileID: "doc", FileUniqueID: "d", FileName: "report.pdf", FileSize: 2048},
+	}
+
+	attachments := messageAttachments(message)
+	if len(attachments) != 2 {
+		t.Fatalf("attachments = %+v, want photo and document", attachments)
+	}
+	if attachments[0].fileID != "large" || attachments[0].name != "photo-l.jpg" {
+		t.Fatalf("photo attachment = %+v, want the largest size", attachments[0])
+	}
+	if attachments[1].fileID != "doc" || attachments[1].name != "report.pdf" || attachments[1].size != 2048 {
+		t.Fatalf("document attachment = %+v", attachments[1])
+	}
+}
+
+func TestSaveAttachmentsRefusesLargeFiles(t *testing.T) {
+	root := t.TempDir()
+	adapter, err := NewAdapter(config.TelegramConfig{Token: "token", Files: config.TelegramFilesConfig{Enabled: true, MaxSizeMB: 1}}, nil)
+	if err != nil {
+		t.Fatalf("NewAdapter error: %v", err)
+	}
+	guard, err := workspace.NewGuard(root)
+	if err != nil {
+		t.Fatalf("NewGuard error: %v", err)
+	}
+	adapter.UseWorkspaces(func(string) (*fstools.Service, error) { return fstools.NewService(guard), nil })
+
+	_, err = adapter.saveAttachments(t.Context(), nil, "telegram:100", 100, []attachment{{fileID: "big", name: "big.zip", size: 2 << 20}})
+	if err == nil || !strings.Contains(err.Error(), "limited to 1 MB") {
+		t.Fatalf("saveAttachments error = %v, want size limit error", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "uploads")); !os.IsNotExist(err) {
+		t.Fatalf("refused file created the uploads directory: %v", err)
+	}
+}
+
+func TestSafeFileName(t *testing.T) {
+	for name, want := range map[string]string{
+		"report.pdf":       "report.pdf",
+		"../../etc/passwd": "_.._etc_passwd",
+		".hidden":          "hidden",
+		"  ":               "file",
+	} {
+		if got := safeFileName(name); got != want {
+			t.Fatalf("safeFileName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestNewAdapterValidatesWebhookMode(t *testing.T) {
+	webhook := config.TelegramWebhookConfig{URL: "https://bot.example.com", SecretToken: "s3cret_token"}
+	for name, cfg := range map[string]config.TelegramConfig{
+		"unknown mode":  {Token: "token", Mode: "push"},
+		"missing url":   {Token: "token", Mode: "webhook", Webhook: config.TelegramWebhookConfig{SecretToken: "s3cret"}},
+		"plain http":    {Token: "token", Mode: "webhook", Webhook: config.TelegramWebhookConfig{URL: "http://bot.example.com", SecretToken: "s3cret"}},
+		"no secret":     {Token: "token", Mode: "webhook", Webhook: config.TelegramWebhookConfig{URL: "https://bot.example.com"}},
+		"bad secret":    {Token: "token", Mode: "webhook", Webhook: config.TelegramWebhookConfig{URL: "https://bot.example.com", SecretToken: "not secret!"}},
+		"relative path": {Token: "token", Mode: "webhook", Webhook: config.TelegramWebhookConfig{URL: "https://bot.example.com", Path: "hook", SecretToken: "s3cret"}},
+	} {
+		if _, err := NewAdapter(cfg, nil); err == nil {
+			t.Fatalf("%s: expected NewAdapter error", name)
+		}
+	}
+
+	adapter, err := NewAdapter(config.TelegramConfig{Token: "token", Mode: "webhook", Webhook: webhook}, nil)
+	if err != nil {
+		t.Fatalf("NewAdapter error: %v", err)
+	}
+	if adapter.WebhookPath() != "/telegram/webhook" || adapter.webhookURL() != "https://bot.example.com/telegram/webhook" {
+		t.Fatalf("webhook path %q, url %q", adapter.WebhookPath(), adapter.webhookURL())
+	}
+
+	polling, err := NewAdapter(config.TelegramConfig{Token: "token"}, nil)
+	if err != nil {
+		t.Fatalf("NewAdapter error: %v", err)
+	}
+	if polling.WebhookPath() != "" {
+		t.Fatalf("polling adapter WebhookPath = %q, want empty", polling.WebhookPath())
+	}
+}
+
+func TestServeHTTPQueuesWebhookUpdates(t *testing.T) {
+	adapter, err := NewAdapter(config.TelegramConfig{Token: "token", Mode: "webhook", Webhook: config.TelegramWebhookConfig{URL: "https://bot.example.com", SecretToken: "s3cret"}}, nil)
+	if err != nil {
+		t.Fatalf("NewAdapter error: %v", err)
+	}
+
+	post := func(secret string, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/telegram/webhook", strings.NewReader(body))
+		req.Header.Set(telego.WebhookSecretTokenHeader, secret)
+		recorder := httptest.NewRecorder()
+		adapter.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+	update := `{"update_id": 7, "message": {"message_id": 1, "date": 0, "chat": {"id": 100, "type": "private"}, "text": "hi"}}`
+
+	if code := post("s3cret", update); code != http.StatusServiceUnavailable {
+		t.Fatalf("update before Run = %d, want 503", code)
+	}
+
+	updates := make(chan telego.Update, 1)
+	done := make(chan struct{})
+	adapter.webhookMu.Lock()
+	adapter.webhookUpdates, adapter.webhookDone = updates, done
+	adapter.webhookMu.Unlock()
+
+	if code := post("wrong", update); code != http.StatusUnauthorized {
+		t.Fatalf("wrong secret = %d, want 401", code)
+	}
+	if code := post("s3cret", "{"); code != http.StatusBadRequest {
+		t.Fatalf("malformed update = %d, want 400", code)
+	}
+	if code := post("s3cret", update); code != http.StatusOK {
+		t.Fatalf("update = %d, want 200", code)
+	}
+	if got := <-updates; got.UpdateID != 7 || got.Message == nil || got.Message.Text != "hi" {
+		t.Fatalf("queued update = %+v", got)
+	}
+}
+
+func TestChatAllowedAcceptsListedGroups(t *testing.T) {
+	adapter, err := NewAdapter(config.TelegramConfig{Token: "token", AllowFrom: []string{"1"}, Groups: config.TelegramGroupsConfig{AllowFrom: []string{"-100"}}}, nil)
+	if err != nil {
+		t.Fatalf("NewAdapter error: %v", err)
+	}
+
+	group := telego.Chat{ID: -100, Type: telego.ChatTypeSupergroup}
+	if !adapter.chatAllowed(group, "2") {
+		t.Fatal("expected any member of a listed group to be allowed")
+	}
+	if adapter.chatAllowed(telego.Chat{ID: -200, Type: telego.ChatTypeGroup}, "2") {
+		t.Fatal("expected an unlisted sender in an unlisted group to be refused")
+	}
+	if !adapter.chatAllowed(telego.Chat{ID: -200, Type: telego.ChatTypeGroup}, "1") {
+		t.Fatal("expected an allow_from sender to be allowed in any group")
+	}
+	if adapter.chatAllowed(telego.Chat{ID: -100, Type: telego.ChatTypePrivate}, "2") {
+		t.Fatal("groups.allow_from should not admit private chats")
+	}
+
+	if err := adapter.Reload(config.ChannelsConfig{Telegram: config.TelegramConfig{Token: "token", AllowFrom: []string{"1"}}}); err != nil {
+		t.Fatalf("Reload error: %v", err)
+	}
+	if adapter.chatAllowed(group, "2") {
+		t.Fatal("expected the reloaded groups.allow_from to drop the group")
+	}
+}
+
+func TestGroupMessageText(t *testing.T) {
+	adapter, err := NewAdapter(config.TelegramConfig{Token: "token"}, nil)
+	if err != nil {
+		t.Fatalf("NewAdapter error: %v", err)
+	}
+	me := &telego.User{ID: 99, IsBot: true, Username: "miniclaw_bot"}
+
+	for _, tc := range []struct {
+		name      string
+		message   telego.Message
+		want      string
+		addressed bool
+	}{
+		{
+			name:    "mention",
+			message: telego.Message{Text: "héllo @MiniClaw_bot what's up", Entities: []telego.MessageEntity{{Type: telego.EntityTypeMention, Offset: 6, Length: 13}}},
+			want:    "héllo what's up", addressed: true,
+		},
+		{
+			name:    "other mention",
+			message: telego.Message{Text: "@other_bot hi", Entities: []telego.MessageEntity{{Type: telego.EntityTypeMention, Offset: 0, Length: 10}}},
+			want:    "@other_bot hi",
+		},
+		{
+			name:    "reply to bot",
+			message: telego.Message{Text: "and then?", ReplyToMessage: &telego.Message{From: me}},
+			want:    "and then?", addressed: true,
+		},
+		{
+			name:    "command",
+			message: telego.Message{Text: "/reset", Entities: []telego.MessageEntity{{Type: telego.EntityTypeBotCommand, Offset: 0, Length: 6}}},
+			want:    "/reset", addressed: true,
+		},
+		{
+			name:    "command for another bot",
+			message: telego.Message{Text: "/reset@other_bot", Entities: []telego.MessageEntity{{Type: telego.EntityTypeBotCommand, Offset: 0, Length: 16}}},
+			want:    "/reset@other_bot",
+		},
+		{
+			name:    "unknown command",
+			message: telego.Message{Text: "/poll", Entities: []telego.MessageEntity{{Type: telego.EntityTypeBotCommand, Offset: 0, Length: 5}}},
+			want:    "/poll",
+		},
+		{
+			name:    "chatter",
+			message: telego.Message{Text: "lunch?"},
+			want:    "lunch?",
+		},
+	} {
+		got, addressed := adapter.groupMessageText(&tc.message, tc.message.Text, tc.message.Entities, me)
+		if got != tc.want || addressed != tc.addressed {
+			t.Fatalf("%s: groupMessageText = %q, %v; want %q, %v", tc.name, got, addressed, tc.want, tc.addressed)
+		}
+	}
+
+	if err := adapter.Reload(config.ChannelsConfig{Telegram: config.TelegramConfig{Token: "token", Groups: config.TelegramGroupsConfig{AlwaysRespond: true}}}); err != nil {
+		t.Fatalf("Reload error: %v", err)
+	}
+	if _, addressed := adapter.groupMessageText(&telego.Message{Text: "lunch?"}, "lunch?", nil, me); !addressed {
+		t.Fatal("expected groups.always_respond to answer every group message")
+	}
+}
+
+func TestMessageSessionKeyPerForumTopic(t *testing.T) {
+	adapter, err := NewAdapter(config.TelegramConfig{Token: "token"}, nil)
+	if err != nil {
+		t.Fatalf("NewAdapter error: %v", err)
+	}
+
+	chat := telego.Chat{ID: -100, Type: telego.ChatTypeSupergroup}
+	if got := adapter.messageSessionKey(&telego.Message{Chat: chat, IsTopicMessage: true, MessageThreadID: 7}); got != "telegram:-100:topic:7" {
+		t.Fatalf("topic session key = %q", got)
+	}
+	// A reply thread outside a forum shares the chat's session.
+	if got := adapter.messageSessionKey(&telego.Message{Chat: chat, MessageThreadID: 7}); got != "telegram:-100" {
+		t.Fatalf("reply thread session key = %q", got)
+	}
+}
+
+func TestMessageSessionKeyPerSender(t *testing.T) {
+	if _, err := NewAdapter(config.TelegramConfig{Token: "token", SessionScope: "user"}, nil); err == nil {
+		t.Fatal("expected an unknown session_scope to be rejected")
+	}
+	adapter, err := NewAdapter(config.TelegramConfig{Token: "token", SessionScope: "sender"}, nil)
+	if err != nil {
+		t.Fatalf("NewAdapter error: %v", err)
+	}
+
+	group := telego.Chat{ID: -100, Type: telego.ChatTypeSupergroup}
+	alice, bob := &telego.User{ID: 1}, &telego.User{ID: 2}
+	if got := adapter.messageSessionKey(&telego.Message{Chat: group, From: alice}); got != "telegram:-100:user:1" {
+		t.Fatalf("alice session key = %q", got)
+	}
+	if got := adapter.messageSessionKey(&telego.Message{Chat: group, From: bob, IsTopicMessage: true, MessageThreadID: 7}); got != "telegram:-100:topic:7:user:2" {
+		t.Fatalf("bob topic session key = %q", got)
+	}
+	if got := adapter.messageSessionKey(&telego.Message{Chat: telego.Chat{ID: 1, Type: telego.ChatTypePrivate}, From: alice}); got != "telegram:1" {
+		t.Fatalf("private chat session key = %q, want the chat's key", got)
+	}
+
+	if err := adapter.Reload(config.ChannelsConfig{Telegram: config.TelegramConfig{Token: "token"}}); err != nil {
+		t.Fatalf("Reload error: %v", err)
+	}
+	if got := adapter.messageSessionKey(&telego.Message{Chat: group, From: alice}); got != "telegram:-100" {
+		t.Fatalf("session key after reload = %q, want the chat's key", got)
+	}
+}
+
+func TestToolNoticeText(t *testing.T) {
+	for _, tc := range []struct {
+		event providertypes.ToolEvent
+		want  string
+	}{
+		{providertypes.ToolEvent{Kind: "call", Tool: "read_file", Payload: `{"path":"src/main.go","offset":0}`}, "🔧 running read_file(src/main.go)…"},
+		{providertypes.ToolEvent{Kind: "call", Tool: "run_command", Payload: `{"command":"go   test\n./...","workdir":"pkg"}`}, "🔧 running run_command(go test ./...)…"},
+		{providertypes.ToolEvent{Kind: "call", Tool: "github__search", Payload: `{"repo":"miniclaw","terms":"webhook"}`}, "🔧 running github__search(miniclaw)…"},
+		{providertypes.ToolEvent{Kind: "call", Tool: "plan_status", Payload: `{}`}, "🔧 running plan_status()…"},
+		{providertypes.ToolEvent{Kind: "call", Tool: "write_file", Payload: `{"path":"` + strings.Repeat("a", 70) + `"}`}, "🔧 running write_file(" + strings.Repeat("a", 60) + "…)…"},
+	} {
+		if got := toolNoticeText(tc.event); got != tc.want {
+			t.Fatalf("toolNoticeText(%s) = %q, want %q", tc.event.Payload, got, tc.want)
+		}
+	}
+}
+
+func TestToolNoticesMode(t *testing.T) {
+	if _, err := NewAdapter(config.TelegramConfig{Token: "token", ToolNotices: "verbose"}, nil); err == nil {
+		t.Fatal("expected an unknown tool_notices mode to be rejected")
+	}
+	adapter, err := NewAdapter(config.TelegramConfig{Token: "token"}, nil)
+	if err != nil {
+		t.Fatalf("NewAdapter error: %v", err)
+	}
+	notices := adapter.startToolNotices(t.Context(), nil, 100, 0)
+	if notices != nil {
+		t.Fatal("expected no tool notices by default")
+	}
+	notices.stop(t.Context())
+}
@@ -0,0 +1,184 @@
+package telegram
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"miniclaw/pkg/config"
+
+	"github.com/mymmrac/telego"
+	tu "github.com/mymmrac/telego/telegoutil"
+)
+
+// defaultWebhookPath is where the gateway HTTP server receives updates when
+// channels.telegram.webhook.path is unset.
+const defaultWebhookPath = "/telegram/webhook"
+
+// webhookQueueSize buffers updates posted while the adapter is busy.
+const webhookQueueSize = 128
+
+// maxWebhookBodyBytes bounds one posted update.
+const maxWebhookBodyBytes = 1 << 20
+
+// webhookSecret matches the secret tokens Telegram accepts.
+var webhookSecret = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// validateMode checks channels.telegram.mode and, in webhook mode, its
+// webhook settings.
+func validateMode(cfg config.TelegramConfig) error {
+	switch strings.TrimSpace(cfg.Mode) {
+	case "", config.TelegramModePolling:
+		return nil
+	case config.TelegramModeWebhook:
+	default:
+		return fmt.Errorf("channels.telegram.mode must be %q or %q", config.TelegramModePolling, config.TelegramModeWebhook)
+	}
+
+	target, err := url.Parse(strings.TrimSpace(cfg.Webhook.URL))
+	if err != nil || target.Scheme != "https" || target.Host == "" {
+		return errors.New("channels.telegram.webhook.url must be an https:// URL in webhook mode")
+	}
+	if path := strings.TrimSpace(cfg.Webhook.Path); path != "" && !strings.HasPrefix(path, "/") {
+		return errors.New("channels.telegram.webhook.path must start with /")
+	}
+	if !webhookSecret.MatchString(cfg.Webhook.SecretToken) {
+		return errors.New("channels.telegram.webhook.secret_token is required in webhook mode: 1-256 letters, digits, _ or -")
+	}
+
+	return nil
+}
+
+// webhookMode reports whether the adapter receives updates through a webhook.
+func (a *Adapter) webhookMode() bool {
+	return strings.TrimSpace(a.cfg.Mode) == config.TelegramModeWebhook
+}
+
+// WebhookPath returns the gateway HTTP path Telegram posts updates to in
+// webhook mode, or "" when the adapter uses long polling.
+func (a *Adapter) WebhookPath() string {
+	if !a.webhookMode() {
+		return ""
+	}
+	if path := strings.TrimSpace(a.cfg.Webhook.Path); path != "" {
+		return path
+	}
+
+	return defaultWebhookPath
+}
+
+// webhookURL is the address Telegram is asked to post updates to: the
+// configured public URL followed by WebhookPath.
+func (a *Adapter) webhookURL() string {
+	return strings.TrimRight(strings.TrimSpace(a.cfg.Webhook.URL), "/") + a.WebhookPath()
+}
+
+// receiveUpdates starts long polling, or in webhook mode registers the
+// webhook with Telegram, and returns the updates channel Run reads.
+func (a *Adapter) receiveUpdates(ctx context.Context, bot *telego.Bot) (<-chan telego.Update, error) {
+	if a.webhookMode() {
+		return a.startWebhook(ctx, bot)
+	}
+
+	// getUpdates fails while a webhook from an earlier webhook-mode run is set.
+	if err := bot.DeleteWebhook(ctx, &telego.DeleteWebhookParams{}); err != nil {
+		a.log.Warn("Failed to delete telegram webhook", "error", err)
+	}
+	updates, err := bot.UpdatesViaLongPolling(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("start long polling: %w", err)
+	}
+
+	return updates, nil
+}
+
+// startWebhook opens the queue ServeHTTP fills and calls setWebhook. The
+// webhook stays registered after Run returns, so Telegram holds updates
+// until the next start.
+func (a *Adapter) startWebhook(ctx context.Context, bot *telego.Bot) (<-chan telego.Update, error) {
+	params := &telego.SetWebhookParams{URL: a.webhookURL(), SecretToken: a.cfg.Webhook.SecretToken}
+	if path := strings.TrimSpace(a.cfg.Webhook.Certificate); path != "" {
+		certificate, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("open channels.telegram.webhook.certificate: %w", err)
+		}
+		defer func() { _ = certificate.Close() }()
+		file := tu.File(certificate)
+		params.Certificate = &file
+	}
+
+	updates := make(chan telego.Update, webhookQueueSize)
+	a.webhookMu.Lock()
+	a.webhookUpdates = updates
+	a.webhookDone = ctx.Done()
+	a.webhookMu.Unlock()
+
+	if err := bot.SetWebhook(ctx, params); err != nil {
+		a.stopWebhook()
+		return nil, fmt.Errorf("set telegram webhook: %w", err)
+	}
+	a.log.Info("Telegram webhook set", "url", params.URL)
+
+	return updates, nil
+}
+
+// stopWebhook makes ServeHTTP refuse updates once Run returns.
+func (a *Adapter) stopWebhook() {
+	a.webhookMu.Lock()
+	defer a.webhookMu.Unlock()
+
+	a.webhookUpdates = nil
+	a.webhookDone = nil
+}
+
+// ServeHTTP receives one update Telegram posts to the webhook and queues it
+// for Run. Requests without the configured secret token are refused, and
+// while Run is not receiving the reply asks Telegram to retry later.
+func (a *Adapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	secret := r.Header.Get(telego.WebhookSecretTokenHeader)
+	if a.cfg.Webhook.SecretToken == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(a.cfg.Webhook.SecretToken)) != 1 {
+		a.log.Warn("Rejected telegram webhook request with a wrong secret token", "remote_addr", r.RemoteAddr)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	a.webhookMu.RLock()
+	updates, done := a.webhookUpdates, a.webhookDone
+	a.webhookMu.RUnlock()
+	if updates == nil {
+		http.Error(w, "telegram channel is not running", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodyBytes))
+	if err != nil {
+		http.Error(w, "invalid update", http.StatusBadRequest)
+		return
+	}
+	var update telego.Update
+	if err := json.Unmarshal(body, &update); err != nil {
+		a.log.Warn("Rejected malformed telegram webhook update", "error", err)
+		http.Error(w, "invalid update", http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case updates <- update:
+		w.WriteHeader(http.StatusOK)
+	case <-done:
+		http.Error(w, "telegram channel is not running", http.StatusServiceUnavailable)
+	case <-r.Context().Done():
+	}
+}
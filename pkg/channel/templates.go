@@ -0,0 +1,141 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+// greetingCommand is the message chat apps send when a user first opens a
+// bot, answered with the greeting template.
+const greetingCommand = "/start"
+
+// TemplateData is what channels.templates templates can use; see
+// config.ChannelTemplatesConfig.
+type TemplateData struct {
+	Channel    string
+	ChatID     string
+	SenderID   string
+	SessionKey string
+	// Agent is the named agent answering the chat, or empty for the default one.
+	Agent string
+	Model string
+	// Error is the built-in reply to a failed message.
+	Error         string
+	ErrorCategory string
+	// RetryAfter is how long to wait before retrying, as in "30s"; empty when
+	// the error does not say.
+	RetryAfter string
+}
+
+// Templates are the parsed reply templates of one channel. Nil templates
+// keep the built-in text.
+type Templates struct {
+	Error     *template.Template
+	RateLimit *template.Template
+	Greeting  *template.Template
+}
+
+// ParseTemplates parses the templates of cfg, rejecting ones that do not
+// parse or use a field TemplateData lacks. name, the channels.templates key,
+// labels the errors.
+func ParseTemplates(name string, cfg config.ChannelTemplatesConfig) (Templates, error) {
+	var templates Templates
+	for _, field := range []struct {
+		key  string
+		text string
+		dst  **template.Template
+	}{
+		{"error", cfg.Error, &templates.Error},
+		{"rate_limit", cfg.RateLimit, &templates.RateLimit},
+		{"greeting", cfg.Greeting, &templates.Greeting},
+	} {
+		if strings.TrimSpace(field.text) == "" {
+			continue
+		}
+		key := fmt.Sprintf("channels.templates.%s.%s", name, field.key)
+		parsed, err := template.New(key).Parse(field.text)
+		if err == nil {
+			err = parsed.Execute(io.Discard, TemplateData{})
+		}
+		if err != nil {
+			return Templates{}, fmt.Errorf("%s: %w", key, err)
+		}
+		*field.dst = parsed
+	}
+
+	return templates, nil
+}
+
+// ApplyTemplates returns middleware that replaces the built-in text of error
+// replies, rate limit replies, and the /start reply with the templates of the
+// message's channel, or those under config.ChannelTemplatesDefault. describe
+// fills in the chat's agent and model. Error replies are set through a
+// providertypes.ReplyError, so the error keeps its category. A template that
+// fails to render, or renders blank, keeps the built-in text. It returns nil,
+// which Chain skips, when templates is empty.
+func ApplyTemplates(templates map[string]Templates, describe func(bus.InboundMessage) TemplateData) Middleware {
+	if len(templates) == 0 {
+		return nil
+	}
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, inbound bus.InboundMessage) (bus.OutboundMessage, error) {
+			outbound, err := next(ctx, inbound)
+			channelTemplates, ok := templates[inbound.Channel]
+			if !ok {
+				channelTemplates = templates[config.ChannelTemplatesDefault]
+			}
+
+			if err != nil {
+				tmpl := channelTemplates.Error
+				category := providertypes.ErrorCategoryOf(err)
+				if category == providertypes.ErrorRateLimit && channelTemplates.RateLimit != nil {
+					tmpl = channelTemplates.RateLimit
+				}
+				if tmpl == nil {
+					return outbound, err
+				}
+				data := describe(inbound)
+				data.Error = providertypes.UserMessage(err)
+				data.ErrorCategory = string(category)
+				var promptErr *providertypes.PromptError
+				if errors.As(err, &promptErr) && promptErr.RetryAfter > 0 {
+					data.RetryAfter = max(promptErr.RetryAfter.Round(time.Second), time.Second).String()
+				}
+				if reply, ok := render(tmpl, data); ok {
+					err = &providertypes.ReplyError{Reply: reply, Err: err}
+				}
+				return outbound, err
+			}
+
+			if name, _, isCommand := ParseCommand(inbound.Content); isCommand && name == greetingCommand && channelTemplates.Greeting != nil {
+				if reply, ok := render(channelTemplates.Greeting, describe(inbound)); ok {
+					outbound.Content = reply
+				}
+			}
+
+			return outbound, nil
+		}
+	}
+}
+
+// render executes tmpl with data, reporting false when it fails or renders
+// only whitespace.
+func render(tmpl *template.Template, data TemplateData) (string, bool) {
+	var text strings.Builder
+	if err := tmpl.Execute(&text, data); err != nil {
+		return "", false
+	}
+	reply := strings.TrimSpace(text.String())
+
+	return reply, reply != ""
+}
@@ -0,0 +1,74 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+func TestParseTemplatesRejectsUnknownFields(t *testing.T) {
+	if _, err := ParseTemplates("telegram", config.ChannelTemplatesConfig{Error: "{{.Modle}} failed"}); err == nil {
+		t.Fatal("expected an unknown field to be rejected")
+	}
+	if _, err := ParseTemplates("telegram", config.ChannelTemplatesConfig{Greeting: "{{if}}"}); err == nil {
+		t.Fatal("expected a malformed template to be rejected")
+	}
+	templates, err := ParseTemplates("telegram", config.ChannelTemplatesConfig{Greeting: "Hi"})
+	if err != nil || templates.Greeting == nil || templates.Error != nil {
+		t.Fatalf("ParseTemplates = %+v, %v", templates, err)
+	}
+}
+
+func TestApplyTemplates(t *testing.T) {
+	if ApplyTemplates(nil, nil) != nil {
+		t.Fatal("expected no middleware without templates")
+	}
+	telegram, err := ParseTemplates("telegram", config.ChannelTemplatesConfig{
+		Error:     "{{.Model}} konnte nicht antworten: {{.Error}}",
+		RateLimit: "Zu viele Nachrichten, bitte {{.RetryAfter}} warten.",
+		Greeting:  "Hallo! Ich bin {{.Model}} ({{.SessionKey}}).",
+	})
+	if err != nil {
+		t.Fatalf("ParseTemplates: %v", err)
+	}
+	describe := func(inbound bus.InboundMessage) TemplateData {
+		return TemplateData{Channel: inbound.Channel, SessionKey: inbound.SessionKey, Model: "gpt-5.2"}
+	}
+	failure := errors.New("boom")
+	handler := Chain(func(_ context.Context, inbound bus.InboundMessage) (bus.OutboundMessage, error) {
+		switch inbound.Content {
+		case "fail":
+			return bus.OutboundMessage{}, failure
+		case "busy":
+			return bus.OutboundMessage{}, &providertypes.PromptError{Category: providertypes.ErrorRateLimit, Provider: "gateway", RetryAfter: 1500 * time.Millisecond, Err: ErrRateLimited}
+		}
+		return bus.OutboundMessage{Content: "Commands: ..."}, nil
+	}, ApplyTemplates(map[string]Templates{"telegram": telegram}, describe))
+
+	_, err = handler(context.Background(), bus.InboundMessage{Channel: "telegram", Content: "fail"})
+	if got := providertypes.UserMessage(err); got != "gpt-5.2 konnte nicht antworten: boom" || !errors.Is(err, failure) {
+		t.Fatalf("error reply = %q (%v)", got, err)
+	}
+	_, err = handler(context.Background(), bus.InboundMessage{Channel: "telegram", Content: "busy"})
+	if got := providertypes.UserMessage(err); got != "Zu viele Nachrichten, bitte 2s warten." || providertypes.ErrorCategoryOf(err) != providertypes.ErrorRateLimit {
+		t.Fatalf("rate limit reply = %q (%v)", got, err)
+	}
+	outbound, _ := handler(context.Background(), bus.InboundMessage{Channel: "telegram", SessionKey: "telegram:42", Content: "/start"})
+	if outbound.Content != "Hallo! Ich bin gpt-5.2 (telegram:42)." {
+		t.Fatalf("greeting = %q", outbound.Content)
+	}
+
+	// Channels without templates keep the built-in replies.
+	_, err = handler(context.Background(), bus.InboundMessage{Channel: "http", Content: "fail"})
+	if got := providertypes.UserMessage(err); got != "boom" {
+		t.Fatalf("http error reply = %q", got)
+	}
+	if outbound, _ := handler(context.Background(), bus.InboundMessage{Channel: "http", Content: "/start"}); outbound.Content != "Commands: ..." {
+		t.Fatalf("http /start reply = %q", outbound.Content)
+	}
+}
@@ -0,0 +1,265 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// websocketGUID is appended to the client key to build Sec-WebSocket-Accept
+// (RFC 6455 section 1.3).
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Frame opcodes (RFC 6455 section 5.2).
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+const (
+	// maxMessageBytes bounds one client message, across its fragments.
+	maxMessageBytes = 1 << 20
+	writeTimeout    = 10 * time.Second
+)
+
+// Close status codes sent to the client.
+const (
+	closeNormal      = 1000
+	closeProtocol    = 1002
+	closeUnsupported = 1003
+	closeTooBig      = 1009
+)
+
+// errClosed is returned by readMessage once the client closed the connection.
+var errClosed = errors.New("websocket: connection closed")
+
+// conn is the server side of one WebSocket connection: it reads client
+// messages and writes text messages, answering pings and close frames.
+type conn struct {
+	raw    net.Conn
+	reader *bufio.Reader
+
+	writeMu sync.Mutex
+}
+
+// upgrade completes the opening handshake and takes over the connection.
+func upgrade(w http.ResponseWriter, r *http.Request) (*conn, error) {
+	if r.Method != http.MethodGet {
+		return nil, errors.New("websocket: method must be GET")
+	}
+	if !headerHasToken(r.Header, "Connection", "upgrade") || !headerHasToken(r.Header, "Upgrade", "websocket") {
+		return nil, errors.New("websocket: not an upgrade request")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, errors.New("websocket: unsupported version")
+	}
+	key := strings.TrimSpace(r.Header.Get("Sec-WebSocket-Key"))
+	if key == "" {
+		return nil, errors.New("websocket: missing Sec-WebSocket-Key")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("websocket: connection cannot be hijacked")
+	}
+	raw, buffered, err := hijacker.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("websocket: hijack: %w", err)
+	}
+
+	_ = raw.SetDeadline(time.Time{})
+	_ = raw.SetWriteDeadline(time.Now().Add(writeTimeout))
+	fmt.Fprintf(buffered, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", acceptKey(key))
+	if err := buffered.Flush(); err != nil {
+		_ = raw.Close()
+		return nil, fmt.Errorf("websocket: handshake: %w", err)
+	}
+
+	return &conn{raw: raw, reader: buffered.Reader}, nil
+}
+
+// acceptKey derives Sec-WebSocket-Accept from the client's key.
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerHasToken reports whether the comma-separated header name contains
+// token, ignoring case.
+func headerHasToken(header http.Header, name string, token string) bool {
+	for _, value := range header.Values(name) {
+		for part := range strings.SplitSeq(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// readMessage returns the next complete text message, answering control
+// frames on the way. It returns errClosed after a close frame.
+func (c *conn) readMessage() ([]byte, error) {
+	var (
+		message []byte
+		opcode  byte
+	)
+	for {
+		fin, frameOp, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		switch frameOp {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			_ = c.writeFrame(opClose, closePayload(closeNormal, ""))
+			return nil, errClosed
+		case opText, opBinary:
+			if opcode != 0 {
+				c.fail(closeProtocol, "expected a continuation frame")
+				return nil, errors.New("websocket: interleaved message")
+			}
+			opcode = frameOp
+		case opContinuation:
+			if opcode == 0 {
+				c.fail(closeProtocol, "unexpected continuation frame")
+				return nil, errors.New("websocket: continuation without a message")
+			}
+		default:
+			c.fail(closeProtocol, "unknown opcode")
+			return nil, fmt.Errorf("websocket: unknown opcode %d", frameOp)
+		}
+
+		if len(message)+len(payload) > maxMessageBytes {
+			c.fail(closeTooBig, "message too big")
+			return nil, errors.New("websocket: message too big")
+		}
+		message = append(message, payload...)
+		if !fin {
+			continue
+		}
+		if opcode == opBinary {
+			c.fail(closeUnsupported, "binary messages are not supported")
+			return nil, errors.New("websocket: binary message")
+		}
+
+		return message, nil
+	}
+}
+
+// readFrame reads and unmasks one frame.
+func (c *conn) readFrame() (bool, byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin := header[0]&0x80 != 0
+	opcode := header[0] & 0x0F
+	if header[1]&0x80 == 0 {
+		c.fail(closeProtocol, "client frames must be masked")
+		return false, 0, nil, errors.New("websocket: unmasked client frame")
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	if length > maxMessageBytes {
+		c.fail(closeTooBig, "message too big")
+		return false, 0, nil, errors.New("websocket: frame too big")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for index := range payload {
+		payload[index] ^= mask[index%4]
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// writeText sends payload as one text message.
+func (c *conn) writeText(payload []byte) error {
+	return c.writeFrame(opText, payload)
+}
+
+// writeFrame sends one unmasked, unfragmented frame.
+func (c *conn) writeFrame(opcode byte, payload []byte) error {
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | opcode
+	switch length := len(payload); {
+	case length < 126:
+		header[1] = byte(length)
+	case length <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_ = c.raw.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.raw.Write(append(header, payload...)); err != nil {
+		return fmt.Errorf("websocket: write: %w", err)
+	}
+
+	return nil
+}
+
+// fail sends a close frame with code and reason before the caller drops the
+// connection.
+func (c *conn) fail(code int, reason string) {
+	_ = c.writeFrame(opClose, closePayload(code, reason))
+}
+
+// closePayload builds the body of a close frame.
+func closePayload(code int, reason string) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(code)), reason...)
+}
+
+// close sends a normal close frame and closes the connection.
+func (c *conn) close() error {
+	_ = c.writeFrame(opClose, closePayload(closeNormal, ""))
+	return c.raw.Close()
+}
@@ -0,0 +1,497 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	agentruntime "miniclaw/pkg/agent/runtime"
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/channel"
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+const channelName = "websocket"
+
+const (
+	defaultHost     = "127.0.0.1"
+	defaultPort     = 18792
+	shutdownTimeout = 5 * time.Second
+)
+
+// Server message types.
+const (
+	messageDelta     = "delta"
+	messageToolEvent = "tool_event"
+	messageDone      = "done"
+	messageError     = "error"
+	messageNotify    = "notify"
+)
+
+// Adapter serves the WebSocket chat channel: clients send prompts as JSON
+// messages on /v1/ws and get the reply streamed back as deltas and tool
+// events, followed by one done or error message per prompt. Upgrades need the
+// gateway.auth credentials, and each caller's session keys are its own.
+type Adapter struct {
+	addr string
+	auth *channel.Auth
+	log  *slog.Logger
+	// webUI serves the embedded browser chat page on the gateway server; see
+	// PagePath.
+	webUI bool
+	pages http.Handler
+
+	// runMu guards the handler and context of Run, which the web chat socket
+	// uses.
+	runMu   sync.Mutex
+	runCtx  context.Context
+	handler channel.Handler
+
+	ticketsMu sync.Mutex
+	tickets   map[string]pageTicket
+
+	originsMu      sync.RWMutex
+	allowedOrigins []string
+
+	// connsMu guards the open connections and the caller-scoped chat IDs
+	// each one has used, which Notify delivers to.
+	connsMu sync.Mutex
+	conns   map[*conn]map[string]struct{}
+}
+
+// clientMessage is one prompt sent by the client.
+type clientMessage struct {
+	// ID is echoed on every server message about this prompt so clients can
+	// run several prompts on one connection.
+	ID string `json:"id,omitempty"`
+	// SessionKey names the conversation; it defaults to the session_key query
+	// parameter of the connection.
+	SessionKey string            `json:"session_key,omitempty"`
+	Prompt     string            `json:"prompt"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+}
+
+// serverMessage is one message sent to the client. Type says which of the
+// other fields are set.
+type serverMessage struct {
+	ID            string          `json:"id,omitempty"`
+	Type          string          `json:"type"`
+	Text          string          `json:"text,omitempty"`
+	ToolEvent     *toolEventJSON  `json:"tool_event,omitempty"`
+	Agent         string          `json:"agent,omitempty"`
+	Usage         *usageJSON      `json:"usage,omitempty"`
+	ToolEvents    []toolEventJSON `json:"tool_events,omitempty"`
+	Error         string          `json:"error,omitempty"`
+	ErrorCategory string          `json:"error_category,omitempty"`
+	// TraceID names the prompt in gateway logs and events; done and error
+	// messages carry it.
+	TraceID string `json:"trace_id,omitempty"`
+}
+
+type usageJSON struct {
+	InputTokens         int64 `json:"input_tokens"`
+	OutputTokens        int64 `json:"output_tokens"`
+	TotalTokens         int64 `json:"total_tokens"`
+	ReasoningTokens     int64 `json:"reasoning_tokens,omitempty"`
+	CacheCreationTokens int64 `json:"cache_creation_tokens,omitempty"`
+	CacheReadTokens     int64 `json:"cache_read_tokens,omitempty"`
+}
+
+type toolEventJSON struct {
+	Kind       string `json:"kind"`
+	Tool       string `json:"tool"`
+	Payload    string `json:"payload,omitempty"`
+	Failed     bool   `json:"failed,omitempty"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
+}
+
+// NewAdapter validates WebSocket channel configuration and constructs an
+// adapter that checks upgrades with auth. It refuses to listen beyond loopback
+// while auth is off.
+func NewAdapter(cfg config.WebSocketChannelConfig, auth *channel.Auth, log *slog.Logger) (*Adapter, error) {
+	if auth == nil {
+		return nil, errors.New("auth is required")
+	}
+	addr, err := listenAddr(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if host, _, _ := net.SplitHostPort(addr); !auth.Enabled() && !channel.IsLoopbackHost(host) {
+		return nil, fmt.Errorf("channels.websocket.host %s is not loopback; set gateway.auth to accept remote clients", host)
+	}
+
+	if log == nil {
+		log = slog.Default()
+	}
+
+	adapter := &Adapter{
+		addr:           addr,
+		auth:           auth,
+		allowedOrigins: normalizeOrigins(cfg.AllowedOrigins),
+		log:            log.With("component", "channel.websocket"),
+		webUI:          cfg.WebUI,
+		tickets:        make(map[string]pageTicket),
+		conns:          make(map[*conn]map[string]struct{}),
+	}
+	adapter.pages = adapter.pageRoutes()
+
+	return adapter, nil
+}
+
+// listenAddr resolves the listener address of cfg, applying the defaults.
+func listenAddr(cfg config.WebSocketChannelConfig) (string, error) {
+	host := strings.TrimSpace(cfg.Host)
+	if host == "" {
+		host = defaultHost
+	}
+	port := cfg.Port
+	if port == 0 {
+		port = defaultPort
+	}
+	if port < 0 || port > 65535 {
+		return "", fmt.Errorf("channels.websocket.port %d is out of range", cfg.Port)
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(port)), nil
+}
+
+// normalizeOrigins trims allowed_origins entries and drops empty ones.
+func normalizeOrigins(origins []string) []string {
+	allowedOrigins := make([]string, 0, len(origins))
+	for _, origin := range origins {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			allowedOrigins = append(allowedOrigins, origin)
+		}
+	}
+
+	return allowedOrigins
+}
+
+// Reload applies changed allowed_origins to new connections. Host, port, and
+// web_ui changes need a restart.
+func (a *Adapter) Reload(cfg config.ChannelsConfig) error {
+	a.originsMu.Lock()
+	a.allowedOrigins = normalizeOrigins(cfg.WebSocket.AllowedOrigins)
+	a.originsMu.Unlock()
+
+	addr, err := listenAddr(cfg.WebSocket)
+	if err != nil {
+		return err
+	}
+	if addr != a.addr || cfg.WebSocket.WebUI != a.webUI {
+		return errors.New("channels.websocket.host, port, and web_ui changes need a restart")
+	}
+
+	return nil
+}
+
+// Name returns the channel identifier used in bus metadata and logs.
+func (a *Adapter) Name() string {
+	return channelName
+}
+
+// Run serves WebSocket connections until ctx ends, passing each prompt to
+// handler.
+func (a *Adapter) Run(ctx context.Context, handler channel.Handler) error {
+	if handler == nil {
+		return errors.New("handler is required")
+	}
+
+	listener, err := net.Listen("tcp", a.addr)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", a.addr, err)
+	}
+
+	server := &http.Server{
+		Handler:           a.routes(handler),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	a.setRunning(ctx, handler)
+	defer a.setRunning(nil, nil)
+
+	a.log.Info("WebSocket channel started", "address", listener.Addr().String())
+	if a.webUI {
+		a.log.Info("Web chat UI available on the gateway status server", "path", pagePath)
+	}
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serve websocket channel: %w", err)
+	}
+
+	return nil
+}
+
+// routes returns the WebSocket endpoint handler.
+func (a *Adapter) routes(handler channel.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/ws", a.handleConnect(handler))
+	return mux
+}
+
+// handleConnect authenticates one request and serves its connection until the
+// client leaves or the request context ends.
+func (a *Adapter) handleConnect(handler channel.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !a.originAllowed(r) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		caller, ok := a.auth.Authenticate(r)
+		if !ok {
+			a.auth.Deny(w, http.StatusUnauthorized, "missing or invalid credentials")
+			return
+		}
+		a.accept(r.Context(), w, r, handler, caller)
+	}
+}
+
+// accept upgrades r and serves the connection of caller until the client
+// leaves or ctx ends.
+func (a *Adapter) accept(ctx context.Context, w http.ResponseWriter, r *http.Request, handler channel.Handler, caller channel.Caller) {
+	c, err := upgrade(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	a.log.Info("Client connected", "remote_addr", r.RemoteAddr, "caller", caller.Name)
+	a.serve(ctx, c, handler, caller, strings.TrimSpace(r.URL.Query().Get("session_key")))
+	a.log.Info("Client disconnected", "remote_addr", r.RemoteAddr)
+}
+
+// originAllowed accepts requests without an Origin header, such as those from
+// scripts, and browser requests from the same host or a configured origin.
+func (a *Adapter) originAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	a.originsMu.RLock()
+	allowed := slices.Contains(a.allowedOrigins, strings.TrimRight(origin, "/"))
+	a.originsMu.RUnlock()
+	if allowed {
+		return true
+	}
+	parsed, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(parsed.Host, r.Host)
+}
+
+// serve reads prompts from c and runs each one concurrently, so a /stop or
+// an approval reply can arrive while a prompt is running. Session keys are
+// scoped to caller.
+func (a *Adapter) serve(ctx context.Context, c *conn, handler channel.Handler, caller channel.Caller, defaultKey string) {
+	ctx, cancel := context.WithCancel(ctx)
+	var prompts sync.WaitGroup
+	if defaultKey == "" {
+		a.track(c, "")
+	} else {
+		a.track(c, channel.ScopeChat(caller, defaultKey))
+	}
+	defer func() {
+		a.untrack(c)
+		cancel()
+		prompts.Wait()
+		_ = c.close()
+	}()
+
+	// Unblock readMessage when the gateway shuts down.
+	go func() {
+		<-ctx.Done()
+		_ = c.raw.SetReadDeadline(time.Now())
+	}()
+
+	for {
+		data, err := c.readMessage()
+		if err != nil {
+			if !errors.Is(err, errClosed) && !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				a.log.Warn("WebSocket read failed", "error", err)
+			}
+			return
+		}
+
+		var message clientMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			a.send(c, serverMessage{Type: messageError, Error: "invalid JSON message: " + err.Error()})
+			continue
+		}
+		key := strings.TrimSpace(message.SessionKey)
+		if key == "" {
+			key = defaultKey
+		}
+		prompt := strings.TrimSpace(message.Prompt)
+		if key == "" || prompt == "" {
+			a.send(c, serverMessage{ID: message.ID, Type: messageError, Error: "session_key and prompt are required"})
+			continue
+		}
+		chatID := channel.ScopeChat(caller, key)
+		a.track(c, chatID)
+
+		prompts.Go(func() {
+			a.runPrompt(ctx, c, handler, message, caller, chatID, prompt)
+		})
+	}
+}
+
+// runPrompt passes one prompt to handler, streaming text deltas and tool
+// events to the client as they happen and the outcome when it returns.
+func (a *Adapter) runPrompt(ctx context.Context, c *conn, handler channel.Handler, message clientMessage, caller channel.Caller, chatID string, prompt string) {
+	inbound := bus.InboundMessage{
+		Channel:    channelName,
+		ChatID:     chatID,
+		SenderID:   caller.Name,
+		SessionKey: sessionKey(chatID),
+		Content:    prompt,
+		Metadata:   message.Metadata,
+	}
+	traceID := channel.TraceInbound(&inbound)
+	log := a.log.With("trace_id", traceID)
+	log.Info("Received message", "session_key", inbound.SessionKey, "id", message.ID)
+
+	promptCtx := providertypes.WithTextDeltaHandler(ctx, func(delta string) {
+		a.send(c, serverMessage{ID: message.ID, Type: messageDelta, Text: delta})
+	})
+	promptCtx = providertypes.WithToolEventHandler(promptCtx, func(event providertypes.ToolEvent) {
+		toolEvent := toolEventJSON(event)
+		a.send(c, serverMessage{ID: message.ID, Type: messageToolEvent, ToolEvent: &toolEvent})
+	})
+
+	outbound, err := handler(promptCtx, inbound)
+	if err != nil {
+		category := providertypes.ErrorCategoryOf(err)
+		log.Error("Failed to process inbound message", "session_key", inbound.SessionKey, "error", err)
+		a.send(c, serverMessage{
+			ID:            message.ID,
+			Type:          messageError,
+			Error:         providertypes.UserMessage(err),
+			ErrorCategory: string(category),
+			TraceID:       traceID,
+		})
+		return
+	}
+
+	done := newDoneMessage(message.ID, outbound)
+	done.TraceID = traceID
+	a.send(c, done)
+}
+
+// newDoneMessage reads the reply text, usage, and tool events from outbound.
+// Text is always the full reply, whether or not it was streamed; tool events
+// already streamed as they happened are not repeated.
+func newDoneMessage(id string, outbound bus.OutboundMessage) serverMessage {
+	metadata := agentruntime.ReadMetadata(outbound)
+	done := serverMessage{
+		ID:    id,
+		Type:  messageDone,
+		Text:  outbound.Content,
+		Agent: metadata.Agent(),
+	}
+	if usage := metadata.Usage(); usage != nil {
+		done.Usage = &usageJSON{
+			InputTokens:         usage.InputTokens,
+			OutputTokens:        usage.OutputTokens,
+			TotalTokens:         usage.TotalTokens,
+			ReasoningTokens:     usage.ReasoningTokens,
+			CacheCreationTokens: usage.CacheCreationTokens,
+			CacheReadTokens:     usage.CacheReadTokens,
+		}
+	}
+	for _, event := range metadata.ToolEvents() {
+		done.ToolEvents = append(done.ToolEvents, toolEventJSON(event))
+	}
+
+	return done
+}
+
+// send writes one server message, logging instead of failing when the client
+// has gone away.
+func (a *Adapter) send(c *conn, message serverMessage) {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		a.log.Error("Failed to encode WebSocket message", "error", err)
+		return
+	}
+	if err := c.writeText(payload); err != nil {
+		a.log.Debug("Failed to send WebSocket message", "type", message.Type, "error", err)
+	}
+}
+
+// Notify pushes a notify message to every open connection that has used the
+// chat outbound.ChatID, the caller-scoped session key of its inbound messages,
+// either as its session_key query parameter or in a prompt. It fails when no
+// such connection is open, as the WebSocket channel keeps no messages for
+// clients that come back later.
+func (a *Adapter) Notify(_ context.Context, outbound bus.OutboundMessage) error {
+	key := strings.TrimSpace(outbound.ChatID)
+	text := strings.TrimSpace(outbound.Content)
+	if text == "" && strings.TrimSpace(outbound.Error) == "" {
+		return nil
+	}
+
+	a.connsMu.Lock()
+	var targets []*conn
+	for c, keys := range a.conns {
+		if _, ok := keys[key]; ok {
+			targets = append(targets, c)
+		}
+	}
+	a.connsMu.Unlock()
+	if len(targets) == 0 {
+		return fmt.Errorf("no websocket client is connected for session %q", key)
+	}
+
+	message := serverMessage{Type: messageNotify, Text: text, Error: strings.TrimSpace(outbound.Error)}
+	a.log.Info("Sending notification", "session_key", sessionKey(key), "connections", len(targets))
+	for _, c := range targets {
+		a.send(c, message)
+	}
+
+	return nil
+}
+
+// track records that c has used the caller-scoped chat ID key, or just that
+// c is open when key is empty.
+func (a *Adapter) track(c *conn, key string) {
+	a.connsMu.Lock()
+	defer a.connsMu.Unlock()
+
+	keys, ok := a.conns[c]
+	if !ok {
+		keys = make(map[string]struct{}, 1)
+		a.conns[c] = keys
+	}
+	if key != "" {
+		keys[key] = struct{}{}
+	}
+}
+
+// untrack forgets c once it closes.
+func (a *Adapter) untrack(c *conn) {
+	a.connsMu.Lock()
+	defer a.connsMu.Unlock()
+
+	delete(a.conns, c)
+}
+
+// sessionKey maps one scoped chat ID to one runtime session namespace.
+func sessionKey(key string) string {
+	return channelName + ":" + strings.TrimSpace(key)
+}
@@ -0,0 +1,412 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	agentruntime "miniclaw/pkg/agent/runtime"
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/channel"
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+// newAuth builds the gateway.auth checker of cfg.
+func newAuth(t *testing.T, cfg config.GatewayAuthConfig) *channel.Auth {
+	t.Helper()
+
+	auth, err := channel.NewAuth(cfg)
+	if err != nil {
+		t.Fatalf("NewAuth: %v", err)
+	}
+	return auth
+}
+
+func TestAcceptKeyMatchesRFCExample(t *testing.T) {
+	if got := acceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("acceptKey = %q", got)
+	}
+}
+
+func TestWebUIServedBehindAuthWithTicketedSocket(t *testing.T) {
+	auth := newAuth(t, config.GatewayAuthConfig{Token: "secret"})
+	adapter, err := NewAdapter(config.WebSocketChannelConfig{Enabled: true, WebUI: true}, auth, nil)
+	if err != nil {
+		t.Fatalf("NewAdapter: %v", err)
+	}
+	if adapter.PagePath() != "/chat/" {
+		t.Fatalf("PagePath = %q, want /chat/", adapter.PagePath())
+	}
+	server := httptest.NewServer(adapter)
+	defer server.Close()
+	get := func(path string, authorize bool) (*http.Response, string) {
+		t.Helper()
+		request, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if authorize {
+			request.Header.Set("Authorization", "Bearer secret")
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer response.Body.Close()
+		body, _ := io.ReadAll(response.Body)
+		return response, string(body)
+	}
+
+	if response, _ := get("/chat/", false); response.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("index without credentials = %d, want 401", response.StatusCode)
+	}
+	response, body := get("/chat/", true)
+	if response.StatusCode != http.StatusOK || !strings.Contains(body, `<script src="app.js">`) {
+		t.Fatalf("index = %d %q", response.StatusCode, body)
+	}
+	if response.Header.Get("Content-Security-Policy") == "" {
+		t.Fatal("index should carry a content security policy")
+	}
+	var ticket *http.Cookie
+	for _, cookie := range response.Cookies() {
+		if cookie.Name == ticketCookie {
+			ticket = cookie
+		}
+	}
+	if ticket == nil || !ticket.HttpOnly || ticket.SameSite != http.SameSiteStrictMode {
+		t.Fatalf("ticket cookie = %+v, want an HttpOnly SameSite=Strict cookie", ticket)
+	}
+	if response, body := get("/chat/app.js", true); response.StatusCode != http.StatusOK || !strings.Contains(body, `new URL("ws", location.href)`) {
+		t.Fatalf("app.js = %d", response.StatusCode)
+	}
+
+	// The socket opens with the ticket once the channel runs.
+	if response, _ := get("/chat/ws", false); response.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("socket before Run = %d, want 503", response.StatusCode)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	inbounds := make(chan bus.InboundMessage, 1)
+	adapter.setRunning(ctx, func(_ context.Context, inbound bus.InboundMessage) (bus.OutboundMessage, error) {
+		inbounds <- inbound
+		return bus.OutboundMessage{Content: "ok"}, nil
+	})
+	if response, _ := get("/chat/ws", false); response.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("socket without a ticket = %d, want 401", response.StatusCode)
+	}
+	client := dial(t, server, "/chat/ws?session_key=web-1", "Cookie: "+ticket.Name+"="+ticket.Value)
+	defer client.Close()
+	client.send(t, `{"prompt":"hi"}`)
+	if message := client.read(t); message.Type != messageDone {
+		t.Fatalf("prompt reply = %+v", message)
+	}
+	if inbound := <-inbounds; inbound.SessionKey != "websocket:operator:web-1" {
+		t.Fatalf("session = %q, want the page loader's", inbound.SessionKey)
+	}
+
+	disabled, err := NewAdapter(config.WebSocketChannelConfig{Enabled: true}, auth, nil)
+	if err != nil {
+		t.Fatalf("NewAdapter: %v", err)
+	}
+	if disabled.PagePath() != "" {
+		t.Fatalf("PagePath without web_ui = %q, want none", disabled.PagePath())
+	}
+}
+
+func TestPromptStreamsDeltasToolEventsAndDone(t *testing.T) {
+	adapter, err := NewAdapter(config.WebSocketChannelConfig{Enabled: true}, newAuth(t, config.GatewayAuthConfig{}), nil)
+	if err != nil {
+		t.Fatalf("NewAdapter: %v", err)
+	}
+
+	inbounds := make(chan bus.InboundMessage, 1)
+	handler := func(ctx context.Context, inbound bus.InboundMessage) (bus.OutboundMessage, error) {
+		inbounds <- inbound
+		onDelta, ok := providertypes.TextDeltaHandlerFromContext(ctx)
+		if !ok {
+			return bus.OutboundMessage{}, fmt.Errorf("no text delta handler")
+		}
+		providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "call", Tool: "read_file", Payload: `{"path":"a.txt"}`})
+		onDelta("hel")
+		onDelta("lo")
+		result := providertypes.PromptResult{
+			Text:     "hello",
+			Metadata: providertypes.PromptMetadata{Usage: &providertypes.TokenUsage{InputTokens: 3, OutputTokens: 2, TotalTokens: 5}},
+		}
+		return bus.OutboundMessage{Content: result.Text, Metadata: agentruntime.PromptResultMetadata(result)}, nil
+	}
+
+	server := httptest.NewServer(adapter.routes(handler))
+	defer server.Close()
+	client := dial(t, server, "/v1/ws?session_key=web-1")
+	defer client.Close()
+
+	client.send(t, `{"id":"m1","prompt":"say hello","metadata":{"source":"test"}}`)
+
+	var messages []serverMessage
+	for len(messages) == 0 || messages[len(messages)-1].Type != messageDone {
+		messages = append(messages, client.read(t))
+	}
+	inbound := <-inbounds
+	if inbound.Channel != "websocket" || inbound.SessionKey != "websocket:web-1" || inbound.Content != "say hello" || inbound.Metadata["source"] != "test" {
+		t.Fatalf("inbound = %+v", inbound)
+	}
+
+	var types []string
+	for _, message := range messages {
+		if message.ID != "m1" {
+			t.Fatalf("message %+v lacks the prompt id", message)
+		}
+		types = append(types, message.Type)
+	}
+	if got := strings.Join(types, ","); got != "tool_event,delta,delta,done" {
+		t.Fatalf("message types = %s", got)
+	}
+	if messages[0].ToolEvent == nil || messages[0].ToolEvent.Tool != "read_file" {
+		t.Fatalf("tool event = %+v", messages[0])
+	}
+	if messages[1].Text+messages[2].Text != "hello" {
+		t.Fatalf("deltas = %q, %q", messages[1].Text, messages[2].Text)
+	}
+	done := messages[3]
+	if done.Text != "hello" || done.Usage == nil || done.Usage.TotalTokens != 5 {
+		t.Fatalf("done = %+v", done)
+	}
+}
+
+func TestPromptErrorsAndRejectedUpgrades(t *testing.T) {
+	adapter, err := NewAdapter(config.WebSocketChannelConfig{Enabled: true, AllowedOrigins: []string{"https://chat.example.com/"}}, newAuth(t, config.GatewayAuthConfig{}), nil)
+	if err != nil {
+		t.Fatalf("NewAdapter: %v", err)
+	}
+	handler := func(context.Context, bus.InboundMessage) (bus.OutboundMessage, error) {
+		return bus.OutboundMessage{}, &providertypes.PromptError{Category: providertypes.ErrorRateLimit, Err: context.DeadlineExceeded}
+	}
+	server := httptest.NewServer(adapter.routes(handler))
+	defer server.Close()
+
+	request, _ := http.NewRequest(http.MethodGet, server.URL+"/v1/ws", nil)
+	request.Header.Set("Origin", "https://evil.example.com")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusForbidden {
+		t.Fatalf("foreign origin status = %d, want 403", response.StatusCode)
+	}
+
+	response, err = http.Get(server.URL + "/v1/ws")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusBadRequest {
+		t.Fatalf("plain GET status = %d, want 400", response.StatusCode)
+	}
+
+	// A reload that allows the origin lets new connections from it upgrade.
+	if err := adapter.Reload(config.ChannelsConfig{WebSocket: config.WebSocketChannelConfig{AllowedOrigins: []string{"https://evil.example.com"}}}); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if !adapter.originAllowed(request) {
+		t.Fatal("reloaded origin should be allowed")
+	}
+	if err := adapter.Reload(config.ChannelsConfig{WebSocket: config.WebSocketChannelConfig{Port: 9999}}); err == nil {
+		t.Fatal("a port change should need a restart")
+	}
+
+	client := dial(t, server, "/v1/ws")
+	defer client.Close()
+
+	client.send(t, `{"id":"m1","prompt":"hi"}`)
+	if message := client.read(t); message.Type != messageError || message.ID != "m1" || message.ErrorCategory != "" {
+		t.Fatalf("missing session key reply = %+v", message)
+	}
+	client.send(t, `{"id":"m2","session_key":"s","prompt":"hi"}`)
+	if message := client.read(t); message.Type != messageError || message.ID != "m2" || message.ErrorCategory != "rate_limit" || message.Error == "" {
+		t.Fatalf("rate limit reply = %+v", message)
+	}
+}
+
+func TestNotifyPushesToClientsOfTheSession(t *testing.T) {
+	adapter, err := NewAdapter(config.WebSocketChannelConfig{Enabled: true}, newAuth(t, config.GatewayAuthConfig{}), nil)
+	if err != nil {
+		t.Fatalf("NewAdapter: %v", err)
+	}
+	handler := func(context.Context, bus.InboundMessage) (bus.OutboundMessage, error) {
+		return bus.OutboundMessage{Content: "ok"}, nil
+	}
+	server := httptest.NewServer(adapter.routes(handler))
+	defer server.Close()
+
+	first := dial(t, server, "/v1/ws?session_key=web-1")
+	defer first.Close()
+	second := dial(t, server, "/v1/ws")
+	defer second.Close()
+	// A finished prompt means each connection is tracked under its key.
+	first.send(t, `{"prompt":"hi"}`)
+	second.send(t, `{"session_key":"web-2","prompt":"hi"}`)
+	for _, client := range []*testClient{first, second} {
+		if message := client.read(t); message.Type != messageDone {
+			t.Fatalf("prompt reply = %+v", message)
+		}
+	}
+
+	if err := adapter.Notify(context.Background(), bus.OutboundMessage{ChatID: "web-2", Content: "build finished"}); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if message := second.read(t); message.Type != messageNotify || message.Text != "build finished" || message.ID != "" {
+		t.Fatalf("notification = %+v", message)
+	}
+	if err := adapter.Notify(context.Background(), bus.OutboundMessage{ChatID: "web-1", Error: "backup failed"}); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if message := first.read(t); message.Type != messageNotify || message.Error != "backup failed" {
+		t.Fatalf("notification = %+v", message)
+	}
+	if err := adapter.Notify(context.Background(), bus.OutboundMessage{ChatID: "web-3", Content: "hello"}); err == nil {
+		t.Fatal("expected an error for a session without connections")
+	}
+}
+
+func TestConnectRequiresAuthAndScopesSessionsToTheCaller(t *testing.T) {
+	if _, err := NewAdapter(config.WebSocketChannelConfig{Host: "0.0.0.0"}, newAuth(t, config.GatewayAuthConfig{}), nil); err == nil {
+		t.Fatal("expected a non-loopback listener without gateway.auth to be refused")
+	}
+	auth := newAuth(t, config.GatewayAuthConfig{Token: "secret", Clients: []config.GatewayAuthClientConfig{{Name: "ci", Token: "ci-token"}}})
+	adapter, err := NewAdapter(config.WebSocketChannelConfig{Enabled: true}, auth, nil)
+	if err != nil {
+		t.Fatalf("NewAdapter: %v", err)
+	}
+	inbounds := make(chan bus.InboundMessage, 2)
+	handler := func(_ context.Context, inbound bus.InboundMessage) (bus.OutboundMessage, error) {
+		inbounds <- inbound
+		return bus.OutboundMessage{Content: "ok"}, nil
+	}
+	server := httptest.NewServer(adapter.routes(handler))
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/v1/ws")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("unauthenticated upgrade = %d, want 401", response.StatusCode)
+	}
+
+	operator := dial(t, server, "/v1/ws?session_key=web-1", "Authorization: Bearer secret")
+	defer operator.Close()
+	client := dial(t, server, "/v1/ws?session_key=web-1", "Authorization: Bearer ci-token")
+	defer client.Close()
+	sessions := map[string]bool{}
+	for _, c := range []*testClient{operator, client} {
+		c.send(t, `{"prompt":"hi"}`)
+		if message := c.read(t); message.Type != messageDone {
+			t.Fatalf("prompt reply = %+v", message)
+		}
+		inbound := <-inbounds
+		sessions[inbound.SessionKey] = true
+	}
+	if !sessions["websocket:operator:web-1"] || !sessions["websocket:ci:web-1"] {
+		t.Fatalf("sessions = %v, want one per caller", sessions)
+	}
+
+	// Notify reaches only the caller the chat ID belongs to.
+	if err := adapter.Notify(context.Background(), bus.OutboundMessage{ChatID: "ci:web-1", Content: "for ci"}); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if message := client.read(t); message.Type != messageNotify || message.Text != "for ci" {
+		t.Fatalf("notification = %+v", message)
+	}
+	if err := adapter.Notify(context.Background(), bus.OutboundMessage{ChatID: "web-1", Content: "unscoped"}); err == nil {
+		t.Fatal("expected an unscoped chat ID to reach no connection")
+	}
+}
+
+// testClient is a minimal WebSocket client: it masks what it sends and reads
+// unfragmented server frames.
+type testClient struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// dial opens a WebSocket connection to path, sending the extra header lines.
+func dial(t *testing.T, server *httptest.Server, path string, headers ...string) *testClient {
+	t.Helper()
+
+	raw, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	_ = raw.SetDeadline(time.Now().Add(5 * time.Second))
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	var extra strings.Builder
+	for _, header := range headers {
+		extra.WriteString(header + "\r\n")
+	}
+	fmt.Fprintf(raw, "GET %s HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n%s\r\n", path, server.Listener.Addr(), key, extra.String())
+
+	reader := bufio.NewReader(raw)
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("read handshake: %v", err)
+	}
+	if response.StatusCode != http.StatusSwitchingProtocols || response.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		t.Fatalf("handshake = %d %v", response.StatusCode, response.Header)
+	}
+
+	return &testClient{Conn: raw, reader: reader}
+}
+
+func (c *testClient) send(t *testing.T, text string) {
+	t.Helper()
+
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opText, 0x80 | byte(len(text))}
+	frame = append(frame, mask[:]...)
+	for index := range len(text) {
+		frame = append(frame, text[index]^mask[index%4])
+	}
+	if _, err := c.Write(frame); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+}
+
+func (c *testClient) read(t *testing.T) serverMessage {
+	t.Helper()
+
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		t.Fatalf("read frame: %v", err)
+	}
+	length := int(header[1] & 0x7F)
+	if length == 126 {
+		var extended [2]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			t.Fatalf("read frame length: %v", err)
+		}
+		length = int(binary.BigEndian.Uint16(extended[:]))
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		t.Fatalf("read frame payload: %v", err)
+	}
+	if opcode := header[0] & 0x0F; opcode != opText {
+		t.Fatalf("frame opcode = %d, payload %q", opcode, payload)
+	}
+
+	var message serverMessage
+	if err := json.Unmarshal(payload, &message); err != nil {
+		t.Fatalf("decode %q: %v", payload, err)
+	}
+	return message
+}
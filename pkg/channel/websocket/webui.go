@@ -0,0 +1,170 @@
+package websocket
+
+import (
+	"context"
+	"crypto/rand"
+	"embed"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"time"
+
+	"miniclaw/pkg/channel"
+)
+
+const (
+	// pagePath is where the gateway serves the web chat; its socket is
+	// pagePath + "ws".
+	pagePath = "/chat/"
+	// ticketCookie carries the ticket that lets the page's socket in, as
+	// browsers cannot send credentials on a WebSocket upgrade.
+	ticketCookie = "miniclaw_chat"
+	// ticketTTL is how long a ticket opens new connections; the page reloads
+	// it before reconnecting.
+	ticketTTL = 12 * time.Hour
+)
+
+// webUIFiles holds the browser chat page. It connects back to the socket next
+// to it on the gateway host that served it, so it passes the same-host origin
+// check without allowed_origins.
+//
+//go:embed webui
+var webUIFiles embed.FS
+
+// pageTicket is who loaded the web chat with the gateway.auth credentials,
+// and until when the page may open connections as them.
+type pageTicket struct {
+	caller  channel.Caller
+	expires time.Time
+}
+
+// PagePath returns the path the gateway serves the web chat under, or "" when
+// web_ui is off.
+func (a *Adapter) PagePath() string {
+	if !a.webUI {
+		return ""
+	}
+
+	return pagePath
+}
+
+// ServeHTTP serves the web chat page behind the gateway.auth operator
+// credentials, handing each load a ticket cookie, and its socket to clients
+// with a ticket or credentials.
+func (a *Adapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.pages.ServeHTTP(w, r)
+}
+
+// pageRoutes returns the handler of the web chat page and its socket.
+func (a *Adapter) pageRoutes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+pagePath+"ws", a.handlePageConnect)
+	mux.Handle("GET "+pagePath, a.auth.Require(a.issueTicket(http.StripPrefix(pagePath[:len(pagePath)-1], webUIHandler()))))
+	return mux
+}
+
+// webUIHandler serves the embedded chat page and its assets.
+func webUIHandler() http.Handler {
+	root, err := fs.Sub(webUIFiles, "webui")
+	if err != nil {
+		panic(err)
+	}
+	files := http.FileServerFS(root)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Content-Security-Policy", "default-src 'self'; connect-src 'self' ws: wss:")
+		files.ServeHTTP(w, r)
+	})
+}
+
+// issueTicket sets a fresh ticket cookie on each load of the page itself
+// while gateway.auth is on.
+func (a *Adapter) issueTicket(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == pagePath && a.auth.Enabled() {
+			caller, _ := a.auth.Authenticate(r)
+			random := make([]byte, 32)
+			_, _ = rand.Read(random)
+			ticket := hex.EncodeToString(random)
+			now := time.Now()
+
+			a.ticketsMu.Lock()
+			for id, issued := range a.tickets {
+				if now.After(issued.expires) {
+					delete(a.tickets, id)
+				}
+			}
+			a.tickets[ticket] = pageTicket{caller: caller, expires: now.Add(ticketTTL)}
+			a.ticketsMu.Unlock()
+
+			http.SetCookie(w, &http.Cookie{
+				Name:     ticketCookie,
+				Value:    ticket,
+				Path:     pagePath,
+				MaxAge:   int(ticketTTL.Seconds()),
+				HttpOnly: true,
+				Secure:   r.TLS != nil,
+				SameSite: http.SameSiteStrictMode,
+			})
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ticketCaller returns the caller of the unexpired ticket cookie r carries.
+func (a *Adapter) ticketCaller(r *http.Request) (channel.Caller, bool) {
+	cookie, err := r.Cookie(ticketCookie)
+	if err != nil {
+		return channel.Caller{}, false
+	}
+
+	a.ticketsMu.Lock()
+	defer a.ticketsMu.Unlock()
+	ticket, ok := a.tickets[cookie.Value]
+	if !ok || time.Now().After(ticket.expires) {
+		return channel.Caller{}, false
+	}
+
+	return ticket.caller, true
+}
+
+// handlePageConnect serves the web chat socket on the gateway server while
+// Run is serving the channel, ending its connections when Run returns.
+func (a *Adapter) handlePageConnect(w http.ResponseWriter, r *http.Request) {
+	a.runMu.Lock()
+	runCtx, handler := a.runCtx, a.handler
+	a.runMu.Unlock()
+	if handler == nil {
+		http.Error(w, "websocket channel is not running", http.StatusServiceUnavailable)
+		return
+	}
+	if !a.originAllowed(r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	caller, ok := a.auth.Authenticate(r)
+	if !ok {
+		caller, ok = a.ticketCaller(r)
+	}
+	if !ok {
+		a.auth.Deny(w, http.StatusUnauthorized, "missing or expired login; reload the page")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	stop := context.AfterFunc(runCtx, cancel)
+	defer stop()
+	a.accept(ctx, w, r, handler, caller)
+}
+
+// setRunning records the handler and context of Run for the web chat socket,
+// or clears them when handler is nil.
+func (a *Adapter) setRunning(ctx context.Context, handler channel.Handler) {
+	a.runMu.Lock()
+	defer a.runMu.Unlock()
+
+	a.runCtx, a.handler = ctx, handler
+}
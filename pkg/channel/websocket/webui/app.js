@@ -0,0 +1,182 @@
+// MiniClaw web chat: talks to the gateway's WebSocket channel through the
+// socket next to this page and renders streamed replies. All text is inserted
+// as plain text.
+(function () {
+  "use strict";
+
+  const messages = document.getElementById("messages");
+  const statusLabel = document.getElementById("status");
+  const composer = document.getElementById("composer");
+  const promptInput = document.getElementById("prompt");
+  const sendButton = document.getElementById("send");
+  const stopButton = document.getElementById("stop");
+  const newChatButton = document.getElementById("new-chat");
+
+  const sessionStorageKey = "miniclaw.session_key";
+  let sessionKey = localStorage.getItem(sessionStorageKey) || newSessionKey();
+  localStorage.setItem(sessionStorageKey, sessionKey);
+
+  let socket = null;
+  let reconnectDelay = 1000;
+  let nextID = 1;
+  // replies maps a prompt ID to the element its reply streams into.
+  const replies = new Map();
+
+  function newSessionKey() {
+    const random = new Uint8Array(8);
+    crypto.getRandomValues(random);
+    return "web-" + Array.from(random, (b) => b.toString(16).padStart(2, "0")).join("");
+  }
+
+  function setStatus(text) {
+    statusLabel.textContent = text;
+  }
+
+  function addMessage(kind, text) {
+    const element = document.createElement("div");
+    element.className = "message " + kind;
+    const body = document.createElement("span");
+    body.className = "body";
+    body.textContent = text;
+    element.appendChild(body);
+    messages.appendChild(element);
+    messages.scrollTop = messages.scrollHeight;
+    return element;
+  }
+
+  function addMeta(element, text) {
+    const meta = document.createElement("span");
+    meta.className = "meta";
+    meta.textContent = text;
+    element.appendChild(meta);
+  }
+
+  function updateButtons() {
+    const open = socket !== null && socket.readyState === WebSocket.OPEN;
+    sendButton.disabled = !open;
+    stopButton.disabled = !open || replies.size === 0;
+  }
+
+  function connect() {
+    const url = new URL("ws", location.href);
+    url.protocol = location.protocol === "https:" ? "wss:" : "ws:";
+    url.searchParams.set("session_key", sessionKey);
+    socket = new WebSocket(url);
+    setStatus("Connecting…");
+
+    socket.addEventListener("open", () => {
+      reconnectDelay = 1000;
+      setStatus("Connected · " + sessionKey);
+      updateButtons();
+    });
+    socket.addEventListener("message", (event) => handleMessage(JSON.parse(event.data)));
+    socket.addEventListener("close", () => {
+      for (const element of replies.values()) {
+        element.classList.add("error");
+        addMeta(element, "Connection lost before the reply finished.");
+      }
+      replies.clear();
+      updateButtons();
+      setStatus("Disconnected; retrying…");
+      setTimeout(reconnect, reconnectDelay);
+      reconnectDelay = Math.min(reconnectDelay * 2, 30000);
+    });
+  }
+
+  // reconnect reloads the page first, which renews the login ticket cookie
+  // the socket needs once it has expired.
+  function reconnect() {
+    fetch("./", { cache: "no-store" }).catch(() => {}).finally(connect);
+  }
+
+  function handleMessage(message) {
+    const element = replies.get(message.id);
+    if (!element) {
+      if (message.type === "error") {
+        addMessage("error", message.error);
+      }
+      return;
+    }
+    const body = element.querySelector(".body");
+
+    switch (message.type) {
+      case "delta":
+        body.textContent += message.text;
+        break;
+      case "tool_event": {
+        const tool = document.createElement("span");
+        tool.className = "tool";
+        const event = message.tool_event;
+        tool.textContent = (event.failed ? "✗ " : "⚙ ") + event.kind + " " + event.tool;
+        element.insertBefore(tool, body);
+        break;
+      }
+      case "done": {
+        body.textContent = message.text;
+        const details = [];
+        if (message.agent) {
+          details.push(message.agent);
+        }
+        if (message.usage) {
+          details.push(message.usage.total_tokens + " tokens");
+        }
+        if (details.length > 0) {
+          addMeta(element, details.join(" · "));
+        }
+        replies.delete(message.id);
+        break;
+      }
+      case "error":
+        element.classList.add("error");
+        body.textContent = message.error;
+        if (message.trace_id) {
+          addMeta(element, "Trace ID: " + message.trace_id);
+        }
+        replies.delete(message.id);
+        break;
+    }
+    messages.scrollTop = messages.scrollHeight;
+    updateButtons();
+  }
+
+  function send(prompt, showReply) {
+    const id = "m" + nextID++;
+    socket.send(JSON.stringify({ id: id, prompt: prompt }));
+    if (showReply) {
+      replies.set(id, addMessage("assistant", ""));
+    }
+    updateButtons();
+  }
+
+  composer.addEventListener("submit", (event) => {
+    event.preventDefault();
+    const prompt = promptInput.value.trim();
+    if (prompt === "" || socket.readyState !== WebSocket.OPEN) {
+      return;
+    }
+    addMessage("user", prompt);
+    send(prompt, true);
+    promptInput.value = "";
+  });
+
+  promptInput.addEventListener("keydown", (event) => {
+    if (event.key === "Enter" && !event.shiftKey) {
+      event.preventDefault();
+      composer.requestSubmit();
+    }
+  });
+
+  stopButton.addEventListener("click", () => send("/stop", false));
+
+  newChatButton.addEventListener("click", () => {
+    sessionKey = newSessionKey();
+    localStorage.setItem(sessionStorageKey, sessionKey);
+    messages.textContent = "";
+    if (socket) {
+      socket.close();
+    }
+  });
+
+  updateButtons();
+  connect();
+})();
@@ -12,6 +12,10 @@ import (
 const (
 	envTelegramBotToken  = "TELEGRAM_BOT_TOKEN"
 	envTelegramAllowFrom = "TELEGRAM_ALLOW_FROM"
+	envTelegramSecret    = "TELEGRAM_WEBHOOK_SECRET"
+	envAdminToken        = "MINICLAW_ADMIN_TOKEN"
+	envGatewayToken      = "MINICLAW_GATEWAY_TOKEN"
+	envGatewayInstanceID = "MINICLAW_GATEWAY_INSTANCE_ID"
 )
 
 // Config is the root runtime configuration loaded from config.json.
@@ -21,9 +25,48 @@ type Config struct {
 	Providers ProvidersConfig `json:"providers"`
 	Tools     ToolsConfig     `json:"tools,omitempty"`
 	Heartbeat HeartbeatConfig `json:"heartbeat"`
+	Runtime   RuntimeConfig   `json:"runtime,omitempty"`
+	Storage   StorageConfig   `json:"storage,omitempty"`
+	Bus       BusConfig       `json:"bus,omitempty"`
 	Devices   DevicesConfig   `json:"devices"`
 	Gateway   GatewayConfig   `json:"gateway"`
 	Logging   LoggingConfig   `json:"logging,omitempty"`
+	Telemetry TelemetryConfig `json:"telemetry,omitempty"`
+}
+
+// TelemetryConfig selects where gateway metrics are exported. Exporters are
+// independent; any combination can be enabled.
+type TelemetryConfig struct {
+	Prometheus PrometheusTelemetryConfig `json:"prometheus,omitempty"`
+	StatsD     StatsDTelemetryConfig     `json:"statsd,omitempty"`
+	OTLP       OTLPTelemetryConfig       `json:"otlp,omitempty"`
+}
+
+// PrometheusTelemetryConfig serves metrics for scraping on the gateway status server.
+type PrometheusTelemetryConfig struct {
+	Enabled bool `json:"enabled"`
+	// Path defaults to /metrics.
+	Path string `json:"path,omitempty"`
+}
+
+// StatsDTelemetryConfig pushes metrics to a StatsD server over UDP.
+type StatsDTelemetryConfig struct {
+	Enabled bool `json:"enabled"`
+	// Address is host:port; defaults to 127.0.0.1:8125.
+	Address string `json:"address,omitempty"`
+	// Prefix is prepended to every metric name, for example "miniclaw.".
+	Prefix          string `json:"prefix,omitempty"`
+	IntervalSeconds int    `json:"interval_seconds,omitempty"`
+}
+
+// OTLPTelemetryConfig pushes metrics to an OpenTelemetry collector over OTLP/HTTP with JSON encoding.
+type OTLPTelemetryConfig struct {
+	Enabled bool `json:"enabled"`
+	// Endpoint is the collector base URL; /v1/metrics is appended unless present. Defaults to http://127.0.0.1:4318.
+	Endpoint        string            `json:"endpoint,omitempty"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	ServiceName     string            `json:"service_name,omitempty"`
+	IntervalSeconds int               `json:"interval_seconds,omitempty"`
 }
 
 // LoggingConfig controls structured log output format and verbosity.
@@ -36,61 +79,444 @@ type LoggingConfig struct {
 // AgentsConfig contains agent runtime defaults.
 type AgentsConfig struct {
 	Defaults AgentDefaults `json:"defaults"`
+	// Named lists agent profiles: the gateway routes to them with a "!<name>"
+	// message prefix, and `miniclaw agent --agent <name>` runs one locally.
+	Named []NamedAgentConfig `json:"named,omitempty"`
+}
+
+// NamedAgentConfig describes one named agent profile.
+//
+// Empty fields fall back to agents.defaults. Profiles that set Provider,
+// Temperature, MaxTokens, or Tools get their own provider client; the others
+// share the default one.
+type NamedAgentConfig struct {
+	Name string `json:"name"`
+	// Description is shown when a user asks for an unknown agent.
+	Description string `json:"description,omitempty"`
+	// Provider overrides agents.defaults.provider; Model is then required.
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+	// Agent selects a provider-side agent (OpenCode) for this route.
+	Agent string `json:"agent,omitempty"`
+	// Instructions are appended to the default system profile.
+	Instructions string  `json:"instructions,omitempty"`
+	Temperature  float64 `json:"temperature,omitempty"`
+	MaxTokens    int     `json:"max_tokens,omitempty"`
+	// Tools limits fantasy-agent to these tool names.
+	Tools []string `json:"tools,omitempty"`
+}
+
+// OwnClient reports whether the profile needs a provider client of its own
+// rather than the default one.
+func (n NamedAgentConfig) OwnClient() bool {
+	return strings.TrimSpace(n.Provider) != "" || n.Temperature > 0 || n.MaxTokens > 0 || len(n.Tools) > 0
+}
+
+// NamedAgent returns the agents.named profile called name, matched case-insensitively.
+func (a AgentsConfig) NamedAgent(name string) (NamedAgentConfig, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return NamedAgentConfig{}, false
+	}
+	for _, named := range a.Named {
+		if strings.EqualFold(strings.TrimSpace(named.Name), name) {
+			return named, true
+		}
+	}
+
+	return NamedAgentConfig{}, false
+}
+
+// WithNamedAgent returns a copy of c whose agents.defaults apply the named
+// profile, so code that reads the defaults runs as that agent.
+func (c *Config) WithNamedAgent(name string) (*Config, error) {
+	named, ok := c.Agents.NamedAgent(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown agent %q", name)
+	}
+	provider := strings.TrimSpace(named.Provider)
+	model := strings.TrimSpace(named.Model)
+	if provider != "" && model == "" {
+		return nil, fmt.Errorf("agent %q sets provider %q but no model", named.Name, provider)
+	}
+
+	out := *c
+	defaults := &out.Agents.Defaults
+	if provider != "" {
+		defaults.Provider = provider
+	}
+	if model != "" {
+		defaults.Model = model
+	}
+	if named.Temperature > 0 {
+		defaults.Temperature = named.Temperature
+	}
+	if named.MaxTokens > 0 {
+		defaults.MaxTokens = named.MaxTokens
+	}
+	if len(named.Tools) > 0 {
+		defaults.Tools = slices.Clone(named.Tools)
+	}
+	if instructions := strings.TrimSpace(named.Instructions); instructions != "" {
+		defaults.Instructions = strings.TrimSpace(defaults.Instructions + "\n\n" + instructions)
+	}
+
+	return &out, nil
 }
 
 // AgentDefaults describes default model/runtime settings for new agent instances.
 type AgentDefaults struct {
-	Type                string  `json:"type"`
-	Workspace           string  `json:"workspace"`
-	RestrictToWorkspace bool    `json:"restrict_to_workspace"`
-	Provider            string  `json:"provider"`
-	Model               string  `json:"model"`
-	MaxTokens           int     `json:"max_tokens"`
-	Temperature         float64 `json:"temperature"`
-	MaxToolIterations   int     `json:"max_tool_iterations"`
+	Type                string `json:"type"`
+	Workspace           string `json:"workspace"`
+	RestrictToWorkspace bool   `json:"restrict_to_workspace"`
+	// AllowedPaths are absolute directories tools may also use when
+	// RestrictToWorkspace is false; everything else stays blocked.
+	AllowedPaths []string `json:"allowed_paths,omitempty"`
+	// SymlinkPolicy is deny, follow_within_workspace (default), or follow_all.
+	SymlinkPolicy     string  `json:"symlink_policy,omitempty"`
+	Provider          string  `json:"provider"`
+	Model             string  `json:"model"`
+	MaxTokens         int     `json:"max_tokens"`
+	Temperature       float64 `json:"temperature"`
+	MaxToolIterations int     `json:"max_tool_iterations"`
+	// Compaction summarizes older fantasy-agent history once it grows large.
+	Compaction CompactionConfig `json:"compaction,omitempty"`
+	// Context tunes the context window tracking shown in the chat UI and gateway.
+	Context ContextConfig `json:"context,omitempty"`
+	// Retrieval adds workspace snippets relevant to each prompt, found by embedding search.
+	Retrieval RetrievalConfig `json:"retrieval,omitempty"`
+	// Titles names each fantasy-agent session from its first prompt.
+	Titles TitlesConfig `json:"titles,omitempty"`
+	// Instructions are appended to the provider's system profile.
+	Instructions string `json:"instructions,omitempty"`
+	// SystemFiles are workspace files, such as AGENTS.md, appended to the
+	// system profile; they are re-read whenever a session starts.
+	SystemFiles []string `json:"system_files,omitempty"`
+	// Tools limits fantasy-agent to these tool names; empty keeps every enabled tool.
+	Tools []string `json:"tools,omitempty"`
+}
+
+// TitlesConfig controls the short names generated for new sessions.
+type TitlesConfig struct {
+	Enabled bool `json:"enabled"`
+	// Model generates the names, ideally a small, cheap one of the same
+	// provider; empty uses agents.defaults.model.
+	Model string `json:"model,omitempty"`
+}
+
+// CompactionConfig controls automatic summarization of long conversation history.
+type CompactionConfig struct {
+	Enabled bool `json:"enabled"`
+	// ThresholdBytes is the encoded history size that triggers a summary; defaults to 200000.
+	ThresholdBytes int `json:"threshold_bytes,omitempty"`
+	// KeepTurns is how many recent user turns stay verbatim; defaults to 4.
+	KeepTurns int `json:"keep_turns,omitempty"`
+}
+
+// ContextConfig controls how context window usage is measured and flagged.
+type ContextConfig struct {
+	// WindowTokens overrides the built-in context window of the model; needed
+	// for models miniclaw does not know.
+	WindowTokens int `json:"window_tokens,omitempty"`
+	// WarnPercent is the usage at which a session is flagged as nearly full; defaults to 80.
+	WarnPercent int `json:"warn_percent,omitempty"`
+}
+
+// RetrievalConfig controls the workspace embedding index whose best matching
+// snippets are added to each prompt. Embeddings use providers.openai and
+// OPENAI_API_KEY whatever the chat provider is.
+type RetrievalConfig struct {
+	Enabled bool `json:"enabled"`
+	// EmbeddingModel defaults to text-embedding-3-small.
+	EmbeddingModel string `json:"embedding_model,omitempty"`
+	// TopK is the most snippets added to one prompt; defaults to 4.
+	TopK int `json:"top_k,omitempty"`
+	// MinScore drops snippets whose cosine similarity to the prompt is lower; defaults to 0.3.
+	MinScore float64 `json:"min_score,omitempty"`
+	// ChunkLines is the number of lines per indexed snippet; defaults to 40.
+	ChunkLines int `json:"chunk_lines,omitempty"`
+	// MaxFileBytes skips larger files; defaults to 262144.
+	MaxFileBytes int `json:"max_file_bytes,omitempty"`
 }
 
 // ProvidersConfig stores per-provider connection settings.
 type ProvidersConfig struct {
-	OpenCode OpenCodeProviderConfig `json:"opencode"`
-	OpenAI   OpenAIProviderConfig   `json:"openai"`
+	OpenCode  OpenCodeProviderConfig  `json:"opencode"`
+	OpenAI    OpenAIProviderConfig    `json:"openai"`
+	Anthropic AnthropicProviderConfig `json:"anthropic,omitempty"`
+	Mock      MockProviderConfig      `json:"mock,omitempty"`
+}
+
+// MockProviderConfig configures the fixture-replay provider used for regression tests.
+type MockProviderConfig struct {
+	// Fixture is a file written by `miniclaw agent --capture-fixture`.
+	Fixture string `json:"fixture"`
 }
 
 // OpenCodeProviderConfig configures the OpenCode provider client.
 type OpenCodeProviderConfig struct {
-	BaseURL               string `json:"base_url"`
-	Username              string `json:"username"`
-	PasswordEnv           string `json:"password_env"`
-	RequestTimeoutSeconds int    `json:"request_timeout_seconds"`
+	BaseURL               string              `json:"base_url"`
+	Username              string              `json:"username"`
+	PasswordEnv           string              `json:"password_env"`
+	RequestTimeoutSeconds int                 `json:"request_timeout_seconds"`
+	Limits                PayloadLimitsConfig `json:"limits,omitempty"`
 }
 
 // OpenAIProviderConfig configures the OpenAI provider client.
 type OpenAIProviderConfig struct {
+	BaseURL               string              `json:"base_url"`
+	Organization          string              `json:"organization"`
+	Project               string              `json:"project"`
+	RequestTimeoutSeconds int                 `json:"request_timeout_seconds"`
+	Limits                PayloadLimitsConfig `json:"limits,omitempty"`
+}
+
+// AnthropicProviderConfig configures the Anthropic provider used by fantasy-agent.
+type AnthropicProviderConfig struct {
 	BaseURL               string `json:"base_url"`
-	Organization          string `json:"organization"`
-	Project               string `json:"project"`
 	RequestTimeoutSeconds int    `json:"request_timeout_seconds"`
+	// DisablePromptCache turns off cache-control hints on stable prompt prefixes.
+	DisablePromptCache bool                `json:"disable_prompt_cache,omitempty"`
+	Limits             PayloadLimitsConfig `json:"limits,omitempty"`
+}
+
+// PayloadLimitsConfig caps how much a provider request and response may carry; zero leaves a limit off.
+type PayloadLimitsConfig struct {
+	// MaxRequestBytes bounds the conversation history, system prompt, and prompt sent per request.
+	MaxRequestBytes int `json:"max_request_bytes,omitempty"`
+	// MaxResponseBytes bounds the reply text returned per request.
+	MaxResponseBytes int `json:"max_response_bytes,omitempty"`
+	// Truncation is "error" (default) to fail oversized requests and responses,
+	// or "truncate" to drop the oldest history and cut long replies instead.
+	Truncation string `json:"truncation,omitempty"`
 }
 
 // ChannelsConfig stores transport adapter settings.
 type ChannelsConfig struct {
-	Telegram TelegramConfig `json:"telegram"`
+	Telegram  TelegramConfig         `json:"telegram"`
+	HTTP      HTTPChannelConfig      `json:"http"`
+	WebSocket WebSocketChannelConfig `json:"websocket"`
+	Policy    ChannelPolicyConfig    `json:"policy,omitempty"`
+	Restart   ChannelRestartConfig   `json:"restart,omitempty"`
+	// Templates replaces built-in reply text, keyed by channel name; see
+	// ChannelTemplatesConfig.
+	Templates map[string]ChannelTemplatesConfig `json:"templates,omitempty"`
+}
+
+// ChannelTemplatesDefault is the channels.templates key whose templates apply
+// to channels without their own.
+const ChannelTemplatesDefault = "default"
+
+// ChannelTemplatesConfig holds Go text/template strings for the replies a
+// channel sends. Each one can use {{.Channel}}, {{.ChatID}}, {{.SenderID}},
+// {{.SessionKey}}, {{.Agent}}, {{.Model}}, and, for error replies,
+// {{.Error}} (the built-in text), {{.ErrorCategory}}, and {{.RetryAfter}}.
+// Empty fields fall back to the "default" entry, then to the built-in text.
+type ChannelTemplatesConfig struct {
+	// Error replaces the reply to a failed message.
+	Error string `json:"error,omitempty"`
+	// RateLimit replaces the reply to a message refused by a rate limit,
+	// channels.policy's or the provider's.
+	RateLimit string `json:"rate_limit,omitempty"`
+	// Greeting replaces the reply to /start.
+	Greeting string `json:"greeting,omitempty"`
+}
+
+// ChannelRestartConfig sets how the gateway restarts a channel adapter that
+// stopped with an error. The wait before each restart starts at
+// InitialBackoffSeconds (default 1) and doubles up to MaxBackoffSeconds
+// (default 60). After MaxRestarts (default 5) restarts in a row that failed
+// again, the channel stays stopped and the gateway exits with its error; a
+// negative MaxRestarts never restarts.
+type ChannelRestartConfig struct {
+	MaxRestarts           int `json:"max_restarts,omitempty"`
+	InitialBackoffSeconds int `json:"initial_backoff_seconds,omitempty"`
+	MaxBackoffSeconds     int `json:"max_backoff_seconds,omitempty"`
+}
+
+// ChannelPolicyConfig sets the checks the gateway runs on messages from every
+// channel before they reach an agent. The zero value checks nothing.
+type ChannelPolicyConfig struct {
+	// AllowFrom lists the channels ("http") or "<channel>:<id>" sender or chat
+	// IDs ("telegram:123456789") whose messages are accepted; empty accepts all.
+	AllowFrom []string               `json:"allow_from,omitempty"`
+	RateLimit ChannelRateLimitConfig `json:"rate_limit,omitempty"`
+	// BlockPatterns are regular expressions; matching messages are refused.
+	BlockPatterns []string `json:"block_patterns,omitempty"`
+	// MaxMessageChars refuses longer messages; 0 leaves their length unchecked.
+	MaxMessageChars int `json:"max_message_chars,omitempty"`
+}
+
+// ChannelRateLimitConfig limits each sender to Messages messages per
+// PeriodSeconds (default 60); Messages 0 turns the limit off.
+type ChannelRateLimitConfig struct {
+	Messages      int `json:"messages,omitempty"`
+	PeriodSeconds int `json:"period_seconds,omitempty"`
+}
+
+// HTTPChannelConfig configures the HTTP chat API channel, which serves
+// POST /v1/prompt on its own listener. Host defaults to 127.0.0.1 and Port
+// to 18791.
+type HTTPChannelConfig struct {
+	Enabled bool   `json:"enabled"`
+	Host    string `json:"host,omitempty"`
+	Port    int    `json:"port,omitempty"`
+}
+
+// WebSocketChannelConfig configures the WebSocket chat channel, which serves
+// /v1/ws on its own listener and streams replies as they are generated. Host
+// defaults to 127.0.0.1 and Port to 18792. Browser clients must connect from
+// the listener's own host or from one of AllowedOrigins. WebUI also serves a
+// browser chat page at /chat/ on the gateway status server, behind
+// gateway.auth.
+type WebSocketChannelConfig struct {
+	Enabled        bool     `json:"enabled"`
+	Host           string   `json:"host,omitempty"`
+	Port           int      `json:"port,omitempty"`
+	AllowedOrigins []string `json:"allowed_origins,omitempty"`
+	WebUI          bool     `json:"web_ui,omitempty"`
 }
 
 // TelegramConfig configures Telegram channel integration.
 type TelegramConfig struct {
-	Enabled   bool     `json:"enabled"`
-	Token     string   `json:"token"`
-	Proxy     string   `json:"proxy"`
-	AllowFrom []string `json:"allow_from"`
+	Enabled   bool                `json:"enabled"`
+	Token     string              `json:"token"`
+	Proxy     string              `json:"proxy"`
+	AllowFrom []string            `json:"allow_from"`
+	Files     TelegramFilesConfig `json:"files,omitempty"`
+	// Mode is "polling" (default) to fetch updates with long polling, or
+	// "webhook" to have Telegram post them to the gateway HTTP server.
+	Mode    string                `json:"mode,omitempty"`
+	Webhook TelegramWebhookConfig `json:"webhook,omitempty"`
+	Groups  TelegramGroupsConfig  `json:"groups,omitempty"`
+	// SessionScope is "chat" (default) to share one session per chat or
+	// forum topic, or "sender" to give each sender in a group their own.
+	SessionScope string `json:"session_scope,omitempty"`
+	// ToolNotices is "off" (default), "messages" to post a short message for
+	// each tool call, or "status" to keep one status message edited to the
+	// latest call and deleted once the reply is sent.
+	ToolNotices string `json:"tool_notices,omitempty"`
+}
+
+// Telegram tool notice modes for TelegramConfig.ToolNotices.
+const (
+	TelegramToolNoticesOff      = "off"
+	TelegramToolNoticesMessages = "messages"
+	TelegramToolNoticesStatus   = "status"
+)
+
+// Telegram session scopes for TelegramConfig.SessionScope.
+const (
+	TelegramSessionScopeChat   = "chat"
+	TelegramSessionScopeSender = "sender"
+)
+
+// TelegramGroupsConfig configures group and supergroup chats. The bot answers
+// a group message only when it mentions the bot, replies to one of the bot's
+// messages, or is a chat command, unless AlwaysRespond is set. AllowFrom lists
+// group chat IDs whose members may all talk to the bot, in addition to the
+// senders of TelegramConfig.AllowFrom.
+type TelegramGroupsConfig struct {
+	AllowFrom     []string `json:"allow_from,omitempty"`
+	AlwaysRespond bool     `json:"always_respond,omitempty"`
+}
+
+// Telegram update modes for TelegramConfig.Mode.
+const (
+	TelegramModePolling = "polling"
+	TelegramModeWebhook = "webhook"
+)
+
+// TelegramWebhookConfig configures webhook mode. Telegram posts updates to URL
+// followed by Path, which the gateway HTTP server routes to the adapter; Path
+// defaults to /telegram/webhook. SecretToken is required, and Telegram sends
+// it with every update. Certificate is the PEM public certificate to upload
+// when the gateway serves a self-signed one through gateway.tls.
+type TelegramWebhookConfig struct {
+	URL         string `json:"url,omitempty"`
+	Path        string `json:"path,omitempty"`
+	SecretToken string `json:"secret_token,omitempty"`
+	Certificate string `json:"certificate,omitempty"`
+}
+
+// TelegramFilesConfig configures saving photos and documents sent to the bot
+// into the chat's session workspace, where the agent's tools can read them.
+// MaxSizeMB bounds one file and defaults to 20, the most the Bot API serves.
+type TelegramFilesConfig struct {
+	Enabled   bool `json:"enabled"`
+	MaxSizeMB int  `json:"max_size_mb,omitempty"`
 }
 
 // ToolsConfig groups optional tool-system configuration.
 type ToolsConfig struct {
-	Web    WebToolsConfig `json:"web"`
-	Cron   CronConfig     `json:"cron"`
-	Exec   ExecConfig     `json:"exec"`
-	Skills SkillsConfig   `json:"skills"`
+	Web        WebToolsConfig        `json:"web"`
+	Cron       CronConfig            `json:"cron"`
+	Exec       ExecConfig            `json:"exec"`
+	Filesystem FilesystemToolsConfig `json:"filesystem,omitempty"`
+	Results    ToolResultsConfig     `json:"results,omitempty"`
+	Skills     SkillsConfig          `json:"skills"`
+	Approval   ToolApprovalConfig    `json:"approval,omitempty"`
+	Quotas     ToolQuotaConfig       `json:"quotas,omitempty"`
+	MCP        MCPConfig             `json:"mcp,omitempty"`
+	Webhooks   []WebhookToolConfig   `json:"webhooks,omitempty"`
+	Memory     MemoryToolsConfig     `json:"memory,omitempty"`
+	Plan       PlanToolsConfig       `json:"plan,omitempty"`
+	Scratchpad ScratchpadToolsConfig `json:"scratchpad,omitempty"`
+	Variables  VariableToolsConfig   `json:"variables,omitempty"`
+	Subagents  SubagentToolsConfig   `json:"subagents,omitempty"`
+}
+
+// WebhookToolConfig declares a tool that POSTs its JSON arguments to an HTTP
+// endpoint and returns the response body to the model.
+type WebhookToolConfig struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Parameters is the JSON schema of the tool input: an object schema with
+	// properties and required. Unset means the tool takes no arguments.
+	Parameters json.RawMessage   `json:"parameters,omitempty"`
+	URL        string            `json:"url"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	// TimeoutSeconds bounds each call; 0 uses the tool default.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+}
+
+// MCPConfig lists external Model Context Protocol servers whose tools the agent may call.
+type MCPConfig struct {
+	Servers []MCPServerConfig `json:"servers,omitempty"`
+}
+
+// MCPServerConfig describes one MCP server.
+//
+// Set Command to launch the server over stdio, or URL to reach it over
+// streamable HTTP.
+type MCPServerConfig struct {
+	// Name prefixes the server's tools, for example "github" exposes "github__create_issue".
+	Name     string            `json:"name"`
+	Command  string            `json:"command,omitempty"`
+	Args     []string          `json:"args,omitempty"`
+	Env      map[string]string `json:"env,omitempty"`
+	URL      string            `json:"url,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Disabled bool              `json:"disabled,omitempty"`
+	// TimeoutSeconds bounds connecting and each tool call; 0 uses the client default.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+}
+
+// ToolApprovalConfig makes selected tools pause for human confirmation before running.
+type ToolApprovalConfig struct {
+	// Enabled turns approval on; destructive tools then ask unless overridden in Tools.
+	Enabled bool `json:"enabled"`
+	// Tools overrides the policy per tool name: "ask" or "allow".
+	Tools map[string]string `json:"tools,omitempty"`
+	// TimeoutSeconds bounds how long a call waits for an answer before it is denied.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+}
+
+// ToolQuotaConfig caps tool use per provider session; zero leaves a limit off.
+type ToolQuotaConfig struct {
+	MaxCallsPerSession        int   `json:"max_calls_per_session,omitempty"`
+	MaxBytesReadPerSession    int64 `json:"max_bytes_read_per_session,omitempty"`
+	MaxBytesWrittenPerSession int64 `json:"max_bytes_written_per_session,omitempty"`
 }
 
 // WebToolsConfig configures web/search providers for tool usage.
@@ -107,15 +533,126 @@ type SearchProviderConfig struct {
 	MaxResults int    `json:"max_results"`
 }
 
-// CronConfig configures cron/tool execution limits.
+// CronConfig configures scheduled prompts and their execution limits.
 type CronConfig struct {
+	// ExecTimeoutMinutes bounds one scheduled prompt run; 0 uses the scheduler default.
 	ExecTimeoutMinutes int `json:"exec_timeout_minutes"`
+	// Timezone is an IANA zone name for evaluating schedules; empty means local time.
+	Timezone string          `json:"timezone,omitempty"`
+	Jobs     []CronJobConfig `json:"jobs,omitempty"`
+	// Maintenance schedules built-in housekeeping tasks alongside the prompt jobs.
+	Maintenance []MaintenanceJobConfig `json:"maintenance,omitempty"`
+}
+
+// MaintenanceJobConfig schedules one built-in maintenance task.
+type MaintenanceJobConfig struct {
+	// Task names the built-in task, for example "session_expiry".
+	Task     string `json:"task"`
+	Schedule string `json:"schedule"`
+	Disabled bool   `json:"disabled,omitempty"`
+	// MaxAgeDays is how long session_expiry keeps a session after its last turn, snapshot_prune
+	// keeps file snapshots, and usage_compact keeps per-session usage rows; 0 uses the task default.
+	MaxAgeDays int `json:"max_age_days,omitempty"`
+	// DriftPercent is how far usage_reconcile lets local and vendor token totals differ before flagging drift; 0 uses the task default.
+	DriftPercent float64 `json:"drift_percent,omitempty"`
+	// Since is the window activity_report covers, as a Go duration such as 24h; empty uses 24h.
+	Since string `json:"since,omitempty"`
+	// Output also publishes the task result; unset only logs it.
+	Output CronOutputConfig `json:"output,omitempty"`
+}
+
+// CronJobConfig describes one prompt run by the gateway on a cron schedule.
+type CronJobConfig struct {
+	Name     string           `json:"name"`
+	Schedule string           `json:"schedule"`
+	Prompt   string           `json:"prompt"`
+	Disabled bool             `json:"disabled,omitempty"`
+	Output   CronOutputConfig `json:"output"`
+}
+
+// CronOutputConfig selects where a scheduled prompt's result is published.
+//
+// Type is one of "log" (default), "file", or "telegram".
+type CronOutputConfig struct {
+	Type   string `json:"type,omitempty"`
+	ChatID string `json:"chat_id,omitempty"`
+	Path   string `json:"path,omitempty"`
 }
 
 // ExecConfig configures local command execution safety behavior.
 type ExecConfig struct {
+	// Enabled registers the run_command tool; command execution is opt-in.
+	Enabled            bool     `json:"enabled"`
 	EnableDenyPatterns bool     `json:"enable_deny_patterns"`
 	CustomDenyPatterns []string `json:"custom_deny_patterns"`
+	TimeoutSeconds     int      `json:"timeout_seconds,omitempty"`
+	MaxOutputBytes     int      `json:"max_output_bytes,omitempty"`
+}
+
+// MemoryToolsConfig configures the remember and recall tools.
+type MemoryToolsConfig struct {
+	// Enabled registers remember and recall for fantasy-agent.
+	Enabled bool `json:"enabled"`
+	// Path is the notes file relative to the workspace; defaults to MEMORY.md.
+	Path          string `json:"path,omitempty"`
+	MaxBytes      int64  `json:"max_bytes,omitempty"`
+	MaxEntryChars int    `json:"max_entry_chars,omitempty"`
+}
+
+// PlanToolsConfig configures the task plan tools.
+type PlanToolsConfig struct {
+	// Enabled registers plan_add, plan_update, and plan_complete for fantasy-agent.
+	Enabled bool `json:"enabled"`
+}
+
+// ScratchpadToolsConfig configures the session scratchpad tools.
+type ScratchpadToolsConfig struct {
+	// Enabled registers scratch_set and scratch_get for fantasy-agent.
+	Enabled bool `json:"enabled"`
+}
+
+// VariableToolsConfig configures per-session variables referenced as {{name}}.
+type VariableToolsConfig struct {
+	// Enabled registers var_set and var_get for fantasy-agent and expands
+	// {{name}} in prompts and the system prompt.
+	Enabled bool `json:"enabled"`
+	// Defaults seed the variables of every new session.
+	Defaults map[string]string `json:"defaults,omitempty"`
+}
+
+// SubagentToolsConfig configures the spawn_agent delegation tool.
+type SubagentToolsConfig struct {
+	// Enabled registers spawn_agent for fantasy-agent.
+	Enabled bool `json:"enabled"`
+	// Model runs child agents on another model of the same provider; empty uses agents.defaults.model.
+	Model string `json:"model,omitempty"`
+	// MaxToolIterations bounds each child's tool steps; defaults to 10.
+	MaxToolIterations int `json:"max_tool_iterations,omitempty"`
+}
+
+// FilesystemToolsConfig tunes the fantasy filesystem tools.
+type FilesystemToolsConfig struct {
+	// Prefetch reads small, likely-next files into a cache after list_dir and search_files.
+	Prefetch         bool `json:"prefetch"`
+	PrefetchMaxFiles int  `json:"prefetch_max_files,omitempty"`
+	// MaxWorkspaceBytes and MaxWorkspaceFiles cap the workspace size for file
+	// tool writes; 0 leaves a limit off.
+	MaxWorkspaceBytes int64 `json:"max_workspace_bytes,omitempty"`
+	MaxWorkspaceFiles int   `json:"max_workspace_files,omitempty"`
+	// Snapshots saves files under .miniclaw/snapshots before tool writes and
+	// registers undo_last_change and restore_file.
+	Snapshots bool `json:"snapshots,omitempty"`
+	// Audit appends every successful file tool write and delete to .miniclaw/audit.jsonl.
+	Audit bool `json:"audit,omitempty"`
+}
+
+// ToolResultsConfig controls compression of oversized tool output before it reaches the model.
+type ToolResultsConfig struct {
+	// Mode is "off" (default), "truncate" (keep head and tail), or "summarize" (model summary, truncate on failure).
+	Mode      string `json:"mode,omitempty"`
+	MaxChars  int    `json:"max_chars,omitempty"`
+	HeadChars int    `json:"head_chars,omitempty"`
+	TailChars int    `json:"tail_chars,omitempty"`
 }
 
 // SkillsConfig configures external skill registries.
@@ -136,6 +673,106 @@ type RegistryConfig struct {
 type HeartbeatConfig struct {
 	Enabled  bool `json:"enabled"`
 	Interval int  `json:"interval"`
+	// Watch prompts the agent when files change in the workspace outside its own turns.
+	Watch WorkspaceWatchConfig `json:"watch,omitempty"`
+}
+
+// WorkspaceWatchConfig controls the workspace file watcher; it only runs with the heartbeat enabled.
+type WorkspaceWatchConfig struct {
+	Enabled bool `json:"enabled"`
+	// DebounceMS is how long changes must be quiet before they are reported; 0 uses 500.
+	DebounceMS int `json:"debounce_ms,omitempty"`
+}
+
+// RuntimeConfig controls local session execution behavior.
+//
+// Workers sets how many bus workers execute prompts concurrently; values
+// below 1 fall back to a single worker.
+//
+// SessionConcurrency sets how many prompts of one session may run at once;
+// values below 1 mean one, which keeps a session's prompts in arrival order.
+// Raise it only for read-only workloads: concurrent prompts share the
+// session history and may interleave their turns.
+//
+// MaxQueuedPerSession caps how many prompts may wait behind a session's
+// running ones; further prompts fail at once with a queue_full error. Zero
+// means no limit.
+type RuntimeConfig struct {
+	Workers             int                  `json:"workers,omitempty"`
+	SessionConcurrency  int                  `json:"session_concurrency,omitempty"`
+	MaxQueuedPerSession int                  `json:"max_queued_per_session,omitempty"`
+	CircuitBreaker      CircuitBreakerConfig `json:"circuit_breaker,omitempty"`
+	Budget              BudgetConfig         `json:"budget,omitempty"`
+}
+
+// BudgetConfig caps what one session may spend, in tokens or in US dollars
+// estimated from the configured token prices. Day caps reset at midnight UTC.
+// A zero cap is off.
+type BudgetConfig struct {
+	Enabled             bool    `json:"enabled,omitempty"`
+	MaxTokensPerSession int64   `json:"max_tokens_per_session,omitempty"`
+	MaxTokensPerDay     int64   `json:"max_tokens_per_day,omitempty"`
+	MaxUSDPerSession    float64 `json:"max_usd_per_session,omitempty"`
+	MaxUSDPerDay        float64 `json:"max_usd_per_day,omitempty"`
+	// InputUSDPerMillion and OutputUSDPerMillion price the model's tokens for
+	// the USD caps, for example 1.25 and 10 for gpt-5.
+	InputUSDPerMillion  float64 `json:"input_usd_per_million,omitempty"`
+	OutputUSDPerMillion float64 `json:"output_usd_per_million,omitempty"`
+}
+
+// CircuitBreakerConfig stops sending a session's prompts to a failing
+// provider. After FailureThreshold consecutive outage failures (default 5)
+// prompts fail at once for CooldownSeconds (default 30); then one trial
+// prompt decides whether the breaker closes again.
+type CircuitBreakerConfig struct {
+	Enabled          bool `json:"enabled,omitempty"`
+	FailureThreshold int  `json:"failure_threshold,omitempty"`
+	CooldownSeconds  int  `json:"cooldown_seconds,omitempty"`
+}
+
+// StorageConfig selects the session persistence backend.
+//
+// Backend is one of "memory" (default), "jsonl" (Path is a directory), or
+// "sqlite" (Path is a database file).
+type StorageConfig struct {
+	Backend string `json:"backend,omitempty"`
+	Path    string `json:"path,omitempty"`
+}
+
+// BusConfig selects the message bus backend of local sessions.
+//
+// Backend is "memory" (default) or "sqlite", which journals the gateway's
+// channel messages in the database file at Path until they are answered, so
+// a crash does not lose them. "nats" and "redis" share the bus with other
+// miniclaw processes
+// through the server at URL, under subjects or stream keys starting with
+// Prefix (default "miniclaw"), over TLS with a tls:// or rediss:// URL or
+// when TLS is set.
+type BusConfig struct {
+	Backend  string         `json:"backend,omitempty"`
+	Path     string         `json:"path,omitempty"`
+	URL      string         `json:"url,omitempty"`
+	Prefix   string         `json:"prefix,omitempty"`
+	TLS      BusTLSConfig   `json:"tls,omitempty"`
+	EventLog EventLogConfig `json:"event_log,omitempty"`
+}
+
+// BusTLSConfig points the nats and redis buses at PEM files. CAFile replaces
+// the system roots for verifying the server; CertFile and KeyFile, set
+// together, are the client certificate. Setting any of them turns TLS on.
+type BusTLSConfig struct {
+	CAFile   string `json:"ca_file,omitempty"`
+	CertFile string `json:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty"`
+}
+
+// EventLogConfig records bus events to a JSON Lines file for later replay.
+// It is enabled when Path is set. The file is rotated once it reaches
+// MaxSizeMB (default 10), keeping MaxFiles rotated files (default 5).
+type EventLogConfig struct {
+	Path      string `json:"path,omitempty"`
+	MaxSizeMB int    `json:"max_size_mb,omitempty"`
+	MaxFiles  int    `json:"max_files,omitempty"`
 }
 
 // DevicesConfig controls optional device-monitoring features.
@@ -148,6 +785,146 @@ type DevicesConfig struct {
 type GatewayConfig struct {
 	Host string `json:"host"`
 	Port int    `json:"port"`
+	// Approvals sends tool approvals from channel sessions to the operator queue.
+	Approvals GatewayApprovalsConfig `json:"approvals,omitempty"`
+	// SessionWorkspaces gives every channel session its own workspace directory.
+	SessionWorkspaces GatewaySessionWorkspacesConfig `json:"session_workspaces,omitempty"`
+	// ChannelAgents maps a channel name (for example "telegram") or one chat's
+	// session key (for example "telegram:100") to the named agent that answers
+	// its messages without a "!<name>" prefix. Session keys win over channels.
+	ChannelAgents map[string]string `json:"channel_agents,omitempty"`
+	// Auth protects the status server's non-health and /admin endpoints.
+	Auth GatewayAuthConfig `json:"auth,omitempty"`
+	// TLS serves the status server over HTTPS.
+	TLS GatewayTLSConfig `json:"tls,omitempty"`
+	// SessionPersistence keeps chat sessions across gateway restarts.
+	SessionPersistence GatewaySessionPersistenceConfig `json:"session_persistence,omitempty"`
+	// Webhooks receive selected gateway events as JSON POSTs.
+	Webhooks []GatewayWebhookConfig `json:"webhooks,omitempty"`
+	// Usage keeps token usage and estimated cost per day, channel, and
+	// session in a persistent store.
+	Usage GatewayUsageConfig `json:"usage,omitempty"`
+	// Cluster runs several gateway instances over a shared NATS or Redis bus.
+	Cluster GatewayClusterConfig `json:"cluster,omitempty"`
+}
+
+// AuthConfig returns gateway.auth, taking the deprecated
+// gateway.approvals.token as its token when gateway.auth.token is unset.
+func (g GatewayConfig) AuthConfig() GatewayAuthConfig {
+	auth := g.Auth
+	if strings.TrimSpace(auth.Token) == "" {
+		auth.Token = g.Approvals.Token
+	}
+
+	return auth
+}
+
+// GatewayClusterConfig lets several gateway instances share channel traffic
+// over the NATS or Redis server of bus. Each session key belongs to one
+// instance of Instances, so a message received anywhere is answered by the
+// instance that holds its conversation.
+type GatewayClusterConfig struct {
+	Enabled bool `json:"enabled"`
+	// InstanceID names this gateway and must appear in Instances (default the
+	// hostname; MINICLAW_GATEWAY_INSTANCE_ID overrides it).
+	InstanceID string `json:"instance_id,omitempty"`
+	// Instances lists every gateway of the cluster; all of them must use the
+	// same list, or they disagree on which instance owns a session.
+	Instances []string `json:"instances,omitempty"`
+	// ForwardTimeoutSeconds bounds waiting for another instance to answer a
+	// forwarded message (default 600).
+	ForwardTimeoutSeconds int `json:"forward_timeout_seconds,omitempty"`
+}
+
+// GatewayUsageConfig records every turn's token usage in a JSON file that
+// GET /v1/usage/report and `miniclaw usage` read.
+type GatewayUsageConfig struct {
+	Enabled bool `json:"enabled"`
+	// Path is the JSON file of the usage store (default
+	// ~/.miniclaw/gateway-usage.json).
+	Path string `json:"path,omitempty"`
+	// RetentionDays is how many UTC days of usage are kept (default 90).
+	RetentionDays int `json:"retention_days,omitempty"`
+	// InputUSDPerMillion and OutputUSDPerMillion price tokens for the cost
+	// estimate. When both are zero, the runtime.budget prices are used.
+	InputUSDPerMillion  float64 `json:"input_usd_per_million,omitempty"`
+	OutputUSDPerMillion float64 `json:"output_usd_per_million,omitempty"`
+}
+
+// GatewayWebhookConfig sends gateway events to one URL.
+type GatewayWebhookConfig struct {
+	URL string `json:"url"`
+	// Events lists the event types to send (default prompt_failed,
+	// channel_disconnected, and provider_down).
+	Events []string `json:"events,omitempty"`
+	// Secret, when set, signs each delivery with HMAC-SHA256 in the
+	// X-Miniclaw-Signature header.
+	Secret string `json:"secret,omitempty"`
+	// MaxRetries bounds redeliveries after a failed attempt (default 3).
+	MaxRetries int `json:"max_retries,omitempty"`
+	// TimeoutSeconds bounds each attempt (default 10).
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+}
+
+// GatewaySessionPersistenceConfig saves which provider session each chat
+// uses, so a restarted gateway continues every conversation.
+type GatewaySessionPersistenceConfig struct {
+	Enabled bool `json:"enabled"`
+	// Path is the JSON file of the session map (default
+	// ~/.miniclaw/gateway-sessions.json).
+	Path string `json:"path,omitempty"`
+	// Memory also keeps each session's gateway memory in a JSONL store in the
+	// directory <path without .json>-memory when storage.backend does not
+	// persist it.
+	Memory bool `json:"memory,omitempty"`
+}
+
+// GatewayAuthConfig protects the status server's /status page, /v1 endpoints,
+// Prometheus endpoint, and /admin and /events endpoints. /healthz and /readyz
+// stay open for probes; /admin and /events are refused while no credential is
+// set. Either credential is accepted when both are set.
+type GatewayAuthConfig struct {
+	// Token, when set, must be sent as "Authorization: Bearer <token>".
+	Token string `json:"token,omitempty"`
+	// Username and Password, when set, are accepted as HTTP basic auth.
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// Clients are bearer tokens for the HTTP and WebSocket chat channels
+	// only. Each client's sessions are kept apart from everyone else's.
+	Clients []GatewayAuthClientConfig `json:"clients,omitempty"`
+}
+
+// GatewayAuthClientConfig names one chat client of GatewayAuthConfig.Clients.
+type GatewayAuthClientConfig struct {
+	// Name identifies the client in session keys and logs: letters, digits,
+	// "_", or "-".
+	Name  string `json:"name"`
+	Token string `json:"token"`
+}
+
+// GatewayTLSConfig points the status server at a PEM certificate and key;
+// both must be set to enable HTTPS.
+type GatewayTLSConfig struct {
+	CertFile string `json:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty"`
+}
+
+// GatewaySessionWorkspacesConfig isolates channel sessions from each other on disk.
+type GatewaySessionWorkspacesConfig struct {
+	// Enabled builds a provider client, and so a Guard and tool set, per
+	// session rooted at <workspace>/sessions/<session key>.
+	Enabled bool `json:"enabled"`
+}
+
+// GatewayApprovalsConfig configures the operator approval queue at /admin/approvals.
+type GatewayApprovalsConfig struct {
+	// Enabled queues tools.approval requests for the operator instead of asking in the chat.
+	Enabled bool `json:"enabled"`
+	// Token is a deprecated alias of GatewayAuthConfig.Token, used only while
+	// that is unset; see GatewayConfig.AuthConfig.
+	Token string `json:"token,omitempty"`
+	// NotifyTelegramChatID, when set, receives a Telegram message for each queued approval.
+	NotifyTelegramChatID string `json:"notify_telegram_chat_id,omitempty"`
 }
 
 // LoadConfig resolves config.json, unmarshals it, and applies environment overrides.
@@ -172,6 +949,21 @@ func LoadConfig() (*Config, error) {
 	return &cfg, nil
 }
 
+// Path returns the absolute path of the config file LoadConfig would read.
+func Path() (string, error) {
+	configPath, err := findConfigPath()
+	if err != nil {
+		return "", err
+	}
+
+	absolute, err := filepath.Abs(configPath)
+	if err != nil {
+		return "", fmt.Errorf("resolve config path: %w", err)
+	}
+
+	return absolute, nil
+}
+
 // applyEnvOverrides injects selected env-driven settings on top of file config.
 func applyEnvOverrides(cfg *Config) {
 	if cfg == nil {
@@ -185,6 +977,22 @@ func applyEnvOverrides(cfg *Config) {
 	if rawAllowFrom := strings.TrimSpace(os.Getenv(envTelegramAllowFrom)); rawAllowFrom != "" {
 		cfg.Channels.Telegram.AllowFrom = parseCSV(rawAllowFrom)
 	}
+
+	if secret := strings.TrimSpace(os.Getenv(envTelegramSecret)); secret != "" {
+		cfg.Channels.Telegram.Webhook.SecretToken = secret
+	}
+
+	if token := strings.TrimSpace(os.Getenv(envAdminToken)); token != "" {
+		cfg.Gateway.Approvals.Token = token
+	}
+
+	if token := strings.TrimSpace(os.Getenv(envGatewayToken)); token != "" {
+		cfg.Gateway.Auth.Token = token
+	}
+
+	if instanceID := strings.TrimSpace(os.Getenv(envGatewayInstanceID)); instanceID != "" {
+		cfg.Gateway.Cluster.InstanceID = instanceID
+	}
 }
 
 // parseCSV splits comma-separated values and returns a trimmed compact slice.
@@ -62,6 +62,7 @@ func TestLoadConfigAppliesTelegramEnvOverrides(t *testing.T) {
 	t.Setenv("MINICLAW_CONFIG", path)
 	t.Setenv("TELEGRAM_BOT_TOKEN", "from-env")
 	t.Setenv("TELEGRAM_ALLOW_FROM", " 123,456 ,, 789 ")
+	t.Setenv("TELEGRAM_WEBHOOK_SECRET", "secret-from-env")
 
 	cfg, err := LoadConfig()
 	if err != nil {
@@ -71,6 +72,9 @@ func TestLoadConfigAppliesTelegramEnvOverrides(t *testing.T) {
 	if cfg.Channels.Telegram.Token != "from-env" {
 		t.Fatalf("channels.telegram.token = %q, want %q", cfg.Channels.Telegram.Token, "from-env")
 	}
+	if cfg.Channels.Telegram.Webhook.SecretToken != "secret-from-env" {
+		t.Fatalf("channels.telegram.webhook.secret_token = %q, want %q", cfg.Channels.Telegram.Webhook.SecretToken, "secret-from-env")
+	}
 
 	wantAllowFrom := []string{"123", "456", "789"}
 	if len(cfg.Channels.Telegram.AllowFrom) != len(wantAllowFrom) {
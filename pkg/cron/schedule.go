@@ -0,0 +1,231 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSearchYears bounds Next so impossible schedules (for example Feb 30) terminate.
+const maxSearchYears = 5
+
+// Schedule is a parsed cron expression.
+//
+// Standard five-field expressions (minute hour day-of-month month day-of-week)
+// are supported with lists, ranges, steps, and month/weekday names, plus the
+// @hourly/@daily/@weekly/@monthly/@yearly macros and "@every <duration>".
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny/dowAny record "*" so the usual day-of-month OR day-of-week rule applies.
+	domAny, dowAny bool
+	every          time.Duration
+}
+
+type field struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day-of-month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// Day-of-week accepts 7 as an alias for Sunday.
+	dowField = field{name: "day-of-week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses a cron expression into a Schedule.
+func Parse(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return Schedule{}, errors.New("cron expression is required")
+	}
+
+	if rest, ok := strings.CutPrefix(expr, "@every "); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return Schedule{}, fmt.Errorf("invalid @every duration: %w", err)
+		}
+		if every < time.Minute {
+			return Schedule{}, errors.New("@every duration must be at least 1m")
+		}
+		return Schedule{every: every}, nil
+	}
+	if expanded, ok := macros[strings.ToLower(expr)]; ok {
+		expr = expanded
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return Schedule{}, fmt.Errorf("cron expression %q must have 5 fields, got %d", expr, len(fields))
+	}
+
+	var schedule Schedule
+	var err error
+	if schedule.minute, err = parseField(fields[0], minuteField); err != nil {
+		return Schedule{}, err
+	}
+	if schedule.hour, err = parseField(fields[1], hourField); err != nil {
+		return Schedule{}, err
+	}
+	if schedule.dom, err = parseField(fields[2], domField); err != nil {
+		return Schedule{}, err
+	}
+	if schedule.month, err = parseField(fields[3], monthField); err != nil {
+		return Schedule{}, err
+	}
+	if schedule.dow, err = parseField(fields[4], dowField); err != nil {
+		return Schedule{}, err
+	}
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow = schedule.dow&^(1<<7) | 1
+	}
+	schedule.domAny = fields[2] == "*" || fields[2] == "?"
+	schedule.dowAny = fields[4] == "*" || fields[4] == "?"
+
+	return schedule, nil
+}
+
+// Next returns the first activation strictly after after, in after's location.
+//
+// It returns the zero time when the schedule never fires.
+func (s Schedule) Next(after time.Time) time.Time {
+	if s.every > 0 {
+		return after.Add(s.every).Truncate(time.Second)
+	}
+
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxSearchYears, 0, 0)
+	for t.Before(limit) {
+		if !has(s.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !has(s.hour, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !has(s.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+// dayMatches applies cron's rule that a restricted day-of-month and day-of-week are ORed.
+func (s Schedule) dayMatches(t time.Time) bool {
+	domMatch := has(s.dom, t.Day())
+	dowMatch := has(s.dow, int(t.Weekday()))
+	if s.domAny || s.dowAny {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
+
+func has(bits uint64, value int) bool {
+	return bits&(1<<uint(value)) != 0
+}
+
+// parseField parses one comma-separated cron field into a bitset.
+func parseField(expr string, f field) (uint64, error) {
+	var bits uint64
+	for part := range strings.SplitSeq(expr, ",") {
+		partBits, err := parseRange(part, f)
+		if err != nil {
+			return 0, err
+		}
+		bits |= partBits
+	}
+
+	return bits, nil
+}
+
+// parseRange parses "*", "N", "N-M", or any of those with a "/step" suffix.
+func parseRange(expr string, f field) (uint64, error) {
+	rangeExpr, stepExpr, hasStep := strings.Cut(expr, "/")
+	step := 1
+	if hasStep {
+		parsed, err := strconv.Atoi(stepExpr)
+		if err != nil || parsed <= 0 {
+			return 0, fmt.Errorf("invalid %s step %q", f.name, stepExpr)
+		}
+		step = parsed
+	}
+
+	var start, end int
+	switch {
+	case rangeExpr == "*" || rangeExpr == "?":
+		start, end = f.min, f.max
+	case strings.Contains(rangeExpr, "-"):
+		low, high, _ := strings.Cut(rangeExpr, "-")
+		var err error
+		if start, err = parseValue(low, f); err != nil {
+			return 0, err
+		}
+		if end, err = parseValue(high, f); err != nil {
+			return 0, err
+		}
+	default:
+		value, err := parseValue(rangeExpr, f)
+		if err != nil {
+			return 0, err
+		}
+		start, end = value, value
+		if hasStep {
+			end = f.max
+		}
+	}
+	if start > end {
+		return 0, fmt.Errorf("invalid %s range %q", f.name, expr)
+	}
+
+	var bits uint64
+	for value := start; value <= end; value += step {
+		bits |= 1 << uint(value)
+	}
+
+	return bits, nil
+}
+
+func parseValue(expr string, f field) (int, error) {
+	if value, ok := f.names[strings.ToLower(expr)]; ok {
+		return value, nil
+	}
+
+	value, err := strconv.Atoi(expr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value %q", f.name, expr)
+	}
+	if value < f.min || value > f.max {
+		return 0, fmt.Errorf("%s value %d out of range %d-%d", f.name, value, f.min, f.max)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,56 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	base := time.Date(2026, time.March, 14, 10, 17, 30, 0, time.UTC) // Saturday
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{expr: "* * * * *", want: time.Date(2026, time.March, 14, 10, 18, 0, 0, time.UTC)},
+		{expr: "*/15 * * * *", want: time.Date(2026, time.March, 14, 10, 30, 0, 0, time.UTC)},
+		{expr: "0 9 * * *", want: time.Date(2026, time.March, 15, 9, 0, 0, 0, time.UTC)},
+		{expr: "30 8 * * mon-fri", want: time.Date(2026, time.March, 16, 8, 30, 0, 0, time.UTC)},
+		{expr: "0 0 1 * *", want: time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 12 * jan,jun *", want: time.Date(2026, time.June, 1, 12, 0, 0, 0, time.UTC)},
+		{expr: "0 0 * * 7", want: time.Date(2026, time.March, 15, 0, 0, 0, 0, time.UTC)},
+		// Restricted day-of-month and day-of-week are ORed, as in classic cron.
+		{expr: "0 0 20 * 1", want: time.Date(2026, time.March, 16, 0, 0, 0, 0, time.UTC)},
+		{expr: "@hourly", want: time.Date(2026, time.March, 14, 11, 0, 0, 0, time.UTC)},
+		{expr: "@every 90m", want: time.Date(2026, time.March, 14, 11, 47, 30, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			schedule, err := Parse(test.expr)
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+			if got := schedule.Next(base); !got.Equal(test.want) {
+				t.Fatalf("Next = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestScheduleNextImpossibleDate(t *testing.T) {
+	schedule, err := Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if got := schedule.Next(time.Now()); !got.IsZero() {
+		t.Fatalf("Next = %s, want zero time", got)
+	}
+}
+
+func TestParseRejectsInvalidExpressions(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "5-1 * * * *", "*/0 * * * *", "* * * foo *", "@every 10s"} {
+		if _, err := Parse(expr); err == nil {
+			t.Fatalf("Parse(%q) expected error", expr)
+		}
+	}
+}
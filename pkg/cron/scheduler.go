@@ -0,0 +1,442 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/channel"
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+const (
+	OutputLog      = "log"
+	OutputFile     = "file"
+	OutputTelegram = "telegram"
+
+	// DefaultRunTimeout bounds one job run when cron.exec_timeout_minutes is unset.
+	DefaultRunTimeout = 10 * time.Minute
+
+	sessionKeyPrefix = "cron:"
+
+	// TaskSessionExpiry deletes stored gateway sessions idle for longer than max_age_days.
+	TaskSessionExpiry = "session_expiry"
+	// TaskUsageReconcile compares yesterday's locally tracked token usage with the vendor's numbers.
+	TaskUsageReconcile = "usage_reconcile"
+	// TaskActivityReport summarizes agent activity over the last since window.
+	TaskActivityReport = "activity_report"
+	// TaskSnapshotPrune drops file snapshots older than max_age_days.
+	TaskSnapshotPrune = "snapshot_prune"
+	// TaskRetrievalReindex brings the retrieval index up to date with the workspace.
+	TaskRetrievalReindex = "retrieval_reindex"
+	// TaskUsageCompact merges per-session usage rows older than max_age_days into per-channel rows.
+	TaskUsageCompact = "usage_compact"
+	// maintenanceNamePrefix keeps maintenance job names apart from prompt job names.
+	maintenanceNamePrefix = "maintenance:"
+)
+
+// MaintenanceTask runs one pass of a built-in maintenance task and summarizes what it did.
+type MaintenanceTask func(ctx context.Context, cfg config.MaintenanceJobConfig) (string, error)
+
+// PromptFunc executes one prompt in the session identified by sessionKey.
+type PromptFunc func(ctx context.Context, sessionKey string, prompt string) (providertypes.PromptResult, error)
+
+// Scheduler runs configured prompts on cron schedules and publishes their results.
+type Scheduler struct {
+	jobs      []*job
+	prompt    PromptFunc
+	notifiers map[string]channel.Notifier
+	timeout   time.Duration
+	location  *time.Location
+	log       *slog.Logger
+	now       func() time.Time
+
+	// fileMu serializes appends so concurrent jobs sharing a file do not interleave.
+	fileMu sync.Mutex
+}
+
+type job struct {
+	name     string
+	prompt   string
+	schedule Schedule
+	output   config.CronOutputConfig
+	// task is set for maintenance jobs, which run it instead of a prompt.
+	task    MaintenanceTask
+	taskCfg config.MaintenanceJobConfig
+	// events receives a maintenance_run event per run of a maintenance job; nil publishes none.
+	events *bus.MessageBus
+
+	// running prevents a slow run from overlapping with its next activation.
+	running sync.Mutex
+}
+
+// NewScheduler validates cron config and builds a scheduler.
+//
+// notifiers maps channel names to adapters able to push messages; jobs whose
+// output targets a channel without a notifier are rejected up front.
+func NewScheduler(cfg config.CronConfig, prompt PromptFunc, notifiers map[string]channel.Notifier, log *slog.Logger) (*Scheduler, error) {
+	if prompt == nil {
+		return nil, errors.New("prompt function is required")
+	}
+	if log == nil {
+		log = slog.Default()
+	}
+
+	location := time.Local
+	if zone := strings.TrimSpace(cfg.Timezone); zone != "" {
+		loaded, err := time.LoadLocation(zone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron timezone %q: %w", zone, err)
+		}
+		location = loaded
+	}
+
+	timeout := DefaultRunTimeout
+	if cfg.ExecTimeoutMinutes > 0 {
+		timeout = time.Duration(cfg.ExecTimeoutMinutes) * time.Minute
+	}
+
+	scheduler := &Scheduler{
+		prompt:    prompt,
+		notifiers: notifiers,
+		timeout:   timeout,
+		location:  location,
+		log:       log.With("component", "cron"),
+		now:       time.Now,
+	}
+
+	seen := make(map[string]struct{}, len(cfg.Jobs))
+	for index, jobCfg := range cfg.Jobs {
+		if jobCfg.Disabled {
+			continue
+		}
+		parsed, err := scheduler.newJob(jobCfg)
+		if err != nil {
+			return nil, fmt.Errorf("cron job %d: %w", index, err)
+		}
+		if _, ok := seen[parsed.name]; ok {
+			return nil, fmt.Errorf("cron job %d: duplicate name %q", index, parsed.name)
+		}
+		seen[parsed.name] = struct{}{}
+		scheduler.jobs = append(scheduler.jobs, parsed)
+	}
+
+	return scheduler, nil
+}
+
+func (s *Scheduler) newJob(cfg config.CronJobConfig) (*job, error) {
+	name := strings.TrimSpace(cfg.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+	prompt := strings.TrimSpace(cfg.Prompt)
+	if prompt == "" {
+		return nil, fmt.Errorf("%s: prompt is required", name)
+	}
+	schedule, err := Parse(cfg.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	output, err := s.newOutput(cfg.Output)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return &job{name: name, prompt: prompt, schedule: schedule, output: output}, nil
+}
+
+// newOutput normalizes and validates where a job publishes its result.
+func (s *Scheduler) newOutput(cfg config.CronOutputConfig) (config.CronOutputConfig, error) {
+	output := cfg
+	output.Type = strings.ToLower(strings.TrimSpace(output.Type))
+	switch output.Type {
+	case "", OutputLog:
+		output.Type = OutputLog
+	case OutputFile:
+		if strings.TrimSpace(output.Path) == "" {
+			return output, errors.New("output.path is required for file output")
+		}
+	case OutputTelegram:
+		if strings.TrimSpace(output.ChatID) == "" {
+			return output, errors.New("output.chat_id is required for telegram output")
+		}
+		if _, ok := s.notifiers[OutputTelegram]; !ok {
+			return output, errors.New("telegram output requires the telegram channel to be enabled")
+		}
+	default:
+		return output, fmt.Errorf("unsupported output type %q", cfg.Type)
+	}
+
+	return output, nil
+}
+
+// AddMaintenance schedules the enabled tools.cron.maintenance entries.
+//
+// tasks maps task names to their implementations; an entry naming a task the
+// caller does not provide is a config error. Maintenance jobs are named
+// "maintenance:<task>"; their results are logged and, when output is set,
+// also published like a prompt job's reply. Each run is also published to
+// events as a bus.EventMaintenanceRun when events is not nil.
+func (s *Scheduler) AddMaintenance(jobs []config.MaintenanceJobConfig, tasks map[string]MaintenanceTask, events *bus.MessageBus) error {
+	for index, jobCfg := range jobs {
+		if jobCfg.Disabled {
+			continue
+		}
+		jobCfg.Task = strings.ToLower(strings.TrimSpace(jobCfg.Task))
+		task, ok := tasks[jobCfg.Task]
+		if !ok {
+			return fmt.Errorf("cron maintenance %d: unsupported task %q", index, jobCfg.Task)
+		}
+		if jobCfg.MaxAgeDays < 0 {
+			return fmt.Errorf("cron maintenance %d: max_age_days must not be negative", index)
+		}
+		if jobCfg.DriftPercent < 0 {
+			return fmt.Errorf("cron maintenance %d: drift_percent must not be negative", index)
+		}
+		if since := strings.TrimSpace(jobCfg.Since); since != "" {
+			if window, err := time.ParseDuration(since); err != nil || window <= 0 {
+				return fmt.Errorf("cron maintenance %d: since must be a positive duration such as 24h", index)
+			}
+		}
+		output, err := s.newOutput(jobCfg.Output)
+		if err != nil {
+			return fmt.Errorf("cron maintenance %d (%s): %w", index, jobCfg.Task, err)
+		}
+		schedule, err := Parse(jobCfg.Schedule)
+		if err != nil {
+			return fmt.Errorf("cron maintenance %d (%s): %w", index, jobCfg.Task, err)
+		}
+
+		name := maintenanceNamePrefix + jobCfg.Task
+		if slices.ContainsFunc(s.jobs, func(existing *job) bool { return existing.name == name }) {
+			return fmt.Errorf("cron maintenance %d: duplicate task %q", index, jobCfg.Task)
+		}
+		s.jobs = append(s.jobs, &job{
+			name:     name,
+			schedule: schedule,
+			output:   output,
+			task:     task,
+			taskCfg:  jobCfg,
+			events:   events,
+		})
+	}
+
+	return nil
+}
+
+// Jobs returns the names of the enabled jobs in config order.
+func (s *Scheduler) Jobs() []string {
+	names := make([]string, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		names = append(names, job.name)
+	}
+
+	return names
+}
+
+// Run fires jobs as their schedules come due and blocks until ctx is canceled.
+//
+// In-flight runs are waited for before Run returns.
+func (s *Scheduler) Run(ctx context.Context) error {
+	if len(s.jobs) == 0 {
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	next := make([]time.Time, len(s.jobs))
+	now := s.now().In(s.location)
+	for index, job := range s.jobs {
+		next[index] = job.schedule.Next(now)
+		s.log.Info("Cron job scheduled", "job", job.name, "next_run", next[index])
+	}
+
+	for {
+		due := time.Time{}
+		for _, at := range next {
+			if !at.IsZero() && (due.IsZero() || at.Before(due)) {
+				due = at
+			}
+		}
+		if due.IsZero() {
+			s.log.Warn("No cron job has a future activation")
+			<-ctx.Done()
+			return nil
+		}
+
+		timer := time.NewTimer(time.Until(due))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		now := s.now().In(s.location)
+		for index, job := range s.jobs {
+			if next[index].IsZero() || next[index].After(now) {
+				continue
+			}
+			next[index] = job.schedule.Next(now)
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.fire(ctx, job)
+			}()
+		}
+	}
+}
+
+// RunJob runs one job immediately by name, regardless of its schedule.
+func (s *Scheduler) RunJob(ctx context.Context, name string) error {
+	for _, job := range s.jobs {
+		if job.name == name {
+			return s.execute(ctx, job)
+		}
+	}
+
+	return fmt.Errorf("unknown cron job %q", name)
+}
+
+// fire runs a scheduled activation, skipping it when the previous run is still going.
+func (s *Scheduler) fire(ctx context.Context, job *job) {
+	if !job.running.TryLock() {
+		s.log.Warn("Skipping cron job; previous run still in progress", "job", job.name)
+		return
+	}
+	defer job.running.Unlock()
+
+	if err := s.execute(ctx, job); err != nil {
+		s.log.Error("Cron job failed", "job", job.name, "error", err)
+	}
+}
+
+// execute runs the job prompt and publishes either its reply or its failure.
+func (s *Scheduler) execute(ctx context.Context, job *job) error {
+	runCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if job.task != nil {
+		return s.maintain(runCtx, job)
+	}
+
+	startedAt := time.Now()
+	s.log.Info("Cron job started", "job", job.name)
+	result, promptErr := s.prompt(runCtx, sessionKeyPrefix+job.name, job.prompt)
+	s.log.Info("Cron job finished", "job", job.name, "duration_ms", time.Since(startedAt).Milliseconds(), "failed", promptErr != nil)
+
+	outbound := bus.OutboundMessage{
+		Channel:    job.output.Type,
+		ChatID:     strings.TrimSpace(job.output.ChatID),
+		SessionKey: sessionKeyPrefix + job.name,
+		Content:    strings.TrimSpace(result.Text),
+	}
+	if promptErr != nil {
+		outbound.Content = ""
+		outbound.Error = fmt.Sprintf("Scheduled job %q failed: %s", job.name, providertypes.UserMessage(promptErr))
+	}
+
+	if err := s.publish(ctx, job, outbound); err != nil {
+		return fmt.Errorf("publish result: %w", err)
+	}
+
+	return promptErr
+}
+
+// maintain runs a maintenance job, logs its summary, and reports it as a
+// maintenance_run event, publishing it too when the job has a non-log output;
+// failures are returned to the caller.
+func (s *Scheduler) maintain(ctx context.Context, job *job) error {
+	startedAt := time.Now()
+	s.log.Info("Maintenance task started", "job", job.name)
+	summary, taskErr := job.task(ctx, job.taskCfg)
+	duration := time.Since(startedAt).Milliseconds()
+	s.log.Info("Maintenance task finished", "job", job.name, "duration_ms", duration, "failed", taskErr != nil, "result", summary)
+	if job.events != nil {
+		payload := bus.MaintenancePayload{Task: job.taskCfg.Task, Result: strings.TrimSpace(summary), DurationMs: duration}
+		event := bus.Event{Type: bus.EventMaintenanceRun, SessionKey: sessionKeyPrefix + job.name}
+		if taskErr != nil {
+			payload.Result = ""
+			event.Error = taskErr.Error()
+		}
+		event.Payload = payload
+		_ = job.events.PublishEvent(ctx, event)
+	}
+	if job.output.Type == OutputLog {
+		return taskErr
+	}
+
+	outbound := bus.OutboundMessage{
+		Channel:    job.output.Type,
+		ChatID:     strings.TrimSpace(job.output.ChatID),
+		SessionKey: sessionKeyPrefix + job.name,
+		Content:    strings.TrimSpace(summary),
+	}
+	if taskErr != nil {
+		outbound.Content = ""
+		outbound.Error = fmt.Sprintf("Maintenance task %q failed: %s", job.name, taskErr)
+	}
+	if err := s.publish(ctx, job, outbound); err != nil {
+		return errors.Join(taskErr, fmt.Errorf("publish result: %w", err))
+	}
+
+	return taskErr
+}
+
+func (s *Scheduler) publish(ctx context.Context, job *job, outbound bus.OutboundMessage) error {
+	switch job.output.Type {
+	case OutputTelegram:
+		return s.notifiers[OutputTelegram].Notify(ctx, outbound)
+	case OutputFile:
+		return s.appendToFile(job, outbound)
+	default:
+		if outbound.Error != "" {
+			s.log.Warn("Cron job result", "job", job.name, "error", outbound.Error)
+			return nil
+		}
+		s.log.Info("Cron job result", "job", job.name, "content", outbound.Content)
+		return nil
+	}
+}
+
+// appendToFile appends one timestamped Markdown section per run.
+func (s *Scheduler) appendToFile(job *job, outbound bus.OutboundMessage) error {
+	s.fileMu.Lock()
+	defer s.fileMu.Unlock()
+
+	path := strings.TrimSpace(job.output.Path)
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("create output directory: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open output file: %w", err)
+	}
+	defer file.Close()
+
+	body := outbound.Content
+	if outbound.Error != "" {
+		body = outbound.Error
+	}
+	entry := fmt.Sprintf("## %s %s\n\n%s\n\n", s.now().In(s.location).Format(time.RFC3339), job.name, body)
+	if _, err := file.WriteString(entry); err != nil {
+		return fmt.Errorf("write output file: %w", err)
+	}
+
+	return nil
+}
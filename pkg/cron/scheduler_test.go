@@ -0,0 +1,252 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/channel"
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+type fakeNotifier struct {
+	sent []bus.OutboundMessage
+}
+
+func (f *fakeNotifier) Notify(_ context.Context, outbound bus.OutboundMessage) error {
+	f.sent = append(f.sent, outbound)
+	return nil
+}
+
+func TestNewSchedulerValidatesJobs(t *testing.T) {
+	prompt := func(context.Context, string, string) (providertypes.PromptResult, error) {
+		return providertypes.PromptResult{}, nil
+	}
+	tests := []struct {
+		name string
+		job  config.CronJobConfig
+	}{
+		{name: "missing name", job: config.CronJobConfig{Schedule: "@daily", Prompt: "hi"}},
+		{name: "missing prompt", job: config.CronJobConfig{Name: "a", Schedule: "@daily"}},
+		{name: "bad schedule", job: config.CronJobConfig{Name: "a", Schedule: "nope", Prompt: "hi"}},
+		{name: "file without path", job: config.CronJobConfig{Name: "a", Schedule: "@daily", Prompt: "hi", Output: config.CronOutputConfig{Type: "file"}}},
+		{name: "telegram without channel", job: config.CronJobConfig{Name: "a", Schedule: "@daily", Prompt: "hi", Output: config.CronOutputConfig{Type: "telegram", ChatID: "1"}}},
+		{name: "unknown output", job: config.CronJobConfig{Name: "a", Schedule: "@daily", Prompt: "hi", Output: config.CronOutputConfig{Type: "email"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewScheduler(config.CronConfig{Jobs: []config.CronJobConfig{test.job}}, prompt, nil, nil)
+			if err == nil {
+				t.Fatal("expected validation error")
+			}
+		})
+	}
+
+	scheduler, err := NewScheduler(config.CronConfig{Jobs: []config.CronJobConfig{
+		{Name: "kept", Schedule: "@daily", Prompt: "hi"},
+		{Name: "skipped", Schedule: "bad", Prompt: "hi", Disabled: true},
+	}}, prompt, nil, nil)
+	if err != nil {
+		t.Fatalf("NewScheduler error: %v", err)
+	}
+	if got := scheduler.Jobs(); len(got) != 1 || got[0] != "kept" {
+		t.Fatalf("Jobs = %v, want [kept]", got)
+	}
+}
+
+func TestRunJobPublishesToOutputs(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "reports", "digest.md")
+	notifier := &fakeNotifier{}
+	var sessionKeys []string
+	prompt := func(_ context.Context, sessionKey string, prompt string) (providertypes.PromptResult, error) {
+		sessionKeys = append(sessionKeys, sessionKey)
+		if prompt == "fail" {
+			return providertypes.PromptResult{}, &providertypes.PromptError{Category: providertypes.ErrorAuth, Provider: "openai", Err: errors.New("401")}
+		}
+		return providertypes.PromptResult{Text: "summary for " + prompt}, nil
+	}
+
+	scheduler, err := NewScheduler(config.CronConfig{Jobs: []config.CronJobConfig{
+		{Name: "digest", Schedule: "0 9 * * *", Prompt: "news", Output: config.CronOutputConfig{Type: "file", Path: outputPath}},
+		{Name: "ping", Schedule: "@hourly", Prompt: "fail", Output: config.CronOutputConfig{Type: "telegram", ChatID: "42"}},
+	}}, prompt, map[string]channel.Notifier{OutputTelegram: notifier}, nil)
+	if err != nil {
+		t.Fatalf("NewScheduler error: %v", err)
+	}
+
+	if err := scheduler.RunJob(context.Background(), "digest"); err != nil {
+		t.Fatalf("RunJob digest error: %v", err)
+	}
+	content, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if !strings.Contains(string(content), "digest") || !strings.Contains(string(content), "summary for news") {
+		t.Fatalf("output file = %q, want job name and result", content)
+	}
+
+	if err := scheduler.RunJob(context.Background(), "ping"); err == nil {
+		t.Fatal("expected prompt error from failing job")
+	}
+	if len(notifier.sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(notifier.sent))
+	}
+	if notifier.sent[0].ChatID != "42" || !strings.Contains(notifier.sent[0].Error, "API key invalid") {
+		t.Fatalf("sent = %+v, want actionable error to chat 42", notifier.sent[0])
+	}
+
+	if strings.Join(sessionKeys, ",") != "cron:digest,cron:ping" {
+		t.Fatalf("session keys = %v", sessionKeys)
+	}
+	if err := scheduler.RunJob(context.Background(), "missing"); err == nil {
+		t.Fatal("expected error for unknown job")
+	}
+}
+
+func TestAddMaintenanceSchedulesBuiltInTasks(t *testing.T) {
+	prompt := func(context.Context, string, string) (providertypes.PromptResult, error) {
+		return providertypes.PromptResult{}, nil
+	}
+	scheduler, err := NewScheduler(config.CronConfig{}, prompt, nil, nil)
+	if err != nil {
+		t.Fatalf("NewScheduler error: %v", err)
+	}
+
+	var got config.MaintenanceJobConfig
+	tasks := map[string]MaintenanceTask{
+		TaskSessionExpiry: func(_ context.Context, cfg config.MaintenanceJobConfig) (string, error) {
+			got = cfg
+			return "deleted 0 sessions", nil
+		},
+	}
+	for name, job := range map[string]config.MaintenanceJobConfig{
+		"unknown task":     {Task: "reindex", Schedule: "@daily"},
+		"bad schedule":     {Task: TaskSessionExpiry, Schedule: "nope"},
+		"negative max age": {Task: TaskSessionExpiry, Schedule: "@daily", MaxAgeDays: -1},
+		"bad since":        {Task: TaskSessionExpiry, Schedule: "@daily", Since: "yesterday"},
+		"bad output":       {Task: TaskSessionExpiry, Schedule: "@daily", Output: config.CronOutputConfig{Type: "telegram", ChatID: "42"}},
+	} {
+		if err := scheduler.AddMaintenance([]config.MaintenanceJobConfig{job}, tasks, nil); err == nil {
+			t.Fatalf("%s: expected validation error", name)
+		}
+	}
+
+	if err := scheduler.AddMaintenance([]config.MaintenanceJobConfig{
+		{Task: " Session_Expiry ", Schedule: "@daily", MaxAgeDays: 7},
+		{Task: "reindex", Schedule: "@daily", Disabled: true},
+	}, tasks, nil); err != nil {
+		t.Fatalf("AddMaintenance error: %v", err)
+	}
+	if jobs := scheduler.Jobs(); len(jobs) != 1 || jobs[0] != "maintenance:session_expiry" {
+		t.Fatalf("Jobs = %v, want [maintenance:session_expiry]", jobs)
+	}
+	if err := scheduler.AddMaintenance([]config.MaintenanceJobConfig{{Task: TaskSessionExpiry, Schedule: "@hourly"}}, tasks, nil); err == nil {
+		t.Fatal("expected duplicate task error")
+	}
+
+	if err := scheduler.RunJob(context.Background(), "maintenance:session_expiry"); err != nil {
+		t.Fatalf("RunJob error: %v", err)
+	}
+	if got.Task != TaskSessionExpiry || got.MaxAgeDays != 7 {
+		t.Fatalf("task config = %+v, want normalized session_expiry with max age 7", got)
+	}
+}
+
+func TestMaintenancePublishesToOutput(t *testing.T) {
+	notifier := &fakeNotifier{}
+	prompt := func(context.Context, string, string) (providertypes.PromptResult, error) {
+		return providertypes.PromptResult{}, nil
+	}
+	scheduler, err := NewScheduler(config.CronConfig{}, prompt, map[string]channel.Notifier{OutputTelegram: notifier}, nil)
+	if err != nil {
+		t.Fatalf("NewScheduler error: %v", err)
+	}
+
+	taskErr := errors.New("store offline")
+	tasks := map[string]MaintenanceTask{
+		TaskActivityReport: func(context.Context, config.MaintenanceJobConfig) (string, error) {
+			return "Sessions: 2", nil
+		},
+		TaskSessionExpiry: func(context.Context, config.MaintenanceJobConfig) (string, error) {
+			return "", taskErr
+		},
+	}
+	if err := scheduler.AddMaintenance([]config.MaintenanceJobConfig{
+		{Task: TaskActivityReport, Schedule: "0 8 * * *", Since: "24h", Output: config.CronOutputConfig{Type: "telegram", ChatID: "42"}},
+		{Task: TaskSessionExpiry, Schedule: "@daily", Output: config.CronOutputConfig{Type: "telegram", ChatID: "42"}},
+	}, tasks, nil); err != nil {
+		t.Fatalf("AddMaintenance error: %v", err)
+	}
+
+	if err := scheduler.RunJob(context.Background(), "maintenance:activity_report"); err != nil {
+		t.Fatalf("RunJob error: %v", err)
+	}
+	if err := scheduler.RunJob(context.Background(), "maintenance:session_expiry"); !errors.Is(err, taskErr) {
+		t.Fatalf("RunJob error = %v, want task error", err)
+	}
+	if len(notifier.sent) != 2 {
+		t.Fatalf("sent %d messages, want 2", len(notifier.sent))
+	}
+	if notifier.sent[0].ChatID != "42" || notifier.sent[0].Content != "Sessions: 2" {
+		t.Fatalf("sent = %+v, want report to chat 42", notifier.sent[0])
+	}
+	if !strings.Contains(notifier.sent[1].Error, "store offline") {
+		t.Fatalf("sent = %+v, want task failure", notifier.sent[1])
+	}
+}
+
+func TestMaintenancePublishesRunEvents(t *testing.T) {
+	prompt := func(context.Context, string, string) (providertypes.PromptResult, error) {
+		return providertypes.PromptResult{}, nil
+	}
+	scheduler, err := NewScheduler(config.CronConfig{}, prompt, nil, nil)
+	if err != nil {
+		t.Fatalf("NewScheduler error: %v", err)
+	}
+	messageBus := bus.NewMessageBus()
+	defer messageBus.Close()
+	events, unsubscribe := messageBus.SubscribeEvents(context.Background(), 4)
+	defer unsubscribe()
+
+	tasks := map[string]MaintenanceTask{
+		TaskSnapshotPrune: func(context.Context, config.MaintenanceJobConfig) (string, error) {
+			return "pruned 2 of 5 changes", nil
+		},
+		TaskUsageCompact: func(context.Context, config.MaintenanceJobConfig) (string, error) {
+			return "", errors.New("usage store is off")
+		},
+	}
+	if err := scheduler.AddMaintenance([]config.MaintenanceJobConfig{
+		{Task: TaskSnapshotPrune, Schedule: "@daily"},
+		{Task: TaskUsageCompact, Schedule: "@daily"},
+	}, tasks, messageBus); err != nil {
+		t.Fatalf("AddMaintenance error: %v", err)
+	}
+
+	if err := scheduler.RunJob(context.Background(), "maintenance:snapshot_prune"); err != nil {
+		t.Fatalf("RunJob error: %v", err)
+	}
+	if err := scheduler.RunJob(context.Background(), "maintenance:usage_compact"); err == nil {
+		t.Fatal("expected usage_compact to fail")
+	}
+
+	succeeded := <-events
+	payload, ok := succeeded.Payload.(bus.MaintenancePayload)
+	if succeeded.Type != bus.EventMaintenanceRun || !ok || payload.Task != TaskSnapshotPrune || payload.Result != "pruned 2 of 5 changes" || succeeded.Error != "" {
+		t.Fatalf("event = %+v, want successful snapshot_prune run", succeeded)
+	}
+	if succeeded.SessionKey != "cron:maintenance:snapshot_prune" {
+		t.Fatalf("session key = %q", succeeded.SessionKey)
+	}
+	failed := <-events
+	payload, ok = failed.Payload.(bus.MaintenancePayload)
+	if !ok || payload.Task != TaskUsageCompact || payload.Result != "" || failed.Error != "usage store is off" {
+		t.Fatalf("event = %+v, want failed usage_compact run", failed)
+	}
+}
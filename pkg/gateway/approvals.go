@@ -0,0 +1,194 @@
+package gateway
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/channel"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+// approvalInputPreviewLimit caps how much tool input an owner notification quotes.
+const approvalInputPreviewLimit = 600
+
+// approvalQueue holds tool approvals from channel sessions until the operator
+// answers them at /admin/approvals.
+type approvalQueue struct {
+	log *slog.Logger
+	// notifier and notifyChatID deliver owner notifications; notifier is nil when they are off.
+	notifier     channel.Notifier
+	notifyChatID string
+
+	mu      sync.Mutex
+	next    uint64
+	pending map[string]*queuedApproval
+}
+
+type queuedApproval struct {
+	view  pendingApproval
+	reply chan bool
+}
+
+// pendingApproval is the JSON view of one queued tool approval.
+type pendingApproval struct {
+	ID         string `json:"id"`
+	SessionKey string `json:"session_key"`
+	Channel    string `json:"channel"`
+	ChatID     string `json:"chat_id,omitempty"`
+	SenderID   string `json:"sender_id,omitempty"`
+	Tool       string `json:"tool"`
+	// Input is the raw JSON arguments the model passed to the tool.
+	Input     string `json:"input"`
+	CreatedAt string `json:"created_at"`
+}
+
+// approvalsResponse is the JSON payload returned by GET /admin/approvals.
+type approvalsResponse struct {
+	Approvals []pendingApproval `json:"approvals"`
+}
+
+// approvalDecisionResponse is the JSON payload returned after an approve or deny action.
+type approvalDecisionResponse struct {
+	ID       string `json:"id"`
+	Approved bool   `json:"approved"`
+}
+
+func newApprovalQueue(notifier channel.Notifier, notifyChatID string, log *slog.Logger) *approvalQueue {
+	return &approvalQueue{
+		log:          log.With("component", "gateway.approvals"),
+		notifier:     notifier,
+		notifyChatID: notifyChatID,
+		pending:      make(map[string]*queuedApproval),
+	}
+}
+
+// approver returns a tool approver that queues requests from inbound's session.
+//
+// It blocks until the operator answers or ctx ends; tools.approval.timeout_seconds
+// bounds ctx, so unanswered calls are denied as in the chat-based flow.
+func (q *approvalQueue) approver(inbound bus.InboundMessage, sessionKey string) providertypes.ToolApprover {
+	return func(ctx context.Context, request providertypes.ToolApprovalRequest) (bool, error) {
+		entry := q.add(pendingApproval{
+			SessionKey: sessionKey,
+			Channel:    inbound.Channel,
+			ChatID:     inbound.ChatID,
+			SenderID:   inbound.SenderID,
+			Tool:       request.Tool,
+			Input:      request.Input,
+		})
+		defer q.remove(entry.view.ID)
+
+		q.log.Info("Tool approval queued", "approval_id", entry.view.ID, "session_key", sessionKey, "tool", request.Tool)
+		q.notify(ctx, entry.view)
+
+		select {
+		case approved := <-entry.reply:
+			return approved, nil
+		case <-ctx.Done():
+			q.log.Info("Tool approval expired", "approval_id", entry.view.ID, "session_key", sessionKey, "tool", request.Tool)
+			return false, ctx.Err()
+		}
+	}
+}
+
+// add queues view under a fresh ID.
+func (q *approvalQueue) add(view pendingApproval) *queuedApproval {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.next++
+	view.ID = strconv.FormatUint(q.next, 10)
+	view.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	entry := &queuedApproval{view: view, reply: make(chan bool, 1)}
+	q.pending[view.ID] = entry
+
+	return entry
+}
+
+func (q *approvalQueue) remove(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.pending, id)
+}
+
+// list returns the pending approvals, oldest first.
+func (q *approvalQueue) list() []pendingApproval {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	views := make([]pendingApproval, 0, len(q.pending))
+	for _, entry := range q.pending {
+		views = append(views, entry.view)
+	}
+	slices.SortFunc(views, func(a, b pendingApproval) int {
+		left, _ := strconv.ParseUint(a.ID, 10, 64)
+		right, _ := strconv.ParseUint(b.ID, 10, 64)
+		return cmp.Compare(left, right)
+	})
+
+	return views
+}
+
+// resolve answers one pending approval and reports whether it was still waiting.
+func (q *approvalQueue) resolve(id string, approved bool) bool {
+	q.mu.Lock()
+	entry, ok := q.pending[id]
+	delete(q.pending, id)
+	q.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	q.log.Info("Tool approval answered", "approval_id", id, "session_key", entry.view.SessionKey, "tool", entry.view.Tool, "approved", approved)
+	entry.reply <- approved
+	return true
+}
+
+// notify tells the owner chat about a queued approval; failures are logged and
+// do not affect the approval itself.
+func (q *approvalQueue) notify(ctx context.Context, view pendingApproval) {
+	if q.notifier == nil {
+		return
+	}
+
+	input := strings.TrimSpace(view.Input)
+	if runes := []rune(input); len(runes) > approvalInputPreviewLimit {
+		input = string(runes[:approvalInputPreviewLimit]) + "..."
+	}
+	text := fmt.Sprintf(
+		"Tool approval %s is waiting.\nSession: %s\nTool: %s\nInput: %s\n\nAnswer with POST /admin/approvals/%s/approve or /deny.",
+		view.ID, view.SessionKey, view.Tool, input, view.ID,
+	)
+
+	err := q.notifier.Notify(ctx, bus.OutboundMessage{Channel: "telegram", ChatID: q.notifyChatID, Content: text})
+	if err != nil {
+		q.log.Warn("Failed to send approval notification", "approval_id", view.ID, "error", err)
+	}
+}
+
+// handleListApprovals reports the tool approvals waiting for an answer.
+func (s *Service) handleListApprovals(w http.ResponseWriter, _ *http.Request) {
+	s.respondJSON(w, http.StatusOK, approvalsResponse{Approvals: s.approvals.list()})
+}
+
+// handleResolveApproval returns a handler that approves or denies the approval named by the path.
+func (s *Service) handleResolveApproval(approved bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimSpace(r.PathValue("id"))
+		if !s.approvals.resolve(id, approved) {
+			s.respondJSON(w, http.StatusNotFound, errorResponse{Error: "approval not pending"})
+			return
+		}
+
+		s.respondJSON(w, http.StatusOK, approvalDecisionResponse{ID: id, Approved: approved})
+	}
+}
@@ -0,0 +1,139 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/channel"
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+type recordingNotifier struct {
+	mu   sync.Mutex
+	sent []bus.OutboundMessage
+}
+
+func (r *recordingNotifier) Notify(_ context.Context, outbound bus.OutboundMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, outbound)
+	return nil
+}
+
+func TestApprovalQueueEndpoints(t *testing.T) {
+	t.Parallel()
+
+	notifier := &recordingNotifier{}
+	cfg := &config.Config{Gateway: config.GatewayConfig{
+		Auth:      config.GatewayAuthConfig{Token: "secret"},
+		Approvals: config.GatewayApprovalsConfig{Enabled: true, NotifyTelegramChatID: "42"},
+	}}
+	auth, _ := channel.NewAuth(cfg.Gateway.AuthConfig())
+	approvals, err := newServiceApprovals(cfg.Gateway.Approvals, auth, map[string]channel.Notifier{"telegram": notifier}, slog.Default())
+	if err != nil {
+		t.Fatalf("newServiceApprovals error: %v", err)
+	}
+	svc := &Service{cfg: cfg, log: slog.Default(), approvals: approvals}
+	handler := svc.statusHandler()
+
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	approver := approvals.approver(bus.InboundMessage{Channel: "telegram", ChatID: "100", SenderID: "7"}, "telegram:100")
+	answered := make(chan bool, 1)
+	go func() {
+		approved, _ := approver(context.Background(), providertypes.ToolApprovalRequest{Tool: "run_command", Input: `{"command":"ls"}`})
+		answered <- approved
+	}()
+
+	var listed approvalsResponse
+	deadline := time.Now().Add(2 * time.Second)
+	for len(listed.Approvals) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("approval was never queued")
+		}
+		time.Sleep(5 * time.Millisecond)
+		recorder := do(http.MethodGet, "/admin/approvals", "secret")
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("list status = %d, want 200", recorder.Code)
+		}
+		listed = approvalsResponse{}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &listed); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+	}
+	pending := listed.Approvals[0]
+	if pending.SessionKey != "telegram:100" || pending.Tool != "run_command" || pending.SenderID != "7" {
+		t.Fatalf("pending = %+v, want run_command from telegram:100", pending)
+	}
+
+	if code := do(http.MethodGet, "/admin/approvals", "").Code; code != http.StatusUnauthorized {
+		t.Fatalf("unauthenticated status = %d, want 401", code)
+	}
+	if code := do(http.MethodPost, "/admin/approvals/"+pending.ID+"/approve", "wrong").Code; code != http.StatusUnauthorized {
+		t.Fatalf("wrong token status = %d, want 401", code)
+	}
+	if code := do(http.MethodPost, "/admin/approvals/"+pending.ID+"/approve", "secret").Code; code != http.StatusOK {
+		t.Fatalf("approve status = %d, want 200", code)
+	}
+	if approved := <-answered; !approved {
+		t.Fatal("expected tool call to be approved")
+	}
+	if code := do(http.MethodPost, "/admin/approvals/"+pending.ID+"/deny", "secret").Code; code != http.StatusNotFound {
+		t.Fatalf("second answer status = %d, want 404", code)
+	}
+
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+	if len(notifier.sent) != 1 || notifier.sent[0].ChatID != "42" || !strings.Contains(notifier.sent[0].Content, "run_command") {
+		t.Fatalf("notifications = %+v, want one run_command notice to chat 42", notifier.sent)
+	}
+}
+
+func TestApprovalQueueExpiresWithContext(t *testing.T) {
+	t.Parallel()
+
+	queue := newApprovalQueue(nil, "", slog.Default())
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	approved, err := queue.approver(bus.InboundMessage{}, "telegram:1")(ctx, providertypes.ToolApprovalRequest{Tool: "write_file"})
+	if approved || err == nil {
+		t.Fatalf("approved = %v, err = %v; want denial with context error", approved, err)
+	}
+	if pending := queue.list(); len(pending) != 0 {
+		t.Fatalf("pending = %+v, want expired approval removed", pending)
+	}
+}
+
+func TestNewServiceApprovalsValidatesConfig(t *testing.T) {
+	t.Parallel()
+
+	noAuth, _ := channel.NewAuth(config.GatewayAuthConfig{})
+	auth, _ := channel.NewAuth(config.GatewayAuthConfig{Token: "t"})
+	if queue, err := newServiceApprovals(config.GatewayApprovalsConfig{}, noAuth, nil, slog.Default()); queue != nil || err != nil {
+		t.Fatalf("disabled = (%v, %v), want nil queue", queue, err)
+	}
+	if _, err := newServiceApprovals(config.GatewayApprovalsConfig{Enabled: true}, noAuth, nil, slog.Default()); err == nil {
+		t.Fatal("expected missing gateway.auth error")
+	}
+	if _, err := newServiceApprovals(config.GatewayApprovalsConfig{Enabled: true, NotifyTelegramChatID: "1"}, auth, nil, slog.Default()); err == nil {
+		t.Fatal("expected missing telegram channel error")
+	}
+}
@@ -0,0 +1,53 @@
+package gateway
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"miniclaw/pkg/channel"
+	"miniclaw/pkg/config"
+	"miniclaw/pkg/workspace"
+)
+
+// requireAdminAuth guards the /admin and /events endpoints with auth, and
+// refuses every request to them while gateway.auth sets no credential.
+func requireAdminAuth(auth *channel.Auth, next http.Handler) http.Handler {
+	if !auth.Enabled() {
+		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			auth.Deny(w, http.StatusForbidden, "set gateway.auth to use this endpoint")
+		})
+	}
+
+	return auth.Require(next)
+}
+
+// loadStatusTLS loads the gateway.tls certificate, or returns nil when TLS is
+// off.
+func loadStatusTLS(cfg config.GatewayTLSConfig) (*tls.Config, error) {
+	certFile := strings.TrimSpace(cfg.CertFile)
+	keyFile := strings.TrimSpace(cfg.KeyFile)
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("gateway.tls.cert_file and gateway.tls.key_file must be set together")
+	}
+
+	certFile, err := workspace.ExpandHome(certFile)
+	if err != nil {
+		return nil, err
+	}
+	keyFile, err = workspace.ExpandHome(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load gateway.tls certificate: %w", err)
+	}
+
+	return &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS12}, nil
+}
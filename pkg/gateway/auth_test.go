@@ -0,0 +1,266 @@
+package gateway
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"miniclaw/pkg/channel"
+	"miniclaw/pkg/config"
+)
+
+func TestStatusAuthProtectsNonHealthEndpoints(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{Gateway: config.GatewayConfig{
+		Auth: config.GatewayAuthConfig{Token: "status-token", Username: "ops", Password: "hunter2"},
+	}}
+	handler := (&Service{cfg: cfg, log: slog.Default(), manager: &runtimeManager{metrics: &turnMetrics{}}}).statusHandler()
+
+	do := func(path string, authorize func(*http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if authorize != nil {
+			authorize(req)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+	bearer := func(token string) func(*http.Request) {
+		return func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) }
+	}
+	basic := func(user, password string) func(*http.Request) {
+		return func(req *http.Request) { req.SetBasicAuth(user, password) }
+	}
+
+	if code := do("/healthz", nil).Code; code != http.StatusOK {
+		t.Fatalf("healthz status = %d, want 200 without credentials", code)
+	}
+	recorder := do("/v1/metrics", nil)
+	if recorder.Code != http.StatusUnauthorized || recorder.Header().Get("WWW-Authenticate") == "" {
+		t.Fatalf("unauthenticated metrics = %d %v, want 401 with a challenge", recorder.Code, recorder.Header())
+	}
+	for name, authorize := range map[string]func(*http.Request){
+		"bearer": bearer("status-token"),
+		"basic":  basic("ops", "hunter2"),
+	} {
+		if code := do("/v1/metrics", authorize).Code; code != http.StatusOK {
+			t.Fatalf("%s metrics status = %d, want 200", name, code)
+		}
+	}
+	for name, authorize := range map[string]func(*http.Request){
+		"wrong token":    bearer("nope"),
+		"wrong password": basic("ops", "nope"),
+	} {
+		if code := do("/v1/metrics", authorize).Code; code != http.StatusUnauthorized {
+			t.Fatalf("%s metrics status = %d, want 401", name, code)
+		}
+	}
+
+	// /admin takes the same credentials.
+	if code := do("/admin/sessions/telegram:1/export", nil).Code; code != http.StatusUnauthorized {
+		t.Fatalf("unauthenticated admin = %d, want 401", code)
+	}
+	if code := do("/admin/sessions/telegram:1/export", bearer("status-token")).Code; code == http.StatusUnauthorized {
+		t.Fatalf("admin with gateway.auth token = %d, want it accepted", code)
+	}
+}
+
+func TestApprovalsTokenIsAGatewayAuthAlias(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{Gateway: config.GatewayConfig{Approvals: config.GatewayApprovalsConfig{Token: "admin-token"}}}
+	handler := (&Service{cfg: cfg, log: slog.Default(), manager: &runtimeManager{metrics: &turnMetrics{}}}).statusHandler()
+
+	for _, path := range []string{"/v1/metrics", "/admin/sessions/telegram:1/export"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer admin-token")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		if recorder.Code == http.StatusUnauthorized {
+			t.Fatalf("%s with the approvals token = %d, want it accepted", path, recorder.Code)
+		}
+	}
+}
+
+func TestAdminEndpointsRefusedWithoutAuth(t *testing.T) {
+	t.Parallel()
+
+	handler := (&Service{cfg: &config.Config{}, log: slog.Default(), manager: &runtimeManager{metrics: &turnMetrics{}}}).statusHandler()
+	for _, path := range []string{"/events", "/admin/events", "/admin/sessions/telegram:1/export"} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if recorder.Code != http.StatusForbidden {
+			t.Fatalf("%s without gateway.auth = %d, want 403", path, recorder.Code)
+		}
+	}
+}
+
+func TestLoadStatusTLS(t *testing.T) {
+	t.Parallel()
+
+	if tlsConfig, err := loadStatusTLS(config.GatewayTLSConfig{}); err != nil || tlsConfig != nil {
+		t.Fatalf("no TLS = %v, %v; want nil, nil", tlsConfig, err)
+	}
+	if _, err := loadStatusTLS(config.GatewayTLSConfig{CertFile: "cert.pem"}); err == nil {
+		t.Fatal("cert without key must be rejected")
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCertificate(t, dir)
+	if _, err := loadStatusTLS(config.GatewayTLSConfig{CertFile: certFile, KeyFile: filepath.Join(dir, "missing.pem")}); err == nil {
+		t.Fatal("missing key file must be rejected")
+	}
+	tlsConfig, err := loadStatusTLS(config.GatewayTLSConfig{CertFile: certFile, KeyFile: keyFile})
+	if err != nil || tlsConfig == nil || len(tlsConfig.Certificates) != 1 {
+		t.Fatalf("loadStatusTLS = %v, %v; want one certificate", tlsConfig, err)
+	}
+}
+
+// writeTestCertificate writes a self-signed PEM certificate and key to dir.
+func writeTestCertificate(t *testing.T, dir string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		t.Fatalf("write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+
+	return certFile, keyFile
+}
+
+// webhookAdapter receives webhooks on /hooks/chat and answers 204.
+type webhookAdapter struct {
+	scriptedAdapter
+}
+
+func (a *webhookAdapter) WebhookPath() string {
+	return "/hooks/chat"
+}
+
+func (a *webhookAdapter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestStatusHandlerRoutesChannelWebhooksWithoutStatusAuth(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{Gateway: config.GatewayConfig{Auth: config.GatewayAuthConfig{Token: "status-token"}}}
+	svc := &Service{cfg: cfg, log: slog.Default(), manager: &runtimeManager{metrics: &turnMetrics{}}}
+	handler := svc.statusHandler()
+
+	do := func(method, path string) int {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+		return recorder.Code
+	}
+
+	if code := do(http.MethodPost, "/hooks/chat"); code != http.StatusNotFound {
+		t.Fatalf("webhook before the channel runs = %d, want 404", code)
+	}
+
+	svc.reloadMu.Lock()
+	svc.running = map[string]*runningChannel{"chat": {adapter: &webhookAdapter{scriptedAdapter{name: "chat"}}}}
+	svc.reloadMu.Unlock()
+
+	if code := do(http.MethodPost, "/hooks/chat"); code != http.StatusNoContent {
+		t.Fatalf("webhook status = %d, want the adapter's 204", code)
+	}
+	if code := do(http.MethodGet, "/hooks/chat"); code != http.StatusNotFound {
+		t.Fatalf("GET webhook path = %d, want 404", code)
+	}
+	if code := do(http.MethodGet, "/v1/metrics"); code != http.StatusUnauthorized {
+		t.Fatalf("metrics without credentials = %d, want 401", code)
+	}
+}
+
+// pageAdapter serves pages under /chat/ and answers 200.
+type pageAdapter struct {
+	scriptedAdapter
+}
+
+func (a *pageAdapter) PagePath() string {
+	return "/chat/"
+}
+
+func (a *pageAdapter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestStatusHandlerRoutesChannelPages(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{Gateway: config.GatewayConfig{Auth: config.GatewayAuthConfig{Token: "status-token"}}}
+	svc := &Service{cfg: cfg, log: slog.Default(), manager: &runtimeManager{metrics: &turnMetrics{}}}
+	handler := svc.statusHandler()
+	do := func(path string) int {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder.Code
+	}
+
+	if code := do("/chat/"); code != http.StatusNotFound {
+		t.Fatalf("page before the channel runs = %d, want 404", code)
+	}
+	svc.reloadMu.Lock()
+	svc.running = map[string]*runningChannel{"chat": {adapter: &pageAdapter{scriptedAdapter{name: "chat"}}}}
+	svc.reloadMu.Unlock()
+	for _, path := range []string{"/chat", "/chat/", "/chat/ws"} {
+		if code := do(path); code != http.StatusOK {
+			t.Fatalf("%s = %d, want the adapter's 200", path, code)
+		}
+	}
+	if code := do("/chatter"); code != http.StatusNotFound {
+		t.Fatalf("/chatter = %d, want 404", code)
+	}
+}
+
+func TestValidateChannelPagesNeedsAuthBeyondLoopback(t *testing.T) {
+	t.Parallel()
+
+	adapters := []channel.Adapter{&pageAdapter{scriptedAdapter{name: "chat"}}}
+	if err := validateChannelPages(config.GatewayConfig{}, adapters); err == nil {
+		t.Fatal("expected pages on the default 0.0.0.0 host without gateway.auth to be refused")
+	}
+	if err := validateChannelPages(config.GatewayConfig{Host: "127.0.0.1"}, adapters); err != nil {
+		t.Fatalf("loopback host: %v", err)
+	}
+	if err := validateChannelPages(config.GatewayConfig{Auth: config.GatewayAuthConfig{Token: "t"}}, adapters); err != nil {
+		t.Fatalf("with gateway.auth: %v", err)
+	}
+}
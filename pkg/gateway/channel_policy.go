@@ -0,0 +1,105 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/channel"
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+	"miniclaw/pkg/telemetry"
+)
+
+// defaultRateLimitPeriod is the channels.policy.rate_limit window when
+// period_seconds is unset.
+const defaultRateLimitPeriod = time.Minute
+
+// newChannelPolicy validates channels.policy and builds its middleware: the
+// allowlist, then the content filter, then the rate limit, so refused
+// messages do not count against a sender's limit.
+func newChannelPolicy(cfg config.ChannelPolicyConfig) ([]channel.Middleware, error) {
+	patterns := make([]*regexp.Regexp, 0, len(cfg.BlockPatterns))
+	for _, raw := range cfg.BlockPatterns {
+		pattern, err := regexp.Compile(raw)
+		if err != nil {
+			return nil, fmt.Errorf("channels.policy.block_patterns %q: %w", raw, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	if cfg.MaxMessageChars < 0 {
+		return nil, errors.New("channels.policy.max_message_chars must not be negative")
+	}
+	if cfg.RateLimit.Messages < 0 || cfg.RateLimit.PeriodSeconds < 0 {
+		return nil, errors.New("channels.policy.rate_limit messages and period_seconds must not be negative")
+	}
+	period := defaultRateLimitPeriod
+	if cfg.RateLimit.PeriodSeconds > 0 {
+		period = time.Duration(cfg.RateLimit.PeriodSeconds) * time.Second
+	}
+
+	return []channel.Middleware{
+		channel.AllowFrom(cfg.AllowFrom),
+		channel.FilterContent(patterns, cfg.MaxMessageChars),
+		channel.RateLimit(cfg.RateLimit.Messages, period),
+	}, nil
+}
+
+// setChannelPolicy replaces the channels.policy middleware when cfg differs
+// from the running policy, so unrelated reloads keep the rate limit counts.
+func (s *Service) setChannelPolicy(cfg config.ChannelPolicyConfig, policy []channel.Middleware) {
+	s.policyMu.Lock()
+	defer s.policyMu.Unlock()
+
+	if s.policy != nil && reflect.DeepEqual(s.policyCfg, cfg) {
+		return
+	}
+	s.policyCfg = cfg
+	s.policy = policy
+}
+
+// UseChannel adds middleware around every message a channel adapter hands
+// the gateway, inside channels.policy and after any added earlier; see
+// channel.Chain. Call it before Run.
+func (s *Service) UseChannel(middleware ...channel.Middleware) {
+	s.policyMu.Lock()
+	defer s.policyMu.Unlock()
+
+	s.channelMiddleware = append(s.channelMiddleware, middleware...)
+}
+
+// dispatchInbound is the handler channel adapters run: handleInbound wrapped
+// in the bus journal, the channel metrics, the channels.templates replies, the
+// channels.policy middleware, and the middleware added with UseChannel.
+// Messages forwarded by other cluster instances skip it, as the receiving
+// instance already applied it.
+func (s *Service) dispatchInbound(ctx context.Context, inbound bus.InboundMessage) (bus.OutboundMessage, error) {
+	s.policyMu.RLock()
+	middleware := slices.Concat([]channel.Middleware{s.journalInbound, channel.Observe(s.recordChannelMessage), s.templates}, s.policy, s.channelMiddleware)
+	s.policyMu.RUnlock()
+
+	return channel.Chain(s.handleInbound, middleware...)(ctx, inbound)
+}
+
+// recordChannelMessage reports one channel message to telemetry by channel
+// and outcome: ok, error, rejected by channels.policy, or rate_limited.
+func (s *Service) recordChannelMessage(inbound bus.InboundMessage, err error, elapsed time.Duration) {
+	outcome := "ok"
+	switch {
+	case errors.Is(err, channel.ErrRateLimited):
+		outcome = "rate_limited"
+	case providertypes.ErrorCategoryOf(err) == providertypes.ErrorRejected:
+		outcome = "rejected"
+	case err != nil:
+		outcome = "error"
+	}
+	name := strings.TrimSpace(inbound.Channel)
+	s.manager.telemetry.Add(metricChannelMessages, 1, telemetry.L("channel", name), telemetry.L("outcome", outcome))
+	s.manager.telemetry.Observe(metricChannelSeconds, elapsed.Seconds(), telemetry.L("channel", name))
+}
@@ -0,0 +1,142 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/channel"
+	"miniclaw/pkg/config"
+)
+
+const (
+	defaultChannelMaxRestarts    = 5
+	defaultChannelRestartBackoff = time.Second
+	defaultChannelMaxBackoff     = time.Minute
+
+	// channelStableRun is how long an adapter must run before its next
+	// failure starts a new run of restarts with the initial backoff.
+	channelStableRun = 5 * time.Minute
+)
+
+// channelRestartPolicy is channels.restart with its defaults applied. The
+// zero value never restarts.
+type channelRestartPolicy struct {
+	maxRestarts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+}
+
+// newChannelRestartPolicy validates channels.restart and applies its defaults.
+func newChannelRestartPolicy(cfg config.ChannelRestartConfig) (channelRestartPolicy, error) {
+	if cfg.InitialBackoffSeconds < 0 || cfg.MaxBackoffSeconds < 0 {
+		return channelRestartPolicy{}, errors.New("channels.restart backoff seconds must not be negative")
+	}
+
+	policy := channelRestartPolicy{
+		maxRestarts: defaultChannelMaxRestarts,
+		backoff:     defaultChannelRestartBackoff,
+		maxBackoff:  defaultChannelMaxBackoff,
+	}
+	if cfg.MaxRestarts != 0 {
+		policy.maxRestarts = max(cfg.MaxRestarts, 0)
+	}
+	if cfg.InitialBackoffSeconds > 0 {
+		policy.backoff = time.Duration(cfg.InitialBackoffSeconds) * time.Second
+	}
+	if cfg.MaxBackoffSeconds > 0 {
+		policy.maxBackoff = time.Duration(cfg.MaxBackoffSeconds) * time.Second
+	}
+	policy.maxBackoff = max(policy.maxBackoff, policy.backoff)
+
+	return policy, nil
+}
+
+// delay is the wait before the attempt-th restart in a row, doubling from the
+// initial backoff up to maxBackoff.
+func (p channelRestartPolicy) delay(attempt int) time.Duration {
+	backoff := p.backoff
+	for range attempt - 1 {
+		backoff *= 2
+		if backoff >= p.maxBackoff {
+			return p.maxBackoff
+		}
+	}
+
+	return backoff
+}
+
+// setChannelRestartPolicy replaces the policy later channel failures follow.
+func (s *Service) setChannelRestartPolicy(policy channelRestartPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.restartPolicy = policy
+}
+
+// superviseChannel runs adapter until ctx ends or it stops without an error.
+// After a failure it restarts the adapter following channels.restart,
+// reporting the restarts and the next attempt in the channel state. Once the
+// restarts run out, the channel stays stopped and its error reaches Run.
+func (s *Service) superviseChannel(ctx context.Context, adapter channel.Adapter, running *runningChannel) {
+	defer close(running.done)
+
+	name := adapter.Name()
+	restarts, attempt := 0, 0
+	for {
+		started := time.Now()
+		err := adapter.Run(ctx, s.dispatchInbound)
+		failed := err != nil && !errors.Is(err, context.Canceled)
+
+		stopped := bus.Event{Type: bus.EventChannelDisconnected, Channel: name}
+		if failed {
+			stopped.Error = err.Error()
+		}
+		_ = s.manager.events.PublishEvent(context.WithoutCancel(ctx), stopped)
+		state := channelState{Running: false, Error: errorString(err), Restarts: restarts}
+		if !failed || ctx.Err() != nil {
+			s.setChannelState(name, state)
+			return
+		}
+
+		s.mu.RLock()
+		policy := s.restartPolicy
+		s.mu.RUnlock()
+		if time.Since(started) >= channelStableRun {
+			attempt = 0
+		}
+		if attempt >= policy.maxRestarts {
+			s.setChannelState(name, state)
+			if policy.maxRestarts > 0 {
+				err = fmt.Errorf("%w (gave up after %d restarts)", err, attempt)
+			}
+			// Run returns on the first failure; later ones are only published.
+			select {
+			case s.channelErrs <- fmt.Errorf("run %s channel: %w", name, err):
+			default:
+			}
+			return
+		}
+
+		attempt++
+		wait := policy.delay(attempt)
+		state.NextRestartAt = time.Now().Add(wait).UTC().Format(time.RFC3339)
+		s.setChannelState(name, state)
+		s.log.Warn("Channel failed; restarting", "channel", name, "error", err, "attempt", attempt, "max_restarts", policy.maxRestarts, "backoff_ms", wait.Milliseconds())
+
+		select {
+		case <-ctx.Done():
+			state.NextRestartAt = ""
+			s.setChannelState(name, state)
+			return
+		case <-time.After(wait):
+		}
+
+		restarts++
+		s.setChannelState(name, channelState{Running: true, Restarts: restarts})
+		_ = s.manager.events.PublishEvent(ctx, bus.Event{Type: bus.EventChannelConnected, Channel: name})
+		s.log.Info("Channel restarted", "channel", name, "restarts", restarts)
+	}
+}
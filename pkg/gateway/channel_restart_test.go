@@ -0,0 +1,148 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"miniclaw/pkg/channel"
+	"miniclaw/pkg/config"
+)
+
+// flakyAdapter fails its first failures runs, then runs until canceled.
+type flakyAdapter struct {
+	name     string
+	failures int
+	done     chan struct{}
+
+	mu   sync.Mutex
+	runs int
+}
+
+func (a *flakyAdapter) Name() string {
+	return a.name
+}
+
+func (a *flakyAdapter) Run(ctx context.Context, _ channel.Handler) error {
+	a.mu.Lock()
+	a.runs++
+	runs := a.runs
+	a.mu.Unlock()
+	if runs <= a.failures {
+		return errors.New("updates channel closed")
+	}
+
+	close(a.done)
+	<-ctx.Done()
+	return nil
+}
+
+func newRestartTestService(t *testing.T, ctx context.Context, adapter channel.Adapter, policy channelRestartPolicy) *Service {
+	t.Helper()
+
+	cfg := &config.Config{
+		Agents:  config.AgentsConfig{Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5.2"}},
+		Gateway: config.GatewayConfig{Host: "127.0.0.1", Port: freeTCPPort(t)},
+	}
+	manager, err := newRuntimeManager(ctx, cfg, &recordingGatewayProvider{}, slog.Default())
+	if err != nil {
+		t.Fatalf("newRuntimeManager error: %v", err)
+	}
+
+	return &Service{
+		cfg:           cfg,
+		log:           slog.Default(),
+		provider:      &recordingGatewayProvider{},
+		manager:       manager,
+		channels:      []channel.Adapter{adapter},
+		channelStates: map[string]channelState{adapter.Name(): {}},
+		restartPolicy: policy,
+	}
+}
+
+func TestSuperviseChannelRestartsFailedAdapter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	telegram := &flakyAdapter{name: "telegram", failures: 2, done: make(chan struct{})}
+	svc := newRestartTestService(t, ctx, telegram, channelRestartPolicy{maxRestarts: 3, backoff: 10 * time.Millisecond, maxBackoff: 20 * time.Millisecond})
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- svc.Run(ctx) }()
+	waitClosed(t, telegram.done)
+
+	state := svc.currentStatus("ok").Channels["telegram"]
+	if !state.Running || state.Restarts != 2 || state.Error != "" || state.NextRestartAt != "" {
+		t.Fatalf("telegram state after restarts = %+v", state)
+	}
+
+	cancel()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("Run error: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for service run to exit")
+	}
+}
+
+func TestSuperviseChannelGivesUpAfterMaxRestarts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	telegram := &flakyAdapter{name: "telegram", failures: 10, done: make(chan struct{})}
+	svc := newRestartTestService(t, ctx, telegram, channelRestartPolicy{maxRestarts: 2, backoff: time.Millisecond, maxBackoff: time.Millisecond})
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- svc.Run(ctx) }()
+	select {
+	case err := <-errCh:
+		if err == nil || !strings.Contains(err.Error(), "run telegram channel: updates channel closed (gave up after 2 restarts)") {
+			t.Fatalf("Run error = %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for Run to give up")
+	}
+
+	telegram.mu.Lock()
+	runs := telegram.runs
+	telegram.mu.Unlock()
+	if runs != 3 {
+		t.Fatalf("adapter ran %d times, want 3", runs)
+	}
+	if state := svc.currentStatus("ok").Channels["telegram"]; state.Running || state.Restarts != 2 || state.Error != "updates channel closed" {
+		t.Fatalf("telegram state after giving up = %+v", state)
+	}
+}
+
+func TestChannelRestartPolicy(t *testing.T) {
+	policy, err := newChannelRestartPolicy(config.ChannelRestartConfig{})
+	if err != nil {
+		t.Fatalf("newChannelRestartPolicy error: %v", err)
+	}
+	if policy.maxRestarts != 5 {
+		t.Fatalf("default max restarts = %d, want 5", policy.maxRestarts)
+	}
+	var delays []time.Duration
+	for attempt := 1; attempt <= 8; attempt++ {
+		delays = append(delays, policy.delay(attempt))
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 32 * time.Second, time.Minute, time.Minute}
+	for i := range want {
+		if delays[i] != want[i] {
+			t.Fatalf("delays = %v, want %v", delays, want)
+		}
+	}
+
+	if policy, _ := newChannelRestartPolicy(config.ChannelRestartConfig{MaxRestarts: -1}); policy.maxRestarts != 0 {
+		t.Fatalf("max_restarts -1 gave %d restarts, want none", policy.maxRestarts)
+	}
+	if _, err := newChannelRestartPolicy(config.ChannelRestartConfig{MaxBackoffSeconds: -5}); err == nil {
+		t.Fatal("expected a negative backoff to be rejected")
+	}
+}
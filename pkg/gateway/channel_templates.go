@@ -0,0 +1,58 @@
+package gateway
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/channel"
+	"miniclaw/pkg/config"
+)
+
+// newChannelTemplates validates channels.templates and parses each entry,
+// filling its empty templates from the "default" entry.
+func newChannelTemplates(cfg map[string]config.ChannelTemplatesConfig) (map[string]channel.Templates, error) {
+	defaults := cfg[config.ChannelTemplatesDefault]
+	templates := make(map[string]channel.Templates, len(cfg))
+	for _, name := range slices.Sorted(maps.Keys(cfg)) {
+		entry := cfg[name]
+		entry.Error = cmp.Or(entry.Error, defaults.Error)
+		entry.RateLimit = cmp.Or(entry.RateLimit, defaults.RateLimit)
+		entry.Greeting = cmp.Or(entry.Greeting, defaults.Greeting)
+		parsed, err := channel.ParseTemplates(name, entry)
+		if err != nil {
+			return nil, err
+		}
+		templates[name] = parsed
+	}
+
+	return templates, nil
+}
+
+// setChannelTemplates replaces the channels.templates middleware.
+func (s *Service) setChannelTemplates(templates map[string]channel.Templates) {
+	s.policyMu.Lock()
+	defer s.policyMu.Unlock()
+
+	s.templates = channel.ApplyTemplates(templates, s.describeChat)
+}
+
+// describeChat fills in the template data of the chat inbound came from,
+// including the agent and model that answer it.
+func (s *Service) describeChat(inbound bus.InboundMessage) channel.TemplateData {
+	data := channel.TemplateData{
+		Channel:    inbound.Channel,
+		ChatID:     inbound.ChatID,
+		SenderID:   inbound.SenderID,
+		SessionKey: inbound.SessionKey,
+		Model:      s.manager.cfg.Agents.Defaults.Model,
+	}
+	if route := s.manager.routeInbound(inbound.Channel, inbound.SessionKey, inbound.Content); route.agent != "" {
+		if agent, ok := s.manager.lookupAgent(route.agent); ok {
+			data.Agent, data.Model = agent.name, agent.model
+		}
+	}
+
+	return data
+}
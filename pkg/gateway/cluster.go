@@ -0,0 +1,165 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	agentruntime "miniclaw/pkg/agent/runtime"
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/channel"
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+// defaultForwardTimeout bounds waiting for another instance's answer when
+// gateway.cluster.forward_timeout_seconds is unset.
+const defaultForwardTimeout = 10 * time.Minute
+
+// gatewayCluster routes inbound messages between the instances of
+// gateway.cluster, so each session is answered by the one instance that owns
+// it no matter which instance's channel received the message.
+type gatewayCluster struct {
+	self      string
+	instances []string
+	timeout   time.Duration
+	// bus reaches the other instances; its inbox is self.
+	bus *bus.MessageBus
+	log *slog.Logger
+}
+
+// newGatewayCluster validates cfg.Gateway.Cluster and opens this instance's
+// peer bus. It returns nil when clustering is disabled.
+func newGatewayCluster(cfg *config.Config, log *slog.Logger) (*gatewayCluster, error) {
+	clusterCfg := cfg.Gateway.Cluster
+	if !clusterCfg.Enabled {
+		return nil, nil
+	}
+
+	self := strings.TrimSpace(clusterCfg.InstanceID)
+	if self == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("gateway.cluster.instance_id: %w", err)
+		}
+		self = hostname
+	}
+
+	instances := make([]string, 0, len(clusterCfg.Instances))
+	for _, instance := range clusterCfg.Instances {
+		instance = strings.TrimSpace(instance)
+		if instance == "" {
+			return nil, errors.New("gateway.cluster.instances must not contain empty names")
+		}
+		if slices.Contains(instances, instance) {
+			return nil, fmt.Errorf("gateway.cluster.instances lists %q twice", instance)
+		}
+		instances = append(instances, instance)
+	}
+	if !slices.Contains(instances, self) {
+		return nil, fmt.Errorf("gateway.cluster.instances must include this instance %q", self)
+	}
+
+	timeout := defaultForwardTimeout
+	if clusterCfg.ForwardTimeoutSeconds > 0 {
+		timeout = time.Duration(clusterCfg.ForwardTimeoutSeconds) * time.Second
+	}
+
+	peerBus, err := bus.OpenPeer(cfg.Bus, self)
+	if err != nil {
+		return nil, fmt.Errorf("gateway.cluster: %w", err)
+	}
+
+	return &gatewayCluster{
+		self:      self,
+		instances: instances,
+		timeout:   timeout,
+		bus:       peerBus,
+		log:       log.With("component", "gateway.cluster", "instance", self),
+	}, nil
+}
+
+// owner returns the instance that answers sessionKey, chosen by rendezvous
+// hashing: every instance computes the same owner from the same list, and
+// removing an instance only moves the sessions it owned.
+func (c *gatewayCluster) owner(sessionKey string) string {
+	owner := ""
+	var best uint64
+	for _, instance := range c.instances {
+		sum := sha256.Sum256([]byte(instance + "\x00" + sessionKey))
+		if score := binary.BigEndian.Uint64(sum[:8]); owner == "" || score > best {
+			owner, best = instance, score
+		}
+	}
+
+	return owner
+}
+
+// forward sends inbound to owner and waits for its answer. A failed answer is
+// returned with the categorized error it carries.
+func (c *gatewayCluster) forward(ctx context.Context, owner string, inbound bus.InboundMessage) (bus.OutboundMessage, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	failed := func(err error) (bus.OutboundMessage, error) {
+		return bus.OutboundMessage{
+			Channel:    inbound.Channel,
+			ChatID:     inbound.ChatID,
+			SessionKey: inbound.SessionKey,
+			Error:      err.Error(),
+			Metadata:   agentruntime.PromptErrorMetadata(err),
+		}, err
+	}
+
+	pending, ok := c.bus.RequestTo(ctx, owner, inbound)
+	if !ok {
+		return failed(fmt.Errorf("forward to gateway instance %s failed", owner))
+	}
+	defer pending.Close()
+
+	reply, err := pending.Wait(ctx)
+	if err != nil {
+		return failed(fmt.Errorf("wait for gateway instance %s: %w", owner, err))
+	}
+
+	return reply, agentruntime.PromptErrorFromOutbound(reply)
+}
+
+// serveCluster answers messages other instances forward to this one until
+// ctx ends. Each message runs on its own goroutine, so a /stop reaches the
+// session while the prompt it cancels is still running.
+func (s *Service) serveCluster(ctx context.Context) {
+	for {
+		inbound, ok := s.cluster.bus.ConsumeInbound(ctx)
+		if !ok {
+			return
+		}
+
+		go func() {
+			traceID := channel.TraceInbound(&inbound)
+			s.cluster.log.Debug("Answering forwarded message", "trace_id", traceID, "session_key", inbound.SessionKey, "from", inbound.Metadata[bus.MetadataReplyInbox])
+
+			outbound, err := s.answerInbound(providertypes.WithTraceID(ctx, traceID), inbound)
+			if err != nil && outbound.Error == "" {
+				outbound.Error = err.Error()
+			}
+			if outbound.Metadata == nil {
+				outbound.Metadata = make(map[string]string, 1)
+			}
+			outbound.Metadata[agentruntime.TraceIDKey] = traceID
+
+			publishCtx := context.WithoutCancel(ctx)
+			if !s.cluster.bus.PublishOutbound(publishCtx, bus.CarryTrace(inbound, outbound)) {
+				s.cluster.log.Warn("Failed to return forwarded answer", "trace_id", traceID, "session_key", inbound.SessionKey)
+			}
+			_ = s.cluster.bus.Ack(publishCtx, inbound)
+		}()
+	}
+}
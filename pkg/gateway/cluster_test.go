@@ -0,0 +1,219 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/config"
+)
+
+// memoryFabric connects memoryTransports by inbox, standing in for a NATS or
+// Redis server.
+type memoryFabric struct {
+	mu         sync.Mutex
+	transports map[string]*memoryTransport
+}
+
+type memoryTransport struct {
+	fabric  *memoryFabric
+	inbox   string
+	inbound chan bus.InboundMessage
+	replies chan bus.OutboundMessage
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (f *memoryFabric) peer(inbox string) *bus.MessageBus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.transports == nil {
+		f.transports = make(map[string]*memoryTransport)
+	}
+	transport := &memoryTransport{
+		fabric:  f,
+		inbox:   inbox,
+		inbound: make(chan bus.InboundMessage, 8),
+		replies: make(chan bus.OutboundMessage, 8),
+		done:    make(chan struct{}),
+	}
+	f.transports[inbox] = transport
+
+	return bus.NewTransportMessageBus(transport)
+}
+
+func (f *memoryFabric) lookup(inbox string) (*memoryTransport, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	transport, ok := f.transports[inbox]
+	if !ok {
+		return nil, errors.New("no such inbox")
+	}
+	return transport, nil
+}
+
+func (t *memoryTransport) Inbox() string { return t.inbox }
+
+func (t *memoryTransport) SendInbound(context.Context, bus.InboundMessage) error {
+	return errors.New("shared work is not used by the cluster")
+}
+
+func (t *memoryTransport) SendInboundTo(ctx context.Context, inbox string, msg bus.InboundMessage) error {
+	peer, err := t.fabric.lookup(inbox)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case peer.inbound <- msg:
+		return nil
+	}
+}
+
+func (t *memoryTransport) ReceiveInbound(ctx context.Context) (bus.InboundMessage, error) {
+	select {
+	case <-ctx.Done():
+		return bus.InboundMessage{}, ctx.Err()
+	case <-t.done:
+		return bus.InboundMessage{}, bus.ErrClosed
+	case msg := <-t.inbound:
+		return msg, nil
+	}
+}
+
+func (t *memoryTransport) SendReply(ctx context.Context, inbox string, msg bus.OutboundMessage) error {
+	peer, err := t.fabric.lookup(inbox)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case peer.replies <- msg:
+		return nil
+	}
+}
+
+func (t *memoryTransport) ReceiveReply(ctx context.Context) (bus.OutboundMessage, error) {
+	select {
+	case <-ctx.Done():
+		return bus.OutboundMessage{}, ctx.Err()
+	case <-t.done:
+		return bus.OutboundMessage{}, bus.ErrClosed
+	case msg := <-t.replies:
+		return msg, nil
+	}
+}
+
+func (t *memoryTransport) Ack(context.Context, bus.InboundMessage) error { return nil }
+
+func (t *memoryTransport) Close() error {
+	t.closeOnce.Do(func() { close(t.done) })
+	return nil
+}
+
+func TestGatewayClusterOwnerIsStableAcrossInstances(t *testing.T) {
+	t.Parallel()
+
+	all := &gatewayCluster{instances: []string{"gw-a", "gw-b", "gw-c"}}
+	reordered := &gatewayCluster{instances: []string{"gw-c", "gw-a", "gw-b"}}
+	withoutC := &gatewayCluster{instances: []string{"gw-a", "gw-b"}}
+
+	owned := make(map[string]int)
+	for i := range 300 {
+		key := "telegram:" + strconv.Itoa(i)
+		owner := all.owner(key)
+		owned[owner]++
+		if got := reordered.owner(key); got != owner {
+			t.Fatalf("owner(%s) = %s with reordered instances, want %s", key, got, owner)
+		}
+		if owner != "gw-c" && withoutC.owner(key) != owner {
+			t.Fatalf("removing gw-c moved %s away from %s", key, owner)
+		}
+	}
+	for _, instance := range all.instances {
+		if owned[instance] < 50 {
+			t.Fatalf("sessions per instance = %v, want an even spread", owned)
+		}
+	}
+}
+
+func TestNewGatewayClusterValidatesConfig(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{Gateway: config.GatewayConfig{Cluster: config.GatewayClusterConfig{
+		Enabled:    true,
+		InstanceID: "gw-a",
+		Instances:  []string{"gw-b"},
+	}}}
+	if _, err := newGatewayCluster(cfg, slog.Default()); err == nil {
+		t.Fatal("expected an error when instances does not list this instance")
+	}
+
+	cfg.Gateway.Cluster.Instances = []string{"gw-a", "gw-b"}
+	if _, err := newGatewayCluster(cfg, slog.Default()); err == nil {
+		t.Fatal("expected an error for a bus backend other processes cannot reach")
+	}
+
+	cfg.Gateway.Cluster.Enabled = false
+	if cluster, err := newGatewayCluster(cfg, slog.Default()); cluster != nil || err != nil {
+		t.Fatalf("disabled cluster = %v, %v; want nil, nil", cluster, err)
+	}
+}
+
+func TestHandleInboundForwardsToSessionOwner(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := &config.Config{Agents: config.AgentsConfig{Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5.2"}}}
+	instances := []string{"gw-a", "gw-b"}
+	fabric := &memoryFabric{}
+	services := make(map[string]*Service, len(instances))
+	providers := make(map[string]*recordingGatewayProvider, len(instances))
+	for _, instance := range instances {
+		providers[instance] = &recordingGatewayProvider{}
+		manager, err := newRuntimeManager(ctx, cfg, providers[instance], slog.Default())
+		if err != nil {
+			t.Fatalf("newRuntimeManager error: %v", err)
+		}
+		t.Cleanup(manager.Close)
+		peer := fabric.peer(instance)
+		t.Cleanup(peer.Close)
+		services[instance] = &Service{
+			cfg:      cfg,
+			log:      slog.Default(),
+			provider: providers[instance],
+			manager:  manager,
+			cluster:  &gatewayCluster{self: instance, instances: instances, timeout: 5 * time.Second, bus: peer, log: slog.Default()},
+		}
+		go services[instance].serveCluster(ctx)
+	}
+
+	sessionKey := ""
+	for i := 0; sessionKey == ""; i++ {
+		if key := "telegram:" + strconv.Itoa(i); services["gw-a"].cluster.owner(key) == "gw-b" {
+			sessionKey = key
+		}
+	}
+
+	outbound, err := services["gw-a"].handleInbound(ctx, bus.InboundMessage{Channel: "telegram", ChatID: "1", SessionKey: sessionKey, Content: "hello"})
+	if err != nil {
+		t.Fatalf("handleInbound error: %v", err)
+	}
+	if outbound.Content != "ok:hello" || outbound.SessionKey != sessionKey {
+		t.Fatalf("outbound = %+v, want ok:hello for %s", outbound, sessionKey)
+	}
+	if _, _, prompts := providers["gw-a"].snapshot(); len(prompts) != 0 {
+		t.Fatalf("gw-a prompted %v, want nothing", prompts)
+	}
+	if _, _, prompts := providers["gw-b"].snapshot(); len(prompts) != 1 || prompts[0] != "hello" {
+		t.Fatalf("gw-b prompts = %v, want [hello]", prompts)
+	}
+}
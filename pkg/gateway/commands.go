@@ -0,0 +1,224 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"miniclaw/pkg/accounting"
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/channel"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+// defaultAgentName selects the default agent in "/model default".
+const defaultAgentName = "default"
+
+// answerCommand answers the chat commands of channel.Commands without
+// prompting the agent. It reports false for any other message, including
+// unknown slash commands, which go to the agent as written.
+func (s *Service) answerCommand(ctx context.Context, inbound bus.InboundMessage) (string, bool) {
+	name, args, ok := channel.ParseCommand(inbound.Content)
+	if !ok {
+		return "", false
+	}
+
+	switch name {
+	case channel.StopCommand:
+		if args != "" {
+			return "", false
+		}
+		if s.manager.CancelSession(inbound.SessionKey) > 0 {
+			return "Stopped.", true
+		}
+		return "Nothing to stop.", true
+	case "/help", "/start":
+		return s.manager.commandHelp(), true
+	case "/reset":
+		count, err := s.manager.ResetSession(ctx, inbound.Channel, inbound.SessionKey)
+		if err != nil {
+			s.log.Warn("Failed to reset session", "session_key", inbound.SessionKey, "error", err)
+			return "Reset failed: " + providertypes.UserMessage(err), true
+		}
+		if count > 1 {
+			return fmt.Sprintf("Started a new conversation for this chat and its %d agents.", count), true
+		}
+		return "Started a new conversation.", true
+	case "/model":
+		if args == "" {
+			return s.manager.describeChatAgent(inbound.Channel, inbound.SessionKey), true
+		}
+		return s.manager.SetChatAgent(inbound.SessionKey, args), true
+	case "/usage":
+		return s.manager.describeUsage(inbound.SessionKey), true
+	}
+
+	return "", false
+}
+
+// commandHelp lists the chat commands and, when configured, the named agents.
+func (m *runtimeManager) commandHelp() string {
+	lines := []string{"Commands:"}
+	for _, command := range channel.Commands {
+		lines = append(lines, command.Name+": "+command.Description)
+	}
+	help := strings.Join(lines, "\n")
+	if len(m.agents) > 0 {
+		help += "\n\n" + m.agentDirectory() + fmt.Sprintf("\n\nStart a message with %s<name> to ask one agent, or use /model <name> to switch this chat.", agentPrefix)
+	}
+
+	return help
+}
+
+// ResetSession starts a new provider conversation for the chat of sessionKey
+// and for each named agent used in it, so the next message has no history,
+// and returns how many conversations it reset. Running prompts are canceled
+// first.
+//
+// The chat's current agent is reset even without a live runtime, so a stored
+// conversation does not resume on the next message.
+func (m *runtimeManager) ResetSession(ctx context.Context, channelName string, sessionKey string) (int, error) {
+	m.CancelSession(sessionKey)
+
+	current := sessionKey
+	profile := namedAgent{model: m.cfg.Agents.Defaults.Model, system: m.system}
+	if route := m.routeInbound(channelName, sessionKey, ""); route.agent != "" {
+		profile, _ = m.lookupAgent(route.agent)
+		current = agentSessionKey(sessionKey, profile.name)
+	}
+	if _, err := m.runtimeForSession(ctx, current, profile); err != nil {
+		return 0, err
+	}
+
+	m.mu.RLock()
+	runtimes := make(map[string]*sessionRuntime)
+	for key, runtime := range m.runtimes {
+		if key == sessionKey || strings.HasPrefix(key, sessionKey+"@") {
+			runtimes[key] = runtime
+		}
+	}
+	m.mu.RUnlock()
+
+	for key, runtime := range runtimes {
+		if err := runtime.instance.Reset(ctx, "miniclaw:"+key); err != nil {
+			return 0, fmt.Errorf("reset %s: %w", key, err)
+		}
+		runtime.statsMu.Lock()
+		runtime.context = nil
+		runtime.statsMu.Unlock()
+		if m.sessionMap != nil {
+			_, agentName, _ := strings.Cut(key, "@")
+			saved := savedSession{SessionID: runtime.instance.SessionID(), Agent: agentName, UpdatedAt: time.Now().UTC()}
+			if err := m.sessionMap.save(key, saved); err != nil {
+				m.log.Warn("Failed to save session map", "session_key", key, "error", err)
+			}
+		}
+	}
+	m.log.Info("Reset session", "session_key", sessionKey, "conversations", len(runtimes))
+
+	return len(runtimes), nil
+}
+
+// SetChatAgent makes the named agent answer the chat of sessionKey in place
+// of its gateway.channel_agents entry until the gateway restarts. The name
+// "default" selects the default agent. It returns the reply for the chat.
+func (m *runtimeManager) SetChatAgent(sessionKey string, name string) string {
+	if len(m.agents) == 0 {
+		return fmt.Sprintf("This gateway has only the default agent (%s); configure agents.named to switch.", m.cfg.Agents.Defaults.Model)
+	}
+
+	agent, ok := m.lookupAgent(name)
+	if !ok && !strings.EqualFold(strings.TrimSpace(name), defaultAgentName) {
+		return fmt.Sprintf("Unknown agent %q.\n\n%s", name, m.agentDirectory())
+	}
+
+	m.mu.Lock()
+	if m.chatAgents == nil {
+		m.chatAgents = make(map[string]string)
+	}
+	m.chatAgents[sessionKey] = agent.name
+	m.mu.Unlock()
+
+	if agent.name == "" {
+		return fmt.Sprintf("This chat now uses the default agent (%s).", m.cfg.Agents.Defaults.Model)
+	}
+	return fmt.Sprintf("This chat now uses %s (%s).", agent.name, agent.model)
+}
+
+// describeChatAgent names the agent and model answering the chat of
+// sessionKey and lists the agents /model can switch to.
+func (m *runtimeManager) describeChatAgent(channelName string, sessionKey string) string {
+	current := fmt.Sprintf("This chat uses the default agent (%s).", m.cfg.Agents.Defaults.Model)
+	if route := m.routeInbound(channelName, sessionKey, ""); route.agent != "" {
+		agent, _ := m.lookupAgent(route.agent)
+		current = fmt.Sprintf("This chat uses %s (%s).", agent.name, agent.model)
+	}
+	if len(m.agents) == 0 {
+		return current
+	}
+
+	lines := []string{current, "", "Switch with /model <name>:", "- " + defaultAgentName + ": " + m.cfg.Agents.Defaults.Model}
+	for _, name := range m.AgentNames() {
+		agent := m.agents[name]
+		line := "- " + name + ": " + agent.model
+		if agent.description != "" {
+			line += ", " + agent.description
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// describeUsage reports the token usage of the chat of sessionKey and its
+// named agents: live counters since the gateway started and, with
+// gateway.usage enabled, the stored totals and cost.
+func (m *runtimeManager) describeUsage(sessionKey string) string {
+	var live sessionStats
+	for key, stats := range m.Snapshots() {
+		if key != sessionKey && !strings.HasPrefix(key, sessionKey+"@") {
+			continue
+		}
+		live.Turns += stats.Turns
+		live.Failures += stats.Failures
+		live.Usage.InputTokens += stats.Usage.InputTokens
+		live.Usage.OutputTokens += stats.Usage.OutputTokens
+		live.Usage.TotalTokens += stats.Usage.TotalTokens
+		live.ToolUsage.Calls += stats.ToolUsage.Calls
+		if key == sessionKey {
+			live.Context = stats.Context
+		}
+	}
+
+	lines := []string{
+		"Since the gateway started:",
+		fmt.Sprintf("Turns: %d (%d failed)", live.Turns, live.Failures),
+		fmt.Sprintf("Tokens: %d (%d in, %d out)", live.Usage.TotalTokens, live.Usage.InputTokens, live.Usage.OutputTokens),
+		fmt.Sprintf("Tool calls: %d", live.ToolUsage.Calls),
+	}
+	if live.Context != nil {
+		lines = append(lines, fmt.Sprintf("Context window: %d%% full", live.Context.Percent()))
+	}
+
+	if m.accounting != nil {
+		var total accounting.Row
+		keys := []string{sessionKey}
+		for _, name := range m.AgentNames() {
+			keys = append(keys, agentSessionKey(sessionKey, name))
+		}
+		for _, key := range keys {
+			report, err := m.accounting.Query(accounting.Filter{SessionKey: key})
+			if err != nil {
+				m.log.Warn("Failed to query usage", "session_key", key, "error", err)
+				return strings.Join(lines, "\n")
+			}
+			total.Turns += report.Total.Turns
+			total.TotalTokens += report.Total.TotalTokens
+			total.CostUSD += report.Total.CostUSD
+		}
+		lines = append(lines, "", fmt.Sprintf("All time: %d turns, %d tokens, $%.4f", total.Turns, total.TotalTokens, total.CostUSD))
+	}
+
+	return strings.Join(lines, "\n")
+}
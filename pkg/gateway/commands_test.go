@@ -0,0 +1,77 @@
+package gateway
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/config"
+)
+
+func TestChatCommands(t *testing.T) {
+	t.Parallel()
+
+	fakeClient := &fakeProviderClient{}
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5-nano"},
+			Named:    []config.NamedAgentConfig{{Name: "coder", Model: "openai/gpt-5.2", Description: "Writes code"}},
+		},
+	}
+	manager, err := newRuntimeManager(context.Background(), cfg, fakeClient, nil)
+	if err != nil {
+		t.Fatalf("newRuntimeManager error: %v", err)
+	}
+	t.Cleanup(manager.Close)
+	svc := &Service{manager: manager, log: slog.Default()}
+
+	inbound := func(content string) bus.OutboundMessage {
+		t.Helper()
+		outbound, err := svc.handleInbound(context.Background(), bus.InboundMessage{Channel: "telegram", ChatID: "100", SessionKey: "telegram:100", Content: content})
+		if err != nil {
+			t.Fatalf("handleInbound(%q) error: %v", content, err)
+		}
+		return outbound
+	}
+
+	if help := inbound("/help@miniclaw_bot"); !strings.Contains(help.Content, "/reset: Start a new conversation") || !strings.Contains(help.Content, "!coder: Writes code") {
+		t.Fatalf("unexpected /help reply: %q", help.Content)
+	}
+	if model := inbound("/model"); !strings.Contains(model.Content, "default agent (openai/gpt-5-nano)") || !strings.Contains(model.Content, "- coder: openai/gpt-5.2, Writes code") {
+		t.Fatalf("unexpected /model reply: %q", model.Content)
+	}
+	if unknown := inbound("/model chef"); !strings.Contains(unknown.Content, `Unknown agent "chef"`) {
+		t.Fatalf("unexpected /model chef reply: %q", unknown.Content)
+	}
+	if switched := inbound("/model Coder"); switched.Content != "This chat now uses coder (openai/gpt-5.2)." {
+		t.Fatalf("unexpected /model coder reply: %q", switched.Content)
+	}
+	if reply := inbound("hello"); reply.SessionKey != "telegram:100@coder" {
+		t.Fatalf("after /model coder, session key = %q, want telegram:100@coder", reply.SessionKey)
+	}
+	if usage := inbound("/usage"); !strings.Contains(usage.Content, "Turns: 1 (0 failed)") {
+		t.Fatalf("unexpected /usage reply: %q", usage.Content)
+	}
+
+	fakeClient.mu.Lock()
+	before := fakeClient.createSessionCount
+	fakeClient.mu.Unlock()
+	if reset := inbound("/reset"); reset.Content != "Started a new conversation." {
+		t.Fatalf("unexpected /reset reply: %q", reset.Content)
+	}
+	fakeClient.mu.Lock()
+	after := fakeClient.createSessionCount
+	fakeClient.mu.Unlock()
+	if after != before+1 {
+		t.Fatalf("createSessionCount = %d after /reset, want %d", after, before+1)
+	}
+
+	if back := inbound("/model default"); back.Content != "This chat now uses the default agent (openai/gpt-5-nano)." {
+		t.Fatalf("unexpected /model default reply: %q", back.Content)
+	}
+	if reply := inbound("/unknown thing"); reply.Content != "ok:/unknown thing" || reply.SessionKey != "telegram:100" {
+		t.Fatalf("unknown command should reach the default agent, got %+v", reply)
+	}
+}
@@ -0,0 +1,169 @@
+package gateway
+
+import (
+	_ "embed"
+	"html/template"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"miniclaw/pkg/bus"
+)
+
+const (
+	// statusHistorySize bounds the provider checks and errors the status page keeps.
+	statusHistorySize = 20
+	// statusPageSessions bounds the sessions listed on the status page, most recently active first.
+	statusPageSessions = 100
+)
+
+// statusPageSource is the HTML template of GET /status.
+//
+//go:embed dashboard.html
+var statusPageSource string
+
+// statusPageTemplate renders statusPage values.
+var statusPageTemplate = template.Must(template.New("status").Parse(statusPageSource))
+
+// providerCheck is one provider health check result.
+type providerCheck struct {
+	At    time.Time
+	Error string
+}
+
+// recentError is one failure shown on the status page.
+type recentError struct {
+	At         time.Time
+	Source     string
+	SessionKey string
+	TraceID    string
+	Error      string
+}
+
+// statusPage is the data behind the status page template.
+type statusPage struct {
+	Ready          bool
+	GeneratedAt    time.Time
+	StartedAt      time.Time
+	Uptime         time.Duration
+	ProviderLastOK time.Time
+	ProviderError  string
+	ProviderChecks []providerCheck
+	Channels       []statusPageChannel
+	Sessions       []statusPageSession
+	TotalSessions  int
+	RecentErrors   []recentError
+}
+
+// statusPageChannel is one channel row of the status page.
+type statusPageChannel struct {
+	Name string
+	channelState
+}
+
+// statusPageSession is one live session row of the status page.
+type statusPageSession struct {
+	Key string
+	sessionStats
+}
+
+// handleStatusPage renders uptime, provider health history, channel state,
+// live sessions, and recent errors as an HTML page for operators.
+func (s *Service) handleStatusPage(w http.ResponseWriter, _ *http.Request) {
+	page := s.statusPageData()
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if err := statusPageTemplate.Execute(w, page); err != nil {
+		s.log.Error("Failed to render status page", "error", err)
+	}
+}
+
+// statusPageData snapshots the service state for the status page, newest
+// entries first.
+func (s *Service) statusPageData() statusPage {
+	page := statusPage{Ready: s.isReady(), GeneratedAt: time.Now().UTC()}
+
+	s.mu.RLock()
+	page.StartedAt = s.startedAt
+	if !s.startedAt.IsZero() {
+		page.Uptime = time.Since(s.startedAt).Truncate(time.Second)
+	}
+	page.ProviderLastOK = s.providerLastOKAt
+	page.ProviderError = s.providerLastErr
+	page.ProviderChecks = slices.Clone(s.providerChecks)
+	page.RecentErrors = slices.Clone(s.recentErrors)
+	for name, state := range s.channelStates {
+		page.Channels = append(page.Channels, statusPageChannel{Name: name, channelState: state})
+	}
+	s.mu.RUnlock()
+
+	slices.Reverse(page.ProviderChecks)
+	slices.Reverse(page.RecentErrors)
+	slices.SortFunc(page.Channels, func(a, b statusPageChannel) int { return strings.Compare(a.Name, b.Name) })
+
+	for key, stats := range s.manager.Snapshots() {
+		page.Sessions = append(page.Sessions, statusPageSession{Key: key, sessionStats: stats})
+	}
+	page.TotalSessions = len(page.Sessions)
+	slices.SortFunc(page.Sessions, func(a, b statusPageSession) int {
+		if c := b.LastActivityAt.Compare(a.LastActivityAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Key, b.Key)
+	})
+	if len(page.Sessions) > statusPageSessions {
+		page.Sessions = page.Sessions[:statusPageSessions]
+	}
+
+	return page
+}
+
+// recordProviderCheckLocked appends one provider health check result; the
+// caller holds s.mu.
+func (s *Service) recordProviderCheckLocked(err error) {
+	s.providerChecks = appendBounded(s.providerChecks, providerCheck{At: time.Now().UTC(), Error: errorString(err)})
+}
+
+// recordRecentErrors keeps failed prompts, channel failures, and provider
+// outages from events for the status page until events closes.
+func (s *Service) recordRecentErrors(events <-chan bus.Event) {
+	for event := range events {
+		source := ""
+		switch event.Type {
+		case bus.EventPromptFailed:
+			source = "prompt"
+		case bus.EventToolFailed:
+			source = "tool"
+		case bus.EventChannelDisconnected:
+			source = "channel " + event.Channel
+		case bus.EventProviderDown:
+			source = "provider"
+		}
+		if source == "" || event.Error == "" {
+			continue
+		}
+
+		s.mu.Lock()
+		s.recentErrors = appendBounded(s.recentErrors, recentError{
+			At:         event.At.UTC(),
+			Source:     source,
+			SessionKey: event.SessionKey,
+			TraceID:    event.TraceID,
+			Error:      event.Error,
+		})
+		s.mu.Unlock()
+	}
+}
+
+// appendBounded appends item and drops the oldest entries beyond statusHistorySize.
+func appendBounded[T any](items []T, item T) []T {
+	items = append(items, item)
+	if len(items) > statusHistorySize {
+		items = slices.Delete(items, 0, len(items)-statusHistorySize)
+	}
+
+	return items
+}
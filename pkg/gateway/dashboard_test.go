@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/config"
+)
+
+func TestStatusPageRendersServiceState(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5-nano"}},
+	}
+	manager, err := newRuntimeManager(context.Background(), cfg, &fakeProviderClient{}, nil)
+	if err != nil {
+		t.Fatalf("newRuntimeManager error: %v", err)
+	}
+	t.Cleanup(manager.Close)
+	if _, err := manager.Prompt(context.Background(), "telegram:100", "hello"); err != nil {
+		t.Fatalf("Prompt error: %v", err)
+	}
+
+	svc := &Service{
+		cfg:           cfg,
+		log:           slog.Default(),
+		manager:       manager,
+		startedAt:     time.Now().Add(-time.Hour).UTC(),
+		channelStates: map[string]channelState{"telegram": {Running: true, HealthError: "telegram getMe: timeout"}},
+	}
+	svc.mu.Lock()
+	svc.recordProviderCheckLocked(errors.New("provider <down>"))
+	svc.mu.Unlock()
+
+	events := make(chan bus.Event, 2)
+	events <- bus.Event{Type: bus.EventPromptFailed, At: time.Now(), SessionKey: "telegram:100", TraceID: "abc123", Error: "rate limited"}
+	events <- bus.Event{Type: bus.EventPromptCompleted, At: time.Now(), SessionKey: "telegram:100"}
+	close(events)
+	svc.recordRecentErrors(events)
+
+	recorder := httptest.NewRecorder()
+	svc.statusHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", recorder.Code)
+	}
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/html") {
+		t.Fatalf("Content-Type = %q, want text/html", contentType)
+	}
+
+	body := recorder.Body.String()
+	for _, want := range []string{"not ready", "Up 1h0m0s", "provider &lt;down&gt;", "unhealthy", "telegram getMe: timeout", "telegram:100", "rate limited", "abc123"} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("status page is missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "provider <down>") {
+		t.Fatal("status page must escape error text")
+	}
+}
+
+func TestAppendBoundedKeepsNewest(t *testing.T) {
+	t.Parallel()
+
+	var items []int
+	for i := range statusHistorySize + 5 {
+		items = appendBounded(items, i)
+	}
+	if len(items) != statusHistorySize || items[0] != 5 || items[len(items)-1] != statusHistorySize+4 {
+		t.Fatalf("items = %v, want the newest %d", items, statusHistorySize)
+	}
+}
@@ -0,0 +1,118 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"miniclaw/pkg/bus"
+)
+
+const (
+	// eventStreamBuffer is how many events a slow /events client may fall
+	// behind before the bus drops events for it.
+	eventStreamBuffer = 256
+	// eventStreamKeepalive spaces the comments that keep idle streams open
+	// through proxies.
+	eventStreamKeepalive = 15 * time.Second
+)
+
+// handleReplayEvents writes the recorded gateway events between the optional
+// RFC 3339 "from" and "to" query times as JSON Lines, oldest first. It answers
+// 404 when bus.event_log is off.
+func (s *Service) handleReplayEvents(w http.ResponseWriter, r *http.Request) {
+	var bounds [2]time.Time
+	for index, name := range []string{"from", "to"} {
+		raw := r.URL.Query().Get(name)
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			s.respondJSON(w, http.StatusBadRequest, errorResponse{Error: name + " must be an RFC 3339 time"})
+			return
+		}
+		bounds[index] = parsed
+	}
+
+	events, err := s.ReplayEvents(r.Context(), bounds[0], bounds[1], 0)
+	if errors.Is(err, bus.ErrNoEventLog) {
+		s.respondJSON(w, http.StatusNotFound, errorResponse{Error: "bus.event_log.path is not set"})
+		return
+	}
+	if err != nil {
+		s.respondJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+	for event := range events {
+		if err := encoder.Encode(event); err != nil {
+			// The request context ends when the handler returns, which stops
+			// the replay.
+			s.log.Error("Failed to write event replay", "error", err)
+			return
+		}
+	}
+}
+
+// handleStreamEvents streams live gateway events as server-sent events until
+// the client disconnects. The optional comma-separated "type" query parameter
+// limits the stream to those event types and "session_key" to one session.
+func (s *Service) handleStreamEvents(w http.ResponseWriter, r *http.Request) {
+	types := map[bus.EventType]bool{}
+	for name := range strings.SplitSeq(r.URL.Query().Get("type"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			types[bus.EventType(name)] = true
+		}
+	}
+	sessionKey := strings.TrimSpace(r.URL.Query().Get("session_key"))
+
+	events, unsubscribe := s.SubscribeEvents(r.Context(), eventStreamBuffer)
+	defer unsubscribe()
+
+	controller := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := controller.Flush(); err != nil {
+		s.log.Error("Failed to start event stream", "error", err)
+		return
+	}
+
+	keepalive := time.NewTicker(eventStreamKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				// The service stopped.
+				return
+			}
+			if (len(types) > 0 && !types[event.Type]) || (sessionKey != "" && event.SessionKey != sessionKey) {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				s.log.Error("Failed to encode event", "type", event.Type, "error", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/config"
+)
+
+func TestEventStreamSendsFilteredPromptLifecycle(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Agents:  config.AgentsConfig{Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5-nano"}},
+		Gateway: config.GatewayConfig{Auth: config.GatewayAuthConfig{Token: "secret"}},
+	}
+	manager, err := newRuntimeManager(context.Background(), cfg, &fakeProviderClient{}, nil)
+	if err != nil {
+		t.Fatalf("newRuntimeManager error: %v", err)
+	}
+	t.Cleanup(manager.Close)
+	server := httptest.NewServer((&Service{cfg: cfg, log: slog.Default(), manager: manager}).statusHandler())
+	t.Cleanup(server.Close)
+
+	response, err := http.Get(server.URL + "/events")
+	if err != nil {
+		t.Fatalf("GET /events: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("unauthenticated status = %d, want 401", response.StatusCode)
+	}
+
+	request, _ := http.NewRequest(http.MethodGet, server.URL+"/admin/events", nil)
+	request.Header.Set("Authorization", "Bearer secret")
+	response, err = http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("GET /admin/events: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusNotFound {
+		t.Fatalf("replay without an event log = %d, want 404", response.StatusCode)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	request, _ = http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/events?type=prompt_received,prompt_completed&session_key=telegram:100", nil)
+	request.Header.Set("Authorization", "Bearer secret")
+	response, err = http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("GET /events: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK || response.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("stream = %d %q", response.StatusCode, response.Header.Get("Content-Type"))
+	}
+
+	// Only the second prompt matches the session filter.
+	if _, err := manager.Prompt(context.Background(), "telegram:200", "ignored"); err != nil {
+		t.Fatalf("Prompt error: %v", err)
+	}
+	if _, err := manager.Prompt(context.Background(), "telegram:100", "hello"); err != nil {
+		t.Fatalf("Prompt error: %v", err)
+	}
+
+	reader := bufio.NewReader(response.Body)
+	var got []bus.Event
+	for len(got) < 2 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream: %v (events so far %+v)", err, got)
+		}
+		data, ok := strings.CutPrefix(strings.TrimSpace(line), "data: ")
+		if !ok {
+			continue
+		}
+		var event bus.Event
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			t.Fatalf("decode %q: %v", data, err)
+		}
+		got = append(got, event)
+	}
+
+	if got[0].Type != bus.EventPromptReceived || got[1].Type != bus.EventPromptCompleted {
+		t.Fatalf("events = %+v, want prompt_received then prompt_completed", got)
+	}
+	for _, event := range got {
+		if event.SessionKey != "telegram:100" || event.RequestID == "" {
+			t.Fatalf("event = %+v, want session telegram:100 with a request id", event)
+		}
+	}
+	if received, ok := got[0].Payload.(bus.PromptReceivedPayload); !ok || received.PromptLength != len("hello") {
+		t.Fatalf("received payload = %#v", got[0].Payload)
+	}
+	if completed, ok := got[1].Payload.(bus.PromptCompletedPayload); !ok || completed.ResponseLength != len("ok:hello") {
+		t.Fatalf("completed payload = %#v", got[1].Payload)
+	}
+}
@@ -0,0 +1,111 @@
+package gateway
+
+import (
+	"context"
+
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/channel"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+// journalInbound keeps each channel message in the bus journal until it is
+// answered or fails for a reason a retry would not fix, so a message that was
+// waiting out a provider outage when the process died is answered on the next
+// start. A message whose dedup_key the journal has seen gets an empty reply.
+func (s *Service) journalInbound(next channel.Handler) channel.Handler {
+	if s.journal == nil {
+		return next
+	}
+
+	return func(ctx context.Context, inbound bus.InboundMessage) (bus.OutboundMessage, error) {
+		// A message replayed from the journal is already in it.
+		deliveryID := inbound.Metadata[bus.MetadataDeliveryID]
+		if deliveryID == "" {
+			id, err := s.journal.Append(ctx, inbound)
+			switch {
+			case err != nil:
+				s.log.Warn("Failed to journal channel message", "channel", inbound.Channel, "session_key", inbound.SessionKey, "error", err)
+				return next(ctx, inbound)
+			case id == "":
+				s.log.Info("Dropped duplicate channel message", "channel", inbound.Channel, "session_key", inbound.SessionKey, "dedup_key", inbound.Metadata[bus.MetadataDedupKey])
+				return bus.OutboundMessage{Channel: inbound.Channel, ChatID: inbound.ChatID, SessionKey: inbound.SessionKey}, nil
+			}
+			deliveryID = id
+		}
+
+		outbound, err := next(ctx, inbound)
+		if retryLater(ctx, err) {
+			s.log.Warn("Kept channel message for the next start", "channel", inbound.Channel, "session_key", inbound.SessionKey, "error", err)
+			return outbound, err
+		}
+		if ackErr := s.journal.Ack(context.WithoutCancel(ctx), deliveryID); ackErr != nil {
+			s.log.Warn("Failed to mark channel message handled", "channel", inbound.Channel, "session_key", inbound.SessionKey, "error", ackErr)
+		}
+
+		return outbound, err
+	}
+}
+
+// retryLater reports whether a channel message that failed with err should
+// stay in the journal: the gateway stopped while answering it, or the
+// provider was down, throttling, or paused by the circuit breaker.
+func retryLater(ctx context.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+	if ctx.Err() != nil {
+		return true
+	}
+	switch providertypes.ErrorCategoryOf(err) {
+	case providertypes.ErrorProviderDown, providertypes.ErrorRateLimit, providertypes.ErrorCircuitOpen:
+		return true
+	default:
+		return false
+	}
+}
+
+// replayJournal answers the channel messages an earlier run journaled and
+// never answered, oldest first, sending each reply with Notify. A message
+// whose channel cannot send unsolicited messages, or is not running, has
+// nobody to reply to and is dropped without prompting the agent. One that
+// fails again for a reason worth retrying stays for the next start.
+func (s *Service) replayJournal(ctx context.Context) {
+	pending, err := s.journal.Pending(ctx)
+	if err != nil {
+		s.log.Error("Failed to load journaled channel messages", "error", err)
+		return
+	}
+	if len(pending) > 0 {
+		s.log.Info("Answering journaled channel messages", "messages", len(pending))
+	}
+
+	for _, inbound := range pending {
+		if ctx.Err() != nil {
+			return
+		}
+
+		s.mu.RLock()
+		_, canNotify := s.notifiers[inbound.Channel]
+		running := s.channelStates[inbound.Channel].Running
+		s.mu.RUnlock()
+		if !canNotify || !running {
+			s.log.Warn("Dropped journaled channel message with nowhere to reply", "channel", inbound.Channel, "session_key", inbound.SessionKey)
+			if err := s.journal.Ack(ctx, inbound.Metadata[bus.MetadataDeliveryID]); err != nil {
+				s.log.Warn("Failed to mark channel message handled", "channel", inbound.Channel, "session_key", inbound.SessionKey, "error", err)
+			}
+			continue
+		}
+
+		outbound, err := s.dispatchInbound(ctx, inbound)
+		if retryLater(ctx, err) {
+			continue
+		}
+		if err != nil {
+			outbound.Error = providertypes.UserMessage(err)
+		}
+		outbound.Channel, outbound.ChatID = inbound.Channel, inbound.ChatID
+		if err := s.Notify(ctx, outbound); err != nil {
+			s.log.Warn("Failed to send journaled channel message reply", "channel", inbound.Channel, "session_key", inbound.SessionKey, "error", err)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/channel"
+	"miniclaw/pkg/config"
+	"miniclaw/pkg/provider"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+// downProviderClient fails every prompt as if the provider were unreachable.
+type downProviderClient struct {
+	fakeProviderClient
+}
+
+func (f *downProviderClient) Prompt(context.Context, string, string, string, string, string) (providertypes.PromptResult, error) {
+	return providertypes.PromptResult{}, providertypes.ClassifyError("openai", 503, errors.New("service unavailable"))
+}
+
+func TestJournalKeepsMessagesTheProviderFailedUntilTheNextStart(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5-nano"}},
+		Bus:    config.BusConfig{Backend: "sqlite", Path: filepath.Join(t.TempDir(), "bus.db")},
+	}
+	newJournaledService := func(client provider.Client) *Service {
+		t.Helper()
+		journal, err := bus.OpenJournal(cfg.Bus)
+		if err != nil {
+			t.Fatalf("OpenJournal error: %v", err)
+		}
+		t.Cleanup(func() { _ = journal.Close() })
+		manager, err := newRuntimeManager(ctx, cfg, client, nil)
+		if err != nil {
+			t.Fatalf("newRuntimeManager error: %v", err)
+		}
+		t.Cleanup(manager.Close)
+		return &Service{manager: manager, log: slog.Default(), journal: journal}
+	}
+
+	// The provider is down: the message fails and stays journaled.
+	svc := newJournaledService(&downProviderClient{})
+	inbound := bus.InboundMessage{Channel: "telegram", ChatID: "100", SessionKey: "telegram:100", Content: "are you there?"}
+	if _, err := svc.dispatchInbound(ctx, inbound); providertypes.ErrorCategoryOf(err) != providertypes.ErrorProviderDown {
+		t.Fatalf("dispatchInbound error = %v, want provider_down", err)
+	}
+
+	// After a restart the message is still pending and is answered through
+	// its channel; one from a channel that cannot be notified is dropped
+	// without prompting the agent.
+	client := &fakeProviderClient{}
+	svc = newJournaledService(client)
+	pending, err := svc.journal.Pending(ctx)
+	if err != nil || len(pending) != 1 || pending[0].Content != "are you there?" {
+		t.Fatalf("pending after restart = %+v, %v; want the failed message", pending, err)
+	}
+	if _, err := svc.journal.Append(ctx, bus.InboundMessage{Channel: "http", ChatID: "ops", SessionKey: "http:ops", Content: "nobody waits for this"}); err != nil {
+		t.Fatalf("Append error: %v", err)
+	}
+
+	telegram := &notifyingAdapter{started: make(chan struct{})}
+	svc.notifiers = map[string]channel.Notifier{"telegram": telegram}
+	svc.channelStates = map[string]channelState{"telegram": {Running: true}, "http": {Running: true}}
+	svc.replayJournal(ctx)
+
+	if len(telegram.sent) != 1 || telegram.sent[0].ChatID != "100" || telegram.sent[0].Content != "ok:are you there?" {
+		t.Fatalf("sent = %+v, want the reply in chat 100", telegram.sent)
+	}
+	if len(client.prompts) != 1 {
+		t.Fatalf("prompts = %q, want only the telegram message answered", client.prompts)
+	}
+	if pending, err := svc.journal.Pending(ctx); err != nil || len(pending) != 0 {
+		t.Fatalf("pending after replay = %+v, %v; want none", pending, err)
+	}
+}
@@ -0,0 +1,121 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"miniclaw/pkg/accounting"
+	"miniclaw/pkg/config"
+	"miniclaw/pkg/cron"
+	"miniclaw/pkg/retrieval"
+	fstools "miniclaw/pkg/tools/fs"
+	"miniclaw/pkg/workspace"
+)
+
+const (
+	// defaultSnapshotMaxAge is how long snapshot_prune keeps file snapshots
+	// when max_age_days is unset.
+	defaultSnapshotMaxAge = 30 * 24 * time.Hour
+	// defaultUsageCompactAge is how long usage_compact keeps per-session usage
+	// rows when max_age_days is unset.
+	defaultUsageCompactAge = 30 * 24 * time.Hour
+)
+
+// maintenanceMaxAge returns the job's max_age_days as a duration, or fallback
+// when it is unset.
+func maintenanceMaxAge(cfg config.MaintenanceJobConfig, fallback time.Duration) time.Duration {
+	if cfg.MaxAgeDays > 0 {
+		return time.Duration(cfg.MaxAgeDays) * 24 * time.Hour
+	}
+
+	return fallback
+}
+
+// snapshotPruneTask backs the snapshot_prune maintenance task, dropping the
+// file snapshots recorded before max_age_days in the agents' workspace and,
+// with gateway.session_workspaces enabled, in every session workspace.
+func snapshotPruneTask(cfg *config.Config, now func() time.Time) cron.MaintenanceTask {
+	return func(ctx context.Context, jobCfg config.MaintenanceJobConfig) (string, error) {
+		cutoff := now().Add(-maintenanceMaxAge(jobCfg, defaultSnapshotMaxAge))
+		defaults := cfg.Agents.Defaults
+		root, err := workspace.ResolveRoot(defaults.Workspace)
+		if err != nil {
+			return "", fmt.Errorf("resolve workspace: %w", err)
+		}
+		roots := []string{root}
+		if cfg.Gateway.SessionWorkspaces.Enabled {
+			entries, err := os.ReadDir(filepath.Join(root, sessionWorkspacesDir))
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return "", fmt.Errorf("list session workspaces: %w", err)
+			}
+			for _, entry := range entries {
+				if entry.IsDir() {
+					roots = append(roots, filepath.Join(root, sessionWorkspacesDir, entry.Name()))
+				}
+			}
+		}
+
+		pruned, total := 0, 0
+		for _, dir := range roots {
+			if err := ctx.Err(); err != nil {
+				return "", err
+			}
+			guard, err := workspace.NewGuardWithOptions(dir, workspace.GuardOptions{
+				RestrictToWorkspace: defaults.RestrictToWorkspace,
+				AllowedPaths:        defaults.AllowedPaths,
+				SymlinkPolicy:       defaults.SymlinkPolicy,
+			})
+			if err != nil {
+				return "", fmt.Errorf("open workspace %s: %w", dir, err)
+			}
+			dropped, recorded, err := fstools.OpenSnapshots(guard).Prune(cutoff)
+			if err != nil {
+				return "", fmt.Errorf("prune snapshots in %s: %w", dir, err)
+			}
+			pruned += dropped
+			total += recorded
+		}
+
+		return fmt.Sprintf("pruned %d of %d snapshotted changes in %d workspaces recorded before %s", pruned, total, len(roots), cutoff.UTC().Format(time.RFC3339)), nil
+	}
+}
+
+// retrievalReindexTask backs the retrieval_reindex maintenance task, bringing
+// the retrieval index up to date with the workspace so the next prompt does
+// not wait for the embeddings of files changed since the last one. It fails
+// each run while agents.defaults.retrieval is off.
+func retrievalReindexTask(index *retrieval.Index) cron.MaintenanceTask {
+	return func(ctx context.Context, _ config.MaintenanceJobConfig) (string, error) {
+		if index == nil {
+			return "", errors.New("retrieval is disabled; set agents.defaults.retrieval.enabled")
+		}
+		stats, err := index.Sync(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("indexed %d files in %d chunks; embedded %d changed files, removed %d", stats.Files, stats.Chunks, stats.Updated, stats.Removed), nil
+	}
+}
+
+// usageCompactTask backs the usage_compact maintenance task, merging the
+// gateway.usage rows of days before max_age_days into one row per day and
+// channel. It fails each run while gateway.usage is off.
+func usageCompactTask(store *accounting.Store, now func() time.Time) cron.MaintenanceTask {
+	return func(_ context.Context, jobCfg config.MaintenanceJobConfig) (string, error) {
+		if store == nil {
+			return "", errors.New("usage store is disabled; set gateway.usage.enabled")
+		}
+		cutoff := now().Add(-maintenanceMaxAge(jobCfg, defaultUsageCompactAge))
+		before, after, err := store.Compact(cutoff)
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("compacted usage rows of days before %s: %d rows, now %d", cutoff.UTC().Format(time.DateOnly), before, after), nil
+	}
+}
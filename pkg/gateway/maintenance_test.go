@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"miniclaw/pkg/accounting"
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+	fstools "miniclaw/pkg/tools/fs"
+	"miniclaw/pkg/workspace"
+)
+
+func TestSnapshotPruneCoversSessionWorkspaces(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	cfg := &config.Config{
+		Agents:  config.AgentsConfig{Defaults: config.AgentDefaults{Workspace: root, RestrictToWorkspace: true}},
+		Gateway: config.GatewayConfig{SessionWorkspaces: config.GatewaySessionWorkspacesConfig{Enabled: true}},
+	}
+	for _, dir := range []string{root, filepath.Join(root, sessionWorkspacesDir, "telegram-100")} {
+		guard, err := workspace.NewGuardWithOptions(dir, workspace.GuardOptions{RestrictToWorkspace: true})
+		if err != nil {
+			t.Fatalf("NewGuardWithOptions error: %v", err)
+		}
+		path := filepath.Join(dir, "notes.txt")
+		if err := os.WriteFile(path, []byte("draft"), 0o644); err != nil {
+			t.Fatalf("WriteFile error: %v", err)
+		}
+		if err := fstools.OpenSnapshots(guard).Record("write_file", path); err != nil {
+			t.Fatalf("Record error: %v", err)
+		}
+	}
+
+	now := time.Now()
+	task := snapshotPruneTask(cfg, func() time.Time { return now })
+	summary, err := task(context.Background(), config.MaintenanceJobConfig{MaxAgeDays: 1})
+	if err != nil || !strings.HasPrefix(summary, "pruned 0 of 2 snapshotted changes in 2 workspaces") {
+		t.Fatalf("task = %q, %v; want nothing pruned yet", summary, err)
+	}
+
+	now = now.Add(48 * time.Hour)
+	summary, err = task(context.Background(), config.MaintenanceJobConfig{MaxAgeDays: 1})
+	if err != nil || !strings.HasPrefix(summary, "pruned 2 of 2 snapshotted changes in 2 workspaces") {
+		t.Fatalf("task = %q, %v; want both changes pruned", summary, err)
+	}
+}
+
+func TestUsageCompactAndReindexNeedTheirStores(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 16, 6, 0, 0, 0, time.UTC)
+	if _, err := usageCompactTask(nil, time.Now)(context.Background(), config.MaintenanceJobConfig{}); err == nil || !strings.Contains(err.Error(), "gateway.usage.enabled") {
+		t.Fatalf("usage_compact without a store error = %v", err)
+	}
+	if _, err := retrievalReindexTask(nil)(context.Background(), config.MaintenanceJobConfig{}); err == nil || !strings.Contains(err.Error(), "agents.defaults.retrieval.enabled") {
+		t.Fatalf("retrieval_reindex without an index error = %v", err)
+	}
+
+	store, err := accounting.Open(config.GatewayUsageConfig{Enabled: true, Path: filepath.Join(t.TempDir(), "usage.json")}, accounting.Prices{})
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	usage := providertypes.TokenUsage{InputTokens: 10, TotalTokens: 10}
+	for _, sessionKey := range []string{"telegram:100", "telegram:200"} {
+		if err := store.Record(sessionKey, usage, now.AddDate(0, 0, -40)); err != nil {
+			t.Fatalf("Record error: %v", err)
+		}
+	}
+	summary, err := usageCompactTask(store, func() time.Time { return now })(context.Background(), config.MaintenanceJobConfig{})
+	if err != nil || !strings.HasSuffix(summary, "2 rows, now 1") {
+		t.Fatalf("usage_compact = %q, %v; want the 40-day-old rows merged", summary, err)
+	}
+}
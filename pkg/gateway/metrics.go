@@ -0,0 +1,151 @@
+package gateway
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	providertypes "miniclaw/pkg/provider/types"
+	"miniclaw/pkg/telemetry"
+)
+
+// Gateway metrics exported through telemetry.
+const (
+	metricTurns           = "miniclaw_turns_total"
+	metricTurnStage       = "miniclaw_turn_stage_seconds"
+	metricTokens          = "miniclaw_tokens_total"
+	metricToolCalls       = "miniclaw_tool_calls_total"
+	metricActiveSessions  = "miniclaw_active_sessions"
+	metricChannelMessages = "miniclaw_channel_messages_total"
+	metricChannelSeconds  = "miniclaw_channel_message_seconds"
+)
+
+// turnMetrics aggregates per-turn timing across all gateway sessions.
+type turnMetrics struct {
+	mu       sync.Mutex
+	turns    int
+	failures int
+	sum      providertypes.TurnTiming
+	max      providertypes.TurnTiming
+	last     providertypes.TurnTiming
+}
+
+// metricsResponse is the JSON payload returned by /v1/metrics.
+type metricsResponse struct {
+	Turns    int                   `json:"turns"`
+	Failures int                   `json:"failures"`
+	TimingMs timingMetricsResponse `json:"timing_ms"`
+}
+
+// timingMetricsResponse reports each turn stage in milliseconds.
+type timingMetricsResponse struct {
+	QueueWait stageMetrics `json:"queue_wait"`
+	Provider  stageMetrics `json:"provider"`
+	Tools     stageMetrics `json:"tools"`
+	Total     stageMetrics `json:"total"`
+}
+
+// stageMetrics summarizes one stage over all successful turns.
+type stageMetrics struct {
+	Last int64 `json:"last"`
+	Avg  int64 `json:"avg"`
+	Max  int64 `json:"max"`
+}
+
+// record adds one prompt attempt; failed attempts only bump the failure count.
+func (t *turnMetrics) record(timing *providertypes.TurnTiming, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err != nil {
+		t.failures++
+		return
+	}
+	if timing == nil {
+		timing = &providertypes.TurnTiming{}
+	}
+
+	t.turns++
+	t.last = *timing
+	t.sum.QueueWait += timing.QueueWait
+	t.sum.Provider += timing.Provider
+	t.sum.Tools += timing.Tools
+	t.sum.Total += timing.Total
+	t.max.QueueWait = max(t.max.QueueWait, timing.QueueWait)
+	t.max.Provider = max(t.max.Provider, timing.Provider)
+	t.max.Tools = max(t.max.Tools, timing.Tools)
+	t.max.Total = max(t.max.Total, timing.Total)
+}
+
+// snapshot converts the running totals into the public metrics payload.
+func (t *turnMetrics) snapshot() metricsResponse {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stage := func(last, sum, peak time.Duration) stageMetrics {
+		metrics := stageMetrics{Last: last.Milliseconds(), Max: peak.Milliseconds()}
+		if t.turns > 0 {
+			metrics.Avg = (sum / time.Duration(t.turns)).Milliseconds()
+		}
+		return metrics
+	}
+
+	return metricsResponse{
+		Turns:    t.turns,
+		Failures: t.failures,
+		TimingMs: timingMetricsResponse{
+			QueueWait: stage(t.last.QueueWait, t.sum.QueueWait, t.max.QueueWait),
+			Provider:  stage(t.last.Provider, t.sum.Provider, t.max.Provider),
+			Tools:     stage(t.last.Tools, t.sum.Tools, t.max.Tools),
+			Total:     stage(t.last.Total, t.sum.Total, t.max.Total),
+		},
+	}
+}
+
+// newGatewayRegistry returns a telemetry registry with the gateway metrics described.
+func newGatewayRegistry() *telemetry.Registry {
+	registry := telemetry.NewRegistry()
+	registry.Describe(metricTurns, "Prompt attempts by outcome (ok or error).")
+	registry.Describe(metricTurnStage, "Time spent in each stage of successful turns.")
+	registry.Describe(metricTokens, "Tokens used by successful turns, by provider and direction.")
+	registry.Describe(metricToolCalls, "Tool calls made by successful turns.")
+	registry.Describe(metricActiveSessions, "Session runtimes the gateway currently holds.")
+	registry.Describe(metricChannelMessages, "Messages channels handed the gateway, by channel and outcome (ok, error, rejected, or rate_limited).")
+	registry.Describe(metricChannelSeconds, "Time from a channel handing the gateway a message to its reply, by channel.")
+	registry.Set(metricActiveSessions, 0)
+
+	return registry
+}
+
+// recordTurnTelemetry reports one prompt attempt to registry.
+func recordTurnTelemetry(registry *telemetry.Registry, result providertypes.PromptResult, err error) {
+	if err != nil {
+		registry.Add(metricTurns, 1, telemetry.L("outcome", "error"))
+		return
+	}
+	registry.Add(metricTurns, 1, telemetry.L("outcome", "ok"))
+
+	if timing := result.Metadata.Timing; timing != nil {
+		for stage, duration := range map[string]time.Duration{
+			"queue_wait": timing.QueueWait,
+			"provider":   timing.Provider,
+			"tools":      timing.Tools,
+			"total":      timing.Total,
+		} {
+			registry.Observe(metricTurnStage, duration.Seconds(), telemetry.L("stage", stage))
+		}
+	}
+	if usage := result.Metadata.Usage; usage != nil {
+		provider := result.Metadata.Provider
+		registry.Add(metricTokens, float64(usage.InputTokens), telemetry.L("provider", provider), telemetry.L("direction", "input"))
+		registry.Add(metricTokens, float64(usage.OutputTokens), telemetry.L("provider", provider), telemetry.L("direction", "output"))
+	}
+	if toolUsage := result.Metadata.ToolUsage; toolUsage != nil {
+		registry.Add(metricToolCalls, float64(toolUsage.Calls))
+	}
+}
+
+// handleMetrics reports aggregate turn timing across all sessions.
+func (s *Service) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	s.respondJSON(w, http.StatusOK, s.manager.metrics.snapshot())
+}
@@ -0,0 +1,60 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/channel"
+)
+
+// Notify delivers a message nobody asked for, such as a cron result or a
+// watcher alert, to the chat outbound.ChatID of the running adapter named by
+// outbound.Channel. It fails when that channel is not running, including while
+// it waits to be restarted, or cannot push messages; see channel.Notifier.
+func (s *Service) Notify(ctx context.Context, outbound bus.OutboundMessage) error {
+	name := strings.TrimSpace(outbound.Channel)
+	if name == "" {
+		return errors.New("notify: channel is required")
+	}
+	if strings.TrimSpace(outbound.ChatID) == "" {
+		return fmt.Errorf("notify %s: chat ID is required", name)
+	}
+
+	s.mu.RLock()
+	notifier, ok := s.notifiers[name]
+	running := s.channelStates[name].Running
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("notify %s: channel is not enabled or cannot send unsolicited messages", name)
+	}
+	if !running {
+		return fmt.Errorf("notify %s: channel is not running", name)
+	}
+
+	if err := notifier.Notify(ctx, outbound); err != nil {
+		return fmt.Errorf("notify %s: %w", name, err)
+	}
+	s.log.Info("Sent notification", "channel", name, "chat_id", outbound.ChatID)
+
+	return nil
+}
+
+// setNotifier records the adapter Notify routes its channel's messages to,
+// or forgets the channel when adapter is nil or cannot push messages.
+func (s *Service) setNotifier(name string, adapter channel.Adapter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notifier, ok := adapter.(channel.Notifier)
+	if !ok {
+		delete(s.notifiers, name)
+		return
+	}
+	if s.notifiers == nil {
+		s.notifiers = make(map[string]channel.Notifier)
+	}
+	s.notifiers[name] = notifier
+}
@@ -0,0 +1,86 @@
+package gateway
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/channel"
+)
+
+// notifyingAdapter runs until canceled and records the messages pushed to it.
+type notifyingAdapter struct {
+	started chan struct{}
+
+	mu   sync.Mutex
+	sent []bus.OutboundMessage
+}
+
+func (a *notifyingAdapter) Name() string {
+	return "telegram"
+}
+
+func (a *notifyingAdapter) Run(ctx context.Context, _ channel.Handler) error {
+	close(a.started)
+	<-ctx.Done()
+	return nil
+}
+
+func (a *notifyingAdapter) Notify(_ context.Context, outbound bus.OutboundMessage) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.sent = append(a.sent, outbound)
+	return nil
+}
+
+func TestServiceNotifyRoutesToRunningAdapter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	telegram := &notifyingAdapter{started: make(chan struct{})}
+	svc := newRestartTestService(t, ctx, telegram, channelRestartPolicy{})
+	if err := svc.Notify(ctx, bus.OutboundMessage{Channel: "telegram", ChatID: "42", Content: "hi"}); err == nil {
+		t.Fatal("expected an error before the channel runs")
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- svc.Run(ctx) }()
+	waitClosed(t, telegram.started)
+
+	if err := svc.Notify(ctx, bus.OutboundMessage{Channel: "telegram", ChatID: "42", Content: "backup finished"}); err != nil {
+		t.Fatalf("Notify error: %v", err)
+	}
+	telegram.mu.Lock()
+	sent := telegram.sent
+	telegram.mu.Unlock()
+	if len(sent) != 1 || sent[0].ChatID != "42" || sent[0].Content != "backup finished" {
+		t.Fatalf("sent = %+v, want one message to chat 42", sent)
+	}
+
+	for _, tc := range []struct {
+		outbound bus.OutboundMessage
+		want     string
+	}{
+		{bus.OutboundMessage{ChatID: "42"}, "channel is required"},
+		{bus.OutboundMessage{Channel: "telegram"}, "chat ID is required"},
+		{bus.OutboundMessage{Channel: "http", ChatID: "42"}, "cannot send unsolicited messages"},
+	} {
+		if err := svc.Notify(ctx, tc.outbound); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("Notify(%+v) error = %v, want %q", tc.outbound, err, tc.want)
+		}
+	}
+
+	cancel()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("Run error: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for service run to exit")
+	}
+}
@@ -0,0 +1,191 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"miniclaw/pkg/config"
+	"miniclaw/pkg/store"
+	"miniclaw/pkg/workspace"
+)
+
+const defaultSessionMapPath = "~/.miniclaw/gateway-sessions.json"
+
+// sessionMapVersion is the format version written to the session map file.
+// Loading migrates older versions forward and refuses newer ones, so an
+// older gateway never overwrites a map it cannot read.
+const sessionMapVersion = 1
+
+// sessionMapFile is the on-disk format of the session map.
+type sessionMapFile struct {
+	Version  int                     `json:"version"`
+	Sessions map[string]savedSession `json:"sessions"`
+}
+
+// savedSession is the provider session one session key continues.
+type savedSession struct {
+	SessionID string    `json:"session_id"`
+	Agent     string    `json:"agent,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// sessionMap persists which provider session each gateway session key uses,
+// for providers that cannot look their sessions up by title after a restart.
+type sessionMap struct {
+	path string
+
+	mu       sync.Mutex
+	sessions map[string]savedSession
+}
+
+// openSessionMap loads the gateway.session_persistence map, or returns nil
+// when persistence is off. A missing file starts an empty map.
+func openSessionMap(cfg config.GatewaySessionPersistenceConfig) (*sessionMap, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	path, err := sessionMapPath(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := &sessionMap{path: path, sessions: map[string]savedSession{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return sessions, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read session map: %w", err)
+	}
+
+	var file sessionMapFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("decode session map %s: %w", path, err)
+	}
+	if err := migrateSessionMap(&file); err != nil {
+		return nil, fmt.Errorf("session map %s: %w", path, err)
+	}
+	for key, saved := range file.Sessions {
+		if strings.TrimSpace(saved.SessionID) != "" {
+			sessions.sessions[key] = saved
+		}
+	}
+
+	return sessions, nil
+}
+
+// migrateSessionMap brings file up to sessionMapVersion.
+func migrateSessionMap(file *sessionMapFile) error {
+	switch {
+	case file.Version == sessionMapVersion:
+		return nil
+	case file.Version > sessionMapVersion:
+		return fmt.Errorf("format version %d is newer than this gateway supports (%d)", file.Version, sessionMapVersion)
+	default:
+		return fmt.Errorf("unknown format version %d", file.Version)
+	}
+}
+
+// sessionMapPath resolves the map file path, expanding a leading "~/".
+func sessionMapPath(cfg config.GatewaySessionPersistenceConfig) (string, error) {
+	path := strings.TrimSpace(cfg.Path)
+	if path == "" {
+		path = defaultSessionMapPath
+	}
+
+	return workspace.ExpandHome(path)
+}
+
+// openPersistentMemoryStore returns the JSONL store that keeps gateway memory
+// next to the session map, or nil when storage.backend already persists it or
+// gateway.session_persistence.memory is off.
+func openPersistentMemoryStore(cfg *config.Config) (store.SessionStore, error) {
+	persistence := cfg.Gateway.SessionPersistence
+	backend := strings.ToLower(strings.TrimSpace(cfg.Storage.Backend))
+	if !persistence.Enabled || !persistence.Memory || (backend != "" && backend != store.BackendMemory) {
+		return nil, nil
+	}
+	path, err := sessionMapPath(persistence)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.NewJSONLStore(strings.TrimSuffix(path, ".json") + "-memory")
+}
+
+// lookup returns the provider session saved for sessionKey.
+func (m *sessionMap) lookup(sessionKey string) (savedSession, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	saved, ok := m.sessions[sessionKey]
+	return saved, ok
+}
+
+// save records the provider session of sessionKey and writes the map.
+func (m *sessionMap) save(sessionKey string, saved savedSession) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sessions[sessionKey] = saved
+	return m.writeLocked()
+}
+
+// prune drops the entries last saved before cutoff whose key keep rejects,
+// writes the map when any were dropped, and returns how many were.
+func (m *sessionMap) prune(cutoff time.Time, keep func(sessionKey string) bool) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pruned := 0
+	for sessionKey, saved := range m.sessions {
+		if saved.UpdatedAt.Before(cutoff) && !keep(sessionKey) {
+			delete(m.sessions, sessionKey)
+			pruned++
+		}
+	}
+	if pruned == 0 {
+		return 0, nil
+	}
+
+	return pruned, m.writeLocked()
+}
+
+// writeLocked replaces the map file through a temporary file, so a crash
+// mid-write leaves the previous map intact; the caller holds m.mu.
+func (m *sessionMap) writeLocked() error {
+	data, err := json.MarshalIndent(sessionMapFile{Version: sessionMapVersion, Sessions: m.sessions}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode session map: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0o755); err != nil {
+		return fmt.Errorf("create session map directory: %w", err)
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(m.path), filepath.Base(m.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("write session map: %w", err)
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(append(data, '\n')); err != nil {
+		_ = temp.Close()
+		return fmt.Errorf("write session map: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("write session map: %w", err)
+	}
+	if err := os.Chmod(temp.Name(), 0o644); err != nil {
+		return fmt.Errorf("write session map: %w", err)
+	}
+	if err := os.Rename(temp.Name(), m.path); err != nil {
+		return fmt.Errorf("write session map: %w", err)
+	}
+
+	return nil
+}
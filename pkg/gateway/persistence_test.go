@@ -0,0 +1,75 @@
+package gateway
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"miniclaw/pkg/config"
+)
+
+func TestSessionPersistenceSurvivesRestart(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "gateway-sessions.json")
+	cfg := &config.Config{
+		Agents:  config.AgentsConfig{Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5-nano"}},
+		Gateway: config.GatewayConfig{SessionPersistence: config.GatewaySessionPersistenceConfig{Enabled: true, Path: path, Memory: true}},
+	}
+
+	first := &fakeProviderClient{}
+	manager, err := newRuntimeManager(context.Background(), cfg, first, nil)
+	if err != nil {
+		t.Fatalf("newRuntimeManager error: %v", err)
+	}
+	if _, err := manager.Prompt(context.Background(), "telegram:100", "remember the trip"); err != nil {
+		t.Fatalf("Prompt error: %v", err)
+	}
+	manager.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read session map: %v", err)
+	}
+	if !strings.Contains(string(data), `"version": 1`) || !strings.Contains(string(data), `"telegram:100"`) {
+		t.Fatalf("session map = %s", data)
+	}
+
+	// A restarted gateway continues the saved provider session and memory.
+	second := &fakeProviderClient{}
+	manager, err = newRuntimeManager(context.Background(), cfg, second, nil)
+	if err != nil {
+		t.Fatalf("newRuntimeManager after restart error: %v", err)
+	}
+	t.Cleanup(manager.Close)
+	if _, err := manager.Prompt(context.Background(), "telegram:100", "where to?"); err != nil {
+		t.Fatalf("Prompt after restart error: %v", err)
+	}
+	if second.createSessionCount != 0 {
+		t.Fatalf("CreateSession calls after restart = %d, want the saved session reused", second.createSessionCount)
+	}
+	instance, _, _ := manager.Snapshot("telegram:100")
+	if instance.SessionID() != "session-id" {
+		t.Fatalf("session id = %q, want the saved one", instance.SessionID())
+	}
+	if memory := instance.MemorySnapshot(); len(memory) != 4 || memory[0].Content != "remember the trip" {
+		t.Fatalf("memory after restart = %+v, want both turns", memory)
+	}
+}
+
+func TestSessionMapRefusesNewerFormat(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "sessions.json")
+	if err := os.WriteFile(path, []byte(`{"version": 2, "sessions": {}}`), 0o644); err != nil {
+		t.Fatalf("write map: %v", err)
+	}
+	if _, err := openSessionMap(config.GatewaySessionPersistenceConfig{Enabled: true, Path: path}); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Fatalf("openSessionMap error = %v, want a newer-version error", err)
+	}
+	if sessions, err := openSessionMap(config.GatewaySessionPersistenceConfig{}); sessions != nil || err != nil {
+		t.Fatalf("disabled map = %v, %v; want nil, nil", sessions, err)
+	}
+}
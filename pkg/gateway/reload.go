@@ -0,0 +1,157 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+
+	agentruntime "miniclaw/pkg/agent/runtime"
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/channel"
+	"miniclaw/pkg/config"
+)
+
+// runningChannel is one channel adapter started by Run or Reload.
+type runningChannel struct {
+	adapter channel.Adapter
+	cancel  context.CancelFunc
+	// done is closed once the adapter's Run returned.
+	done chan struct{}
+}
+
+// Reload applies the parts of a reloaded config that are safe to change
+// while sessions are live, without dropping them:
+//
+//   - adapters lists the channels cfg enables. Channels that are no longer
+//     listed stop, new ones start, and running ones that implement
+//     channel.Reloader take their changed settings, such as allowlists.
+//   - a changed channels.policy applies to the next message, with fresh
+//     rate limit counts, as does channels.templates; channels.restart applies
+//     to the next channel failure.
+//   - runtime session limits reach running sessions; budgets and circuit
+//     breakers apply to new ones.
+//
+// Other changed sections are logged as needing a restart and keep their
+// running values. Reload changes nothing when cfg is invalid.
+func (s *Service) Reload(cfg *config.Config, adapters []channel.Adapter) error {
+	if cfg == nil {
+		return errors.New("config is required")
+	}
+	if len(adapters) == 0 && s.cluster == nil {
+		return errors.New("at least one channel adapter is required")
+	}
+	if _, err := agentruntime.NewBudget(cfg.Runtime.Budget, s.log); err != nil {
+		return err
+	}
+	policy, err := newChannelPolicy(cfg.Channels.Policy)
+	if err != nil {
+		return err
+	}
+	restartPolicy, err := newChannelRestartPolicy(cfg.Channels.Restart)
+	if err != nil {
+		return err
+	}
+	templates, err := newChannelTemplates(cfg.Channels.Templates)
+	if err != nil {
+		return err
+	}
+	// The status server keeps the gateway settings it started with.
+	if err := validateChannelPages(s.cfg.Gateway, adapters); err != nil {
+		return err
+	}
+
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	if s.runCtx == nil {
+		return errors.New("gateway is not running")
+	}
+
+	enabled := make(map[string]channel.Adapter, len(adapters))
+	for _, adapter := range adapters {
+		enabled[adapter.Name()] = adapter
+	}
+	for name, running := range s.running {
+		if _, ok := enabled[name]; ok {
+			continue
+		}
+		running.cancel()
+		<-running.done
+		delete(s.running, name)
+		s.mu.Lock()
+		delete(s.channelStates, name)
+		delete(s.notifiers, name)
+		s.mu.Unlock()
+		s.log.Info("Channel disabled", "channel", name)
+	}
+	for _, adapter := range adapters {
+		running, ok := s.running[adapter.Name()]
+		if !ok {
+			s.startChannel(adapter)
+			s.log.Info("Channel enabled", "channel", adapter.Name())
+			continue
+		}
+		if reloader, ok := running.adapter.(channel.Reloader); ok {
+			if err := reloader.Reload(cfg.Channels); err != nil {
+				s.log.Warn("Channel settings need a restart", "channel", adapter.Name(), "error", err)
+			}
+		}
+	}
+
+	s.setChannelPolicy(cfg.Channels.Policy, policy)
+	s.setChannelTemplates(templates)
+	s.setChannelRestartPolicy(restartPolicy)
+	s.manager.setRuntimeLimits(cfg.Runtime)
+
+	if sections := restartSections(s.cfg, cfg); len(sections) > 0 {
+		s.log.Warn("Config changes need a restart", "sections", strings.Join(sections, ","))
+	}
+	s.log.Info("Config reloaded", "channels", len(s.running))
+
+	return nil
+}
+
+// startChannel runs adapter under the Run context until it stops or Reload
+// disables it, restarting it after failures; the caller holds s.reloadMu.
+func (s *Service) startChannel(adapter channel.Adapter) {
+	ctx, cancel := context.WithCancel(s.runCtx)
+	running := &runningChannel{adapter: adapter, cancel: cancel, done: make(chan struct{})}
+	s.running[adapter.Name()] = running
+
+	if user, ok := adapter.(channel.WorkspaceUser); ok {
+		user.UseWorkspaces(s.manager.sessionFiles)
+	}
+	s.setNotifier(adapter.Name(), adapter)
+	s.setChannelState(adapter.Name(), channelState{Running: true})
+	_ = s.manager.events.PublishEvent(ctx, bus.Event{Type: bus.EventChannelConnected, Channel: adapter.Name()})
+
+	go s.superviseChannel(ctx, adapter, running)
+}
+
+// restartSections names the changed config sections Reload cannot apply.
+func restartSections(current, next *config.Config) []string {
+	var sections []string
+	for _, section := range []struct {
+		name          string
+		current, next any
+	}{
+		{"agents", current.Agents, next.Agents},
+		{"providers", current.Providers, next.Providers},
+		{"tools", current.Tools, next.Tools},
+		{"heartbeat", current.Heartbeat, next.Heartbeat},
+		{"runtime.workers", current.Runtime.Workers, next.Runtime.Workers},
+		{"storage", current.Storage, next.Storage},
+		{"bus", current.Bus, next.Bus},
+		{"devices", current.Devices, next.Devices},
+		{"gateway", current.Gateway, next.Gateway},
+		{"logging.format", current.Logging.Format, next.Logging.Format},
+		{"logging.add_source", current.Logging.AddSource, next.Logging.AddSource},
+		{"telemetry", current.Telemetry, next.Telemetry},
+	} {
+		if !reflect.DeepEqual(section.current, section.next) {
+			sections = append(sections, section.name)
+		}
+	}
+
+	return sections
+}
@@ -0,0 +1,134 @@
+package gateway
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"miniclaw/pkg/channel"
+	"miniclaw/pkg/config"
+)
+
+// reloadableAdapter is a scripted adapter that records the channel configs
+// Reload hands it.
+type reloadableAdapter struct {
+	scriptedAdapter
+
+	reloadMu sync.Mutex
+	reloads  []config.ChannelsConfig
+}
+
+func (a *reloadableAdapter) Reload(cfg config.ChannelsConfig) error {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+	a.reloads = append(a.reloads, cfg)
+	return nil
+}
+
+func TestServiceReloadAppliesChannelsAndLimits(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := &config.Config{
+		Agents:  config.AgentsConfig{Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5.2"}},
+		Gateway: config.GatewayConfig{Host: "127.0.0.1", Port: freeTCPPort(t)},
+	}
+	manager, err := newRuntimeManager(ctx, cfg, &recordingGatewayProvider{}, slog.Default())
+	if err != nil {
+		t.Fatalf("newRuntimeManager error: %v", err)
+	}
+	telegram := &reloadableAdapter{scriptedAdapter: scriptedAdapter{name: "telegram", done: make(chan struct{})}}
+	svc := &Service{
+		cfg:           cfg,
+		log:           slog.Default(),
+		provider:      &recordingGatewayProvider{},
+		manager:       manager,
+		channels:      []channel.Adapter{telegram},
+		channelStates: map[string]channelState{"telegram": {}},
+	}
+
+	if err := svc.Reload(cfg, []channel.Adapter{telegram}); err == nil {
+		t.Fatal("Reload before Run should fail")
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- svc.Run(ctx) }()
+	waitClosed(t, telegram.done)
+
+	// Enabling a channel starts it beside telegram, which takes the new settings.
+	reloaded := *cfg
+	reloaded.Channels.Telegram.AllowFrom = []string{"42"}
+	reloaded.Runtime.MaxQueuedPerSession = 3
+	http := &scriptedAdapter{name: "http", done: make(chan struct{})}
+	replacement := &reloadableAdapter{scriptedAdapter: scriptedAdapter{name: "telegram", done: make(chan struct{})}}
+	if err := svc.Reload(&reloaded, []channel.Adapter{replacement, http}); err != nil {
+		t.Fatalf("Reload error: %v", err)
+	}
+	waitClosed(t, http.done)
+	telegram.reloadMu.Lock()
+	if len(telegram.reloads) != 1 || !slices.Equal(telegram.reloads[0].Telegram.AllowFrom, []string{"42"}) {
+		t.Fatalf("telegram reloads = %+v, want the new allow list", telegram.reloads)
+	}
+	telegram.reloadMu.Unlock()
+	if len(replacement.reloads) != 0 {
+		t.Fatal("the running telegram adapter should be kept")
+	}
+	manager.mu.RLock()
+	limit := manager.limits.MaxQueuedPerSession
+	manager.mu.RUnlock()
+	if limit != 3 {
+		t.Fatalf("max queued per session = %d, want 3", limit)
+	}
+
+	// Disabling telegram stops it and drops it from the status.
+	if err := svc.Reload(&reloaded, []channel.Adapter{http}); err != nil {
+		t.Fatalf("Reload error: %v", err)
+	}
+	channels := svc.currentStatus("ok").Channels
+	if _, ok := channels["telegram"]; ok || !channels["http"].Running {
+		t.Fatalf("channels after disabling telegram = %+v", channels)
+	}
+	if !svc.isReady() {
+		t.Fatal("gateway should stay ready with http running")
+	}
+
+	cancel()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("Run error: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for service run to exit")
+	}
+}
+
+func TestRestartSectionsNamesUnappliedChanges(t *testing.T) {
+	current := &config.Config{Gateway: config.GatewayConfig{Port: 18790}}
+	next := *current
+	next.Channels.Telegram.AllowFrom = []string{"1"}
+	next.Runtime.SessionConcurrency = 2
+	next.Logging.Level = "debug"
+	if sections := restartSections(current, &next); len(sections) != 0 {
+		t.Fatalf("restart sections = %v, want none for reloadable changes", sections)
+	}
+
+	next.Gateway.Port = 18799
+	next.Logging.Format = "json"
+	if sections := restartSections(current, &next); !slices.Equal(sections, []string{"gateway", "logging.format"}) {
+		t.Fatalf("restart sections = %v", sections)
+	}
+}
+
+func waitClosed(t *testing.T, done <-chan struct{}) {
+	t.Helper()
+
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for the adapter to start")
+	}
+}
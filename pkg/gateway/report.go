@@ -0,0 +1,32 @@
+package gateway
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"miniclaw/pkg/config"
+	"miniclaw/pkg/cron"
+	"miniclaw/pkg/report"
+	"miniclaw/pkg/store"
+)
+
+// activityReportTask backs the activity_report maintenance task, summarizing
+// stored sessions over the job's since window (24h by default).
+func activityReportTask(cfg *config.Config, sessions store.SessionStore, now func() time.Time) cron.MaintenanceTask {
+	return func(ctx context.Context, jobCfg config.MaintenanceJobConfig) (string, error) {
+		window := report.DefaultWindow
+		if since := strings.TrimSpace(jobCfg.Since); since != "" {
+			// AddMaintenance has already validated since.
+			window, _ = time.ParseDuration(since)
+		}
+
+		until := now()
+		activity, err := report.Generate(ctx, cfg, sessions, until.Add(-window), until)
+		if err != nil {
+			return "", err
+		}
+
+		return activity.String(), nil
+	}
+}
@@ -0,0 +1,87 @@
+package gateway
+
+import (
+	"fmt"
+	"strings"
+)
+
+// agentPrefix marks an inbound message addressed to a named agent, as in "!coder fix this".
+const agentPrefix = "!"
+
+// agentRoute is the outcome of parsing an inbound message for a named-agent prefix.
+type agentRoute struct {
+	// agent is the lower-cased named agent, or empty for the default agent.
+	agent  string
+	prompt string
+	// reply is set instead of agent when the gateway should answer directly,
+	// for example for an unknown agent name or a prefix without a prompt.
+	reply string
+}
+
+// routeInbound splits a "!<name> <prompt>" message into its agent and prompt.
+//
+// Messages without the prefix go to the gateway.channel_agents agent of the
+// chat's session key or of its channel, or to the default agent unchanged.
+// With no named agents configured every message goes to the default agent.
+func (m *runtimeManager) routeInbound(channel string, sessionKey string, content string) agentRoute {
+	trimmed := strings.TrimSpace(content)
+	if len(m.agents) == 0 {
+		return agentRoute{prompt: content}
+	}
+	fallback := agentRoute{prompt: content}
+	if agent, ok := m.lookupAgent(m.channelAgent(channel, sessionKey)); ok {
+		fallback.agent = agent.name
+	}
+	if !strings.HasPrefix(trimmed, agentPrefix) {
+		return fallback
+	}
+
+	name, prompt, _ := strings.Cut(strings.TrimPrefix(trimmed, agentPrefix), " ")
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fallback
+	}
+
+	agent, ok := m.lookupAgent(name)
+	if !ok {
+		return agentRoute{reply: fmt.Sprintf("Unknown agent %q.\n\n%s", name, m.agentDirectory())}
+	}
+
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		return agentRoute{reply: fmt.Sprintf("Usage: %s%s <message>", agentPrefix, agent.name)}
+	}
+
+	return agentRoute{agent: agent.name, prompt: prompt}
+}
+
+// channelAgent returns the agent that answers one chat: its /model choice,
+// else its gateway.channel_agents entry, where its session key (for example
+// "telegram:100") wins over its channel name.
+func (m *runtimeManager) channelAgent(channel string, sessionKey string) string {
+	m.mu.RLock()
+	name, ok := m.chatAgents[strings.TrimSpace(sessionKey)]
+	m.mu.RUnlock()
+	if ok {
+		return name
+	}
+	if name, ok := m.cfg.Gateway.ChannelAgents[strings.TrimSpace(sessionKey)]; ok {
+		return name
+	}
+
+	return m.cfg.Gateway.ChannelAgents[channel]
+}
+
+// agentDirectory lists the named agents with their descriptions.
+func (m *runtimeManager) agentDirectory() string {
+	lines := []string{"Available agents:"}
+	for _, name := range m.AgentNames() {
+		line := "- " + agentPrefix + name
+		if description := m.agents[name].description; description != "" {
+			line += ": " + description
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
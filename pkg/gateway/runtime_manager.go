@@ -2,17 +2,46 @@ package gateway
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
+	"unicode"
 
+	"miniclaw/pkg/accounting"
 	"miniclaw/pkg/agent"
 	agentprofile "miniclaw/pkg/agent/profile"
+	agentruntime "miniclaw/pkg/agent/runtime"
+	"miniclaw/pkg/bus"
 	"miniclaw/pkg/config"
 	"miniclaw/pkg/provider"
 	providertypes "miniclaw/pkg/provider/types"
+	"miniclaw/pkg/store"
+	"miniclaw/pkg/telemetry"
+	fstools "miniclaw/pkg/tools/fs"
+	"miniclaw/pkg/transcript"
+	"miniclaw/pkg/workspace"
 )
 
+// sessionWorkspacesDir is the directory under the workspace that holds per-session workspaces.
+const sessionWorkspacesDir = "sessions"
+
+// maxSessionWorkspaceName bounds the directory name of a session workspace
+// well below the 255 bytes file systems allow.
+const maxSessionWorkspaceName = 100
+
+// defaultSessionMaxAge is how long session_expiry keeps idle transcripts when max_age_days is unset.
+const defaultSessionMaxAge = 30 * 24 * time.Hour
+
 // runtimeManager owns per-session agent runtimes for gateway-driven prompts.
 type runtimeManager struct {
 	ctx    context.Context
@@ -20,16 +49,96 @@ type runtimeManager struct {
 	cfg    *config.Config
 	log    *slog.Logger
 	system string
+	store  store.SessionStore
+	// agents holds the named agents from agents.named, keyed by lower-cased name.
+	agents map[string]namedAgent
+	// metrics aggregates turn timing for /v1/metrics.
+	metrics *turnMetrics
+	// usage aggregates daily token usage for /v1/usage and usage_reconcile.
+	usage *usageLedger
+	// accounting persists per-session usage and cost for /v1/usage/report;
+	// nil when gateway.usage is off.
+	accounting *accounting.Store
+	// telemetry holds the metrics served and pushed per the telemetry config.
+	telemetry *telemetry.Registry
+	// newClient builds the provider client for one session workspace when
+	// gateway.session_workspaces is enabled.
+	newClient func(cfg *config.Config) (provider.Client, error)
+	// middleware wraps every agent prompt, outermost first; set before serving.
+	middleware []agentruntime.Middleware
+	// events carries session, tool, heartbeat, and channel events to
+	// Service.SubscribeEvents; no messages go through it.
+	events *bus.MessageBus
+	// sessionMap saves each session key's provider session across restarts;
+	// nil when gateway.session_persistence is off.
+	sessionMap *sessionMap
+
+	// inflight holds the running prompts keyed by request ID, so /stop can
+	// cancel them.
+	inflightMu    sync.Mutex
+	inflight      map[uint64]inflightPrompt
+	nextRequestID uint64
 
-	mu       sync.RWMutex
+	mu sync.RWMutex
+	// limits are the runtime settings new sessions start with; Service.Reload
+	// replaces them.
+	limits   config.RuntimeConfig
 	runtimes map[string]*sessionRuntime
+	// chatAgents holds the agent each chat switched to with /model, keyed by
+	// session key; "" is the default agent.
+	chatAgents map[string]string
+	// sessionClients holds the clients built for session workspaces and named
+	// profiles, keyed by workspace directory name and "@<agent>" for profiles.
+	sessionClients map[string]provider.Client
 }
 
 // sessionRuntime is the mutable runtime state tracked for one session key.
 type sessionRuntime struct {
-	instance   *agent.Instance
-	promptMu   sync.Mutex
+	instance *agent.Instance
+	// queue admits this session's prompts under the runtime session limits.
+	queue *agentruntime.PromptQueue
+	// breaker fails prompts fast while the provider keeps failing; nil when disabled.
+	breaker *agentruntime.CircuitBreaker
+	// budget refuses prompts once the session spent runtime.budget; nil when disabled.
+	budget     *agentruntime.Budget
 	cancelLoop context.CancelFunc
+
+	statsMu        sync.Mutex
+	turns          int
+	failures       int
+	usage          providertypes.TokenUsage
+	toolUsage      providertypes.ToolUsage
+	context        *providertypes.ContextUsage
+	lastActivityAt time.Time
+}
+
+// inflightPrompt is one running PromptAgent call.
+type inflightPrompt struct {
+	sessionKey string
+	cancel     context.CancelFunc
+}
+
+// namedAgent is the resolved prompt configuration for one routed agent.
+type namedAgent struct {
+	name        string
+	description string
+	model       string
+	agent       string
+	system      string
+	// cfg is set for profiles that need their own provider client
+	// (config.NamedAgentConfig.OwnClient); its agents.defaults apply the profile.
+	cfg *config.Config
+}
+
+// sessionStats is a point-in-time copy of one session's counters.
+type sessionStats struct {
+	Turns     int
+	Failures  int
+	Usage     providertypes.TokenUsage
+	ToolUsage providertypes.ToolUsage
+	// Context is the context window fill after the last turn; nil when not reported.
+	Context        *providertypes.ContextUsage
+	LastActivityAt time.Time
 }
 
 // newRuntimeManager builds a session runtime manager and resolves the system profile once.
@@ -47,35 +156,434 @@ func newRuntimeManager(ctx context.Context, cfg *config.Config, client provider.
 		log = slog.Default()
 	}
 
+	agents, err := resolveNamedAgents(cfg, systemProfile)
+	if err != nil {
+		return nil, err
+	}
+	for channel, name := range cfg.Gateway.ChannelAgents {
+		if _, ok := agents[strings.ToLower(strings.TrimSpace(name))]; !ok {
+			return nil, fmt.Errorf("gateway.channel_agents.%s: unknown agent %q", channel, name)
+		}
+	}
+
+	// Each runtime builds its own budget; check the settings once up front.
+	if _, err := agentruntime.NewBudget(cfg.Runtime.Budget, log); err != nil {
+		return nil, err
+	}
+
+	events := bus.NewMessageBus()
+	if strings.TrimSpace(cfg.Bus.EventLog.Path) != "" {
+		eventLog, err := bus.OpenEventLog(cfg.Bus.EventLog)
+		if err != nil {
+			return nil, err
+		}
+		events.AttachEventLog(eventLog)
+	}
+
+	sessionStore, err := openPersistentMemoryStore(cfg)
+	if err == nil && sessionStore == nil {
+		sessionStore, err = store.Open(cfg.Storage)
+	}
+	if err != nil {
+		events.Close()
+		return nil, fmt.Errorf("open session store: %w", err)
+	}
+	// The session map keeps no file open between saves, so the failures
+	// after it only have the event bus and session store to release.
+	closeOpened := func() {
+		events.Close()
+		_ = sessionStore.Close()
+	}
+	sessions, err := openSessionMap(cfg.Gateway.SessionPersistence)
+	if err != nil {
+		closeOpened()
+		return nil, err
+	}
+	usageStore, err := openUsageStore(cfg)
+	if err != nil {
+		closeOpened()
+		return nil, fmt.Errorf("open usage store: %w", err)
+	}
+	if cfg.Gateway.SessionWorkspaces.Enabled && !cfg.Agents.Defaults.RestrictToWorkspace {
+		log.Warn("Session workspaces do not isolate sessions unless restrict_to_workspace is true", "component", "gateway.runtime_manager")
+	}
+
 	return &runtimeManager{
-		ctx:      ctx,
-		client:   client,
-		cfg:      cfg,
-		log:      log.With("component", "gateway.runtime_manager"),
-		system:   systemProfile,
-		runtimes: make(map[string]*sessionRuntime),
+		ctx:        ctx,
+		client:     client,
+		cfg:        cfg,
+		log:        log.With("component", "gateway.runtime_manager"),
+		system:     agentprofile.WithInstructions(systemProfile, cfg.Agents.Defaults.Instructions),
+		store:      sessionStore,
+		agents:     agents,
+		metrics:    &turnMetrics{},
+		usage:      newUsageLedger(time.Now()),
+		accounting: usageStore,
+		telemetry:  newGatewayRegistry(),
+		newClient:  newProviderClient,
+		events:     events,
+		sessionMap: sessions,
+		limits:     cfg.Runtime,
+		runtimes:   make(map[string]*sessionRuntime),
 	}, nil
 }
 
-// Prompt routes one prompt to a session runtime and serializes requests per session.
+// resolveNamedAgents validates agents.named and fills unset fields from agents.defaults.
+func resolveNamedAgents(cfg *config.Config, systemProfile string) (map[string]namedAgent, error) {
+	agents := make(map[string]namedAgent, len(cfg.Agents.Named))
+	for index, agentCfg := range cfg.Agents.Named {
+		name := strings.ToLower(strings.TrimSpace(agentCfg.Name))
+		if name == "" {
+			return nil, fmt.Errorf("agents.named[%d]: name is required", index)
+		}
+		if strings.ContainsFunc(name, unicode.IsSpace) || strings.Contains(name, "@") {
+			return nil, fmt.Errorf("agents.named[%d]: name %q must not contain spaces or @", index, agentCfg.Name)
+		}
+		if _, ok := agents[name]; ok {
+			return nil, fmt.Errorf("agents.named[%d]: duplicate name %q", index, name)
+		}
+
+		namedCfg, err := cfg.WithNamedAgent(name)
+		if err != nil {
+			return nil, fmt.Errorf("agents.named[%d]: %w", index, err)
+		}
+		profile := systemProfile
+		if strings.TrimSpace(agentCfg.Provider) != "" {
+			if profile, err = agentprofile.ResolveSystemProfile(namedCfg.Agents.Defaults.Provider); err != nil {
+				return nil, fmt.Errorf("agents.named[%d]: resolve agent profile: %w", index, err)
+			}
+		}
+
+		resolved := namedAgent{
+			name:        name,
+			description: strings.TrimSpace(agentCfg.Description),
+			model:       namedCfg.Agents.Defaults.Model,
+			agent:       strings.TrimSpace(agentCfg.Agent),
+			system:      agentprofile.WithInstructions(profile, namedCfg.Agents.Defaults.Instructions),
+		}
+		if agentCfg.OwnClient() {
+			resolved.cfg = namedCfg
+		}
+		agents[name] = resolved
+	}
+
+	return agents, nil
+}
+
+// AgentNames returns the configured named agents in sorted order.
+func (m *runtimeManager) AgentNames() []string {
+	return slices.Sorted(maps.Keys(m.agents))
+}
+
+// lookupAgent reports whether name (case-insensitive) is a configured named agent.
+func (m *runtimeManager) lookupAgent(name string) (namedAgent, bool) {
+	agent, ok := m.agents[strings.ToLower(strings.TrimSpace(name))]
+	return agent, ok
+}
+
+// Prompt routes one prompt to the default agent's session runtime.
 func (m *runtimeManager) Prompt(ctx context.Context, sessionKey string, prompt string) (providertypes.PromptResult, error) {
-	runtime, err := m.runtimeForSession(ctx, sessionKey)
+	return m.PromptAgent(ctx, "", sessionKey, prompt)
+}
+
+// PromptAgent routes one prompt to the named agent's runtime for sessionKey and
+// admits it through the session's prompt queue, which runs one prompt at a
+// time unless runtime.session_concurrency allows more.
+//
+// An empty name selects the default agent. Each named agent keeps its own
+// history under agentSessionKey(sessionKey, name).
+func (m *runtimeManager) PromptAgent(ctx context.Context, name string, sessionKey string, prompt string) (providertypes.PromptResult, error) {
+	profile := namedAgent{model: m.cfg.Agents.Defaults.Model, system: m.system}
+	if name != "" {
+		var ok bool
+		if profile, ok = m.lookupAgent(name); !ok {
+			return providertypes.PromptResult{}, fmt.Errorf("unknown agent %q", name)
+		}
+		sessionKey = agentSessionKey(sessionKey, profile.name)
+	}
+
+	runtime, err := m.runtimeForSession(ctx, sessionKey, profile)
 	if err != nil {
 		return providertypes.PromptResult{}, err
 	}
 
-	runtime.promptMu.Lock()
-	defer runtime.promptMu.Unlock()
+	ctx, cancel := context.WithCancel(ctx)
+	requestID := m.trackPrompt(sessionKey, cancel)
+	defer m.untrackPrompt(requestID)
+	template := bus.Event{
+		SessionKey: sessionKey,
+		RequestID:  strconv.FormatUint(requestID, 10),
+		TraceID:    providertypes.TraceIDFromContext(ctx),
+	}
+	log := providertypes.TraceLogger(ctx, m.log)
+	ctx, restoreToolEvents := agentruntime.WithToolEventBus(ctx, m.events, template)
+
+	received := template
+	received.Type = bus.EventPromptReceived
+	received.Payload = bus.PromptReceivedPayload{PromptLength: len(prompt)}
+	_ = m.events.PublishEvent(ctx, received)
+
+	// File tool audit entries name the channel session rather than the provider's session ID.
+	ctx = fstools.WithAuditSession(ctx, sessionKey)
+
+	// Time spent behind earlier prompts for the same session counts as queue wait.
+	lockStartedAt := time.Now()
+	release, err := runtime.queue.Acquire(ctx)
+	if err != nil {
+		// Stopped while queued behind an earlier prompt, or the queue is full.
+		m.publishPromptOutcome(ctx, template, providertypes.PromptResult{}, err)
+		return providertypes.PromptResult{}, err
+	}
+	defer release()
+	lockWait := time.Since(lockStartedAt)
+
+	handler := agentruntime.Chain(func(ctx context.Context, prompt string) (providertypes.PromptResult, error) {
+		if runtime.instance.HeartbeatEnabled() {
+			return runtime.instance.EnqueueAndWait(ctx, prompt)
+		}
+		return runtime.instance.Prompt(ctx, prompt)
+	}, slices.Concat(m.middleware, []agentruntime.Middleware{runtime.budget.Middleware(), runtime.breaker.Middleware()})...)
+	result, err := handler(ctx, prompt)
+	restoreToolEvents(&result)
+	if err == nil {
+		timing := result.Metadata.EnsureTiming()
+		timing.QueueWait += lockWait
+		timing.Total += lockWait
+		agentruntime.LogTurnTiming(log, *timing, "session_key", sessionKey)
+	}
+	if runtime.recordPrompt(result, err) {
+		// Warn once, on the turn that crosses agents.defaults.context.warn_percent.
+		result.Text += contextWarning(*result.Metadata.Context)
+		log.Warn("Session context nearly full", "session_key", sessionKey, "context_percent", result.Metadata.Context.Percent())
+	}
+	m.metrics.record(result.Metadata.Timing, err)
+	recordTurnTelemetry(m.telemetry, result, err)
+	if err == nil {
+		m.usage.record(result.Metadata, time.Now())
+		m.recordAccounting(log, sessionKey, result.Metadata)
+	}
+	m.publishPromptOutcome(ctx, template, result, err)
+
+	return result, err
+}
+
+// publishPromptOutcome publishes prompt_completed or prompt_failed for one
+// prompt, identified like template.
+func (m *runtimeManager) publishPromptOutcome(ctx context.Context, template bus.Event, result providertypes.PromptResult, err error) {
+	event := template
+	var timing *bus.PromptTiming
+	if turn := result.Metadata.Timing; turn != nil {
+		timing = &bus.PromptTiming{
+			QueueWaitMs:  turn.QueueWait.Milliseconds(),
+			ProcessingMs: (turn.Total - turn.QueueWait).Milliseconds(),
+		}
+	}
+
+	if err != nil {
+		event.Type = bus.EventPromptFailed
+		event.Error = err.Error()
+		event.Payload = bus.PromptFailedPayload{Timing: timing, ErrorCategory: string(providertypes.ErrorCategoryOf(err))}
+	} else {
+		completed := bus.PromptCompletedPayload{Timing: timing, ResponseLength: len(result.Text)}
+		if usage := result.Metadata.Usage; usage != nil {
+			completed.Usage = &bus.TokenUsage{InputTokens: usage.InputTokens, OutputTokens: usage.OutputTokens, TotalTokens: usage.TotalTokens}
+		}
+		if tools := result.Metadata.ToolUsage; tools != nil {
+			completed.Tools = &bus.ToolUsage{
+				Calls:        tools.Calls,
+				QuotaDenied:  tools.QuotaDenied,
+				BytesRead:    tools.BytesRead,
+				BytesWritten: tools.BytesWritten,
+				DurationMs:   tools.Duration.Milliseconds(),
+			}
+		}
+		event.Type = bus.EventPromptCompleted
+		event.Payload = completed
+	}
+
+	// A stopped prompt's context is already canceled; its outcome still counts.
+	_ = m.events.PublishEvent(context.WithoutCancel(ctx), event)
+}
+
+// CancelSession cancels the running and queued prompts of sessionKey and of
+// the named agents within it, and returns how many it stopped.
+func (m *runtimeManager) CancelSession(sessionKey string) int {
+	m.inflightMu.Lock()
+	defer m.inflightMu.Unlock()
+
+	stopped := 0
+	for _, prompt := range m.inflight {
+		if prompt.sessionKey == sessionKey || strings.HasPrefix(prompt.sessionKey, sessionKey+"@") {
+			prompt.cancel()
+			stopped++
+		}
+	}
+	if stopped > 0 {
+		m.log.Info("Canceled running prompts", "session_key", sessionKey, "count", stopped)
+	}
+
+	return stopped
+}
+
+// trackPrompt registers a running prompt and returns its request ID.
+func (m *runtimeManager) trackPrompt(sessionKey string, cancel context.CancelFunc) uint64 {
+	m.inflightMu.Lock()
+	defer m.inflightMu.Unlock()
+
+	if m.inflight == nil {
+		m.inflight = make(map[uint64]inflightPrompt)
+	}
+	m.nextRequestID++
+	m.inflight[m.nextRequestID] = inflightPrompt{sessionKey: sessionKey, cancel: cancel}
+	return m.nextRequestID
+}
+
+// untrackPrompt releases the prompt registered under requestID.
+func (m *runtimeManager) untrackPrompt(requestID uint64) {
+	m.inflightMu.Lock()
+	defer m.inflightMu.Unlock()
+
+	if prompt, ok := m.inflight[requestID]; ok {
+		prompt.cancel()
+		delete(m.inflight, requestID)
+	}
+}
+
+// Snapshot returns the runtime and counters for a tracked session key.
+func (m *runtimeManager) Snapshot(sessionKey string) (*agent.Instance, sessionStats, bool) {
+	m.mu.RLock()
+	runtime, ok := m.runtimes[sessionKey]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, sessionStats{}, false
+	}
+
+	return runtime.instance, runtime.stats(), true
+}
+
+// Snapshots returns the counters of every live session runtime by session key.
+func (m *runtimeManager) Snapshots() map[string]sessionStats {
+	m.mu.RLock()
+	runtimes := make(map[string]*sessionRuntime, len(m.runtimes))
+	for key, runtime := range m.runtimes {
+		runtimes[key] = runtime
+	}
+	m.mu.RUnlock()
+
+	snapshots := make(map[string]sessionStats, len(runtimes))
+	for key, runtime := range runtimes {
+		snapshots[key] = runtime.stats()
+	}
+
+	return snapshots
+}
+
+// errSessionNotFound reports a session key with no live runtime or stored conversation.
+var errSessionNotFound = errors.New("session not found")
+
+// ExportSession copies the conversation of sessionKey out of the session
+// store: the live runtime's session, or else the latest one stored for the key.
+func (m *runtimeManager) ExportSession(ctx context.Context, sessionKey string) (transcript.Transcript, error) {
+	if instance, _, ok := m.Snapshot(sessionKey); ok && instance.SessionID() != "" {
+		return instance.ExportSession(ctx)
+	}
+
+	exporter, ok := m.client.(provider.SessionExporter)
+	resumer, canResume := m.client.(provider.SessionResumer)
+	if !ok || !canResume {
+		return transcript.Transcript{}, errors.New("provider does not support session export")
+	}
+	sessionID, found, err := resumer.LatestSession(ctx, "miniclaw:"+sessionKey)
+	if err != nil {
+		return transcript.Transcript{}, err
+	}
+	if !found {
+		return transcript.Transcript{}, errSessionNotFound
+	}
+
+	return exporter.ExportSession(ctx, sessionID)
+}
+
+// ImportSession stores exported as the conversation of sessionKey and drops
+// the key's live runtime, so the next message resumes the import.
+func (m *runtimeManager) ImportSession(ctx context.Context, sessionKey string, exported transcript.Transcript) (string, error) {
+	exporter, ok := m.client.(provider.SessionExporter)
+	if !ok {
+		return "", errors.New("provider does not support session import")
+	}
+	sessionID, err := exporter.ImportSession(ctx, exported, "miniclaw:"+sessionKey)
+	if err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	if runtime, live := m.runtimes[sessionKey]; live {
+		runtime.cancelLoop()
+		delete(m.runtimes, sessionKey)
+		m.telemetry.Set(metricActiveSessions, float64(len(m.runtimes)))
+		m.publishEvicted(ctx, sessionKey, "import")
+	}
+	m.mu.Unlock()
+	m.log.Info("Imported session", "session_key", sessionKey, "session_id", sessionID, "messages", len(exported.Messages))
+
+	return sessionID, nil
+}
+
+// recordPrompt updates session counters after one prompt attempt and reports
+// whether this turn left the context window nearly full for the first time.
+func (r *sessionRuntime) recordPrompt(result providertypes.PromptResult, err error) bool {
+	r.statsMu.Lock()
+	defer r.statsMu.Unlock()
 
-	if runtime.instance.HeartbeatEnabled() {
-		return runtime.instance.EnqueueAndWait(ctx, prompt)
+	r.lastActivityAt = time.Now().UTC()
+	if err != nil {
+		r.failures++
+		return false
 	}
 
-	return runtime.instance.Prompt(ctx, prompt)
+	crossed := false
+	if context := result.Metadata.Context; context != nil {
+		crossed = context.NearlyFull && (r.context == nil || !r.context.NearlyFull)
+		r.context = context
+	}
+
+	r.turns++
+	if usage := result.Metadata.Usage; usage != nil {
+		r.usage.InputTokens += usage.InputTokens
+		r.usage.OutputTokens += usage.OutputTokens
+		r.usage.TotalTokens += usage.TotalTokens
+		r.usage.ReasoningTokens += usage.ReasoningTokens
+		r.usage.CacheCreationTokens += usage.CacheCreationTokens
+		r.usage.CacheReadTokens += usage.CacheReadTokens
+	}
+	if toolUsage := result.Metadata.ToolUsage; toolUsage != nil {
+		r.toolUsage.Add(*toolUsage)
+	}
+
+	return crossed
+}
+
+// contextWarning is appended to the reply that leaves the context window nearly full.
+func contextWarning(context providertypes.ContextUsage) string {
+	return fmt.Sprintf("\n\n(Heads up: this conversation fills %d%% of the model's context window.)", context.Percent())
+}
+
+func (r *sessionRuntime) stats() sessionStats {
+	r.statsMu.Lock()
+	defer r.statsMu.Unlock()
+
+	return sessionStats{
+		Turns:          r.turns,
+		Failures:       r.failures,
+		Usage:          r.usage,
+		ToolUsage:      r.toolUsage,
+		Context:        r.context,
+		LastActivityAt: r.lastActivityAt,
+	}
 }
 
 // runtimeForSession returns an existing runtime or lazily initializes a new one.
-func (m *runtimeManager) runtimeForSession(ctx context.Context, sessionKey string) (*sessionRuntime, error) {
+func (m *runtimeManager) runtimeForSession(ctx context.Context, sessionKey string, profile namedAgent) (*sessionRuntime, error) {
 	m.mu.RLock()
 	runtime, ok := m.runtimes[sessionKey]
 	m.mu.RUnlock()
@@ -91,13 +599,44 @@ func (m *runtimeManager) runtimeForSession(ctx context.Context, sessionKey strin
 		return runtime, nil
 	}
 
-	instance := agent.New(m.client, m.cfg.Agents.Defaults.Model, m.cfg.Heartbeat, "", m.system)
-	if err := instance.StartSession(ctx, "miniclaw:"+sessionKey); err != nil {
+	client, err := m.clientForSession(sessionKey, profile)
+	if err != nil {
+		return nil, err
+	}
+	// System files are read per new runtime so edits reach the next session without a restart.
+	workspacePrompt, err := agentprofile.WorkspacePrompt(m.cfg.Agents.Defaults)
+	if err != nil {
+		return nil, err
+	}
+	system := agentprofile.WithInstructions(profile.system, workspacePrompt)
+	instance := agent.New(client, profile.model, m.cfg.Heartbeat, profile.agent, system)
+	if err := instance.UseStore(ctx, m.store, memoryStoreID(sessionKey)); err != nil {
+		return nil, fmt.Errorf("load memory for %s: %w", sessionKey, err)
+	}
+	resumed, err := m.startSession(ctx, instance, client, sessionKey, profile.name)
+	if err != nil {
 		return nil, fmt.Errorf("start session for %s: %w", sessionKey, err)
 	}
+	if resumed {
+		m.log.Info("Resumed stored session", "session_key", sessionKey, "session_id", instance.SessionID())
+	}
 
-	runtime = &sessionRuntime{instance: instance, cancelLoop: func() {}}
+	sessionLog := m.log.With("session_key", sessionKey)
+	budget, err := agentruntime.NewBudget(m.limits.Budget, sessionLog)
+	if err != nil {
+		return nil, err
+	}
+	runtime = &sessionRuntime{
+		instance:   instance,
+		queue:      agentruntime.NewPromptQueue(m.limits),
+		breaker:    agentruntime.NewCircuitBreaker(m.limits.CircuitBreaker, sessionLog),
+		budget:     budget,
+		cancelLoop: func() {},
+	}
 	if instance.HeartbeatEnabled() {
+		instance.OnHeartbeat(func() {
+			_ = m.events.PublishEvent(m.ctx, bus.Event{Type: bus.EventHeartbeatTick, SessionKey: sessionKey})
+		})
 		loopCtx, cancelLoop := context.WithCancel(m.ctx)
 		runtime.cancelLoop = cancelLoop
 		go func() {
@@ -108,10 +647,161 @@ func (m *runtimeManager) runtimeForSession(ctx context.Context, sessionKey strin
 	}
 
 	m.runtimes[sessionKey] = runtime
+	m.telemetry.Set(metricActiveSessions, float64(len(m.runtimes)))
+	_ = m.events.PublishEvent(ctx, bus.Event{
+		Type:       bus.EventSessionCreated,
+		SessionKey: sessionKey,
+		TraceID:    providertypes.TraceIDFromContext(ctx),
+		Payload:    bus.SessionPayload{Resumed: resumed, Agent: profile.name},
+	})
 	return runtime, nil
 }
 
-// Close stops all heartbeat loops and drops tracked session runtimes.
+// startSession continues the stored conversation of sessionKey, if any, so a
+// restarted gateway keeps each chat's history, and reports whether it did.
+//
+// Providers that can resume by title find the session themselves. For the
+// others the session map, when enabled, remembers which provider session the
+// key used.
+func (m *runtimeManager) startSession(ctx context.Context, instance *agent.Instance, client provider.Client, sessionKey string, agentName string) (bool, error) {
+	_, canResume := client.(provider.SessionResumer)
+	if m.sessionMap == nil || canResume {
+		return instance.ResumeSession(ctx, "miniclaw:"+sessionKey)
+	}
+
+	resumed := false
+	if saved, ok := m.sessionMap.lookup(sessionKey); ok {
+		instance.UseSession(saved.SessionID)
+		resumed = true
+	} else if err := instance.StartSession(ctx, "miniclaw:"+sessionKey); err != nil {
+		return false, err
+	}
+	saved := savedSession{SessionID: instance.SessionID(), Agent: agentName, UpdatedAt: time.Now().UTC()}
+	if err := m.sessionMap.save(sessionKey, saved); err != nil {
+		// The chat still works; it just starts over after the next restart.
+		m.log.Warn("Failed to save session map", "session_key", sessionKey, "error", err)
+	}
+
+	return resumed, nil
+}
+
+// clientForSession returns the provider client a new runtime for sessionKey
+// prompts through; the caller holds m.mu.
+//
+// With gateway.session_workspaces enabled, every channel session gets its own
+// client whose workspace, and so whose Guard and file and exec tools, is
+// <workspace>/sessions/<name>. Named agents in the same chat share that
+// workspace. A named profile with its own provider settings gets its own
+// client, per session workspace when those are enabled.
+func (m *runtimeManager) clientForSession(sessionKey string, profile namedAgent) (provider.Client, error) {
+	workspaces := m.cfg.Gateway.SessionWorkspaces.Enabled
+	if !workspaces && profile.cfg == nil {
+		return m.client, nil
+	}
+
+	clientCfg := *m.cfg
+	if profile.cfg != nil {
+		clientCfg = *profile.cfg
+	}
+	key := ""
+	if workspaces {
+		key = m.sessionWorkspaceName(sessionKey)
+	}
+	if profile.cfg != nil {
+		key += "@" + profile.name
+	}
+	if client, ok := m.sessionClients[key]; ok {
+		return client, nil
+	}
+
+	if workspaces {
+		dir, err := m.sessionWorkspace(sessionKey)
+		if err != nil {
+			return nil, err
+		}
+		clientCfg.Agents.Defaults.Workspace = dir
+	}
+	client, err := m.newClient(&clientCfg)
+	if err != nil {
+		return nil, fmt.Errorf("initialize provider for %s: %w", sessionKey, err)
+	}
+
+	if m.sessionClients == nil {
+		m.sessionClients = make(map[string]provider.Client)
+	}
+	m.sessionClients[key] = client
+	if workspaces {
+		m.log.Info("Provisioned session workspace", "session_key", sessionKey, "workspace", clientCfg.Agents.Defaults.Workspace)
+	}
+	return client, nil
+}
+
+// sessionWorkspace returns the workspace directory the agents of sessionKey
+// work in, creating it if needed: <workspace>/sessions/<name> with
+// gateway.session_workspaces enabled, else agents.defaults.workspace.
+func (m *runtimeManager) sessionWorkspace(sessionKey string) (string, error) {
+	root, err := workspace.ResolveRoot(m.cfg.Agents.Defaults.Workspace)
+	if err != nil {
+		return "", fmt.Errorf("resolve workspace: %w", err)
+	}
+	if !m.cfg.Gateway.SessionWorkspaces.Enabled {
+		return root, nil
+	}
+	dir, err := workspace.ResolveRoot(filepath.Join(root, sessionWorkspacesDir, m.sessionWorkspaceName(sessionKey)))
+	if err != nil {
+		return "", fmt.Errorf("provision workspace for %s: %w", sessionKey, err)
+	}
+
+	return dir, nil
+}
+
+// sessionFiles returns a file service for the workspace of sessionKey with
+// the containment policy and disk quota of the agent's file tools, for
+// channels that save received files there.
+func (m *runtimeManager) sessionFiles(sessionKey string) (*fstools.Service, error) {
+	dir, err := m.sessionWorkspace(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+	defaults := m.cfg.Agents.Defaults
+	guard, err := workspace.NewGuardWithOptions(dir, workspace.GuardOptions{
+		RestrictToWorkspace: defaults.RestrictToWorkspace,
+		AllowedPaths:        defaults.AllowedPaths,
+		SymlinkPolicy:       defaults.SymlinkPolicy,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("open workspace %s: %w", dir, err)
+	}
+	files := fstools.NewService(guard)
+	files.EnableDiskQuota(m.cfg.Tools.Filesystem.MaxWorkspaceBytes, m.cfg.Tools.Filesystem.MaxWorkspaceFiles)
+
+	return files, nil
+}
+
+// publishEvicted reports that the live runtime of sessionKey was dropped.
+func (m *runtimeManager) publishEvicted(ctx context.Context, sessionKey string, reason string) {
+	_ = m.events.PublishEvent(ctx, bus.Event{
+		Type:       bus.EventSessionEvicted,
+		SessionKey: sessionKey,
+		Payload:    bus.SessionPayload{Reason: reason},
+	})
+}
+
+// setRuntimeLimits applies reloaded runtime settings. Session concurrency and
+// queue limits reach running sessions at once; budgets and circuit breakers
+// apply to sessions started afterwards.
+func (m *runtimeManager) setRuntimeLimits(cfg config.RuntimeConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.limits = cfg
+	for _, runtime := range m.runtimes {
+		runtime.queue.SetLimits(cfg)
+	}
+}
+
+// Close stops all heartbeat loops, drops tracked session runtimes, closes the
+// event bus, and closes the provider client and session store.
 func (m *runtimeManager) Close() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -119,5 +809,124 @@ func (m *runtimeManager) Close() {
 	for sessionKey, runtime := range m.runtimes {
 		runtime.cancelLoop()
 		delete(m.runtimes, sessionKey)
+		m.publishEvicted(context.Background(), sessionKey, "shutdown")
+	}
+	m.telemetry.Set(metricActiveSessions, 0)
+	m.events.Close()
+	for name, client := range m.sessionClients {
+		if closer, ok := client.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				m.log.Warn("Failed to close session provider client", "workspace", name, "error", err)
+			}
+		}
+		delete(m.sessionClients, name)
+	}
+	if closer, ok := m.client.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			m.log.Warn("Failed to close provider client", "error", err)
+		}
 	}
+	if m.store != nil {
+		if err := m.store.Close(); err != nil {
+			m.log.Warn("Failed to close session store", "error", err)
+		}
+		m.store = nil
+	}
+}
+
+// expireSessions deletes stored gateway transcripts whose last turn is older
+// than cfg.MaxAgeDays (default defaultSessionMaxAge). Sessions with a live
+// runtime are kept even when idle, so an active chat never loses its store entry.
+func (m *runtimeManager) expireSessions(ctx context.Context, cfg config.MaintenanceJobConfig) (string, error) {
+	maxAge := defaultSessionMaxAge
+	if cfg.MaxAgeDays > 0 {
+		maxAge = time.Duration(cfg.MaxAgeDays) * 24 * time.Hour
+	}
+	cutoff := time.Now().Add(-maxAge)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.store == nil {
+		return "", errors.New("session store is closed")
+	}
+
+	summaries, err := m.store.List(ctx)
+	if err != nil {
+		return "", fmt.Errorf("list sessions: %w", err)
+	}
+	deleted := 0
+	for _, summary := range summaries {
+		sessionKey, ok := strings.CutPrefix(summary.ID, memoryStoreID(""))
+		if !ok || summary.UpdatedAt.After(cutoff) {
+			continue
+		}
+		if _, live := m.runtimes[sessionKey]; live {
+			continue
+		}
+		if err := m.store.Delete(ctx, summary.ID); err != nil {
+			return "", fmt.Errorf("delete session %s: %w", summary.ID, err)
+		}
+		deleted++
+	}
+
+	summary := fmt.Sprintf("deleted %d of %d stored sessions idle since %s", deleted, len(summaries), cutoff.UTC().Format(time.RFC3339))
+	if m.sessionMap != nil {
+		forgotten, err := m.sessionMap.prune(cutoff, func(sessionKey string) bool {
+			_, live := m.runtimes[sessionKey]
+			return live
+		})
+		if err != nil {
+			return "", fmt.Errorf("prune session map: %w", err)
+		}
+		summary += fmt.Sprintf("; forgot %d saved provider sessions", forgotten)
+	}
+
+	return summary, nil
+}
+
+// agentSessionKey derives the session key a named agent uses within a channel session.
+func agentSessionKey(sessionKey string, name string) string {
+	return sessionKey + "@" + name
+}
+
+// sessionWorkspaceName names the workspace directory of sessionKey, shared by
+// the named agents of its chat: "telegram:100@coder" becomes "telegram-100".
+//
+// The name is an escaping, so distinct chats never share a directory: ":"
+// becomes "-", ASCII letters and digits and "." (except a leading one) stay,
+// and every other byte becomes "_" and two hex digits. A name longer than
+// maxSessionWorkspaceName is cut short and ends in a hash of the whole key.
+func (m *runtimeManager) sessionWorkspaceName(sessionKey string) string {
+	if at := strings.LastIndex(sessionKey, "@"); at >= 0 {
+		if _, ok := m.agents[sessionKey[at+1:]]; ok {
+			sessionKey = sessionKey[:at]
+		}
+	}
+	if sessionKey == "" {
+		return "_"
+	}
+
+	var name strings.Builder
+	for i := range len(sessionKey) {
+		c := sessionKey[i]
+		switch {
+		case c == ':':
+			name.WriteByte('-')
+		case c < unicode.MaxASCII && (unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))), c == '.' && i > 0:
+			name.WriteByte(c)
+		default:
+			fmt.Fprintf(&name, "_%02x", c)
+		}
+	}
+	if name.Len() <= maxSessionWorkspaceName {
+		return name.String()
+	}
+	sum := sha256.Sum256([]byte(sessionKey))
+
+	return name.String()[:maxSessionWorkspaceName-17] + "_" + hex.EncodeToString(sum[:8])
+}
+
+// memoryStoreID namespaces gateway transcripts so they never collide with provider session IDs.
+func memoryStoreID(sessionKey string) string {
+	return "gateway:" + sessionKey
 }
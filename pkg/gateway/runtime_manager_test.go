@@ -2,11 +2,20 @@ package gateway
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	agentruntime "miniclaw/pkg/agent/runtime"
+	"miniclaw/pkg/bus"
 	"miniclaw/pkg/config"
+	"miniclaw/pkg/provider"
 	providertypes "miniclaw/pkg/provider/types"
+	"miniclaw/pkg/store"
 )
 
 type fakeProviderClient struct {
@@ -14,6 +23,9 @@ type fakeProviderClient struct {
 	createSessionCount int
 	promptCount        int
 	prompts            []string
+	models             []string
+	agents             []string
+	systems            []string
 }
 
 func (f *fakeProviderClient) Health(context.Context) error {
@@ -27,11 +39,14 @@ func (f *fakeProviderClient) CreateSession(context.Context, string) (string, err
 	return "session-id", nil
 }
 
-func (f *fakeProviderClient) Prompt(_ context.Context, _ string, prompt string, _ string, _ string, _ string) (providertypes.PromptResult, error) {
+func (f *fakeProviderClient) Prompt(_ context.Context, _ string, prompt string, model string, agent string, system string) (providertypes.PromptResult, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	f.promptCount++
 	f.prompts = append(f.prompts, prompt)
+	f.models = append(f.models, model)
+	f.agents = append(f.agents, agent)
+	f.systems = append(f.systems, system)
 	return providertypes.PromptResult{Text: "ok:" + prompt}, nil
 }
 
@@ -95,3 +110,547 @@ func TestRuntimeManagerCreatesSessionPerSessionKey(t *testing.T) {
 		t.Fatalf("createSessionCount = %d, want 2", fakeClient.createSessionCount)
 	}
 }
+
+func TestHandleInboundRoutesNamedAgents(t *testing.T) {
+	t.Parallel()
+
+	fakeClient := &fakeProviderClient{}
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5-nano"},
+			Named: []config.NamedAgentConfig{
+				{Name: "Coder", Model: "openai/gpt-5.2", Instructions: "Answer with code."},
+				{Name: "notes", Description: "Summarizes notes"},
+			},
+		},
+	}
+
+	manager, err := newRuntimeManager(context.Background(), cfg, fakeClient, nil)
+	if err != nil {
+		t.Fatalf("newRuntimeManager error: %v", err)
+	}
+	t.Cleanup(manager.Close)
+	svc := &Service{manager: manager}
+
+	inbound := func(content string) bus.OutboundMessage {
+		t.Helper()
+		outbound, err := svc.handleInbound(context.Background(), bus.InboundMessage{Channel: "telegram", ChatID: "100", SessionKey: "telegram:100", Content: content})
+		if err != nil {
+			t.Fatalf("handleInbound(%q) error: %v", content, err)
+		}
+		return outbound
+	}
+
+	coder := inbound("!coder fix this")
+	if coder.Content != "ok:fix this" || coder.SessionKey != "telegram:100@coder" || agentruntime.ReadMetadata(coder).Agent() != "coder" {
+		t.Fatalf("unexpected coder outbound: %+v", coder)
+	}
+	plain := inbound("hello")
+	if plain.Content != "ok:hello" || plain.SessionKey != "telegram:100" {
+		t.Fatalf("unexpected default outbound: %+v", plain)
+	}
+	unknown := inbound("!chef cook")
+	if !strings.Contains(unknown.Content, `Unknown agent "chef"`) || !strings.Contains(unknown.Content, "!notes: Summarizes notes") {
+		t.Fatalf("unexpected unknown-agent reply: %q", unknown.Content)
+	}
+	if usage := inbound("!notes"); usage.Content != "Usage: !notes <message>" {
+		t.Fatalf("unexpected usage reply: %q", usage.Content)
+	}
+
+	fakeClient.mu.Lock()
+	defer fakeClient.mu.Unlock()
+	if fakeClient.createSessionCount != 2 {
+		t.Fatalf("createSessionCount = %d, want 2 (default and coder)", fakeClient.createSessionCount)
+	}
+	if fakeClient.models[0] != "openai/gpt-5.2" || fakeClient.models[1] != "openai/gpt-5-nano" {
+		t.Fatalf("models = %v, want coder override then default", fakeClient.models)
+	}
+	if !strings.HasSuffix(fakeClient.systems[0], "Answer with code.") || strings.Contains(fakeClient.systems[1], "Answer with code.") {
+		t.Fatalf("unexpected system prompts: %q", fakeClient.systems)
+	}
+}
+
+// tracingProviderClient records the trace ID each prompt reaches the provider with.
+type tracingProviderClient struct {
+	fakeProviderClient
+	traceIDs []string
+}
+
+func (f *tracingProviderClient) Prompt(ctx context.Context, sessionID string, prompt string, model string, agent string, system string) (providertypes.PromptResult, error) {
+	f.mu.Lock()
+	f.traceIDs = append(f.traceIDs, providertypes.TraceIDFromContext(ctx))
+	f.mu.Unlock()
+	return f.fakeProviderClient.Prompt(ctx, sessionID, prompt, model, agent, system)
+}
+
+func TestHandleInboundCarriesTraceID(t *testing.T) {
+	t.Parallel()
+
+	fakeClient := &tracingProviderClient{}
+	cfg := &config.Config{Agents: config.AgentsConfig{Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5-nano"}}}
+	manager, err := newRuntimeManager(context.Background(), cfg, fakeClient, nil)
+	if err != nil {
+		t.Fatalf("newRuntimeManager error: %v", err)
+	}
+	t.Cleanup(manager.Close)
+	svc := &Service{manager: manager}
+	events, unsubscribe := manager.events.SubscribeEvents(context.Background(), 8)
+	defer unsubscribe()
+
+	inbound := bus.InboundMessage{
+		Channel:    "telegram",
+		ChatID:     "100",
+		SessionKey: "telegram:100",
+		Content:    "hello",
+		Metadata:   map[string]string{agentruntime.TraceIDKey: "trace-1"},
+	}
+	outbound, err := svc.handleInbound(context.Background(), inbound)
+	if err != nil {
+		t.Fatalf("handleInbound error: %v", err)
+	}
+	if traceID := agentruntime.ReadMetadata(outbound).TraceID(); traceID != "trace-1" {
+		t.Fatalf("outbound trace ID = %q, want trace-1", traceID)
+	}
+	fakeClient.mu.Lock()
+	if len(fakeClient.traceIDs) != 1 || fakeClient.traceIDs[0] != "trace-1" {
+		t.Fatalf("provider trace IDs = %v", fakeClient.traceIDs)
+	}
+	fakeClient.mu.Unlock()
+	// session_created, prompt_received, and prompt_completed.
+	for range 3 {
+		if event := <-events; event.TraceID != "trace-1" {
+			t.Fatalf("event = %+v, want trace-1", event)
+		}
+	}
+
+	// Messages that arrive untraced get an ID, replies to commands included.
+	inbound.Metadata = nil
+	inbound.Content = "/stop"
+	stopped, err := svc.handleInbound(context.Background(), inbound)
+	if err != nil {
+		t.Fatalf("handleInbound error: %v", err)
+	}
+	if traceID := agentruntime.ReadMetadata(stopped).TraceID(); len(traceID) != 16 {
+		t.Fatalf("assigned trace ID = %q, want 16 hex characters", traceID)
+	}
+}
+
+// blockingProviderClient holds each prompt until its context ends.
+type blockingProviderClient struct {
+	fakeProviderClient
+	started chan struct{}
+}
+
+func (b *blockingProviderClient) Prompt(ctx context.Context, _ string, _ string, _ string, _ string, _ string) (providertypes.PromptResult, error) {
+	b.started <- struct{}{}
+	<-ctx.Done()
+	return providertypes.PromptResult{}, ctx.Err()
+}
+
+func TestStopCommandCancelsRunningPrompt(t *testing.T) {
+	t.Parallel()
+
+	client := &blockingProviderClient{started: make(chan struct{}, 1)}
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5-nano"},
+			Named:    []config.NamedAgentConfig{{Name: "coder"}},
+		},
+	}
+	manager, err := newRuntimeManager(context.Background(), cfg, client, nil)
+	if err != nil {
+		t.Fatalf("newRuntimeManager error: %v", err)
+	}
+	t.Cleanup(manager.Close)
+	svc := &Service{manager: manager}
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := svc.handleInbound(context.Background(), bus.InboundMessage{Channel: "telegram", ChatID: "100", SessionKey: "telegram:100", Content: "!coder long task"})
+		errCh <- err
+	}()
+	<-client.started
+
+	stop, err := svc.handleInbound(context.Background(), bus.InboundMessage{Channel: "telegram", ChatID: "100", SessionKey: "telegram:100", Content: "/stop@miniclaw_bot"})
+	if err != nil || stop.Content != "Stopped." {
+		t.Fatalf("stop reply = %q, %v; want Stopped.", stop.Content, err)
+	}
+	select {
+	case err := <-errCh:
+		if providertypes.ErrorCategoryOf(err) != providertypes.ErrorCanceled {
+			t.Fatalf("prompt error = %v, want canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("prompt was not canceled")
+	}
+
+	again, err := svc.handleInbound(context.Background(), bus.InboundMessage{Channel: "telegram", ChatID: "100", SessionKey: "telegram:100", Content: "/stop"})
+	if err != nil || again.Content != "Nothing to stop." {
+		t.Fatalf("second stop reply = %q, %v; want Nothing to stop.", again.Content, err)
+	}
+}
+
+func TestNewRuntimeManagerRejectsInvalidNamedAgents(t *testing.T) {
+	t.Parallel()
+
+	for _, named := range [][]config.NamedAgentConfig{
+		{{Name: ""}},
+		{{Name: "two words"}},
+		{{Name: "coder"}, {Name: "CODER"}},
+		{{Name: "coder", Provider: "anthropic"}},
+	} {
+		cfg := &config.Config{Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5-nano"},
+			Named:    named,
+		}}
+		if _, err := newRuntimeManager(context.Background(), cfg, &fakeProviderClient{}, nil); err == nil {
+			t.Fatalf("expected error for named agents %+v", named)
+		}
+	}
+}
+
+func TestNamedProfilesWithOwnClientAndChannelDefault(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5-nano", Instructions: "Be kind."},
+			Named: []config.NamedAgentConfig{
+				{Name: "coder", Provider: "anthropic", Model: "anthropic/claude-sonnet-4-5", Temperature: 0.2, Tools: []string{"read_file"}, Instructions: "Answer with code."},
+			},
+		},
+		Gateway: config.GatewayConfig{ChannelAgents: map[string]string{"telegram": "coder"}},
+	}
+	defaultClient := &fakeProviderClient{}
+	manager, err := newRuntimeManager(context.Background(), cfg, defaultClient, nil)
+	if err != nil {
+		t.Fatalf("newRuntimeManager error: %v", err)
+	}
+	t.Cleanup(manager.Close)
+
+	var profileCfg *config.Config
+	coderClient := &fakeProviderClient{}
+	manager.newClient = func(clientCfg *config.Config) (provider.Client, error) {
+		profileCfg = clientCfg
+		return coderClient, nil
+	}
+	svc := &Service{manager: manager}
+
+	for _, inbound := range []bus.InboundMessage{
+		{Channel: "telegram", ChatID: "1", SessionKey: "telegram:1", Content: "fix this"},
+		{Channel: "telegram", ChatID: "1", SessionKey: "telegram:1", Content: "!coder and this"},
+		{Channel: "cli", ChatID: "1", SessionKey: "cli:1", Content: "hello"},
+	} {
+		if _, err := svc.handleInbound(context.Background(), inbound); err != nil {
+			t.Fatalf("handleInbound(%q) error: %v", inbound.Content, err)
+		}
+	}
+
+	if coderClient.promptCount != 2 || defaultClient.promptCount != 1 {
+		t.Fatalf("prompts coder = %d, default = %d; want 2 and 1", coderClient.promptCount, defaultClient.promptCount)
+	}
+	defaults := profileCfg.Agents.Defaults
+	if defaults.Provider != "anthropic" || defaults.Temperature != 0.2 || !slices.Equal(defaults.Tools, []string{"read_file"}) {
+		t.Fatalf("profile client defaults = %+v", defaults)
+	}
+	if coderClient.models[0] != "anthropic/claude-sonnet-4-5" || !strings.HasSuffix(coderClient.systems[0], "Be kind.\n\nAnswer with code.") {
+		t.Fatalf("coder model = %q, system = %q", coderClient.models[0], coderClient.systems[0])
+	}
+	if cfg.Agents.Defaults.Provider != "openai" {
+		t.Fatalf("shared config provider changed to %q", cfg.Agents.Defaults.Provider)
+	}
+
+	cfg.Gateway.ChannelAgents = map[string]string{"telegram": "chef"}
+	if _, err := newRuntimeManager(context.Background(), cfg, defaultClient, nil); err == nil {
+		t.Fatal("expected error for an unknown channel agent")
+	}
+}
+
+func TestChatAgentOverridesChannelAgent(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5.2"},
+			Named: []config.NamedAgentConfig{
+				{Name: "cheap", Model: "openai/gpt-5-nano"},
+				{Name: "strong", Model: "openai/gpt-5"},
+			},
+		},
+		Gateway: config.GatewayConfig{ChannelAgents: map[string]string{"telegram": "cheap", "telegram:1": "strong"}},
+	}
+	client := &fakeProviderClient{}
+	manager, err := newRuntimeManager(context.Background(), cfg, client, nil)
+	if err != nil {
+		t.Fatalf("newRuntimeManager error: %v", err)
+	}
+	t.Cleanup(manager.Close)
+	svc := &Service{manager: manager}
+
+	for _, inbound := range []bus.InboundMessage{
+		{Channel: "telegram", ChatID: "1", SessionKey: "telegram:1", Content: "admin question"},
+		{Channel: "telegram", ChatID: "2", SessionKey: "telegram:2", Content: "family question"},
+		{Channel: "http", ChatID: "x", SessionKey: "http:x", Content: "script question"},
+	} {
+		if _, err := svc.handleInbound(context.Background(), inbound); err != nil {
+			t.Fatalf("handleInbound(%q) error: %v", inbound.Content, err)
+		}
+	}
+
+	if want := []string{"openai/gpt-5", "openai/gpt-5-nano", "openai/gpt-5.2"}; !slices.Equal(client.models, want) {
+		t.Fatalf("models = %v, want %v", client.models, want)
+	}
+}
+
+// agedStore reports every stored session as last updated at updatedAt.
+type agedStore struct {
+	store.SessionStore
+	updatedAt time.Time
+}
+
+func (s agedStore) List(ctx context.Context) ([]store.Summary, error) {
+	summaries, err := s.SessionStore.List(ctx)
+	for i := range summaries {
+		summaries[i].UpdatedAt = s.updatedAt
+	}
+	return summaries, err
+}
+
+func TestExpireSessionsDeletesIdleGatewaySessions(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5-nano"}},
+	}
+	manager, err := newRuntimeManager(context.Background(), cfg, &fakeProviderClient{}, nil)
+	if err != nil {
+		t.Fatalf("newRuntimeManager error: %v", err)
+	}
+	t.Cleanup(manager.Close)
+
+	ctx := context.Background()
+	if _, err := manager.Prompt(ctx, "telegram:live", "hello"); err != nil {
+		t.Fatalf("Prompt error: %v", err)
+	}
+	for _, id := range []string{memoryStoreID("telegram:idle"), "cli-session"} {
+		if _, err := manager.store.Create(ctx, id, ""); err != nil {
+			t.Fatalf("Create(%s) error: %v", id, err)
+		}
+	}
+	manager.store = agedStore{SessionStore: manager.store, updatedAt: time.Now().Add(-10 * 24 * time.Hour)}
+
+	result, err := manager.expireSessions(ctx, config.MaintenanceJobConfig{Task: "session_expiry", MaxAgeDays: 7})
+	if err != nil {
+		t.Fatalf("expireSessions error: %v", err)
+	}
+	if !strings.HasPrefix(result, "deleted 1 of 3 stored sessions") {
+		t.Fatalf("result = %q, want one of three deleted", result)
+	}
+	if _, err := manager.store.Load(ctx, memoryStoreID("telegram:idle")); err != store.ErrNotFound {
+		t.Fatalf("idle session Load error = %v, want ErrNotFound", err)
+	}
+	for _, id := range []string{memoryStoreID("telegram:live"), "cli-session"} {
+		if _, err := manager.store.Load(ctx, id); err != nil {
+			t.Fatalf("Load(%s) error = %v, want kept", id, err)
+		}
+	}
+
+	result, err = manager.expireSessions(ctx, config.MaintenanceJobConfig{Task: "session_expiry", MaxAgeDays: 30})
+	if err != nil {
+		t.Fatalf("expireSessions error: %v", err)
+	}
+	if !strings.HasPrefix(result, "deleted 0 of 2") {
+		t.Fatalf("result = %q, want nothing deleted within max age", result)
+	}
+}
+
+func TestRuntimeManagerProvisionsSessionWorkspaces(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5-nano", Workspace: root, RestrictToWorkspace: true},
+			Named:    []config.NamedAgentConfig{{Name: "coder"}},
+		},
+		Gateway: config.GatewayConfig{SessionWorkspaces: config.GatewaySessionWorkspacesConfig{Enabled: true}},
+	}
+
+	manager, err := newRuntimeManager(context.Background(), cfg, &fakeProviderClient{}, nil)
+	if err != nil {
+		t.Fatalf("newRuntimeManager error: %v", err)
+	}
+	t.Cleanup(manager.Close)
+
+	var workspaces []string
+	clients := make(map[string]*fakeProviderClient)
+	manager.newClient = func(sessionCfg *config.Config) (provider.Client, error) {
+		workspaces = append(workspaces, sessionCfg.Agents.Defaults.Workspace)
+		client := &fakeProviderClient{}
+		clients[sessionCfg.Agents.Defaults.Workspace] = client
+		return client, nil
+	}
+
+	for _, prompt := range []struct{ agent, key string }{{"", "telegram:100"}, {"coder", "telegram:100"}, {"", "telegram:200"}} {
+		if _, err := manager.PromptAgent(context.Background(), prompt.agent, prompt.key, "hi"); err != nil {
+			t.Fatalf("PromptAgent(%q, %q) error: %v", prompt.agent, prompt.key, err)
+		}
+	}
+
+	want := []string{filepath.Join(root, "sessions", "telegram-100"), filepath.Join(root, "sessions", "telegram-200")}
+	if !slices.Equal(workspaces, want) {
+		t.Fatalf("workspaces = %v, want %v", workspaces, want)
+	}
+	for _, dir := range want {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			t.Fatalf("session workspace %s not provisioned: %v", dir, err)
+		}
+	}
+	if got := clients[want[0]].promptCount; got != 2 {
+		t.Fatalf("telegram:100 client prompts = %d, want 2 (default and named agent)", got)
+	}
+	if cfg.Agents.Defaults.Workspace != root {
+		t.Fatalf("shared config workspace changed to %q", cfg.Agents.Defaults.Workspace)
+	}
+}
+
+func TestSessionWorkspaceName(t *testing.T) {
+	t.Parallel()
+
+	manager := &runtimeManager{agents: map[string]namedAgent{"coder": {name: "coder"}}}
+	for key, want := range map[string]string{
+		"telegram:100":       "telegram-100",
+		"telegram:100@coder": "telegram-100",
+		"http:bob@example":   "http-bob_40example",
+		"../../etc":          "_2e._2f.._2fetc",
+		"":                   "_",
+	} {
+		if got := manager.sessionWorkspaceName(key); got != want {
+			t.Fatalf("sessionWorkspaceName(%q) = %q, want %q", key, got, want)
+		}
+	}
+
+	long := "http:" + strings.Repeat("x", 200)
+	name := manager.sessionWorkspaceName(long)
+	if len(name) != maxSessionWorkspaceName || !strings.HasPrefix(name, "http-xxx") || name == manager.sessionWorkspaceName(long+"y") {
+		t.Fatalf("sessionWorkspaceName(long key) = %q, want %d bytes ending in a hash of the key", name, maxSessionWorkspaceName)
+	}
+
+	// Keys the old "replace with -" sanitizer mapped to one directory stay apart.
+	seen := make(map[string]string)
+	for _, key := range []string{"http:al:ice-x", "http:al-ice:x", "http:al_ice:x", "http:al ice:x", "http:al-ice-x", "telegram:100", "telegram-100"} {
+		name := manager.sessionWorkspaceName(key)
+		if other, ok := seen[name]; ok {
+			t.Fatalf("%q and %q share the workspace %q", key, other, name)
+		}
+		seen[name] = key
+	}
+}
+
+func TestSessionWorkspacesKeepCollidingKeysApart(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	cfg := &config.Config{
+		Agents:  config.AgentsConfig{Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5-nano", Workspace: root, RestrictToWorkspace: true}},
+		Gateway: config.GatewayConfig{SessionWorkspaces: config.GatewaySessionWorkspacesConfig{Enabled: true}},
+	}
+	manager, err := newRuntimeManager(context.Background(), cfg, &fakeProviderClient{}, nil)
+	if err != nil {
+		t.Fatalf("newRuntimeManager error: %v", err)
+	}
+	t.Cleanup(manager.Close)
+	var workspaces []string
+	manager.newClient = func(cfg *config.Config) (provider.Client, error) {
+		workspaces = append(workspaces, cfg.Agents.Defaults.Workspace)
+		return &fakeProviderClient{}, nil
+	}
+
+	for _, key := range []string{"http:al:ice-x", "http:al-ice:x"} {
+		if _, err := manager.Prompt(context.Background(), key, "hello"); err != nil {
+			t.Fatalf("Prompt(%q) error: %v", key, err)
+		}
+	}
+	if len(workspaces) != 2 || workspaces[0] == workspaces[1] {
+		t.Fatalf("workspaces = %q, want one client and directory per session", workspaces)
+	}
+}
+
+// fillingProviderClient reports a context window that fills by 30% per prompt.
+type fillingProviderClient struct {
+	fakeProviderClient
+}
+
+func (f *fillingProviderClient) Prompt(ctx context.Context, sessionID string, prompt string, model string, agent string, system string) (providertypes.PromptResult, error) {
+	result, err := f.fakeProviderClient.Prompt(ctx, sessionID, prompt, model, agent, system)
+	f.mu.Lock()
+	used := int64(f.promptCount) * 30_000
+	f.mu.Unlock()
+	result.Metadata.Context = providertypes.ContextBudget{}.Usage("claude-sonnet-4-5", used, false)
+	return result, err
+}
+
+func TestRuntimeManagerWarnsOnceWhenContextNearlyFull(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{Defaults: config.AgentDefaults{Provider: "anthropic", Model: "anthropic/claude-sonnet-4-5"}},
+	}
+	manager, err := newRuntimeManager(context.Background(), cfg, &fillingProviderClient{}, nil)
+	if err != nil {
+		t.Fatalf("newRuntimeManager error: %v", err)
+	}
+	t.Cleanup(manager.Close)
+
+	var warned []int
+	for turn := 1; turn <= 7; turn++ {
+		result, err := manager.Prompt(context.Background(), "telegram:100", "more")
+		if err != nil {
+			t.Fatalf("Prompt error: %v", err)
+		}
+		if strings.Contains(result.Text, "context window") {
+			warned = append(warned, turn)
+		}
+	}
+	// 30000 tokens per turn of a 200000 window reaches 80% on turn 6.
+	if len(warned) != 1 || warned[0] != 6 {
+		t.Fatalf("warned on turns %v, want only turn 6", warned)
+	}
+
+	_, stats, _ := manager.Snapshot("telegram:100")
+	if stats.Context == nil || stats.Context.Percent() != 105 || !stats.Context.NearlyFull {
+		t.Fatalf("context = %+v, want 105%% and nearly full", stats.Context)
+	}
+}
+
+func TestRuntimeManagerAppliesMiddleware(t *testing.T) {
+	t.Parallel()
+
+	fakeClient := &fakeProviderClient{}
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5-nano"}},
+	}
+
+	manager, err := newRuntimeManager(context.Background(), cfg, fakeClient, nil)
+	if err != nil {
+		t.Fatalf("newRuntimeManager error: %v", err)
+	}
+	t.Cleanup(manager.Close)
+	manager.middleware = []agentruntime.Middleware{
+		agentruntime.PrePrompt(func(_ context.Context, prompt string) (string, error) {
+			return "[template] " + prompt, nil
+		}),
+		agentruntime.PostResponse(func(_ context.Context, _ string, result providertypes.PromptResult) (providertypes.PromptResult, error) {
+			result.Text += " (checked)"
+			return result, nil
+		}),
+	}
+
+	result, err := manager.Prompt(context.Background(), "telegram:100", "hello")
+	if err != nil {
+		t.Fatalf("Prompt error: %v", err)
+	}
+	if result.Text != "ok:[template] hello (checked)" {
+		t.Fatalf("result text = %q, want %q", result.Text, "ok:[template] hello (checked)")
+	}
+}
@@ -2,10 +2,12 @@ package gateway
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
@@ -16,12 +18,20 @@ import (
 	"miniclaw/pkg/bus"
 	"miniclaw/pkg/channel"
 	"miniclaw/pkg/config"
+	"miniclaw/pkg/cron"
 	"miniclaw/pkg/provider"
+	providerfantasy "miniclaw/pkg/provider/fantasy"
+	providertypes "miniclaw/pkg/provider/types"
+	"miniclaw/pkg/retrieval"
+	"miniclaw/pkg/telemetry"
 )
 
 const (
 	defaultHealthHost = "0.0.0.0"
 	defaultHealthPort = 18790
+
+	// channelHealthTimeout bounds one adapter's probe in checkChannelHealth.
+	channelHealthTimeout = 10 * time.Second
 )
 
 // Service coordinates channel adapters, runtime routing, and health endpoints.
@@ -31,18 +41,60 @@ type Service struct {
 	provider provider.Client
 	manager  *runtimeManager
 	channels []channel.Adapter
+	cron     *cron.Scheduler
+	// approvals queues tool approvals for /admin/approvals; nil when gateway.approvals is off.
+	approvals *approvalQueue
+	// exporters push manager telemetry to StatsD and OTLP; Prometheus is served by statusHandler.
+	exporters []telemetry.Exporter
+	// statusTLS serves the status server over HTTPS; nil when gateway.tls is off.
+	statusTLS *tls.Config
+	// webhooks send selected gateway events to gateway.webhooks URLs.
+	webhooks []*webhookSender
+	// cluster forwards messages to the instance owning their session; nil when gateway.cluster is off.
+	cluster *gatewayCluster
+	// journal holds channel messages until they are answered; nil unless bus.backend is sqlite.
+	journal bus.Journal
+
+	// policyMu guards the middleware dispatchInbound wraps around channel
+	// messages: channels.templates and channels.policy, which Reload
+	// replaces, and UseChannel's.
+	policyMu          sync.RWMutex
+	templates         channel.Middleware
+	policyCfg         config.ChannelPolicyConfig
+	policy            []channel.Middleware
+	channelMiddleware []channel.Middleware
+
+	// reloadMu guards the channels Run started, which Reload starts and stops.
+	reloadMu    sync.Mutex
+	runCtx      context.Context
+	channelErrs chan error
+	running     map[string]*runningChannel
 
 	mu               sync.RWMutex
 	startedAt        time.Time
 	providerLastOKAt time.Time
 	providerLastErr  string
 	channelStates    map[string]channelState
+	// notifiers are the running adapters Notify can push messages through, by channel.
+	notifiers map[string]channel.Notifier
+	// restartPolicy is channels.restart, which Reload replaces.
+	restartPolicy channelRestartPolicy
+	// providerChecks and recentErrors feed the /status page, oldest first.
+	providerChecks []providerCheck
+	recentErrors   []recentError
 }
 
 // channelState captures runtime status for one configured channel adapter.
 type channelState struct {
 	Running bool   `json:"running"`
 	Error   string `json:"error,omitempty"`
+	// HealthError is the last failed probe of a running adapter that implements channel.HealthChecker.
+	HealthError    string `json:"health_error,omitempty"`
+	LastActivityAt string `json:"last_activity_at,omitempty"`
+	// Restarts counts the times the gateway restarted the adapter after it failed.
+	Restarts int `json:"restarts,omitempty"`
+	// NextRestartAt is when a failed adapter is restarted; empty unless one is due.
+	NextRestartAt string `json:"next_restart_at,omitempty"`
 }
 
 // statusResponse is the JSON payload returned by health/readiness endpoints.
@@ -59,14 +111,14 @@ func NewService(ctx context.Context, cfg *config.Config, adapters []channel.Adap
 	if cfg == nil {
 		return nil, errors.New("config is required")
 	}
-	if len(adapters) == 0 {
+	if len(adapters) == 0 && !cfg.Gateway.Cluster.Enabled {
 		return nil, errors.New("at least one channel adapter is required")
 	}
 	if log == nil {
 		log = slog.Default()
 	}
 
-	client, err := provider.New(cfg)
+	client, err := newProviderClient(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("initialize provider: %w", err)
 	}
@@ -75,23 +127,197 @@ func NewService(ctx context.Context, cfg *config.Config, adapters []channel.Adap
 	if err != nil {
 		return nil, err
 	}
+	index, err := retrieval.Open(cfg)
+	if err != nil {
+		manager.Close()
+		return nil, err
+	}
+	if index != nil {
+		manager.middleware = append(manager.middleware, agentruntime.PrePrompt(index.Augment))
+	}
 
 	channelStates := make(map[string]channelState, len(adapters))
+	notifiers := make(map[string]channel.Notifier, len(adapters))
 	for _, adapter := range adapters {
 		channelStates[adapter.Name()] = channelState{}
+		if notifier, ok := adapter.(channel.Notifier); ok {
+			notifiers[adapter.Name()] = notifier
+		}
+	}
+
+	scheduler, err := cron.NewScheduler(cfg.Tools.Cron, manager.Prompt, notifiers, log)
+	if err == nil {
+		err = scheduler.AddMaintenance(cfg.Tools.Cron.Maintenance, map[string]cron.MaintenanceTask{
+			cron.TaskSessionExpiry:    manager.expireSessions,
+			cron.TaskUsageReconcile:   reconcileUsageTask(manager.usage, openAIUsageFetcher(cfg.Providers.OpenAI), time.Now),
+			cron.TaskActivityReport:   activityReportTask(cfg, manager.store, time.Now),
+			cron.TaskSnapshotPrune:    snapshotPruneTask(cfg, time.Now),
+			cron.TaskRetrievalReindex: retrievalReindexTask(index),
+			cron.TaskUsageCompact:     usageCompactTask(manager.accounting, time.Now),
+		}, manager.events)
+	}
+	if err != nil {
+		manager.Close()
+		return nil, fmt.Errorf("initialize cron scheduler: %w", err)
+	}
+
+	auth, err := channel.NewAuth(cfg.Gateway.AuthConfig())
+	if err == nil {
+		err = validateChannelPages(cfg.Gateway, adapters)
+	}
+	if err != nil {
+		manager.Close()
+		return nil, err
+	}
+	if strings.TrimSpace(cfg.Gateway.Approvals.Token) != "" {
+		log.Warn("gateway.approvals.token is deprecated; set gateway.auth.token instead")
+	}
+	approvals, err := newServiceApprovals(cfg.Gateway.Approvals, auth, notifiers, log)
+	if err != nil {
+		manager.Close()
+		return nil, err
 	}
 
-	return &Service{
+	if _, err := prometheusPath(cfg.Telemetry.Prometheus); err != nil {
+		manager.Close()
+		return nil, err
+	}
+	statusTLS, err := loadStatusTLS(cfg.Gateway.TLS)
+	if err != nil {
+		manager.Close()
+		return nil, err
+	}
+	webhooks, err := newWebhookSenders(cfg.Gateway.Webhooks, log)
+	if err != nil {
+		manager.Close()
+		return nil, err
+	}
+	exporters, err := telemetry.NewExporters(cfg.Telemetry)
+	if err != nil {
+		manager.Close()
+		return nil, fmt.Errorf("initialize telemetry: %w", err)
+	}
+	cluster, err := newGatewayCluster(cfg, log)
+	if err != nil {
+		manager.Close()
+		return nil, err
+	}
+	policy, err := newChannelPolicy(cfg.Channels.Policy)
+	if err != nil {
+		manager.Close()
+		return nil, err
+	}
+	restartPolicy, err := newChannelRestartPolicy(cfg.Channels.Restart)
+	if err != nil {
+		manager.Close()
+		return nil, err
+	}
+	templates, err := newChannelTemplates(cfg.Channels.Templates)
+	if err != nil {
+		manager.Close()
+		return nil, err
+	}
+	journal, err := bus.OpenJournal(cfg.Bus)
+	if err != nil {
+		manager.Close()
+		return nil, fmt.Errorf("open bus journal: %w", err)
+	}
+
+	svc := &Service{
 		cfg:           cfg,
 		log:           log.With("component", "gateway.service"),
 		provider:      client,
 		manager:       manager,
 		channels:      adapters,
+		cron:          scheduler,
+		approvals:     approvals,
+		exporters:     exporters,
+		statusTLS:     statusTLS,
+		webhooks:      webhooks,
+		cluster:       cluster,
+		journal:       journal,
 		channelStates: channelStates,
-	}, nil
+		policyCfg:     cfg.Channels.Policy,
+		policy:        policy,
+		restartPolicy: restartPolicy,
+	}
+	svc.setChannelTemplates(templates)
+
+	return svc, nil
+}
+
+// SubscribeEvents subscribes to the gateway's session, tool, heartbeat, and
+// channel events; see bus.MessageBus.SubscribeEvents. The channel closes when
+// the service stops.
+func (s *Service) SubscribeEvents(ctx context.Context, buffer int) (<-chan bus.Event, func()) {
+	return s.manager.events.SubscribeEvents(ctx, buffer)
 }
 
-// Run starts channel adapters, provider health checks, and the status HTTP server.
+// ReplayEvents streams the gateway events recorded in bus.event_log between
+// from and to; see bus.MessageBus.ReplayEvents.
+func (s *Service) ReplayEvents(ctx context.Context, from, to time.Time, buffer int) (<-chan bus.Event, error) {
+	return s.manager.events.ReplayEvents(ctx, from, to, buffer)
+}
+
+// Use adds middleware around every agent prompt the service runs, after any
+// added earlier; see agentruntime.Chain. Call it before Run.
+func (s *Service) Use(middleware ...agentruntime.Middleware) {
+	s.manager.middleware = append(s.manager.middleware, middleware...)
+}
+
+// newServiceApprovals validates gateway.approvals and builds the operator queue, or nil when it is off.
+func newServiceApprovals(cfg config.GatewayApprovalsConfig, auth *channel.Auth, notifiers map[string]channel.Notifier, log *slog.Logger) (*approvalQueue, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if !auth.Enabled() {
+		return nil, errors.New("gateway.auth is required when gateway.approvals is enabled")
+	}
+
+	var notifier channel.Notifier
+	chatID := strings.TrimSpace(cfg.NotifyTelegramChatID)
+	if chatID != "" {
+		var ok bool
+		if notifier, ok = notifiers["telegram"]; !ok {
+			return nil, errors.New("gateway.approvals.notify_telegram_chat_id requires the telegram channel")
+		}
+	}
+
+	return newApprovalQueue(notifier, chatID, log), nil
+}
+
+// prometheusPath returns the scrape path for telemetry.prometheus, rejecting
+// paths that would shadow the other status endpoints.
+func prometheusPath(cfg config.PrometheusTelemetryConfig) (string, error) {
+	path := strings.TrimSpace(cfg.Path)
+	if path == "" {
+		return telemetry.DefaultPrometheusPath, nil
+	}
+	if !strings.HasPrefix(path, "/") || strings.ContainsAny(path, " {}") {
+		return "", fmt.Errorf("telemetry.prometheus.path %q must be an absolute path", cfg.Path)
+	}
+	for _, reserved := range []string{"/healthz", "/readyz", "/status", "/events", "/v1/", "/admin/"} {
+		if path == strings.TrimSuffix(reserved, "/") || strings.HasPrefix(path, reserved) {
+			return "", fmt.Errorf("telemetry.prometheus.path %q conflicts with %s", cfg.Path, reserved)
+		}
+	}
+
+	return path, nil
+}
+
+// newProviderClient selects the fantasy client for fantasy-agent so gateway
+// sessions get the same tools as CLI mode; other types, and fixture replay
+// with the mock provider, use provider.New.
+func newProviderClient(cfg *config.Config) (provider.Client, error) {
+	if strings.EqualFold(strings.TrimSpace(cfg.Agents.Defaults.Type), "fantasy-agent") &&
+		!strings.EqualFold(strings.TrimSpace(cfg.Agents.Defaults.Provider), "mock") {
+		return providerfantasy.New(cfg)
+	}
+
+	return provider.New(cfg)
+}
+
+// Run starts channel adapters, provider health checks, webhook delivery, and the status HTTP server.
 func (s *Service) Run(ctx context.Context) error {
 	if ctx == nil {
 		ctx = context.Background()
@@ -105,6 +331,16 @@ func (s *Service) Run(ctx context.Context) error {
 		return err
 	}
 
+	errorEvents, unsubscribeErrors := s.SubscribeEvents(ctx, eventStreamBuffer)
+	defer unsubscribeErrors()
+	go s.recordRecentErrors(errorEvents)
+
+	if len(s.webhooks) > 0 {
+		events, unsubscribe := s.SubscribeEvents(ctx, eventStreamBuffer)
+		defer unsubscribe()
+		go s.runWebhooks(ctx, events)
+	}
+
 	serverErrors := make(chan error, 1)
 	go s.runHealthServer(ctx, serverErrors)
 
@@ -117,24 +353,42 @@ func (s *Service) Run(ctx context.Context) error {
 				return
 			case <-ticker.C:
 				_ = s.checkProviderHealth(ctx)
+				s.checkChannelHealth(ctx)
 			}
 		}
 	}()
 
-	errCh := make(chan error, len(s.channels))
-	for _, adapter := range s.channels {
-		adapter := adapter
-		s.setChannelState(adapter.Name(), channelState{Running: true})
-
+	if s.cron != nil {
 		go func() {
-			err := adapter.Run(ctx, s.handleInbound)
-			s.setChannelState(adapter.Name(), channelState{Running: false, Error: errorString(err)})
-			if err != nil && !errors.Is(err, context.Canceled) {
-				errCh <- fmt.Errorf("run %s channel: %w", adapter.Name(), err)
-			}
+			_ = s.cron.Run(ctx)
 		}()
 	}
 
+	for _, exporter := range s.exporters {
+		go telemetry.Push(ctx, s.manager.telemetry, exporter, s.log)
+	}
+
+	if s.cluster != nil {
+		defer s.cluster.bus.Close()
+		go s.serveCluster(ctx)
+		s.log.Info("Gateway cluster joined", "instance", s.cluster.self, "instances", strings.Join(s.cluster.instances, ","))
+	}
+
+	errCh := make(chan error, 1)
+	s.reloadMu.Lock()
+	s.runCtx = ctx
+	s.channelErrs = errCh
+	s.running = make(map[string]*runningChannel, len(s.channels))
+	for _, adapter := range s.channels {
+		s.startChannel(adapter)
+	}
+	s.reloadMu.Unlock()
+
+	if s.journal != nil {
+		defer s.journal.Close()
+		go s.replayJournal(ctx)
+	}
+
 	select {
 	case <-ctx.Done():
 		s.manager.Close()
@@ -148,48 +402,147 @@ func (s *Service) Run(ctx context.Context) error {
 	}
 }
 
-// handleInbound executes one inbound message through runtime manager prompt flow.
+// handleInbound answers one inbound message under its trace ID, which the reply
+// metadata repeats. In a cluster, a message whose session another instance
+// owns is forwarded to it.
 func (s *Service) handleInbound(ctx context.Context, inbound bus.InboundMessage) (bus.OutboundMessage, error) {
-	result, err := s.manager.Prompt(ctx, inbound.SessionKey, inbound.Content)
-	if err != nil {
+	// Adapters trace at ingress; this covers ones that do not.
+	traceID := channel.TraceInbound(&inbound)
+	ctx = providertypes.WithTraceID(ctx, traceID)
+
+	var outbound bus.OutboundMessage
+	var err error
+	if owner := s.sessionOwner(inbound.SessionKey); owner != "" {
+		outbound, err = s.cluster.forward(ctx, owner, inbound)
+	} else {
+		outbound, err = s.answerInbound(ctx, inbound)
+	}
+	if outbound.Metadata == nil {
+		outbound.Metadata = make(map[string]string, 1)
+	}
+	outbound.Metadata[agentruntime.TraceIDKey] = traceID
+
+	return outbound, err
+}
+
+// sessionOwner returns the cluster instance that owns sessionKey, or "" when
+// this instance answers it.
+func (s *Service) sessionOwner(sessionKey string) string {
+	if s.cluster == nil || sessionKey == "" {
+		return ""
+	}
+	if owner := s.cluster.owner(sessionKey); owner != s.cluster.self {
+		return owner
+	}
+
+	return ""
+}
+
+// answerInbound routes one inbound message to its named or default agent and prompts it.
+func (s *Service) answerInbound(ctx context.Context, inbound bus.InboundMessage) (bus.OutboundMessage, error) {
+	if reply, ok := s.answerCommand(ctx, inbound); ok {
 		return bus.OutboundMessage{
 			Channel:    inbound.Channel,
 			ChatID:     inbound.ChatID,
 			SessionKey: inbound.SessionKey,
+			Content:    reply,
+		}, nil
+	}
+
+	route := s.manager.routeInbound(inbound.Channel, inbound.SessionKey, inbound.Content)
+	if route.reply != "" {
+		return bus.OutboundMessage{
+			Channel:    inbound.Channel,
+			ChatID:     inbound.ChatID,
+			SessionKey: inbound.SessionKey,
+			Content:    route.reply,
+		}, nil
+	}
+
+	sessionKey := inbound.SessionKey
+	if route.agent != "" {
+		sessionKey = agentSessionKey(sessionKey, route.agent)
+	}
+
+	if s.approvals != nil {
+		ctx = providertypes.WithToolApprover(ctx, s.approvals.approver(inbound, sessionKey))
+	}
+	// Files the agent sends with send_file go out after the reply text.
+	outbox := &providertypes.FileOutbox{}
+	ctx = providertypes.WithFileOutbox(ctx, outbox)
+
+	result, err := s.manager.PromptAgent(ctx, route.agent, inbound.SessionKey, withAttachments(route.prompt, channel.Attachments(inbound)))
+	if err != nil {
+		return bus.OutboundMessage{
+			Channel:    inbound.Channel,
+			ChatID:     inbound.ChatID,
+			SessionKey: sessionKey,
 			Error:      err.Error(),
+			Metadata:   withAgent(agentruntime.PromptErrorMetadata(err), route.agent),
 		}, err
 	}
 
+	metadata := withAgent(agentruntime.PromptResultMetadata(result), route.agent)
+	if files := outbox.Paths(); len(files) > 0 {
+		if metadata == nil {
+			metadata = make(map[string]string, 1)
+		}
+		metadata[channel.MetadataFiles] = strings.Join(files, "\n")
+	}
+
 	return bus.OutboundMessage{
 		Channel:    inbound.Channel,
 		ChatID:     inbound.ChatID,
-		SessionKey: inbound.SessionKey,
+		SessionKey: sessionKey,
 		Content:    result.Text,
-		Metadata:   agentruntime.PromptResultMetadata(result),
+		Metadata:   metadata,
 	}, nil
 }
 
-// runHealthServer hosts /healthz and /readyz status endpoints.
-func (s *Service) runHealthServer(ctx context.Context, errCh chan<- error) {
-	host := strings.TrimSpace(s.cfg.Gateway.Host)
-	if host == "" {
-		host = defaultHealthHost
+// withAttachments tells the agent where the files a message carried were
+// saved, so it can read them with its file tools.
+func withAttachments(prompt string, paths []string) string {
+	if len(paths) == 0 {
+		return prompt
+	}
+	note := "The user sent these files, saved in the workspace:\n- " + strings.Join(paths, "\n- ")
+	if strings.TrimSpace(prompt) == "" {
+		return note
 	}
 
+	return prompt + "\n\n" + note
+}
+
+// withAgent records the named agent that handled a message in outbound metadata.
+func withAgent(metadata map[string]string, agent string) map[string]string {
+	if agent == "" {
+		return metadata
+	}
+	if metadata == nil {
+		metadata = make(map[string]string, 1)
+	}
+	metadata[agentruntime.AgentKey] = agent
+
+	return metadata
+}
+
+// runHealthServer hosts /healthz, /readyz, /status, /v1/sessions, /v1/metrics, and, when enabled, /events and /admin/approvals.
+func (s *Service) runHealthServer(ctx context.Context, errCh chan<- error) {
+	host := statusHost(s.cfg.Gateway)
+
 	port := s.cfg.Gateway.Port
 	if port <= 0 {
 		port = defaultHealthPort
 	}
 
 	addr := host + ":" + strconv.Itoa(port)
-	mux := http.NewServeMux()
-	mux.HandleFunc("/healthz", s.handleHealth)
-	mux.HandleFunc("/readyz", s.handleReady)
-
 	server := &http.Server{
 		Addr:              addr,
-		Handler:           mux,
+		Handler:           s.statusHandler(),
 		ReadHeaderTimeout: 5 * time.Second,
+		TLSConfig:         s.statusTLS,
+		// Ends open /events streams when the gateway stops.
+		BaseContext: func(net.Listener) context.Context { return ctx },
 	}
 
 	go func() {
@@ -199,12 +552,141 @@ func (s *Service) runHealthServer(ctx context.Context, errCh chan<- error) {
 		_ = server.Shutdown(shutdownCtx)
 	}()
 
-	s.log.Info("Gateway status server started", "address", addr)
-	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+	if auth, _ := channel.NewAuth(s.cfg.Gateway.AuthConfig()); !auth.Enabled() && !channel.IsLoopbackHost(host) {
+		s.log.Warn("Gateway status server has no authentication; set gateway.auth or bind gateway.host to 127.0.0.1", "address", addr)
+	}
+
+	s.log.Info("Gateway status server started", "address", addr, "tls", s.statusTLS != nil)
+	serve := server.ListenAndServe
+	if s.statusTLS != nil {
+		// The certificate is already in TLSConfig.
+		serve = func() error { return server.ListenAndServeTLS("", "") }
+	}
+	if err := serve(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		errCh <- fmt.Errorf("start status server: %w", err)
 	}
 }
 
+// statusHost returns the address the status server binds, applying the
+// default.
+func statusHost(cfg config.GatewayConfig) string {
+	host := strings.TrimSpace(cfg.Host)
+	if host == "" {
+		host = defaultHealthHost
+	}
+
+	return host
+}
+
+// statusHandler routes health, readiness, the status page, session introspection, metrics, usage, Prometheus, approval, session transfer, event stream, and event replay endpoints, plus channel webhooks and pages.
+func (s *Service) statusHandler() http.Handler {
+	// NewService has already validated gateway.auth.
+	auth, _ := channel.NewAuth(s.cfg.Gateway.AuthConfig())
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.handleHealth)
+	mux.HandleFunc("/readyz", s.handleReady)
+	mux.Handle("GET /status", auth.Require(http.HandlerFunc(s.handleStatusPage)))
+	mux.Handle("GET /v1/sessions/{key}", auth.Require(http.HandlerFunc(s.handleSession)))
+	mux.Handle("GET /v1/metrics", auth.Require(http.HandlerFunc(s.handleMetrics)))
+	mux.Handle("GET /v1/usage", auth.Require(http.HandlerFunc(s.handleUsage)))
+	if s.cfg.Gateway.Usage.Enabled {
+		mux.Handle("GET /v1/usage/report", auth.Require(http.HandlerFunc(s.handleUsageReport)))
+	}
+	if s.cfg.Telemetry.Prometheus.Enabled {
+		// NewService has already validated the path.
+		path, _ := prometheusPath(s.cfg.Telemetry.Prometheus)
+		mux.Handle("GET "+path, auth.Require(telemetry.PrometheusHandler(s.manager.telemetry)))
+	}
+	if s.approvals != nil {
+		mux.Handle("GET /admin/approvals", requireAdminAuth(auth, http.HandlerFunc(s.handleListApprovals)))
+		mux.Handle("POST /admin/approvals/{id}/approve", requireAdminAuth(auth, s.handleResolveApproval(true)))
+		mux.Handle("POST /admin/approvals/{id}/deny", requireAdminAuth(auth, s.handleResolveApproval(false)))
+	}
+	mux.Handle("GET /admin/sessions/{key}/export", requireAdminAuth(auth, http.HandlerFunc(s.handleExportSession)))
+	mux.Handle("POST /admin/sessions/{key}/import", requireAdminAuth(auth, http.HandlerFunc(s.handleImportSession)))
+	mux.Handle("GET /events", requireAdminAuth(auth, http.HandlerFunc(s.handleStreamEvents)))
+	mux.Handle("GET /admin/events", requireAdminAuth(auth, http.HandlerFunc(s.handleReplayEvents)))
+	return s.routeChannelPages(s.routeChannelWebhooks(mux))
+}
+
+// routeChannelWebhooks sends POST requests for the WebhookPath of a running
+// adapter implementing channel.WebhookReceiver to that adapter, without
+// status authentication, and everything else to next. Adapters are looked up
+// per request, so channels Reload starts are reachable too.
+func (s *Service) routeChannelWebhooks(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			if receiver := s.webhookReceiver(r.URL.Path); receiver != nil {
+				receiver.ServeHTTP(w, r)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// webhookReceiver returns the running adapter receiving webhooks on path, or nil.
+func (s *Service) webhookReceiver(path string) channel.WebhookReceiver {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	for _, running := range s.running {
+		if receiver, ok := running.adapter.(channel.WebhookReceiver); ok && receiver.WebhookPath() != "" && receiver.WebhookPath() == path {
+			return receiver
+		}
+	}
+
+	return nil
+}
+
+// routeChannelPages sends requests under the PagePath of a running adapter
+// implementing channel.PageServer to that adapter, which checks gateway.auth
+// itself, and everything else to next. Adapters are looked up per request, so
+// channels Reload starts are reachable too.
+func (s *Service) routeChannelPages(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if server := s.pageServer(r.URL.Path); server != nil {
+			server.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// pageServer returns the running adapter serving the pages under path, or nil.
+// The prefix without its trailing slash matches too, so it can redirect.
+func (s *Service) pageServer(path string) channel.PageServer {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	for _, running := range s.running {
+		if server, ok := running.adapter.(channel.PageServer); ok && server.PagePath() != "" && strings.HasPrefix(path+"/", server.PagePath()) {
+			return server
+		}
+	}
+
+	return nil
+}
+
+// validateChannelPages refuses adapters that serve pages on a status server
+// any host can reach without gateway.auth.
+func validateChannelPages(cfg config.GatewayConfig, adapters []channel.Adapter) error {
+	auth, err := channel.NewAuth(cfg.AuthConfig())
+	if err != nil {
+		return err
+	}
+	if auth.Enabled() || channel.IsLoopbackHost(statusHost(cfg)) {
+		return nil
+	}
+	for _, adapter := range adapters {
+		if server, ok := adapter.(channel.PageServer); ok && server.PagePath() != "" {
+			return fmt.Errorf("%s channel serves pages on the gateway status server; set gateway.auth or bind gateway.host to 127.0.0.1", adapter.Name())
+		}
+	}
+
+	return nil
+}
+
 // handleHealth always reports process liveness.
 func (s *Service) handleHealth(w http.ResponseWriter, _ *http.Request) {
 	s.respondStatus(w, http.StatusOK, "ok")
@@ -267,13 +749,14 @@ func (s *Service) isReady() bool {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	if len(s.channelStates) == 0 {
+	if len(s.channelStates) == 0 && s.cluster == nil {
 		return false
 	}
 
-	anyRunning := false
+	// A cluster instance without channels only answers forwarded messages.
+	anyRunning := len(s.channelStates) == 0
 	for _, state := range s.channelStates {
-		if state.Running {
+		if state.Running && state.HealthError == "" {
 			anyRunning = true
 			break
 		}
@@ -295,22 +778,78 @@ func (s *Service) isReady() bool {
 }
 
 // checkProviderHealth updates provider status state from a live health request.
+// It publishes provider_down and provider_recovered when the result changes.
 func (s *Service) checkProviderHealth(ctx context.Context) error {
 	if err := s.provider.Health(ctx); err != nil {
 		s.mu.Lock()
+		wasHealthy := s.providerLastErr == ""
 		s.providerLastErr = err.Error()
+		s.recordProviderCheckLocked(err)
 		s.mu.Unlock()
+		if wasHealthy {
+			_ = s.manager.events.PublishEvent(ctx, bus.Event{Type: bus.EventProviderDown, Error: err.Error()})
+		}
 		return fmt.Errorf("provider health check failed: %w", err)
 	}
 
 	s.mu.Lock()
+	wasDown := s.providerLastErr != ""
 	s.providerLastErr = ""
 	s.providerLastOKAt = time.Now().UTC()
+	s.recordProviderCheckLocked(nil)
 	s.mu.Unlock()
+	if wasDown {
+		_ = s.manager.events.PublishEvent(ctx, bus.Event{Type: bus.EventProviderRecovered})
+	}
 
 	return nil
 }
 
+// checkChannelHealth probes running adapters that implement
+// channel.HealthChecker, so a receive loop that failed without Run returning
+// stops counting toward readiness.
+func (s *Service) checkChannelHealth(ctx context.Context) {
+	s.reloadMu.Lock()
+	checkers := make(map[string]channel.HealthChecker, len(s.running))
+	for name, running := range s.running {
+		if checker, ok := running.adapter.(channel.HealthChecker); ok {
+			checkers[name] = checker
+		}
+	}
+	s.reloadMu.Unlock()
+
+	for name, checker := range checkers {
+		probeCtx, cancel := context.WithTimeout(ctx, channelHealthTimeout)
+		lastActivity, err := checker.CheckHealth(probeCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		s.mu.Lock()
+		state, ok := s.channelStates[name]
+		if !ok || !state.Running {
+			// Stopped or disabled while the probe ran.
+			s.mu.Unlock()
+			continue
+		}
+		wasHealthy := state.HealthError == ""
+		state.HealthError = errorString(err)
+		if !lastActivity.IsZero() {
+			state.LastActivityAt = lastActivity.UTC().Format(time.RFC3339)
+		}
+		s.channelStates[name] = state
+		s.mu.Unlock()
+
+		switch {
+		case err != nil && wasHealthy:
+			s.log.Warn("Channel health check failed", "channel", name, "error", err)
+		case err == nil && !wasHealthy:
+			s.log.Info("Channel health recovered", "channel", name)
+		}
+	}
+}
+
 // setChannelState updates state for one channel adapter.
 func (s *Service) setChannelState(name string, state channelState) {
 	s.mu.Lock()
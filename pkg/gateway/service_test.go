@@ -1,10 +1,17 @@
 package gateway
 
 import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
 	"testing"
 	"time"
 
 	agentruntime "miniclaw/pkg/agent/runtime"
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/channel"
+	"miniclaw/pkg/config"
 	providertypes "miniclaw/pkg/provider/types"
 )
 
@@ -27,6 +34,46 @@ func TestIsReady(t *testing.T) {
 	}
 }
 
+// probedAdapter is a scripted adapter whose health probe returns err.
+type probedAdapter struct {
+	scriptedAdapter
+
+	lastActivity time.Time
+	err          error
+}
+
+func (a *probedAdapter) CheckHealth(context.Context) (time.Time, error) {
+	return a.lastActivity, a.err
+}
+
+func TestCheckChannelHealthGatesReadiness(t *testing.T) {
+	t.Parallel()
+
+	lastActivity := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	telegram := &probedAdapter{scriptedAdapter: scriptedAdapter{name: "telegram"}, lastActivity: lastActivity, err: errors.New("telegram getMe: timeout")}
+	svc := &Service{
+		log:              slog.Default(),
+		providerLastOKAt: time.Now().UTC(),
+		running:          map[string]*runningChannel{"telegram": {adapter: telegram}},
+		channelStates:    map[string]channelState{"telegram": {Running: true}},
+	}
+
+	svc.checkChannelHealth(context.Background())
+	state := svc.currentStatus("not_ready").Channels["telegram"]
+	if state.HealthError != "telegram getMe: timeout" || state.LastActivityAt != "2026-10-16T12:00:00Z" {
+		t.Fatalf("telegram state = %+v", state)
+	}
+	if svc.isReady() {
+		t.Fatal("expected not ready while the only channel fails its probe")
+	}
+
+	telegram.err = nil
+	svc.checkChannelHealth(context.Background())
+	if !svc.isReady() {
+		t.Fatal("expected ready once the probe recovers")
+	}
+}
+
 func TestPromptResultMetadata(t *testing.T) {
 	t.Parallel()
 
@@ -50,3 +97,181 @@ func TestPromptResultMetadata(t *testing.T) {
 		t.Fatalf("total tokens = %q, want 21", got)
 	}
 }
+
+func TestHandleInboundTellsAgentAboutAttachments(t *testing.T) {
+	t.Parallel()
+
+	fakeClient := &fakeProviderClient{}
+	cfg := &config.Config{Agents: config.AgentsConfig{Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5-nano"}}}
+	manager, err := newRuntimeManager(context.Background(), cfg, fakeClient, nil)
+	if err != nil {
+		t.Fatalf("newRuntimeManager error: %v", err)
+	}
+	t.Cleanup(manager.Close)
+	svc := &Service{manager: manager, log: slog.Default()}
+
+	for _, content := range []string{"summarize this", ""} {
+		inbound := bus.InboundMessage{
+			Channel:    "telegram",
+			ChatID:     "100",
+			SessionKey: "telegram:100",
+			Content:    content,
+			Metadata:   map[string]string{channel.MetadataAttachments: "uploads/telegram-100/1-report.pdf\nuploads/telegram-100/2-photo.jpg"},
+		}
+		if _, err := svc.handleInbound(context.Background(), inbound); err != nil {
+			t.Fatalf("handleInbound error: %v", err)
+		}
+	}
+
+	fakeClient.mu.Lock()
+	defer fakeClient.mu.Unlock()
+	note := "The user sent these files, saved in the workspace:\n- uploads/telegram-100/1-report.pdf\n- uploads/telegram-100/2-photo.jpg"
+	if fakeClient.prompts[0] != "summarize this\n\n"+note || fakeClient.prompts[1] != note {
+		t.Fatalf("prompts = %q, want the attachment note", fakeClient.prompts)
+	}
+}
+
+// sendingProviderClient queues a file, as the send_file tool does, on every prompt.
+type sendingProviderClient struct {
+	fakeProviderClient
+}
+
+func (f *sendingProviderClient) Prompt(ctx context.Context, sessionID string, prompt string, model string, agent string, system string) (providertypes.PromptResult, error) {
+	if outbox, ok := providertypes.FileOutboxFromContext(ctx); ok {
+		outbox.Add("/workspace/report.pdf")
+	}
+	return f.fakeProviderClient.Prompt(ctx, sessionID, prompt, model, agent, system)
+}
+
+func TestHandleInboundCarriesSentFiles(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{Agents: config.AgentsConfig{Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5-nano"}}}
+	manager, err := newRuntimeManager(context.Background(), cfg, &sendingProviderClient{}, nil)
+	if err != nil {
+		t.Fatalf("newRuntimeManager error: %v", err)
+	}
+	t.Cleanup(manager.Close)
+	svc := &Service{manager: manager, log: slog.Default()}
+
+	outbound, err := svc.handleInbound(context.Background(), bus.InboundMessage{Channel: "telegram", ChatID: "100", SessionKey: "telegram:100", Content: "send the report"})
+	if err != nil {
+		t.Fatalf("handleInbound error: %v", err)
+	}
+	if files := channel.Files(outbound); len(files) != 1 || files[0] != "/workspace/report.pdf" {
+		t.Fatalf("outbound files = %v, want /workspace/report.pdf", files)
+	}
+}
+
+func TestDispatchInboundAppliesChannelPolicy(t *testing.T) {
+	t.Parallel()
+
+	fakeClient := &fakeProviderClient{}
+	cfg := &config.Config{Agents: config.AgentsConfig{Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5-nano"}}}
+	manager, err := newRuntimeManager(context.Background(), cfg, fakeClient, nil)
+	if err != nil {
+		t.Fatalf("newRuntimeManager error: %v", err)
+	}
+	t.Cleanup(manager.Close)
+	policy, err := newChannelPolicy(config.ChannelPolicyConfig{
+		AllowFrom:     []string{"telegram:100"},
+		BlockPatterns: []string{`(?i)\bsecret\b`},
+		RateLimit:     config.ChannelRateLimitConfig{Messages: 2},
+	})
+	if err != nil {
+		t.Fatalf("newChannelPolicy error: %v", err)
+	}
+	svc := &Service{manager: manager, log: slog.Default(), policy: policy}
+	var seen []string
+	svc.UseChannel(func(next channel.Handler) channel.Handler {
+		return func(ctx context.Context, inbound bus.InboundMessage) (bus.OutboundMessage, error) {
+			seen = append(seen, inbound.Content)
+			return next(ctx, inbound)
+		}
+	})
+
+	send := func(senderID, content string) error {
+		_, err := svc.dispatchInbound(context.Background(), bus.InboundMessage{Channel: "telegram", SenderID: senderID, ChatID: senderID, SessionKey: "telegram:" + senderID, Content: content})
+		return err
+	}
+	if err := send("100", "hello"); err != nil {
+		t.Fatalf("allowed message error: %v", err)
+	}
+	if err := send("200", "hello"); providertypes.ErrorCategoryOf(err) != providertypes.ErrorRejected {
+		t.Fatalf("unlisted sender error = %v, want rejected", err)
+	}
+	if err := send("100", "tell me the SECRET"); providertypes.ErrorCategoryOf(err) != providertypes.ErrorRejected {
+		t.Fatalf("blocked message error = %v, want rejected", err)
+	}
+	if err := send("100", "again"); err != nil {
+		t.Fatalf("second allowed message error: %v", err)
+	}
+	if err := send("100", "once more"); !errors.Is(err, channel.ErrRateLimited) {
+		t.Fatalf("third allowed message error = %v, want rate limited", err)
+	}
+	if len(seen) != 2 {
+		t.Fatalf("UseChannel middleware saw %v, want only the two admitted messages", seen)
+	}
+
+	outcomes := make(map[string]float64)
+	for _, series := range manager.telemetry.Snapshot() {
+		if series.Name == metricChannelMessages {
+			outcomes[series.Labels[1].Value] = series.Value
+		}
+	}
+	if outcomes["ok"] != 2 || outcomes["rejected"] != 2 || outcomes["rate_limited"] != 1 {
+		t.Fatalf("channel message outcomes = %v", outcomes)
+	}
+}
+
+func TestDispatchInboundAppliesChannelTemplates(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{Agents: config.AgentsConfig{Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5-nano"}}}
+	manager, err := newRuntimeManager(context.Background(), cfg, &fakeProviderClient{}, nil)
+	if err != nil {
+		t.Fatalf("newRuntimeManager error: %v", err)
+	}
+	t.Cleanup(manager.Close)
+	templates, err := newChannelTemplates(map[string]config.ChannelTemplatesConfig{
+		config.ChannelTemplatesDefault: {Greeting: "Welcome to {{.Channel}}, answered by {{.Model}}.", RateLimit: "Slow down."},
+		"telegram":                     {RateLimit: "Easy, {{.SenderID}}. Retry in {{.RetryAfter}}."},
+	})
+	if err != nil {
+		t.Fatalf("newChannelTemplates error: %v", err)
+	}
+	policy, err := newChannelPolicy(config.ChannelPolicyConfig{RateLimit: config.ChannelRateLimitConfig{Messages: 1}})
+	if err != nil {
+		t.Fatalf("newChannelPolicy error: %v", err)
+	}
+	svc := &Service{manager: manager, log: slog.Default(), policy: policy}
+	svc.setChannelTemplates(templates)
+
+	send := func(channelName, content string) (bus.OutboundMessage, error) {
+		return svc.dispatchInbound(context.Background(), bus.InboundMessage{Channel: channelName, SenderID: "100", ChatID: "100", SessionKey: channelName + ":100", Content: content})
+	}
+	if outbound, err := send("telegram", "/start"); err != nil || outbound.Content != "Welcome to telegram, answered by openai/gpt-5-nano." {
+		t.Fatalf("greeting = %q, %v", outbound.Content, err)
+	}
+	if _, err := send("telegram", "hello"); !errors.Is(err, channel.ErrRateLimited) || !strings.HasPrefix(providertypes.UserMessage(err), "Easy, 100. Retry in ") {
+		t.Fatalf("telegram rate limit reply = %q (%v)", providertypes.UserMessage(err), err)
+	}
+	if _, err := send("http", "/help"); err != nil {
+		t.Fatalf("http message error: %v", err)
+	}
+	if _, err := send("http", "hello"); providertypes.UserMessage(err) != "Slow down." {
+		t.Fatalf("http rate limit reply = %q (%v)", providertypes.UserMessage(err), err)
+	}
+
+	if _, err := newChannelTemplates(map[string]config.ChannelTemplatesConfig{"websocket": {Error: "{{.Nope}}"}}); err == nil || !strings.Contains(err.Error(), "channels.templates.websocket.error") {
+		t.Fatalf("invalid template error = %v", err)
+	}
+}
+
+func TestNewChannelPolicyRejectsInvalidPatterns(t *testing.T) {
+	t.Parallel()
+
+	if _, err := newChannelPolicy(config.ChannelPolicyConfig{BlockPatterns: []string{"("}}); err == nil {
+		t.Fatal("expected an invalid block pattern to fail")
+	}
+}
@@ -0,0 +1,238 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"miniclaw/pkg/agent"
+	"miniclaw/pkg/store"
+	"miniclaw/pkg/transcript"
+)
+
+// sessionResponse is the JSON payload returned by /v1/sessions/{key}.
+type sessionResponse struct {
+	SessionKey        string                  `json:"session_key"`
+	ProviderSessionID string                  `json:"provider_session_id,omitempty"`
+	Name              string                  `json:"name,omitempty"`
+	Turns             int                     `json:"turns"`
+	Failures          int                     `json:"failures"`
+	LastActivityAt    string                  `json:"last_activity_at,omitempty"`
+	Usage             sessionUsageResponse    `json:"usage"`
+	Tools             sessionToolsResponse    `json:"tools"`
+	Context           *sessionContextResponse `json:"context,omitempty"`
+	Redacted          bool                    `json:"redacted"`
+	Memory            []sessionMemoryPayload  `json:"memory"`
+}
+
+// sessionUsageResponse reports cumulative token usage for one session.
+type sessionUsageResponse struct {
+	InputTokens         int64 `json:"input_tokens"`
+	OutputTokens        int64 `json:"output_tokens"`
+	TotalTokens         int64 `json:"total_tokens"`
+	ReasoningTokens     int64 `json:"reasoning_tokens"`
+	CacheCreationTokens int64 `json:"cache_creation_tokens"`
+	CacheReadTokens     int64 `json:"cache_read_tokens"`
+}
+
+// sessionToolsResponse reports cumulative tool usage for one session.
+type sessionToolsResponse struct {
+	Calls        int64 `json:"calls"`
+	QuotaDenied  int64 `json:"quota_denied"`
+	BytesRead    int64 `json:"bytes_read"`
+	BytesWritten int64 `json:"bytes_written"`
+	DurationMs   int64 `json:"duration_ms"`
+}
+
+// sessionContextResponse reports how full the model's context window was after the last turn.
+type sessionContextResponse struct {
+	UsedTokens   int64 `json:"used_tokens"`
+	WindowTokens int64 `json:"window_tokens"`
+	Percent      int   `json:"percent"`
+	Estimated    bool  `json:"estimated"`
+	NearlyFull   bool  `json:"nearly_full"`
+}
+
+// sessionMemoryPayload is one transcript entry; content is omitted when redacted.
+type sessionMemoryPayload struct {
+	Role          string `json:"role"`
+	Content       string `json:"content,omitempty"`
+	ContentLength int    `json:"content_length"`
+	At            string `json:"at"`
+}
+
+// errorResponse is the JSON payload for API errors.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// handleSession reports agent state for one gateway session key.
+//
+// Pass ?redact=true to drop message content and keep only roles, lengths,
+// and timestamps, which is enough for dashboards that must not see user text.
+func (s *Service) handleSession(w http.ResponseWriter, r *http.Request) {
+	sessionKey := strings.TrimSpace(r.PathValue("key"))
+	if sessionKey == "" {
+		s.respondJSON(w, http.StatusBadRequest, errorResponse{Error: "session key is required"})
+		return
+	}
+
+	redact := false
+	if raw := r.URL.Query().Get("redact"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			s.respondJSON(w, http.StatusBadRequest, errorResponse{Error: "redact must be a boolean"})
+			return
+		}
+		redact = parsed
+	}
+
+	instance, stats, ok := s.manager.Snapshot(sessionKey)
+	if !ok {
+		s.respondJSON(w, http.StatusNotFound, errorResponse{Error: "session not found"})
+		return
+	}
+
+	response := buildSessionResponse(sessionKey, instance, stats, redact)
+	if name, err := instance.SessionName(r.Context()); err != nil {
+		s.log.Warn("Failed to load session name", "session_key", sessionKey, "error", err)
+	} else {
+		response.Name = name
+	}
+
+	s.respondJSON(w, http.StatusOK, response)
+}
+
+// buildSessionResponse converts runtime state into the public session payload.
+func buildSessionResponse(sessionKey string, instance *agent.Instance, stats sessionStats, redact bool) sessionResponse {
+	entries := instance.MemorySnapshot()
+	memory := make([]sessionMemoryPayload, 0, len(entries))
+	for _, entry := range entries {
+		payload := sessionMemoryPayload{
+			Role:          entry.Role,
+			ContentLength: len(entry.Content),
+			At:            entry.At.Format(time.RFC3339),
+		}
+		if !redact {
+			payload.Content = entry.Content
+		}
+		memory = append(memory, payload)
+	}
+
+	lastActivity := ""
+	if !stats.LastActivityAt.IsZero() {
+		lastActivity = stats.LastActivityAt.Format(time.RFC3339)
+	}
+
+	var context *sessionContextResponse
+	if stats.Context != nil {
+		context = &sessionContextResponse{
+			UsedTokens:   stats.Context.UsedTokens,
+			WindowTokens: stats.Context.WindowTokens,
+			Percent:      stats.Context.Percent(),
+			Estimated:    stats.Context.Estimated,
+			NearlyFull:   stats.Context.NearlyFull,
+		}
+	}
+
+	return sessionResponse{
+		SessionKey:        sessionKey,
+		ProviderSessionID: instance.SessionID(),
+		Turns:             stats.Turns,
+		Failures:          stats.Failures,
+		LastActivityAt:    lastActivity,
+		Usage: sessionUsageResponse{
+			InputTokens:         stats.Usage.InputTokens,
+			OutputTokens:        stats.Usage.OutputTokens,
+			TotalTokens:         stats.Usage.TotalTokens,
+			ReasoningTokens:     stats.Usage.ReasoningTokens,
+			CacheCreationTokens: stats.Usage.CacheCreationTokens,
+			CacheReadTokens:     stats.Usage.CacheReadTokens,
+		},
+		Tools: sessionToolsResponse{
+			Calls:        stats.ToolUsage.Calls,
+			QuotaDenied:  stats.ToolUsage.QuotaDenied,
+			BytesRead:    stats.ToolUsage.BytesRead,
+			BytesWritten: stats.ToolUsage.BytesWritten,
+			DurationMs:   stats.ToolUsage.Duration.Milliseconds(),
+		},
+		Context:  context,
+		Redacted: redact,
+		Memory:   memory,
+	}
+}
+
+// respondJSON writes one JSON API payload.
+func (s *Service) respondJSON(w http.ResponseWriter, statusCode int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		s.log.Error("Failed to write API response", "error", err)
+	}
+}
+
+// sessionImportResponse is the JSON payload returned by a session import.
+type sessionImportResponse struct {
+	SessionKey string `json:"session_key"`
+	SessionID  string `json:"session_id"`
+	Messages   int    `json:"messages"`
+}
+
+// maxImportBytes bounds the transcript body accepted by a session import.
+const maxImportBytes = 32 << 20
+
+// handleExportSession writes the conversation of one session key as a
+// transcript; ?format=md selects Markdown instead of the default JSON.
+func (s *Service) handleExportSession(w http.ResponseWriter, r *http.Request) {
+	sessionKey := strings.TrimSpace(r.PathValue("key"))
+	format := transcript.FormatJSON
+	if raw := r.URL.Query().Get("format"); raw != "" {
+		parsed, err := transcript.ParseFormat(raw)
+		if err != nil {
+			s.respondJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		format = parsed
+	}
+
+	exported, err := s.manager.ExportSession(r.Context(), sessionKey)
+	if errors.Is(err, errSessionNotFound) || errors.Is(err, store.ErrNotFound) {
+		s.respondJSON(w, http.StatusNotFound, errorResponse{Error: "session not found"})
+		return
+	}
+	if err != nil {
+		s.respondJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		return
+	}
+
+	if format == transcript.FormatMarkdown {
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	if err := transcript.Encode(w, exported, format); err != nil {
+		s.log.Error("Failed to write session export", "session_key", sessionKey, "error", err)
+	}
+}
+
+// handleImportSession stores the JSON transcript in the request body as the
+// conversation of one session key; its next message continues it.
+func (s *Service) handleImportSession(w http.ResponseWriter, r *http.Request) {
+	sessionKey := strings.TrimSpace(r.PathValue("key"))
+	exported, err := transcript.Decode(http.MaxBytesReader(w, r.Body, maxImportBytes))
+	if err != nil {
+		s.respondJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	sessionID, err := s.manager.ImportSession(r.Context(), sessionKey, exported)
+	if err != nil {
+		s.respondJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	s.respondJSON(w, http.StatusOK, sessionImportResponse{SessionKey: sessionKey, SessionID: sessionID, Messages: len(exported.Messages)})
+}
@@ -0,0 +1,295 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"miniclaw/pkg/agent"
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+	"miniclaw/pkg/store"
+	"miniclaw/pkg/transcript"
+)
+
+func TestSessionEndpointReportsStateAndRedacts(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5-nano"}},
+	}
+	manager, err := newRuntimeManager(context.Background(), cfg, &fakeProviderClient{}, nil)
+	if err != nil {
+		t.Fatalf("newRuntimeManager error: %v", err)
+	}
+	t.Cleanup(manager.Close)
+
+	if _, err := manager.Prompt(context.Background(), "telegram:100", "hello"); err != nil {
+		t.Fatalf("Prompt error: %v", err)
+	}
+
+	svc := &Service{cfg: cfg, log: slog.Default(), manager: manager}
+	handler := svc.statusHandler()
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/sessions/telegram:100", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", recorder.Code)
+	}
+
+	var payload sessionResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if payload.Turns != 1 || payload.ProviderSessionID != "session-id" || payload.LastActivityAt == "" {
+		t.Fatalf("payload = %+v, want 1 turn with session id and last activity", payload)
+	}
+	if len(payload.Memory) != 2 || payload.Memory[0].Content != "hello" {
+		t.Fatalf("memory = %+v, want user/assistant entries", payload.Memory)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/sessions/telegram:100?redact=true", nil))
+	payload = sessionResponse{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !payload.Redacted || payload.Memory[0].Content != "" || payload.Memory[0].ContentLength != 5 {
+		t.Fatalf("redacted memory = %+v, want content dropped and length kept", payload.Memory)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/sessions/unknown", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", recorder.Code)
+	}
+}
+
+func TestBuildSessionResponseReportsToolUsage(t *testing.T) {
+	t.Parallel()
+
+	runtime := &sessionRuntime{}
+	for range 2 {
+		runtime.recordPrompt(providertypes.PromptResult{Metadata: providertypes.PromptMetadata{
+			ToolUsage: &providertypes.ToolUsage{Calls: 2, BytesRead: 100, BytesWritten: 10, Duration: 15 * time.Millisecond},
+		}}, nil)
+	}
+
+	instance := agent.New(&fakeProviderClient{}, "openai/gpt-5-nano", config.HeartbeatConfig{}, "", "")
+	payload := buildSessionResponse("telegram:100", instance, runtime.stats(), true)
+	want := sessionToolsResponse{Calls: 4, BytesRead: 200, BytesWritten: 20, DurationMs: 30}
+	if payload.Tools != want {
+		t.Fatalf("tools = %+v, want %+v", payload.Tools, want)
+	}
+}
+
+func TestMetricsEndpointAggregatesTurnTiming(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5-nano"}},
+	}
+	manager, err := newRuntimeManager(context.Background(), cfg, &fakeProviderClient{}, nil)
+	if err != nil {
+		t.Fatalf("newRuntimeManager error: %v", err)
+	}
+	t.Cleanup(manager.Close)
+
+	for _, key := range []string{"telegram:1", "telegram:2"} {
+		if _, err := manager.Prompt(context.Background(), key, "hello"); err != nil {
+			t.Fatalf("Prompt error: %v", err)
+		}
+	}
+
+	svc := &Service{cfg: cfg, log: slog.Default(), manager: manager}
+	recorder := httptest.NewRecorder()
+	svc.statusHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/metrics", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", recorder.Code)
+	}
+
+	var payload metricsResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if payload.Turns != 2 || payload.Failures != 0 {
+		t.Fatalf("payload = %+v, want 2 turns and no failures", payload)
+	}
+	if payload.TimingMs.Total.Max < payload.TimingMs.Total.Avg {
+		t.Fatalf("total timing = %+v, want max >= avg", payload.TimingMs.Total)
+	}
+}
+
+func TestTurnMetricsRecord(t *testing.T) {
+	t.Parallel()
+
+	metrics := &turnMetrics{}
+	metrics.record(&providertypes.TurnTiming{Provider: 100 * time.Millisecond, Total: 150 * time.Millisecond}, nil)
+	metrics.record(&providertypes.TurnTiming{Provider: 300 * time.Millisecond, QueueWait: 50 * time.Millisecond, Total: 350 * time.Millisecond}, nil)
+	metrics.record(nil, errors.New("boom"))
+
+	snapshot := metrics.snapshot()
+	if snapshot.Turns != 2 || snapshot.Failures != 1 {
+		t.Fatalf("snapshot = %+v, want 2 turns and 1 failure", snapshot)
+	}
+	if got := snapshot.TimingMs.Provider; got.Last != 300 || got.Avg != 200 || got.Max != 300 {
+		t.Fatalf("provider = %+v, want last 300 avg 200 max 300", got)
+	}
+	if got := snapshot.TimingMs.QueueWait; got.Avg != 25 || got.Max != 50 {
+		t.Fatalf("queue wait = %+v, want avg 25 max 50", got)
+	}
+}
+
+func TestPrometheusEndpointExportsTurnMetrics(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Agents:    config.AgentsConfig{Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5-nano"}},
+		Telemetry: config.TelemetryConfig{Prometheus: config.PrometheusTelemetryConfig{Enabled: true}},
+	}
+	manager, err := newRuntimeManager(context.Background(), cfg, &fakeProviderClient{}, nil)
+	if err != nil {
+		t.Fatalf("newRuntimeManager error: %v", err)
+	}
+	t.Cleanup(manager.Close)
+
+	if _, err := manager.Prompt(context.Background(), "telegram:1", "hello"); err != nil {
+		t.Fatalf("Prompt error: %v", err)
+	}
+
+	svc := &Service{cfg: cfg, log: slog.Default(), manager: manager}
+	recorder := httptest.NewRecorder()
+	svc.statusHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", recorder.Code)
+	}
+	body := recorder.Body.String()
+	for _, want := range []string{
+		"# TYPE miniclaw_turns_total counter\n",
+		`miniclaw_turns_total{outcome="ok"} 1`,
+		"miniclaw_active_sessions 1\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("body missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestPrometheusPathRejectsStatusRoutes(t *testing.T) {
+	t.Parallel()
+
+	if path, err := prometheusPath(config.PrometheusTelemetryConfig{}); err != nil || path != "/metrics" {
+		t.Fatalf("default path = %q, %v; want /metrics", path, err)
+	}
+	for _, path := range []string{"metrics", "/v1/metrics", "/healthz"} {
+		if _, err := prometheusPath(config.PrometheusTelemetryConfig{Path: path}); err == nil {
+			t.Fatalf("prometheusPath(%q) error = nil, want rejection", path)
+		}
+	}
+}
+
+// storingProviderClient keeps sessions in a store so they can be exported and imported.
+type storingProviderClient struct {
+	*fakeProviderClient
+	sessions store.SessionStore
+}
+
+func (c *storingProviderClient) LatestSession(ctx context.Context, title string) (string, bool, error) {
+	summaries, err := c.sessions.List(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	latest := ""
+	for _, summary := range summaries {
+		if summary.Title == title {
+			latest = summary.ID
+		}
+	}
+	return latest, latest != "", nil
+}
+
+func (c *storingProviderClient) ExportSession(ctx context.Context, sessionID string) (transcript.Transcript, error) {
+	return transcript.Export(ctx, c.sessions, sessionID)
+}
+
+func (c *storingProviderClient) ImportSession(ctx context.Context, exported transcript.Transcript, title string) (string, error) {
+	return transcript.Import(ctx, c.sessions, exported, title)
+}
+
+func TestSessionTransferEndpoints(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Agents:  config.AgentsConfig{Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5-nano"}},
+		Gateway: config.GatewayConfig{Auth: config.GatewayAuthConfig{Token: "secret"}},
+	}
+	client := &storingProviderClient{fakeProviderClient: &fakeProviderClient{}, sessions: store.NewMemoryStore()}
+	manager, err := newRuntimeManager(context.Background(), cfg, client, nil)
+	if err != nil {
+		t.Fatalf("newRuntimeManager error: %v", err)
+	}
+	t.Cleanup(manager.Close)
+	handler := (&Service{cfg: cfg, log: slog.Default(), manager: manager}).statusHandler()
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	body := `{"version":1,"messages":[{"role":"user","text":"plan a trip"},{"role":"assistant","text":"Where to?"}]}`
+	if code := do(http.MethodPost, "/admin/sessions/telegram:100/import", "", body).Code; code != http.StatusUnauthorized {
+		t.Fatalf("unauthenticated import status = %d, want 401", code)
+	}
+	if code := do(http.MethodGet, "/admin/sessions/telegram:100/export", "secret", "").Code; code != http.StatusNotFound {
+		t.Fatalf("export before import status = %d, want 404", code)
+	}
+
+	recorder := do(http.MethodPost, "/admin/sessions/telegram:100/import", "secret", body)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("import status = %d, want 200: %s", recorder.Code, recorder.Body)
+	}
+	var imported sessionImportResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &imported); err != nil {
+		t.Fatalf("decode import: %v", err)
+	}
+	if imported.SessionID == "" || imported.Messages != 2 {
+		t.Fatalf("import = %+v, want a session with 2 messages", imported)
+	}
+
+	// The next message resumes the import, and export reads it from the live runtime.
+	if _, err := manager.Prompt(context.Background(), "telegram:100", "Lisbon"); err != nil {
+		t.Fatalf("Prompt error: %v", err)
+	}
+	instance, _, _ := manager.Snapshot("telegram:100")
+	if instance.SessionID() != imported.SessionID {
+		t.Fatalf("live session = %q, want imported %q", instance.SessionID(), imported.SessionID)
+	}
+	recorder = do(http.MethodGet, "/admin/sessions/telegram:100/export?format=md", "secret", "")
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "Where to?") {
+		t.Fatalf("markdown export = %d %q, want the imported reply", recorder.Code, recorder.Body)
+	}
+	if code := do(http.MethodGet, "/admin/sessions/telegram:100/export?format=xml", "secret", "").Code; code != http.StatusBadRequest {
+		t.Fatalf("bad format status = %d, want 400", code)
+	}
+
+	if code := do(http.MethodPost, "/admin/sessions/telegram:100/import", "secret", body).Code; code != http.StatusOK {
+		t.Fatalf("second import status = %d, want 200", code)
+	}
+	if _, _, live := manager.Snapshot("telegram:100"); live {
+		t.Fatal("import must drop the live runtime so the next message resumes it")
+	}
+	if code := do(http.MethodPost, "/admin/sessions/telegram:100/import", "secret", `{"version":1,"messages":[]}`).Code; code != http.StatusBadRequest {
+		t.Fatalf("empty import status = %d, want 400", code)
+	}
+}
@@ -0,0 +1,307 @@
+package gateway
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"miniclaw/pkg/accounting"
+	"miniclaw/pkg/config"
+	"miniclaw/pkg/cron"
+	"miniclaw/pkg/provider/openai"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+const (
+	// usageLedgerDays is how many UTC days of local usage the ledger keeps.
+	usageLedgerDays = 8
+	// defaultDriftPercent is the usage_reconcile threshold when drift_percent is unset.
+	defaultDriftPercent = 5.0
+	// reconciledProvider is the provider whose vendor usage usage_reconcile reads.
+	reconciledProvider = "openai"
+)
+
+// usageFetcher returns the vendor's usage for the UTC day containing day.
+type usageFetcher func(ctx context.Context, day time.Time) (openai.DailyUsage, error)
+
+// usageLedger aggregates token usage per UTC day and provider across all
+// gateway sessions, and keeps the latest vendor reconciliation.
+type usageLedger struct {
+	mu        sync.Mutex
+	startedAt time.Time
+	days      map[usageDayKey]providertypes.TokenUsage
+	turns     map[usageDayKey]int64
+	last      *usageReconciliation
+}
+
+type usageDayKey struct {
+	day      string
+	provider string
+}
+
+// usageResponse is the JSON payload returned by /v1/usage.
+type usageResponse struct {
+	// TrackingSince is when this gateway process started counting.
+	TrackingSince  string               `json:"tracking_since"`
+	Days           []usageDayResponse   `json:"days"`
+	Reconciliation *usageReconciliation `json:"reconciliation,omitempty"`
+}
+
+// usageDayResponse reports local token usage for one UTC day and provider.
+type usageDayResponse struct {
+	Day          string `json:"day"`
+	Provider     string `json:"provider"`
+	Turns        int64  `json:"turns"`
+	InputTokens  int64  `json:"input_tokens"`
+	OutputTokens int64  `json:"output_tokens"`
+	TotalTokens  int64  `json:"total_tokens"`
+}
+
+// usageReconciliation compares one day of local and vendor token totals.
+type usageReconciliation struct {
+	Day       string `json:"day"`
+	Provider  string `json:"provider"`
+	CheckedAt string `json:"checked_at"`
+	// LocalPartial is true when the gateway started after the day began, so
+	// local totals are incomplete and drift is not flagged.
+	LocalPartial       bool    `json:"local_partial"`
+	LocalInputTokens   int64   `json:"local_input_tokens"`
+	LocalOutputTokens  int64   `json:"local_output_tokens"`
+	VendorInputTokens  int64   `json:"vendor_input_tokens"`
+	VendorOutputTokens int64   `json:"vendor_output_tokens"`
+	VendorRequests     int64   `json:"vendor_requests"`
+	VendorCostUSD      float64 `json:"vendor_cost_usd"`
+	DriftPercent       float64 `json:"drift_percent"`
+	ThresholdPercent   float64 `json:"threshold_percent"`
+	Drifted            bool    `json:"drifted"`
+	Error              string  `json:"error,omitempty"`
+}
+
+func newUsageLedger(now time.Time) *usageLedger {
+	return &usageLedger{
+		startedAt: now.UTC(),
+		days:      make(map[usageDayKey]providertypes.TokenUsage),
+		turns:     make(map[usageDayKey]int64),
+	}
+}
+
+// record adds one successful turn's usage to its UTC day and drops days past retention.
+func (l *usageLedger) record(metadata providertypes.PromptMetadata, at time.Time) {
+	if metadata.Usage == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := usageDayKey{day: at.UTC().Format(time.DateOnly), provider: metadata.Provider}
+	total := l.days[key]
+	total.InputTokens += metadata.Usage.InputTokens
+	total.OutputTokens += metadata.Usage.OutputTokens
+	total.TotalTokens += metadata.Usage.TotalTokens
+	total.ReasoningTokens += metadata.Usage.ReasoningTokens
+	total.CacheCreationTokens += metadata.Usage.CacheCreationTokens
+	total.CacheReadTokens += metadata.Usage.CacheReadTokens
+	l.days[key] = total
+	l.turns[key]++
+
+	oldest := at.UTC().AddDate(0, 0, -(usageLedgerDays - 1)).Format(time.DateOnly)
+	for existing := range l.days {
+		if existing.day < oldest {
+			delete(l.days, existing)
+			delete(l.turns, existing)
+		}
+	}
+}
+
+// day returns local usage for one UTC day and provider.
+func (l *usageLedger) day(day time.Time, provider string) providertypes.TokenUsage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.days[usageDayKey{day: day.UTC().Format(time.DateOnly), provider: provider}]
+}
+
+func (l *usageLedger) setReconciliation(result usageReconciliation) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.last = &result
+}
+
+// snapshot converts the ledger into the /v1/usage payload, newest day first.
+func (l *usageLedger) snapshot() usageResponse {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	days := make([]usageDayResponse, 0, len(l.days))
+	for key, usage := range l.days {
+		days = append(days, usageDayResponse{
+			Day:          key.day,
+			Provider:     key.provider,
+			Turns:        l.turns[key],
+			InputTokens:  usage.InputTokens,
+			OutputTokens: usage.OutputTokens,
+			TotalTokens:  usage.TotalTokens,
+		})
+	}
+	slices.SortFunc(days, func(a, b usageDayResponse) int {
+		return cmp.Or(cmp.Compare(b.Day, a.Day), cmp.Compare(a.Provider, b.Provider))
+	})
+
+	response := usageResponse{TrackingSince: l.startedAt.Format(time.RFC3339), Days: days}
+	if l.last != nil {
+		last := *l.last
+		response.Reconciliation = &last
+	}
+
+	return response
+}
+
+// reconcileUsageTask returns the usage_reconcile maintenance task.
+//
+// Each run compares yesterday's (UTC) local OpenAI token totals with what
+// fetch reports, stores the result for /v1/usage, and flags drift above
+// drift_percent. Vendor totals cover every caller of the key's organization or
+// project, so scope providers.openai.project to the gateway to keep them comparable.
+func reconcileUsageTask(ledger *usageLedger, fetch usageFetcher, now func() time.Time) cron.MaintenanceTask {
+	return func(ctx context.Context, cfg config.MaintenanceJobConfig) (string, error) {
+		threshold := defaultDriftPercent
+		if cfg.DriftPercent > 0 {
+			threshold = cfg.DriftPercent
+		}
+		checkedAt := now().UTC()
+		day := checkedAt.Truncate(24*time.Hour).AddDate(0, 0, -1)
+		local := ledger.day(day, reconciledProvider)
+
+		result := usageReconciliation{
+			Day:               day.Format(time.DateOnly),
+			Provider:          reconciledProvider,
+			CheckedAt:         checkedAt.Format(time.RFC3339),
+			LocalPartial:      ledger.startedAt.After(day),
+			LocalInputTokens:  local.InputTokens,
+			LocalOutputTokens: local.OutputTokens,
+			ThresholdPercent:  threshold,
+		}
+
+		vendor, err := fetch(ctx, day)
+		if err != nil {
+			result.Error = err.Error()
+			ledger.setReconciliation(result)
+			return "", fmt.Errorf("fetch %s usage: %w", reconciledProvider, err)
+		}
+		result.VendorInputTokens = vendor.InputTokens
+		result.VendorOutputTokens = vendor.OutputTokens
+		result.VendorRequests = vendor.Requests
+		result.VendorCostUSD = vendor.CostUSD
+		result.DriftPercent = driftPercent(local.InputTokens+local.OutputTokens, vendor.InputTokens+vendor.OutputTokens)
+		result.Drifted = !result.LocalPartial && result.DriftPercent > threshold
+		ledger.setReconciliation(result)
+
+		summary := fmt.Sprintf("%s %s tokens: local %d, vendor %d, drift %.1f%%",
+			result.Day, reconciledProvider,
+			local.InputTokens+local.OutputTokens, vendor.InputTokens+vendor.OutputTokens, result.DriftPercent)
+		switch {
+		case result.LocalPartial:
+			summary += " (local totals partial; gateway started mid-day)"
+		case result.Drifted:
+			return "", fmt.Errorf("usage drift above %.1f%%: %s", threshold, summary)
+		}
+
+		return summary, nil
+	}
+}
+
+// driftPercent is how far local is from vendor, relative to vendor.
+func driftPercent(local int64, vendor int64) float64 {
+	if vendor == 0 {
+		if local == 0 {
+			return 0
+		}
+		return 100
+	}
+
+	drift := math.Abs(float64(vendor-local)) / float64(vendor) * 100
+	return math.Round(drift*10) / 10
+}
+
+// openAIUsageFetcher reads vendor usage through a usage client built per run,
+// so a missing OPENAI_ADMIN_KEY surfaces as a failed run rather than a startup error.
+func openAIUsageFetcher(cfg config.OpenAIProviderConfig) usageFetcher {
+	return func(ctx context.Context, day time.Time) (openai.DailyUsage, error) {
+		client, err := openai.NewUsageClient(cfg)
+		if err != nil {
+			return openai.DailyUsage{}, err
+		}
+		return client.Daily(ctx, day)
+	}
+}
+
+// handleUsage reports daily token usage and the latest vendor reconciliation.
+func (s *Service) handleUsage(w http.ResponseWriter, _ *http.Request) {
+	s.respondJSON(w, http.StatusOK, s.manager.usage.snapshot())
+}
+
+// openUsageStore opens the gateway.usage store, or returns nil when it is off.
+// Without prices of its own it estimates cost at the runtime.budget prices.
+func openUsageStore(cfg *config.Config) (*accounting.Store, error) {
+	usageCfg := cfg.Gateway.Usage
+	if !usageCfg.Enabled {
+		return nil, nil
+	}
+	prices := accounting.Prices{InputUSDPerMillion: usageCfg.InputUSDPerMillion, OutputUSDPerMillion: usageCfg.OutputUSDPerMillion}
+	if prices == (accounting.Prices{}) {
+		prices = accounting.Prices{InputUSDPerMillion: cfg.Runtime.Budget.InputUSDPerMillion, OutputUSDPerMillion: cfg.Runtime.Budget.OutputUSDPerMillion}
+	}
+
+	return accounting.Open(usageCfg, prices)
+}
+
+// recordAccounting adds one successful turn's usage to the usage store. A
+// failed write is logged; the turn's reply is unaffected.
+func (m *runtimeManager) recordAccounting(log *slog.Logger, sessionKey string, metadata providertypes.PromptMetadata) {
+	if m.accounting == nil || metadata.Usage == nil {
+		return
+	}
+	if err := m.accounting.Record(sessionKey, *metadata.Usage, time.Now()); err != nil {
+		log.Warn("Failed to record usage", "session_key", sessionKey, "error", err)
+	}
+}
+
+// handleUsageReport reports stored usage between the optional "from" and "to"
+// days (YYYY-MM-DD, inclusive), grouped by "group_by" (day, channel, or
+// session) and optionally narrowed to one "channel" or "session_key".
+func (s *Service) handleUsageReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := accounting.Filter{
+		Channel:    query.Get("channel"),
+		SessionKey: query.Get("session_key"),
+		GroupBy:    query.Get("group_by"),
+	}
+	for _, bound := range []struct {
+		name string
+		day  *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		raw := query.Get(bound.name)
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			s.respondJSON(w, http.StatusBadRequest, errorResponse{Error: bound.name + " must be a date such as 2026-10-16"})
+			return
+		}
+		*bound.day = parsed
+	}
+
+	report, err := s.manager.accounting.Query(filter)
+	if err != nil {
+		s.respondJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	s.respondJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,177 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"miniclaw/pkg/accounting"
+	"miniclaw/pkg/config"
+	"miniclaw/pkg/provider/openai"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+func TestReconcileUsageFlagsDrift(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 16, 6, 0, 0, 0, time.UTC)
+	yesterday := now.AddDate(0, 0, -1)
+	ledger := newUsageLedger(now.AddDate(0, 0, -3))
+	ledger.record(providertypes.PromptMetadata{Provider: "openai", Usage: &providertypes.TokenUsage{InputTokens: 800, OutputTokens: 200, TotalTokens: 1000}}, yesterday.Add(time.Hour))
+	ledger.record(providertypes.PromptMetadata{Provider: "anthropic", Usage: &providertypes.TokenUsage{InputTokens: 50, TotalTokens: 50}}, yesterday.Add(time.Hour))
+
+	vendor := openai.DailyUsage{InputTokens: 900, OutputTokens: 200, Requests: 3, CostUSD: 0.02}
+	var fetchedDay time.Time
+	fetch := func(_ context.Context, day time.Time) (openai.DailyUsage, error) {
+		fetchedDay = day
+		return vendor, nil
+	}
+	task := reconcileUsageTask(ledger, fetch, func() time.Time { return now })
+
+	if _, err := task(context.Background(), config.MaintenanceJobConfig{DriftPercent: 5}); err == nil || !strings.Contains(err.Error(), "usage drift") {
+		t.Fatalf("task error = %v, want drift error", err)
+	}
+	if !fetchedDay.Equal(time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("fetched day = %v, want start of yesterday", fetchedDay)
+	}
+	last := ledger.snapshot().Reconciliation
+	if last == nil || !last.Drifted || last.DriftPercent != 9.1 || last.LocalInputTokens != 800 || last.VendorCostUSD != 0.02 {
+		t.Fatalf("reconciliation = %+v, want 9.1%% drift flagged", last)
+	}
+
+	summary, err := task(context.Background(), config.MaintenanceJobConfig{DriftPercent: 10})
+	if err != nil {
+		t.Fatalf("task error = %v, want drift within threshold", err)
+	}
+	if !strings.Contains(summary, "local 1000, vendor 1100") {
+		t.Fatalf("summary = %q, want local and vendor totals", summary)
+	}
+
+	fetchErr := errors.New("http 401")
+	failing := reconcileUsageTask(ledger, func(context.Context, time.Time) (openai.DailyUsage, error) {
+		return openai.DailyUsage{}, fetchErr
+	}, func() time.Time { return now })
+	if _, err := failing(context.Background(), config.MaintenanceJobConfig{}); !errors.Is(err, fetchErr) {
+		t.Fatalf("task error = %v, want fetch error", err)
+	}
+	if last := ledger.snapshot().Reconciliation; last.Error == "" || last.Drifted {
+		t.Fatalf("reconciliation = %+v, want fetch error recorded", last)
+	}
+}
+
+func TestReconcileUsageSkipsPartialDays(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 16, 6, 0, 0, 0, time.UTC)
+	ledger := newUsageLedger(now.Add(-12 * time.Hour))
+	task := reconcileUsageTask(ledger, func(context.Context, time.Time) (openai.DailyUsage, error) {
+		return openai.DailyUsage{InputTokens: 5000}, nil
+	}, func() time.Time { return now })
+
+	summary, err := task(context.Background(), config.MaintenanceJobConfig{})
+	if err != nil {
+		t.Fatalf("task error = %v, want partial day not flagged", err)
+	}
+	if !strings.Contains(summary, "partial") {
+		t.Fatalf("summary = %q, want partial note", summary)
+	}
+	if last := ledger.snapshot().Reconciliation; !last.LocalPartial || last.Drifted || last.DriftPercent != 100 {
+		t.Fatalf("reconciliation = %+v, want partial with 100%% drift unflagged", last)
+	}
+}
+
+func TestUsageEndpointReportsDailyTotals(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5-nano"}},
+	}
+	manager, err := newRuntimeManager(context.Background(), cfg, &fakeProviderClient{}, nil)
+	if err != nil {
+		t.Fatalf("newRuntimeManager error: %v", err)
+	}
+	t.Cleanup(manager.Close)
+
+	today := time.Now()
+	manager.usage.record(providertypes.PromptMetadata{Provider: "openai", Usage: &providertypes.TokenUsage{InputTokens: 99, TotalTokens: 99}}, today.AddDate(0, 0, -usageLedgerDays))
+	manager.usage.record(providertypes.PromptMetadata{Provider: "openai", Usage: &providertypes.TokenUsage{InputTokens: 10, OutputTokens: 5, TotalTokens: 15}}, today)
+	manager.usage.record(providertypes.PromptMetadata{Provider: "openai", Usage: &providertypes.TokenUsage{InputTokens: 20, OutputTokens: 5, TotalTokens: 25}}, today)
+
+	svc := &Service{cfg: cfg, log: slog.Default(), manager: manager}
+	recorder := httptest.NewRecorder()
+	svc.statusHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/usage", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", recorder.Code)
+	}
+
+	var payload usageResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	want := usageDayResponse{Day: today.UTC().Format(time.DateOnly), Provider: "openai", Turns: 2, InputTokens: 30, OutputTokens: 10, TotalTokens: 40}
+	if len(payload.Days) != 1 || payload.Days[0] != want {
+		t.Fatalf("days = %+v, want only %+v", payload.Days, want)
+	}
+	if payload.Reconciliation != nil {
+		t.Fatalf("reconciliation = %+v, want none before the first run", payload.Reconciliation)
+	}
+}
+
+func TestUsageReportEndpointReadsStore(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Agents:  config.AgentsConfig{Defaults: config.AgentDefaults{Provider: "openai", Model: "openai/gpt-5-nano"}},
+		Gateway: config.GatewayConfig{Usage: config.GatewayUsageConfig{Enabled: true, Path: filepath.Join(t.TempDir(), "usage.json")}},
+		Runtime: config.RuntimeConfig{Budget: config.BudgetConfig{InputUSDPerMillion: 1, OutputUSDPerMillion: 4}},
+	}
+	manager, err := newRuntimeManager(context.Background(), cfg, &usageProviderClient{}, nil)
+	if err != nil {
+		t.Fatalf("newRuntimeManager error: %v", err)
+	}
+	t.Cleanup(manager.Close)
+	for _, sessionKey := range []string{"telegram:100", "telegram:100", "http:ci"} {
+		if _, err := manager.Prompt(context.Background(), sessionKey, "hello"); err != nil {
+			t.Fatalf("Prompt error: %v", err)
+		}
+	}
+
+	svc := &Service{cfg: cfg, log: slog.Default(), manager: manager}
+	recorder := httptest.NewRecorder()
+	svc.statusHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/usage/report?group_by=session&channel=telegram", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", recorder.Code, recorder.Body)
+	}
+	var report accounting.Report
+	if err := json.Unmarshal(recorder.Body.Bytes(), &report); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	want := accounting.Row{Channel: "telegram", SessionKey: "telegram:100", Turns: 2, InputTokens: 2_000_000, OutputTokens: 500_000, TotalTokens: 2_500_000, CostUSD: 4}
+	if len(report.Rows) != 1 || report.Rows[0] != want {
+		t.Fatalf("rows = %+v, want only %+v", report.Rows, want)
+	}
+
+	recorder = httptest.NewRecorder()
+	svc.statusHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/usage/report?from=yesterday", nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("bad from status = %d, want 400", recorder.Code)
+	}
+}
+
+// usageProviderClient reports fixed token usage for every prompt.
+type usageProviderClient struct {
+	fakeProviderClient
+}
+
+func (f *usageProviderClient) Prompt(ctx context.Context, sessionID string, prompt string, model string, agent string, system string) (providertypes.PromptResult, error) {
+	result, err := f.fakeProviderClient.Prompt(ctx, sessionID, prompt, model, agent, system)
+	result.Metadata.Usage = &providertypes.TokenUsage{InputTokens: 1_000_000, OutputTokens: 250_000, TotalTokens: 1_250_000}
+	return result, err
+}
@@ -0,0 +1,198 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/config"
+)
+
+const (
+	defaultWebhookRetries = 3
+	defaultWebhookTimeout = 10 * time.Second
+	// webhookQueueSize is how many events one webhook may have waiting
+	// before new ones are dropped.
+	webhookQueueSize = 64
+	// maxWebhookBackoff caps the wait between redeliveries.
+	maxWebhookBackoff = 30 * time.Second
+)
+
+// defaultWebhookEvents are the event types sent when a webhook lists none.
+var defaultWebhookEvents = []bus.EventType{bus.EventPromptFailed, bus.EventChannelDisconnected, bus.EventProviderDown}
+
+// webhookSender delivers gateway events to one gateway.webhooks URL, one at
+// a time and in order, retrying failed attempts with exponential backoff.
+type webhookSender struct {
+	url        string
+	secret     string
+	events     map[bus.EventType]bool
+	maxRetries int
+	client     *http.Client
+	log        *slog.Logger
+	queue      chan bus.Event
+	// backoff is the wait before the first redelivery; it doubles after each.
+	backoff time.Duration
+}
+
+// newWebhookSenders validates gateway.webhooks and builds one sender per URL.
+func newWebhookSenders(cfgs []config.GatewayWebhookConfig, log *slog.Logger) ([]*webhookSender, error) {
+	senders := make([]*webhookSender, 0, len(cfgs))
+	for index, cfg := range cfgs {
+		endpoint := strings.TrimSpace(cfg.URL)
+		parsed, err := url.Parse(endpoint)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("gateway.webhooks[%d].url %q must be an http or https URL", index, cfg.URL)
+		}
+		if cfg.MaxRetries < 0 || cfg.TimeoutSeconds < 0 {
+			return nil, fmt.Errorf("gateway.webhooks[%d]: max_retries and timeout_seconds must not be negative", index)
+		}
+
+		events := make(map[bus.EventType]bool)
+		for _, name := range cfg.Events {
+			if name = strings.TrimSpace(name); name != "" {
+				events[bus.EventType(name)] = true
+			}
+		}
+		if len(events) == 0 {
+			for _, eventType := range defaultWebhookEvents {
+				events[eventType] = true
+			}
+		}
+		maxRetries := cfg.MaxRetries
+		if maxRetries == 0 {
+			maxRetries = defaultWebhookRetries
+		}
+		timeout := defaultWebhookTimeout
+		if cfg.TimeoutSeconds > 0 {
+			timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+		}
+
+		senders = append(senders, &webhookSender{
+			url:        endpoint,
+			secret:     cfg.Secret,
+			events:     events,
+			maxRetries: maxRetries,
+			client:     &http.Client{Timeout: timeout},
+			log:        log.With("component", "gateway.webhooks", "webhook", parsed.Host),
+			queue:      make(chan bus.Event, webhookQueueSize),
+			backoff:    time.Second,
+		})
+	}
+
+	return senders, nil
+}
+
+// runWebhooks fans gateway events out to the webhook senders until ctx ends.
+func (s *Service) runWebhooks(ctx context.Context, events <-chan bus.Event) {
+	for _, sender := range s.webhooks {
+		go sender.run(ctx)
+	}
+
+	for event := range events {
+		for _, sender := range s.webhooks {
+			if !sender.events[event.Type] {
+				continue
+			}
+			select {
+			case sender.queue <- event:
+			default:
+				sender.log.Warn("Webhook queue full; dropping event", "type", event.Type)
+			}
+		}
+	}
+}
+
+// run delivers queued events until ctx ends.
+func (w *webhookSender) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-w.queue:
+			if err := w.deliver(ctx, event); err != nil && ctx.Err() == nil {
+				w.log.Warn("Webhook delivery failed", "type", event.Type, "error", err)
+			}
+		}
+	}
+}
+
+// deliver POSTs one event, retrying network errors, 429s, and 5xx replies.
+func (w *webhookSender) deliver(ctx context.Context, event bus.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+
+	backoff := w.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(ctx, event.Type, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= w.maxRetries {
+			return fmt.Errorf("after %d attempts: %w", attempt+1, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxWebhookBackoff)
+	}
+}
+
+// post makes one delivery attempt and reports whether a failure is worth
+// retrying.
+func (w *webhookSender) post(ctx context.Context, eventType bus.EventType, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "miniclaw-gateway")
+	req.Header.Set("X-Miniclaw-Event", string(eventType))
+	req.Header.Set("X-Miniclaw-Timestamp", timestamp)
+	if w.secret != "" {
+		req.Header.Set("X-Miniclaw-Signature", "sha256="+signWebhook(w.secret, timestamp, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("status %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("status %d", resp.StatusCode)
+	}
+}
+
+// signWebhook returns the hex HMAC-SHA256 of "<timestamp>.<body>" under
+// secret, which receivers recompute to check that a delivery is genuine and
+// recent.
+func signWebhook(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,113 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"miniclaw/pkg/bus"
+	"miniclaw/pkg/config"
+)
+
+func TestWebhooksRetrySignAndFilterEvents(t *testing.T) {
+	t.Parallel()
+
+	type delivery struct {
+		eventType string
+		timestamp string
+		signature string
+		body      []byte
+	}
+	var (
+		mu         sync.Mutex
+		attempts   int
+		deliveries = make(chan delivery, 4)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		failing := attempts == 1
+		mu.Unlock()
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		deliveries <- delivery{
+			eventType: r.Header.Get("X-Miniclaw-Event"),
+			timestamp: r.Header.Get("X-Miniclaw-Timestamp"),
+			signature: r.Header.Get("X-Miniclaw-Signature"),
+			body:      body,
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	senders, err := newWebhookSenders([]config.GatewayWebhookConfig{{URL: server.URL, Secret: "s3cret"}}, slog.Default())
+	if err != nil {
+		t.Fatalf("newWebhookSenders error: %v", err)
+	}
+	senders[0].backoff = time.Millisecond
+	svc := &Service{webhooks: senders}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	events := make(chan bus.Event, 2)
+	events <- bus.Event{Type: bus.EventPromptCompleted, SessionKey: "telegram:1"}
+	events <- bus.Event{Type: bus.EventPromptFailed, SessionKey: "telegram:1", Error: "provider down"}
+	close(events)
+	go svc.runWebhooks(ctx, events)
+
+	var got delivery
+	select {
+	case got = <-deliveries:
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was never delivered")
+	}
+	if got.eventType != "prompt_failed" {
+		t.Fatalf("event type = %q, want only the default prompt_failed", got.eventType)
+	}
+	if want := "sha256=" + signWebhook("s3cret", got.timestamp, got.body); got.signature != want {
+		t.Fatalf("signature = %q, want %q", got.signature, want)
+	}
+	var event bus.Event
+	if err := json.Unmarshal(got.body, &event); err != nil || event.Error != "provider down" {
+		t.Fatalf("body = %s (%v)", got.body, err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 2 {
+		t.Fatalf("attempts = %d, want one retry after the 503", attempts)
+	}
+}
+
+func TestWebhookDoesNotRetryClientErrors(t *testing.T) {
+	t.Parallel()
+
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	t.Cleanup(server.Close)
+
+	senders, err := newWebhookSenders([]config.GatewayWebhookConfig{{URL: server.URL}}, slog.Default())
+	if err != nil {
+		t.Fatalf("newWebhookSenders error: %v", err)
+	}
+	if err := senders[0].deliver(context.Background(), bus.Event{Type: bus.EventProviderDown}); err == nil {
+		t.Fatal("deliver error = nil, want the 400")
+	}
+	if attempts != 1 {
+		t.Fatalf("attempts = %d, want no retry", attempts)
+	}
+
+	if _, err := newWebhookSenders([]config.GatewayWebhookConfig{{URL: "ftp://example.com"}}, slog.Default()); err == nil {
+		t.Fatal("non-HTTP URL must be rejected")
+	}
+}
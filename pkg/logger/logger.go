@@ -3,6 +3,7 @@ package logger
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -35,7 +36,6 @@ type LogEntry struct {
 
 // entryHandler implements slog.Handler for MiniClaw JSON log output.
 type entryHandler struct {
-	level     slog.Level
 	addSource bool
 	writer    io.Writer
 	attrs     []slog.Attr
@@ -70,38 +70,64 @@ func newWithWriter(cfg config.LoggingConfig, writer io.Writer) (*slog.Logger, er
 		addSource = parseBool(env)
 	}
 
-	h := &entryHandler{
-		level:     level,
-		addSource: addSource,
-		writer:    writer,
-		mu:        &sync.Mutex{},
-	}
+	levelVar := &slog.LevelVar{}
+	levelVar.Set(level)
 
 	if format == "text" {
+		// The text logger passes everything; levelHandler does the filtering.
 		pretty := charmLog.NewWithOptions(writer, charmLog.Options{
-			Level:           charmLevel(level),
+			Level:           charmLog.DebugLevel,
 			ReportTimestamp: true,
 			ReportCaller:    addSource,
 			Formatter:       charmLog.TextFormatter,
 		})
-		return slog.New(pretty), nil
+		return slog.New(&levelHandler{Handler: pretty, level: levelVar}), nil
 	}
 
-	return slog.New(h), nil
+	h := &entryHandler{
+		addSource: addSource,
+		writer:    writer,
+		mu:        &sync.Mutex{},
+	}
+
+	return slog.New(&levelHandler{Handler: h, level: levelVar}), nil
 }
 
-// charmLevel maps slog levels to charm/log levels for text output mode.
-func charmLevel(level slog.Level) charmLog.Level {
-	switch {
-	case level <= slog.LevelDebug:
-		return charmLog.DebugLevel
-	case level <= slog.LevelInfo:
-		return charmLog.InfoLevel
-	case level <= slog.LevelWarn:
-		return charmLog.WarnLevel
-	default:
-		return charmLog.ErrorLevel
+// SetLevel changes the level of a logger built by New, including the loggers
+// already derived from it. MINICLAW_LOG_LEVEL still takes precedence over
+// level, as it does in New.
+func SetLevel(log *slog.Logger, level string) error {
+	handler, ok := log.Handler().(*levelHandler)
+	if !ok {
+		return errors.New("logger was not built by logger.New")
 	}
+
+	parsed, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+	handler.level.Set(parsed)
+
+	return nil
+}
+
+// levelHandler drops records below a level shared with every handler derived
+// from it, so SetLevel reaches loggers built with With before the change.
+type levelHandler struct {
+	slog.Handler
+	level *slog.LevelVar
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.Handler.Enabled(ctx, level)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithAttrs(attrs), level: h.level}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithGroup(name), level: h.level}
 }
 
 // parseLevel resolves the effective log level from config and env.
@@ -138,8 +164,8 @@ func parseBool(input string) bool {
 	}
 }
 
-func (h *entryHandler) Enabled(_ context.Context, level slog.Level) bool {
-	return level >= h.level
+func (h *entryHandler) Enabled(context.Context, slog.Level) bool {
+	return true
 }
 
 func (h *entryHandler) Handle(_ context.Context, record slog.Record) error {
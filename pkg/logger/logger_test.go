@@ -3,6 +3,7 @@ package logger
 import (
 	"bytes"
 	"encoding/json"
+	"log/slog"
 	"os"
 	"strings"
 	"testing"
@@ -71,6 +72,32 @@ func TestLoggerLevelFiltering(t *testing.T) {
 	}
 }
 
+func TestSetLevelReachesDerivedLoggers(t *testing.T) {
+	unsetLoggingEnv(t)
+
+	var out bytes.Buffer
+	log, err := newWithWriter(config.LoggingConfig{Format: "json", Level: "error"}, &out)
+	if err != nil {
+		t.Fatalf("newWithWriter error: %v", err)
+	}
+	derived := log.With("component", "test")
+
+	if err := SetLevel(log, "debug"); err != nil {
+		t.Fatalf("SetLevel error: %v", err)
+	}
+	derived.Debug("Now visible")
+	if !strings.Contains(out.String(), "Now visible") {
+		t.Fatalf("expected debug output after SetLevel, got %q", out.String())
+	}
+
+	if err := SetLevel(log, "loud"); err == nil {
+		t.Fatal("expected an error for an unknown level")
+	}
+	if err := SetLevel(slog.New(slog.NewTextHandler(&out, nil)), "info"); err == nil {
+		t.Fatal("expected an error for a foreign logger")
+	}
+}
+
 func TestLoggerEnvironmentOverrides(t *testing.T) {
 	t.Setenv("MINICLAW_LOG_LEVEL", "debug")
 	t.Setenv("MINICLAW_LOG_FORMAT", "text")
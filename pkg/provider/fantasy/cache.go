@@ -0,0 +1,63 @@
+package fantasy
+
+import (
+	"maps"
+	"slices"
+
+	core "charm.land/fantasy"
+	provideranthropic "charm.land/fantasy/providers/anthropic"
+)
+
+// cacheControlOptions returns provider options that mark a prompt prefix as cacheable.
+func cacheControlOptions() core.ProviderOptions {
+	return core.ProviderOptions{
+		provideranthropic.Name: &provideranthropic.ProviderCacheControlOptions{
+			CacheControl: provideranthropic.CacheControl{Type: "ephemeral"},
+		},
+	}
+}
+
+// markToolsCacheable sets a cache breakpoint on the last tool definition.
+//
+// Anthropic caches everything up to a breakpoint, so one hint on the final
+// tool covers the whole (unchanging) tool list.
+func markToolsCacheable(tools []core.AgentTool) {
+	if len(tools) == 0 {
+		return
+	}
+
+	tools[len(tools)-1].SetProviderOptions(cacheControlOptions())
+}
+
+// applyCacheHints returns a copy of history with cache breakpoints on its stable prefixes.
+//
+// The system prompt and the last message of prior history are marked, so
+// each turn reuses the cached conversation and only the new prompt is billed
+// at the full input rate. Together with the tool breakpoint this stays under
+// Anthropic's limit of four breakpoints per request. Stored history is never
+// modified.
+func applyCacheHints(history []core.Message) []core.Message {
+	if len(history) == 0 {
+		return history
+	}
+
+	hinted := slices.Clone(history)
+	marked := make([]int, 0, 2)
+	if hinted[0].Role == core.MessageRoleSystem {
+		marked = append(marked, 0)
+	}
+	if last := len(hinted) - 1; len(marked) == 0 || marked[0] != last {
+		marked = append(marked, last)
+	}
+
+	for _, index := range marked {
+		options := maps.Clone(hinted[index].ProviderOptions)
+		if options == nil {
+			options = core.ProviderOptions{}
+		}
+		maps.Copy(options, cacheControlOptions())
+		hinted[index].ProviderOptions = options
+	}
+
+	return hinted
+}
@@ -0,0 +1,147 @@
+package fantasy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	core "charm.land/fantasy"
+
+	"miniclaw/pkg/config"
+	"miniclaw/pkg/store"
+	"miniclaw/pkg/transcript"
+)
+
+const (
+	defaultCompactionThresholdBytes = 200_000
+	defaultCompactionKeepTurns      = 4
+
+	// compactionRole marks a stored turn whose payload, a JSON message list,
+	// replaces all history before it. Turns stay append-only; replay applies it.
+	compactionRole = transcript.CompactionRole
+	// compactionSummaryPrefix starts the system message that carries a summary.
+	compactionSummaryPrefix = "Summary of the earlier conversation:\n\n"
+	// maxTranscriptPartBytes bounds each tool input or result in the text sent for summarizing.
+	maxTranscriptPartBytes = 2000
+)
+
+// compaction holds the resolved agents.defaults.compaction settings.
+type compaction struct {
+	thresholdBytes int
+	keepTurns      int
+}
+
+// newCompaction returns nil when compaction is disabled.
+func newCompaction(cfg config.CompactionConfig) *compaction {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	policy := &compaction{thresholdBytes: cfg.ThresholdBytes, keepTurns: cfg.KeepTurns}
+	if policy.thresholdBytes <= 0 {
+		policy.thresholdBytes = defaultCompactionThresholdBytes
+	}
+	if policy.keepTurns <= 0 {
+		policy.keepTurns = defaultCompactionKeepTurns
+	}
+
+	return policy
+}
+
+// compactHistory replaces the older turns of history with a model-written
+// summary once the encoded history passes the threshold.
+//
+// The leading system prompt and the last keepTurns user turns (with their
+// replies and tool calls) are kept verbatim; an earlier summary is folded
+// into the new one. The result is persisted as a compaction turn so later
+// prompts and restarts load the compacted history. History below the
+// threshold, or with too few turns to compact, is returned unchanged.
+func (c *Client) compactHistory(ctx context.Context, sessionID string, model core.LanguageModel, history []core.Message) ([]core.Message, core.Usage, error) {
+	if c.compaction == nil || requestSize(history, "") < c.compaction.thresholdBytes {
+		return history, core.Usage{}, nil
+	}
+
+	lead := 0
+	for lead < len(history) && history[lead].Role == core.MessageRoleSystem && !isCompactionSummary(history[lead]) {
+		lead++
+	}
+	cut := len(history)
+	for kept := 0; cut > lead && kept < c.compaction.keepTurns; {
+		cut--
+		if history[cut].Role == core.MessageRoleUser {
+			kept++
+		}
+	}
+	if cut <= lead {
+		return history, core.Usage{}, nil
+	}
+
+	maxTokens := int64(2048)
+	response, err := model.Generate(ctx, core.Call{
+		Prompt: core.Prompt{
+			core.NewSystemMessage("Summarize the conversation below so an assistant can continue it without the original messages. Keep the user's goals, decisions, open tasks, file paths, identifiers, and numbers exact. Be concise."),
+			core.NewUserMessage(transcriptText(history[lead:cut])),
+		},
+		MaxOutputTokens: &maxTokens,
+	})
+	if err != nil {
+		return history, core.Usage{}, fmt.Errorf("summarize history: %w", err)
+	}
+	summary := extractText(response.Content)
+	if summary == "" {
+		return history, response.Usage, errors.New("summarize history: model returned no text")
+	}
+
+	compacted := make([]core.Message, 0, lead+1+len(history)-cut)
+	compacted = append(compacted, history[:lead]...)
+	compacted = append(compacted, core.NewSystemMessage(compactionSummaryPrefix+summary))
+	compacted = append(compacted, history[cut:]...)
+
+	payload, err := json.Marshal(compacted)
+	if err != nil {
+		return history, response.Usage, fmt.Errorf("encode compacted history: %w", err)
+	}
+	if err := c.sessions.AppendTurn(ctx, sessionID, store.Turn{Role: compactionRole, Content: summary, Payload: payload}); err != nil {
+		return history, response.Usage, fmt.Errorf("persist compacted history: %w", err)
+	}
+
+	return compacted, response.Usage, nil
+}
+
+// isCompactionSummary reports whether message carries an earlier compaction summary.
+func isCompactionSummary(message core.Message) bool {
+	return message.Role == core.MessageRoleSystem && strings.HasPrefix(messageText(message), strings.TrimSpace(compactionSummaryPrefix))
+}
+
+// transcriptText renders messages as plain text for the summarizer. Tool
+// inputs and results are cut to maxTranscriptPartBytes.
+func transcriptText(messages []core.Message) string {
+	var b strings.Builder
+	for _, message := range messages {
+		for _, part := range message.Content {
+			switch typed := part.(type) {
+			case core.TextPart:
+				if text := strings.TrimSpace(typed.Text); text != "" {
+					fmt.Fprintf(&b, "%s: %s\n\n", message.Role, text)
+				}
+			case core.ToolCallPart:
+				fmt.Fprintf(&b, "%s called %s: %s\n\n", message.Role, typed.ToolName, clipText(typed.Input))
+			case core.ToolResultPart:
+				fmt.Fprintf(&b, "tool result: %s\n\n", clipText(formatToolResultOutput(typed.Output)))
+			}
+		}
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+func clipText(text string) string {
+	text = strings.TrimSpace(text)
+	if len(text) <= maxTranscriptPartBytes {
+		return text
+	}
+
+	return strings.ToValidUTF8(text[:maxTranscriptPartBytes], "") + " [truncated]"
+}
@@ -0,0 +1,57 @@
+package fantasy
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	core "charm.land/fantasy"
+
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+// classifyError attaches a providertypes error category to a fantasy failure.
+func (c *Client) classifyError(err error) error {
+	var providerErr *core.ProviderError
+	if !errors.As(err, &providerErr) {
+		return providertypes.ClassifyError(c.providerName(), 0, err)
+	}
+
+	classified := providertypes.ClassifyError(c.providerName(), providerErr.StatusCode, err)
+	var promptErr *providertypes.PromptError
+	if errors.As(classified, &promptErr) {
+		if providerErr.IsContextTooLarge() {
+			promptErr.Category = providertypes.ErrorContextLength
+		}
+		promptErr.RetryAfter = providertypes.ParseRetryAfter(providertypes.HeaderValue(providerErr.ResponseHeaders, "Retry-After"), time.Now())
+	}
+
+	return classified
+}
+
+// failureTaggedTool marks errors returned by a tool so they classify as tool failures.
+//
+// Fantasy aborts the whole agent run when a tool returns an error (as opposed
+// to an error response), which would otherwise look like a provider failure.
+type failureTaggedTool struct {
+	core.AgentTool
+}
+
+func (t *failureTaggedTool) Run(ctx context.Context, params core.ToolCall) (core.ToolResponse, error) {
+	response, err := t.AgentTool.Run(ctx, params)
+	if err != nil && ctx.Err() == nil {
+		err = &providertypes.ToolFailureError{Tool: t.Info().Name, Err: err}
+	}
+
+	return response, err
+}
+
+// tagToolFailures wraps tools so run errors carry the failing tool name.
+func tagToolFailures(tools []core.AgentTool) []core.AgentTool {
+	tagged := make([]core.AgentTool, len(tools))
+	for index, tool := range tools {
+		tagged[index] = &failureTaggedTool{AgentTool: tool}
+	}
+
+	return tagged
+}
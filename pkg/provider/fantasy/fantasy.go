@@ -2,32 +2,50 @@ package fantasy
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	core "charm.land/fantasy"
+	provideranthropic "charm.land/fantasy/providers/anthropic"
 	provideropenai "charm.land/fantasy/providers/openai"
 
 	"miniclaw/pkg/config"
 	providertypes "miniclaw/pkg/provider/types"
+	"miniclaw/pkg/store"
 	fantasytools "miniclaw/pkg/tools/fantasy"
 	fstools "miniclaw/pkg/tools/fs"
-	"miniclaw/pkg/workspace"
+	"miniclaw/pkg/tools/mcp"
+	plantools "miniclaw/pkg/tools/plan"
+	"miniclaw/pkg/tools/scratchpad"
+	"miniclaw/pkg/tools/variables"
+	"miniclaw/pkg/transcript"
+)
+
+const (
+	providerOpenAI    = "openai"
+	providerAnthropic = "anthropic"
 )
 
 type languageModelProvider interface {
 	LanguageModel(ctx context.Context, modelID string) (core.LanguageModel, error)
 }
 
-// Client is an in-memory session provider powered by charm.land/fantasy.
+// Client is a session provider powered by charm.land/fantasy.
+//
+// Conversation history lives in a store.SessionStore: in memory by default,
+// or on disk when storage is configured.
 type Client struct {
 	provider        languageModelProvider
+	providerID      string
+	promptCache     bool
 	requestTimeout  time.Duration
 	modelID         string
 	maxOutputTokens *int64
@@ -35,70 +53,122 @@ type Client struct {
 	generate        func(context.Context, core.LanguageModel, core.AgentCall, []core.AgentOption) (*core.AgentResult, error)
 	tools           []core.AgentTool
 	maxToolSteps    int
-
-	mu            sync.RWMutex
+	mcpClients      []*mcp.Client
+	limits          providertypes.PayloadLimits
+	contextBudget   providertypes.ContextBudget
+	// compaction is nil unless agents.defaults.compaction is enabled.
+	compaction *compaction
+	// subagents is nil unless tools.subagents is enabled.
+	subagents *subagents
+	// titles is nil unless agents.defaults.titles is enabled.
+	titles *titles
+	// variableDefaults seeds each session's variables; nil unless tools.variables is enabled.
+	variableDefaults map[string]string
+
+	sessions store.SessionStore
+
+	mu            sync.Mutex
 	nextSessionID uint64
-	sessions      map[string][]core.Message
+	// toolMeters holds in-memory tool usage per session for quotas and metrics.
+	toolMeters map[string]*fantasytools.ToolMeter
+	// plans holds the in-memory task plan per session for the plan tools.
+	plans map[string]*plantools.Plan
+	// scratchpads holds the in-memory snippets per session for the scratchpad tools.
+	scratchpads map[string]*scratchpad.Pad
+	// variables holds the in-memory variables per session for {{name}} expansion.
+	variables map[string]*variables.Store
 }
 
-// New constructs a fantasy-backed OpenAI provider client.
+// New constructs a fantasy-backed client for the OpenAI or Anthropic provider.
 func New(cfg *config.Config) (*Client, error) {
 	if cfg == nil {
 		return nil, errors.New("config is required")
 	}
 
-	if strings.TrimSpace(cfg.Agents.Defaults.Provider) != "openai" {
-		return nil, fmt.Errorf("fantasy-agent currently supports only provider openai, got %q", cfg.Agents.Defaults.Provider)
+	providerID := strings.TrimSpace(cfg.Agents.Defaults.Provider)
+	var (
+		fantasyProvider languageModelProvider
+		requestTimeout  time.Duration
+		promptCache     bool
+		limitsConfig    config.PayloadLimitsConfig
+		err             error
+	)
+	switch providerID {
+	case providerOpenAI:
+		fantasyProvider, err = newOpenAIProvider(cfg.Providers.OpenAI)
+		requestTimeout = time.Duration(cfg.Providers.OpenAI.RequestTimeoutSeconds) * time.Second
+		limitsConfig = cfg.Providers.OpenAI.Limits
+	case providerAnthropic:
+		fantasyProvider, err = newAnthropicProvider(cfg.Providers.Anthropic)
+		requestTimeout = time.Duration(cfg.Providers.Anthropic.RequestTimeoutSeconds) * time.Second
+		limitsConfig = cfg.Providers.Anthropic.Limits
+		promptCache = !cfg.Providers.Anthropic.DisablePromptCache
+	default:
+		return nil, fmt.Errorf("fantasy-agent supports providers openai and anthropic, got %q", cfg.Agents.Defaults.Provider)
 	}
-
-	apiKey := resolveAPIKey()
-	if apiKey == "" {
-		return nil, errors.New("OPENAI_API_KEY must be set")
+	if err != nil {
+		return nil, err
 	}
 
-	modelID, err := normalizeOpenAIModel(cfg.Agents.Defaults.Model)
+	modelID, err := normalizeModel(providerID, cfg.Agents.Defaults.Model)
 	if err != nil {
 		return nil, err
 	}
 
-	providerOptions := []provideropenai.Option{provideropenai.WithAPIKey(apiKey)}
-	if baseURL := strings.TrimSpace(cfg.Providers.OpenAI.BaseURL); baseURL != "" {
-		providerOptions = append(providerOptions, provideropenai.WithBaseURL(baseURL))
-	}
-	if organization := strings.TrimSpace(cfg.Providers.OpenAI.Organization); organization != "" {
-		providerOptions = append(providerOptions, provideropenai.WithOrganization(organization))
+	limits, err := providertypes.NewPayloadLimits(providerID, limitsConfig)
+	if err != nil {
+		return nil, err
 	}
-	if project := strings.TrimSpace(cfg.Providers.OpenAI.Project); project != "" {
-		providerOptions = append(providerOptions, provideropenai.WithProject(project))
+	contextBudget, err := providertypes.NewContextBudget(cfg.Agents.Defaults.Context)
+	if err != nil {
+		return nil, err
 	}
 
-	fantasyProvider, err := provideropenai.New(providerOptions...)
+	tools, err := fantasytools.BuildWorkspaceTools(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("initialize fantasy openai provider: %w", err)
+		return nil, err
+	}
+	if cfg.Tools.Plan.Enabled {
+		tools = append(tools, fantasytools.BuildPlanTools()...)
+	}
+	if cfg.Tools.Scratchpad.Enabled {
+		tools = append(tools, fantasytools.BuildScratchpadTools()...)
+	}
+	var variableDefaults map[string]string
+	if cfg.Tools.Variables.Enabled {
+		if err := variables.Validate(cfg.Tools.Variables.Defaults); err != nil {
+			return nil, fmt.Errorf("tools.variables.defaults: %w", err)
+		}
+		variableDefaults = cfg.Tools.Variables.Defaults
+		if variableDefaults == nil {
+			variableDefaults = map[string]string{}
+		}
+		tools = append(tools, fantasytools.BuildVariableTools()...)
 	}
 
-	requestTimeout := time.Duration(cfg.Providers.OpenAI.RequestTimeoutSeconds) * time.Second
-
-	guard, err := workspace.NewGuardWithPolicy(cfg.Agents.Defaults.Workspace, cfg.Agents.Defaults.RestrictToWorkspace)
+	sessionStore, err := store.Open(cfg.Storage)
 	if err != nil {
-		return nil, fmt.Errorf("initialize workspace guard: %w", err)
+		return nil, fmt.Errorf("open session store: %w", err)
 	}
-
-	fsService := fstools.NewService(guard)
-	tools := fantasytools.BuildFSTools(fsService, guard)
 	maxToolSteps := cfg.Agents.Defaults.MaxToolIterations
 	if maxToolSteps <= 0 {
 		maxToolSteps = 20
 	}
 
 	client := &Client{
-		provider:       fantasyProvider,
-		requestTimeout: requestTimeout,
-		modelID:        modelID,
-		tools:          tools,
-		maxToolSteps:   maxToolSteps,
-		sessions:       make(map[string][]core.Message),
-		generate:       generateWithFantasyAgent,
+		provider:         fantasyProvider,
+		providerID:       providerID,
+		promptCache:      promptCache,
+		requestTimeout:   requestTimeout,
+		modelID:          modelID,
+		tools:            tools,
+		maxToolSteps:     maxToolSteps,
+		sessions:         sessionStore,
+		generate:         generateWithFantasyAgent,
+		limits:           limits,
+		contextBudget:    contextBudget,
+		compaction:       newCompaction(cfg.Agents.Defaults.Compaction),
+		variableDefaults: variableDefaults,
 	}
 
 	if cfg.Agents.Defaults.MaxTokens > 0 {
@@ -110,26 +180,117 @@ func New(cfg *config.Config) (*Client, error) {
 		client.temperature = &temp
 	}
 
+	if client.subagents, err = newSubagents(cfg.Tools.Subagents, providerID, modelID); err != nil {
+		return nil, err
+	}
+	if client.subagents != nil {
+		client.tools = append(client.tools, fantasytools.BuildSubagentTools(client.runSubagent)...)
+	}
+	if client.titles, err = newTitles(cfg.Agents.Defaults.Titles, providerID, modelID); err != nil {
+		return nil, err
+	}
+
+	compressor, err := fantasytools.NewResultCompressor(cfg.Tools.Results, client.summarizeToolResult)
+	if err != nil {
+		return nil, err
+	}
+	approval, err := fantasytools.NewApprovalPolicy(cfg.Tools.Approval)
+	if err != nil {
+		return nil, err
+	}
+	quota, err := fantasytools.NewToolQuota(cfg.Tools.Quotas)
+	if err != nil {
+		return nil, err
+	}
+	cacheStamps, err := fantasytools.WorkspaceCacheStamps(cfg)
+	if err != nil {
+		return nil, err
+	}
+	webhookTools, err := fantasytools.BuildWebhookTools(cfg.Tools.Webhooks)
+	if err != nil {
+		return nil, err
+	}
+	for _, tool := range client.tools {
+		if slices.ContainsFunc(webhookTools, func(webhook core.AgentTool) bool { return webhook.Info().Name == tool.Info().Name }) {
+			return nil, fmt.Errorf("tools.webhooks: %q is a built-in tool name", tool.Info().Name)
+		}
+	}
+	client.tools = append(client.tools, webhookTools...)
+
+	// Connect MCP servers last so no config error above leaves them running.
+	mcpTools, mcpClients, err := fantasytools.ConnectMCPTools(context.Background(), cfg.Tools.MCP)
+	if err != nil {
+		return nil, err
+	}
+	client.tools = append(client.tools, mcpTools...)
+	client.mcpClients = mcpClients
+	if client.tools, err = selectTools(client.tools, cfg.Agents.Defaults.Tools); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+
+	if promptCache {
+		markToolsCacheable(client.tools)
+	}
+	client.tools = tagToolFailures(fantasytools.WrapWithTiming(fantasytools.WrapWithResultCache(fantasytools.WrapWithMetering(fantasytools.WrapWithApproval(fantasytools.WrapWithCompression(client.tools, compressor), approval), quota), cacheStamps)))
+
 	return client, nil
 }
 
+// selectTools keeps the tools named in allow, or all of them when allow is
+// empty. Names that match no enabled tool are an error.
+func selectTools(tools []core.AgentTool, allow []string) ([]core.AgentTool, error) {
+	if len(allow) == 0 {
+		return tools, nil
+	}
+
+	selected := make([]core.AgentTool, 0, len(allow))
+	for _, name := range allow {
+		name = strings.TrimSpace(name)
+		index := slices.IndexFunc(tools, func(tool core.AgentTool) bool { return tool.Info().Name == name })
+		if index < 0 {
+			return nil, fmt.Errorf("agents.defaults.tools: %q is not an enabled tool", name)
+		}
+		selected = append(selected, tools[index])
+	}
+
+	return selected, nil
+}
+
+// Close waits for session names still being generated and stops the MCP
+// servers the client connected to.
+func (c *Client) Close() error {
+	if c.titles != nil {
+		c.titles.pending.Wait()
+	}
+
+	var errs []error
+	for _, client := range c.mcpClients {
+		if err := client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close mcp server %s: %w", client.Name(), err))
+		}
+	}
+	c.mcpClients = nil
+
+	return errors.Join(errs...)
+}
+
 // Health verifies that the configured model can be resolved.
 func (c *Client) Health(ctx context.Context) error {
 	ctx, cancel := c.withTimeout(ctx)
 	defer cancel()
 
 	if _, err := c.provider.LanguageModel(ctx, c.modelID); err != nil {
-		return fmt.Errorf("health check failed: %w", err)
+		return c.classifyError(fmt.Errorf("health check failed: %w", err))
 	}
 
 	return nil
 }
 
-// CreateSession allocates an in-memory session identifier.
+// CreateSession allocates a session identifier in the session store.
+//
+// IDs already taken in a persistent store (from earlier runs) are skipped.
 func (c *Client) CreateSession(ctx context.Context, title string) (string, error) {
-	// The fantasy provider keeps sessions in-memory only; title is currently informational.
-	_ = title
-
 	ctx, cancel := c.withTimeout(ctx)
 	defer cancel()
 	if err := ctx.Err(); err != nil {
@@ -139,11 +300,41 @@ func (c *Client) CreateSession(ctx context.Context, title string) (string, error
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.nextSessionID++
-	sessionID := "fantasy-session-" + strconv.FormatUint(c.nextSessionID, 10)
-	c.sessions[sessionID] = nil
+	for {
+		c.nextSessionID++
+		sessionID := "fantasy-session-" + strconv.FormatUint(c.nextSessionID, 10)
+		_, err := c.sessions.Create(ctx, sessionID, title)
+		if errors.Is(err, store.ErrExists) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("create session: %w", err)
+		}
+
+		return sessionID, nil
+	}
+}
+
+// LatestSession returns the most recently updated stored session created
+// with title. With a persistent storage backend this finds sessions from
+// earlier runs, so callers can resume them.
+func (c *Client) LatestSession(ctx context.Context, title string) (string, bool, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	summaries, err := c.sessions.List(ctx)
+	if err != nil {
+		return "", false, fmt.Errorf("list sessions: %w", err)
+	}
+	// List is ordered oldest first, so ties on a coarse file mtime go to the newer session.
+	var latest store.Summary
+	for _, summary := range summaries {
+		if summary.Title == title && (latest.ID == "" || !summary.UpdatedAt.Before(latest.UpdatedAt)) {
+			latest = summary
+		}
+	}
 
-	return sessionID, nil
+	return latest.ID, latest.ID != "", nil
 }
 
 // Prompt executes one prompt against the selected model and updates session history.
@@ -163,17 +354,23 @@ func (c *Client) Prompt(ctx context.Context, sessionID string, prompt string, mo
 		return providertypes.PromptResult{}, errors.New("prompt is required")
 	}
 
-	modelID, err := normalizeOpenAIModel(model)
+	modelID, err := normalizeModel(c.providerName(), model)
 	if err != nil {
 		return providertypes.PromptResult{}, err
 	}
 
-	history, ok := c.sessionHistory(sessionID)
-	if !ok {
+	history, err := c.sessionHistory(ctx, sessionID)
+	if errors.Is(err, store.ErrNotFound) {
 		return providertypes.PromptResult{}, errors.New("session is not started")
 	}
+	if err != nil {
+		return providertypes.PromptResult{}, err
+	}
+	firstPrompt := !slices.ContainsFunc(history, func(message core.Message) bool { return message.Role == core.MessageRoleUser })
 
-	trimmedSystemPrompt := strings.TrimSpace(systemPrompt)
+	vars := c.Variables(sessionID)
+	prompt = vars.Expand(prompt)
+	trimmedSystemPrompt := strings.TrimSpace(vars.Expand(systemPrompt))
 	if trimmedSystemPrompt != "" && len(history) == 0 {
 		systemMessage := core.Message{
 			Role: core.MessageRoleSystem,
@@ -182,12 +379,36 @@ func (c *Client) Prompt(ctx context.Context, sessionID string, prompt string, mo
 			},
 		}
 		history = append(history, systemMessage)
-		c.appendSessionMessages(sessionID, systemMessage)
+		if err := c.appendSessionMessages(ctx, sessionID, systemMessage); err != nil {
+			return providertypes.PromptResult{}, err
+		}
 	}
 
 	languageModel, err := c.provider.LanguageModel(ctx, modelID)
 	if err != nil {
-		return providertypes.PromptResult{}, fmt.Errorf("resolve language model: %w", err)
+		return providertypes.PromptResult{}, c.classifyError(fmt.Errorf("resolve language model: %w", err))
+	}
+
+	history, compactionUsage, err := c.compactHistory(ctx, sessionID, languageModel, history)
+	if err != nil {
+		// The uncompacted history still works; the request limits below apply to it.
+		providertypes.TraceLogger(ctx, slog.Default().With("component", "provider.fantasy")).Warn("History compaction failed", "session_id", sessionID, "error", err)
+	}
+
+	history, dropped, err := c.fitRequest(history, prompt)
+	if err != nil {
+		return providertypes.PromptResult{}, err
+	}
+	if dropped > 0 {
+		providertypes.TraceLogger(ctx, slog.Default().With("component", "provider.fantasy")).Warn("Request history truncated",
+			"session_id", sessionID,
+			"dropped_messages", dropped,
+			"max_request_bytes", c.limits.MaxRequestBytes,
+		)
+	}
+
+	if c.promptCache {
+		history = applyCacheHints(history)
 	}
 
 	call := core.AgentCall{
@@ -207,23 +428,44 @@ func (c *Client) Prompt(ctx context.Context, sessionID string, prompt string, mo
 	}
 
 	agentOptions := c.buildAgentOptions()
+	ctx, toolTimer := providertypes.WithToolTimer(ctx)
+	meter := c.toolMeter(sessionID)
+	usageBefore := meter.Usage()
+	ctx = fantasytools.WithToolMeter(ctx, meter)
+	ctx = fantasytools.WithToolResultCache(ctx, fantasytools.NewToolResultCache())
+	ctx = fantasytools.WithPlan(ctx, c.plan(sessionID))
+	ctx = fantasytools.WithScratchpad(ctx, c.scratchpad(sessionID))
+	if vars != nil {
+		ctx = fantasytools.WithVariables(ctx, vars)
+	}
+	if _, ok := fstools.AuditSessionFromContext(ctx); !ok {
+		ctx = fstools.WithAuditSession(ctx, sessionID)
+	}
+	children := &childUsage{}
+	ctx = withChildUsage(ctx, children)
 	result, err := generate(ctx, languageModel, call, agentOptions)
 	if err != nil {
-		return providertypes.PromptResult{}, fmt.Errorf("prompt failed: %w", err)
+		return providertypes.PromptResult{}, c.classifyError(fmt.Errorf("prompt failed: %w", err))
 	}
 
 	if c.shouldFinalizeAfterLimit(result) {
 		finalized, finalizeErr := c.generateFinalSummaryStep(ctx, languageModel, history, prompt, result, agentOptions)
 		if finalizeErr != nil {
-			return providertypes.PromptResult{}, fmt.Errorf("finalize limited tool run: %w", finalizeErr)
+			return providertypes.PromptResult{}, c.classifyError(fmt.Errorf("finalize limited tool run: %w", finalizeErr))
 		}
 		result = finalized
 	}
 
+	result.TotalUsage = addUsage(addUsage(result.TotalUsage, compactionUsage), children.usage())
+
 	response := extractText(result.Response.Content)
 	if response == "" {
 		return providertypes.PromptResult{}, errors.New("prompt succeeded but returned no text")
 	}
+	response, err = c.limits.LimitResponse(response)
+	if err != nil {
+		return providertypes.PromptResult{}, err
+	}
 
 	messagesToAppend := []core.Message{core.NewUserMessage(prompt)}
 	if len(c.tools) > 0 {
@@ -246,7 +488,12 @@ func (c *Client) Prompt(ctx context.Context, sessionID string, prompt string, mo
 			},
 		})
 	}
-	c.appendSessionMessages(sessionID, messagesToAppend...)
+	if err := c.appendSessionMessages(ctx, sessionID, messagesToAppend...); err != nil {
+		return providertypes.PromptResult{}, err
+	}
+	if firstPrompt {
+		c.nameSession(ctx, sessionID, prompt)
+	}
 
 	usage := providertypes.TokenUsage{
 		InputTokens:         result.TotalUsage.InputTokens,
@@ -257,8 +504,23 @@ func (c *Client) Prompt(ctx context.Context, sessionID string, prompt string, mo
 		CacheReadTokens:     result.TotalUsage.CacheReadTokens,
 	}
 
+	if !usage.IsZero() {
+		if err := c.appendUsage(ctx, sessionID, usage); err != nil {
+			return providertypes.PromptResult{}, err
+		}
+	}
+
+	if usage.CacheReadTokens > 0 || usage.CacheCreationTokens > 0 {
+		providertypes.TraceLogger(ctx, slog.Default().With("component", "provider.fantasy")).Debug("Prompt cache usage",
+			"session_id", sessionID,
+			"cache_read_tokens", usage.CacheReadTokens,
+			"cache_creation_tokens", usage.CacheCreationTokens,
+			"input_tokens", usage.InputTokens,
+		)
+	}
+
 	metadata := providertypes.PromptMetadata{
-		Provider: "openai",
+		Provider: c.providerName(),
 		Model:    modelID,
 		Agent:    strings.TrimSpace(agent),
 	}
@@ -268,6 +530,13 @@ func (c *Client) Prompt(ctx context.Context, sessionID string, prompt string, mo
 	if !usage.IsZero() {
 		metadata.Usage = &usage
 	}
+	if toolTime := toolTimer.Total(); toolTime > 0 {
+		metadata.Timing = &providertypes.TurnTiming{Tools: toolTime}
+	}
+	if toolUsage := meter.Usage().Sub(usageBefore); !toolUsage.IsZero() {
+		metadata.ToolUsage = &toolUsage
+	}
+	metadata.Context = c.contextUsage(modelID, result.Steps, history, messagesToAppend)
 
 	return providertypes.PromptResult{
 		Text:     response,
@@ -275,6 +544,88 @@ func (c *Client) Prompt(ctx context.Context, sessionID string, prompt string, mo
 	}, nil
 }
 
+// toolMeter returns the tool usage meter for sessionID, creating it on first use.
+func (c *Client) toolMeter(sessionID string) *fantasytools.ToolMeter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.toolMeters == nil {
+		c.toolMeters = make(map[string]*fantasytools.ToolMeter)
+	}
+	meter, ok := c.toolMeters[sessionID]
+	if !ok {
+		meter = &fantasytools.ToolMeter{}
+		c.toolMeters[sessionID] = meter
+	}
+
+	return meter
+}
+
+// plan returns the task plan for sessionID, creating it on first use.
+func (c *Client) plan(sessionID string) *plantools.Plan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.plans == nil {
+		c.plans = make(map[string]*plantools.Plan)
+	}
+	p, ok := c.plans[sessionID]
+	if !ok {
+		p = &plantools.Plan{}
+		c.plans[sessionID] = p
+	}
+
+	return p
+}
+
+// scratchpad returns the scratchpad for sessionID, creating it on first use.
+func (c *Client) scratchpad(sessionID string) *scratchpad.Pad {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.scratchpads == nil {
+		c.scratchpads = make(map[string]*scratchpad.Pad)
+	}
+	pad, ok := c.scratchpads[sessionID]
+	if !ok {
+		pad = &scratchpad.Pad{}
+		c.scratchpads[sessionID] = pad
+	}
+
+	return pad
+}
+
+// Variables returns the variables of sessionID, creating them from
+// tools.variables.defaults on first use, or nil when tools.variables is off.
+func (c *Client) Variables(sessionID string) *variables.Store {
+	if c.variableDefaults == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.variables == nil {
+		c.variables = make(map[string]*variables.Store)
+	}
+	vars, ok := c.variables[sessionID]
+	if !ok {
+		vars = variables.NewStore(c.variableDefaults)
+		c.variables[sessionID] = vars
+	}
+
+	return vars
+}
+
+// providerName reports the configured provider, defaulting to openai.
+func (c *Client) providerName() string {
+	if c.providerID == "" {
+		return providerOpenAI
+	}
+
+	return c.providerID
+}
+
 func (c *Client) buildAgentOptions() []core.AgentOption {
 	if len(c.tools) == 0 {
 		return nil
@@ -377,6 +728,28 @@ func stepMessages(steps []core.StepResult) []core.Message {
 	return messages
 }
 
+// summarizeToolResult asks the session model for a compact summary of one tool result.
+func (c *Client) summarizeToolResult(ctx context.Context, toolName string, text string) (string, error) {
+	languageModel, err := c.provider.LanguageModel(ctx, c.modelID)
+	if err != nil {
+		return "", fmt.Errorf("resolve language model: %w", err)
+	}
+
+	maxTokens := int64(1024)
+	response, err := languageModel.Generate(ctx, core.Call{
+		Prompt: core.Prompt{
+			core.NewSystemMessage("Summarize the following tool output for an agent that will act on it. Keep file paths, line numbers, identifiers, error messages, and numbers exact. Be concise."),
+			core.NewUserMessage(fmt.Sprintf("Tool: %s\n\n%s", toolName, text)),
+		},
+		MaxOutputTokens: &maxTokens,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return extractText(response.Content), nil
+}
+
 // withTimeout wraps context with provider-level request timeout when configured.
 func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
 	if c.requestTimeout <= 0 {
@@ -386,43 +759,197 @@ func (c *Client) withTimeout(ctx context.Context) (context.Context, context.Canc
 	return context.WithTimeout(ctx, c.requestTimeout)
 }
 
-// sessionHistory returns a defensive copy of session messages.
-func (c *Client) sessionHistory(sessionID string) ([]core.Message, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+// sessionHistory loads session messages from the session store. A
+// compaction turn replaces the messages before it with its own, and a
+// rollback turn drops the last of them.
+func (c *Client) sessionHistory(ctx context.Context, sessionID string) ([]core.Message, error) {
+	session, err := c.sessions.Load(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
 
-	history, ok := c.sessions[sessionID]
-	if !ok {
-		return nil, false
+	history := make([]core.Message, 0, len(session.Turns))
+	for _, turn := range session.Turns {
+		if turn.Role == transcript.UsageRole {
+			continue
+		}
+		if turn.Role == compactionRole {
+			var compacted []core.Message
+			if err := json.Unmarshal(turn.Payload, &compacted); err != nil {
+				return nil, fmt.Errorf("decode compacted history: %w", err)
+			}
+			history = compacted
+			continue
+		}
+		if turn.Role == transcript.RollbackRole {
+			var rollback transcript.Rollback
+			if err := json.Unmarshal(turn.Payload, &rollback); err != nil {
+				return nil, fmt.Errorf("decode rollback: %w", err)
+			}
+			history = history[:max(len(history)-rollback.Messages, 0)]
+			continue
+		}
+		var message core.Message
+		if err := json.Unmarshal(turn.Payload, &message); err != nil {
+			return nil, fmt.Errorf("decode session message: %w", err)
+		}
+		history = append(history, message)
 	}
 
-	// Copy so callers cannot mutate shared session history.
-	copyHistory := make([]core.Message, len(history))
-	copy(copyHistory, history)
-	return copyHistory, true
+	return history, nil
 }
 
-// appendSessionMessages appends messages to one tracked in-memory session.
-func (c *Client) appendSessionMessages(sessionID string, messages ...core.Message) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// appendSessionMessages persists messages as turns of one session.
+//
+// The full fantasy message is kept as the turn payload so tool calls and
+// results replay exactly; Content carries the plain text for readers of the store.
+func (c *Client) appendSessionMessages(ctx context.Context, sessionID string, messages ...core.Message) error {
+	turns := make([]store.Turn, 0, len(messages))
+	for _, message := range messages {
+		payload, err := json.Marshal(message)
+		if err != nil {
+			return fmt.Errorf("encode session message: %w", err)
+		}
+		turns = append(turns, store.Turn{
+			Role:    string(message.Role),
+			Content: messageText(message),
+			Payload: payload,
+		})
+	}
 
-	history, ok := c.sessions[sessionID]
-	if !ok {
-		return
+	if err := c.sessions.AppendTurn(ctx, sessionID, turns...); err != nil {
+		return fmt.Errorf("persist session messages: %w", err)
+	}
+
+	return nil
+}
+
+// appendUsage persists one prompt's token usage as a usage turn, which
+// session replay skips and transcript exports attach to the prompt.
+func (c *Client) appendUsage(ctx context.Context, sessionID string, usage providertypes.TokenUsage) error {
+	payload, err := json.Marshal(transcript.Usage{
+		InputTokens:         usage.InputTokens,
+		OutputTokens:        usage.OutputTokens,
+		TotalTokens:         usage.TotalTokens,
+		ReasoningTokens:     usage.ReasoningTokens,
+		CacheCreationTokens: usage.CacheCreationTokens,
+		CacheReadTokens:     usage.CacheReadTokens,
+	})
+	if err != nil {
+		return fmt.Errorf("encode usage: %w", err)
+	}
+	if err := c.sessions.AppendTurn(ctx, sessionID, store.Turn{Role: transcript.UsageRole, Payload: payload}); err != nil {
+		return fmt.Errorf("persist usage: %w", err)
+	}
+
+	return nil
+}
+
+// RollbackLastTurn drops the last user prompt of a session and the replies
+// and tool calls after it. The stored turns stay append-only: a rollback turn
+// records how many messages replay should drop. Token usage already spent
+// stays recorded.
+func (c *Client) RollbackLastTurn(ctx context.Context, sessionID string) error {
+	history, err := c.sessionHistory(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	for index, message := range slices.Backward(history) {
+		if message.Role != core.MessageRoleUser {
+			continue
+		}
+		payload, err := json.Marshal(transcript.Rollback{Messages: len(history) - index})
+		if err != nil {
+			return fmt.Errorf("encode rollback: %w", err)
+		}
+		turn := store.Turn{Role: transcript.RollbackRole, Content: messageText(message), Payload: payload}
+		if err := c.sessions.AppendTurn(ctx, sessionID, turn); err != nil {
+			return fmt.Errorf("persist rollback: %w", err)
+		}
+		return nil
 	}
 
-	history = append(history, messages...)
-	c.sessions[sessionID] = history
+	return providertypes.ErrNoTurnToUndo
+}
+
+// ExportSession copies a stored session, with its tool calls and usage, out
+// of the session store.
+func (c *Client) ExportSession(ctx context.Context, sessionID string) (transcript.Transcript, error) {
+	return transcript.Export(ctx, c.sessions, sessionID)
 }
 
-// resolveAPIKey reads OPENAI_API_KEY from environment.
-func resolveAPIKey() string {
-	return strings.TrimSpace(os.Getenv("OPENAI_API_KEY"))
+// ImportSession stores an exported session as a new session titled title and
+// returns its ID; resuming title continues the imported conversation.
+func (c *Client) ImportSession(ctx context.Context, exported transcript.Transcript, title string) (string, error) {
+	return transcript.Import(ctx, c.sessions, exported, title)
 }
 
-// normalizeOpenAIModel accepts bare model IDs or openai/<model> references.
-func normalizeOpenAIModel(model string) (string, error) {
+// messageText joins the text parts of a message.
+func messageText(message core.Message) string {
+	lines := make([]string, 0, len(message.Content))
+	for _, part := range message.Content {
+		if text, ok := part.(core.TextPart); ok && strings.TrimSpace(text.Text) != "" {
+			lines = append(lines, strings.TrimSpace(text.Text))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// newOpenAIProvider builds the fantasy OpenAI provider from config and OPENAI_API_KEY.
+func newOpenAIProvider(cfg config.OpenAIProviderConfig) (languageModelProvider, error) {
+	apiKey := resolveAPIKey("OPENAI_API_KEY")
+	if apiKey == "" {
+		return nil, errors.New("OPENAI_API_KEY must be set")
+	}
+
+	providerOptions := []provideropenai.Option{provideropenai.WithAPIKey(apiKey)}
+	if baseURL := strings.TrimSpace(cfg.BaseURL); baseURL != "" {
+		providerOptions = append(providerOptions, provideropenai.WithBaseURL(baseURL))
+	}
+	if organization := strings.TrimSpace(cfg.Organization); organization != "" {
+		providerOptions = append(providerOptions, provideropenai.WithOrganization(organization))
+	}
+	if project := strings.TrimSpace(cfg.Project); project != "" {
+		providerOptions = append(providerOptions, provideropenai.WithProject(project))
+	}
+
+	fantasyProvider, err := provideropenai.New(providerOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("initialize fantasy openai provider: %w", err)
+	}
+
+	return fantasyProvider, nil
+}
+
+// newAnthropicProvider builds the fantasy Anthropic provider from config and ANTHROPIC_API_KEY.
+func newAnthropicProvider(cfg config.AnthropicProviderConfig) (languageModelProvider, error) {
+	apiKey := resolveAPIKey("ANTHROPIC_API_KEY")
+	if apiKey == "" {
+		return nil, errors.New("ANTHROPIC_API_KEY must be set")
+	}
+
+	providerOptions := []provideranthropic.Option{provideranthropic.WithAPIKey(apiKey)}
+	if baseURL := strings.TrimSpace(cfg.BaseURL); baseURL != "" {
+		providerOptions = append(providerOptions, provideranthropic.WithBaseURL(baseURL))
+	}
+
+	fantasyProvider, err := provideranthropic.New(providerOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("initialize fantasy anthropic provider: %w", err)
+	}
+
+	return fantasyProvider, nil
+}
+
+// resolveAPIKey reads a provider API key from environment.
+func resolveAPIKey(envName string) string {
+	return strings.TrimSpace(os.Getenv(envName))
+}
+
+// normalizeModel accepts bare model IDs or <provider>/<model> references for the active provider.
+func normalizeModel(providerID string, model string) (string, error) {
 	model = strings.TrimSpace(model)
 	if model == "" {
 		return "", errors.New("model is required")
@@ -433,13 +960,13 @@ func normalizeOpenAIModel(model string) (string, error) {
 		return model, nil
 	}
 
-	providerID := strings.TrimSpace(parts[0])
+	prefix := strings.TrimSpace(parts[0])
 	modelID := strings.TrimSpace(parts[1])
-	if providerID == "" || modelID == "" {
+	if prefix == "" || modelID == "" {
 		return "", errors.New("model is invalid")
 	}
-	if providerID != "openai" {
-		return "", fmt.Errorf("model provider %q is not supported by fantasy openai provider", providerID)
+	if prefix != providerID {
+		return "", fmt.Errorf("model provider %q is not supported by fantasy %s provider", prefix, providerID)
 	}
 
 	return modelID, nil
@@ -513,7 +1040,35 @@ func formatToolResultOutput(output any) string {
 }
 
 // generateWithFantasyAgent delegates prompt generation to fantasy runtime.
+// With a text delta handler on ctx it streams the reply and passes each text
+// delta to the handler; the result is the same as without one.
 func generateWithFantasyAgent(ctx context.Context, model core.LanguageModel, call core.AgentCall, options []core.AgentOption) (*core.AgentResult, error) {
 	runtime := core.NewAgent(model, options...)
-	return runtime.Generate(ctx, call)
+	onDelta, ok := providertypes.TextDeltaHandlerFromContext(ctx)
+	if !ok {
+		return runtime.Generate(ctx, call)
+	}
+
+	return runtime.Stream(ctx, core.AgentStreamCall{
+		Prompt:           call.Prompt,
+		Files:            call.Files,
+		Messages:         call.Messages,
+		MaxOutputTokens:  call.MaxOutputTokens,
+		Temperature:      call.Temperature,
+		TopP:             call.TopP,
+		TopK:             call.TopK,
+		PresencePenalty:  call.PresencePenalty,
+		FrequencyPenalty: call.FrequencyPenalty,
+		ActiveTools:      call.ActiveTools,
+		ProviderOptions:  call.ProviderOptions,
+		OnRetry:          call.OnRetry,
+		MaxRetries:       call.MaxRetries,
+		StopWhen:         call.StopWhen,
+		PrepareStep:      call.PrepareStep,
+		RepairToolCall:   call.RepairToolCall,
+		OnTextDelta: func(_ string, text string) error {
+			onDelta(text)
+			return nil
+		},
+	})
 }
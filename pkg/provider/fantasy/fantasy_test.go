@@ -6,11 +6,18 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	core "charm.land/fantasy"
+	provideranthropic "charm.land/fantasy/providers/anthropic"
 
 	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+	"miniclaw/pkg/store"
+	fantasytools "miniclaw/pkg/tools/fantasy"
+	"miniclaw/pkg/transcript"
 )
 
 type fakeLanguageModelProvider struct {
@@ -90,46 +97,192 @@ func TestNewInitializesToolsAndDefaultIterationLimit(t *testing.T) {
 	if err != nil {
 		t.Fatalf("New error: %v", err)
 	}
-	if len(client.tools) != 5 {
-		t.Fatalf("tools length = %d, want 5", len(client.tools))
+	if len(client.tools) != 15 {
+		t.Fatalf("tools length = %d, want 15", len(client.tools))
 	}
 	if client.maxToolSteps != 20 {
 		t.Fatalf("maxToolSteps = %d, want 20", client.maxToolSteps)
 	}
 }
 
-func TestNormalizeOpenAIModel(t *testing.T) {
+func TestNewRegistersWebhookToolsAndRejectsNameClashes(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+
+	cfg := &config.Config{}
+	cfg.Agents.Defaults.Provider = "openai"
+	cfg.Agents.Defaults.Model = "openai/gpt-5.2"
+	cfg.Agents.Defaults.Workspace = filepath.Join(t.TempDir(), "workspace")
+	cfg.Tools.Webhooks = []config.WebhookToolConfig{{Name: "notify", URL: "https://hooks.example.com/notify"}}
+
+	client, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	if len(client.tools) != 16 || client.tools[15].Info().Name != "notify" {
+		t.Fatalf("tools length = %d, want 15 built-in tools plus notify", len(client.tools))
+	}
+
+	cfg.Tools.Webhooks[0].Name = "read_file"
+	if _, err := New(cfg); err == nil {
+		t.Fatal("expected a webhook named like a built-in tool to be rejected")
+	}
+}
+
+func TestNewRegistersRunCommandWhenExecEnabled(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+
+	cfg := &config.Config{}
+	cfg.Agents.Defaults.Provider = "openai"
+	cfg.Agents.Defaults.Model = "openai/gpt-5.2"
+	cfg.Agents.Defaults.Workspace = filepath.Join(t.TempDir(), "workspace")
+	cfg.Tools.Exec.Enabled = true
+
+	client, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	if len(client.tools) != 16 {
+		t.Fatalf("tools length = %d, want 16", len(client.tools))
+	}
+	if name := client.tools[len(client.tools)-1].Info().Name; name != "run_command" {
+		t.Fatalf("last tool = %q, want run_command", name)
+	}
+}
+
+func TestNormalizeModel(t *testing.T) {
 	tests := []struct {
-		name    string
-		input   string
-		want    string
-		wantErr bool
+		name     string
+		provider string
+		input    string
+		want     string
+		wantErr  bool
 	}{
-		{name: "plain model", input: "gpt-5.2", want: "gpt-5.2"},
-		{name: "openai prefixed", input: "openai/gpt-5.2", want: "gpt-5.2"},
-		{name: "non openai prefixed", input: "anthropic/claude", wantErr: true},
-		{name: "empty", input: "", wantErr: true},
+		{name: "plain model", provider: "openai", input: "gpt-5.2", want: "gpt-5.2"},
+		{name: "openai prefixed", provider: "openai", input: "openai/gpt-5.2", want: "gpt-5.2"},
+		{name: "non openai prefixed", provider: "openai", input: "anthropic/claude", wantErr: true},
+		{name: "anthropic prefixed", provider: "anthropic", input: "anthropic/claude-sonnet-4-5", want: "claude-sonnet-4-5"},
+		{name: "openai model on anthropic", provider: "anthropic", input: "openai/gpt-5.2", wantErr: true},
+		{name: "empty", provider: "openai", input: "", wantErr: true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := normalizeOpenAIModel(tt.input)
+			got, err := normalizeModel(tt.provider, tt.input)
 			if (err != nil) != tt.wantErr {
-				t.Fatalf("normalizeOpenAIModel(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+				t.Fatalf("normalizeModel(%q, %q) error = %v, wantErr %v", tt.provider, tt.input, err, tt.wantErr)
 			}
 			if got != tt.want {
-				t.Fatalf("normalizeOpenAIModel(%q) = %q, want %q", tt.input, got, tt.want)
+				t.Fatalf("normalizeModel(%q, %q) = %q, want %q", tt.provider, tt.input, got, tt.want)
 			}
 		})
 	}
 }
 
+func TestNewAnthropicEnablesPromptCache(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "sk-ant-test")
+
+	cfg := &config.Config{}
+	cfg.Agents.Defaults.Provider = "anthropic"
+	cfg.Agents.Defaults.Model = "anthropic/claude-sonnet-4-5"
+	cfg.Agents.Defaults.Workspace = filepath.Join(t.TempDir(), "workspace")
+
+	client, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	if !client.promptCache || client.providerName() != "anthropic" {
+		t.Fatalf("client provider = %q, promptCache = %v; want anthropic with cache", client.providerName(), client.promptCache)
+	}
+	if provideranthropic.GetCacheControl(client.tools[len(client.tools)-1].ProviderOptions()) == nil {
+		t.Fatal("last tool has no cache-control hint")
+	}
+	if provideranthropic.GetCacheControl(client.tools[0].ProviderOptions()) != nil {
+		t.Fatal("first tool unexpectedly has a cache-control hint")
+	}
+
+	cfg.Providers.Anthropic.DisablePromptCache = true
+	client, err = New(cfg)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	if client.promptCache {
+		t.Fatal("promptCache = true, want false when disabled")
+	}
+}
+
+func TestApplyCacheHintsMarksStablePrefixes(t *testing.T) {
+	history := []core.Message{
+		{Role: core.MessageRoleSystem, Content: []core.MessagePart{core.TextPart{Text: "system"}}},
+		core.NewUserMessage("first"),
+		{Role: core.MessageRoleAssistant, Content: []core.MessagePart{core.TextPart{Text: "reply"}}},
+	}
+
+	hinted := applyCacheHints(history)
+	for index, want := range []bool{true, false, true} {
+		got := provideranthropic.GetCacheControl(hinted[index].ProviderOptions) != nil
+		if got != want {
+			t.Fatalf("message %d cache hint = %v, want %v", index, got, want)
+		}
+	}
+	for index, message := range history {
+		if message.ProviderOptions != nil {
+			t.Fatalf("original message %d was modified", index)
+		}
+	}
+}
+
+func TestPromptAppliesCacheHintsAndReportsCacheUsage(t *testing.T) {
+	provider := &fakeLanguageModelProvider{model: &fakeLanguageModel{}}
+	client := &Client{
+		provider:    provider,
+		providerID:  "anthropic",
+		promptCache: true,
+		modelID:     "claude-sonnet-4-5",
+		sessions:    store.NewMemoryStore(),
+	}
+
+	var lastCall core.AgentCall
+	client.generate = func(_ context.Context, _ core.LanguageModel, call core.AgentCall, _ []core.AgentOption) (*core.AgentResult, error) {
+		lastCall = call
+		return &core.AgentResult{
+			Response:   core.Response{Content: core.ResponseContent{core.TextContent{Text: "ok"}}},
+			TotalUsage: core.Usage{InputTokens: 10, OutputTokens: 2, TotalTokens: 12, CacheReadTokens: 900},
+		}, nil
+	}
+
+	sessionID, err := client.CreateSession(context.Background(), "cache")
+	if err != nil {
+		t.Fatalf("CreateSession error: %v", err)
+	}
+	result, err := client.Prompt(context.Background(), sessionID, "hello", "anthropic/claude-sonnet-4-5", "", "be brief")
+	if err != nil {
+		t.Fatalf("Prompt error: %v", err)
+	}
+	if provideranthropic.GetCacheControl(lastCall.Messages[0].ProviderOptions) == nil {
+		t.Fatal("system message has no cache-control hint")
+	}
+	if result.Metadata.Provider != "anthropic" {
+		t.Fatalf("provider = %q, want anthropic", result.Metadata.Provider)
+	}
+	if result.Metadata.Usage == nil || result.Metadata.Usage.CacheReadTokens != 900 {
+		t.Fatalf("usage = %+v, want cache read tokens 900", result.Metadata.Usage)
+	}
+
+	history, err := client.sessionHistory(context.Background(), sessionID)
+	if err != nil {
+		t.Fatalf("sessionHistory error: %v", err)
+	}
+	if history[0].ProviderOptions != nil {
+		t.Fatal("stored system message kept cache-control hint")
+	}
+}
+
 func TestCreateSessionAndHealth(t *testing.T) {
 	provider := &fakeLanguageModelProvider{model: &fakeLanguageModel{}}
 	client := &Client{
 		provider: provider,
 		modelID:  "gpt-5.2",
-		sessions: map[string][]core.Message{},
+		sessions: store.NewMemoryStore(),
 	}
 
 	sessionID, err := client.CreateSession(context.Background(), "title")
@@ -155,7 +308,7 @@ func TestPromptValidatesSessionAndInput(t *testing.T) {
 	client := &Client{
 		provider: &fakeLanguageModelProvider{model: &fakeLanguageModel{}},
 		modelID:  "gpt-5.2",
-		sessions: map[string][]core.Message{},
+		sessions: store.NewMemoryStore(),
 	}
 
 	if _, err := client.Prompt(context.Background(), "", "hello", "gpt-5.2", "", ""); err == nil {
@@ -181,7 +334,7 @@ func TestPromptMaintainsSessionHistory(t *testing.T) {
 	client := &Client{
 		provider: provider,
 		modelID:  "gpt-5.2",
-		sessions: map[string][]core.Message{},
+		sessions: store.NewMemoryStore(),
 		generate: func(ctx context.Context, model core.LanguageModel, call core.AgentCall, _ []core.AgentOption) (*core.AgentResult, error) {
 			generationCalls++
 			return &core.AgentResult{
@@ -215,9 +368,9 @@ func TestPromptMaintainsSessionHistory(t *testing.T) {
 		t.Fatalf("second response = %q, want %q", second.Text, "reply-2")
 	}
 
-	history, ok := client.sessionHistory(sessionID)
-	if !ok {
-		t.Fatal("expected session history")
+	history, err := client.sessionHistory(context.Background(), sessionID)
+	if err != nil {
+		t.Fatalf("sessionHistory error: %v", err)
 	}
 	if len(history) != 4 {
 		t.Fatalf("history length = %d, want 4", len(history))
@@ -250,7 +403,7 @@ func TestPromptInjectsSystemMessageOnFirstTurn(t *testing.T) {
 	client := &Client{
 		provider: provider,
 		modelID:  "gpt-5.2",
-		sessions: map[string][]core.Message{},
+		sessions: store.NewMemoryStore(),
 		generate: func(ctx context.Context, model core.LanguageModel, call core.AgentCall, _ []core.AgentOption) (*core.AgentResult, error) {
 			if firstCallMessages == nil {
 				firstCallMessages = call.Messages
@@ -291,7 +444,7 @@ func TestPromptPersistsStepMessagesWhenToolsEnabled(t *testing.T) {
 		provider: provider,
 		modelID:  "gpt-5.2",
 		tools:    []core.AgentTool{tool},
-		sessions: map[string][]core.Message{},
+		sessions: store.NewMemoryStore(),
 		generate: func(ctx context.Context, model core.LanguageModel, call core.AgentCall, _ []core.AgentOption) (*core.AgentResult, error) {
 			return &core.AgentResult{
 				Steps: []core.StepResult{
@@ -324,9 +477,9 @@ func TestPromptPersistsStepMessagesWhenToolsEnabled(t *testing.T) {
 		t.Fatalf("first tool event kind = %q, want %q", result.Metadata.ToolEvents[0].Kind, "result")
 	}
 
-	history, ok := client.sessionHistory(sessionID)
-	if !ok {
-		t.Fatal("expected session history")
+	history, err := client.sessionHistory(context.Background(), sessionID)
+	if err != nil {
+		t.Fatalf("sessionHistory error: %v", err)
 	}
 	if len(history) != 3 {
 		t.Fatalf("history length = %d, want 3", len(history))
@@ -355,7 +508,7 @@ func TestPromptGeneratesFinalSummaryWhenToolLimitReached(t *testing.T) {
 		modelID:      "gpt-5.2",
 		tools:        []core.AgentTool{tool},
 		maxToolSteps: 1,
-		sessions:     map[string][]core.Message{},
+		sessions:     store.NewMemoryStore(),
 		generate: func(ctx context.Context, model core.LanguageModel, call core.AgentCall, options []core.AgentOption) (*core.AgentResult, error) {
 			calls++
 			_ = model
@@ -461,3 +614,490 @@ func TestExtractToolEventsFromSteps(t *testing.T) {
 		t.Fatalf("result tool = %q, want %q", got, "read_file")
 	}
 }
+
+func TestPromptClassifiesProviderErrors(t *testing.T) {
+	client := &Client{
+		provider:   &fakeLanguageModelProvider{model: &fakeLanguageModel{}},
+		providerID: providerAnthropic,
+		modelID:    "claude-sonnet-4-5",
+		sessions:   store.NewMemoryStore(),
+		generate: func(context.Context, core.LanguageModel, core.AgentCall, []core.AgentOption) (*core.AgentResult, error) {
+			return nil, &core.RetryError{Errors: []error{&core.ProviderError{
+				Message:         "rate limit exceeded",
+				StatusCode:      429,
+				ResponseHeaders: map[string]string{"retry-after": "7"},
+			}}}
+		},
+	}
+
+	sessionID, err := client.CreateSession(context.Background(), "")
+	if err != nil {
+		t.Fatalf("CreateSession error: %v", err)
+	}
+
+	_, err = client.Prompt(context.Background(), sessionID, "hello", "claude-sonnet-4-5", "", "")
+	var promptErr *providertypes.PromptError
+	if !errors.As(err, &promptErr) {
+		t.Fatalf("expected PromptError, got %v", err)
+	}
+	if promptErr.Category != providertypes.ErrorRateLimit {
+		t.Fatalf("category = %q, want rate_limit", promptErr.Category)
+	}
+	if promptErr.Provider != providerAnthropic || promptErr.StatusCode != 429 || promptErr.RetryAfter != 7*time.Second {
+		t.Fatalf("got provider %q status %d retry %s", promptErr.Provider, promptErr.StatusCode, promptErr.RetryAfter)
+	}
+}
+
+func TestTagToolFailuresMarksRunErrors(t *testing.T) {
+	failing := core.NewAgentTool("explode", "always fails", func(context.Context, struct{}, core.ToolCall) (core.ToolResponse, error) {
+		return core.ToolResponse{}, errors.New("boom")
+	})
+	tagged := tagToolFailures([]core.AgentTool{failing})
+
+	_, err := tagged[0].Run(context.Background(), core.ToolCall{ID: "1", Name: "explode", Input: "{}"})
+	if got := providertypes.ErrorCategoryOf(err); got != providertypes.ErrorToolFailure {
+		t.Fatalf("category = %q, want tool_failure", got)
+	}
+}
+
+func TestFitRequestDropsOldestTurns(t *testing.T) {
+	system := core.Message{Role: core.MessageRoleSystem, Content: []core.MessagePart{core.TextPart{Text: "system"}}}
+	reply := func(text string) core.Message {
+		return core.Message{Role: core.MessageRoleAssistant, Content: []core.MessagePart{core.TextPart{Text: text}}}
+	}
+	history := []core.Message{
+		system,
+		core.NewUserMessage("first question"), reply("first answer"),
+		core.NewUserMessage("second question"), reply("second answer"),
+	}
+	want := []core.Message{system, history[3], history[4]}
+	limit := requestSize(want, "third")
+
+	strict := &Client{limits: providertypes.PayloadLimits{Provider: providerOpenAI, MaxRequestBytes: limit}}
+	if _, _, err := strict.fitRequest(history, "third"); providertypes.ErrorCategoryOf(err) != providertypes.ErrorRequestTooLarge {
+		t.Fatalf("strict error = %v, want request_too_large", err)
+	}
+
+	truncating := &Client{limits: providertypes.PayloadLimits{Provider: providerOpenAI, MaxRequestBytes: limit, Truncate: true}}
+	fitted, dropped, err := truncating.fitRequest(history, "third")
+	if err != nil {
+		t.Fatalf("fitRequest error: %v", err)
+	}
+	if dropped != 2 || len(fitted) != 3 || fitted[0].Role != core.MessageRoleSystem || messageText(fitted[1]) != "second question" {
+		t.Fatalf("fitted = %+v (dropped %d), want system prompt and last turn", fitted, dropped)
+	}
+	if len(history) != 5 || messageText(history[1]) != "first question" {
+		t.Fatal("expected stored history to stay unchanged")
+	}
+
+	truncating.limits.MaxRequestBytes = 1
+	if _, _, err := truncating.fitRequest(history, "third"); providertypes.ErrorCategoryOf(err) != providertypes.ErrorRequestTooLarge {
+		t.Fatalf("oversized prompt error = %v, want request_too_large", err)
+	}
+}
+
+func TestLatestSessionResumesAcrossClients(t *testing.T) {
+	dir := t.TempDir()
+	newClient := func() *Client {
+		sessions, err := store.NewJSONLStore(dir)
+		if err != nil {
+			t.Fatalf("NewJSONLStore error: %v", err)
+		}
+		t.Cleanup(func() { _ = sessions.Close() })
+		client := &Client{
+			provider: &fakeLanguageModelProvider{model: &fakeLanguageModel{}},
+			modelID:  "gpt-5.2",
+			sessions: sessions,
+		}
+		client.generate = func(context.Context, core.LanguageModel, core.AgentCall, []core.AgentOption) (*core.AgentResult, error) {
+			return &core.AgentResult{
+				Response:   core.Response{Content: core.ResponseContent{core.TextContent{Text: "noted"}}},
+				TotalUsage: core.Usage{InputTokens: 7, OutputTokens: 2, TotalTokens: 9},
+			}, nil
+		}
+		return client
+	}
+
+	first := newClient()
+	if _, err := first.CreateSession(context.Background(), "other"); err != nil {
+		t.Fatalf("CreateSession error: %v", err)
+	}
+	sessionID, err := first.CreateSession(context.Background(), "miniclaw")
+	if err != nil {
+		t.Fatalf("CreateSession error: %v", err)
+	}
+	if _, err := first.Prompt(context.Background(), sessionID, "remember 42", "openai/gpt-5.2", "", ""); err != nil {
+		t.Fatalf("Prompt error: %v", err)
+	}
+
+	restarted := newClient()
+	if _, found, err := restarted.LatestSession(context.Background(), "missing"); err != nil || found {
+		t.Fatalf("LatestSession(missing) = %v, %v; want not found", found, err)
+	}
+	resumed, found, err := restarted.LatestSession(context.Background(), "miniclaw")
+	if err != nil || !found {
+		t.Fatalf("LatestSession error = %v, found = %v", err, found)
+	}
+	if resumed != sessionID {
+		t.Fatalf("resumed session = %q, want %q", resumed, sessionID)
+	}
+	history, err := restarted.sessionHistory(context.Background(), resumed)
+	if err != nil {
+		t.Fatalf("sessionHistory error: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("history length = %d, want 2", len(history))
+	}
+
+	exported, err := restarted.ExportSession(context.Background(), resumed)
+	if err != nil {
+		t.Fatalf("ExportSession error: %v", err)
+	}
+	if len(exported.Messages) != 2 || exported.Usage.TotalTokens != 9 || exported.Messages[1].Usage == nil {
+		t.Fatalf("export = %d messages, usage %+v; want 2 messages with 9 tokens on the reply", len(exported.Messages), exported.Usage)
+	}
+	imported, err := restarted.ImportSession(context.Background(), exported, "miniclaw")
+	if err != nil {
+		t.Fatalf("ImportSession error: %v", err)
+	}
+	if latest, _, _ := restarted.LatestSession(context.Background(), "miniclaw"); latest != imported {
+		t.Fatalf("latest session = %q, want import %q", latest, imported)
+	}
+	if history, err := restarted.sessionHistory(context.Background(), imported); err != nil || len(history) != 2 {
+		t.Fatalf("imported history = %d messages (%v), want 2", len(history), err)
+	}
+}
+
+// summarizingLanguageModel answers direct Generate calls with a fixed summary.
+type summarizingLanguageModel struct {
+	fakeLanguageModel
+	calls      int
+	lastPrompt string
+}
+
+func (s *summarizingLanguageModel) Generate(_ context.Context, call core.Call) (*core.Response, error) {
+	s.calls++
+	s.lastPrompt = messageText(call.Prompt[len(call.Prompt)-1])
+	return &core.Response{
+		Content: core.ResponseContent{core.TextContent{Text: "user asked about 42"}},
+		Usage:   core.Usage{InputTokens: 100, OutputTokens: 10, TotalTokens: 110},
+	}, nil
+}
+
+func TestPromptCompactsLongHistory(t *testing.T) {
+	model := &summarizingLanguageModel{}
+	sessions := store.NewMemoryStore()
+	client := &Client{
+		provider:   &fakeLanguageModelProvider{model: model},
+		modelID:    "gpt-5.2",
+		sessions:   sessions,
+		compaction: newCompaction(config.CompactionConfig{Enabled: true, ThresholdBytes: 1500, KeepTurns: 1}),
+	}
+	var lastCall core.AgentCall
+	client.generate = func(_ context.Context, _ core.LanguageModel, call core.AgentCall, _ []core.AgentOption) (*core.AgentResult, error) {
+		lastCall = call
+		return &core.AgentResult{
+			Response:   core.Response{Content: core.ResponseContent{core.TextContent{Text: "reply " + call.Prompt}}},
+			TotalUsage: core.Usage{InputTokens: 5, OutputTokens: 1, TotalTokens: 6},
+		}, nil
+	}
+
+	sessionID, err := client.CreateSession(context.Background(), "long")
+	if err != nil {
+		t.Fatalf("CreateSession error: %v", err)
+	}
+	longPrompt := strings.Repeat("long question ", 100)
+	for _, prompt := range []string{"what is 42", "tell me more", longPrompt} {
+		if _, err := client.Prompt(context.Background(), sessionID, prompt, "openai/gpt-5.2", "", "be brief"); err != nil {
+			t.Fatalf("Prompt(%q) error: %v", prompt, err)
+		}
+	}
+	if model.calls != 0 {
+		t.Fatalf("summary calls = %d before the threshold, want 0", model.calls)
+	}
+
+	result, err := client.Prompt(context.Background(), sessionID, "and again", "openai/gpt-5.2", "", "be brief")
+	if err != nil {
+		t.Fatalf("Prompt error: %v", err)
+	}
+	if model.calls != 1 || !strings.Contains(model.lastPrompt, "user: what is 42") || strings.Contains(model.lastPrompt, "long question") {
+		t.Fatalf("summary calls = %d, prompt = %q", model.calls, model.lastPrompt)
+	}
+	if result.Metadata.Usage == nil || result.Metadata.Usage.TotalTokens != 116 {
+		t.Fatalf("usage = %+v, want prompt and summary tokens", result.Metadata.Usage)
+	}
+	// System prompt, summary, and the one kept user turn with its reply.
+	if len(lastCall.Messages) != 4 || !isCompactionSummary(lastCall.Messages[1]) {
+		t.Fatalf("compacted request messages = %d, want system, summary, and the last turn", len(lastCall.Messages))
+	}
+	if got := messageText(lastCall.Messages[2]); got != strings.TrimSpace(longPrompt) {
+		t.Fatalf("kept turn = %q, want the long prompt", got)
+	}
+
+	history, err := client.sessionHistory(context.Background(), sessionID)
+	if err != nil {
+		t.Fatalf("sessionHistory error: %v", err)
+	}
+	if len(history) != 6 || messageText(history[0]) != "be brief" || !isCompactionSummary(history[1]) {
+		t.Fatalf("stored history = %d messages, want the compacted history plus the new turn", len(history))
+	}
+}
+
+func TestPromptNamesSessionFromFirstPrompt(t *testing.T) {
+	titleCfg, err := newTitles(config.TitlesConfig{Enabled: true, Model: "openai/gpt-5-nano"}, "openai", "gpt-5.2")
+	if err != nil {
+		t.Fatalf("newTitles error: %v", err)
+	}
+	model := &summarizingLanguageModel{}
+	provider := &fakeLanguageModelProvider{model: model}
+	client := &Client{
+		provider: provider,
+		modelID:  "gpt-5.2",
+		sessions: store.NewMemoryStore(),
+		titles:   titleCfg,
+		generate: func(_ context.Context, _ core.LanguageModel, call core.AgentCall, _ []core.AgentOption) (*core.AgentResult, error) {
+			return &core.AgentResult{Response: core.Response{Content: core.ResponseContent{core.TextContent{Text: "reply"}}}}, nil
+		},
+	}
+
+	sessionID, err := client.CreateSession(context.Background(), "miniclaw")
+	if err != nil {
+		t.Fatalf("CreateSession error: %v", err)
+	}
+	for _, prompt := range []string{"what is 42", "tell me more"} {
+		if _, err := client.Prompt(context.Background(), sessionID, prompt, "openai/gpt-5.2", "", "be brief"); err != nil {
+			t.Fatalf("Prompt(%q) error: %v", prompt, err)
+		}
+	}
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	if model.calls != 1 || model.lastPrompt != "what is 42" {
+		t.Fatalf("title calls = %d with prompt %q, want one call for the first prompt", model.calls, model.lastPrompt)
+	}
+	name, err := client.SessionName(context.Background(), sessionID)
+	if err != nil || name != "user asked about 42" {
+		t.Fatalf("SessionName = %q, %v; want the generated title", name, err)
+	}
+	session, _ := client.sessions.Load(context.Background(), sessionID)
+	if session.Title != "miniclaw" {
+		t.Fatalf("session title = %q, want the resume key kept", session.Title)
+	}
+
+	for raw, want := range map[string]string{
+		"\"Planning the Launch.\"\nextra": "Planning the Launch",
+		"  \n**Budget review**":           "Budget review",
+		strings.Repeat("word ", 20):       strings.TrimSpace(strings.Repeat("word ", 12)) + "...",
+	} {
+		if got := cleanTitle(raw); got != want {
+			t.Fatalf("cleanTitle(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestSubagentUsageMergesIntoParentPrompt(t *testing.T) {
+	provider := &fakeLanguageModelProvider{model: &fakeLanguageModel{}}
+	subagentCfg, err := newSubagents(config.SubagentToolsConfig{Enabled: true, Model: "openai/gpt-5-nano"}, "openai", "gpt-5.2")
+	if err != nil {
+		t.Fatalf("newSubagents error: %v", err)
+	}
+	client := &Client{
+		provider:   provider,
+		providerID: "openai",
+		modelID:    "gpt-5.2",
+		sessions:   store.NewMemoryStore(),
+		subagents:  subagentCfg,
+	}
+	client.tools = fantasytools.BuildSubagentTools(client.runSubagent)
+
+	var childCall core.AgentCall
+	var childAnswer string
+	client.generate = func(ctx context.Context, _ core.LanguageModel, call core.AgentCall, _ []core.AgentOption) (*core.AgentResult, error) {
+		if call.Prompt == "count the files" {
+			childCall = call
+			return &core.AgentResult{
+				Response:   core.Response{Content: core.ResponseContent{core.TextContent{Text: "7 files"}}},
+				TotalUsage: core.Usage{InputTokens: 20, OutputTokens: 5, TotalTokens: 25},
+			}, nil
+		}
+
+		// Stand in for the model calling spawn_agent during the parent prompt.
+		answer, err := client.runSubagent(ctx, "count the files")
+		if err != nil {
+			return nil, err
+		}
+		childAnswer = answer
+		return &core.AgentResult{
+			Response:   core.Response{Content: core.ResponseContent{core.TextContent{Text: "there are " + answer}}},
+			TotalUsage: core.Usage{InputTokens: 10, OutputTokens: 2, TotalTokens: 12},
+		}, nil
+	}
+
+	sessionID, err := client.CreateSession(context.Background(), "parent")
+	if err != nil {
+		t.Fatalf("CreateSession error: %v", err)
+	}
+	result, err := client.Prompt(context.Background(), sessionID, "how many files?", "openai/gpt-5.2", "", "")
+	if err != nil {
+		t.Fatalf("Prompt error: %v", err)
+	}
+
+	if childAnswer != "7 files" || provider.lastID != "gpt-5-nano" {
+		t.Fatalf("child answer = %q on model %q, want 7 files on gpt-5-nano", childAnswer, provider.lastID)
+	}
+	if len(childCall.Messages) != 1 || messageText(childCall.Messages[0]) != subagentSystemPrompt {
+		t.Fatalf("child messages = %+v, want only the subagent system prompt", childCall.Messages)
+	}
+	if len(client.childTools()) != 0 {
+		t.Fatalf("child tools = %d, want spawn_agent excluded", len(client.childTools()))
+	}
+	if usage := result.Metadata.Usage; usage == nil || usage.TotalTokens != 37 || usage.InputTokens != 30 {
+		t.Fatalf("usage = %+v, want parent and child tokens", usage)
+	}
+}
+
+func TestPromptReportsContextUsage(t *testing.T) {
+	steps := []core.StepResult{}
+	client := &Client{
+		provider:   &fakeLanguageModelProvider{model: &fakeLanguageModel{}},
+		providerID: providerAnthropic,
+		modelID:    "claude-sonnet-4-5",
+		sessions:   store.NewMemoryStore(),
+		generate: func(context.Context, core.LanguageModel, core.AgentCall, []core.AgentOption) (*core.AgentResult, error) {
+			return &core.AgentResult{
+				Response: core.Response{Content: core.ResponseContent{core.TextContent{Text: "done"}}},
+				Steps:    steps,
+			}, nil
+		},
+	}
+	sessionID, err := client.CreateSession(context.Background(), "")
+	if err != nil {
+		t.Fatalf("CreateSession error: %v", err)
+	}
+
+	// Without step usage the window fill is estimated from the history size.
+	estimated, err := client.Prompt(context.Background(), sessionID, "hello", "anthropic/claude-sonnet-4-5", "", "")
+	if err != nil {
+		t.Fatalf("Prompt error: %v", err)
+	}
+	if context := estimated.Metadata.Context; context == nil || !context.Estimated || context.UsedTokens <= 0 || context.WindowTokens != 200_000 {
+		t.Fatalf("estimated context = %+v, want an estimate of a 200000 token window", context)
+	}
+
+	// Anthropic reports cached prompt tokens apart from InputTokens.
+	steps = []core.StepResult{
+		{Response: core.Response{Usage: core.Usage{InputTokens: 1_000, OutputTokens: 5_000}}},
+		{Response: core.Response{Usage: core.Usage{InputTokens: 2_000, OutputTokens: 1_000, CacheReadTokens: 150_000, CacheCreationTokens: 10_000}}},
+	}
+	reported, err := client.Prompt(context.Background(), sessionID, "again", "anthropic/claude-sonnet-4-5", "", "")
+	if err != nil {
+		t.Fatalf("Prompt error: %v", err)
+	}
+	if context := reported.Metadata.Context; context == nil || context.Estimated || context.UsedTokens != 163_000 || !context.NearlyFull {
+		t.Fatalf("reported context = %+v, want 163000 tokens from the last step, nearly full", context)
+	}
+}
+
+func TestPromptExpandsSessionVariables(t *testing.T) {
+	var calls []core.AgentCall
+	client := &Client{
+		provider:         &fakeLanguageModelProvider{model: &fakeLanguageModel{}},
+		providerID:       providerOpenAI,
+		modelID:          "gpt-5-nano",
+		sessions:         store.NewMemoryStore(),
+		variableDefaults: map[string]string{"client": "Acme"},
+		generate: func(_ context.Context, _ core.LanguageModel, call core.AgentCall, _ []core.AgentOption) (*core.AgentResult, error) {
+			calls = append(calls, call)
+			return &core.AgentResult{Response: core.Response{Content: core.ResponseContent{core.TextContent{Text: "done"}}}}, nil
+		},
+	}
+	sessionID, err := client.CreateSession(context.Background(), "")
+	if err != nil {
+		t.Fatalf("CreateSession error: %v", err)
+	}
+
+	if _, err := client.Prompt(context.Background(), sessionID, "Draft a note for {{ client }} about {{topic}}", "openai/gpt-5-nano", "", "You write for {{client}}."); err != nil {
+		t.Fatalf("Prompt error: %v", err)
+	}
+	if got := calls[0].Prompt; got != "Draft a note for Acme about {{topic}}" {
+		t.Fatalf("prompt = %q, want the set variable expanded and the unset one kept", got)
+	}
+	if got := messageText(calls[0].Messages[0]); got != "You write for Acme." {
+		t.Fatalf("system prompt = %q, want it expanded", got)
+	}
+
+	if _, err := client.Variables(sessionID).Set("topic", "invoices"); err != nil {
+		t.Fatalf("Set error: %v", err)
+	}
+	if _, err := client.Prompt(context.Background(), sessionID, "Again for {{topic}}", "openai/gpt-5-nano", "", ""); err != nil {
+		t.Fatalf("Prompt error: %v", err)
+	}
+	if got := calls[1].Prompt; got != "Again for invoices" {
+		t.Fatalf("prompt = %q, want the new variable expanded", got)
+	}
+
+	disabled := &Client{}
+	if disabled.Variables(sessionID) != nil {
+		t.Fatal("expected no variables while tools.variables is off")
+	}
+}
+
+func TestRollbackLastTurnDropsHistory(t *testing.T) {
+	var calls []core.AgentCall
+	sessions := store.NewMemoryStore()
+	client := &Client{
+		provider:   &fakeLanguageModelProvider{model: &fakeLanguageModel{}},
+		providerID: providerOpenAI,
+		modelID:    "gpt-5-nano",
+		sessions:   sessions,
+		generate: func(_ context.Context, _ core.LanguageModel, call core.AgentCall, _ []core.AgentOption) (*core.AgentResult, error) {
+			calls = append(calls, call)
+			return &core.AgentResult{Response: core.Response{Content: core.ResponseContent{core.TextContent{Text: "reply to " + call.Prompt}}}}, nil
+		},
+	}
+	sessionID, err := client.CreateSession(context.Background(), "")
+	if err != nil {
+		t.Fatalf("CreateSession error: %v", err)
+	}
+	if err := client.RollbackLastTurn(context.Background(), sessionID); !errors.Is(err, providertypes.ErrNoTurnToUndo) {
+		t.Fatalf("RollbackLastTurn on a new session = %v, want ErrNoTurnToUndo", err)
+	}
+
+	for _, prompt := range []string{"first", "bad"} {
+		if _, err := client.Prompt(context.Background(), sessionID, prompt, "openai/gpt-5-nano", "", ""); err != nil {
+			t.Fatalf("Prompt error: %v", err)
+		}
+	}
+	if err := client.RollbackLastTurn(context.Background(), sessionID); err != nil {
+		t.Fatalf("RollbackLastTurn error: %v", err)
+	}
+	if _, err := client.Prompt(context.Background(), sessionID, "better", "openai/gpt-5-nano", "", ""); err != nil {
+		t.Fatalf("Prompt error: %v", err)
+	}
+
+	var replayed []string
+	for _, message := range calls[2].Messages {
+		replayed = append(replayed, messageText(message))
+	}
+	if got := strings.Join(replayed, "|"); got != "first|reply to first" {
+		t.Fatalf("history after undo = %q, want only the first turn", got)
+	}
+
+	exported, err := client.ExportSession(context.Background(), sessionID)
+	if err != nil {
+		t.Fatalf("ExportSession error: %v", err)
+	}
+	rollbacks := 0
+	for _, message := range exported.Messages {
+		if message.Role == transcript.RollbackRole {
+			rollbacks++
+			if message.Text != "bad" || len(message.ToolEvents) != 0 {
+				t.Fatalf("rollback message = %+v, want the undone prompt", message)
+			}
+		}
+	}
+	if rollbacks != 1 {
+		t.Fatalf("exported %d rollback messages, want 1", rollbacks)
+	}
+}
@@ -0,0 +1,77 @@
+package fantasy
+
+import (
+	"encoding/json"
+
+	core "charm.land/fantasy"
+
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+// requestSize approximates the request payload as the encoded history plus the prompt.
+func requestSize(history []core.Message, prompt string) int {
+	encoded, err := json.Marshal(history)
+	if err != nil {
+		return len(prompt)
+	}
+
+	return len(encoded) + len(prompt)
+}
+
+// fitRequest applies the request limit to history and prompt.
+//
+// With truncation enabled the oldest turns are dropped, one user message at a
+// time, until the request fits; the leading system prompt is always kept.
+// The returned slice is a view for this request only and stored history is
+// never modified. Requests that still do not fit fail with a categorized error.
+func (c *Client) fitRequest(history []core.Message, prompt string) ([]core.Message, int, error) {
+	size := requestSize(history, prompt)
+	if c.limits.RequestFits(size) || !c.limits.Truncate {
+		return history, 0, c.limits.CheckRequest(size)
+	}
+
+	start := 0
+	if len(history) > 0 && history[0].Role == core.MessageRoleSystem {
+		start = 1
+	}
+
+	turns := history[start:]
+	dropped := 0
+	for len(turns) > 0 {
+		next := 1
+		for next < len(turns) && turns[next].Role != core.MessageRoleUser {
+			next++
+		}
+		turns = turns[next:]
+		dropped += next
+
+		fitted := append(append([]core.Message{}, history[:start]...), turns...)
+		size = requestSize(fitted, prompt)
+		if c.limits.RequestFits(size) {
+			return fitted, dropped, nil
+		}
+	}
+
+	return nil, 0, c.limits.CheckRequest(size)
+}
+
+// contextUsage reports how full modelID's context window is after a turn that
+// sent history and stored appended. The last step's token counts are used
+// when the provider reports them; otherwise the size of the history is
+// converted to an estimate.
+func (c *Client) contextUsage(modelID string, steps []core.StepResult, history []core.Message, appended []core.Message) *providertypes.ContextUsage {
+	if len(steps) > 0 {
+		usage := steps[len(steps)-1].Usage
+		used := usage.InputTokens + usage.OutputTokens
+		if c.providerName() == providerAnthropic {
+			// Anthropic counts cached prompt tokens separately from InputTokens.
+			used += usage.CacheReadTokens + usage.CacheCreationTokens
+		}
+		if used > 0 {
+			return c.contextBudget.Usage(modelID, used, false)
+		}
+	}
+
+	size := requestSize(history, "") + requestSize(appended, "")
+	return c.contextBudget.Usage(modelID, providertypes.EstimateTokens(size), true)
+}
@@ -0,0 +1,147 @@
+package fantasy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	core "charm.land/fantasy"
+
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+	fantasytools "miniclaw/pkg/tools/fantasy"
+)
+
+const (
+	defaultSubagentToolSteps = 10
+	subagentSystemPrompt     = "You are a subagent working on one delegated task. Use the available tools as needed, then reply with a concise, complete answer to the task; it is passed back to the agent that delegated it."
+)
+
+// subagents holds the resolved tools.subagents settings.
+type subagents struct {
+	modelID  string
+	maxSteps int
+}
+
+// newSubagents returns nil when spawn_agent is disabled.
+func newSubagents(cfg config.SubagentToolsConfig, providerID string, defaultModelID string) (*subagents, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	resolved := &subagents{modelID: defaultModelID, maxSteps: cfg.MaxToolIterations}
+	if cfg.Model != "" {
+		modelID, err := normalizeModel(providerID, cfg.Model)
+		if err != nil {
+			return nil, fmt.Errorf("tools.subagents.model: %w", err)
+		}
+		resolved.modelID = modelID
+	}
+	if resolved.maxSteps <= 0 {
+		resolved.maxSteps = defaultSubagentToolSteps
+	}
+
+	return resolved, nil
+}
+
+// childUsage accumulates the token usage of the subagents one prompt spawns.
+type childUsage struct {
+	mu    sync.Mutex
+	total core.Usage
+}
+
+type childUsageKey struct{}
+
+func withChildUsage(ctx context.Context, usage *childUsage) context.Context {
+	return context.WithValue(ctx, childUsageKey{}, usage)
+}
+
+func (u *childUsage) add(usage core.Usage) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.total = addUsage(u.total, usage)
+}
+
+func (u *childUsage) usage() core.Usage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	return u.total
+}
+
+// runSubagent backs spawn_agent: it runs task in a child agent with a fresh,
+// unstored history and every tool except spawn_agent, and adds the child's
+// token usage to the spawning prompt's usage. Tool calls the child makes are
+// metered against the parent session through the shared context.
+func (c *Client) runSubagent(ctx context.Context, task string) (string, error) {
+	if c.subagents == nil {
+		return "", errors.New("subagents are not enabled")
+	}
+	model, err := c.provider.LanguageModel(ctx, c.subagents.modelID)
+	if err != nil {
+		return "", c.classifyError(fmt.Errorf("resolve subagent model: %w", err))
+	}
+
+	var options []core.AgentOption
+	if tools := c.childTools(); len(tools) > 0 {
+		options = []core.AgentOption{
+			core.WithTools(tools...),
+			core.WithStopConditions(core.StepCountIs(c.subagents.maxSteps)),
+		}
+	}
+	call := core.AgentCall{
+		Prompt:   task,
+		Messages: []core.Message{core.NewSystemMessage(subagentSystemPrompt)},
+	}
+	if c.maxOutputTokens != nil {
+		call.MaxOutputTokens = c.maxOutputTokens
+	}
+	if c.temperature != nil {
+		call.Temperature = c.temperature
+	}
+
+	generate := c.generate
+	if generate == nil {
+		generate = generateWithFantasyAgent
+	}
+
+	start := time.Now()
+	// Only the child's final answer reaches the parent, never its deltas.
+	result, err := generate(providertypes.WithoutTextDeltas(ctx), model, call, options)
+	if err != nil {
+		return "", c.classifyError(fmt.Errorf("subagent failed: %w", err))
+	}
+	if usage, ok := ctx.Value(childUsageKey{}).(*childUsage); ok {
+		usage.add(result.TotalUsage)
+	}
+
+	answer := extractText(result.Response.Content)
+	slog.Default().With("component", "provider.fantasy").Debug("Subagent finished",
+		"model", c.subagents.modelID,
+		"steps", len(result.Steps),
+		"total_tokens", result.TotalUsage.TotalTokens,
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+	if answer == "" {
+		return "", errors.New("subagent returned no answer")
+	}
+
+	return answer, nil
+}
+
+// childTools returns the client tools a subagent may use: all but spawn_agent,
+// so delegation is one level deep.
+func (c *Client) childTools() []core.AgentTool {
+	tools := make([]core.AgentTool, 0, len(c.tools))
+	for _, tool := range c.tools {
+		if tool.Info().Name != fantasytools.ToolSpawnAgent {
+			tools = append(tools, tool)
+		}
+	}
+
+	return tools
+}
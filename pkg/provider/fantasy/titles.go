@@ -0,0 +1,125 @@
+package fantasy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	core "charm.land/fantasy"
+
+	"miniclaw/pkg/config"
+)
+
+const (
+	titleSystemPrompt = "Write a short title, at most six words, for a conversation that starts with the user message below. Reply with the title only: no quotes, no trailing period."
+	titleTimeout      = 30 * time.Second
+	maxTitleRunes     = 60
+)
+
+// titles holds the resolved agents.defaults.titles settings and tracks the
+// naming calls still running.
+type titles struct {
+	modelID string
+	pending sync.WaitGroup
+}
+
+// newTitles returns nil when session naming is disabled.
+func newTitles(cfg config.TitlesConfig, providerID string, defaultModelID string) (*titles, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	resolved := &titles{modelID: defaultModelID}
+	if cfg.Model != "" {
+		modelID, err := normalizeModel(providerID, cfg.Model)
+		if err != nil {
+			return nil, fmt.Errorf("agents.defaults.titles.model: %w", err)
+		}
+		resolved.modelID = modelID
+	}
+
+	return resolved, nil
+}
+
+// nameSession generates a name for sessionID from its first prompt in the
+// background, so the reply is not held up, and stores it with the session.
+// Close waits for naming calls still running. Failures are only logged.
+func (c *Client) nameSession(ctx context.Context, sessionID string, prompt string) {
+	if c.titles == nil {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	c.titles.pending.Go(func() {
+		ctx, cancel := context.WithTimeout(ctx, titleTimeout)
+		defer cancel()
+
+		log := slog.Default().With("component", "provider.fantasy")
+		name, err := c.generateTitle(ctx, prompt)
+		if err != nil {
+			log.Warn("Session naming failed", "session_id", sessionID, "error", err)
+			return
+		}
+		if name == "" {
+			return
+		}
+		if err := c.sessions.Rename(ctx, sessionID, name); err != nil {
+			log.Warn("Session naming failed", "session_id", sessionID, "error", err)
+		}
+	})
+}
+
+// SessionName returns the stored name of sessionID, which is empty until
+// one has been generated.
+func (c *Client) SessionName(ctx context.Context, sessionID string) (string, error) {
+	session, err := c.sessions.Load(ctx, sessionID)
+	if err != nil {
+		return "", err
+	}
+
+	return session.Name, nil
+}
+
+// generateTitle asks the titles model for a name for a conversation that
+// starts with prompt.
+func (c *Client) generateTitle(ctx context.Context, prompt string) (string, error) {
+	languageModel, err := c.provider.LanguageModel(ctx, c.titles.modelID)
+	if err != nil {
+		return "", fmt.Errorf("resolve language model: %w", err)
+	}
+
+	maxTokens := int64(256)
+	response, err := languageModel.Generate(ctx, core.Call{
+		Prompt: core.Prompt{
+			core.NewSystemMessage(titleSystemPrompt),
+			core.NewUserMessage(clipText(prompt)),
+		},
+		MaxOutputTokens: &maxTokens,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return cleanTitle(extractText(response.Content)), nil
+}
+
+// cleanTitle keeps the first line of a generated title without quotes,
+// markup, or a trailing period, cut to maxTitleRunes.
+func cleanTitle(text string) string {
+	for line := range strings.Lines(text) {
+		line = strings.Trim(strings.TrimSpace(line), "\"'`*#")
+		line = strings.TrimSuffix(strings.TrimSpace(line), ".")
+		if line == "" {
+			continue
+		}
+		if runes := []rune(line); len(runes) > maxTitleRunes {
+			line = strings.TrimSpace(string(runes[:maxTitleRunes])) + "..."
+		}
+		return line
+	}
+
+	return ""
+}
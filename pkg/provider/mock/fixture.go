@@ -0,0 +1,150 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+// FixtureVersion is the fixture file format written by Recorder.
+const FixtureVersion = 1
+
+// Fixture is a recorded provider conversation the mock client replays turn by turn.
+type Fixture struct {
+	Version    int       `json:"version"`
+	CapturedAt time.Time `json:"captured_at"`
+	// Provider and Model describe the live backend the fixture was captured from.
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+	Turns    []Turn `json:"turns"`
+}
+
+// Turn is one prompt and the provider's reply to it.
+type Turn struct {
+	Prompt     string      `json:"prompt"`
+	Text       string      `json:"text,omitempty"`
+	ToolEvents []ToolEvent `json:"tool_events,omitempty"`
+	Usage      *Usage      `json:"usage,omitempty"`
+	// Error records a failed prompt; replay returns it as a categorized PromptError.
+	Error *TurnError `json:"error,omitempty"`
+}
+
+// ToolEvent is the fixture form of providertypes.ToolEvent.
+type ToolEvent struct {
+	Kind       string `json:"kind"`
+	Tool       string `json:"tool"`
+	Payload    string `json:"payload,omitempty"`
+	Failed     bool   `json:"failed,omitempty"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
+}
+
+// Usage is the fixture form of providertypes.TokenUsage.
+type Usage struct {
+	InputTokens         int64 `json:"input_tokens,omitempty"`
+	OutputTokens        int64 `json:"output_tokens,omitempty"`
+	TotalTokens         int64 `json:"total_tokens,omitempty"`
+	ReasoningTokens     int64 `json:"reasoning_tokens,omitempty"`
+	CacheCreationTokens int64 `json:"cache_creation_tokens,omitempty"`
+	CacheReadTokens     int64 `json:"cache_read_tokens,omitempty"`
+}
+
+// TurnError is a recorded prompt failure.
+type TurnError struct {
+	Category providertypes.ErrorCategory `json:"category"`
+	Message  string                      `json:"message"`
+}
+
+// LoadFixture reads a fixture file.
+func LoadFixture(path string) (Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Fixture{}, fmt.Errorf("read fixture: %w", err)
+	}
+
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return Fixture{}, fmt.Errorf("parse fixture %s: %w", path, err)
+	}
+	if fixture.Version != FixtureVersion {
+		return Fixture{}, fmt.Errorf("fixture %s has version %d, want %d", path, fixture.Version, FixtureVersion)
+	}
+
+	return fixture, nil
+}
+
+// SaveFixture writes fixture as indented JSON, creating parent directories.
+func SaveFixture(path string, fixture Fixture) error {
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode fixture: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("create fixture directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write fixture: %w", err)
+	}
+
+	return nil
+}
+
+// newTurn converts one live prompt outcome into its fixture form.
+func newTurn(prompt string, result providertypes.PromptResult, err error) Turn {
+	turn := Turn{Prompt: prompt, Text: result.Text}
+	for _, event := range result.Metadata.ToolEvents {
+		turn.ToolEvents = append(turn.ToolEvents, ToolEvent(event))
+	}
+	if usage := result.Metadata.Usage; usage != nil && !usage.IsZero() {
+		turn.Usage = &Usage{
+			InputTokens:         usage.InputTokens,
+			OutputTokens:        usage.OutputTokens,
+			TotalTokens:         usage.TotalTokens,
+			ReasoningTokens:     usage.ReasoningTokens,
+			CacheCreationTokens: usage.CacheCreationTokens,
+			CacheReadTokens:     usage.CacheReadTokens,
+		}
+	}
+	if err != nil {
+		turn.Error = &TurnError{Category: providertypes.ErrorCategoryOf(err), Message: err.Error()}
+	}
+
+	return turn
+}
+
+// result converts a recorded turn back into the provider response it captured.
+func (t Turn) result(model string) (providertypes.PromptResult, error) {
+	if t.Error != nil {
+		category := t.Error.Category
+		if category == "" {
+			category = providertypes.ErrorUnknown
+		}
+		return providertypes.PromptResult{}, &providertypes.PromptError{
+			Category: category,
+			Provider: providerName,
+			Err:      fmt.Errorf("%s", t.Error.Message),
+		}
+	}
+
+	metadata := providertypes.PromptMetadata{Provider: providerName, Model: model}
+	for _, event := range t.ToolEvents {
+		metadata.ToolEvents = append(metadata.ToolEvents, providertypes.ToolEvent(event))
+	}
+	if t.Usage != nil {
+		metadata.Usage = &providertypes.TokenUsage{
+			InputTokens:         t.Usage.InputTokens,
+			OutputTokens:        t.Usage.OutputTokens,
+			TotalTokens:         t.Usage.TotalTokens,
+			ReasoningTokens:     t.Usage.ReasoningTokens,
+			CacheCreationTokens: t.Usage.CacheCreationTokens,
+			CacheReadTokens:     t.Usage.CacheReadTokens,
+		}
+	}
+
+	return providertypes.PromptResult{Text: t.Text, Metadata: metadata}, nil
+}
@@ -0,0 +1,99 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+const providerName = "mock"
+
+// Client replays a Fixture instead of calling a live provider.
+//
+// Prompts consume turns in order across all sessions. A prompt that differs
+// from the recorded one is logged and answered with the recorded reply, so
+// fixtures keep working when the prompt was sanitized.
+type Client struct {
+	fixture Fixture
+
+	mu       sync.Mutex
+	next     int
+	sessions int
+}
+
+// New loads the fixture named by providers.mock.fixture.
+func New(cfg *config.Config) (*Client, error) {
+	path := strings.TrimSpace(cfg.Providers.Mock.Fixture)
+	if path == "" {
+		return nil, errors.New("providers.mock.fixture must be set")
+	}
+
+	fixture, err := LoadFixture(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFromFixture(fixture), nil
+}
+
+// NewFromFixture builds a replay client for an in-memory fixture.
+func NewFromFixture(fixture Fixture) *Client {
+	return &Client{fixture: fixture}
+}
+
+// Health always succeeds.
+func (c *Client) Health(context.Context) error {
+	return nil
+}
+
+// CreateSession returns a fresh synthetic session ID.
+func (c *Client) CreateSession(context.Context, string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sessions++
+
+	return fmt.Sprintf("mock-session-%d", c.sessions), nil
+}
+
+// Prompt returns the next recorded turn.
+func (c *Client) Prompt(ctx context.Context, sessionID string, prompt string, model string, agent string, systemPrompt string) (providertypes.PromptResult, error) {
+	_ = agent
+	_ = systemPrompt
+	if err := ctx.Err(); err != nil {
+		return providertypes.PromptResult{}, err
+	}
+
+	c.mu.Lock()
+	index := c.next
+	if index >= len(c.fixture.Turns) {
+		c.mu.Unlock()
+		return providertypes.PromptResult{}, fmt.Errorf("mock fixture exhausted: no turn %d for prompt %q", index+1, prompt)
+	}
+	c.next++
+	turn := c.fixture.Turns[index]
+	c.mu.Unlock()
+
+	if strings.TrimSpace(turn.Prompt) != strings.TrimSpace(prompt) {
+		providerLogger().Warn("Prompt differs from fixture", "session_id", sessionID, "turn", index+1, "want", turn.Prompt, "got", prompt)
+	}
+
+	return turn.result(model)
+}
+
+// Remaining reports how many recorded turns have not been replayed yet.
+func (c *Client) Remaining() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.fixture.Turns) - c.next
+}
+
+func providerLogger() *slog.Logger {
+	return slog.Default().With("component", "provider.mock")
+}
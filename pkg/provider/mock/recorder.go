@@ -0,0 +1,207 @@
+package mock
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+// Backend is the provider contract a Recorder wraps (provider.Client).
+type Backend interface {
+	Health(ctx context.Context) error
+	CreateSession(ctx context.Context, title string) (string, error)
+	Prompt(ctx context.Context, sessionID string, prompt string, model string, agent string, systemPrompt string) (providertypes.PromptResult, error)
+}
+
+// Recorder passes calls through to a live backend and records every prompt as a fixture turn.
+type Recorder struct {
+	inner Backend
+
+	mu      sync.Mutex
+	fixture Fixture
+}
+
+// NewRecorder wraps inner; provider and model are stored in the fixture header.
+func NewRecorder(inner Backend, provider string, model string) *Recorder {
+	return &Recorder{
+		inner: inner,
+		fixture: Fixture{
+			Version:  FixtureVersion,
+			Provider: strings.TrimSpace(provider),
+			Model:    strings.TrimSpace(model),
+		},
+	}
+}
+
+func (r *Recorder) Health(ctx context.Context) error {
+	return r.inner.Health(ctx)
+}
+
+func (r *Recorder) CreateSession(ctx context.Context, title string) (string, error) {
+	return r.inner.CreateSession(ctx, title)
+}
+
+func (r *Recorder) Prompt(ctx context.Context, sessionID string, prompt string, model string, agent string, systemPrompt string) (providertypes.PromptResult, error) {
+	result, err := r.inner.Prompt(ctx, sessionID, prompt, model, agent, systemPrompt)
+
+	r.mu.Lock()
+	r.fixture.Turns = append(r.fixture.Turns, newTurn(prompt, result, err))
+	r.mu.Unlock()
+
+	return result, err
+}
+
+// Fixture returns the turns recorded so far, sanitized with sanitizer when it is non-nil.
+func (r *Recorder) Fixture(sanitizer *Sanitizer) Fixture {
+	r.mu.Lock()
+	fixture := r.fixture
+	fixture.Turns = slices.Clone(r.fixture.Turns)
+	r.mu.Unlock()
+
+	fixture.CapturedAt = time.Now().UTC().Truncate(time.Second)
+	if sanitizer != nil {
+		fixture = sanitizer.Fixture(fixture)
+	}
+
+	return fixture
+}
+
+// Save writes the sanitized recording to path.
+func (r *Recorder) Save(path string, sanitizer *Sanitizer) error {
+	return SaveFixture(path, r.Fixture(sanitizer))
+}
+
+const redacted = "[REDACTED]"
+
+// secretPatterns match credential shapes that may appear in prompts, replies, or tool output.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`sk-[A-Za-z0-9_-]{16,}`),
+	regexp.MustCompile(`\b\d{8,10}:[A-Za-z0-9_-]{30,}`),
+	regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/=-]{16,}`),
+	regexp.MustCompile(`(?i)\b(api[_-]?key|token|password|secret)(["']?\s*[:=]\s*["']?)[^\s"',]{8,}`),
+}
+
+// Sanitizer scrubs credentials and machine-specific paths from captured fixtures.
+type Sanitizer struct {
+	secrets []string
+	// paths maps absolute local paths to stable placeholders, longest first.
+	paths [][2]string
+}
+
+// NewSanitizer collects the secrets and paths cfg and the environment expose.
+func NewSanitizer(cfg *config.Config) *Sanitizer {
+	s := &Sanitizer{}
+
+	envNames := []string{"OPENAI_API_KEY", "ANTHROPIC_API_KEY", "TELEGRAM_BOT_TOKEN"}
+	if cfg != nil {
+		envNames = append(envNames, strings.TrimSpace(cfg.Providers.OpenCode.PasswordEnv))
+		s.addSecret(cfg.Channels.Telegram.Token)
+		s.addSecret(cfg.Tools.Web.Brave.APIKey)
+		s.addSecret(cfg.Tools.Web.DuckDuckGo.APIKey)
+		s.addSecret(cfg.Tools.Web.Perplexity.APIKey)
+		for _, webhook := range cfg.Tools.Webhooks {
+			for _, value := range webhook.Headers {
+				s.addSecret(value)
+			}
+		}
+	}
+	for _, name := range envNames {
+		if name != "" {
+			s.addSecret(os.Getenv(name))
+		}
+	}
+
+	home, _ := os.UserHomeDir()
+	if cfg != nil {
+		if workspace := absPath(cfg.Agents.Defaults.Workspace, home); workspace != "" {
+			s.paths = append(s.paths, [2]string{workspace, "<workspace>"})
+		}
+	}
+	if home != "" && home != "/" {
+		s.paths = append(s.paths, [2]string{home, "~"})
+	}
+	slices.SortFunc(s.paths, func(a, b [2]string) int {
+		return len(b[0]) - len(a[0])
+	})
+
+	return s
+}
+
+// addSecret registers a literal secret; short values are skipped to avoid mangling ordinary text.
+func (s *Sanitizer) addSecret(value string) {
+	value = strings.TrimSpace(value)
+	if len(value) >= 8 {
+		s.secrets = append(s.secrets, value)
+	}
+}
+
+// Text redacts secrets and replaces local paths in one string.
+func (s *Sanitizer) Text(text string) string {
+	for _, secret := range s.secrets {
+		text = strings.ReplaceAll(text, secret, redacted)
+	}
+	for _, pattern := range secretPatterns {
+		text = pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if sub := pattern.FindStringSubmatch(match); len(sub) == 3 {
+				return sub[1] + sub[2] + redacted
+			}
+			return redacted
+		})
+	}
+	for _, path := range s.paths {
+		text = strings.ReplaceAll(text, path[0], path[1])
+	}
+
+	return text
+}
+
+// Fixture returns a copy of fixture with every prompt, reply, tool payload, and error sanitized.
+func (s *Sanitizer) Fixture(fixture Fixture) Fixture {
+	turns := make([]Turn, 0, len(fixture.Turns))
+	for _, turn := range fixture.Turns {
+		turn.Prompt = s.Text(turn.Prompt)
+		turn.Text = s.Text(turn.Text)
+
+		events := make([]ToolEvent, 0, len(turn.ToolEvents))
+		for _, event := range turn.ToolEvents {
+			event.Payload = s.Text(event.Payload)
+			events = append(events, event)
+		}
+		if len(events) > 0 {
+			turn.ToolEvents = events
+		}
+		if turn.Error != nil {
+			turn.Error = &TurnError{Category: turn.Error.Category, Message: s.Text(turn.Error.Message)}
+		}
+
+		turns = append(turns, turn)
+	}
+	fixture.Turns = turns
+
+	return fixture
+}
+
+// absPath expands a leading ~ and makes path absolute without touching the filesystem.
+func absPath(path string, home string) string {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return ""
+	}
+	if home != "" && (path == "~" || strings.HasPrefix(path, "~/")) {
+		path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Clean(abs)
+}
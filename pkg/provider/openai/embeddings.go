@@ -0,0 +1,80 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"miniclaw/pkg/config"
+
+	osdk "github.com/openai/openai-go/v3"
+)
+
+// DefaultEmbeddingModel is used when agents.defaults.retrieval.embedding_model is unset.
+const DefaultEmbeddingModel = "text-embedding-3-small"
+
+// Embedder turns text into vectors with the OpenAI embeddings endpoint.
+type Embedder struct {
+	client osdk.Client
+	model  string
+}
+
+// NewEmbedder builds an embedder from providers.openai and OPENAI_API_KEY.
+// An empty model uses DefaultEmbeddingModel.
+func NewEmbedder(providerCfg config.OpenAIProviderConfig, model string) (*Embedder, error) {
+	apiKey := resolveAPIKey()
+	if apiKey == "" {
+		return nil, errors.New("OPENAI_API_KEY must be set for embeddings")
+	}
+
+	if strings.TrimSpace(model) == "" {
+		model = DefaultEmbeddingModel
+	}
+	model, err := normalizeModel(model)
+	if err != nil {
+		return nil, fmt.Errorf("embedding model: %w", err)
+	}
+
+	return &Embedder{client: osdk.NewClient(requestOptions(providerCfg, apiKey)...), model: model}, nil
+}
+
+// Model returns the embedding model ID.
+func (e *Embedder) Model() string {
+	return e.model
+}
+
+// Embed returns one vector per text, in order.
+func (e *Embedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	log := providerLogger().With("operation", "embed")
+	startedAt := time.Now()
+	response, err := e.client.Embeddings.New(ctx, osdk.EmbeddingNewParams{
+		Model: osdk.EmbeddingModel(e.model),
+		Input: osdk.EmbeddingNewParamsInputUnion{OfArrayOfStrings: texts},
+	})
+	if err != nil {
+		log.Debug("Provider request failed", "duration_ms", time.Since(startedAt).Milliseconds(), "error", err)
+		return nil, classifyError(fmt.Errorf("create embeddings: %w", err))
+	}
+	log.Debug("Provider request completed", "duration_ms", time.Since(startedAt).Milliseconds(), "inputs", len(texts), "total_tokens", response.Usage.TotalTokens)
+
+	vectors := make([][]float64, len(texts))
+	for _, item := range response.Data {
+		if item.Index < 0 || int(item.Index) >= len(texts) {
+			return nil, fmt.Errorf("create embeddings: unexpected index %d", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	for i, vector := range vectors {
+		if len(vector) == 0 {
+			return nil, fmt.Errorf("create embeddings: no vector for input %d", i)
+		}
+	}
+
+	return vectors, nil
+}
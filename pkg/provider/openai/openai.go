@@ -21,6 +21,8 @@ import (
 type Client struct {
 	client         osdk.Client
 	requestTimeout time.Duration
+	limits         providertypes.PayloadLimits
+	contextBudget  providertypes.ContextBudget
 }
 
 // New constructs an OpenAI provider client from config/env.
@@ -31,6 +33,25 @@ func New(cfg *config.Config) (*Client, error) {
 		return nil, errors.New("OPENAI_API_KEY must be set")
 	}
 
+	limits, err := providertypes.NewPayloadLimits("openai", providerCfg.Limits)
+	if err != nil {
+		return nil, err
+	}
+	contextBudget, err := providertypes.NewContextBudget(cfg.Agents.Defaults.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		client:         osdk.NewClient(requestOptions(providerCfg, apiKey)...),
+		requestTimeout: time.Duration(providerCfg.RequestTimeoutSeconds) * time.Second,
+		limits:         limits,
+		contextBudget:  contextBudget,
+	}, nil
+}
+
+// requestOptions applies providers.openai connection settings to SDK requests.
+func requestOptions(providerCfg config.OpenAIProviderConfig, apiKey string) []option.RequestOption {
 	opts := []option.RequestOption{option.WithAPIKey(apiKey)}
 	if baseURL := strings.TrimSpace(providerCfg.BaseURL); baseURL != "" {
 		opts = append(opts, option.WithBaseURL(baseURL))
@@ -41,16 +62,11 @@ func New(cfg *config.Config) (*Client, error) {
 	if project := strings.TrimSpace(providerCfg.Project); project != "" {
 		opts = append(opts, option.WithProject(project))
 	}
-
-	requestTimeout := time.Duration(providerCfg.RequestTimeoutSeconds) * time.Second
-	if requestTimeout > 0 {
+	if requestTimeout := time.Duration(providerCfg.RequestTimeoutSeconds) * time.Second; requestTimeout > 0 {
 		opts = append(opts, option.WithRequestTimeout(requestTimeout))
 	}
 
-	return &Client{
-		client:         osdk.NewClient(opts...),
-		requestTimeout: requestTimeout,
-	}, nil
+	return opts
 }
 
 // Health performs a lightweight provider connectivity check.
@@ -63,7 +79,7 @@ func (c *Client) Health(ctx context.Context) error {
 
 	if _, err := c.client.Models.List(ctx); err != nil {
 		log.Debug("Provider request failed", "duration_ms", time.Since(startedAt).Milliseconds(), "error", err)
-		return fmt.Errorf("health check failed: %w", err)
+		return classifyError(fmt.Errorf("health check failed: %w", err))
 	}
 	log.Debug("Provider request completed", "duration_ms", time.Since(startedAt).Milliseconds())
 
@@ -81,7 +97,7 @@ func (c *Client) CreateSession(ctx context.Context, title string) (string, error
 	conversation, err := c.client.Conversations.New(ctx, conversations.ConversationNewParams{})
 	if err != nil {
 		log.Debug("Provider request failed", "duration_ms", time.Since(startedAt).Milliseconds(), "error", err)
-		return "", fmt.Errorf("create session failed: %w", err)
+		return "", classifyError(fmt.Errorf("create session failed: %w", err))
 	}
 	if conversation == nil || strings.TrimSpace(conversation.ID) == "" {
 		log.Debug("Provider request failed", "duration_ms", time.Since(startedAt).Milliseconds(), "error", "empty conversation id")
@@ -96,7 +112,7 @@ func (c *Client) CreateSession(ctx context.Context, title string) (string, error
 func (c *Client) Prompt(ctx context.Context, sessionID string, prompt string, model string, agent string, systemPrompt string) (providertypes.PromptResult, error) {
 	ctx, cancel := c.withTimeout(ctx)
 	defer cancel()
-	log := providerLogger().With("operation", "prompt")
+	log := providertypes.TraceLogger(ctx, providerLogger().With("operation", "prompt"))
 	startedAt := time.Now()
 
 	sessionID = strings.TrimSpace(sessionID)
@@ -120,6 +136,12 @@ func (c *Client) Prompt(ctx context.Context, sessionID string, prompt string, mo
 		"prompt_length", len(prompt),
 	)
 
+	// History lives server-side, so only the new input counts toward the limit and it cannot be trimmed.
+	if err := c.limits.CheckRequest(len(prompt) + len(strings.TrimSpace(systemPrompt))); err != nil {
+		log.Debug("Provider request failed", "duration_ms", time.Since(startedAt).Milliseconds(), "error", err)
+		return providertypes.PromptResult{}, err
+	}
+
 	params := responses.ResponseNewParams{
 		Model: normalizedModel,
 		Input: responses.ResponseNewParamsInputUnion{OfString: osdk.String(prompt)},
@@ -131,10 +153,15 @@ func (c *Client) Prompt(ctx context.Context, sessionID string, prompt string, mo
 		params.Instructions = osdk.String(strings.TrimSpace(systemPrompt))
 	}
 
-	response, err := c.client.Responses.New(ctx, params)
+	var opts []option.RequestOption
+	if traceID := providertypes.TraceIDFromContext(ctx); traceID != "" {
+		// Lets OpenAI support find the request by the gateway's trace ID.
+		opts = append(opts, option.WithHeader("X-Client-Request-Id", traceID))
+	}
+	response, err := c.client.Responses.New(ctx, params, opts...)
 	if err != nil {
 		log.Debug("Provider request failed", "duration_ms", time.Since(startedAt).Milliseconds(), "error", err)
-		return providertypes.PromptResult{}, fmt.Errorf("prompt failed: %w", err)
+		return providertypes.PromptResult{}, classifyError(fmt.Errorf("prompt failed: %w", err))
 	}
 
 	text := strings.TrimSpace(response.OutputText())
@@ -143,6 +170,10 @@ func (c *Client) Prompt(ctx context.Context, sessionID string, prompt string, mo
 		return providertypes.PromptResult{}, errors.New("prompt succeeded but returned no text")
 	}
 	log.Debug("Provider request completed", "duration_ms", time.Since(startedAt).Milliseconds(), "response_length", len(text))
+	text, err = c.limits.LimitResponse(text)
+	if err != nil {
+		return providertypes.PromptResult{}, err
+	}
 
 	usage := providertypes.TokenUsage{
 		InputTokens:     response.Usage.InputTokens,
@@ -159,6 +190,9 @@ func (c *Client) Prompt(ctx context.Context, sessionID string, prompt string, mo
 			Model:    normalizedModel,
 			Agent:    strings.TrimSpace(agent),
 			Usage:    &usage,
+			// The conversation lives server-side, so this request's input plus
+			// its reply is the whole history.
+			Context: c.contextBudget.Usage(normalizedModel, usage.InputTokens+usage.OutputTokens, false),
 		},
 	}, nil
 }
@@ -176,6 +210,22 @@ func (c *Client) withTimeout(ctx context.Context) (context.Context, context.Canc
 	return context.WithTimeout(ctx, c.requestTimeout)
 }
 
+// classifyError attaches a providertypes error category using the SDK status code.
+func classifyError(err error) error {
+	var apiErr *osdk.Error
+	if !errors.As(err, &apiErr) {
+		return providertypes.ClassifyError("openai", 0, err)
+	}
+
+	classified := providertypes.ClassifyError("openai", apiErr.StatusCode, err)
+	var promptErr *providertypes.PromptError
+	if errors.As(classified, &promptErr) && apiErr.Response != nil {
+		promptErr.RetryAfter = providertypes.ParseRetryAfter(apiErr.Response.Header.Get("Retry-After"), time.Now())
+	}
+
+	return classified
+}
+
 // resolveAPIKey reads OPENAI_API_KEY from environment.
 func resolveAPIKey() string {
 	return strings.TrimSpace(os.Getenv("OPENAI_API_KEY"))
@@ -0,0 +1,184 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"miniclaw/pkg/config"
+)
+
+const (
+	defaultBaseURL = "https://api.openai.com/v1"
+	// defaultUsageTimeout bounds one Admin API request when request_timeout_seconds is unset.
+	defaultUsageTimeout = 30 * time.Second
+	// maxUsagePages stops pagination before a misbehaving API can loop forever.
+	maxUsagePages = 50
+)
+
+// DailyUsage is what OpenAI recorded for the organization, or the configured
+// project, over one UTC day.
+type DailyUsage struct {
+	Day               time.Time
+	InputTokens       int64
+	OutputTokens      int64
+	CachedInputTokens int64
+	Requests          int64
+	// CostUSD is the billed amount; costs lag usage, so recent days may still read low.
+	CostUSD float64
+}
+
+// UsageClient reads the organization usage and costs endpoints of the OpenAI Admin API.
+type UsageClient struct {
+	baseURL      string
+	adminKey     string
+	organization string
+	project      string
+	http         *http.Client
+}
+
+// NewUsageClient builds a usage client from providers.openai and OPENAI_ADMIN_KEY.
+//
+// Usage endpoints reject regular project keys, so an admin key is required.
+// When providers.openai.project is set, results are limited to that project.
+func NewUsageClient(cfg config.OpenAIProviderConfig) (*UsageClient, error) {
+	adminKey := strings.TrimSpace(os.Getenv("OPENAI_ADMIN_KEY"))
+	if adminKey == "" {
+		return nil, errors.New("OPENAI_ADMIN_KEY must be set to read OpenAI usage")
+	}
+
+	baseURL := strings.TrimRight(strings.TrimSpace(cfg.BaseURL), "/")
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	timeout := defaultUsageTimeout
+	if cfg.RequestTimeoutSeconds > 0 {
+		timeout = time.Duration(cfg.RequestTimeoutSeconds) * time.Second
+	}
+
+	return &UsageClient{
+		baseURL:      baseURL,
+		adminKey:     adminKey,
+		organization: strings.TrimSpace(cfg.Organization),
+		project:      strings.TrimSpace(cfg.Project),
+		http:         &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// usagePage is one page of /organization/usage/completions or /organization/costs.
+type usagePage struct {
+	Data []struct {
+		Results []struct {
+			InputTokens       int64 `json:"input_tokens"`
+			OutputTokens      int64 `json:"output_tokens"`
+			InputCachedTokens int64 `json:"input_cached_tokens"`
+			NumModelRequests  int64 `json:"num_model_requests"`
+			Amount            struct {
+				Value float64 `json:"value"`
+			} `json:"amount"`
+		} `json:"results"`
+	} `json:"data"`
+	HasMore  bool   `json:"has_more"`
+	NextPage string `json:"next_page"`
+}
+
+// Daily returns completions usage and cost for the UTC day containing day.
+func (c *UsageClient) Daily(ctx context.Context, day time.Time) (DailyUsage, error) {
+	start := day.UTC().Truncate(24 * time.Hour)
+	usage := DailyUsage{Day: start}
+	log := providerLogger().With("operation", "usage")
+	startedAt := time.Now()
+	log.Debug("Provider request started", "day", start.Format(time.DateOnly))
+
+	err := c.eachPage(ctx, "/organization/usage/completions", start, func(page usagePage) {
+		for _, bucket := range page.Data {
+			for _, result := range bucket.Results {
+				usage.InputTokens += result.InputTokens
+				usage.OutputTokens += result.OutputTokens
+				usage.CachedInputTokens += result.InputCachedTokens
+				usage.Requests += result.NumModelRequests
+			}
+		}
+	})
+	if err == nil {
+		err = c.eachPage(ctx, "/organization/costs", start, func(page usagePage) {
+			for _, bucket := range page.Data {
+				for _, result := range bucket.Results {
+					usage.CostUSD += result.Amount.Value
+				}
+			}
+		})
+	}
+	if err != nil {
+		log.Debug("Provider request failed", "duration_ms", time.Since(startedAt).Milliseconds(), "error", err)
+		return DailyUsage{}, err
+	}
+	log.Debug("Provider request completed", "duration_ms", time.Since(startedAt).Milliseconds())
+
+	return usage, nil
+}
+
+// eachPage fetches one day of path in a single daily bucket, following pagination.
+func (c *UsageClient) eachPage(ctx context.Context, path string, start time.Time, visit func(usagePage)) error {
+	query := url.Values{}
+	query.Set("start_time", strconv.FormatInt(start.Unix(), 10))
+	query.Set("end_time", strconv.FormatInt(start.Add(24*time.Hour).Unix(), 10))
+	query.Set("bucket_width", "1d")
+	if c.project != "" {
+		query.Add("project_ids", c.project)
+	}
+
+	for range maxUsagePages {
+		page, err := c.get(ctx, path, query)
+		if err != nil {
+			return err
+		}
+		visit(page)
+		if !page.HasMore || page.NextPage == "" {
+			return nil
+		}
+		query.Set("page", page.NextPage)
+	}
+
+	return fmt.Errorf("%s: more than %d pages", path, maxUsagePages)
+}
+
+func (c *UsageClient) get(ctx context.Context, path string, query url.Values) (usagePage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return usagePage{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.adminKey)
+	if c.organization != "" {
+		req.Header.Set("OpenAI-Organization", c.organization)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return usagePage{}, fmt.Errorf("%s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return usagePage{}, fmt.Errorf("%s: read response: %w", path, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return usagePage{}, fmt.Errorf("%s: http %d: %s", path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var page usagePage
+	if err := json.Unmarshal(body, &page); err != nil {
+		return usagePage{}, fmt.Errorf("%s: decode response: %w", path, err)
+	}
+
+	return page, nil
+}
@@ -0,0 +1,82 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"miniclaw/pkg/config"
+)
+
+func TestNewUsageClientRequiresAdminKey(t *testing.T) {
+	t.Setenv("OPENAI_ADMIN_KEY", "")
+
+	if _, err := NewUsageClient(config.OpenAIProviderConfig{}); err == nil {
+		t.Fatal("expected error when admin key is missing")
+	}
+}
+
+func TestUsageClientDailySumsPagesAndCosts(t *testing.T) {
+	t.Setenv("OPENAI_ADMIN_KEY", "sk-admin")
+
+	day := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-admin" {
+			t.Errorf("Authorization = %q", got)
+		}
+		query := r.URL.Query()
+		if query.Get("start_time") != fmt.Sprint(day.Unix()) || query.Get("end_time") != fmt.Sprint(day.Add(24*time.Hour).Unix()) {
+			t.Errorf("time range = %s..%s", query.Get("start_time"), query.Get("end_time"))
+		}
+		if query.Get("project_ids") != "proj_1" {
+			t.Errorf("project_ids = %q, want proj_1", query.Get("project_ids"))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/v1/organization/usage/completions" && query.Get("page") == "":
+			fmt.Fprint(w, `{"data":[{"results":[{"input_tokens":100,"output_tokens":40,"input_cached_tokens":10,"num_model_requests":2}]}],"has_more":true,"next_page":"p2"}`)
+		case r.URL.Path == "/v1/organization/usage/completions" && query.Get("page") == "p2":
+			fmt.Fprint(w, `{"data":[{"results":[{"input_tokens":50,"output_tokens":10,"num_model_requests":1}]}],"has_more":false}`)
+		case r.URL.Path == "/v1/organization/costs":
+			fmt.Fprint(w, `{"data":[{"results":[{"amount":{"value":0.25,"currency":"usd"}},{"amount":{"value":0.5,"currency":"usd"}}]}],"has_more":false}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewUsageClient(config.OpenAIProviderConfig{BaseURL: server.URL + "/v1/", Project: "proj_1"})
+	if err != nil {
+		t.Fatalf("NewUsageClient error: %v", err)
+	}
+	usage, err := client.Daily(context.Background(), day.Add(15*time.Hour))
+	if err != nil {
+		t.Fatalf("Daily error: %v", err)
+	}
+
+	want := DailyUsage{Day: day, InputTokens: 150, OutputTokens: 50, CachedInputTokens: 10, Requests: 3, CostUSD: 0.75}
+	if usage != want {
+		t.Fatalf("usage = %+v, want %+v", usage, want)
+	}
+}
+
+func TestUsageClientDailyReportsHTTPErrors(t *testing.T) {
+	t.Setenv("OPENAI_ADMIN_KEY", "sk-project")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, `{"error":{"message":"admin key required"}}`, http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewUsageClient(config.OpenAIProviderConfig{BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewUsageClient error: %v", err)
+	}
+	if _, err := client.Daily(context.Background(), time.Now()); err == nil {
+		t.Fatal("expected error for 401 response")
+	}
+}
@@ -16,11 +16,13 @@ import (
 
 	sdk "github.com/sst/opencode-sdk-go"
 	"github.com/sst/opencode-sdk-go/option"
+	"github.com/sst/opencode-sdk-go/shared"
 )
 
 type Client struct {
 	client         *sdk.Client
 	requestTimeout time.Duration
+	limits         providertypes.PayloadLimits
 }
 
 // healthResponse models the OpenCode health endpoint payload.
@@ -42,10 +44,15 @@ func New(cfg *config.Config) (*Client, error) {
 	}
 
 	requestTimeout := time.Duration(cfg.Providers.OpenCode.RequestTimeoutSeconds) * time.Second
+	limits, err := providertypes.NewPayloadLimits("opencode", cfg.Providers.OpenCode.Limits)
+	if err != nil {
+		return nil, err
+	}
 
 	return &Client{
 		client:         sdk.NewClient(opts...),
 		requestTimeout: requestTimeout,
+		limits:         limits,
 	}, nil
 }
 
@@ -60,7 +67,7 @@ func (c *Client) Health(ctx context.Context) error {
 	var response healthResponse
 	if err := c.client.Get(ctx, "/global/health", nil, &response); err != nil {
 		log.Debug("Provider request failed", "duration_ms", time.Since(startedAt).Milliseconds(), "error", err)
-		return fmt.Errorf("health check failed: %w", err)
+		return classifyError(fmt.Errorf("health check failed: %w", err))
 	}
 	if !response.Healthy {
 		log.Debug("Provider request failed", "duration_ms", time.Since(startedAt).Milliseconds(), "error", "server unhealthy")
@@ -86,7 +93,7 @@ func (c *Client) CreateSession(ctx context.Context, title string) (string, error
 	session, err := c.client.Session.New(ctx, params)
 	if err != nil {
 		log.Debug("Provider request failed", "duration_ms", time.Since(startedAt).Milliseconds(), "error", err)
-		return "", fmt.Errorf("create session failed: %w", err)
+		return "", classifyError(fmt.Errorf("create session failed: %w", err))
 	}
 	if session.ID == "" {
 		log.Debug("Provider request failed", "duration_ms", time.Since(startedAt).Milliseconds(), "error", "empty session id")
@@ -103,7 +110,7 @@ func (c *Client) Prompt(ctx context.Context, sessionID string, prompt string, mo
 
 	ctx, cancel := c.withTimeout(ctx)
 	defer cancel()
-	log := providerLogger().With("operation", "prompt")
+	log := providertypes.TraceLogger(ctx, providerLogger().With("operation", "prompt"))
 	startedAt := time.Now()
 	log.Debug("Provider request started",
 		"session_id", strings.TrimSpace(sessionID),
@@ -112,6 +119,12 @@ func (c *Client) Prompt(ctx context.Context, sessionID string, prompt string, mo
 		"prompt_length", len(strings.TrimSpace(prompt)),
 	)
 
+	// History lives server-side, so only the new prompt counts toward the limit and it cannot be trimmed.
+	if err := c.limits.CheckRequest(len(prompt)); err != nil {
+		log.Debug("Provider request failed", "duration_ms", time.Since(startedAt).Milliseconds(), "error", err)
+		return providertypes.PromptResult{}, err
+	}
+
 	params := sdk.SessionPromptParams{
 		Parts: sdk.F([]sdk.SessionPromptParamsPartUnion{
 			sdk.TextPartInputParam{
@@ -135,7 +148,11 @@ func (c *Client) Prompt(ctx context.Context, sessionID string, prompt string, mo
 	response, err := c.client.Session.Prompt(ctx, sessionID, params)
 	if err != nil {
 		log.Debug("Provider request failed", "duration_ms", time.Since(startedAt).Milliseconds(), "error", err)
-		return providertypes.PromptResult{}, fmt.Errorf("prompt failed: %w", err)
+		return providertypes.PromptResult{}, classifyError(fmt.Errorf("prompt failed: %w", err))
+	}
+	if err := assistantError(response.Info); err != nil {
+		log.Debug("Provider request failed", "duration_ms", time.Since(startedAt).Milliseconds(), "error", err)
+		return providertypes.PromptResult{}, err
 	}
 
 	text := extractText(response.Parts)
@@ -148,6 +165,10 @@ func (c *Client) Prompt(ctx context.Context, sessionID string, prompt string, mo
 		"response_length", len(text),
 		"parts_count", len(response.Parts),
 	)
+	text, err = c.limits.LimitResponse(text)
+	if err != nil {
+		return providertypes.PromptResult{}, err
+	}
 
 	usage := providertypes.TokenUsage{
 		InputTokens:     tokenCount(response.Info.Tokens.Input),
@@ -184,6 +205,57 @@ func (c *Client) withTimeout(ctx context.Context) (context.Context, context.Canc
 	return context.WithTimeout(ctx, c.requestTimeout)
 }
 
+// classifyError attaches a providertypes error category using the SDK status code.
+func classifyError(err error) error {
+	var apiErr *sdk.Error
+	if errors.As(err, &apiErr) {
+		return providertypes.ClassifyError("opencode", apiErr.StatusCode, err)
+	}
+
+	return providertypes.ClassifyError("opencode", 0, err)
+}
+
+// assistantError converts an error recorded on the assistant message into a categorized error.
+//
+// OpenCode reports upstream model failures in the message body with a 200
+// status, so they would otherwise surface as an empty reply.
+func assistantError(info sdk.AssistantMessage) error {
+	if info.Error.Name == "" {
+		return nil
+	}
+
+	provider := strings.TrimSpace(info.ProviderID)
+	if provider == "" {
+		provider = "opencode"
+	}
+
+	switch info.Error.Name {
+	case sdk.AssistantMessageErrorNameProviderAuthError:
+		message := string(info.Error.Name)
+		if typed, ok := info.Error.AsUnion().(shared.ProviderAuthError); ok && typed.Data.Message != "" {
+			message = typed.Data.Message
+		}
+		return &providertypes.PromptError{
+			Category: providertypes.ErrorAuth,
+			Provider: provider,
+			Err:      fmt.Errorf("prompt failed: %s", message),
+		}
+	case sdk.AssistantMessageErrorNameAPIError:
+		typed, ok := info.Error.AsUnion().(sdk.AssistantMessageErrorAPIError)
+		if !ok {
+			break
+		}
+		classified := providertypes.ClassifyError(provider, int(typed.Data.StatusCode), fmt.Errorf("prompt failed: %s", typed.Data.Message))
+		var promptErr *providertypes.PromptError
+		if errors.As(classified, &promptErr) {
+			promptErr.RetryAfter = providertypes.ParseRetryAfter(providertypes.HeaderValue(typed.Data.ResponseHeaders, "Retry-After"), time.Now())
+		}
+		return classified
+	}
+
+	return providertypes.ClassifyError(provider, 0, fmt.Errorf("prompt failed: %s", info.Error.Name))
+}
+
 // buildBasicAuthHeader builds a Basic auth header from configured credentials.
 func buildBasicAuthHeader(cfg config.OpenCodeProviderConfig) (string, bool) {
 	passwordEnv := strings.TrimSpace(cfg.PasswordEnv)
@@ -6,9 +6,11 @@ import (
 	"log/slog"
 
 	"miniclaw/pkg/config"
+	"miniclaw/pkg/provider/mock"
 	provideropenai "miniclaw/pkg/provider/openai"
 	"miniclaw/pkg/provider/opencode"
 	providertypes "miniclaw/pkg/provider/types"
+	"miniclaw/pkg/transcript"
 )
 
 // Client is the provider-agnostic contract used by agent/runtime layers.
@@ -18,6 +20,37 @@ type Client interface {
 	Prompt(ctx context.Context, sessionID string, prompt string, model string, agent string, systemPrompt string) (providertypes.PromptResult, error)
 }
 
+// SessionResumer is implemented by clients that persist sessions and can
+// continue one started by an earlier process.
+type SessionResumer interface {
+	// LatestSession returns the most recently updated session created with
+	// title, and false when there is none.
+	LatestSession(ctx context.Context, title string) (string, bool, error)
+}
+
+// SessionExporter is implemented by clients whose stored sessions can be
+// copied out and back in (see pkg/transcript).
+type SessionExporter interface {
+	ExportSession(ctx context.Context, sessionID string) (transcript.Transcript, error)
+	// ImportSession stores exported as a new session titled title and returns its ID.
+	ImportSession(ctx context.Context, exported transcript.Transcript, title string) (string, error)
+}
+
+// SessionNamer is implemented by clients that give sessions a short display
+// name, such as one generated from the first prompt.
+type SessionNamer interface {
+	// SessionName returns the name of sessionID, or "" while it has none.
+	SessionName(ctx context.Context, sessionID string) (string, error)
+}
+
+// TurnRollbacker is implemented by clients that keep session history and can
+// drop its last turn.
+type TurnRollbacker interface {
+	// RollbackLastTurn removes the last user prompt of sessionID and every
+	// message after it. It returns providertypes.ErrNoTurnToUndo when there is none.
+	RollbackLastTurn(ctx context.Context, sessionID string) error
+}
+
 // New resolves the configured provider and returns the matching client.
 func New(cfg *config.Config) (Client, error) {
 	providerID := cfg.Agents.Defaults.Provider
@@ -32,6 +65,8 @@ func New(cfg *config.Config) (Client, error) {
 		return opencode.New(cfg)
 	case "openai":
 		return provideropenai.New(cfg)
+	case "mock":
+		return mock.New(cfg)
 	default:
 		return nil, fmt.Errorf("unsupported provider: %s", providerID)
 	}
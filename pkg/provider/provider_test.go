@@ -1,9 +1,11 @@
 package provider
 
 import (
+	"path/filepath"
 	"testing"
 
 	"miniclaw/pkg/config"
+	providermock "miniclaw/pkg/provider/mock"
 	provideropenai "miniclaw/pkg/provider/openai"
 	provideropencode "miniclaw/pkg/provider/opencode"
 )
@@ -47,3 +49,23 @@ func TestNewReturnsOpenAIProvider(t *testing.T) {
 		t.Fatalf("expected *openai.Client, got %T", client)
 	}
 }
+
+func TestNewReturnsMockProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	if err := providermock.SaveFixture(path, providermock.Fixture{Version: providermock.FixtureVersion}); err != nil {
+		t.Fatalf("SaveFixture error: %v", err)
+	}
+
+	cfg := &config.Config{}
+	cfg.Agents.Defaults.Provider = "mock"
+	cfg.Providers.Mock.Fixture = path
+
+	client, err := New(cfg)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if _, ok := client.(*providermock.Client); !ok {
+		t.Fatalf("expected *mock.Client, got %T", client)
+	}
+}
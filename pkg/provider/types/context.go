@@ -0,0 +1,122 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"miniclaw/pkg/config"
+)
+
+// DefaultContextWarnPercent is the share of the context window at which a
+// session is flagged as nearly full when agents.defaults.context.warn_percent is unset.
+const DefaultContextWarnPercent = 80
+
+// bytesPerToken approximates token counts from encoded history size.
+const bytesPerToken = 4
+
+// knownContextWindows maps model ID prefixes to their context window in tokens.
+// More specific prefixes come first.
+var knownContextWindows = []struct {
+	prefix string
+	tokens int64
+}{
+	{prefix: "gpt-5", tokens: 400_000},
+	{prefix: "gpt-4.1", tokens: 1_047_576},
+	{prefix: "gpt-4o", tokens: 128_000},
+	{prefix: "o4-mini", tokens: 200_000},
+	{prefix: "o3", tokens: 200_000},
+	{prefix: "claude-", tokens: 200_000},
+}
+
+// ContextBudget resolves agents.defaults.context for one provider client.
+//
+// The zero value uses the built-in model windows and DefaultContextWarnPercent.
+type ContextBudget struct {
+	// WindowTokens overrides the built-in window of every model when positive.
+	WindowTokens int64
+	WarnPercent  int
+}
+
+// NewContextBudget validates agents.defaults.context.
+func NewContextBudget(cfg config.ContextConfig) (ContextBudget, error) {
+	if cfg.WindowTokens < 0 {
+		return ContextBudget{}, errors.New("agents.defaults.context.window_tokens must not be negative")
+	}
+	if cfg.WarnPercent < 0 || cfg.WarnPercent > 100 {
+		return ContextBudget{}, errors.New("agents.defaults.context.warn_percent must be between 0 and 100")
+	}
+
+	return ContextBudget{WindowTokens: int64(cfg.WindowTokens), WarnPercent: cfg.WarnPercent}, nil
+}
+
+// Window returns the context window of model in tokens, or 0 when it is unknown.
+func (b ContextBudget) Window(model string) int64 {
+	if b.WindowTokens > 0 {
+		return b.WindowTokens
+	}
+
+	model = strings.ToLower(strings.TrimSpace(model))
+	if _, id, ok := strings.Cut(model, "/"); ok {
+		model = id
+	}
+	for _, known := range knownContextWindows {
+		if strings.HasPrefix(model, known.prefix) {
+			return known.tokens
+		}
+	}
+
+	return 0
+}
+
+// Usage describes usedTokens of model's window; it returns nil when the window is unknown.
+func (b ContextBudget) Usage(model string, usedTokens int64, estimated bool) *ContextUsage {
+	window := b.Window(model)
+	if window <= 0 || usedTokens <= 0 {
+		return nil
+	}
+
+	warnPercent := b.WarnPercent
+	if warnPercent <= 0 {
+		warnPercent = DefaultContextWarnPercent
+	}
+	usage := &ContextUsage{UsedTokens: usedTokens, WindowTokens: window, Estimated: estimated}
+	usage.NearlyFull = usage.Percent() >= warnPercent
+	return usage
+}
+
+// EstimateTokens approximates the tokens in size bytes of encoded history.
+func EstimateTokens(size int) int64 {
+	return int64((size + bytesPerToken - 1) / bytesPerToken)
+}
+
+// ContextUsage is how much of the model's context window a session's history
+// fills after a turn.
+type ContextUsage struct {
+	UsedTokens   int64
+	WindowTokens int64
+	// Estimated is set when UsedTokens comes from the history size rather than
+	// provider token counts.
+	Estimated bool
+	// NearlyFull is set once usage reaches the configured warn percent.
+	NearlyFull bool
+}
+
+// Percent returns UsedTokens as a whole percentage of WindowTokens.
+func (u ContextUsage) Percent() int {
+	if u.WindowTokens <= 0 {
+		return 0
+	}
+
+	return int(u.UsedTokens * 100 / u.WindowTokens)
+}
+
+// String renders usage as "34% (68000/200000 tokens)", with "~" marking estimates.
+func (u ContextUsage) String() string {
+	approx := ""
+	if u.Estimated {
+		approx = "~"
+	}
+
+	return fmt.Sprintf("%s%d%% (%d/%d tokens)", approx, u.Percent(), u.UsedTokens, u.WindowTokens)
+}
@@ -0,0 +1,52 @@
+package types
+
+import (
+	"testing"
+
+	"miniclaw/pkg/config"
+)
+
+func TestContextBudgetUsage(t *testing.T) {
+	budget, err := NewContextBudget(config.ContextConfig{})
+	if err != nil {
+		t.Fatalf("NewContextBudget error: %v", err)
+	}
+	for model, want := range map[string]int64{
+		"openai/gpt-5-nano":           400_000,
+		"gpt-4o-mini":                 128_000,
+		"anthropic/claude-sonnet-4-5": 200_000,
+		"llama-3":                     0,
+	} {
+		if got := budget.Window(model); got != want {
+			t.Fatalf("Window(%q) = %d, want %d", model, got, want)
+		}
+	}
+
+	usage := budget.Usage("claude-sonnet-4-5", 68_000, false)
+	if usage == nil || usage.Percent() != 34 || usage.NearlyFull || usage.String() != "34% (68000/200000 tokens)" {
+		t.Fatalf("usage = %+v, want 34%% and not nearly full", usage)
+	}
+	if usage := budget.Usage("claude-sonnet-4-5", 160_000, true); usage == nil || !usage.NearlyFull || usage.String()[0] != '~' {
+		t.Fatalf("usage = %+v, want an estimated, nearly full window at 80%%", usage)
+	}
+	if usage := budget.Usage("llama-3", 1_000, false); usage != nil {
+		t.Fatalf("usage = %+v, want nil for an unknown window", usage)
+	}
+
+	custom, err := NewContextBudget(config.ContextConfig{WindowTokens: 8_000, WarnPercent: 50})
+	if err != nil {
+		t.Fatalf("NewContextBudget error: %v", err)
+	}
+	if usage := custom.Usage("llama-3", 4_000, false); usage == nil || usage.WindowTokens != 8_000 || !usage.NearlyFull {
+		t.Fatalf("usage = %+v, want the 8000 token override flagged at 50%%", usage)
+	}
+
+	for name, cfg := range map[string]config.ContextConfig{
+		"negative window": {WindowTokens: -1},
+		"percent above":   {WarnPercent: 101},
+	} {
+		if _, err := NewContextBudget(cfg); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
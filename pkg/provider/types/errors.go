@@ -0,0 +1,377 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrorCategory classifies a prompt failure by what the user can do about it.
+type ErrorCategory string
+
+const (
+	// ErrorAuth means the provider rejected the credentials.
+	ErrorAuth ErrorCategory = "auth"
+	// ErrorRateLimit means the provider throttled the request or quota ran out.
+	ErrorRateLimit ErrorCategory = "rate_limit"
+	// ErrorContextLength means the conversation no longer fits the model context window.
+	ErrorContextLength ErrorCategory = "context_length"
+	// ErrorTimeout means the request exceeded its deadline.
+	ErrorTimeout ErrorCategory = "timeout"
+	// ErrorProviderDown means the provider was unreachable or failed server-side.
+	ErrorProviderDown ErrorCategory = "provider_down"
+	// ErrorRequestTooLarge means the request exceeded the configured max_request_bytes.
+	ErrorRequestTooLarge ErrorCategory = "request_too_large"
+	// ErrorResponseTooLarge means the reply exceeded the configured max_response_bytes.
+	ErrorResponseTooLarge ErrorCategory = "response_too_large"
+	// ErrorToolFailure means a tool aborted the agent run.
+	ErrorToolFailure ErrorCategory = "tool_failure"
+	// ErrorCanceled means the prompt was stopped before it finished.
+	ErrorCanceled ErrorCategory = "canceled"
+	// ErrorQueueFull means the session already had too many prompts waiting.
+	ErrorQueueFull ErrorCategory = "queue_full"
+	// ErrorCircuitOpen means prompts are paused after repeated provider failures.
+	ErrorCircuitOpen ErrorCategory = "circuit_open"
+	// ErrorBudgetExhausted means the session spent its configured token or cost budget.
+	ErrorBudgetExhausted ErrorCategory = "budget_exhausted"
+	// ErrorRejected means a channel policy refused the message before any agent saw it.
+	ErrorRejected ErrorCategory = "rejected"
+	// ErrorUnknown is used when no other category matches.
+	ErrorUnknown ErrorCategory = "unknown"
+)
+
+// ErrNoTurnToUndo is returned when a session has no turn left to roll back.
+var ErrNoTurnToUndo = errors.New("no turn to undo")
+
+// PromptError is a provider failure annotated with its ErrorCategory.
+//
+// Error returns the wrapped message unchanged so logs keep full detail;
+// Summarize and UserMessage give channels and the UI an actionable summary.
+type PromptError struct {
+	Category   ErrorCategory
+	Provider   string
+	StatusCode int
+	RetryAfter time.Duration
+	// RequestID correlates the failure with a runtime request when one is known.
+	RequestID string
+	Err       error
+}
+
+func (e *PromptError) Error() string {
+	if e.Err == nil {
+		return string(e.Category)
+	}
+	return e.Err.Error()
+}
+
+func (e *PromptError) Unwrap() error {
+	return e.Err
+}
+
+// ToolFailureError marks an error raised by a tool rather than the model provider.
+type ToolFailureError struct {
+	Tool string
+	Err  error
+}
+
+func (e *ToolFailureError) Error() string {
+	return fmt.Sprintf("tool %s failed: %v", e.Tool, e.Err)
+}
+
+func (e *ToolFailureError) Unwrap() error {
+	return e.Err
+}
+
+// ReplyError sets the reply UserMessage gives for Err, such as text from an
+// operator's message template, while Err keeps its category for logs, status
+// codes, and metrics.
+type ReplyError struct {
+	Reply string
+	Err   error
+}
+
+func (e *ReplyError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ReplyError) Unwrap() error {
+	return e.Err
+}
+
+// ClassifyError wraps err in a PromptError for provider.
+//
+// statusCode is the HTTP status reported by the provider SDK, or 0 when the
+// request never got a response. Errors that already carry a PromptError are
+// returned as-is so the innermost classification wins.
+func ClassifyError(provider string, statusCode int, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var existing *PromptError
+	if errors.As(err, &existing) {
+		return err
+	}
+
+	return &PromptError{
+		Category:   categorize(statusCode, err),
+		Provider:   strings.TrimSpace(provider),
+		StatusCode: statusCode,
+		Err:        err,
+	}
+}
+
+// ErrorCategoryOf reports the category of err, classifying unwrapped errors on the fly.
+func ErrorCategoryOf(err error) ErrorCategory {
+	if err == nil {
+		return ""
+	}
+
+	var promptErr *PromptError
+	if errors.As(err, &promptErr) {
+		return promptErr.Category
+	}
+
+	return categorize(0, err)
+}
+
+// ErrorSummary is a display-ready description of a failed prompt.
+type ErrorSummary struct {
+	Category ErrorCategory
+	// Title says what went wrong, for example "API key invalid or missing for openai".
+	Title string
+	// Hint suggests a fix, for example "Set OPENAI_API_KEY and restart."; empty when none applies.
+	Hint string
+	// Detail is the raw error text, kept for logs and detail views.
+	Detail    string
+	RequestID string
+}
+
+// Summarize maps err onto a title and remediation hint for its category.
+func Summarize(err error) ErrorSummary {
+	if err == nil {
+		return ErrorSummary{}
+	}
+
+	var promptErr *PromptError
+	if !errors.As(err, &promptErr) {
+		promptErr = &PromptError{Category: categorize(0, err), Err: err}
+	}
+
+	summary := ErrorSummary{
+		Category:  promptErr.Category,
+		Detail:    err.Error(),
+		RequestID: promptErr.RequestID,
+	}
+	provider := promptErr.Provider
+	if provider == "" {
+		provider = "provider"
+	}
+
+	switch promptErr.Category {
+	case ErrorAuth:
+		summary.Title = fmt.Sprintf("API key invalid or missing for %s", provider)
+		summary.Hint = credentialHint(promptErr.Provider)
+	case ErrorRateLimit:
+		summary.Title = fmt.Sprintf("Rate limited by %s", provider)
+		summary.Hint = "Wait a moment and retry."
+		if promptErr.RetryAfter > 0 {
+			summary.Hint = fmt.Sprintf("Retry in %s.", promptErr.RetryAfter.Round(time.Second))
+		}
+	case ErrorContextLength:
+		summary.Title = "Conversation is too long for the model context window"
+		summary.Hint = "Start a new session or shorten the prompt."
+	case ErrorRequestTooLarge:
+		summary.Title = "Request exceeds the configured size limit"
+		summary.Hint = fmt.Sprintf("Start a new session, raise providers.%s.limits.max_request_bytes, or set truncation to \"truncate\".", provider)
+	case ErrorResponseTooLarge:
+		summary.Title = "Reply exceeds the configured size limit"
+		summary.Hint = fmt.Sprintf("Ask for a shorter answer, raise providers.%s.limits.max_response_bytes, or set truncation to \"truncate\".", provider)
+	case ErrorTimeout:
+		summary.Title = fmt.Sprintf("Request to %s timed out", provider)
+		summary.Hint = "Retry, or raise the provider request_timeout_seconds."
+		if promptErr.Provider != "" {
+			summary.Hint = fmt.Sprintf("Retry, or raise providers.%s.request_timeout_seconds.", promptErr.Provider)
+		}
+	case ErrorProviderDown:
+		summary.Title = fmt.Sprintf("%s is unavailable right now", provider)
+		summary.Hint = "Retry later; check the provider status page if it persists."
+	case ErrorCanceled:
+		summary.Title = "Prompt canceled"
+	case ErrorQueueFull:
+		summary.Title = "Too many prompts are waiting in this session"
+		summary.Hint = "Wait for the current replies, or raise runtime.max_queued_per_session."
+	case ErrorCircuitOpen:
+		summary.Title = fmt.Sprintf("%s keeps failing, so requests are paused", provider)
+		summary.Hint = "Retry in a moment."
+		if promptErr.RetryAfter > 0 {
+			summary.Hint = fmt.Sprintf("Retry in %s.", promptErr.RetryAfter.Round(time.Second))
+		}
+	case ErrorBudgetExhausted:
+		summary.Title = "Budget exhausted for this session"
+		summary.Hint = "Ask the operator to raise runtime.budget."
+		if promptErr.RetryAfter > 0 {
+			summary.Hint = fmt.Sprintf("The daily budget resets in %s.", max(promptErr.RetryAfter.Round(time.Minute), time.Minute))
+		}
+	case ErrorRejected:
+		summary.Title = promptErr.Error()
+	case ErrorToolFailure:
+		summary.Title = "A tool failed while running the prompt"
+		var toolErr *ToolFailureError
+		if errors.As(err, &toolErr) {
+			summary.Title = fmt.Sprintf("Tool %s failed: %v", toolErr.Tool, toolErr.Err)
+		}
+		summary.Hint = "Check the tool input and workspace state, then retry."
+	default:
+		summary.Category = ErrorUnknown
+		summary.Title = err.Error()
+	}
+
+	return summary
+}
+
+// UserMessage returns an actionable one-line description of err for channel replies.
+//
+// A ReplyError's reply is used as is. Categorized errors get a title and
+// remediation hint; anything unknown falls back to the raw error text.
+func UserMessage(err error) string {
+	var replyErr *ReplyError
+	if errors.As(err, &replyErr) {
+		return replyErr.Reply
+	}
+
+	summary := Summarize(err)
+	if summary.Hint == "" {
+		return summary.Title
+	}
+
+	return summary.Title + ". " + summary.Hint
+}
+
+// credentialHint names the setting to fix for an auth failure on provider.
+func credentialHint(provider string) string {
+	switch provider {
+	case "openai":
+		return "Set OPENAI_API_KEY and restart."
+	case "anthropic":
+		return "Set ANTHROPIC_API_KEY and restart."
+	case "opencode":
+		return "Check providers.opencode.username and the password_env variable."
+	default:
+		return "Check the configured provider credentials."
+	}
+}
+
+// ParseRetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+
+	return 0
+}
+
+// HeaderValue looks up a header case-insensitively in a plain string map, as
+// SDKs that hand response headers back as map[string]string use.
+func HeaderValue(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// categorize maps a status code and error chain onto an ErrorCategory.
+func categorize(statusCode int, err error) ErrorCategory {
+	var toolErr *ToolFailureError
+	if errors.As(err, &toolErr) {
+		return ErrorToolFailure
+	}
+
+	message := strings.ToLower(err.Error())
+	if isContextLengthMessage(message) {
+		return ErrorContextLength
+	}
+
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return ErrorAuth
+	case statusCode == http.StatusTooManyRequests:
+		return ErrorRateLimit
+	case statusCode == http.StatusRequestTimeout || statusCode == http.StatusGatewayTimeout:
+		return ErrorTimeout
+	case statusCode == http.StatusRequestEntityTooLarge:
+		return ErrorContextLength
+	case statusCode >= http.StatusInternalServerError:
+		return ErrorProviderDown
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorTimeout
+	}
+	if errors.Is(err, context.Canceled) {
+		return ErrorCanceled
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorTimeout
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return ErrorProviderDown
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return ErrorProviderDown
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return ErrorProviderDown
+	}
+
+	// Some SDKs flatten the HTTP failure into text, so fall back to wording.
+	switch {
+	case strings.Contains(message, "api key") || strings.Contains(message, "api_key") || strings.Contains(message, "unauthorized"):
+		return ErrorAuth
+	case strings.Contains(message, "rate limit") || strings.Contains(message, "rate_limit") || strings.Contains(message, "quota"):
+		return ErrorRateLimit
+	case strings.Contains(message, "overloaded"):
+		return ErrorProviderDown
+	}
+
+	return ErrorUnknown
+}
+
+// isContextLengthMessage reports whether a lower-cased provider message describes a context overflow.
+func isContextLengthMessage(message string) bool {
+	for _, marker := range []string{
+		"context_length_exceeded",
+		"maximum context length",
+		"context window",
+		"prompt is too long",
+		"too many tokens",
+	} {
+		if strings.Contains(message, marker) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,105 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassifyErrorCategories(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		err        error
+		want       ErrorCategory
+	}{
+		{name: "unauthorized", statusCode: 401, err: errors.New("prompt failed"), want: ErrorAuth},
+		{name: "forbidden", statusCode: 403, err: errors.New("prompt failed"), want: ErrorAuth},
+		{name: "rate limited", statusCode: 429, err: errors.New("prompt failed"), want: ErrorRateLimit},
+		{name: "server error", statusCode: 503, err: errors.New("prompt failed"), want: ErrorProviderDown},
+		{name: "context length by code", statusCode: 400, err: errors.New(`{"code":"context_length_exceeded"}`), want: ErrorContextLength},
+		{name: "deadline", err: fmt.Errorf("prompt failed: %w", context.DeadlineExceeded), want: ErrorTimeout},
+		{name: "canceled", err: fmt.Errorf("prompt failed: %w", context.Canceled), want: ErrorCanceled},
+		{name: "tool", err: fmt.Errorf("prompt failed: %w", &ToolFailureError{Tool: "read_file", Err: errors.New("boom")}), want: ErrorToolFailure},
+		{name: "missing key text", err: errors.New("OPENAI_API_KEY must be set"), want: ErrorAuth},
+		{name: "unknown", statusCode: 400, err: errors.New("bad request"), want: ErrorUnknown},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ClassifyError("openai", test.statusCode, test.err)
+			if got := ErrorCategoryOf(err); got != test.want {
+				t.Fatalf("category = %q, want %q", got, test.want)
+			}
+			if !errors.Is(err, test.err) {
+				t.Fatal("expected classified error to wrap the original")
+			}
+			if err.Error() != test.err.Error() {
+				t.Fatalf("message = %q, want %q", err.Error(), test.err.Error())
+			}
+		})
+	}
+}
+
+func TestClassifyErrorKeepsInnermostCategory(t *testing.T) {
+	inner := ClassifyError("anthropic", 429, errors.New("slow down"))
+	outer := ClassifyError("openai", 0, fmt.Errorf("prompt failed: %w", inner))
+
+	var promptErr *PromptError
+	if !errors.As(outer, &promptErr) {
+		t.Fatal("expected PromptError")
+	}
+	if promptErr.Category != ErrorRateLimit || promptErr.Provider != "anthropic" {
+		t.Fatalf("got %q from %q, want rate_limit from anthropic", promptErr.Category, promptErr.Provider)
+	}
+}
+
+func TestUserMessage(t *testing.T) {
+	rateLimited := &PromptError{Category: ErrorRateLimit, Provider: "openai", RetryAfter: 20 * time.Second, Err: errors.New("429")}
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "auth", err: &PromptError{Category: ErrorAuth, Provider: "openai", Err: errors.New("401")}, want: "API key invalid"},
+		{name: "auth hint", err: &PromptError{Category: ErrorAuth, Provider: "openai", Err: errors.New("401")}, want: "Set OPENAI_API_KEY"},
+		{name: "rate limit", err: rateLimited, want: "Retry in 20s"},
+		{name: "context", err: &PromptError{Category: ErrorContextLength, Err: errors.New("too long")}, want: "context window"},
+		{name: "unknown falls back to raw", err: errors.New("something odd"), want: "something odd"},
+		{name: "reply overrides summary", err: fmt.Errorf("prompt: %w", &ReplyError{Reply: "Bitte später erneut versuchen", Err: rateLimited}), want: "Bitte später"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := UserMessage(test.err); !strings.Contains(got, test.want) {
+				t.Fatalf("UserMessage = %q, want it to contain %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	if got := ParseRetryAfter("7", now); got != 7*time.Second {
+		t.Fatalf("seconds = %s, want 7s", got)
+	}
+	if got := ParseRetryAfter(now.Add(30*time.Second).Format("Mon, 02 Jan 2006 15:04:05 GMT"), now); got != 30*time.Second {
+		t.Fatalf("date = %s, want 30s", got)
+	}
+	if got := ParseRetryAfter("soon", now); got != 0 {
+		t.Fatalf("invalid = %s, want 0", got)
+	}
+}
+
+func TestHeaderValueIgnoresCase(t *testing.T) {
+	headers := map[string]string{"retry-after": "7"}
+	if got := HeaderValue(headers, "Retry-After"); got != "7" {
+		t.Fatalf("HeaderValue = %q, want 7", got)
+	}
+	if got := HeaderValue(headers, "X-Request-Id"); got != "" {
+		t.Fatalf("HeaderValue of a missing header = %q, want empty", got)
+	}
+}
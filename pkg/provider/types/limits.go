@@ -0,0 +1,89 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"miniclaw/pkg/config"
+)
+
+const (
+	TruncationError    = "error"
+	TruncationTruncate = "truncate"
+)
+
+// PayloadLimits bounds request and response sizes for one provider.
+//
+// The zero value imposes no limits.
+type PayloadLimits struct {
+	Provider         string
+	MaxRequestBytes  int
+	MaxResponseBytes int
+	// Truncate trims oversized payloads instead of failing them.
+	Truncate bool
+}
+
+// NewPayloadLimits validates providers.<name>.limits for provider.
+func NewPayloadLimits(provider string, cfg config.PayloadLimitsConfig) (PayloadLimits, error) {
+	if cfg.MaxRequestBytes < 0 || cfg.MaxResponseBytes < 0 {
+		return PayloadLimits{}, fmt.Errorf("providers.%s.limits must not be negative", provider)
+	}
+
+	limits := PayloadLimits{
+		Provider:         provider,
+		MaxRequestBytes:  cfg.MaxRequestBytes,
+		MaxResponseBytes: cfg.MaxResponseBytes,
+	}
+	switch strings.ToLower(strings.TrimSpace(cfg.Truncation)) {
+	case "", TruncationError:
+	case TruncationTruncate:
+		limits.Truncate = true
+	default:
+		return PayloadLimits{}, fmt.Errorf("unsupported providers.%s.limits.truncation %q (want error or truncate)", provider, cfg.Truncation)
+	}
+
+	return limits, nil
+}
+
+// RequestFits reports whether a request of size bytes is within MaxRequestBytes.
+func (l PayloadLimits) RequestFits(size int) bool {
+	return l.MaxRequestBytes <= 0 || size <= l.MaxRequestBytes
+}
+
+// CheckRequest returns an ErrorRequestTooLarge error when size exceeds MaxRequestBytes.
+func (l PayloadLimits) CheckRequest(size int) error {
+	if l.RequestFits(size) {
+		return nil
+	}
+
+	return &PromptError{
+		Category: ErrorRequestTooLarge,
+		Provider: l.Provider,
+		Err:      fmt.Errorf("request payload is %d bytes, over the %d byte limit", size, l.MaxRequestBytes),
+	}
+}
+
+// LimitResponse applies MaxResponseBytes to text.
+//
+// Oversized replies are cut at a UTF-8 boundary with a marker when Truncate
+// is set, and rejected with an ErrorResponseTooLarge error otherwise.
+func (l PayloadLimits) LimitResponse(text string) (string, error) {
+	if l.MaxResponseBytes <= 0 || len(text) <= l.MaxResponseBytes {
+		return text, nil
+	}
+	if !l.Truncate {
+		return "", &PromptError{
+			Category: ErrorResponseTooLarge,
+			Provider: l.Provider,
+			Err:      fmt.Errorf("response is %d bytes, over the %d byte limit", len(text), l.MaxResponseBytes),
+		}
+	}
+
+	cut := l.MaxResponseBytes
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+
+	return text[:cut] + fmt.Sprintf("\n\n[response truncated: showing %d of %d bytes]", cut, len(text)), nil
+}
@@ -0,0 +1,68 @@
+package types
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"miniclaw/pkg/config"
+)
+
+func TestNewPayloadLimitsValidatesConfig(t *testing.T) {
+	limits, err := NewPayloadLimits("openai", config.PayloadLimitsConfig{MaxRequestBytes: 10, Truncation: " Truncate "})
+	if err != nil {
+		t.Fatalf("NewPayloadLimits error: %v", err)
+	}
+	if !limits.Truncate || limits.MaxRequestBytes != 10 {
+		t.Fatalf("limits = %+v, want truncating 10 byte request limit", limits)
+	}
+
+	for name, cfg := range map[string]config.PayloadLimitsConfig{
+		"negative request":  {MaxRequestBytes: -1},
+		"negative response": {MaxResponseBytes: -1},
+		"unknown policy":    {Truncation: "drop"},
+	} {
+		if _, err := NewPayloadLimits("openai", cfg); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestPayloadLimitsCheckRequest(t *testing.T) {
+	if err := (PayloadLimits{}).CheckRequest(1 << 30); err != nil {
+		t.Fatalf("zero limits error = %v, want nil", err)
+	}
+
+	limits := PayloadLimits{Provider: "anthropic", MaxRequestBytes: 100}
+	if err := limits.CheckRequest(100); err != nil {
+		t.Fatalf("request at limit error = %v, want nil", err)
+	}
+	err := limits.CheckRequest(101)
+	if got := ErrorCategoryOf(err); got != ErrorRequestTooLarge {
+		t.Fatalf("category = %q, want %q", got, ErrorRequestTooLarge)
+	}
+	if summary := Summarize(err); !strings.Contains(summary.Hint, "providers.anthropic.limits.max_request_bytes") {
+		t.Fatalf("hint = %q, want config key", summary.Hint)
+	}
+}
+
+func TestPayloadLimitsLimitResponse(t *testing.T) {
+	text := strings.Repeat("é", 10)
+
+	_, err := PayloadLimits{Provider: "openai", MaxResponseBytes: 5}.LimitResponse(text)
+	if got := ErrorCategoryOf(err); got != ErrorResponseTooLarge {
+		t.Fatalf("category = %q, want %q", got, ErrorResponseTooLarge)
+	}
+
+	truncated, err := PayloadLimits{MaxResponseBytes: 5, Truncate: true}.LimitResponse(text)
+	if err != nil {
+		t.Fatalf("LimitResponse error: %v", err)
+	}
+	if !strings.HasPrefix(truncated, "éé\n\n[response truncated: showing 4 of 20 bytes]") || !utf8.ValidString(truncated) {
+		t.Fatalf("truncated = %q, want two runes and a marker", truncated)
+	}
+
+	if got, _ := (PayloadLimits{MaxResponseBytes: 20}).LimitResponse(text); got != text {
+		t.Fatalf("response at limit = %q, want unchanged", got)
+	}
+}
@@ -0,0 +1,57 @@
+package types
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+type fileOutboxKey struct{}
+
+// FileOutbox collects the workspace files a prompt asks its channel to
+// deliver with the reply, for example through the send_file tool.
+type FileOutbox struct {
+	mu    sync.Mutex
+	paths []string
+}
+
+// Add queues the file at the absolute path for delivery; a path queued twice
+// is sent once.
+func (o *FileOutbox) Add(path string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !slices.Contains(o.paths, path) {
+		o.paths = append(o.paths, path)
+	}
+}
+
+// Paths returns the queued files in the order they were added.
+func (o *FileOutbox) Paths() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return slices.Clone(o.paths)
+}
+
+// WithFileOutbox returns a context whose prompt can send files through outbox.
+func WithFileOutbox(ctx context.Context, outbox *FileOutbox) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if outbox == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, fileOutboxKey{}, outbox)
+}
+
+// FileOutboxFromContext returns the context-carried file outbox.
+func FileOutboxFromContext(ctx context.Context) (*FileOutbox, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+
+	outbox, ok := ctx.Value(fileOutboxKey{}).(*FileOutbox)
+	return outbox, ok && outbox != nil
+}
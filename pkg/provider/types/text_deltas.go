@@ -0,0 +1,49 @@
+package types
+
+import "context"
+
+type textDeltaHandlerKey struct{}
+
+// TextDeltaHandler receives assistant reply text as the provider streams it.
+// Deltas concatenate to the text of each step; the final PromptResult.Text
+// remains the authoritative reply.
+type TextDeltaHandler func(delta string)
+
+// WithTextDeltaHandler returns a context carrying a text delta handler.
+// Providers that can stream switch to streaming when one is attached; the
+// others never call it.
+func WithTextDeltaHandler(ctx context.Context, handler TextDeltaHandler) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if handler == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, textDeltaHandlerKey{}, handler)
+}
+
+// TextDeltaHandlerFromContext returns a context-carried text delta handler.
+func TextDeltaHandlerFromContext(ctx context.Context) (TextDeltaHandler, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+
+	handler, ok := ctx.Value(textDeltaHandlerKey{}).(TextDeltaHandler)
+	if !ok || handler == nil {
+		return nil, false
+	}
+
+	return handler, true
+}
+
+// WithoutTextDeltas returns ctx with any text delta handler hidden, so a
+// nested generation, such as a subagent's, does not stream into its caller's
+// reply.
+func WithoutTextDeltas(ctx context.Context) context.Context {
+	if _, ok := TextDeltaHandlerFromContext(ctx); !ok {
+		return ctx
+	}
+
+	return context.WithValue(ctx, textDeltaHandlerKey{}, TextDeltaHandler(nil))
+}
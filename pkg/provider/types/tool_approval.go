@@ -0,0 +1,44 @@
+package types
+
+import "context"
+
+type toolApproverKey struct{}
+
+// ToolApprovalRequest describes a tool call waiting for human confirmation.
+type ToolApprovalRequest struct {
+	Tool string
+	// Input is the raw JSON arguments the model passed to the tool.
+	Input string
+}
+
+// ToolApprover asks a human whether a tool call may run.
+//
+// It blocks until the user answers or ctx ends; a non-nil error means no
+// answer was obtained and the call is treated as denied.
+type ToolApprover func(ctx context.Context, request ToolApprovalRequest) (bool, error)
+
+// WithToolApprover returns a context carrying a tool approver.
+func WithToolApprover(ctx context.Context, approver ToolApprover) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if approver == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, toolApproverKey{}, approver)
+}
+
+// ToolApproverFromContext returns a context-carried tool approver.
+func ToolApproverFromContext(ctx context.Context) (ToolApprover, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+
+	approver, ok := ctx.Value(toolApproverKey{}).(ToolApprover)
+	if !ok || approver == nil {
+		return nil, false
+	}
+
+	return approver, true
+}
@@ -0,0 +1,52 @@
+package types
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+type toolTimerKey struct{}
+
+// ToolTimer accumulates tool execution time for one prompt.
+//
+// Tools may run concurrently, so the total can exceed the prompt wall-clock time.
+type ToolTimer struct {
+	nanos atomic.Int64
+}
+
+// Add records one tool run.
+func (t *ToolTimer) Add(elapsed time.Duration) {
+	if t == nil {
+		return
+	}
+	t.nanos.Add(int64(elapsed))
+}
+
+// Total returns the accumulated tool time.
+func (t *ToolTimer) Total() time.Duration {
+	if t == nil {
+		return 0
+	}
+	return time.Duration(t.nanos.Load())
+}
+
+// WithToolTimer returns a context carrying a fresh ToolTimer.
+func WithToolTimer(ctx context.Context) (context.Context, *ToolTimer) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	timer := &ToolTimer{}
+	return context.WithValue(ctx, toolTimerKey{}, timer), timer
+}
+
+// AddToolTime records elapsed on the context-carried ToolTimer, when present.
+func AddToolTime(ctx context.Context, elapsed time.Duration) {
+	if ctx == nil {
+		return
+	}
+
+	timer, _ := ctx.Value(toolTimerKey{}).(*ToolTimer)
+	timer.Add(elapsed)
+}
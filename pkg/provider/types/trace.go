@@ -0,0 +1,52 @@
+package types
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+)
+
+type traceIDKey struct{}
+
+// NewTraceID returns a random 16-character hex ID for one request.
+func NewTraceID() string {
+	var id [8]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// WithTraceID returns a context carrying the trace ID of the request it
+// serves, so providers and tools can log it.
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	traceID = strings.TrimSpace(traceID)
+	if traceID == "" {
+		return ctx
+	}
+
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+// TraceIDFromContext returns the context-carried trace ID, or "".
+func TraceIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	traceID, _ := ctx.Value(traceIDKey{}).(string)
+	return traceID
+}
+
+// TraceLogger returns log with a trace_id attribute when ctx carries a
+// trace ID, and log unchanged otherwise.
+func TraceLogger(ctx context.Context, log *slog.Logger) *slog.Logger {
+	if traceID := TraceIDFromContext(ctx); traceID != "" {
+		return log.With("trace_id", traceID)
+	}
+
+	return log
+}
@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 // PromptResult is the normalized provider response payload.
 type PromptResult struct {
 	Text     string
@@ -13,16 +15,34 @@ type PromptMetadata struct {
 	Agent      string
 	Usage      *TokenUsage
 	ToolEvents []ToolEvent
+	// Timing breaks down where the turn spent its wall-clock time; nil when not measured.
+	Timing *TurnTiming
+	// ToolUsage counts this turn's tool calls; nil when no tool was called.
+	ToolUsage *ToolUsage
+	// Context is how full the model's context window is after the turn; nil
+	// when the provider or model does not report it.
+	Context *ContextUsage
 }
 
 // ToolEvent captures one tool call/result event emitted during a prompt.
 type ToolEvent struct {
-	Kind       string
-	Tool       string
-	Payload    string
+	Kind    string
+	Tool    string
+	Payload string
+	// Failed marks a "result" event whose tool call returned an error.
+	Failed     bool
 	DurationMs int64
 }
 
+// EnsureTiming returns Timing, allocating it first when unset.
+func (m *PromptMetadata) EnsureTiming() *TurnTiming {
+	if m.Timing == nil {
+		m.Timing = &TurnTiming{}
+	}
+
+	return m.Timing
+}
+
 // TokenUsage captures token accounting across providers.
 type TokenUsage struct {
 	InputTokens         int64
@@ -42,3 +62,58 @@ func (u TokenUsage) IsZero() bool {
 		u.CacheCreationTokens == 0 &&
 		u.CacheReadTokens == 0
 }
+
+// ToolUsage counts tool calls and the data they moved.
+type ToolUsage struct {
+	Calls int64
+	// QuotaDenied counts calls rejected because a session quota was exhausted.
+	QuotaDenied int64
+	// BytesRead is the size of tool results returned to the model.
+	BytesRead int64
+	// BytesWritten is the size of content written by file-writing tools.
+	BytesWritten int64
+	Duration     time.Duration
+}
+
+// Add accumulates other into u.
+func (u *ToolUsage) Add(other ToolUsage) {
+	u.Calls += other.Calls
+	u.QuotaDenied += other.QuotaDenied
+	u.BytesRead += other.BytesRead
+	u.BytesWritten += other.BytesWritten
+	u.Duration += other.Duration
+}
+
+// Sub returns the usage accumulated since earlier.
+func (u ToolUsage) Sub(earlier ToolUsage) ToolUsage {
+	return ToolUsage{
+		Calls:        u.Calls - earlier.Calls,
+		QuotaDenied:  u.QuotaDenied - earlier.QuotaDenied,
+		BytesRead:    u.BytesRead - earlier.BytesRead,
+		BytesWritten: u.BytesWritten - earlier.BytesWritten,
+		Duration:     u.Duration - earlier.Duration,
+	}
+}
+
+// IsZero reports whether no tool call was recorded.
+func (u ToolUsage) IsZero() bool {
+	return u.Calls == 0 && u.QuotaDenied == 0
+}
+
+// TurnTiming splits one turn's wall-clock time into its stages.
+//
+// Stages are filled in by the layer that observes them: the agent measures
+// provider and tool time, the runtime adds queue wait and total, and the chat
+// UI adds render time. Unmeasured stages stay zero.
+type TurnTiming struct {
+	// QueueWait is time spent waiting behind other prompts before the provider was called.
+	QueueWait time.Duration
+	// Provider is provider call time excluding tool execution.
+	Provider time.Duration
+	// Tools is the summed execution time of tool calls reported in ToolEvents.
+	Tools time.Duration
+	// Render is time spent rendering the reply for the user.
+	Render time.Duration
+	// Total is wall-clock time from submitting the prompt to receiving the reply.
+	Total time.Duration
+}
@@ -0,0 +1,285 @@
+// Package report summarizes what the agent did over a time window from the
+// persisted session store: sessions, prompts, tools used, files changed, and
+// vendor cost when it can be looked up.
+package report
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"miniclaw/pkg/config"
+	"miniclaw/pkg/provider/openai"
+	"miniclaw/pkg/store"
+	fstools "miniclaw/pkg/tools/fs"
+	"miniclaw/pkg/transcript"
+
+	core "charm.land/fantasy"
+)
+
+// DefaultWindow is the period a report covers when none is given.
+const DefaultWindow = 24 * time.Hour
+
+// maxListedFiles caps the changed files named in the text report.
+const maxListedFiles = 20
+
+// gatewayTranscriptPrefix marks the plain-text transcripts the gateway keeps per channel session.
+const gatewayTranscriptPrefix = "gateway:"
+
+// CostFetcher returns vendor usage for the UTC day containing day.
+type CostFetcher func(ctx context.Context, day time.Time) (openai.DailyUsage, error)
+
+// ToolCount is how many times one tool was called.
+type ToolCount struct {
+	Name  string
+	Calls int
+}
+
+// Cost is vendor-reported spend over whole UTC days.
+type Cost struct {
+	FirstDay time.Time
+	LastDay  time.Time
+	USD      float64
+	Requests int64
+}
+
+// Report is agent activity between Since and Until.
+type Report struct {
+	Since time.Time
+	Until time.Time
+	// Sessions counts stored sessions with at least one turn in the window.
+	Sessions int
+	// Prompts counts user turns in the window.
+	Prompts int
+	// Tools lists tool calls by name, most used first.
+	Tools []ToolCount
+	// FilesChanged lists workspace paths written by file tools, sorted.
+	FilesChanged []string
+	// Cost is nil when no vendor cost was fetched; CostNote then says why.
+	Cost     *Cost
+	CostNote string
+}
+
+// Generate builds the report for [since, until) and, when the default
+// provider is openai, adds the OpenAI cost of the days it spans.
+func Generate(ctx context.Context, cfg *config.Config, sessions store.SessionStore, since time.Time, until time.Time) (Report, error) {
+	report, err := Build(ctx, sessions, since, until)
+	if err != nil {
+		return Report{}, err
+	}
+
+	provider := strings.ToLower(strings.TrimSpace(cfg.Agents.Defaults.Provider))
+	if provider != "openai" {
+		report.CostNote = fmt.Sprintf("not tracked for provider %q", provider)
+		return report, nil
+	}
+	report.AddCost(ctx, func(ctx context.Context, day time.Time) (openai.DailyUsage, error) {
+		client, err := openai.NewUsageClient(cfg.Providers.OpenAI)
+		if err != nil {
+			return openai.DailyUsage{}, err
+		}
+		return client.Daily(ctx, day)
+	})
+
+	return report, nil
+}
+
+// Build counts activity in sessions between since and until.
+//
+// fantasy-agent stores full provider sessions next to the gateway's plain
+// transcripts of the same conversations. When any provider session was active
+// in the window, gateway transcripts are skipped so prompts are not counted twice.
+func Build(ctx context.Context, sessions store.SessionStore, since time.Time, until time.Time) (Report, error) {
+	if sessions == nil {
+		return Report{}, errors.New("session store is required")
+	}
+	if !until.After(since) {
+		return Report{}, errors.New("report window must end after it starts")
+	}
+
+	summaries, err := sessions.List(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("list sessions: %w", err)
+	}
+
+	var active []store.Session
+	providerSessions := false
+	for _, summary := range summaries {
+		if summary.UpdatedAt.Before(since) {
+			continue
+		}
+		session, err := sessions.Load(ctx, summary.ID)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return Report{}, fmt.Errorf("load session %s: %w", summary.ID, err)
+		}
+
+		session.Turns = slices.DeleteFunc(session.Turns, func(turn store.Turn) bool {
+			return turn.At.Before(since) || !turn.At.Before(until)
+		})
+		if len(session.Turns) == 0 {
+			continue
+		}
+		if slices.ContainsFunc(session.Turns, func(turn store.Turn) bool { return len(turn.Payload) > 0 && turn.Role != transcript.RollbackRole }) {
+			providerSessions = true
+		}
+		active = append(active, session)
+	}
+
+	report := Report{Since: since, Until: until}
+	tools := make(map[string]int)
+	files := make(map[string]bool)
+	for _, session := range active {
+		if providerSessions && strings.HasPrefix(session.ID, gatewayTranscriptPrefix) {
+			continue
+		}
+		report.Sessions++
+		for _, turn := range session.Turns {
+			if turn.Role == "user" && strings.TrimSpace(turn.Content) != "" {
+				report.Prompts++
+			}
+			for _, call := range toolCalls(turn) {
+				tools[call.ToolName]++
+				for _, path := range changedPaths(call) {
+					files[path] = true
+				}
+			}
+		}
+	}
+
+	for name, calls := range tools {
+		report.Tools = append(report.Tools, ToolCount{Name: name, Calls: calls})
+	}
+	slices.SortFunc(report.Tools, func(a, b ToolCount) int {
+		return cmp.Or(cmp.Compare(b.Calls, a.Calls), cmp.Compare(a.Name, b.Name))
+	})
+	for path := range files {
+		report.FilesChanged = append(report.FilesChanged, path)
+	}
+	slices.Sort(report.FilesChanged)
+
+	return report, nil
+}
+
+// AddCost sums vendor cost over every UTC day the window touches. Days are
+// whole, so the cost can include activity just outside the window.
+func (r *Report) AddCost(ctx context.Context, fetch CostFetcher) {
+	first := startOfDay(r.Since)
+	last := startOfDay(r.Until.Add(-time.Nanosecond))
+
+	cost := Cost{FirstDay: first, LastDay: last}
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		usage, err := fetch(ctx, day)
+		if err != nil {
+			r.CostNote = "unavailable: " + err.Error()
+			return
+		}
+		cost.USD += usage.CostUSD
+		cost.Requests += usage.Requests
+	}
+
+	r.Cost = &cost
+}
+
+// String renders the report as plain text suitable for a terminal or chat message.
+func (r Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Agent activity %s to %s UTC\n", r.Since.UTC().Format("2006-01-02 15:04"), r.Until.UTC().Format("2006-01-02 15:04"))
+	fmt.Fprintf(&b, "Sessions: %d\n", r.Sessions)
+	fmt.Fprintf(&b, "Prompts: %d\n", r.Prompts)
+
+	total := 0
+	parts := make([]string, 0, len(r.Tools))
+	for _, tool := range r.Tools {
+		total += tool.Calls
+		parts = append(parts, fmt.Sprintf("%s %d", tool.Name, tool.Calls))
+	}
+	if total == 0 {
+		b.WriteString("Tools: none\n")
+	} else {
+		fmt.Fprintf(&b, "Tools: %d calls (%s)\n", total, strings.Join(parts, ", "))
+	}
+
+	switch {
+	case len(r.FilesChanged) == 0:
+		b.WriteString("Files changed: none\n")
+	case len(r.FilesChanged) > maxListedFiles:
+		fmt.Fprintf(&b, "Files changed: %d (%s, and %d more)\n", len(r.FilesChanged), strings.Join(r.FilesChanged[:maxListedFiles], ", "), len(r.FilesChanged)-maxListedFiles)
+	default:
+		fmt.Fprintf(&b, "Files changed: %d (%s)\n", len(r.FilesChanged), strings.Join(r.FilesChanged, ", "))
+	}
+
+	switch {
+	case r.Cost == nil && r.CostNote != "":
+		fmt.Fprintf(&b, "Cost: %s\n", r.CostNote)
+	case r.Cost == nil:
+		b.WriteString("Cost: not tracked\n")
+	case r.Cost.FirstDay.Equal(r.Cost.LastDay):
+		fmt.Fprintf(&b, "Cost: $%.2f over %d requests on %s UTC (OpenAI)\n", r.Cost.USD, r.Cost.Requests, r.Cost.FirstDay.Format("2006-01-02"))
+	default:
+		fmt.Fprintf(&b, "Cost: $%.2f over %d requests on %s to %s UTC (OpenAI)\n", r.Cost.USD, r.Cost.Requests, r.Cost.FirstDay.Format("2006-01-02"), r.Cost.LastDay.Format("2006-01-02"))
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// toolCalls decodes the tool calls of a persisted provider message; other turns have none.
+func toolCalls(turn store.Turn) []core.ToolCallPart {
+	if len(turn.Payload) == 0 || turn.Role != string(core.MessageRoleAssistant) {
+		return nil
+	}
+
+	var message core.Message
+	if err := json.Unmarshal(turn.Payload, &message); err != nil {
+		return nil
+	}
+
+	var calls []core.ToolCallPart
+	for _, part := range message.Content {
+		if call, ok := part.(core.ToolCallPart); ok && call.ToolName != "" {
+			calls = append(calls, call)
+		}
+	}
+
+	return calls
+}
+
+// changedPaths returns the workspace paths a file tool call writes.
+func changedPaths(call core.ToolCallPart) []string {
+	var input struct {
+		Path        string `json:"path"`
+		Destination string `json:"destination"`
+		Patch       string `json:"patch"`
+	}
+	if err := json.Unmarshal([]byte(call.Input), &input); err != nil {
+		return nil
+	}
+
+	var paths []string
+	switch call.ToolName {
+	case "write_file", "append_file", "edit_file":
+		paths = []string{input.Path}
+	case "copy_file":
+		paths = []string{input.Destination}
+	case "create_archive", "restore_file":
+		paths = []string{input.Path}
+	case "extract_archive":
+		paths = []string{cmp.Or(strings.TrimSpace(input.Destination), ".")}
+	case "apply_patch":
+		paths, _ = fstools.PatchPaths(input.Patch)
+	}
+
+	return slices.DeleteFunc(paths, func(path string) bool { return strings.TrimSpace(path) == "" })
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
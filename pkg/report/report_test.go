@@ -0,0 +1,142 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"miniclaw/pkg/provider/openai"
+	"miniclaw/pkg/store"
+
+	core "charm.land/fantasy"
+)
+
+func providerTurn(t *testing.T, message core.Message, at time.Time) store.Turn {
+	t.Helper()
+
+	payload, err := json.Marshal(message)
+	if err != nil {
+		t.Fatalf("marshal message: %v", err)
+	}
+	return store.Turn{Role: string(message.Role), At: at, Payload: payload}
+}
+
+func toolCall(name string, input string) core.MessagePart {
+	return core.ToolCallPart{ToolCallID: name + "-1", ToolName: name, Input: input}
+}
+
+func TestBuildCountsProviderSessions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	until := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	since := until.Add(-DefaultWindow)
+	sessions := store.NewMemoryStore()
+
+	if _, err := sessions.Create(ctx, "fantasy-session-1", "chat"); err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	patch := "--- a/docs/old.md\n+++ /dev/null\n@@ -1 +0,0 @@\n-old\n"
+	if err := sessions.AppendTurn(ctx, "fantasy-session-1",
+		store.Turn{Role: "user", Content: "too old", At: since.Add(-time.Hour)},
+		store.Turn{Role: "user", Content: "fix the build", At: since.Add(time.Hour)},
+		providerTurn(t, core.Message{Role: core.MessageRoleAssistant, Content: []core.MessagePart{
+			toolCall("read_file", `{"path":"main.go"}`),
+			toolCall("edit_file", `{"path":"main.go","old_text":"a","new_text":"b"}`),
+		}}, since.Add(time.Hour)),
+		providerTurn(t, core.Message{Role: core.MessageRoleAssistant, Content: []core.MessagePart{
+			toolCall("read_file", `{"path":"go.mod"}`),
+			toolCall("apply_patch", `{"patch":`+strconv.Quote(patch)+`}`),
+			toolCall("copy_file", `{"source":"a.txt","destination":"b.txt"}`),
+		}}, since.Add(2*time.Hour)),
+	); err != nil {
+		t.Fatalf("AppendTurn error: %v", err)
+	}
+	// The gateway transcript mirrors the provider session and must not be counted again.
+	if _, err := sessions.Create(ctx, "gateway:telegram:1", ""); err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	if err := sessions.AppendTurn(ctx, "gateway:telegram:1", store.Turn{Role: "user", Content: "fix the build", At: since.Add(time.Hour)}); err != nil {
+		t.Fatalf("AppendTurn error: %v", err)
+	}
+
+	report, err := Build(ctx, sessions, since, until)
+	if err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+	if report.Sessions != 1 || report.Prompts != 1 {
+		t.Fatalf("report = %+v, want 1 session and 1 prompt", report)
+	}
+	if len(report.Tools) != 4 || report.Tools[0] != (ToolCount{Name: "read_file", Calls: 2}) {
+		t.Fatalf("tools = %+v, want read_file first with 2 calls", report.Tools)
+	}
+	if got := strings.Join(report.FilesChanged, ","); got != "b.txt,docs/old.md,main.go" {
+		t.Fatalf("files changed = %q", got)
+	}
+
+	text := report.String()
+	for _, want := range []string{"Prompts: 1", "Tools: 5 calls (read_file 2, apply_patch 1, copy_file 1, edit_file 1)", "Files changed: 3 (b.txt, docs/old.md, main.go)", "Cost: not tracked"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("report text missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestBuildCountsGatewayTranscriptsWithoutProviderSessions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	until := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	sessions := store.NewMemoryStore()
+	for _, id := range []string{"gateway:telegram:1", "gateway:telegram:2"} {
+		if _, err := sessions.Create(ctx, id, ""); err != nil {
+			t.Fatalf("Create error: %v", err)
+		}
+		if err := sessions.AppendTurn(ctx, id,
+			store.Turn{Role: "user", Content: "hello", At: until.Add(-time.Hour)},
+			store.Turn{Role: "assistant", Content: "hi", At: until.Add(-time.Hour)},
+		); err != nil {
+			t.Fatalf("AppendTurn error: %v", err)
+		}
+	}
+
+	report, err := Build(ctx, sessions, until.Add(-DefaultWindow), until)
+	if err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+	if report.Sessions != 2 || report.Prompts != 2 || len(report.Tools) != 0 {
+		t.Fatalf("report = %+v, want 2 sessions and 2 prompts", report)
+	}
+}
+
+func TestAddCostSumsWholeDays(t *testing.T) {
+	t.Parallel()
+
+	report := Report{
+		Since: time.Date(2026, 10, 15, 8, 0, 0, 0, time.UTC),
+		Until: time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC),
+	}
+	var days []string
+	report.AddCost(context.Background(), func(_ context.Context, day time.Time) (openai.DailyUsage, error) {
+		days = append(days, day.Format("2006-01-02"))
+		return openai.DailyUsage{CostUSD: 0.25, Requests: 4}, nil
+	})
+	if strings.Join(days, ",") != "2026-10-15,2026-10-16" {
+		t.Fatalf("fetched days = %v", days)
+	}
+	if !strings.Contains(report.String(), "Cost: $0.50 over 8 requests on 2026-10-15 to 2026-10-16 UTC (OpenAI)") {
+		t.Fatalf("report text = %q", report.String())
+	}
+
+	report.Cost = nil
+	report.AddCost(context.Background(), func(context.Context, time.Time) (openai.DailyUsage, error) {
+		return openai.DailyUsage{}, errors.New("OPENAI_ADMIN_KEY must be set")
+	})
+	if report.Cost != nil || !strings.Contains(report.String(), "Cost: unavailable: OPENAI_ADMIN_KEY must be set") {
+		t.Fatalf("report text = %q, want unavailable cost", report.String())
+	}
+}
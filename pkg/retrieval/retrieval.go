@@ -0,0 +1,518 @@
+// Package retrieval indexes workspace files as embedding vectors and adds the
+// snippets most relevant to a prompt to that prompt.
+package retrieval
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"miniclaw/pkg/config"
+	"miniclaw/pkg/provider/openai"
+	fstools "miniclaw/pkg/tools/fs"
+	"miniclaw/pkg/workspace"
+)
+
+const (
+	// IndexFile is where the index is stored, relative to the workspace root.
+	IndexFile = ".miniclaw/index.json"
+
+	defaultTopK         = 4
+	defaultMinScore     = 0.3
+	defaultChunkLines   = 40
+	defaultMaxFileBytes = 256 << 10
+	// maxChunkBytes cuts snippets of very long lines so each fits one embedding input.
+	maxChunkBytes = 6000
+	// maxIndexedFiles bounds the files one index covers.
+	maxIndexedFiles = 5000
+	// embedBatchSize is the most snippets sent in one embeddings request.
+	embedBatchSize = 64
+)
+
+// skippedDirs are never indexed: version control and miniclaw's own state.
+var skippedDirs = []string{".git", ".miniclaw"}
+
+// Embedder turns text into vectors; openai.Embedder implements it.
+type Embedder interface {
+	Model() string
+	Embed(ctx context.Context, texts []string) ([][]float64, error)
+}
+
+// Chunk is one indexed snippet: a run of lines from a workspace file.
+type Chunk struct {
+	Path      string    `json:"path"`
+	StartLine int       `json:"start_line"`
+	EndLine   int       `json:"end_line"`
+	Text      string    `json:"text"`
+	Vector    []float64 `json:"vector"`
+}
+
+// Result is a chunk matched by Search with its cosine similarity to the query.
+type Result struct {
+	Chunk
+	Score float64
+}
+
+// Stats describes the index after a Sync.
+type Stats struct {
+	Files   int
+	Chunks  int
+	Updated int
+	Removed int
+}
+
+// fileEntry is the indexed state of one file. Size and ModTime decide
+// whether it changed; binary and empty files have no chunks.
+type fileEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Chunks  []Chunk   `json:"chunks,omitempty"`
+}
+
+// indexData is the IndexFile format. An index built with another model or
+// chunk size is discarded.
+type indexData struct {
+	Model      string               `json:"model"`
+	ChunkLines int                  `json:"chunk_lines"`
+	Files      map[string]fileEntry `json:"files"`
+}
+
+// Index is the embedding index of one workspace.
+type Index struct {
+	root         string
+	embedder     Embedder
+	topK         int
+	minScore     float64
+	chunkLines   int
+	maxFileBytes int64
+	log          *slog.Logger
+
+	mu     sync.Mutex
+	files  map[string]fileEntry
+	loaded bool
+}
+
+// Open builds the index for agents.defaults.retrieval, or returns nil when
+// retrieval is disabled. Nothing is embedded until the first Sync.
+func Open(cfg *config.Config) (*Index, error) {
+	retrieval := cfg.Agents.Defaults.Retrieval
+	if !retrieval.Enabled {
+		return nil, nil
+	}
+
+	root, err := workspace.ResolveRoot(cfg.Agents.Defaults.Workspace)
+	if err != nil {
+		return nil, fmt.Errorf("resolve workspace: %w", err)
+	}
+	embedder, err := openai.NewEmbedder(cfg.Providers.OpenAI, retrieval.EmbeddingModel)
+	if err != nil {
+		return nil, fmt.Errorf("agents.defaults.retrieval: %w", err)
+	}
+
+	return New(retrieval, root, embedder)
+}
+
+// New builds an index of root that embeds through embedder. Unset settings
+// use their defaults.
+func New(cfg config.RetrievalConfig, root string, embedder Embedder) (*Index, error) {
+	if cfg.TopK < 0 || cfg.ChunkLines < 0 || cfg.MaxFileBytes < 0 {
+		return nil, errors.New("agents.defaults.retrieval: top_k, chunk_lines, and max_file_bytes must not be negative")
+	}
+	if cfg.MinScore < 0 || cfg.MinScore > 1 {
+		return nil, fmt.Errorf("agents.defaults.retrieval.min_score must be between 0 and 1, got %g", cfg.MinScore)
+	}
+
+	index := &Index{
+		root:         root,
+		embedder:     embedder,
+		topK:         cmp.Or(cfg.TopK, defaultTopK),
+		minScore:     cmp.Or(cfg.MinScore, defaultMinScore),
+		chunkLines:   cmp.Or(cfg.ChunkLines, defaultChunkLines),
+		maxFileBytes: int64(cmp.Or(cfg.MaxFileBytes, defaultMaxFileBytes)),
+		log:          slog.Default().With("component", "retrieval"),
+	}
+
+	return index, nil
+}
+
+// Sync brings the index up to date with the workspace: new and changed files
+// are embedded, and removed ones dropped. Unchanged files cost one stat each,
+// so Sync is cheap enough to run before every prompt. Files matched by the
+// workspace .miniclawignore, binary files, and files over max_file_bytes are
+// skipped. Progress is saved even when embedding fails part way.
+func (x *Index) Sync(ctx context.Context) (Stats, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if !x.loaded {
+		x.files = x.load()
+		x.loaded = true
+	}
+
+	seen, err := x.scan()
+	if err != nil {
+		return x.stats(), err
+	}
+
+	var stats Stats
+	for path := range x.files {
+		if _, ok := seen[path]; !ok {
+			delete(x.files, path)
+			stats.Removed++
+		}
+	}
+
+	var pending []string
+	var pendingChunks int
+	var syncErr error
+	for _, path := range slices.Sorted(maps.Keys(seen)) {
+		info := seen[path]
+		if entry, ok := x.files[path]; ok && entry.Size == info.Size && entry.ModTime.Equal(info.ModTime) {
+			continue
+		}
+		entry, err := x.chunkFile(path, info)
+		if err != nil {
+			x.log.Warn("Skipping unreadable file", "path", path, "error", err)
+			continue
+		}
+		x.files[path] = entry
+		stats.Updated++
+		if len(entry.Chunks) == 0 {
+			continue
+		}
+		// Chunks without vectors are embedded in batches across files.
+		pending = append(pending, path)
+		pendingChunks += len(entry.Chunks)
+		if pendingChunks >= embedBatchSize {
+			if syncErr = x.embed(ctx, pending); syncErr != nil {
+				break
+			}
+			pending, pendingChunks = pending[:0], 0
+		}
+	}
+	if syncErr == nil {
+		syncErr = x.embed(ctx, pending)
+	}
+	if syncErr != nil {
+		// Files whose chunks never got vectors are retried next time.
+		for path, entry := range x.files {
+			if len(entry.Chunks) > 0 && entry.Chunks[0].Vector == nil {
+				delete(x.files, path)
+				stats.Updated--
+			}
+		}
+	}
+
+	if stats.Updated > 0 || stats.Removed > 0 {
+		if err := x.save(); err != nil {
+			syncErr = errors.Join(syncErr, err)
+		}
+	}
+	current := x.stats()
+	current.Updated, current.Removed = stats.Updated, stats.Removed
+	if syncErr != nil {
+		return current, fmt.Errorf("sync workspace index: %w", syncErr)
+	}
+	if stats.Updated > 0 || stats.Removed > 0 {
+		x.log.Debug("Workspace index synced", "files", current.Files, "chunks", current.Chunks, "updated", stats.Updated, "removed", stats.Removed)
+	}
+
+	return current, nil
+}
+
+// fileInfo is the part of a file's metadata that marks a change.
+type fileInfo struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// scan lists the indexable files under root, keyed by slash-separated relative path.
+func (x *Index) scan() (map[string]fileInfo, error) {
+	var ignore *fstools.Ignore
+	if content, err := os.ReadFile(filepath.Join(x.root, fstools.IgnoreFile)); err == nil {
+		ignore = fstools.ParseIgnore(string(content))
+	}
+
+	files := make(map[string]fileInfo)
+	err := filepath.WalkDir(x.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == x.root {
+				return err
+			}
+			return nil
+		}
+		if path == x.root {
+			return nil
+		}
+		rel, err := filepath.Rel(x.root, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if entry.IsDir() {
+			if slices.Contains(skippedDirs, entry.Name()) || ignore.Match(rel, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() || ignore.Match(rel, false) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil || info.Size() > x.maxFileBytes {
+			return nil
+		}
+		if len(files) >= maxIndexedFiles {
+			x.log.Warn("Workspace index file limit reached; remaining files are not indexed", "max_files", maxIndexedFiles)
+			return fs.SkipAll
+		}
+		files[rel] = fileInfo{Size: info.Size(), ModTime: info.ModTime()}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan workspace: %w", err)
+	}
+
+	return files, nil
+}
+
+// chunkFile splits a text file into runs of chunkLines lines. Binary files
+// get an entry without chunks so they are not read again until they change.
+func (x *Index) chunkFile(path string, info fileInfo) (fileEntry, error) {
+	content, err := os.ReadFile(filepath.Join(x.root, filepath.FromSlash(path)))
+	if err != nil {
+		return fileEntry{}, err
+	}
+	entry := fileEntry{Size: info.Size, ModTime: info.ModTime}
+	if bytes.IndexByte(content, 0) >= 0 || !utf8.Valid(content) {
+		return entry, nil
+	}
+
+	lines := strings.Split(string(content), "\n")
+	for start := 0; start < len(lines); start += x.chunkLines {
+		end := min(start+x.chunkLines, len(lines))
+		text := strings.TrimSpace(strings.Join(lines[start:end], "\n"))
+		if text == "" {
+			continue
+		}
+		if len(text) > maxChunkBytes {
+			text = strings.ToValidUTF8(text[:maxChunkBytes], "")
+		}
+		entry.Chunks = append(entry.Chunks, Chunk{Path: path, StartLine: start + 1, EndLine: end, Text: text})
+	}
+
+	return entry, nil
+}
+
+// embed fills in the vectors of the chunks of paths.
+func (x *Index) embed(ctx context.Context, paths []string) error {
+	type ref struct {
+		path  string
+		chunk int
+	}
+	var refs []ref
+	var texts []string
+	for _, path := range paths {
+		for i, chunk := range x.files[path].Chunks {
+			refs = append(refs, ref{path: path, chunk: i})
+			// The path gives the model context a bare snippet lacks.
+			texts = append(texts, chunk.Path+"\n\n"+chunk.Text)
+		}
+	}
+
+	for start := 0; start < len(texts); start += embedBatchSize {
+		end := min(start+embedBatchSize, len(texts))
+		vectors, err := x.embedder.Embed(ctx, texts[start:end])
+		if err != nil {
+			return err
+		}
+		if len(vectors) != end-start {
+			return fmt.Errorf("embedder returned %d vectors for %d inputs", len(vectors), end-start)
+		}
+		for i, vector := range vectors {
+			r := refs[start+i]
+			x.files[r.path].Chunks[r.chunk].Vector = vector
+		}
+	}
+
+	return nil
+}
+
+// Search returns up to top_k chunks whose similarity to query is at least
+// min_score, best first. It searches the index as last synced.
+func (x *Index) Search(ctx context.Context, query string) ([]Result, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if len(x.files) == 0 {
+		return nil, nil
+	}
+	vectors, err := x.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("embed query: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("embedder returned %d vectors for 1 input", len(vectors))
+	}
+
+	var results []Result
+	for _, entry := range x.files {
+		for _, chunk := range entry.Chunks {
+			if score := cosine(vectors[0], chunk.Vector); score >= x.minScore {
+				results = append(results, Result{Chunk: chunk, Score: score})
+			}
+		}
+	}
+	slices.SortFunc(results, func(a, b Result) int {
+		if a.Score != b.Score {
+			if a.Score > b.Score {
+				return -1
+			}
+			return 1
+		}
+		if a.Path != b.Path {
+			return strings.Compare(a.Path, b.Path)
+		}
+		return a.StartLine - b.StartLine
+	})
+	if len(results) > x.topK {
+		results = results[:x.topK]
+	}
+
+	return results, nil
+}
+
+// Augment syncs the index and appends the snippets matching prompt to it. It
+// fits agentruntime.PrePrompt. Index and embedding failures are logged and
+// the prompt is sent as is, so retrieval never blocks a prompt.
+func (x *Index) Augment(ctx context.Context, prompt string) (string, error) {
+	if strings.TrimSpace(prompt) == "" {
+		return prompt, nil
+	}
+	if _, err := x.Sync(ctx); err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		x.log.Warn("Workspace index sync failed", "error", err)
+	}
+	results, err := x.Search(ctx, prompt)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		x.log.Warn("Workspace retrieval failed", "error", err)
+		return prompt, nil
+	}
+	if len(results) == 0 {
+		return prompt, nil
+	}
+
+	return prompt + "\n\n" + FormatResults(results), nil
+}
+
+// FormatResults renders results as the block Augment appends to a prompt.
+func FormatResults(results []Result) string {
+	var b strings.Builder
+	b.WriteString("Workspace snippets that may be relevant (retrieved automatically; read the files for full context):")
+	for _, result := range results {
+		fmt.Fprintf(&b, "\n\n--- %s (lines %d-%d) ---\n%s", result.Path, result.StartLine, result.EndLine, result.Text)
+	}
+
+	return b.String()
+}
+
+// stats counts the indexed files and chunks. Callers hold mu.
+func (x *Index) stats() Stats {
+	stats := Stats{Files: len(x.files)}
+	for _, entry := range x.files {
+		stats.Chunks += len(entry.Chunks)
+	}
+
+	return stats
+}
+
+// load reads IndexFile, starting empty when it is missing, unreadable, or
+// was built with another model or chunk size.
+func (x *Index) load() map[string]fileEntry {
+	content, err := os.ReadFile(filepath.Join(x.root, IndexFile))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			x.log.Warn("Rebuilding unreadable workspace index", "error", err)
+		}
+		return make(map[string]fileEntry)
+	}
+
+	var data indexData
+	if err := json.Unmarshal(content, &data); err != nil {
+		x.log.Warn("Rebuilding unreadable workspace index", "error", err)
+		return make(map[string]fileEntry)
+	}
+	if data.Model != x.embedder.Model() || data.ChunkLines != x.chunkLines || data.Files == nil {
+		return make(map[string]fileEntry)
+	}
+
+	return data.Files
+}
+
+// save writes IndexFile through a temporary file so a crash never leaves it half written.
+func (x *Index) save() error {
+	content, err := json.Marshal(indexData{Model: x.embedder.Model(), ChunkLines: x.chunkLines, Files: x.files})
+	if err != nil {
+		return fmt.Errorf("encode workspace index: %w", err)
+	}
+
+	path := filepath.Join(x.root, IndexFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create index directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".index-*.json")
+	if err != nil {
+		return fmt.Errorf("save workspace index: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("save workspace index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("save workspace index: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("save workspace index: %w", err)
+	}
+
+	return nil
+}
+
+// cosine is the cosine similarity of a and b, or 0 when their lengths differ.
+func cosine(a []float64, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
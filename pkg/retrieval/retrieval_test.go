@@ -0,0 +1,164 @@
+package retrieval
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"miniclaw/pkg/config"
+)
+
+// wordEmbedder embeds text as counts of a fixed vocabulary, so texts sharing
+// words score high and texts sharing none score zero.
+type wordEmbedder struct {
+	inputs int
+	err    error
+}
+
+var vocabulary = []string{"apple", "banana", "cherry", "invoice", "deploy"}
+
+func (e *wordEmbedder) Model() string { return "test-embedding" }
+
+func (e *wordEmbedder) Embed(_ context.Context, texts []string) ([][]float64, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	e.inputs += len(texts)
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vector := make([]float64, len(vocabulary))
+		for j, word := range vocabulary {
+			vector[j] = float64(strings.Count(strings.ToLower(text), word))
+		}
+		vectors[i] = vector
+	}
+
+	return vectors, nil
+}
+
+func writeFile(t *testing.T, root string, name string, content string) {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("MkdirAll error: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+}
+
+func newTestIndex(t *testing.T, root string, embedder *wordEmbedder) *Index {
+	t.Helper()
+	index, err := New(config.RetrievalConfig{Enabled: true, TopK: 2}, root, embedder)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+
+	return index
+}
+
+func TestSyncIndexesOnlyChangedFiles(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "fruit.md", "apple banana")
+	writeFile(t, root, "ops/deploy.md", "deploy steps")
+	writeFile(t, root, "image.bin", "\x00\x01apple")
+	writeFile(t, root, "secret/keys.md", "apple")
+	writeFile(t, root, ".miniclawignore", "secret/\n")
+	embedder := &wordEmbedder{}
+	index := newTestIndex(t, root, embedder)
+
+	stats, err := index.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync error: %v", err)
+	}
+	// .miniclawignore itself is a text file and gets indexed too.
+	if stats.Files != 4 || stats.Chunks != 3 || stats.Updated != 4 {
+		t.Fatalf("first sync stats = %+v, want 4 files, 3 chunks, 4 updated", stats)
+	}
+
+	embedder.inputs = 0
+	if stats, err = index.Sync(context.Background()); err != nil || stats.Updated != 0 || embedder.inputs != 0 {
+		t.Fatalf("unchanged sync = %+v, %v with %d inputs; want nothing embedded", stats, err, embedder.inputs)
+	}
+
+	writeFile(t, root, "fruit.md", "cherry cherry and more cherry")
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(root, "fruit.md"), later, later); err != nil {
+		t.Fatalf("Chtimes error: %v", err)
+	}
+	if err := os.Remove(filepath.Join(root, "ops", "deploy.md")); err != nil {
+		t.Fatalf("Remove error: %v", err)
+	}
+	stats, err = index.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync error: %v", err)
+	}
+	if stats.Updated != 1 || stats.Removed != 1 || embedder.inputs != 1 {
+		t.Fatalf("changed sync = %+v with %d inputs, want 1 updated, 1 removed, 1 input", stats, embedder.inputs)
+	}
+}
+
+func TestSearchRanksAndPersists(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "fruit.md", "apple banana")
+	writeFile(t, root, "billing.md", "invoice invoice")
+	writeFile(t, root, "deploy.md", "deploy")
+	index := newTestIndex(t, root, &wordEmbedder{})
+	if _, err := index.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync error: %v", err)
+	}
+
+	// A new index loads the stored vectors instead of embedding again.
+	embedder := &wordEmbedder{}
+	reopened := newTestIndex(t, root, embedder)
+	if stats, err := reopened.Sync(context.Background()); err != nil || stats.Updated != 0 || stats.Chunks != 3 {
+		t.Fatalf("reopened sync = %+v, %v; want 3 stored chunks and nothing updated", stats, err)
+	}
+
+	results, err := reopened.Search(context.Background(), "where is the invoice?")
+	if err != nil {
+		t.Fatalf("Search error: %v", err)
+	}
+	if len(results) != 1 || results[0].Path != "billing.md" || results[0].StartLine != 1 {
+		t.Fatalf("results = %+v, want only billing.md", results)
+	}
+}
+
+func TestAugmentAppendsSnippetsAndToleratesFailures(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "billing.md", "Send the invoice on Fridays.")
+	embedder := &wordEmbedder{}
+	index := newTestIndex(t, root, embedder)
+
+	augmented, err := index.Augment(context.Background(), "When does the invoice go out?")
+	if err != nil {
+		t.Fatalf("Augment error: %v", err)
+	}
+	for _, want := range []string{"When does the invoice go out?", "--- billing.md (lines 1-1) ---", "Send the invoice on Fridays."} {
+		if !strings.Contains(augmented, want) {
+			t.Fatalf("augmented prompt missing %q:\n%s", want, augmented)
+		}
+	}
+
+	if unrelated, _ := index.Augment(context.Background(), "hello"); unrelated != "hello" {
+		t.Fatalf("unrelated prompt = %q, want it unchanged", unrelated)
+	}
+
+	embedder.err = errors.New("embeddings down")
+	if prompt, err := index.Augment(context.Background(), "invoice"); err != nil || prompt != "invoice" {
+		t.Fatalf("Augment with failing embedder = %q, %v; want the prompt unchanged", prompt, err)
+	}
+}
+
+func TestNewRejectsInvalidSettings(t *testing.T) {
+	if _, err := New(config.RetrievalConfig{MinScore: 1.5}, t.TempDir(), &wordEmbedder{}); err == nil {
+		t.Fatal("expected an error for min_score above 1")
+	}
+	if _, err := New(config.RetrievalConfig{TopK: -1}, t.TempDir(), &wordEmbedder{}); err == nil {
+		t.Fatal("expected an error for a negative top_k")
+	}
+}
@@ -0,0 +1,281 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	jsonlExtension   = ".jsonl"
+	jsonlKindSession = "session"
+	jsonlKindTurn    = "turn"
+	jsonlKindName    = "name"
+)
+
+// JSONLStore keeps one append-only JSON Lines file per session in a directory.
+//
+// The first line of each file is a session header; every following line is
+// one turn, or a name record whose latest value is the session's Name. Files
+// are human-readable and safe to tail while the process runs.
+type JSONLStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// jsonlRecord is the on-disk line format for headers and turns.
+type jsonlRecord struct {
+	Kind      string    `json:"kind"`
+	ID        string    `json:"id,omitempty"`
+	Title     string    `json:"title,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	Turn      *Turn     `json:"turn,omitempty"`
+}
+
+// NewJSONLStore creates a JSONL store rooted at dir, creating it when missing.
+func NewJSONLStore(dir string) (*JSONLStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create session store directory: %w", err)
+	}
+
+	return &JSONLStore{dir: dir}, nil
+}
+
+func (j *JSONLStore) Create(ctx context.Context, id string, title string) (Session, error) {
+	if err := validateID(id); err != nil {
+		return Session{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return Session{}, err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	file, err := os.OpenFile(j.sessionPath(id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return Session{}, ErrExists
+		}
+		return Session{}, fmt.Errorf("create session file: %w", err)
+	}
+	defer file.Close()
+
+	now := time.Now().UTC()
+	if err := writeJSONLRecord(file, jsonlRecord{Kind: jsonlKindSession, ID: id, Title: title, CreatedAt: now}); err != nil {
+		return Session{}, err
+	}
+
+	return Session{ID: id, Title: title, CreatedAt: now, UpdatedAt: now}, nil
+}
+
+func (j *JSONLStore) Load(ctx context.Context, id string) (Session, error) {
+	if err := ctx.Err(); err != nil {
+		return Session{}, err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.readSession(id, true)
+}
+
+func (j *JSONLStore) AppendTurn(ctx context.Context, id string, turns ...Turn) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	file, err := os.OpenFile(j.sessionPath(id), os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("open session file: %w", err)
+	}
+	defer file.Close()
+
+	// Encode every turn first so a marshal failure never leaves a partial batch.
+	var batch strings.Builder
+	for _, turn := range stampTurns(turns, time.Now().UTC()) {
+		encoded, err := json.Marshal(jsonlRecord{Kind: jsonlKindTurn, Turn: &turn})
+		if err != nil {
+			return fmt.Errorf("encode turn: %w", err)
+		}
+		batch.Write(encoded)
+		batch.WriteByte('\n')
+	}
+	if _, err := file.WriteString(batch.String()); err != nil {
+		return fmt.Errorf("append turns: %w", err)
+	}
+
+	return nil
+}
+
+func (j *JSONLStore) Rename(ctx context.Context, id string, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	file, err := os.OpenFile(j.sessionPath(id), os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("open session file: %w", err)
+	}
+	defer file.Close()
+
+	return writeJSONLRecord(file, jsonlRecord{Kind: jsonlKindName, Name: name})
+}
+
+func (j *JSONLStore) List(ctx context.Context) ([]Summary, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entries, err := os.ReadDir(j.dir)
+	if err != nil {
+		return nil, fmt.Errorf("list session files: %w", err)
+	}
+
+	summaries := make([]Summary, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, jsonlExtension) {
+			continue
+		}
+		id, err := url.PathUnescape(strings.TrimSuffix(name, jsonlExtension))
+		if err != nil {
+			continue
+		}
+
+		session, err := j.readSession(id, false)
+		if err != nil {
+			// Skip unreadable files so one corrupt session does not hide the rest.
+			continue
+		}
+		summaries = append(summaries, Summary{
+			ID:        session.ID,
+			Title:     session.Title,
+			Name:      session.Name,
+			CreatedAt: session.CreatedAt,
+			UpdatedAt: session.UpdatedAt,
+			TurnCount: len(session.Turns),
+		})
+	}
+	sortSummaries(summaries)
+	return summaries, nil
+}
+
+func (j *JSONLStore) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := os.Remove(j.sessionPath(id)); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("delete session file: %w", err)
+	}
+
+	return nil
+}
+
+func (j *JSONLStore) Close() error {
+	return nil
+}
+
+// readSession parses one session file; payloads are dropped when withPayloads is false.
+func (j *JSONLStore) readSession(id string, withPayloads bool) (Session, error) {
+	path := j.sessionPath(id)
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Session{}, ErrNotFound
+		}
+		return Session{}, fmt.Errorf("open session file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return Session{}, fmt.Errorf("stat session file: %w", err)
+	}
+
+	session := Session{ID: id, UpdatedAt: info.ModTime().UTC()}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var record jsonlRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return Session{}, fmt.Errorf("parse %s line %d: %w", filepath.Base(path), lineNumber, err)
+		}
+		switch record.Kind {
+		case jsonlKindSession:
+			session.Title = record.Title
+			session.CreatedAt = record.CreatedAt
+		case jsonlKindName:
+			session.Name = record.Name
+		case jsonlKindTurn:
+			if record.Turn == nil {
+				continue
+			}
+			turn := *record.Turn
+			if !withPayloads {
+				turn.Payload = nil
+			}
+			session.Turns = append(session.Turns, turn)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Session{}, fmt.Errorf("read session file: %w", err)
+	}
+
+	return session, nil
+}
+
+// sessionPath maps a session ID to its file, escaping characters unsafe in file names.
+func (j *JSONLStore) sessionPath(id string) string {
+	return filepath.Join(j.dir, url.PathEscape(id)+jsonlExtension)
+}
+
+func writeJSONLRecord(file *os.File, record jsonlRecord) error {
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("encode session record: %w", err)
+	}
+	if _, err := file.Write(append(encoded, '\n')); err != nil {
+		return fmt.Errorf("write session record: %w", err)
+	}
+
+	return nil
+}
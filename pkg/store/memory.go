@@ -0,0 +1,148 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps sessions in process memory only.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	sessions map[string]*Session
+}
+
+// NewMemoryStore creates an empty in-process session store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]*Session)}
+}
+
+func (m *MemoryStore) Create(ctx context.Context, id string, title string) (Session, error) {
+	if err := validateID(id); err != nil {
+		return Session{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return Session{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.sessions[id]; ok {
+		return Session{}, ErrExists
+	}
+
+	now := time.Now().UTC()
+	session := &Session{ID: id, Title: title, CreatedAt: now, UpdatedAt: now}
+	m.sessions[id] = session
+	return *session, nil
+}
+
+func (m *MemoryStore) Load(ctx context.Context, id string) (Session, error) {
+	if err := ctx.Err(); err != nil {
+		return Session{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	session, ok := m.sessions[id]
+	if !ok {
+		return Session{}, ErrNotFound
+	}
+
+	// Copy so callers cannot mutate stored history.
+	loaded := *session
+	loaded.Turns = slices.Clone(session.Turns)
+	return loaded, nil
+}
+
+func (m *MemoryStore) AppendTurn(ctx context.Context, id string, turns ...Turn) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	now := time.Now().UTC()
+	session.Turns = append(session.Turns, stampTurns(turns, now)...)
+	session.UpdatedAt = now
+	return nil
+}
+
+func (m *MemoryStore) Rename(ctx context.Context, id string, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	session.Name = name
+	return nil
+}
+
+func (m *MemoryStore) List(ctx context.Context) ([]Summary, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	summaries := make([]Summary, 0, len(m.sessions))
+	for _, session := range m.sessions {
+		summaries = append(summaries, Summary{
+			ID:        session.ID,
+			Title:     session.Title,
+			Name:      session.Name,
+			CreatedAt: session.CreatedAt,
+			UpdatedAt: session.UpdatedAt,
+			TurnCount: len(session.Turns),
+		})
+	}
+	sortSummaries(summaries)
+	return summaries, nil
+}
+
+func (m *MemoryStore) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.sessions[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.sessions, id)
+	return nil
+}
+
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+// sortSummaries orders summaries by creation time, then ID, so List output is stable.
+func sortSummaries(summaries []Summary) {
+	sort.Slice(summaries, func(i int, j int) bool {
+		if !summaries[i].CreatedAt.Equal(summaries[j].CreatedAt) {
+			return summaries[i].CreatedAt.Before(summaries[j].CreatedAt)
+		}
+		return summaries[i].ID < summaries[j].ID
+	})
+}
@@ -0,0 +1,229 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS sessions (
+	id         TEXT PRIMARY KEY,
+	title      TEXT NOT NULL DEFAULT '',
+	name       TEXT NOT NULL DEFAULT '',
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS turns (
+	session_id TEXT NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+	seq        INTEGER NOT NULL,
+	role       TEXT NOT NULL,
+	content    TEXT NOT NULL DEFAULT '',
+	at         TEXT NOT NULL,
+	payload    BLOB,
+	PRIMARY KEY (session_id, seq)
+);
+`
+
+// SQLiteStore keeps sessions in a single SQLite database file.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens (or creates) a SQLite session database at path.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("create session store directory: %w", err)
+		}
+	}
+
+	dsn := "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite session store: %w", err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("initialize sqlite session store: %w", err)
+	}
+	// Databases created before session names lack the column.
+	if _, err := db.Exec(`ALTER TABLE sessions ADD COLUMN name TEXT NOT NULL DEFAULT ''`); err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+		_ = db.Close()
+		return nil, fmt.Errorf("migrate sqlite session store: %w", err)
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Create(ctx context.Context, id string, title string) (Session, error) {
+	if err := validateID(id); err != nil {
+		return Session{}, err
+	}
+
+	now := time.Now().UTC()
+	_, err := s.db.ExecContext(ctx, `INSERT INTO sessions (id, title, created_at, updated_at) VALUES (?, ?, ?, ?)`, id, title, formatTime(now), formatTime(now))
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return Session{}, ErrExists
+		}
+		return Session{}, fmt.Errorf("create session: %w", err)
+	}
+
+	return Session{ID: id, Title: title, CreatedAt: now, UpdatedAt: now}, nil
+}
+
+func (s *SQLiteStore) Load(ctx context.Context, id string) (Session, error) {
+	var session Session
+	var createdAt, updatedAt string
+	err := s.db.QueryRowContext(ctx, `SELECT id, title, name, created_at, updated_at FROM sessions WHERE id = ?`, id).Scan(&session.ID, &session.Title, &session.Name, &createdAt, &updatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Session{}, ErrNotFound
+		}
+		return Session{}, fmt.Errorf("load session: %w", err)
+	}
+	session.CreatedAt = parseTime(createdAt)
+	session.UpdatedAt = parseTime(updatedAt)
+
+	rows, err := s.db.QueryContext(ctx, `SELECT role, content, at, payload FROM turns WHERE session_id = ? ORDER BY seq`, id)
+	if err != nil {
+		return Session{}, fmt.Errorf("load turns: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var turn Turn
+		var at string
+		var payload []byte
+		if err := rows.Scan(&turn.Role, &turn.Content, &at, &payload); err != nil {
+			return Session{}, fmt.Errorf("scan turn: %w", err)
+		}
+		turn.At = parseTime(at)
+		if len(payload) > 0 {
+			turn.Payload = payload
+		}
+		session.Turns = append(session.Turns, turn)
+	}
+	if err := rows.Err(); err != nil {
+		return Session{}, fmt.Errorf("load turns: %w", err)
+	}
+
+	return session, nil
+}
+
+func (s *SQLiteStore) AppendTurn(ctx context.Context, id string, turns ...Turn) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin append: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var next int64
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(seq), 0) FROM turns WHERE session_id = ?`, id).Scan(&next); err != nil {
+		return fmt.Errorf("read turn sequence: %w", err)
+	}
+
+	now := time.Now().UTC()
+	result, err := tx.ExecContext(ctx, `UPDATE sessions SET updated_at = ? WHERE id = ?`, formatTime(now), id)
+	if err != nil {
+		return fmt.Errorf("touch session: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrNotFound
+	}
+
+	for _, turn := range stampTurns(turns, now) {
+		next++
+		var payload []byte
+		if len(turn.Payload) > 0 {
+			payload = turn.Payload
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO turns (session_id, seq, role, content, at, payload) VALUES (?, ?, ?, ?, ?, ?)`, id, next, turn.Role, turn.Content, formatTime(turn.At), payload); err != nil {
+			return fmt.Errorf("append turn: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit append: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SQLiteStore) Rename(ctx context.Context, id string, name string) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE sessions SET name = ? WHERE id = ?`, name, id)
+	if err != nil {
+		return fmt.Errorf("rename session: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *SQLiteStore) List(ctx context.Context) ([]Summary, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT s.id, s.title, s.name, s.created_at, s.updated_at, COUNT(t.seq)
+		FROM sessions s LEFT JOIN turns t ON t.session_id = s.id
+		GROUP BY s.id`)
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var summaries []Summary
+	for rows.Next() {
+		var summary Summary
+		var createdAt, updatedAt string
+		if err := rows.Scan(&summary.ID, &summary.Title, &summary.Name, &createdAt, &updatedAt, &summary.TurnCount); err != nil {
+			return nil, fmt.Errorf("scan session: %w", err)
+		}
+		summary.CreatedAt = parseTime(createdAt)
+		summary.UpdatedAt = parseTime(updatedAt)
+		summaries = append(summaries, summary)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+
+	sortSummaries(summaries)
+	return summaries, nil
+}
+
+func (s *SQLiteStore) Delete(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete session: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func formatTime(at time.Time) string {
+	return at.UTC().Format(time.RFC3339Nano)
+}
+
+func parseTime(value string) time.Time {
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return parsed
+}
@@ -0,0 +1,129 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"miniclaw/pkg/config"
+	"miniclaw/pkg/workspace"
+)
+
+const (
+	BackendMemory = "memory"
+	BackendJSONL  = "jsonl"
+	BackendSQLite = "sqlite"
+)
+
+var (
+	// ErrNotFound is returned when a session ID is unknown to the store.
+	ErrNotFound = errors.New("session not found")
+	// ErrExists is returned by Create when the session ID is already taken.
+	ErrExists = errors.New("session already exists")
+)
+
+// Turn is one persisted transcript entry.
+type Turn struct {
+	Role    string    `json:"role"`
+	Content string    `json:"content,omitempty"`
+	At      time.Time `json:"at"`
+	// Payload carries caller-specific encoded data, such as full provider messages.
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Session is a stored session with its full turn history.
+//
+// Title selects who resumes the session (for example "miniclaw:telegram:42");
+// Name is a short human-readable description, such as one generated from the
+// first prompt, and is empty until set with Rename.
+type Session struct {
+	ID        string
+	Title     string
+	Name      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Turns     []Turn
+}
+
+// Summary describes a stored session without loading its turns.
+type Summary struct {
+	ID        string
+	Title     string
+	Name      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	TurnCount int
+}
+
+// SessionStore persists sessions and their append-only turn history.
+//
+// Implementations must be safe for concurrent use. Turns are only ever
+// appended, which keeps the JSONL backend a plain append log and lets every
+// backend recover a session by replaying its turns in order.
+type SessionStore interface {
+	Create(ctx context.Context, id string, title string) (Session, error)
+	Load(ctx context.Context, id string) (Session, error)
+	AppendTurn(ctx context.Context, id string, turns ...Turn) error
+	// Rename sets the display name of a session; it leaves Title and the turns alone.
+	Rename(ctx context.Context, id string, name string) error
+	List(ctx context.Context) ([]Summary, error)
+	Delete(ctx context.Context, id string) error
+	Close() error
+}
+
+// Open builds the store selected by storage config.
+//
+// An empty backend selects the in-memory store, which keeps the historical
+// behavior of losing sessions on restart.
+func Open(cfg config.StorageConfig) (SessionStore, error) {
+	backend := strings.ToLower(strings.TrimSpace(cfg.Backend))
+	path, err := workspace.ExpandHome(strings.TrimSpace(cfg.Path))
+	if err != nil {
+		return nil, err
+	}
+
+	switch backend {
+	case "", BackendMemory:
+		return NewMemoryStore(), nil
+	case BackendJSONL:
+		if path == "" {
+			return nil, errors.New("storage.path is required for the jsonl backend")
+		}
+		return NewJSONLStore(path)
+	case BackendSQLite:
+		if path == "" {
+			return nil, errors.New("storage.path is required for the sqlite backend")
+		}
+		return NewSQLiteStore(path)
+	default:
+		return nil, fmt.Errorf("unsupported storage backend: %s", cfg.Backend)
+	}
+}
+
+// validateID rejects IDs that cannot be stored safely by every backend.
+func validateID(id string) error {
+	if strings.TrimSpace(id) == "" {
+		return errors.New("session id is required")
+	}
+	if strings.ContainsAny(id, "\x00\r\n") {
+		return fmt.Errorf("invalid session id %q", id)
+	}
+
+	return nil
+}
+
+// stampTurns fills missing turn timestamps with now.
+func stampTurns(turns []Turn, now time.Time) []Turn {
+	stamped := make([]Turn, len(turns))
+	for index, turn := range turns {
+		if turn.At.IsZero() {
+			turn.At = now
+		}
+		stamped[index] = turn
+	}
+
+	return stamped
+}
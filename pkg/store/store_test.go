@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"miniclaw/pkg/config"
+)
+
+func TestSessionStoreContract(t *testing.T) {
+	backends := map[string]func(t *testing.T) SessionStore{
+		BackendMemory: func(t *testing.T) SessionStore {
+			return NewMemoryStore()
+		},
+		BackendJSONL: func(t *testing.T) SessionStore {
+			store, err := NewJSONLStore(filepath.Join(t.TempDir(), "sessions"))
+			if err != nil {
+				t.Fatalf("NewJSONLStore error: %v", err)
+			}
+			return store
+		},
+		BackendSQLite: func(t *testing.T) SessionStore {
+			store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "sessions.db"))
+			if err != nil {
+				t.Fatalf("NewSQLiteStore error: %v", err)
+			}
+			return store
+		},
+	}
+
+	for name, open := range backends {
+		t.Run(name, func(t *testing.T) {
+			store := open(t)
+			t.Cleanup(func() { _ = store.Close() })
+			ctx := context.Background()
+
+			if _, err := store.Create(ctx, "telegram:42", "chat"); err != nil {
+				t.Fatalf("Create error: %v", err)
+			}
+			if _, err := store.Create(ctx, "telegram:42", "chat"); !errors.Is(err, ErrExists) {
+				t.Fatalf("Create duplicate error = %v, want ErrExists", err)
+			}
+
+			payload := json.RawMessage(`{"role":"assistant"}`)
+			if err := store.AppendTurn(ctx, "telegram:42", Turn{Role: "user", Content: "hi"}, Turn{Role: "assistant", Content: "hello", Payload: payload}); err != nil {
+				t.Fatalf("AppendTurn error: %v", err)
+			}
+			if err := store.AppendTurn(ctx, "missing", Turn{Role: "user", Content: "x"}); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("AppendTurn missing error = %v, want ErrNotFound", err)
+			}
+
+			session, err := store.Load(ctx, "telegram:42")
+			if err != nil {
+				t.Fatalf("Load error: %v", err)
+			}
+			if session.Title != "chat" || len(session.Turns) != 2 {
+				t.Fatalf("session = %+v, want title chat and 2 turns", session)
+			}
+			if session.Turns[1].Content != "hello" || string(session.Turns[1].Payload) != string(payload) || session.Turns[0].At.IsZero() {
+				t.Fatalf("turns = %+v, want ordered turns with payload and timestamps", session.Turns)
+			}
+
+			if err := store.Rename(ctx, "telegram:42", "Planning the launch"); err != nil {
+				t.Fatalf("Rename error: %v", err)
+			}
+			if err := store.Rename(ctx, "missing", "x"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Rename missing error = %v, want ErrNotFound", err)
+			}
+			if renamed, err := store.Load(ctx, "telegram:42"); err != nil || renamed.Name != "Planning the launch" || renamed.Title != "chat" || len(renamed.Turns) != 2 {
+				t.Fatalf("renamed session = %+v, %v; want the new name with title and turns kept", renamed, err)
+			}
+
+			if _, err := store.Create(ctx, "other", ""); err != nil {
+				t.Fatalf("Create error: %v", err)
+			}
+			summaries, err := store.List(ctx)
+			if err != nil {
+				t.Fatalf("List error: %v", err)
+			}
+			if len(summaries) != 2 || summaries[0].ID != "telegram:42" || summaries[0].TurnCount != 2 || summaries[0].Name != "Planning the launch" {
+				t.Fatalf("summaries = %+v, want telegram:42 with 2 turns first", summaries)
+			}
+
+			if err := store.Delete(ctx, "telegram:42"); err != nil {
+				t.Fatalf("Delete error: %v", err)
+			}
+			if _, err := store.Load(ctx, "telegram:42"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Load deleted error = %v, want ErrNotFound", err)
+			}
+			if err := store.Delete(ctx, "telegram:42"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Delete missing error = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestJSONLStoreSurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	first, err := NewJSONLStore(dir)
+	if err != nil {
+		t.Fatalf("NewJSONLStore error: %v", err)
+	}
+	if _, err := first.Create(ctx, "a/b", ""); err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	if err := first.AppendTurn(ctx, "a/b", Turn{Role: "user", Content: "persisted"}); err != nil {
+		t.Fatalf("AppendTurn error: %v", err)
+	}
+
+	second, err := NewJSONLStore(dir)
+	if err != nil {
+		t.Fatalf("NewJSONLStore error: %v", err)
+	}
+	session, err := second.Load(ctx, "a/b")
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if len(session.Turns) != 1 || session.Turns[0].Content != "persisted" {
+		t.Fatalf("turns = %+v, want persisted turn", session.Turns)
+	}
+}
+
+func TestOpenSelectsBackend(t *testing.T) {
+	store, err := Open(config.StorageConfig{})
+	if err != nil {
+		t.Fatalf("Open default error: %v", err)
+	}
+	if _, ok := store.(*MemoryStore); !ok {
+		t.Fatalf("default store = %T, want *MemoryStore", store)
+	}
+
+	if _, err := Open(config.StorageConfig{Backend: BackendSQLite}); err == nil {
+		t.Fatal("expected error for sqlite without path")
+	}
+	if _, err := Open(config.StorageConfig{Backend: "redis", Path: "x"}); err == nil {
+		t.Fatal("expected error for unsupported backend")
+	}
+}
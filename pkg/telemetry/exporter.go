@@ -0,0 +1,80 @@
+package telemetry
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"miniclaw/pkg/config"
+)
+
+// Exporter pushes registry snapshots to a metrics backend.
+type Exporter interface {
+	// Name identifies the exporter in logs.
+	Name() string
+	// Interval is how often Push sends a snapshot.
+	Interval() time.Duration
+	// Export sends one snapshot.
+	Export(ctx context.Context, series []Series) error
+}
+
+// NewExporters builds the push exporters enabled in cfg. Prometheus is not
+// among them: it is pulled through PrometheusHandler instead.
+func NewExporters(cfg config.TelemetryConfig) ([]Exporter, error) {
+	var exporters []Exporter
+	if cfg.StatsD.Enabled {
+		exporter, err := NewStatsD(cfg.StatsD)
+		if err != nil {
+			return nil, err
+		}
+		exporters = append(exporters, exporter)
+	}
+	if cfg.OTLP.Enabled {
+		exporter, err := NewOTLP(cfg.OTLP)
+		if err != nil {
+			return nil, err
+		}
+		exporters = append(exporters, exporter)
+	}
+
+	return exporters, nil
+}
+
+// Push exports registry snapshots every exporter interval until ctx ends,
+// then sends a final snapshot so the last interval is not lost. Failures are
+// logged and retried on the next tick.
+func Push(ctx context.Context, registry *Registry, exporter Exporter, log *slog.Logger) {
+	if log == nil {
+		log = slog.Default()
+	}
+	log = log.With("component", "telemetry", "exporter", exporter.Name())
+
+	export := func(ctx context.Context) {
+		if err := exporter.Export(ctx, registry.Snapshot()); err != nil {
+			log.Warn("Failed to export metrics", "error", err)
+		}
+	}
+
+	ticker := time.NewTicker(exporter.Interval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			export(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			export(ctx)
+		}
+	}
+}
+
+// interval converts interval_seconds, using fallback when it is unset.
+func interval(seconds int, fallback time.Duration) time.Duration {
+	if seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	return fallback
+}
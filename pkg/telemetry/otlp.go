@@ -0,0 +1,227 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"miniclaw/pkg/config"
+)
+
+const (
+	defaultOTLPEndpoint    = "http://127.0.0.1:4318"
+	defaultOTLPInterval    = 30 * time.Second
+	defaultOTLPServiceName = "miniclaw"
+	otlpMetricsPath        = "/v1/metrics"
+	// otlpCumulative is AGGREGATION_TEMPORALITY_CUMULATIVE.
+	otlpCumulative = 2
+)
+
+// OTLP pushes metrics to an OpenTelemetry collector using OTLP/HTTP with
+// JSON encoding. Counters are cumulative monotonic sums, gauges are gauges,
+// and summaries carry count and sum without quantiles.
+type OTLP struct {
+	endpoint    string
+	headers     map[string]string
+	serviceName string
+	interval    time.Duration
+	client      *http.Client
+	// start begins every cumulative series; the registry is created just before the exporter.
+	start time.Time
+}
+
+// NewOTLP builds an OTLP exporter from telemetry.otlp.
+func NewOTLP(cfg config.OTLPTelemetryConfig) (*OTLP, error) {
+	if cfg.IntervalSeconds < 0 {
+		return nil, errors.New("telemetry.otlp.interval_seconds must not be negative")
+	}
+	endpoint := strings.TrimRight(strings.TrimSpace(cfg.Endpoint), "/")
+	if endpoint == "" {
+		endpoint = defaultOTLPEndpoint
+	}
+	parsed, err := url.Parse(endpoint)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("telemetry.otlp.endpoint: invalid url %q", cfg.Endpoint)
+	}
+	if !strings.HasSuffix(endpoint, otlpMetricsPath) {
+		endpoint += otlpMetricsPath
+	}
+	serviceName := strings.TrimSpace(cfg.ServiceName)
+	if serviceName == "" {
+		serviceName = defaultOTLPServiceName
+	}
+
+	return &OTLP{
+		endpoint:    endpoint,
+		headers:     cfg.Headers,
+		serviceName: serviceName,
+		interval:    interval(cfg.IntervalSeconds, defaultOTLPInterval),
+		client:      &http.Client{Timeout: 10 * time.Second},
+		start:       time.Now(),
+	}, nil
+}
+
+// Name implements Exporter.
+func (o *OTLP) Name() string {
+	return "otlp"
+}
+
+// Interval implements Exporter.
+func (o *OTLP) Interval() time.Duration {
+	return o.interval
+}
+
+// Export posts one ExportMetricsServiceRequest.
+func (o *OTLP) Export(ctx context.Context, series []Series) error {
+	if len(series) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(o.request(series, o.start, time.Now()))
+	if err != nil {
+		return fmt.Errorf("encode otlp request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range o.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post otlp metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("post otlp metrics: http %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+
+	return nil
+}
+
+// OTLP JSON mapping of opentelemetry.proto.collector.metrics.v1.ExportMetricsServiceRequest.
+type (
+	otlpRequest struct {
+		ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+	}
+	otlpResourceMetrics struct {
+		Resource     otlpResource       `json:"resource"`
+		ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeMetrics struct {
+		Scope   otlpScope    `json:"scope"`
+		Metrics []otlpMetric `json:"metrics"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpMetric struct {
+		Name        string       `json:"name"`
+		Description string       `json:"description,omitempty"`
+		Sum         *otlpSum     `json:"sum,omitempty"`
+		Gauge       *otlpGauge   `json:"gauge,omitempty"`
+		Summary     *otlpSummary `json:"summary,omitempty"`
+	}
+	otlpSum struct {
+		AggregationTemporality int             `json:"aggregationTemporality"`
+		IsMonotonic            bool            `json:"isMonotonic"`
+		DataPoints             []otlpDataPoint `json:"dataPoints"`
+	}
+	otlpGauge struct {
+		DataPoints []otlpDataPoint `json:"dataPoints"`
+	}
+	otlpSummary struct {
+		DataPoints []otlpDataPoint `json:"dataPoints"`
+	}
+	otlpDataPoint struct {
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano,omitempty"`
+		TimeUnixNano      string          `json:"timeUnixNano"`
+		AsDouble          *float64        `json:"asDouble,omitempty"`
+		// Count and Sum are set on summary points; 64-bit integers are strings in OTLP JSON.
+		Count string   `json:"count,omitempty"`
+		Sum   *float64 `json:"sum,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string        `json:"key"`
+		Value otlpAttrValue `json:"value"`
+	}
+	otlpAttrValue struct {
+		StringValue string `json:"stringValue"`
+	}
+)
+
+// request groups series into one metric per name. start is the start of the
+// cumulative series; zero leaves it unset.
+func (o *OTLP) request(series []Series, start time.Time, now time.Time) otlpRequest {
+	startNano := ""
+	if !start.IsZero() {
+		startNano = strconv.FormatInt(start.UnixNano(), 10)
+	}
+	nowNano := strconv.FormatInt(now.UnixNano(), 10)
+
+	var metrics []otlpMetric
+	for _, s := range series {
+		if len(metrics) == 0 || metrics[len(metrics)-1].Name != s.Name {
+			metric := otlpMetric{Name: s.Name, Description: s.Help}
+			switch s.Kind {
+			case KindCounter:
+				metric.Sum = &otlpSum{AggregationTemporality: otlpCumulative, IsMonotonic: true}
+			case KindGauge:
+				metric.Gauge = &otlpGauge{}
+			default:
+				metric.Summary = &otlpSummary{}
+			}
+			metrics = append(metrics, metric)
+		}
+		metric := &metrics[len(metrics)-1]
+
+		point := otlpDataPoint{Attributes: otlpAttributes(s.Labels), TimeUnixNano: nowNano}
+		switch {
+		case metric.Sum != nil:
+			value := s.Value
+			point.StartTimeUnixNano = startNano
+			point.AsDouble = &value
+			metric.Sum.DataPoints = append(metric.Sum.DataPoints, point)
+		case metric.Gauge != nil:
+			value := s.Value
+			point.AsDouble = &value
+			metric.Gauge.DataPoints = append(metric.Gauge.DataPoints, point)
+		default:
+			sum := s.Sum
+			point.StartTimeUnixNano = startNano
+			point.Count = strconv.FormatUint(s.Count, 10)
+			point.Sum = &sum
+			metric.Summary.DataPoints = append(metric.Summary.DataPoints, point)
+		}
+	}
+
+	return otlpRequest{ResourceMetrics: []otlpResourceMetrics{{
+		Resource:     otlpResource{Attributes: []otlpAttribute{{Key: "service.name", Value: otlpAttrValue{StringValue: o.serviceName}}}},
+		ScopeMetrics: []otlpScopeMetrics{{Scope: otlpScope{Name: "miniclaw"}, Metrics: metrics}},
+	}}}
+}
+
+func otlpAttributes(labels []Label) []otlpAttribute {
+	attributes := make([]otlpAttribute, 0, len(labels))
+	for _, label := range labels {
+		attributes = append(attributes, otlpAttribute{Key: label.Key, Value: otlpAttrValue{StringValue: label.Value}})
+	}
+
+	return attributes
+}
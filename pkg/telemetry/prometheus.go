@@ -0,0 +1,80 @@
+package telemetry
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultPrometheusPath is where the gateway serves metrics when telemetry.prometheus.path is unset.
+const DefaultPrometheusPath = "/metrics"
+
+// PrometheusHandler serves the registry in the Prometheus text exposition format.
+func PrometheusHandler(registry *Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = WritePrometheus(w, registry.Snapshot())
+	})
+}
+
+// WritePrometheus writes series in the Prometheus text exposition format.
+// Summaries are written as <name>_sum and <name>_count without quantiles.
+func WritePrometheus(w io.Writer, series []Series) error {
+	var b strings.Builder
+	previous := ""
+	for _, s := range series {
+		if s.Name != previous {
+			previous = s.Name
+			if s.Help != "" {
+				fmt.Fprintf(&b, "# HELP %s %s\n", s.Name, escapeHelp(s.Help))
+			}
+			fmt.Fprintf(&b, "# TYPE %s %s\n", s.Name, prometheusType(s.Kind))
+		}
+
+		labels := prometheusLabels(s.Labels)
+		switch s.Kind {
+		case KindSummary:
+			fmt.Fprintf(&b, "%s_sum%s %s\n", s.Name, labels, formatFloat(s.Sum))
+			fmt.Fprintf(&b, "%s_count%s %d\n", s.Name, labels, s.Count)
+		default:
+			fmt.Fprintf(&b, "%s%s %s\n", s.Name, labels, formatFloat(s.Value))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func prometheusType(kind Kind) string {
+	switch kind {
+	case KindCounter:
+		return "counter"
+	case KindGauge:
+		return "gauge"
+	default:
+		return "summary"
+	}
+}
+
+func prometheusLabels(labels []Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0, len(labels))
+	for _, label := range labels {
+		parts = append(parts, label.Key+"="+strconv.Quote(label.Value))
+	}
+
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,155 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"miniclaw/pkg/config"
+)
+
+const (
+	defaultStatsDAddress  = "127.0.0.1:8125"
+	defaultStatsDInterval = 10 * time.Second
+	// maxStatsDPacket keeps each datagram under a typical Ethernet MTU.
+	maxStatsDPacket = 1432
+)
+
+// StatsD pushes metrics as StatsD lines over UDP, with labels as DogStatsD
+// "|#key:value" tags.
+//
+// Counters are sent as the increase since the previous export, gauges as
+// their current value, and summaries as <name>.count and <name>.sum counters.
+type StatsD struct {
+	address  string
+	prefix   string
+	interval time.Duration
+
+	mu   sync.Mutex
+	last map[string]float64
+}
+
+// NewStatsD builds a StatsD exporter from telemetry.statsd.
+func NewStatsD(cfg config.StatsDTelemetryConfig) (*StatsD, error) {
+	if cfg.IntervalSeconds < 0 {
+		return nil, errors.New("telemetry.statsd.interval_seconds must not be negative")
+	}
+	address := strings.TrimSpace(cfg.Address)
+	if address == "" {
+		address = defaultStatsDAddress
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return nil, fmt.Errorf("telemetry.statsd.address: %w", err)
+	}
+	prefix := strings.TrimSpace(cfg.Prefix)
+	if strings.ContainsAny(prefix, ":|@# ") {
+		return nil, fmt.Errorf("telemetry.statsd.prefix %q must not contain ':', '|', '@', '#', or spaces", prefix)
+	}
+
+	return &StatsD{
+		address:  address,
+		prefix:   prefix,
+		interval: interval(cfg.IntervalSeconds, defaultStatsDInterval),
+		last:     make(map[string]float64),
+	}, nil
+}
+
+// Name implements Exporter.
+func (s *StatsD) Name() string {
+	return "statsd"
+}
+
+// Interval implements Exporter.
+func (s *StatsD) Interval() time.Duration {
+	return s.interval
+}
+
+// Export sends the change since the previous export.
+func (s *StatsD) Export(ctx context.Context, series []Series) error {
+	lines := s.lines(series)
+	if len(lines) == 0 {
+		return nil
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", s.address)
+	if err != nil {
+		return fmt.Errorf("dial statsd: %w", err)
+	}
+	defer conn.Close()
+
+	for _, packet := range packets(lines, maxStatsDPacket) {
+		if _, err := conn.Write([]byte(packet)); err != nil {
+			return fmt.Errorf("write statsd: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// lines formats series as StatsD lines and remembers counter totals for the next delta.
+func (s *StatsD) lines(series []Series) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var lines []string
+	counter := func(name string, labels []Label, total float64) {
+		key := seriesKey(name, labels)
+		delta := total - s.last[key]
+		s.last[key] = total
+		if delta > 0 {
+			lines = append(lines, fmt.Sprintf("%s%s:%s|c%s", s.prefix, name, formatFloat(delta), statsDTags(labels)))
+		}
+	}
+	for _, item := range series {
+		switch item.Kind {
+		case KindCounter:
+			counter(item.Name, item.Labels, item.Value)
+		case KindGauge:
+			lines = append(lines, fmt.Sprintf("%s%s:%s|g%s", s.prefix, item.Name, formatFloat(item.Value), statsDTags(item.Labels)))
+		case KindSummary:
+			counter(item.Name+".count", item.Labels, float64(item.Count))
+			counter(item.Name+".sum", item.Labels, item.Sum)
+		}
+	}
+
+	return lines
+}
+
+func statsDTags(labels []Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	tags := make([]string, 0, len(labels))
+	for _, label := range labels {
+		tags = append(tags, label.Key+":"+strings.NewReplacer(",", "_", "|", "_", "\n", " ").Replace(label.Value))
+	}
+
+	return "|#" + strings.Join(tags, ",")
+}
+
+// packets joins lines with newlines into datagrams of at most limit bytes.
+func packets(lines []string, limit int) []string {
+	var out []string
+	var current strings.Builder
+	for _, line := range lines {
+		if current.Len() > 0 && current.Len()+1+len(line) > limit {
+			out = append(out, current.String())
+			current.Reset()
+		}
+		if current.Len() > 0 {
+			current.WriteByte('\n')
+		}
+		current.WriteString(line)
+	}
+	if current.Len() > 0 {
+		out = append(out, current.String())
+	}
+
+	return out
+}
@@ -0,0 +1,160 @@
+// Package telemetry collects gateway metrics in one registry and hands them
+// to exporters: a Prometheus scrape handler, StatsD push, and OTLP push.
+package telemetry
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Kind is how a series aggregates.
+type Kind int
+
+const (
+	// KindCounter only goes up; exporters send its running total or deltas.
+	KindCounter Kind = iota
+	// KindGauge is a point-in-time value.
+	KindGauge
+	// KindSummary keeps the count and sum of observed values.
+	KindSummary
+)
+
+// Label is one dimension of a series, for example provider="openai".
+type Label struct {
+	Key   string
+	Value string
+}
+
+// L is shorthand for building a Label.
+func L(key string, value string) Label {
+	return Label{Key: key, Value: value}
+}
+
+// Series is a snapshot of one metric with one label set.
+type Series struct {
+	Name   string
+	Help   string
+	Kind   Kind
+	Labels []Label
+	// Value is the counter total or gauge value; unused for summaries.
+	Value float64
+	// Count and Sum describe summary observations.
+	Count uint64
+	Sum   float64
+}
+
+// Registry holds every series the process reports. Methods are safe for
+// concurrent use and a nil *Registry ignores all updates.
+type Registry struct {
+	mu     sync.Mutex
+	help   map[string]string
+	series map[string]*Series
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		help:   make(map[string]string),
+		series: make(map[string]*Series),
+	}
+}
+
+// Describe sets the help text exporters attach to name.
+func (r *Registry) Describe(name string, help string) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.help[name] = help
+}
+
+// Add increases a counter.
+func (r *Registry) Add(name string, delta float64, labels ...Label) {
+	if r == nil || delta < 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lookup(name, KindCounter, labels).Value += delta
+}
+
+// Set replaces a gauge value.
+func (r *Registry) Set(name string, value float64, labels ...Label) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lookup(name, KindGauge, labels).Value = value
+}
+
+// Observe records one value, such as a duration in seconds, on a summary.
+func (r *Registry) Observe(name string, value float64, labels ...Label) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	series := r.lookup(name, KindSummary, labels)
+	series.Count++
+	series.Sum += value
+}
+
+// Snapshot copies every series, sorted by name and labels.
+func (r *Registry) Snapshot() []Series {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snapshot := make([]Series, 0, len(r.series))
+	for _, series := range r.series {
+		copied := *series
+		copied.Labels = slices.Clone(series.Labels)
+		copied.Help = r.help[series.Name]
+		snapshot = append(snapshot, copied)
+	}
+	slices.SortFunc(snapshot, func(a, b Series) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(seriesKey(a.Name, a.Labels), seriesKey(b.Name, b.Labels)))
+	})
+
+	return snapshot
+}
+
+// lookup returns the series for name and labels, creating it on first use;
+// the caller holds r.mu. A name keeps the kind it was first used with.
+func (r *Registry) lookup(name string, kind Kind, labels []Label) *Series {
+	labels = slices.Clone(labels)
+	slices.SortFunc(labels, func(a, b Label) int { return cmp.Compare(a.Key, b.Key) })
+	key := seriesKey(name, labels)
+
+	series, ok := r.series[key]
+	if !ok {
+		series = &Series{Name: name, Kind: kind, Labels: labels}
+		r.series[key] = series
+	}
+
+	return series
+}
+
+func seriesKey(name string, labels []Label) string {
+	var b strings.Builder
+	b.WriteString(name)
+	for _, label := range labels {
+		b.WriteString("\x00")
+		b.WriteString(label.Key)
+		b.WriteString("=")
+		b.WriteString(label.Value)
+	}
+
+	return b.String()
+}
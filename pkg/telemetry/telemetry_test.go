@@ -0,0 +1,145 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"miniclaw/pkg/config"
+)
+
+func testRegistry() *Registry {
+	registry := NewRegistry()
+	registry.Describe("miniclaw_turns_total", "Prompt attempts.")
+	registry.Add("miniclaw_turns_total", 2, L("outcome", "ok"))
+	registry.Add("miniclaw_turns_total", 1, L("outcome", "error"))
+	registry.Set("miniclaw_active_sessions", 3)
+	registry.Observe("miniclaw_turn_stage_seconds", 0.5, L("stage", "total"))
+	registry.Observe("miniclaw_turn_stage_seconds", 1.5, L("stage", "total"))
+
+	return registry
+}
+
+func TestWritePrometheus(t *testing.T) {
+	t.Parallel()
+
+	var b strings.Builder
+	if err := WritePrometheus(&b, testRegistry().Snapshot()); err != nil {
+		t.Fatalf("WritePrometheus error: %v", err)
+	}
+
+	want := `# TYPE miniclaw_active_sessions gauge
+miniclaw_active_sessions 3
+# TYPE miniclaw_turn_stage_seconds summary
+miniclaw_turn_stage_seconds_sum{stage="total"} 2
+miniclaw_turn_stage_seconds_count{stage="total"} 2
+# HELP miniclaw_turns_total Prompt attempts.
+# TYPE miniclaw_turns_total counter
+miniclaw_turns_total{outcome="error"} 1
+miniclaw_turns_total{outcome="ok"} 2
+`
+	if got := b.String(); got != want {
+		t.Fatalf("output =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestStatsDSendsCounterDeltas(t *testing.T) {
+	t.Parallel()
+
+	exporter, err := NewStatsD(config.StatsDTelemetryConfig{Prefix: "mc."})
+	if err != nil {
+		t.Fatalf("NewStatsD error: %v", err)
+	}
+	registry := testRegistry()
+
+	first := exporter.lines(registry.Snapshot())
+	want := []string{
+		"mc.miniclaw_active_sessions:3|g",
+		"mc.miniclaw_turn_stage_seconds.count:2|c|#stage:total",
+		"mc.miniclaw_turn_stage_seconds.sum:2|c|#stage:total",
+		"mc.miniclaw_turns_total:1|c|#outcome:error",
+		"mc.miniclaw_turns_total:2|c|#outcome:ok",
+	}
+	if strings.Join(first, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("first export = %q, want %q", first, want)
+	}
+
+	registry.Add("miniclaw_turns_total", 1, L("outcome", "ok"))
+	second := exporter.lines(registry.Snapshot())
+	want = []string{"mc.miniclaw_active_sessions:3|g", "mc.miniclaw_turns_total:1|c|#outcome:ok"}
+	if strings.Join(second, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("second export = %q, want only changed counters", second)
+	}
+
+	if _, err := NewStatsD(config.StatsDTelemetryConfig{Prefix: "bad:prefix"}); err == nil {
+		t.Fatal("NewStatsD error = nil, want invalid prefix")
+	}
+}
+
+func TestPacketsRespectsLimit(t *testing.T) {
+	t.Parallel()
+
+	got := packets([]string{"aaaa", "bbbb", "cccc"}, 9)
+	if len(got) != 2 || got[0] != "aaaa\nbbbb" || got[1] != "cccc" {
+		t.Fatalf("packets = %q, want two datagrams", got)
+	}
+}
+
+func TestOTLPExportPostsJSON(t *testing.T) {
+	t.Parallel()
+
+	var (
+		path    string
+		auth    string
+		payload otlpRequest
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		auth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	exporter, err := NewOTLP(config.OTLPTelemetryConfig{
+		Endpoint:    server.URL,
+		Headers:     map[string]string{"Authorization": "Bearer token"},
+		ServiceName: "miniclaw-test",
+	})
+	if err != nil {
+		t.Fatalf("NewOTLP error: %v", err)
+	}
+	if err := exporter.Export(context.Background(), testRegistry().Snapshot()); err != nil {
+		t.Fatalf("Export error: %v", err)
+	}
+
+	if path != "/v1/metrics" || auth != "Bearer token" {
+		t.Fatalf("path = %q auth = %q, want /v1/metrics with configured header", path, auth)
+	}
+	resource := payload.ResourceMetrics[0]
+	if got := resource.Resource.Attributes[0].Value.StringValue; got != "miniclaw-test" {
+		t.Fatalf("service.name = %q, want miniclaw-test", got)
+	}
+	metrics := resource.ScopeMetrics[0].Metrics
+	if len(metrics) != 3 {
+		t.Fatalf("metrics = %+v, want 3", metrics)
+	}
+	turns := metrics[2]
+	if turns.Name != "miniclaw_turns_total" || turns.Sum == nil || !turns.Sum.IsMonotonic || len(turns.Sum.DataPoints) != 2 {
+		t.Fatalf("turns metric = %+v, want monotonic sum with 2 points", turns)
+	}
+	stage := metrics[1].Summary
+	if stage == nil || stage.DataPoints[0].Count != "2" || *stage.DataPoints[0].Sum != 2 {
+		t.Fatalf("stage metric = %+v, want summary count 2 sum 2", metrics[1])
+	}
+
+	if _, err := NewOTLP(config.OTLPTelemetryConfig{Endpoint: "collector:4318"}); err == nil {
+		t.Fatal("NewOTLP error = nil, want invalid endpoint")
+	}
+}
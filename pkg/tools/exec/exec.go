@@ -0,0 +1,231 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"miniclaw/pkg/config"
+	"miniclaw/pkg/workspace"
+)
+
+const (
+	DefaultTimeout        = 30 * time.Second
+	MaxTimeout            = 10 * time.Minute
+	DefaultMaxOutputBytes = 64 * 1024
+
+	// waitDelay bounds how long Run waits for output pipes after the process is killed.
+	waitDelay = 2 * time.Second
+)
+
+// defaultDenyPatterns block destructive commands when deny patterns are enabled.
+var defaultDenyPatterns = []string{
+	`\brm\s+-[a-zA-Z]*[rf][a-zA-Z]*\s+(/|~|\*|\$HOME)(\s|$)`,
+	`\b(mkfs|mkswap|fdisk|parted|diskpart)\b`,
+	`\bdd\s+.*\bof=/dev/`,
+	`>\s*/dev/(sd|nvme|hd|disk)`,
+	`\b(shutdown|reboot|poweroff|halt)\b`,
+	`:\(\)\s*\{.*\};\s*:`,
+	`\bchmod\s+-R\s+777\s+/(\s|$)`,
+	`\b(curl|wget)\b.*\|\s*(sudo\s+)?(ba|z)?sh\b`,
+}
+
+// passthroughEnv lists host variables a command may see; everything else,
+// including provider API keys and bot tokens, is scrubbed.
+var passthroughEnv = []string{"PATH", "HOME", "USER", "LOGNAME", "SHELL", "LANG", "LC_ALL", "LC_CTYPE", "TERM", "TMPDIR", "TZ"}
+
+// Service runs shell commands inside the workspace with bounded time and output.
+type Service struct {
+	guard          *workspace.Guard
+	shell          string
+	timeout        time.Duration
+	maxOutputBytes int
+	denyPatterns   []*regexp.Regexp
+}
+
+// RunResult reports one finished (or timed-out) command.
+type RunResult struct {
+	Command  string
+	Dir      string
+	ExitCode int
+	Stdout   string
+	Stderr   string
+	// Truncated reports that stdout or stderr exceeded the output cap.
+	Truncated bool
+	TimedOut  bool
+	Duration  time.Duration
+}
+
+// NewService builds an exec service from tools.exec config.
+//
+// Custom deny patterns are compiled up front so a bad pattern fails startup
+// instead of silently allowing commands.
+func NewService(guard *workspace.Guard, cfg config.ExecConfig) (*Service, error) {
+	if guard == nil {
+		return nil, errors.New("workspace guard is required")
+	}
+
+	service := &Service{
+		guard:          guard,
+		shell:          "/bin/sh",
+		timeout:        DefaultTimeout,
+		maxOutputBytes: DefaultMaxOutputBytes,
+	}
+	if cfg.TimeoutSeconds > 0 {
+		service.timeout = min(time.Duration(cfg.TimeoutSeconds)*time.Second, MaxTimeout)
+	}
+	if cfg.MaxOutputBytes > 0 {
+		service.maxOutputBytes = cfg.MaxOutputBytes
+	}
+
+	if cfg.EnableDenyPatterns {
+		patterns := append(append([]string{}, defaultDenyPatterns...), cfg.CustomDenyPatterns...)
+		for _, pattern := range patterns {
+			compiled, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("compile exec deny pattern %q: %w", pattern, err)
+			}
+			service.denyPatterns = append(service.denyPatterns, compiled)
+		}
+	}
+
+	return service, nil
+}
+
+// Run executes command with the shell in dir (workspace root when empty).
+//
+// timeout overrides the configured timeout when positive; it is capped at
+// MaxTimeout. A non-zero exit status is reported in the result, not as an error.
+func (s *Service) Run(ctx context.Context, command string, dir string, timeout time.Duration) (RunResult, error) {
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return RunResult{}, workspace.NewError(workspace.ErrorInvalidArgument, "command is required")
+	}
+	if pattern := s.deniedBy(command); pattern != "" {
+		return RunResult{}, workspace.NewError(workspace.ErrorCommandDenied, fmt.Sprintf("command matches deny pattern %q", pattern))
+	}
+
+	if strings.TrimSpace(dir) == "" {
+		dir = "."
+	}
+	resolvedDir, err := s.guard.ResolvePath(dir)
+	if err != nil {
+		return RunResult{}, err
+	}
+	info, err := os.Stat(resolvedDir)
+	if err != nil {
+		return RunResult{}, workspace.NormalizeIOError(err, "stat working directory failed")
+	}
+	if !info.IsDir() {
+		return RunResult{}, workspace.NewError(workspace.ErrorInvalidPath, "working directory is not a directory")
+	}
+
+	if timeout <= 0 {
+		timeout = s.timeout
+	}
+	timeout = min(timeout, MaxTimeout)
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	stdout := &cappedBuffer{limit: s.maxOutputBytes}
+	stderr := &cappedBuffer{limit: s.maxOutputBytes}
+	cmd := osexec.CommandContext(runCtx, s.shell, "-c", command)
+	cmd.Dir = resolvedDir
+	cmd.Env = scrubbedEnv()
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = waitDelay
+
+	start := time.Now()
+	runErr := cmd.Run()
+	result := RunResult{
+		Command:   command,
+		Dir:       resolvedDir,
+		ExitCode:  0,
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		Truncated: stdout.truncated || stderr.truncated,
+		Duration:  time.Since(start),
+	}
+
+	if runErr != nil {
+		if errors.Is(runCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			result.TimedOut = true
+			result.ExitCode = -1
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			return RunResult{}, workspace.NewError(workspace.ErrorIO, ctx.Err().Error())
+		}
+
+		var exitErr *osexec.ExitError
+		if errors.As(runErr, &exitErr) {
+			result.ExitCode = exitErr.ExitCode()
+			return result, nil
+		}
+		return RunResult{}, workspace.NormalizeIOError(runErr, "start command failed")
+	}
+
+	return result, nil
+}
+
+// Timeout reports the default per-command timeout.
+func (s *Service) Timeout() time.Duration {
+	return s.timeout
+}
+
+// deniedBy returns the first deny pattern matching command, or "".
+func (s *Service) deniedBy(command string) string {
+	for _, pattern := range s.denyPatterns {
+		if pattern.MatchString(command) {
+			return pattern.String()
+		}
+	}
+
+	return ""
+}
+
+// scrubbedEnv returns the allowlisted subset of the host environment.
+func scrubbedEnv() []string {
+	env := make([]string, 0, len(passthroughEnv))
+	for _, name := range passthroughEnv {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+
+	return env
+}
+
+// cappedBuffer keeps the first limit bytes written and drops the rest.
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.buf.Len(); remaining > 0 {
+		if len(p) > remaining {
+			b.buf.Write(p[:remaining])
+			b.truncated = true
+		} else {
+			b.buf.Write(p)
+		}
+	} else if len(p) > 0 {
+		b.truncated = true
+	}
+
+	// Report the full length so the command never sees a short write.
+	return len(p), nil
+}
+
+func (b *cappedBuffer) String() string {
+	return b.buf.String()
+}
@@ -0,0 +1,114 @@
+package exec
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"miniclaw/pkg/config"
+	"miniclaw/pkg/workspace"
+)
+
+func TestRunReportsOutputAndExitCode(t *testing.T) {
+	service, guard := mustService(t, config.ExecConfig{})
+	if err := os.MkdirAll(filepath.Join(guard.Root(), "sub"), 0o755); err != nil {
+		t.Fatalf("MkdirAll error: %v", err)
+	}
+
+	result, err := service.Run(context.Background(), "pwd; echo oops >&2; exit 3", "sub", 0)
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if result.ExitCode != 3 {
+		t.Fatalf("exit code = %d, want 3", result.ExitCode)
+	}
+	if got := strings.TrimSpace(result.Stdout); filepath.Base(got) != "sub" {
+		t.Fatalf("stdout = %q, want workspace sub directory", got)
+	}
+	if strings.TrimSpace(result.Stderr) != "oops" {
+		t.Fatalf("stderr = %q, want oops", result.Stderr)
+	}
+
+	if _, err := service.Run(context.Background(), "ls", "../", 0); workspace.CategoryFromError(err) != workspace.ErrorOutsideWorkspace {
+		t.Fatalf("error category = %q, want %q", workspace.CategoryFromError(err), workspace.ErrorOutsideWorkspace)
+	}
+}
+
+func TestRunEnforcesDenyPatterns(t *testing.T) {
+	service, _ := mustService(t, config.ExecConfig{EnableDenyPatterns: true, CustomDenyPatterns: []string{`\bgit\s+push\b`}})
+
+	for _, command := range []string{"rm -rf /", "sudo reboot", "git push origin main"} {
+		if _, err := service.Run(context.Background(), command, "", 0); workspace.CategoryFromError(err) != workspace.ErrorCommandDenied {
+			t.Fatalf("%q error category = %q, want %q", command, workspace.CategoryFromError(err), workspace.ErrorCommandDenied)
+		}
+	}
+	if _, err := service.Run(context.Background(), "rm -rf build", "", 0); err != nil {
+		t.Fatalf("Run error for allowed command: %v", err)
+	}
+
+	if _, err := NewService(mustGuard(t), config.ExecConfig{EnableDenyPatterns: true, CustomDenyPatterns: []string{"("}}); err == nil {
+		t.Fatal("expected invalid custom deny pattern error")
+	}
+}
+
+func TestRunScrubsEnvironment(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-secret")
+	service, _ := mustService(t, config.ExecConfig{})
+
+	result, err := service.Run(context.Background(), "env", "", 0)
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if strings.Contains(result.Stdout, "sk-secret") {
+		t.Fatalf("environment leaked secret: %q", result.Stdout)
+	}
+	if !strings.Contains(result.Stdout, "PATH=") {
+		t.Fatalf("environment missing PATH: %q", result.Stdout)
+	}
+}
+
+func TestRunCapsOutputAndTimesOut(t *testing.T) {
+	service, _ := mustService(t, config.ExecConfig{MaxOutputBytes: 10})
+
+	result, err := service.Run(context.Background(), "printf '0123456789abcdef'", "", 0)
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if result.Stdout != "0123456789" || !result.Truncated {
+		t.Fatalf("result = %+v, want 10 bytes and Truncated", result)
+	}
+
+	result, err = service.Run(context.Background(), "sleep 5", "", 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if !result.TimedOut || result.Duration > 4*time.Second {
+		t.Fatalf("result = %+v, want quick timeout", result)
+	}
+}
+
+func mustService(t *testing.T, cfg config.ExecConfig) (*Service, *workspace.Guard) {
+	t.Helper()
+
+	guard := mustGuard(t)
+	service, err := NewService(guard, cfg)
+	if err != nil {
+		t.Fatalf("NewService error: %v", err)
+	}
+
+	return service, guard
+}
+
+func mustGuard(t *testing.T) *workspace.Guard {
+	t.Helper()
+
+	guard, err := workspace.NewGuard(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewGuard error: %v", err)
+	}
+
+	return guard
+}
@@ -0,0 +1,145 @@
+package fantasy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	core "charm.land/fantasy"
+
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+const (
+	ApprovalAsk   = "ask"
+	ApprovalAllow = "allow"
+
+	// DefaultApprovalTimeout bounds the wait for an answer when tools.approval.timeout_seconds is unset.
+	DefaultApprovalTimeout = 5 * time.Minute
+)
+
+// DestructiveTools are the tools that ask for approval by default: they write,
+// edit, delete, or execute.
+var DestructiveTools = []string{
+	"write_file",
+	"append_file",
+	"edit_file",
+	"apply_patch",
+	"copy_file",
+	"create_archive",
+	"extract_archive",
+	"remove_dir",
+	"run_command",
+	"undo_last_change",
+	"restore_file",
+}
+
+// ApprovalPolicy decides which tool calls need human confirmation.
+type ApprovalPolicy struct {
+	ask     map[string]bool
+	timeout time.Duration
+}
+
+// NewApprovalPolicy builds a policy from tools.approval config.
+//
+// It returns nil when approval is disabled.
+func NewApprovalPolicy(cfg config.ToolApprovalConfig) (*ApprovalPolicy, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	ask := make(map[string]bool, len(DestructiveTools)+len(cfg.Tools))
+	for _, name := range DestructiveTools {
+		ask[name] = true
+	}
+	for name, policy := range cfg.Tools {
+		switch strings.ToLower(strings.TrimSpace(policy)) {
+		case ApprovalAsk:
+			ask[strings.TrimSpace(name)] = true
+		case ApprovalAllow:
+			ask[strings.TrimSpace(name)] = false
+		default:
+			return nil, fmt.Errorf("unsupported tools.approval policy %q for %s (want ask or allow)", policy, name)
+		}
+	}
+
+	timeout := DefaultApprovalTimeout
+	if cfg.TimeoutSeconds > 0 {
+		timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+
+	return &ApprovalPolicy{ask: ask, timeout: timeout}, nil
+}
+
+// Requires reports whether calls to toolName must be approved.
+func (p *ApprovalPolicy) Requires(toolName string) bool {
+	return p != nil && p.ask[toolName]
+}
+
+// WrapWithApproval makes tools that the policy marks "ask" wait for a
+// context-carried providertypes.ToolApprover before running.
+//
+// Tools are returned unchanged when policy is nil.
+func WrapWithApproval(tools []core.AgentTool, policy *ApprovalPolicy) []core.AgentTool {
+	if policy == nil {
+		return tools
+	}
+
+	wrapped := make([]core.AgentTool, 0, len(tools))
+	for _, tool := range tools {
+		if !policy.Requires(tool.Info().Name) {
+			wrapped = append(wrapped, tool)
+			continue
+		}
+		wrapped = append(wrapped, &approvalTool{AgentTool: tool, timeout: policy.timeout})
+	}
+
+	return wrapped
+}
+
+// approvalTool asks for confirmation and only then delegates to the wrapped tool.
+//
+// Denials are returned as tool error responses so the model can adjust
+// instead of the whole run failing.
+type approvalTool struct {
+	core.AgentTool
+	timeout time.Duration
+}
+
+func (t *approvalTool) Run(ctx context.Context, params core.ToolCall) (core.ToolResponse, error) {
+	name := t.Info().Name
+	approver, ok := providertypes.ToolApproverFromContext(ctx)
+	if !ok {
+		logApproval(name, "unavailable")
+		return core.NewTextErrorResponse(fmt.Sprintf("%s requires user approval, but no one is available to approve it here. Do not retry; tell the user what you wanted to do instead.", name)), nil
+	}
+
+	approvalCtx, cancel := context.WithTimeout(ctx, t.timeout)
+	approved, err := approver(approvalCtx, providertypes.ToolApprovalRequest{Tool: name, Input: params.Input})
+	cancel()
+	if err != nil {
+		if ctx.Err() != nil {
+			return core.ToolResponse{}, ctx.Err()
+		}
+		logApproval(name, "no_answer")
+		return core.NewTextErrorResponse(fmt.Sprintf("%s was not approved: %v", name, err)), nil
+	}
+	if !approved {
+		logApproval(name, "denied")
+		return core.NewTextErrorResponse(fmt.Sprintf("The user denied %s. Do not retry it; ask the user how to proceed.", name)), nil
+	}
+
+	logApproval(name, "approved")
+	return t.AgentTool.Run(ctx, params)
+}
+
+func logApproval(toolName string, outcome string) {
+	slog.Default().Info("Tool approval",
+		"component", "provider.fantasy",
+		"tool", toolName,
+		"outcome", outcome,
+	)
+}
@@ -0,0 +1,89 @@
+package fantasy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	core "charm.land/fantasy"
+
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+func TestNewApprovalPolicyDefaultsAndOverrides(t *testing.T) {
+	policy, err := NewApprovalPolicy(config.ToolApprovalConfig{})
+	if err != nil || policy != nil {
+		t.Fatalf("NewApprovalPolicy(disabled) = %v, %v; want nil, nil", policy, err)
+	}
+
+	policy, err = NewApprovalPolicy(config.ToolApprovalConfig{
+		Enabled: true,
+		Tools:   map[string]string{"run_command": "allow", "read_file": "ASK"},
+	})
+	if err != nil {
+		t.Fatalf("NewApprovalPolicy error: %v", err)
+	}
+	for tool, want := range map[string]bool{
+		"write_file":  true,
+		"remove_dir":  true,
+		"run_command": false,
+		"read_file":   true,
+		"list_dir":    false,
+	} {
+		if got := policy.Requires(tool); got != want {
+			t.Fatalf("Requires(%q) = %v, want %v", tool, got, want)
+		}
+	}
+
+	if _, err := NewApprovalPolicy(config.ToolApprovalConfig{Enabled: true, Tools: map[string]string{"write_file": "maybe"}}); err == nil {
+		t.Fatal("expected unsupported policy error")
+	}
+}
+
+func TestWrapWithApprovalGatesToolRuns(t *testing.T) {
+	runs := 0
+	inner := core.NewAgentTool("write_file", "writes", func(context.Context, struct{}, core.ToolCall) (core.ToolResponse, error) {
+		runs++
+		return core.NewTextResponse("written"), nil
+	})
+	policy, err := NewApprovalPolicy(config.ToolApprovalConfig{Enabled: true})
+	if err != nil {
+		t.Fatalf("NewApprovalPolicy error: %v", err)
+	}
+	tool := WrapWithApproval([]core.AgentTool{inner}, policy)[0]
+	call := core.ToolCall{ID: "1", Name: "write_file", Input: `{"path":"a.txt"}`}
+
+	withAnswer := func(approved bool, err error) context.Context {
+		return providertypes.WithToolApprover(context.Background(), func(_ context.Context, request providertypes.ToolApprovalRequest) (bool, error) {
+			if request.Tool != "write_file" || request.Input != call.Input {
+				t.Fatalf("unexpected approval request: %+v", request)
+			}
+			return approved, err
+		})
+	}
+
+	for name, tc := range map[string]struct {
+		ctx     context.Context
+		want    string
+		wantRun bool
+	}{
+		"no approver": {ctx: context.Background(), want: "requires user approval"},
+		"denied":      {ctx: withAnswer(false, nil), want: "user denied write_file"},
+		"no answer":   {ctx: withAnswer(false, errors.New("timed out")), want: "not approved: timed out"},
+		"approved":    {ctx: withAnswer(true, nil), want: "written", wantRun: true},
+	} {
+		runs = 0
+		response, err := tool.Run(tc.ctx, call)
+		if err != nil {
+			t.Fatalf("%s: Run error: %v", name, err)
+		}
+		if !strings.Contains(strings.ToLower(response.Content), strings.ToLower(tc.want)) {
+			t.Fatalf("%s: response = %q, want %q", name, response.Content, tc.want)
+		}
+		if response.IsError == tc.wantRun || (runs == 1) != tc.wantRun {
+			t.Fatalf("%s: IsError = %v, runs = %d, wantRun = %v", name, response.IsError, runs, tc.wantRun)
+		}
+	}
+}
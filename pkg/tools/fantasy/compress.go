@@ -0,0 +1,142 @@
+package fantasy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	core "charm.land/fantasy"
+
+	"miniclaw/pkg/config"
+)
+
+const (
+	CompressionOff       = "off"
+	CompressionTruncate  = "truncate"
+	CompressionSummarize = "summarize"
+
+	DefaultMaxResultChars = 16 * 1024
+
+	// maxSummarizeInputFactor bounds how much of an oversized result is sent to the summarizer.
+	maxSummarizeInputFactor = 8
+)
+
+// Summarizer condenses an oversized tool result, for example with a model call.
+type Summarizer func(ctx context.Context, toolName string, text string) (string, error)
+
+// ResultCompressor shrinks oversized text tool results before the model sees them.
+type ResultCompressor struct {
+	mode      string
+	maxChars  int
+	headChars int
+	tailChars int
+	summarize Summarizer
+}
+
+// NewResultCompressor builds a compressor from tools.results config.
+//
+// It returns nil when compression is off. Summarize mode without a summarizer
+// falls back to truncation.
+func NewResultCompressor(cfg config.ToolResultsConfig, summarize Summarizer) (*ResultCompressor, error) {
+	mode := strings.ToLower(strings.TrimSpace(cfg.Mode))
+	switch mode {
+	case "", CompressionOff:
+		return nil, nil
+	case CompressionTruncate, CompressionSummarize:
+	default:
+		return nil, fmt.Errorf("unsupported tool result compression mode: %s", cfg.Mode)
+	}
+
+	maxChars := cfg.MaxChars
+	if maxChars <= 0 {
+		maxChars = DefaultMaxResultChars
+	}
+	headChars, tailChars := cfg.HeadChars, cfg.TailChars
+	if headChars <= 0 && tailChars <= 0 {
+		headChars = maxChars * 2 / 3
+		tailChars = maxChars - headChars
+	}
+	if headChars+tailChars > maxChars {
+		return nil, fmt.Errorf("tools.results head_chars + tail_chars (%d) exceed max_chars (%d)", headChars+tailChars, maxChars)
+	}
+
+	if mode == CompressionSummarize && summarize == nil {
+		mode = CompressionTruncate
+	}
+
+	return &ResultCompressor{
+		mode:      mode,
+		maxChars:  maxChars,
+		headChars: headChars,
+		tailChars: tailChars,
+		summarize: summarize,
+	}, nil
+}
+
+// Compress returns text unchanged when it fits, otherwise a summary or head/tail excerpt.
+func (c *ResultCompressor) Compress(ctx context.Context, toolName string, text string) string {
+	if c == nil || utf8.RuneCountInString(text) <= c.maxChars {
+		return text
+	}
+
+	if c.mode == CompressionSummarize {
+		input := elideMiddle(text, c.maxChars*maxSummarizeInputFactor*2/3, c.maxChars*maxSummarizeInputFactor/3)
+		summary, err := c.summarize(ctx, toolName, input)
+		summary = strings.TrimSpace(summary)
+		if err == nil && summary != "" && utf8.RuneCountInString(summary) <= c.maxChars {
+			return fmt.Sprintf("[summarized %d characters of %s output]\n%s", utf8.RuneCountInString(text), toolName, summary)
+		}
+		slog.Default().Debug("Tool result summarization failed; truncating instead",
+			"component", "provider.fantasy",
+			"tool", toolName,
+			"error", err,
+		)
+	}
+
+	return elideMiddle(text, c.headChars, c.tailChars)
+}
+
+// elideMiddle keeps the first head and last tail runes of text with a marker in between.
+func elideMiddle(text string, head int, tail int) string {
+	runes := []rune(text)
+	if len(runes) <= head+tail {
+		return text
+	}
+
+	elided := len(runes) - head - tail
+	return string(runes[:head]) + fmt.Sprintf("\n... [%d characters elided] ...\n", elided) + string(runes[len(runes)-tail:])
+}
+
+// WrapWithCompression applies compressor to every tool's text results.
+//
+// Tools are returned unchanged when compressor is nil.
+func WrapWithCompression(tools []core.AgentTool, compressor *ResultCompressor) []core.AgentTool {
+	if compressor == nil {
+		return tools
+	}
+
+	wrapped := make([]core.AgentTool, 0, len(tools))
+	for _, tool := range tools {
+		wrapped = append(wrapped, &compressedTool{AgentTool: tool, compressor: compressor})
+	}
+
+	return wrapped
+}
+
+// compressedTool delegates to the wrapped tool and compresses its text output.
+type compressedTool struct {
+	core.AgentTool
+	compressor *ResultCompressor
+}
+
+func (t *compressedTool) Run(ctx context.Context, params core.ToolCall) (core.ToolResponse, error) {
+	response, err := t.AgentTool.Run(ctx, params)
+	if err != nil || response.Type != "text" {
+		return response, err
+	}
+
+	response.Content = t.compressor.Compress(ctx, t.Info().Name, response.Content)
+	return response, nil
+}
@@ -0,0 +1,89 @@
+package fantasy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	core "charm.land/fantasy"
+
+	"miniclaw/pkg/config"
+)
+
+func TestResultCompressorTruncatesMiddle(t *testing.T) {
+	compressor, err := NewResultCompressor(config.ToolResultsConfig{Mode: "truncate", MaxChars: 10, HeadChars: 4, TailChars: 3}, nil)
+	if err != nil {
+		t.Fatalf("NewResultCompressor error: %v", err)
+	}
+
+	if got := compressor.Compress(context.Background(), "read_file", "short"); got != "short" {
+		t.Fatalf("Compress(short) = %q, want unchanged", got)
+	}
+
+	got := compressor.Compress(context.Background(), "read_file", "abcdefghijklmnopqrstuvwxyz")
+	want := "abcd\n... [19 characters elided] ...\nxyz"
+	if got != want {
+		t.Fatalf("Compress = %q, want %q", got, want)
+	}
+}
+
+func TestResultCompressorSummarizesAndFallsBack(t *testing.T) {
+	summarize := func(_ context.Context, toolName string, text string) (string, error) {
+		if strings.HasPrefix(text, "fail") {
+			return "", errors.New("model unavailable")
+		}
+		return "summary of " + toolName, nil
+	}
+	compressor, err := NewResultCompressor(config.ToolResultsConfig{Mode: "summarize", MaxChars: 40}, summarize)
+	if err != nil {
+		t.Fatalf("NewResultCompressor error: %v", err)
+	}
+
+	got := compressor.Compress(context.Background(), "search_files", strings.Repeat("x", 100))
+	if !strings.Contains(got, "summary of search_files") || !strings.HasPrefix(got, "[summarized 100 characters") {
+		t.Fatalf("Compress = %q, want summary", got)
+	}
+
+	got = compressor.Compress(context.Background(), "search_files", "fail"+strings.Repeat("y", 100))
+	if !strings.Contains(got, "characters elided") {
+		t.Fatalf("Compress = %q, want truncation fallback", got)
+	}
+}
+
+func TestResultCompressorOffAndInvalidConfig(t *testing.T) {
+	compressor, err := NewResultCompressor(config.ToolResultsConfig{}, nil)
+	if err != nil || compressor != nil {
+		t.Fatalf("NewResultCompressor(off) = %v, %v; want nil, nil", compressor, err)
+	}
+	if _, err := NewResultCompressor(config.ToolResultsConfig{Mode: "zip"}, nil); err == nil {
+		t.Fatal("expected unsupported mode error")
+	}
+	if _, err := NewResultCompressor(config.ToolResultsConfig{Mode: "truncate", MaxChars: 10, HeadChars: 8, TailChars: 8}, nil); err == nil {
+		t.Fatal("expected head+tail over max error")
+	}
+}
+
+func TestWrapWithCompressionKeepsToolIdentity(t *testing.T) {
+	inner := core.NewAgentTool("big", "returns a lot", func(context.Context, struct{}, core.ToolCall) (core.ToolResponse, error) {
+		return core.NewTextResponse(strings.Repeat("z", 50)), nil
+	})
+	compressor, err := NewResultCompressor(config.ToolResultsConfig{Mode: "truncate", MaxChars: 20}, nil)
+	if err != nil {
+		t.Fatalf("NewResultCompressor error: %v", err)
+	}
+
+	tools := WrapWithCompression([]core.AgentTool{inner}, compressor)
+	tools[0].SetProviderOptions(core.ProviderOptions{})
+	if tools[0].Info().Name != "big" || inner.ProviderOptions() == nil {
+		t.Fatal("wrapped tool does not delegate to inner tool")
+	}
+
+	response, err := tools[0].Run(context.Background(), core.ToolCall{ID: "1", Name: "big", Input: "{}"})
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if !strings.Contains(response.Content, "characters elided") {
+		t.Fatalf("response = %q, want compressed", response.Content)
+	}
+}
@@ -0,0 +1,69 @@
+package fantasy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	core "charm.land/fantasy"
+
+	providertypes "miniclaw/pkg/provider/types"
+	exectools "miniclaw/pkg/tools/exec"
+	"miniclaw/pkg/workspace"
+)
+
+type runCommandInput struct {
+	Command        string `json:"command" description:"Shell command to run with /bin/sh -c."`
+	Workdir        string `json:"workdir,omitempty" description:"Working directory relative to the workspace root. Defaults to '.' when omitted."`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty" description:"Override the default timeout for this command (capped at 600)."`
+}
+
+// BuildExecTools constructs the run_command tool for fantasy-agent.
+func BuildExecTools(service *exectools.Service, guard *workspace.Guard) []core.AgentTool {
+	if service == nil || guard == nil {
+		return nil
+	}
+
+	description := fmt.Sprintf("Run a shell command in the workspace and return its exit code, stdout, and stderr. Commands time out after %s by default; the environment is scrubbed of secrets.", service.Timeout())
+
+	return []core.AgentTool{
+		core.NewAgentTool("run_command", description, func(ctx context.Context, input runCommandInput, _ core.ToolCall) (core.ToolResponse, error) {
+			start := time.Now()
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "call", Tool: "run_command", Payload: toolEventPayload(input)})
+			result, err := service.Run(ctx, input.Command, input.Workdir, time.Duration(input.TimeoutSeconds)*time.Second)
+			if err != nil {
+				elapsed := time.Since(start)
+				logToolResult("run_command", input.Workdir, false, elapsed, workspace.CategoryFromError(err))
+				providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "run_command", Payload: err.Error(), Failed: true, DurationMs: elapsed.Milliseconds()})
+				return toolErrorResponse(err), nil
+			}
+
+			relDir := safeRelPath(guard, result.Dir)
+			summary := fmt.Sprintf("exit_code=%d dir=%s duration_ms=%d", result.ExitCode, relDir, result.Duration.Milliseconds())
+			if result.TimedOut {
+				summary = fmt.Sprintf("timed out after %s dir=%s", result.Duration.Round(time.Millisecond), relDir)
+			}
+			if result.Truncated {
+				summary += " (output truncated)"
+			}
+
+			var b strings.Builder
+			b.WriteString(summary)
+			b.WriteString("\n--- stdout ---\n")
+			b.WriteString(result.Stdout)
+			if result.Stderr != "" {
+				b.WriteString("\n--- stderr ---\n")
+				b.WriteString(result.Stderr)
+			}
+
+			elapsed := time.Since(start)
+			logToolResult("run_command", relDir, result.ExitCode == 0 && !result.TimedOut, elapsed, "")
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "run_command", Payload: summary, DurationMs: elapsed.Milliseconds()})
+			if result.TimedOut {
+				return core.NewTextErrorResponse(b.String()), nil
+			}
+			return core.NewTextResponse(b.String()), nil
+		}),
+	}
+}
@@ -17,7 +17,9 @@ import (
 )
 
 type readFileInput struct {
-	Path string `json:"path" description:"File path relative to the workspace root."`
+	Path   string `json:"path" description:"File path relative to the workspace root."`
+	Offset int    `json:"offset,omitempty" description:"1-based line number to start reading from. Use with limit to page through large files."`
+	Limit  int    `json:"limit,omitempty" description:"Maximum number of lines to return. When offset or limit is set, only that line range is read."`
 }
 
 type writeFileInput struct {
@@ -41,6 +43,59 @@ type editFileInput struct {
 	ReplaceAll bool   `json:"replace_all,omitempty" description:"Replace all matches when true. Default false requires exactly one match."`
 }
 
+type searchFilesInput struct {
+	Pattern string `json:"pattern" description:"Regular expression (RE2 syntax) or literal text to search for."`
+	Path    string `json:"path,omitempty" description:"File or directory to search, relative to the workspace root. Defaults to '.' when omitted."`
+	Literal bool   `json:"literal,omitempty" description:"Treat pattern as literal text instead of a regular expression."`
+}
+
+type makeDirInput struct {
+	Path string `json:"path" description:"Directory path relative to the workspace root. Missing parents are created."`
+}
+
+type removeDirInput struct {
+	Path      string `json:"path" description:"Directory path relative to the workspace root."`
+	Recursive bool   `json:"recursive,omitempty" description:"Remove the directory and all of its contents when true. Default false only removes empty directories."`
+}
+
+type applyPatchInput struct {
+	Patch string `json:"patch" description:"Unified diff (git diff or diff -u format) with ---/+++ file headers and @@ hunks. Paths are relative to the workspace root."`
+}
+
+type fileInfoInput struct {
+	Path string `json:"path" description:"File or directory path relative to the workspace root."`
+}
+
+type copyFileInput struct {
+	Source       string `json:"source" description:"Source file path relative to the workspace root."`
+	Destination  string `json:"destination" description:"Destination file path relative to the workspace root. Missing parents are created."`
+	Overwrite    bool   `json:"overwrite,omitempty" description:"Replace the destination when it already exists. Default false fails instead."`
+	PreserveMode bool   `json:"preserve_mode,omitempty" description:"Keep the source file permissions instead of using 0644."`
+}
+
+type hashFileInput struct {
+	Path      string `json:"path" description:"File path relative to the workspace root."`
+	Algorithm string `json:"algorithm,omitempty" description:"Hash algorithm: sha256 (default) or md5."`
+}
+
+type createArchiveInput struct {
+	Path      string   `json:"path" description:"Archive path relative to the workspace root. The extension picks the format: .zip, .tar.gz, or .tgz."`
+	Sources   []string `json:"sources" description:"Files or directories to add, relative to the workspace root. Directories are added recursively under their own name."`
+	Overwrite bool     `json:"overwrite,omitempty" description:"Replace the archive when it already exists. Default false fails instead."`
+}
+
+type extractArchiveInput struct {
+	Path        string `json:"path" description:"Archive (.zip, .tar.gz, or .tgz) relative to the workspace root."`
+	Destination string `json:"destination,omitempty" description:"Directory to extract into, relative to the workspace root. Defaults to '.' when omitted; missing directories are created."`
+	Overwrite   bool   `json:"overwrite,omitempty" description:"Replace existing files. Default false fails before anything is written when a file already exists."`
+}
+
+type undoLastChangeInput struct{}
+
+type restoreFileInput struct {
+	Path string `json:"path" description:"File path relative to the workspace root."`
+}
+
 // BuildFSTools constructs the phase-1 filesystem tools for fantasy-agent.
 func BuildFSTools(service *fstools.Service, guard *workspace.Guard) []core.AgentTool {
 	if service == nil || guard == nil {
@@ -48,22 +103,39 @@ func BuildFSTools(service *fstools.Service, guard *workspace.Guard) []core.Agent
 	}
 
 	tools := []core.AgentTool{
-		core.NewAgentTool("read_file", "Read a UTF-8 text file from the workspace.", func(ctx context.Context, input readFileInput, _ core.ToolCall) (core.ToolResponse, error) {
+		core.NewAgentTool("read_file", "Read a UTF-8 text file from the workspace. Pass offset/limit to read a line range of large files.", func(ctx context.Context, input readFileInput, _ core.ToolCall) (core.ToolResponse, error) {
 			start := time.Now()
 			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "call", Tool: "read_file", Payload: toolEventPayload(input)})
-			result, err := service.ReadFile(ctx, input.Path)
+			ranged := input.Offset > 0 || input.Limit > 0
+			var result fstools.ReadResult
+			var err error
+			if ranged {
+				result, err = service.ReadFileLines(ctx, input.Path, input.Offset, input.Limit)
+			} else {
+				result, err = service.ReadFile(ctx, input.Path)
+			}
 			if err != nil {
 				elapsed := time.Since(start)
 				logToolResult("read_file", input.Path, false, elapsed, workspace.CategoryFromError(err))
-				providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "read_file", Payload: err.Error(), DurationMs: elapsed.Milliseconds()})
+				providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "read_file", Payload: err.Error(), Failed: true, DurationMs: elapsed.Milliseconds()})
 				return toolErrorResponse(err), nil
 			}
 
 			relPath := safeRelPath(guard, result.Path)
+			summary := fmt.Sprintf("ok: read %d bytes from %s", result.Bytes, relPath)
+			if ranged {
+				summary = fmt.Sprintf("ok: read lines %d-%d (%d bytes) from %s", result.StartLine, result.EndLine, result.Bytes, relPath)
+				if result.PartialLine {
+					summary += fmt.Sprintf("; line %d is longer than max_read_bytes and was cut", result.EndLine)
+				}
+				if result.HasMore {
+					summary += fmt.Sprintf("; more lines follow, continue with offset=%d", result.EndLine+1)
+				}
+			}
 			elapsed := time.Since(start)
 			logToolResult("read_file", relPath, true, elapsed, "")
-			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "read_file", Payload: fmt.Sprintf("ok: read %d bytes from %s", result.Bytes, relPath), DurationMs: elapsed.Milliseconds()})
-			return core.NewTextResponse(fmt.Sprintf("ok: read %d bytes from %s\n%s", result.Bytes, relPath, result.Content)), nil
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "read_file", Payload: summary, DurationMs: elapsed.Milliseconds()})
+			return core.NewTextResponse(fmt.Sprintf("%s\n%s", summary, result.Content)), nil
 		}),
 		core.NewAgentTool("write_file", "Write a full text file inside the workspace.", func(ctx context.Context, input writeFileInput, _ core.ToolCall) (core.ToolResponse, error) {
 			start := time.Now()
@@ -72,7 +144,7 @@ func BuildFSTools(service *fstools.Service, guard *workspace.Guard) []core.Agent
 			if err != nil {
 				elapsed := time.Since(start)
 				logToolResult("write_file", input.Path, false, elapsed, workspace.CategoryFromError(err))
-				providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "write_file", Payload: err.Error(), DurationMs: elapsed.Milliseconds()})
+				providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "write_file", Payload: err.Error(), Failed: true, DurationMs: elapsed.Milliseconds()})
 				return toolErrorResponse(err), nil
 			}
 
@@ -89,7 +161,7 @@ func BuildFSTools(service *fstools.Service, guard *workspace.Guard) []core.Agent
 			if err != nil {
 				elapsed := time.Since(start)
 				logToolResult("append_file", input.Path, false, elapsed, workspace.CategoryFromError(err))
-				providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "append_file", Payload: err.Error(), DurationMs: elapsed.Milliseconds()})
+				providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "append_file", Payload: err.Error(), Failed: true, DurationMs: elapsed.Milliseconds()})
 				return toolErrorResponse(err), nil
 			}
 
@@ -106,7 +178,7 @@ func BuildFSTools(service *fstools.Service, guard *workspace.Guard) []core.Agent
 			if err != nil {
 				elapsed := time.Since(start)
 				logToolResult("list_dir", input.Path, false, elapsed, workspace.CategoryFromError(err))
-				providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "list_dir", Payload: err.Error(), DurationMs: elapsed.Milliseconds()})
+				providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "list_dir", Payload: err.Error(), Failed: true, DurationMs: elapsed.Milliseconds()})
 				return toolErrorResponse(err), nil
 			}
 
@@ -136,7 +208,7 @@ func BuildFSTools(service *fstools.Service, guard *workspace.Guard) []core.Agent
 			if err != nil {
 				elapsed := time.Since(start)
 				logToolResult("edit_file", input.Path, false, elapsed, workspace.CategoryFromError(err))
-				providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "edit_file", Payload: err.Error(), DurationMs: elapsed.Milliseconds()})
+				providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "edit_file", Payload: err.Error(), Failed: true, DurationMs: elapsed.Milliseconds()})
 				return toolErrorResponse(err), nil
 			}
 
@@ -146,11 +218,253 @@ func BuildFSTools(service *fstools.Service, guard *workspace.Guard) []core.Agent
 			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "edit_file", Payload: fmt.Sprintf("ok: replaced %d match(es) in %s", result.ReplacedCount, relPath), DurationMs: elapsed.Milliseconds()})
 			return core.NewTextResponse(fmt.Sprintf("ok: replaced %d match(es) in %s", result.ReplacedCount, relPath)), nil
 		}),
+		core.NewAgentTool("search_files", "Search workspace text files for a regex or literal pattern and return matching lines.", func(ctx context.Context, input searchFilesInput, _ core.ToolCall) (core.ToolResponse, error) {
+			start := time.Now()
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "call", Tool: "search_files", Payload: toolEventPayload(input)})
+			result, err := service.SearchFiles(ctx, input.Path, input.Pattern, input.Literal)
+			if err != nil {
+				elapsed := time.Since(start)
+				logToolResult("search_files", input.Path, false, elapsed, workspace.CategoryFromError(err))
+				providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "search_files", Payload: err.Error(), Failed: true, DurationMs: elapsed.Milliseconds()})
+				return toolErrorResponse(err), nil
+			}
+
+			relPath := safeRelPath(guard, result.Path)
+			summary := fmt.Sprintf("ok: found %d match(es) in %s (scanned %d files)", len(result.Matches), relPath, result.FilesScanned)
+			if result.Truncated {
+				summary += " (truncated)"
+			}
+			var b strings.Builder
+			b.WriteString(summary)
+			for _, match := range result.Matches {
+				fmt.Fprintf(&b, "\n%s:%d: %s", safeRelPath(guard, match.Path), match.Line, match.Snippet)
+			}
+			elapsed := time.Since(start)
+			logToolResult("search_files", relPath, true, elapsed, "")
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "search_files", Payload: summary, DurationMs: elapsed.Milliseconds()})
+
+			return core.NewTextResponse(b.String()), nil
+		}),
+		core.NewAgentTool("make_dir", "Create a directory (and missing parents) inside the workspace.", func(ctx context.Context, input makeDirInput, _ core.ToolCall) (core.ToolResponse, error) {
+			start := time.Now()
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "call", Tool: "make_dir", Payload: toolEventPayload(input)})
+			result, err := service.MakeDir(ctx, input.Path)
+			if err != nil {
+				elapsed := time.Since(start)
+				logToolResult("make_dir", input.Path, false, elapsed, workspace.CategoryFromError(err))
+				providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "make_dir", Payload: err.Error(), Failed: true, DurationMs: elapsed.Milliseconds()})
+				return toolErrorResponse(err), nil
+			}
+
+			relPath := safeRelPath(guard, result.Path)
+			summary := fmt.Sprintf("ok: created directory %s", relPath)
+			if !result.Created {
+				summary = fmt.Sprintf("ok: directory %s already exists", relPath)
+			}
+			elapsed := time.Since(start)
+			logToolResult("make_dir", relPath, true, elapsed, "")
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "make_dir", Payload: summary, DurationMs: elapsed.Milliseconds()})
+			return core.NewTextResponse(summary), nil
+		}),
+		core.NewAgentTool("remove_dir", "Remove a directory inside the workspace. Non-empty directories require recursive=true.", func(ctx context.Context, input removeDirInput, _ core.ToolCall) (core.ToolResponse, error) {
+			start := time.Now()
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "call", Tool: "remove_dir", Payload: toolEventPayload(input)})
+			result, err := service.RemoveDir(ctx, input.Path, input.Recursive)
+			if err != nil {
+				elapsed := time.Since(start)
+				logToolResult("remove_dir", input.Path, false, elapsed, workspace.CategoryFromError(err))
+				providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "remove_dir", Payload: err.Error(), Failed: true, DurationMs: elapsed.Milliseconds()})
+				return toolErrorResponse(err), nil
+			}
+
+			relPath := safeRelPath(guard, result.Path)
+			summary := fmt.Sprintf("ok: removed directory %s (%d entries)", relPath, result.EntriesRemoved)
+			elapsed := time.Since(start)
+			logToolResult("remove_dir", relPath, true, elapsed, "")
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "remove_dir", Payload: summary, DurationMs: elapsed.Milliseconds()})
+			return core.NewTextResponse(summary), nil
+		}),
+		core.NewAgentTool("apply_patch", "Apply a unified diff to one or more workspace files. All hunks must apply or nothing is written; rejected hunks are reported individually.", func(ctx context.Context, input applyPatchInput, _ core.ToolCall) (core.ToolResponse, error) {
+			start := time.Now()
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "call", Tool: "apply_patch", Payload: toolEventPayload(input)})
+			result, err := service.ApplyPatch(ctx, input.Patch)
+			if err != nil {
+				elapsed := time.Since(start)
+				logToolResult("apply_patch", ".", false, elapsed, workspace.CategoryFromError(err))
+				providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "apply_patch", Payload: err.Error(), Failed: true, DurationMs: elapsed.Milliseconds()})
+				return toolErrorResponse(err), nil
+			}
+
+			hunks := 0
+			for _, file := range result.Files {
+				hunks += file.HunksApplied
+			}
+			var b strings.Builder
+			summary := fmt.Sprintf("ok: applied %d hunk(s) to %d file(s)", hunks, len(result.Files))
+			b.WriteString(summary)
+			for _, file := range result.Files {
+				fmt.Fprintf(&b, "\n- %s %s (%d hunk(s))", file.Operation, safeRelPath(guard, file.Path), file.HunksApplied)
+			}
+			elapsed := time.Since(start)
+			logToolResult("apply_patch", ".", true, elapsed, "")
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "apply_patch", Payload: summary, DurationMs: elapsed.Milliseconds()})
+			return core.NewTextResponse(b.String()), nil
+		}),
+		core.NewAgentTool("file_info", "Return type, size, permissions, and modification time for a workspace path without reading it.", func(ctx context.Context, input fileInfoInput, _ core.ToolCall) (core.ToolResponse, error) {
+			start := time.Now()
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "call", Tool: "file_info", Payload: toolEventPayload(input)})
+			result, err := service.FileInfo(ctx, input.Path)
+			if err != nil {
+				elapsed := time.Since(start)
+				logToolResult("file_info", input.Path, false, elapsed, workspace.CategoryFromError(err))
+				providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "file_info", Payload: err.Error(), Failed: true, DurationMs: elapsed.Milliseconds()})
+				return toolErrorResponse(err), nil
+			}
+
+			relPath := safeRelPath(guard, result.Path)
+			summary := fmt.Sprintf("ok: %s %s size=%d mode=%s modified=%s", result.Type, relPath, result.Size, result.Mode, result.ModTime.Format(time.RFC3339))
+			if result.Type == "file" && !result.Readable {
+				summary += " (exceeds max_read_bytes; use read_file offset/limit)"
+			}
+			elapsed := time.Since(start)
+			logToolResult("file_info", relPath, true, elapsed, "")
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "file_info", Payload: summary, DurationMs: elapsed.Milliseconds()})
+			return core.NewTextResponse(summary), nil
+		}),
+		core.NewAgentTool("copy_file", "Copy a file inside the workspace, for example to back it up before editing. Existing destinations require overwrite=true.", func(ctx context.Context, input copyFileInput, _ core.ToolCall) (core.ToolResponse, error) {
+			start := time.Now()
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "call", Tool: "copy_file", Payload: toolEventPayload(input)})
+			result, err := service.CopyFile(ctx, input.Source, input.Destination, input.Overwrite, input.PreserveMode)
+			if err != nil {
+				elapsed := time.Since(start)
+				logToolResult("copy_file", input.Destination, false, elapsed, workspace.CategoryFromError(err))
+				providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "copy_file", Payload: err.Error(), Failed: true, DurationMs: elapsed.Milliseconds()})
+				return toolErrorResponse(err), nil
+			}
+
+			relSource := safeRelPath(guard, result.Source)
+			relDestination := safeRelPath(guard, result.Destination)
+			summary := fmt.Sprintf("ok: copied %d bytes from %s to %s", result.BytesCopied, relSource, relDestination)
+			if result.Overwrote {
+				summary += " (overwrote existing file)"
+			}
+			elapsed := time.Since(start)
+			logToolResult("copy_file", relDestination, true, elapsed, "")
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "copy_file", Payload: summary, DurationMs: elapsed.Milliseconds()})
+			return core.NewTextResponse(summary), nil
+		}),
+		core.NewAgentTool("hash_file", "Compute the sha256 or md5 checksum of a workspace file, for example to verify a download or detect changes between steps.", func(ctx context.Context, input hashFileInput, _ core.ToolCall) (core.ToolResponse, error) {
+			start := time.Now()
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "call", Tool: "hash_file", Payload: toolEventPayload(input)})
+			result, err := service.HashFile(ctx, input.Path, input.Algorithm)
+			if err != nil {
+				elapsed := time.Since(start)
+				logToolResult("hash_file", input.Path, false, elapsed, workspace.CategoryFromError(err))
+				providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "hash_file", Payload: err.Error(), Failed: true, DurationMs: elapsed.Milliseconds()})
+				return toolErrorResponse(err), nil
+			}
+
+			relPath := safeRelPath(guard, result.Path)
+			summary := fmt.Sprintf("ok: %s %s  %s (%d bytes)", result.Algorithm, result.Digest, relPath, result.Size)
+			elapsed := time.Since(start)
+			logToolResult("hash_file", relPath, true, elapsed, "")
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "hash_file", Payload: summary, DurationMs: elapsed.Milliseconds()})
+			return core.NewTextResponse(summary), nil
+		}),
+		core.NewAgentTool("create_archive", "Pack workspace files and directories into a .zip or .tar.gz archive (like zip -r or tar czf). Symlinks are skipped.", func(ctx context.Context, input createArchiveInput, _ core.ToolCall) (core.ToolResponse, error) {
+			start := time.Now()
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "call", Tool: "create_archive", Payload: toolEventPayload(input)})
+			result, err := service.CreateArchive(ctx, input.Path, input.Sources, input.Overwrite)
+			if err != nil {
+				elapsed := time.Since(start)
+				logToolResult("create_archive", input.Path, false, elapsed, workspace.CategoryFromError(err))
+				providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "create_archive", Payload: err.Error(), Failed: true, DurationMs: elapsed.Milliseconds()})
+				return toolErrorResponse(err), nil
+			}
+
+			relPath := safeRelPath(guard, result.Path)
+			summary := fmt.Sprintf("ok: created %s archive %s with %d entries (%d bytes uncompressed)", result.Format, relPath, result.Entries, result.Bytes)
+			if result.Skipped > 0 {
+				summary += fmt.Sprintf("; skipped %d symlinks or special files", result.Skipped)
+			}
+			if result.Overwrote {
+				summary += " (overwrote existing archive)"
+			}
+			elapsed := time.Since(start)
+			logToolResult("create_archive", relPath, true, elapsed, "")
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "create_archive", Payload: summary, DurationMs: elapsed.Milliseconds()})
+			return core.NewTextResponse(summary), nil
+		}),
+		core.NewAgentTool("extract_archive", "Extract a .zip or .tar.gz archive into a workspace directory (like unzip or tar xzf). Entries that would land outside the destination, symlinks, and existing files without overwrite=true are refused before anything is written.", func(ctx context.Context, input extractArchiveInput, _ core.ToolCall) (core.ToolResponse, error) {
+			start := time.Now()
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "call", Tool: "extract_archive", Payload: toolEventPayload(input)})
+			result, err := service.ExtractArchive(ctx, input.Path, input.Destination, input.Overwrite)
+			if err != nil {
+				elapsed := time.Since(start)
+				logToolResult("extract_archive", input.Path, false, elapsed, workspace.CategoryFromError(err))
+				providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "extract_archive", Payload: err.Error(), Failed: true, DurationMs: elapsed.Milliseconds()})
+				return toolErrorResponse(err), nil
+			}
+
+			relPath := safeRelPath(guard, result.Path)
+			relDestination := safeRelPath(guard, result.Destination)
+			summary := fmt.Sprintf("ok: extracted %d entries (%d bytes) from %s into %s", result.Entries, result.Bytes, relPath, relDestination)
+			if result.Overwrote {
+				summary += " (overwrote existing files)"
+			}
+			elapsed := time.Since(start)
+			logToolResult("extract_archive", relDestination, true, elapsed, "")
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "extract_archive", Payload: summary, DurationMs: elapsed.Milliseconds()})
+			return core.NewTextResponse(summary), nil
+		}),
+	}
+	if service.SnapshotsEnabled() {
+		tools = append(tools, buildSnapshotTools(service)...)
 	}
 
 	return tools
 }
 
+// buildSnapshotTools constructs undo_last_change and restore_file over the service's file snapshots.
+func buildSnapshotTools(service *fstools.Service) []core.AgentTool {
+	return []core.AgentTool{
+		core.NewAgentTool("undo_last_change", "Roll back the most recent file change made by a workspace tool (write, edit, patch, copy, archive, or recursive remove_dir). Call it again to step further back.", func(ctx context.Context, input undoLastChangeInput, _ core.ToolCall) (core.ToolResponse, error) {
+			start := time.Now()
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "call", Tool: "undo_last_change", Payload: toolEventPayload(input)})
+			result, err := service.UndoLastChange(ctx)
+			if err != nil {
+				elapsed := time.Since(start)
+				logToolResult("undo_last_change", "", false, elapsed, workspace.CategoryFromError(err))
+				providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "undo_last_change", Payload: err.Error(), Failed: true, DurationMs: elapsed.Milliseconds()})
+				return toolErrorResponse(err), nil
+			}
+
+			summary := "ok: undid " + fstools.DescribeRestore(result)
+			elapsed := time.Since(start)
+			logToolResult("undo_last_change", "", true, elapsed, "")
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "undo_last_change", Payload: summary, DurationMs: elapsed.Milliseconds()})
+			return core.NewTextResponse(summary), nil
+		}),
+		core.NewAgentTool("restore_file", "Restore one workspace file to how it was before the most recent tool change to it. A file the change created is deleted. The restore can itself be reverted with undo_last_change.", func(ctx context.Context, input restoreFileInput, _ core.ToolCall) (core.ToolResponse, error) {
+			start := time.Now()
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "call", Tool: "restore_file", Payload: toolEventPayload(input)})
+			result, err := service.RestoreFile(ctx, input.Path)
+			if err != nil {
+				elapsed := time.Since(start)
+				logToolResult("restore_file", input.Path, false, elapsed, workspace.CategoryFromError(err))
+				providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "restore_file", Payload: err.Error(), Failed: true, DurationMs: elapsed.Milliseconds()})
+				return toolErrorResponse(err), nil
+			}
+
+			summary := "ok: restored from " + fstools.DescribeRestore(result)
+			elapsed := time.Since(start)
+			logToolResult("restore_file", input.Path, true, elapsed, "")
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "restore_file", Payload: summary, DurationMs: elapsed.Milliseconds()})
+			return core.NewTextResponse(summary), nil
+		}),
+	}
+}
+
 func toolErrorResponse(err error) core.ToolResponse {
 	if err == nil {
 		return core.NewTextErrorResponse(workspace.ErrorIO + ": unknown error")
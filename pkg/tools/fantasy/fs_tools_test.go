@@ -19,8 +19,8 @@ func TestBuildFSToolsRegistersExpectedNames(t *testing.T) {
 	}
 
 	tools := BuildFSTools(fstools.NewService(guard), guard)
-	if len(tools) != 5 {
-		t.Fatalf("tool count = %d, want 5", len(tools))
+	if len(tools) != 14 {
+		t.Fatalf("tool count = %d, want 14", len(tools))
 	}
 
 	names := make([]string, 0, len(tools))
@@ -28,7 +28,7 @@ func TestBuildFSToolsRegistersExpectedNames(t *testing.T) {
 		names = append(names, tool.Info().Name)
 	}
 
-	want := []string{"read_file", "write_file", "append_file", "list_dir", "edit_file"}
+	want := []string{"read_file", "write_file", "append_file", "list_dir", "edit_file", "search_files", "make_dir", "remove_dir", "apply_patch", "file_info", "copy_file", "hash_file", "create_archive", "extract_archive"}
 	for i := range want {
 		if names[i] != want[i] {
 			t.Fatalf("tool[%d] name = %q, want %q", i, names[i], want[i])
@@ -39,6 +39,23 @@ func TestBuildFSToolsRegistersExpectedNames(t *testing.T) {
 	}
 }
 
+func TestBuildFSToolsAddsUndoToolsWithSnapshots(t *testing.T) {
+	guard, err := workspace.NewGuard(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewGuard error: %v", err)
+	}
+	service := fstools.NewService(guard)
+	service.EnableSnapshots()
+
+	tools := BuildFSTools(service, guard)
+	if len(tools) != 16 {
+		t.Fatalf("tool count = %d, want 16", len(tools))
+	}
+	if got := tools[14].Info().Name + "," + tools[15].Info().Name; got != "undo_last_change,restore_file" {
+		t.Fatalf("snapshot tools = %s, want undo_last_change,restore_file", got)
+	}
+}
+
 func TestBuildFSToolsSchemaHasRequiredPath(t *testing.T) {
 	guard, err := workspace.NewGuard(t.TempDir())
 	if err != nil {
@@ -0,0 +1,65 @@
+package fantasy
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	core "charm.land/fantasy"
+
+	"miniclaw/pkg/tools/mcp"
+)
+
+// MCPServerTools exposes agent tools to MCP clients under their own names.
+//
+// Wrappers such as approval and metering keep applying, so served tools stay
+// behind the same workspace guard and policies as the agent's.
+func MCPServerTools(tools []core.AgentTool) []mcp.ServerTool {
+	var nextCallID atomic.Int64
+	served := make([]mcp.ServerTool, 0, len(tools))
+	for _, tool := range tools {
+		info := tool.Info()
+		properties, required := info.Parameters, info.Required
+		if properties == nil {
+			properties = map[string]any{}
+		}
+		if required == nil {
+			required = []string{}
+		}
+		schema, _ := json.Marshal(map[string]any{"type": "object", "properties": properties, "required": required})
+
+		served = append(served, mcp.ServerTool{
+			Tool: mcp.Tool{Name: info.Name, Description: info.Description, InputSchema: schema},
+			Call: func(ctx context.Context, arguments json.RawMessage) (mcp.CallResult, error) {
+				resp, err := tool.Run(ctx, core.ToolCall{
+					ID:    fmt.Sprintf("mcp-%d", nextCallID.Add(1)),
+					Name:  info.Name,
+					Input: string(arguments),
+				})
+				if err != nil {
+					return mcp.CallResult{}, err
+				}
+
+				return mcp.CallResult{Content: []mcp.Content{mcpContent(resp)}, IsError: resp.IsError}, nil
+			},
+		})
+	}
+
+	return served
+}
+
+// mcpContent converts a tool response to an MCP content block.
+func mcpContent(resp core.ToolResponse) mcp.Content {
+	if len(resp.Data) > 0 {
+		for _, kind := range []string{"image", "audio"} {
+			if strings.HasPrefix(resp.MediaType, kind+"/") {
+				return mcp.Content{Type: kind, Data: base64.StdEncoding.EncodeToString(resp.Data), MimeType: resp.MediaType}
+			}
+		}
+	}
+
+	return mcp.Content{Type: "text", Text: resp.Content}
+}
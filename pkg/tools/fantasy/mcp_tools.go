@@ -0,0 +1,191 @@
+package fantasy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	core "charm.land/fantasy"
+
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+	"miniclaw/pkg/tools/mcp"
+)
+
+const (
+	// mcpToolSeparator joins server and tool names so MCP tools never collide with built-in ones.
+	mcpToolSeparator = "__"
+	// maxToolNameLength is the longest tool name providers accept.
+	maxToolNameLength = 64
+)
+
+// ConnectMCPTools connects the enabled tools.mcp servers and returns their tools.
+//
+// A server that fails to start or list its tools is logged and skipped so one
+// broken server does not keep the agent from starting; invalid config is an
+// error. The returned clients should be closed on shutdown.
+func ConnectMCPTools(ctx context.Context, cfg config.MCPConfig) ([]core.AgentTool, []*mcp.Client, error) {
+	seen := make(map[string]struct{}, len(cfg.Servers))
+	for index, server := range cfg.Servers {
+		name := strings.TrimSpace(server.Name)
+		if name == "" {
+			return nil, nil, fmt.Errorf("tools.mcp.servers[%d]: name is required", index)
+		}
+		if _, ok := seen[name]; ok {
+			return nil, nil, fmt.Errorf("tools.mcp.servers[%d]: duplicate name %q", index, name)
+		}
+		seen[name] = struct{}{}
+	}
+
+	log := slog.Default().With("component", "provider.fantasy")
+	var tools []core.AgentTool
+	var clients []*mcp.Client
+	for _, server := range cfg.Servers {
+		if server.Disabled {
+			continue
+		}
+		name := strings.TrimSpace(server.Name)
+
+		client, err := mcp.Connect(ctx, server)
+		if err != nil {
+			log.Warn("Skipping MCP server", "server", name, "error", err)
+			continue
+		}
+		remoteTools, err := client.ListTools(ctx)
+		if err != nil {
+			log.Warn("Skipping MCP server", "server", name, "error", err)
+			_ = client.Close()
+			continue
+		}
+
+		clients = append(clients, client)
+		tools = append(tools, BuildMCPTools(client, remoteTools)...)
+		log.Info("MCP server connected", "server", name, "tools", len(remoteTools))
+	}
+
+	return tools, clients, nil
+}
+
+// BuildMCPTools adapts the tools one MCP server advertises to fantasy tools named "<server>__<tool>".
+func BuildMCPTools(client *mcp.Client, remoteTools []mcp.Tool) []core.AgentTool {
+	tools := make([]core.AgentTool, 0, len(remoteTools))
+	for _, remote := range remoteTools {
+		parameters, required := mcpSchema(remote.InputSchema)
+		tools = append(tools, &mcpTool{
+			client:     client,
+			remote:     remote,
+			name:       MCPToolName(client.Name(), remote.Name),
+			parameters: parameters,
+			required:   required,
+		})
+	}
+
+	return tools
+}
+
+// MCPToolName builds the agent-facing name for a server tool, keeping only
+// characters providers accept in tool names.
+func MCPToolName(server string, tool string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, server+mcpToolSeparator+tool)
+	if len(name) > maxToolNameLength {
+		name = name[:maxToolNameLength]
+	}
+
+	return name
+}
+
+// mcpSchema splits an MCP input schema into fantasy's parameter and required lists.
+func mcpSchema(raw json.RawMessage) (map[string]any, []string) {
+	var schema struct {
+		Properties map[string]any `json:"properties"`
+		Required   []string       `json:"required"`
+	}
+	if len(raw) > 0 {
+		_ = json.Unmarshal(raw, &schema)
+	}
+	if schema.Properties == nil {
+		schema.Properties = map[string]any{}
+	}
+	if schema.Required == nil {
+		schema.Required = []string{}
+	}
+
+	return schema.Properties, schema.Required
+}
+
+// mcpTool forwards calls to one tool on an MCP server.
+//
+// Server and transport failures are returned as tool error responses so the
+// model can carry on without the tool.
+type mcpTool struct {
+	client          *mcp.Client
+	remote          mcp.Tool
+	name            string
+	parameters      map[string]any
+	required        []string
+	providerOptions core.ProviderOptions
+}
+
+func (t *mcpTool) Info() core.ToolInfo {
+	description := t.remote.Description
+	if description == "" {
+		description = fmt.Sprintf("%s tool from the %s MCP server.", t.remote.Name, t.client.Name())
+	}
+
+	return core.ToolInfo{
+		Name:        t.name,
+		Description: description,
+		Parameters:  t.parameters,
+		Required:    t.required,
+	}
+}
+
+func (t *mcpTool) ProviderOptions() core.ProviderOptions {
+	return t.providerOptions
+}
+
+func (t *mcpTool) SetProviderOptions(opts core.ProviderOptions) {
+	t.providerOptions = opts
+}
+
+func (t *mcpTool) Run(ctx context.Context, params core.ToolCall) (core.ToolResponse, error) {
+	start := time.Now()
+	providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "call", Tool: t.name, Payload: params.Input})
+
+	result, err := t.client.CallTool(ctx, t.remote.Name, json.RawMessage(params.Input))
+	elapsed := time.Since(start)
+	if err != nil {
+		t.logResult(false, elapsed)
+		providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: t.name, Payload: err.Error(), Failed: true, DurationMs: elapsed.Milliseconds()})
+		return core.NewTextErrorResponse(fmt.Sprintf("mcp server %s: %v", t.client.Name(), err)), nil
+	}
+
+	text := result.Text()
+	t.logResult(!result.IsError, elapsed)
+	providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: t.name, Payload: text, Failed: result.IsError, DurationMs: elapsed.Milliseconds()})
+	if result.IsError {
+		return core.NewTextErrorResponse(text), nil
+	}
+
+	return core.NewTextResponse(text), nil
+}
+
+func (t *mcpTool) logResult(success bool, duration time.Duration) {
+	slog.Default().Debug("Fantasy tool execution",
+		"component", "provider.fantasy",
+		"tool", t.name,
+		"mcp_server", t.client.Name(),
+		"success", success,
+		"duration_ms", duration.Milliseconds(),
+	)
+}
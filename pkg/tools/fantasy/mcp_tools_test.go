@@ -0,0 +1,74 @@
+package fantasy
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	core "charm.land/fantasy"
+
+	"miniclaw/pkg/config"
+)
+
+func TestMCPToolNameSanitizesAndBounds(t *testing.T) {
+	if got := MCPToolName("git hub", "create.issue"); got != "git_hub__create_issue" {
+		t.Fatalf("MCPToolName = %q, want git_hub__create_issue", got)
+	}
+	if got := MCPToolName("server", strings.Repeat("x", 100)); len(got) != maxToolNameLength {
+		t.Fatalf("len(MCPToolName) = %d, want %d", len(got), maxToolNameLength)
+	}
+}
+
+func TestMCPSchemaSplitsPropertiesAndRequired(t *testing.T) {
+	parameters, required := mcpSchema(json.RawMessage(`{"type":"object","properties":{"query":{"type":"string"}},"required":["query"]}`))
+	if _, ok := parameters["query"]; !ok || len(required) != 1 || required[0] != "query" {
+		t.Fatalf("mcpSchema = %v, %v; want query property and requirement", parameters, required)
+	}
+
+	parameters, required = mcpSchema(nil)
+	if parameters == nil || required == nil {
+		t.Fatal("mcpSchema(nil) should return empty, non-nil values")
+	}
+}
+
+func TestConnectMCPToolsValidatesNamesAndSkipsBrokenServers(t *testing.T) {
+	_, _, err := ConnectMCPTools(context.Background(), config.MCPConfig{Servers: []config.MCPServerConfig{{Name: "a", Command: "true"}, {Name: "a", Command: "true"}}})
+	if err == nil || !strings.Contains(err.Error(), "duplicate name") {
+		t.Fatalf("err = %v, want duplicate name error", err)
+	}
+
+	tools, clients, err := ConnectMCPTools(context.Background(), config.MCPConfig{Servers: []config.MCPServerConfig{
+		{Name: "missing", Command: "/nonexistent/mcp-server"},
+		{Name: "off", Command: "/nonexistent/mcp-server", Disabled: true},
+	}})
+	if err != nil || len(tools) != 0 || len(clients) != 0 {
+		t.Fatalf("ConnectMCPTools = %d tools, %d clients, %v; want broken and disabled servers skipped", len(tools), len(clients), err)
+	}
+}
+
+func TestMCPServerToolsAdaptsAgentTools(t *testing.T) {
+	type input struct {
+		Path string `json:"path" description:"File path."`
+	}
+	tool := core.NewAgentTool("read_thing", "Reads a thing.", func(_ context.Context, in input, call core.ToolCall) (core.ToolResponse, error) {
+		if in.Path == "" {
+			return core.NewTextErrorResponse("path is required"), nil
+		}
+		return core.NewTextResponse("read " + in.Path), nil
+	})
+
+	served := MCPServerTools([]core.AgentTool{tool})
+	if len(served) != 1 || served[0].Name != "read_thing" || !strings.Contains(string(served[0].InputSchema), `"required":["path"]`) {
+		t.Fatalf("served = %+v, want read_thing with path required", served)
+	}
+
+	result, err := served[0].Call(context.Background(), json.RawMessage(`{"path":"a.txt"}`))
+	if err != nil || result.IsError || result.Text() != "read a.txt" {
+		t.Fatalf("result = %+v, err = %v", result, err)
+	}
+	result, err = served[0].Call(context.Background(), json.RawMessage(`{}`))
+	if err != nil || !result.IsError {
+		t.Fatalf("result = %+v, err = %v; want tool error", result, err)
+	}
+}
@@ -0,0 +1,72 @@
+package fantasy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	core "charm.land/fantasy"
+
+	providertypes "miniclaw/pkg/provider/types"
+	memorytools "miniclaw/pkg/tools/memory"
+	"miniclaw/pkg/workspace"
+)
+
+type rememberInput struct {
+	Fact string `json:"fact" description:"One self-contained fact to keep for future sessions, for example a user preference or project decision."`
+}
+
+type recallInput struct {
+	Query string `json:"query,omitempty" description:"Words that must all appear in a remembered fact. Omit to list the most recent facts."`
+	Limit int    `json:"limit,omitempty" description:"Maximum number of facts to return (default 20)."`
+}
+
+// BuildMemoryTools constructs the remember and recall tools for fantasy-agent.
+func BuildMemoryTools(store *memorytools.Store, guard *workspace.Guard) []core.AgentTool {
+	if store == nil || guard == nil {
+		return nil
+	}
+	file := safeRelPath(guard, store.Path())
+
+	return []core.AgentTool{
+		core.NewAgentTool("remember", fmt.Sprintf("Save a fact to long-term memory (%s) so it is available in future sessions. Only save durable facts worth recalling later.", file), func(ctx context.Context, input rememberInput, _ core.ToolCall) (core.ToolResponse, error) {
+			start := time.Now()
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "call", Tool: "remember", Payload: toolEventPayload(input)})
+			entry, err := store.Remember(input.Fact)
+			elapsed := time.Since(start)
+			if err != nil {
+				logToolResult("remember", file, false, elapsed, workspace.CategoryFromError(err))
+				providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "remember", Payload: err.Error(), Failed: true, DurationMs: elapsed.Milliseconds()})
+				return toolErrorResponse(err), nil
+			}
+
+			summary := fmt.Sprintf("ok: remembered in %s: %s", file, entry.Text)
+			logToolResult("remember", file, true, elapsed, "")
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "remember", Payload: summary, DurationMs: elapsed.Milliseconds()})
+			return core.NewTextResponse(summary), nil
+		}),
+		core.NewAgentTool("recall", "Search long-term memory for facts saved in earlier sessions, newest first.", func(ctx context.Context, input recallInput, _ core.ToolCall) (core.ToolResponse, error) {
+			start := time.Now()
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "call", Tool: "recall", Payload: toolEventPayload(input)})
+			entries, err := store.Recall(input.Query, input.Limit)
+			elapsed := time.Since(start)
+			if err != nil {
+				logToolResult("recall", file, false, elapsed, workspace.CategoryFromError(err))
+				providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "recall", Payload: err.Error(), Failed: true, DurationMs: elapsed.Milliseconds()})
+				return toolErrorResponse(err), nil
+			}
+
+			summary := fmt.Sprintf("ok: %d remembered facts", len(entries))
+			var b strings.Builder
+			b.WriteString(summary)
+			for _, entry := range entries {
+				b.WriteString("\n- ")
+				b.WriteString(entry.String())
+			}
+			logToolResult("recall", file, true, elapsed, "")
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: "recall", Payload: summary, DurationMs: elapsed.Milliseconds()})
+			return core.NewTextResponse(b.String()), nil
+		}),
+	}
+}
@@ -0,0 +1,90 @@
+package fantasy
+
+import (
+	"context"
+	"time"
+
+	core "charm.land/fantasy"
+
+	providertypes "miniclaw/pkg/provider/types"
+	plantools "miniclaw/pkg/tools/plan"
+	"miniclaw/pkg/workspace"
+)
+
+type planAddInput struct {
+	Items []string `json:"items" description:"Steps to append to the plan, in order. Each is one short line."`
+}
+
+type planUpdateInput struct {
+	ID     int    `json:"id" description:"ID of the plan item to change."`
+	Text   string `json:"text,omitempty" description:"New text for the item. Omit to keep the current text."`
+	Status string `json:"status,omitempty" description:"New status: pending, in_progress, or done. Omit to keep the current status."`
+}
+
+type planCompleteInput struct {
+	ID int `json:"id" description:"ID of the plan item that is finished."`
+}
+
+type planKey struct{}
+
+// WithPlan returns a context whose plan tools read and change p.
+func WithPlan(ctx context.Context, p *plantools.Plan) context.Context {
+	return context.WithValue(ctx, planKey{}, p)
+}
+
+func planFromContext(ctx context.Context) *plantools.Plan {
+	if ctx == nil {
+		return nil
+	}
+
+	p, _ := ctx.Value(planKey{}).(*plantools.Plan)
+	return p
+}
+
+// BuildPlanTools constructs plan_add, plan_update, and plan_complete.
+//
+// They work on the plan carried in the call context (see WithPlan) and return
+// the whole rendered plan, which the chat UI shows as a plan card.
+func BuildPlanTools() []core.AgentTool {
+	return []core.AgentTool{
+		core.NewAgentTool(plantools.ToolAdd, "Add steps to your task plan. Use it at the start of multi-step work to lay out the steps, then track progress with plan_update and plan_complete. Returns the whole plan with item IDs.", func(ctx context.Context, input planAddInput, _ core.ToolCall) (core.ToolResponse, error) {
+			return runPlanTool(ctx, plantools.ToolAdd, input, func(p *plantools.Plan) error {
+				_, err := p.Add(input.Items)
+				return err
+			}), nil
+		}),
+		core.NewAgentTool(plantools.ToolUpdate, "Change the text or status (pending, in_progress, done) of a plan item. Mark the step you start as in_progress. Returns the whole plan.", func(ctx context.Context, input planUpdateInput, _ core.ToolCall) (core.ToolResponse, error) {
+			return runPlanTool(ctx, plantools.ToolUpdate, input, func(p *plantools.Plan) error {
+				return p.Update(input.ID, input.Text, input.Status)
+			}), nil
+		}),
+		core.NewAgentTool(plantools.ToolComplete, "Mark a plan item done. Returns the whole plan.", func(ctx context.Context, input planCompleteInput, _ core.ToolCall) (core.ToolResponse, error) {
+			return runPlanTool(ctx, plantools.ToolComplete, input, func(p *plantools.Plan) error {
+				return p.Complete(input.ID)
+			}), nil
+		}),
+	}
+}
+
+// runPlanTool applies change to the context plan and returns the rendered plan.
+func runPlanTool(ctx context.Context, name string, input any, change func(*plantools.Plan) error) core.ToolResponse {
+	start := time.Now()
+	providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "call", Tool: name, Payload: toolEventPayload(input)})
+
+	err := workspace.NewError(workspace.ErrorInvalidArgument, "no plan is available in this session")
+	p := planFromContext(ctx)
+	if p != nil {
+		err = change(p)
+	}
+	elapsed := time.Since(start)
+	if err != nil {
+		logToolResult(name, "", false, elapsed, workspace.CategoryFromError(err))
+		providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: name, Payload: err.Error(), Failed: true, DurationMs: elapsed.Milliseconds()})
+		return toolErrorResponse(err)
+	}
+
+	rendered := p.Render()
+	logToolResult(name, "", true, elapsed, "")
+	providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: name, Payload: rendered, DurationMs: elapsed.Milliseconds()})
+	return core.NewTextResponse(rendered)
+}
@@ -0,0 +1,184 @@
+package fantasy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	core "charm.land/fantasy"
+
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+// writtenContentFields names the input field holding the data each file-writing tool writes.
+var writtenContentFields = map[string]string{
+	"write_file":  "content",
+	"append_file": "content",
+	"edit_file":   "new_text",
+	"apply_patch": "patch",
+}
+
+// ToolQuota caps tool use per session.
+//
+// Byte limits are checked before each call, so the call that crosses a limit
+// still completes and later calls are rejected.
+type ToolQuota struct {
+	maxCalls        int64
+	maxBytesRead    int64
+	maxBytesWritten int64
+}
+
+// NewToolQuota builds a quota from tools.quotas config.
+//
+// It returns nil when no limit is set.
+func NewToolQuota(cfg config.ToolQuotaConfig) (*ToolQuota, error) {
+	if cfg.MaxCallsPerSession < 0 || cfg.MaxBytesReadPerSession < 0 || cfg.MaxBytesWrittenPerSession < 0 {
+		return nil, errors.New("tools.quotas limits must not be negative")
+	}
+	if cfg.MaxCallsPerSession == 0 && cfg.MaxBytesReadPerSession == 0 && cfg.MaxBytesWrittenPerSession == 0 {
+		return nil, nil
+	}
+
+	return &ToolQuota{
+		maxCalls:        int64(cfg.MaxCallsPerSession),
+		maxBytesRead:    cfg.MaxBytesReadPerSession,
+		maxBytesWritten: cfg.MaxBytesWrittenPerSession,
+	}, nil
+}
+
+// exceeded describes the first limit usage has reached, or returns "" when none is.
+func (q *ToolQuota) exceeded(usage providertypes.ToolUsage) string {
+	switch {
+	case q == nil:
+		return ""
+	case q.maxCalls > 0 && usage.Calls >= q.maxCalls:
+		return fmt.Sprintf("%d tool calls", q.maxCalls)
+	case q.maxBytesRead > 0 && usage.BytesRead >= q.maxBytesRead:
+		return fmt.Sprintf("%d bytes read", q.maxBytesRead)
+	case q.maxBytesWritten > 0 && usage.BytesWritten >= q.maxBytesWritten:
+		return fmt.Sprintf("%d bytes written", q.maxBytesWritten)
+	default:
+		return ""
+	}
+}
+
+// ToolMeter accumulates tool usage for one session.
+type ToolMeter struct {
+	mu    sync.Mutex
+	usage providertypes.ToolUsage
+}
+
+// Usage returns the usage recorded so far.
+func (m *ToolMeter) Usage() providertypes.ToolUsage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.usage
+}
+
+// begin counts one call against quota, or records a denial and returns the exhausted limit.
+func (m *ToolMeter) begin(quota *ToolQuota) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if limit := quota.exceeded(m.usage); limit != "" {
+		m.usage.QuotaDenied++
+		return limit
+	}
+	m.usage.Calls++
+
+	return ""
+}
+
+// finish records what a completed call moved and how long it took.
+func (m *ToolMeter) finish(bytesRead int64, bytesWritten int64, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.usage.BytesRead += bytesRead
+	m.usage.BytesWritten += bytesWritten
+	m.usage.Duration += elapsed
+}
+
+type toolMeterKey struct{}
+
+// WithToolMeter returns a context whose tool calls are recorded on meter.
+func WithToolMeter(ctx context.Context, meter *ToolMeter) context.Context {
+	return context.WithValue(ctx, toolMeterKey{}, meter)
+}
+
+func toolMeterFromContext(ctx context.Context) *ToolMeter {
+	if ctx == nil {
+		return nil
+	}
+
+	meter, _ := ctx.Value(toolMeterKey{}).(*ToolMeter)
+	return meter
+}
+
+// WrapWithMetering records every tool call on the context-carried ToolMeter
+// and rejects calls once quota is exhausted.
+//
+// Wrap it outside approval so calls over quota are rejected without asking
+// the user first. Calls without a meter in context run unmetered.
+func WrapWithMetering(tools []core.AgentTool, quota *ToolQuota) []core.AgentTool {
+	wrapped := make([]core.AgentTool, 0, len(tools))
+	for _, tool := range tools {
+		wrapped = append(wrapped, &meteredTool{AgentTool: tool, quota: quota})
+	}
+
+	return wrapped
+}
+
+// meteredTool counts the wrapped tool's calls and enforces the session quota.
+//
+// Calls over quota are returned as tool error responses so the model can
+// answer with what it has instead of the whole run failing.
+type meteredTool struct {
+	core.AgentTool
+	quota *ToolQuota
+}
+
+func (t *meteredTool) Run(ctx context.Context, params core.ToolCall) (core.ToolResponse, error) {
+	meter := toolMeterFromContext(ctx)
+	if meter == nil {
+		return t.AgentTool.Run(ctx, params)
+	}
+
+	name := t.Info().Name
+	if limit := meter.begin(t.quota); limit != "" {
+		slog.Default().Warn("Tool quota exceeded",
+			"component", "provider.fantasy",
+			"tool", name,
+			"limit", limit,
+		)
+		return core.NewTextErrorResponse(fmt.Sprintf("%s was not run: this session has used its quota of %s. Do not call more tools; answer with what you have.", name, limit)), nil
+	}
+
+	start := time.Now()
+	response, err := t.AgentTool.Run(ctx, params)
+	meter.finish(int64(len(response.Content)+len(response.Data)), writtenBytes(name, params.Input), time.Since(start))
+
+	return response, err
+}
+
+// writtenBytes reports the size of the content a file-writing tool call writes.
+func writtenBytes(toolName string, input string) int64 {
+	field, ok := writtenContentFields[toolName]
+	if !ok {
+		return 0
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(input), &fields); err != nil {
+		return 0
+	}
+	content, _ := fields[field].(string)
+
+	return int64(len(content))
+}
@@ -0,0 +1,64 @@
+package fantasy
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	core "charm.land/fantasy"
+
+	"miniclaw/pkg/config"
+)
+
+func TestNewToolQuota(t *testing.T) {
+	quota, err := NewToolQuota(config.ToolQuotaConfig{})
+	if err != nil || quota != nil {
+		t.Fatalf("NewToolQuota(unset) = %v, %v; want nil, nil", quota, err)
+	}
+	if _, err := NewToolQuota(config.ToolQuotaConfig{MaxCallsPerSession: -1}); err == nil {
+		t.Fatal("expected negative limit error")
+	}
+}
+
+func TestWrapWithMeteringCountsUsageAndEnforcesQuota(t *testing.T) {
+	runs := 0
+	inner := core.NewAgentTool("write_file", "writes", func(context.Context, struct{}, core.ToolCall) (core.ToolResponse, error) {
+		runs++
+		return core.NewTextResponse("written"), nil
+	})
+	quota, err := NewToolQuota(config.ToolQuotaConfig{MaxCallsPerSession: 2})
+	if err != nil {
+		t.Fatalf("NewToolQuota error: %v", err)
+	}
+	tool := WrapWithMetering([]core.AgentTool{inner}, quota)[0]
+	call := core.ToolCall{ID: "1", Name: "write_file", Input: `{"path":"a.txt","content":"hello"}`}
+
+	meter := &ToolMeter{}
+	ctx := WithToolMeter(context.Background(), meter)
+	for range 3 {
+		if _, err := tool.Run(ctx, call); err != nil {
+			t.Fatalf("Run error: %v", err)
+		}
+	}
+
+	response, err := tool.Run(ctx, call)
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if !response.IsError || !strings.Contains(response.Content, "quota of 2 tool calls") {
+		t.Fatalf("response = %+v, want quota error", response)
+	}
+	if runs != 2 {
+		t.Fatalf("runs = %d, want 2", runs)
+	}
+
+	usage := meter.Usage()
+	if usage.Calls != 2 || usage.QuotaDenied != 2 || usage.BytesWritten != 10 || usage.BytesRead != int64(2*len("written")) {
+		t.Fatalf("usage = %+v, want 2 calls, 2 denials, 10 bytes written, 14 bytes read", usage)
+	}
+
+	// Without a meter in context the wrapper neither counts nor limits.
+	if _, err := tool.Run(context.Background(), call); err != nil || runs != 3 {
+		t.Fatalf("unmetered Run = %v (runs %d), want a third run", err, runs)
+	}
+}
@@ -0,0 +1,201 @@
+package fantasy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	core "charm.land/fantasy"
+
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+	"miniclaw/pkg/workspace"
+)
+
+// cachedResultNote prefixes results served from the cache so the model knows
+// repeating the call will not change the answer.
+const cachedResultNote = "[cached: identical call earlier in this prompt; the target is unchanged]\n"
+
+// CacheStamp fingerprints the state a tool call's result depends on, such as
+// a file's size and modification time. ok is false when the call must not be
+// served from cache.
+type CacheStamp func(input string) (stamp string, ok bool)
+
+// ToolResultCache holds idempotent tool results for one prompt.
+type ToolResultCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedResult
+}
+
+type cachedResult struct {
+	stamp    string
+	response core.ToolResponse
+}
+
+// NewToolResultCache returns an empty cache.
+func NewToolResultCache() *ToolResultCache {
+	return &ToolResultCache{entries: make(map[string]cachedResult)}
+}
+
+func (c *ToolResultCache) get(key string, stamp string) (core.ToolResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || entry.stamp != stamp {
+		return core.ToolResponse{}, false
+	}
+
+	return entry.response, true
+}
+
+func (c *ToolResultCache) put(key string, stamp string, response core.ToolResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = cachedResult{stamp: stamp, response: response}
+}
+
+func (c *ToolResultCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
+
+type toolResultCacheKey struct{}
+
+// WithToolResultCache returns a context whose cacheable tool calls reuse results from cache.
+func WithToolResultCache(ctx context.Context, cache *ToolResultCache) context.Context {
+	return context.WithValue(ctx, toolResultCacheKey{}, cache)
+}
+
+func toolResultCacheFromContext(ctx context.Context) *ToolResultCache {
+	if ctx == nil {
+		return nil
+	}
+
+	cache, _ := ctx.Value(toolResultCacheKey{}).(*ToolResultCache)
+	return cache
+}
+
+// WorkspaceCacheStamps returns the stamps for the cacheable workspace tools,
+// read_file and list_dir, keyed by tool name. A result is reused while the
+// target path's size and modification time are unchanged.
+func WorkspaceCacheStamps(cfg *config.Config) (map[string]CacheStamp, error) {
+	guard, err := workspaceGuard(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]CacheStamp{
+		"read_file": pathStamp(guard, ""),
+		"list_dir":  pathStamp(guard, "."),
+	}, nil
+}
+
+// pathStamp stamps the "path" input field, using fallback when it is empty.
+func pathStamp(guard *workspace.Guard, fallback string) CacheStamp {
+	return func(input string) (string, bool) {
+		var fields struct {
+			Path string `json:"path"`
+		}
+		if strings.TrimSpace(input) != "" {
+			if err := json.Unmarshal([]byte(input), &fields); err != nil {
+				return "", false
+			}
+		}
+		path := strings.TrimSpace(fields.Path)
+		if path == "" {
+			path = fallback
+		}
+
+		resolved, err := guard.ResolvePath(path)
+		if err != nil {
+			return "", false
+		}
+		info, err := os.Stat(resolved)
+		if err != nil {
+			return "", false
+		}
+
+		return fmt.Sprintf("%s:%d:%d", resolved, info.Size(), info.ModTime().UnixNano()), true
+	}
+}
+
+// WrapWithResultCache serves repeated calls to the tools in stamps from the
+// context-carried ToolResultCache while their stamp is unchanged.
+//
+// Only successful results are cached. A call to any other tool clears the
+// cache, since it may have changed what the cached calls read. Wrap it outside
+// metering so cache hits do not count against tools.quotas; calls without a
+// cache in context run uncached.
+func WrapWithResultCache(tools []core.AgentTool, stamps map[string]CacheStamp) []core.AgentTool {
+	wrapped := make([]core.AgentTool, 0, len(tools))
+	for _, tool := range tools {
+		wrapped = append(wrapped, &cachingTool{AgentTool: tool, stamp: stamps[tool.Info().Name]})
+	}
+
+	return wrapped
+}
+
+// cachingTool reuses the wrapped tool's results, or clears the cache after
+// running when the tool is not cacheable.
+type cachingTool struct {
+	core.AgentTool
+	stamp CacheStamp
+}
+
+func (t *cachingTool) Run(ctx context.Context, params core.ToolCall) (core.ToolResponse, error) {
+	cache := toolResultCacheFromContext(ctx)
+	if cache == nil {
+		return t.AgentTool.Run(ctx, params)
+	}
+	if t.stamp == nil {
+		defer cache.clear()
+		return t.AgentTool.Run(ctx, params)
+	}
+
+	name := t.Info().Name
+	stamp, ok := t.stamp(params.Input)
+	if !ok {
+		return t.AgentTool.Run(ctx, params)
+	}
+	key := name + "\x00" + canonicalToolInput(params.Input)
+	if response, hit := cache.get(key, stamp); hit {
+		slog.Default().Debug("Tool result served from cache",
+			"component", "provider.fantasy",
+			"tool", name,
+		)
+		providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "call", Tool: name, Payload: params.Input})
+		providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: name, Payload: "ok: cached result reused"})
+		if response.Type == "text" || response.Type == "" {
+			response.Content = cachedResultNote + response.Content
+		}
+		return response, nil
+	}
+
+	response, err := t.AgentTool.Run(ctx, params)
+	if err == nil && !response.IsError {
+		cache.put(key, stamp, response)
+	}
+
+	return response, err
+}
+
+// canonicalToolInput normalizes JSON input so key order and spacing do not
+// split cache entries; invalid JSON is used as-is.
+func canonicalToolInput(input string) string {
+	var value any
+	if err := json.Unmarshal([]byte(input), &value); err != nil {
+		return input
+	}
+	canonical, err := json.Marshal(value)
+	if err != nil {
+		return input
+	}
+
+	return string(canonical)
+}
@@ -0,0 +1,108 @@
+package fantasy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	core "charm.land/fantasy"
+
+	"miniclaw/pkg/config"
+)
+
+func TestWrapWithResultCacheReusesReadsUntilTargetChanges(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(path, []byte("v1"), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	stamps, err := WorkspaceCacheStamps(&config.Config{Agents: config.AgentsConfig{Defaults: config.AgentDefaults{Workspace: dir}}})
+	if err != nil {
+		t.Fatalf("WorkspaceCacheStamps error: %v", err)
+	}
+
+	reads, writes := 0, 0
+	tools := WrapWithResultCache([]core.AgentTool{
+		core.NewAgentTool("read_file", "reads", func(context.Context, struct{}, core.ToolCall) (core.ToolResponse, error) {
+			reads++
+			content, err := os.ReadFile(path)
+			return core.NewTextResponse(string(content)), err
+		}),
+		core.NewAgentTool("write_file", "writes", func(context.Context, struct{}, core.ToolCall) (core.ToolResponse, error) {
+			writes++
+			return core.NewTextResponse("written"), nil
+		}),
+	}, stamps)
+	read := func(ctx context.Context, input string) core.ToolResponse {
+		t.Helper()
+		response, err := tools[0].Run(ctx, core.ToolCall{ID: "1", Name: "read_file", Input: input})
+		if err != nil {
+			t.Fatalf("Run error: %v", err)
+		}
+		return response
+	}
+
+	ctx := WithToolResultCache(context.Background(), NewToolResultCache())
+	read(ctx, `{"path":"notes.txt"}`)
+	cached := read(ctx, `{ "path": "notes.txt" }`)
+	if reads != 1 || !strings.HasPrefix(cached.Content, cachedResultNote) || !strings.HasSuffix(cached.Content, "v1") {
+		t.Fatalf("reads = %d, response = %q; want second call served from cache", reads, cached.Content)
+	}
+
+	// A changed file (new size and mtime) is read again.
+	if err := os.WriteFile(path, []byte("version 2"), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatalf("Chtimes error: %v", err)
+	}
+	if response := read(ctx, `{"path":"notes.txt"}`); reads != 2 || response.Content != "version 2" {
+		t.Fatalf("reads = %d, response = %q; want fresh read after change", reads, response.Content)
+	}
+
+	// Any non-cacheable call clears the cache.
+	if _, err := tools[1].Run(ctx, core.ToolCall{ID: "2", Name: "write_file", Input: `{}`}); err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	read(ctx, `{"path":"notes.txt"}`)
+	if reads != 3 || writes != 1 {
+		t.Fatalf("reads = %d, writes = %d; want read after write to miss the cache", reads, writes)
+	}
+
+	// A new prompt gets a new cache, and no cache means no reuse.
+	read(WithToolResultCache(context.Background(), NewToolResultCache()), `{"path":"notes.txt"}`)
+	read(context.Background(), `{"path":"notes.txt"}`)
+	read(context.Background(), `{"path":"notes.txt"}`)
+	if reads != 6 {
+		t.Fatalf("reads = %d, want 6", reads)
+	}
+}
+
+func TestWrapWithResultCacheSkipsErrors(t *testing.T) {
+	stamps, err := WorkspaceCacheStamps(&config.Config{Agents: config.AgentsConfig{Defaults: config.AgentDefaults{Workspace: t.TempDir()}}})
+	if err != nil {
+		t.Fatalf("WorkspaceCacheStamps error: %v", err)
+	}
+
+	runs := 0
+	tool := WrapWithResultCache([]core.AgentTool{
+		core.NewAgentTool("list_dir", "lists", func(context.Context, struct{}, core.ToolCall) (core.ToolResponse, error) {
+			runs++
+			return core.NewTextErrorResponse("busy"), nil
+		}),
+	}, stamps)[0]
+
+	ctx := WithToolResultCache(context.Background(), NewToolResultCache())
+	for range 2 {
+		if _, err := tool.Run(ctx, core.ToolCall{ID: "1", Name: "list_dir", Input: "{}"}); err != nil {
+			t.Fatalf("Run error: %v", err)
+		}
+	}
+	if runs != 2 {
+		t.Fatalf("runs = %d, want error responses to be re-run", runs)
+	}
+}
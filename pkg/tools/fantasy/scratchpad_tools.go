@@ -0,0 +1,97 @@
+package fantasy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	core "charm.land/fantasy"
+
+	providertypes "miniclaw/pkg/provider/types"
+	"miniclaw/pkg/tools/scratchpad"
+	"miniclaw/pkg/workspace"
+)
+
+type scratchSetInput struct {
+	Name    string `json:"name" description:"Snippet name: letters, digits, '.', '_', or '-'."`
+	Content string `json:"content" description:"Text to keep. Replaces any earlier value; empty content removes the snippet."`
+}
+
+type scratchGetInput struct {
+	Name string `json:"name,omitempty" description:"Snippet to read. Omit to list the stored snippet names and sizes."`
+}
+
+type scratchpadKey struct{}
+
+// WithScratchpad returns a context whose scratchpad tools read and change pad.
+func WithScratchpad(ctx context.Context, pad *scratchpad.Pad) context.Context {
+	return context.WithValue(ctx, scratchpadKey{}, pad)
+}
+
+func scratchpadFromContext(ctx context.Context) *scratchpad.Pad {
+	if ctx == nil {
+		return nil
+	}
+
+	pad, _ := ctx.Value(scratchpadKey{}).(*scratchpad.Pad)
+	return pad
+}
+
+// BuildScratchpadTools constructs scratch_set and scratch_get.
+//
+// They work on the session scratchpad carried in the call context (see
+// WithScratchpad), which lives in memory only and is never written to the workspace.
+func BuildScratchpadTools() []core.AgentTool {
+	return []core.AgentTool{
+		core.NewAgentTool(scratchpad.ToolSet, "Stash a named text snippet, such as an intermediate result or notes, in this session's in-memory scratchpad instead of writing a throwaway file. Empty content removes the snippet.", func(ctx context.Context, input scratchSetInput, _ core.ToolCall) (core.ToolResponse, error) {
+			return runScratchpadTool(ctx, scratchpad.ToolSet, input, func(pad *scratchpad.Pad) (string, error) {
+				replaced, err := pad.Set(input.Name, input.Content)
+				if err != nil {
+					return "", err
+				}
+				name := strings.TrimSpace(input.Name)
+				switch {
+				case input.Content == "" && replaced:
+					return fmt.Sprintf("ok: removed %s; %s", name, pad.Render()), nil
+				case input.Content == "":
+					return fmt.Sprintf("ok: %s was not set; %s", name, pad.Render()), nil
+				case replaced:
+					return fmt.Sprintf("ok: replaced %s (%d bytes)", name, len(input.Content)), nil
+				default:
+					return fmt.Sprintf("ok: saved %s (%d bytes)", name, len(input.Content)), nil
+				}
+			}), nil
+		}),
+		core.NewAgentTool(scratchpad.ToolGet, "Read a snippet from this session's scratchpad by name, or list the stored snippets when name is omitted.", func(ctx context.Context, input scratchGetInput, _ core.ToolCall) (core.ToolResponse, error) {
+			return runScratchpadTool(ctx, scratchpad.ToolGet, input, func(pad *scratchpad.Pad) (string, error) {
+				if strings.TrimSpace(input.Name) == "" {
+					return pad.Render(), nil
+				}
+				return pad.Get(input.Name)
+			}), nil
+		}),
+	}
+}
+
+// runScratchpadTool applies run to the context scratchpad and returns its text.
+func runScratchpadTool(ctx context.Context, name string, input any, run func(*scratchpad.Pad) (string, error)) core.ToolResponse {
+	start := time.Now()
+	providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "call", Tool: name, Payload: toolEventPayload(input)})
+
+	var text string
+	err := workspace.NewError(workspace.ErrorInvalidArgument, "no scratchpad is available in this session")
+	if pad := scratchpadFromContext(ctx); pad != nil {
+		text, err = run(pad)
+	}
+	elapsed := time.Since(start)
+	if err != nil {
+		logToolResult(name, "", false, elapsed, workspace.CategoryFromError(err))
+		providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: name, Payload: err.Error(), Failed: true, DurationMs: elapsed.Milliseconds()})
+		return toolErrorResponse(err)
+	}
+
+	logToolResult(name, "", true, elapsed, "")
+	providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: name, Payload: text, DurationMs: elapsed.Milliseconds()})
+	return core.NewTextResponse(text)
+}
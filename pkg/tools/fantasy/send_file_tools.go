@@ -0,0 +1,61 @@
+package fantasy
+
+import (
+	"context"
+	"time"
+
+	core "charm.land/fantasy"
+
+	providertypes "miniclaw/pkg/provider/types"
+	fstools "miniclaw/pkg/tools/fs"
+	"miniclaw/pkg/workspace"
+)
+
+// SendFileTool is the name of the tool that attaches a workspace file to the reply.
+const SendFileTool = "send_file"
+
+type sendFileInput struct {
+	Path string `json:"path" description:"File path relative to the workspace root."`
+}
+
+// BuildSendFileTool constructs send_file, which queues a workspace file for
+// the chat channel to deliver after the reply text. It fails in sessions
+// whose context carries no providertypes.FileOutbox, such as the CLI, and for
+// files the read-side tools of service hide.
+func BuildSendFileTool(service *fstools.Service, guard *workspace.Guard) core.AgentTool {
+	return core.NewAgentTool(SendFileTool, "Send a workspace file to the user as an attachment, delivered after your reply. Use it for reports or files you created or edited when the user should receive the file itself. Only works in chat channels.", func(ctx context.Context, input sendFileInput, _ core.ToolCall) (core.ToolResponse, error) {
+		start := time.Now()
+		providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "call", Tool: SendFileTool, Payload: toolEventPayload(input)})
+
+		relPath, err := queueFile(ctx, service, guard, input.Path)
+		elapsed := time.Since(start)
+		if err != nil {
+			logToolResult(SendFileTool, input.Path, false, elapsed, workspace.CategoryFromError(err))
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: SendFileTool, Payload: err.Error(), Failed: true, DurationMs: elapsed.Milliseconds()})
+			return toolErrorResponse(err), nil
+		}
+
+		summary := "ok: " + relPath + " will be sent after your reply"
+		logToolResult(SendFileTool, relPath, true, elapsed, "")
+		providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: SendFileTool, Payload: summary, DurationMs: elapsed.Milliseconds()})
+		return core.NewTextResponse(summary), nil
+	})
+}
+
+// queueFile checks that path names a regular file inside the workspace that
+// service does not hide and adds it to the context's outbox, returning its
+// workspace-relative path.
+func queueFile(ctx context.Context, service *fstools.Service, guard *workspace.Guard, path string) (string, error) {
+	outbox, ok := providertypes.FileOutboxFromContext(ctx)
+	if !ok {
+		return "", workspace.NewError(workspace.ErrorInvalidArgument, "this session has no chat channel to send files to")
+	}
+
+	resolved, err := service.ResolveFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	outbox.Add(resolved)
+	return safeRelPath(guard, resolved), nil
+}
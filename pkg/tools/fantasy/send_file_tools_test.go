@@ -0,0 +1,69 @@
+package fantasy
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	core "charm.land/fantasy"
+
+	providertypes "miniclaw/pkg/provider/types"
+	fstools "miniclaw/pkg/tools/fs"
+	"miniclaw/pkg/workspace"
+)
+
+func TestSendFileQueuesWorkspaceFile(t *testing.T) {
+	root := t.TempDir()
+	guard, err := workspace.NewGuard(root)
+	if err != nil {
+		t.Fatalf("NewGuard error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "report.txt"), []byte("done"), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	tool := BuildSendFileTool(fstools.NewService(guard), guard)
+
+	run := func(ctx context.Context, path string) core.ToolResponse {
+		t.Helper()
+		input, _ := json.Marshal(sendFileInput{Path: path})
+		response, err := tool.Run(ctx, core.ToolCall{Input: string(input)})
+		if err != nil {
+			t.Fatalf("send_file(%q) error: %v", path, err)
+		}
+		return response
+	}
+
+	if response := run(context.Background(), "report.txt"); !response.IsError || !strings.Contains(response.Content, "no chat channel") {
+		t.Fatalf("send_file without outbox = %+v, want error", response)
+	}
+
+	outbox := &providertypes.FileOutbox{}
+	ctx := providertypes.WithFileOutbox(context.Background(), outbox)
+	if response := run(ctx, "report.txt"); response.IsError {
+		t.Fatalf("send_file error response: %q", response.Content)
+	}
+	run(ctx, "report.txt")
+	if response := run(ctx, "missing.txt"); !response.IsError || !strings.Contains(response.Content, workspace.ErrorPathNotFound) {
+		t.Fatalf("send_file missing file = %+v, want %s", response, workspace.ErrorPathNotFound)
+	}
+	if response := run(ctx, "."); !response.IsError {
+		t.Fatal("send_file on a directory should fail")
+	}
+	if err := os.WriteFile(filepath.Join(root, "secrets.env"), []byte("TOKEN=x"), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, fstools.IgnoreFile), []byte("*.env\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	if response := run(ctx, "secrets.env"); !response.IsError || !strings.Contains(response.Content, workspace.ErrorPermissionDenied) {
+		t.Fatalf("send_file ignored file = %+v, want %s", response, workspace.ErrorPermissionDenied)
+	}
+
+	paths := outbox.Paths()
+	if len(paths) != 1 || filepath.Base(paths[0]) != "report.txt" || !filepath.IsAbs(paths[0]) {
+		t.Fatalf("outbox paths = %v, want one absolute report.txt", paths)
+	}
+}
@@ -0,0 +1,48 @@
+package fantasy
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	core "charm.land/fantasy"
+
+	providertypes "miniclaw/pkg/provider/types"
+	"miniclaw/pkg/workspace"
+)
+
+// ToolSpawnAgent is the name of the subagent delegation tool.
+const ToolSpawnAgent = "spawn_agent"
+
+type spawnAgentInput struct {
+	Task string `json:"task" description:"The subtask for the child agent, with every detail it needs: it does not see this conversation. Say what to return."`
+}
+
+// SubagentFunc runs task in a fresh child agent and returns its final answer.
+type SubagentFunc func(ctx context.Context, task string) (string, error)
+
+// BuildSubagentTools constructs spawn_agent, which hands a bounded subtask to
+// a child agent through run and returns the child's answer.
+func BuildSubagentTools(run SubagentFunc) []core.AgentTool {
+	return []core.AgentTool{
+		core.NewAgentTool(ToolSpawnAgent, "Delegate a self-contained subtask, such as researching a question across many files, to a child agent with the same workspace tools and a fresh context. Only its final answer comes back, which keeps this conversation short. The child cannot spawn agents itself.", func(ctx context.Context, input spawnAgentInput, _ core.ToolCall) (core.ToolResponse, error) {
+			start := time.Now()
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "call", Tool: ToolSpawnAgent, Payload: toolEventPayload(input)})
+
+			answer, err := "", workspace.NewError(workspace.ErrorInvalidArgument, "task is required")
+			if task := strings.TrimSpace(input.Task); task != "" {
+				answer, err = run(ctx, task)
+			}
+			elapsed := time.Since(start)
+			if err != nil {
+				logToolResult(ToolSpawnAgent, "", false, elapsed, workspace.CategoryFromError(err))
+				providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: ToolSpawnAgent, Payload: err.Error(), Failed: true, DurationMs: elapsed.Milliseconds()})
+				return toolErrorResponse(err), nil
+			}
+
+			logToolResult(ToolSpawnAgent, "", true, elapsed, "")
+			providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: ToolSpawnAgent, Payload: answer, DurationMs: elapsed.Milliseconds()})
+			return core.NewTextResponse(answer), nil
+		}),
+	}
+}
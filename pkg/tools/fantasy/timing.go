@@ -0,0 +1,37 @@
+package fantasy
+
+import (
+	"context"
+	"time"
+
+	core "charm.land/fantasy"
+
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+// WrapWithTiming reports each tool run's duration to the context-carried ToolTimer.
+//
+// Wrap it outside approval so time spent waiting for a user's answer is
+// attributed to tools rather than to the provider.
+func WrapWithTiming(tools []core.AgentTool) []core.AgentTool {
+	wrapped := make([]core.AgentTool, 0, len(tools))
+	for _, tool := range tools {
+		wrapped = append(wrapped, &timedTool{AgentTool: tool})
+	}
+
+	return wrapped
+}
+
+// timedTool measures the wrapped tool's Run.
+type timedTool struct {
+	core.AgentTool
+}
+
+func (t *timedTool) Run(ctx context.Context, params core.ToolCall) (core.ToolResponse, error) {
+	start := time.Now()
+	defer func() {
+		providertypes.AddToolTime(ctx, time.Since(start))
+	}()
+
+	return t.AgentTool.Run(ctx, params)
+}
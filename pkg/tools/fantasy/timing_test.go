@@ -0,0 +1,34 @@
+package fantasy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	core "charm.land/fantasy"
+
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+func TestWrapWithTimingAccumulatesToolTime(t *testing.T) {
+	inner := core.NewAgentTool("slow", "sleeps", func(context.Context, struct{}, core.ToolCall) (core.ToolResponse, error) {
+		time.Sleep(5 * time.Millisecond)
+		return core.NewTextResponse("ok"), nil
+	})
+	tool := WrapWithTiming([]core.AgentTool{inner})[0]
+
+	ctx, timer := providertypes.WithToolTimer(context.Background())
+	for range 2 {
+		if _, err := tool.Run(ctx, core.ToolCall{ID: "1", Name: "slow", Input: "{}"}); err != nil {
+			t.Fatalf("Run error: %v", err)
+		}
+	}
+	if got := timer.Total(); got < 10*time.Millisecond {
+		t.Fatalf("tool time = %s, want at least 10ms", got)
+	}
+
+	// Without a timer in context the wrapper is a no-op passthrough.
+	if _, err := tool.Run(context.Background(), core.ToolCall{ID: "2", Name: "slow", Input: "{}"}); err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package fantasy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	core "charm.land/fantasy"
+
+	providertypes "miniclaw/pkg/provider/types"
+	"miniclaw/pkg/tools/variables"
+	"miniclaw/pkg/workspace"
+)
+
+type varSetInput struct {
+	Name  string `json:"name" description:"Variable name: letters, digits, '.', '_', or '-'."`
+	Value string `json:"value" description:"Value to store. Replaces any earlier value; an empty value removes the variable."`
+}
+
+type varGetInput struct {
+	Name string `json:"name,omitempty" description:"Variable to read. Omit to list every variable with its value."`
+}
+
+type variablesKey struct{}
+
+// WithVariables returns a context whose variable tools read and change vars.
+func WithVariables(ctx context.Context, vars *variables.Store) context.Context {
+	return context.WithValue(ctx, variablesKey{}, vars)
+}
+
+func variablesFromContext(ctx context.Context) *variables.Store {
+	if ctx == nil {
+		return nil
+	}
+	vars, _ := ctx.Value(variablesKey{}).(*variables.Store)
+	return vars
+}
+
+// BuildVariableTools constructs var_set and var_get.
+//
+// They work on the session variables carried in the call context (see
+// WithVariables); later prompts replace {{name}} with the stored value.
+func BuildVariableTools() []core.AgentTool {
+	return []core.AgentTool{
+		core.NewAgentTool(variables.ToolSet, "Set a session variable. Later user prompts that contain {{name}} get the value in its place, so the user can rerun a workflow with other parameters. An empty value removes the variable.", func(ctx context.Context, input varSetInput, _ core.ToolCall) (core.ToolResponse, error) {
+			return runVariableTool(ctx, variables.ToolSet, input, func(vars *variables.Store) (string, error) {
+				replaced, err := vars.Set(input.Name, input.Value)
+				if err != nil {
+					return "", err
+				}
+				name := strings.TrimSpace(input.Name)
+				switch {
+				case input.Value == "" && replaced:
+					return fmt.Sprintf("ok: removed %s; %s", name, vars.Render()), nil
+				case input.Value == "":
+					return fmt.Sprintf("ok: %s was not set; %s", name, vars.Render()), nil
+				case replaced:
+					return fmt.Sprintf("ok: replaced %s", name), nil
+				default:
+					return fmt.Sprintf("ok: set %s", name), nil
+				}
+			}), nil
+		}),
+		core.NewAgentTool(variables.ToolGet, "Read a session variable by name, or list every variable when name is omitted.", func(ctx context.Context, input varGetInput, _ core.ToolCall) (core.ToolResponse, error) {
+			return runVariableTool(ctx, variables.ToolGet, input, func(vars *variables.Store) (string, error) {
+				if strings.TrimSpace(input.Name) == "" {
+					return vars.Render(), nil
+				}
+				return vars.Get(input.Name)
+			}), nil
+		}),
+	}
+}
+
+// runVariableTool applies run to the context variables and returns its text.
+func runVariableTool(ctx context.Context, name string, input any, run func(*variables.Store) (string, error)) core.ToolResponse {
+	start := time.Now()
+	providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "call", Tool: name, Payload: toolEventPayload(input)})
+
+	var text string
+	err := workspace.NewError(workspace.ErrorInvalidArgument, "no variables are available in this session")
+	if vars := variablesFromContext(ctx); vars != nil {
+		text, err = run(vars)
+	}
+	elapsed := time.Since(start)
+	if err != nil {
+		logToolResult(name, "", false, elapsed, workspace.CategoryFromError(err))
+		providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: name, Payload: err.Error(), Failed: true, DurationMs: elapsed.Milliseconds()})
+		return toolErrorResponse(err)
+	}
+
+	logToolResult(name, "", true, elapsed, "")
+	providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: name, Payload: text, DurationMs: elapsed.Milliseconds()})
+	return core.NewTextResponse(text)
+}
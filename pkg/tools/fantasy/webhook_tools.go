@@ -0,0 +1,191 @@
+package fantasy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	core "charm.land/fantasy"
+
+	"miniclaw/pkg/config"
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+const (
+	// DefaultWebhookTimeout bounds a webhook call when timeout_seconds is unset.
+	DefaultWebhookTimeout = 30 * time.Second
+	// maxWebhookResponseBytes caps how much of a response body is returned to the model.
+	maxWebhookResponseBytes = 1 << 20
+)
+
+// BuildWebhookTools builds the tools declared in tools.webhooks.
+//
+// Each tool POSTs its arguments as JSON to the configured URL and returns the
+// response body. Invalid declarations are config errors.
+func BuildWebhookTools(webhooks []config.WebhookToolConfig) ([]core.AgentTool, error) {
+	tools := make([]core.AgentTool, 0, len(webhooks))
+	seen := make(map[string]struct{}, len(webhooks))
+	for index, webhook := range webhooks {
+		name := strings.TrimSpace(webhook.Name)
+		if !validToolName(name) {
+			return nil, fmt.Errorf("tools.webhooks[%d]: name %q must be 1-%d letters, digits, '_' or '-'", index, webhook.Name, maxToolNameLength)
+		}
+		if _, ok := seen[name]; ok {
+			return nil, fmt.Errorf("tools.webhooks[%d]: duplicate name %q", index, name)
+		}
+		seen[name] = struct{}{}
+
+		endpoint := strings.TrimSpace(webhook.URL)
+		parsed, err := url.Parse(endpoint)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("tools.webhooks[%d] (%s): invalid url %q", index, name, webhook.URL)
+		}
+		if webhook.TimeoutSeconds < 0 {
+			return nil, fmt.Errorf("tools.webhooks[%d] (%s): timeout_seconds must not be negative", index, name)
+		}
+
+		timeout := DefaultWebhookTimeout
+		if webhook.TimeoutSeconds > 0 {
+			timeout = time.Duration(webhook.TimeoutSeconds) * time.Second
+		}
+		description := strings.TrimSpace(webhook.Description)
+		if description == "" {
+			description = fmt.Sprintf("Send a JSON request to the %s webhook and return its response.", name)
+		}
+		parameters, required := mcpSchema(webhook.Parameters)
+
+		tools = append(tools, &webhookTool{
+			name:        name,
+			description: description,
+			parameters:  parameters,
+			required:    required,
+			endpoint:    endpoint,
+			headers:     webhook.Headers,
+			client:      &http.Client{Timeout: timeout},
+		})
+	}
+
+	return tools, nil
+}
+
+// validToolName reports whether name is a tool name every provider accepts.
+func validToolName(name string) bool {
+	if name == "" || len(name) > maxToolNameLength {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// webhookTool POSTs tool arguments to an HTTP endpoint.
+//
+// Transport failures and non-2xx responses are returned as tool error
+// responses so the model can carry on without the integration.
+type webhookTool struct {
+	name            string
+	description     string
+	parameters      map[string]any
+	required        []string
+	endpoint        string
+	headers         map[string]string
+	client          *http.Client
+	providerOptions core.ProviderOptions
+}
+
+func (t *webhookTool) Info() core.ToolInfo {
+	return core.ToolInfo{
+		Name:        t.name,
+		Description: t.description,
+		Parameters:  t.parameters,
+		Required:    t.required,
+	}
+}
+
+func (t *webhookTool) ProviderOptions() core.ProviderOptions {
+	return t.providerOptions
+}
+
+func (t *webhookTool) SetProviderOptions(opts core.ProviderOptions) {
+	t.providerOptions = opts
+}
+
+func (t *webhookTool) Run(ctx context.Context, params core.ToolCall) (core.ToolResponse, error) {
+	start := time.Now()
+	providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "call", Tool: t.name, Payload: params.Input})
+
+	body, status, err := t.post(ctx, params.Input)
+	elapsed := time.Since(start)
+	success := err == nil && status >= 200 && status <= 299
+	t.logResult(success, status, elapsed)
+
+	var text string
+	switch {
+	case err != nil:
+		text = fmt.Sprintf("webhook %s: %v", t.name, err)
+	case !success:
+		text = fmt.Sprintf("webhook %s: http %d: %s", t.name, status, body)
+	default:
+		text = body
+	}
+	providertypes.EmitToolEvent(ctx, providertypes.ToolEvent{Kind: "result", Tool: t.name, Payload: text, Failed: !success, DurationMs: elapsed.Milliseconds()})
+	if !success {
+		return core.NewTextErrorResponse(text), nil
+	}
+
+	return core.NewTextResponse(text), nil
+}
+
+// post sends input and returns the response body, capped at maxWebhookResponseBytes, and status.
+func (t *webhookTool) post(ctx context.Context, input string) (string, int, error) {
+	if strings.TrimSpace(input) == "" {
+		input = "{}"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader([]byte(input)))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "miniclaw")
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseBytes+1))
+	if err != nil {
+		return "", resp.StatusCode, fmt.Errorf("read response: %w", err)
+	}
+	text := strings.TrimSpace(string(body))
+	if len(body) > maxWebhookResponseBytes {
+		text = strings.TrimSpace(string(body[:maxWebhookResponseBytes])) + fmt.Sprintf("\n[response truncated at %d bytes]", maxWebhookResponseBytes)
+	}
+
+	return text, resp.StatusCode, nil
+}
+
+func (t *webhookTool) logResult(success bool, status int, duration time.Duration) {
+	slog.Default().Debug("Fantasy tool execution",
+		"component", "provider.fantasy",
+		"tool", t.name,
+		"webhook_status", status,
+		"success", success,
+		"duration_ms", duration.Milliseconds(),
+	)
+}
@@ -0,0 +1,70 @@
+package fantasy
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	core "charm.land/fantasy"
+
+	"miniclaw/pkg/config"
+)
+
+func TestWebhookToolPostsArgumentsAndReturnsBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || r.Header.Get("Authorization") != "Bearer token" || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "bad request shape", http.StatusBadRequest)
+			return
+		}
+		if strings.Contains(string(body), "fail") {
+			http.Error(w, "upstream broke", http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write(append([]byte("got "), body...))
+	}))
+	defer server.Close()
+
+	tools, err := BuildWebhookTools([]config.WebhookToolConfig{{
+		Name:        "create_ticket",
+		Description: "Create a ticket.",
+		Parameters:  json.RawMessage(`{"type":"object","properties":{"title":{"type":"string"}},"required":["title"]}`),
+		URL:         server.URL,
+		Headers:     map[string]string{"Authorization": "Bearer token"},
+	}})
+	if err != nil {
+		t.Fatalf("BuildWebhookTools error: %v", err)
+	}
+	info := tools[0].Info()
+	if info.Name != "create_ticket" || len(info.Required) != 1 || info.Parameters["title"] == nil {
+		t.Fatalf("info = %+v, want schema from config", info)
+	}
+
+	resp, err := tools[0].Run(context.Background(), core.ToolCall{ID: "1", Name: "create_ticket", Input: `{"title":"hi"}`})
+	if err != nil || resp.IsError || resp.Content != `got {"title":"hi"}` {
+		t.Fatalf("resp = %+v, err = %v", resp, err)
+	}
+
+	resp, err = tools[0].Run(context.Background(), core.ToolCall{ID: "2", Name: "create_ticket", Input: `{"title":"fail"}`})
+	if err != nil || !resp.IsError || !strings.Contains(resp.Content, "http 502: upstream broke") {
+		t.Fatalf("resp = %+v, err = %v; want http error as tool error", resp, err)
+	}
+}
+
+func TestBuildWebhookToolsRejectsInvalidConfig(t *testing.T) {
+	for name, webhooks := range map[string][]config.WebhookToolConfig{
+		"no name":   {{URL: "https://example.com"}},
+		"bad name":  {{Name: "has space", URL: "https://example.com"}},
+		"duplicate": {{Name: "a", URL: "https://example.com"}, {Name: "a", URL: "https://example.com"}},
+		"bad url":   {{Name: "a", URL: "ftp://example.com"}},
+		"timeout":   {{Name: "a", URL: "https://example.com", TimeoutSeconds: -1}},
+	} {
+		if _, err := BuildWebhookTools(webhooks); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
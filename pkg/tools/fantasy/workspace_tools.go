@@ -0,0 +1,67 @@
+package fantasy
+
+import (
+	"fmt"
+
+	core "charm.land/fantasy"
+
+	"miniclaw/pkg/config"
+	exectools "miniclaw/pkg/tools/exec"
+	fstools "miniclaw/pkg/tools/fs"
+	memorytools "miniclaw/pkg/tools/memory"
+	"miniclaw/pkg/workspace"
+)
+
+// BuildWorkspaceTools builds the filesystem tools and send_file for the
+// configured workspace, plus run_command when tools.exec is enabled and
+// remember/recall when tools.memory is enabled.
+func BuildWorkspaceTools(cfg *config.Config) ([]core.AgentTool, error) {
+	guard, err := workspaceGuard(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	fsService := fstools.NewService(guard)
+	if cfg.Tools.Filesystem.Prefetch {
+		fsService.EnablePrefetch(cfg.Tools.Filesystem.PrefetchMaxFiles)
+	}
+	if cfg.Tools.Filesystem.Snapshots {
+		fsService.EnableSnapshots()
+	}
+	if cfg.Tools.Filesystem.Audit {
+		fsService.EnableAudit()
+	}
+	fsService.EnableDiskQuota(cfg.Tools.Filesystem.MaxWorkspaceBytes, cfg.Tools.Filesystem.MaxWorkspaceFiles)
+	tools := append(BuildFSTools(fsService, guard), BuildSendFileTool(fsService, guard))
+	if cfg.Tools.Exec.Enabled {
+		execService, err := exectools.NewService(guard, cfg.Tools.Exec)
+		if err != nil {
+			return nil, fmt.Errorf("initialize exec tool: %w", err)
+		}
+		tools = append(tools, BuildExecTools(execService, guard)...)
+	}
+	if cfg.Tools.Memory.Enabled {
+		memoryStore, err := memorytools.NewStore(guard, cfg.Tools.Memory)
+		if err != nil {
+			return nil, fmt.Errorf("initialize memory tools: %w", err)
+		}
+		tools = append(tools, BuildMemoryTools(memoryStore, guard)...)
+	}
+
+	return tools, nil
+}
+
+// workspaceGuard builds the guard for the configured workspace and containment policy.
+func workspaceGuard(cfg *config.Config) (*workspace.Guard, error) {
+	defaults := cfg.Agents.Defaults
+	guard, err := workspace.NewGuardWithOptions(defaults.Workspace, workspace.GuardOptions{
+		RestrictToWorkspace: defaults.RestrictToWorkspace,
+		AllowedPaths:        defaults.AllowedPaths,
+		SymlinkPolicy:       defaults.SymlinkPolicy,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("initialize workspace guard: %w", err)
+	}
+
+	return guard, nil
+}
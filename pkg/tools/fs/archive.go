@@ -0,0 +1,530 @@
+package fs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"miniclaw/pkg/workspace"
+)
+
+const (
+	ArchiveZip   = "zip"
+	ArchiveTarGz = "tar.gz"
+
+	// MaxArchiveEntries bounds the files and directories in one archive.
+	MaxArchiveEntries = 1000
+	// MaxArchiveBytes bounds the uncompressed size of one archive's files.
+	MaxArchiveBytes = 32 * 1024 * 1024
+)
+
+// ArchiveResult reports the outcome of CreateArchive or ExtractArchive.
+type ArchiveResult struct {
+	Path   string
+	Format string
+	// Destination is the directory an archive was extracted into.
+	Destination string
+	// Entries counts files and directories; Bytes is their uncompressed size.
+	Entries int
+	Bytes   int64
+	// Skipped counts symlinks, special files, and ignored paths left out of a new archive.
+	Skipped   int
+	Overwrote bool
+}
+
+// archiveEntry is one file or directory read from an archive.
+type archiveEntry struct {
+	name string
+	dir  bool
+	// regular is false for symlinks, devices, and other entry types extraction refuses.
+	regular bool
+	size    int64
+	mode    os.FileMode
+}
+
+// CreateArchive packs workspace files and directories into a zip or tar.gz
+// archive; the format follows the archive extension (.zip, .tar.gz, .tgz).
+//
+// Directories are added recursively under their own name. Symlinks and
+// special files are skipped. Archives over max_archive_entries entries or
+// max_archive_bytes of file content are refused before anything is written.
+func (s *Service) CreateArchive(ctx context.Context, archivePath string, sources []string, overwrite bool) (ArchiveResult, error) {
+	ctx, cancel := s.withOperationContext(ctx)
+	defer cancel()
+
+	if err := checkContext(ctx); err != nil {
+		return ArchiveResult{}, err
+	}
+	format, err := archiveFormat(archivePath)
+	if err != nil {
+		return ArchiveResult{}, err
+	}
+	if len(sources) == 0 {
+		return ArchiveResult{}, workspace.NewError(workspace.ErrorInvalidArgument, "sources must list at least one file or directory")
+	}
+
+	resolvedArchive, err := s.guard.ResolvePath(archivePath)
+	if err != nil {
+		return ArchiveResult{}, err
+	}
+	overwrote := false
+	var sizeBefore int64
+	if info, statErr := os.Stat(resolvedArchive); statErr == nil {
+		if info.IsDir() {
+			return ArchiveResult{}, workspace.NewError(workspace.ErrorInvalidPath, "archive path is a directory")
+		}
+		if !overwrite {
+			return ArchiveResult{}, workspace.NewError(workspace.ErrorAlreadyExists, "archive exists; set overwrite to replace it")
+		}
+		overwrote = true
+		sizeBefore = info.Size()
+	} else if !os.IsNotExist(statErr) {
+		return ArchiveResult{}, workspace.NormalizeIOError(statErr, "stat archive failed")
+	}
+
+	type member struct {
+		entry archiveEntry
+		path  string
+	}
+	var members []member
+	seen := make(map[string]bool)
+	result := ArchiveResult{Path: resolvedArchive, Format: format, Overwrote: overwrote}
+	for _, source := range sources {
+		resolvedSource, err := s.guard.ResolvePath(source)
+		if err != nil {
+			return ArchiveResult{}, err
+		}
+		if err := s.checkIgnored(resolvedSource); err != nil {
+			return ArchiveResult{}, err
+		}
+		base := filepath.Dir(resolvedSource)
+		err = filepath.WalkDir(resolvedSource, func(current string, d iofs.DirEntry, walkErr error) error {
+			if walkErr != nil {
+				return workspace.NormalizeIOError(walkErr, "walk source failed")
+			}
+			if err := checkContext(ctx); err != nil {
+				return err
+			}
+			if current == resolvedArchive {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return workspace.NormalizeIOError(err, "stat source failed")
+			}
+			if s.ignored(current, info.IsDir()) {
+				result.Skipped++
+				if info.IsDir() {
+					return iofs.SkipDir
+				}
+				return nil
+			}
+			if !info.IsDir() && !info.Mode().IsRegular() {
+				result.Skipped++
+				return nil
+			}
+
+			rel, err := filepath.Rel(base, current)
+			if err != nil {
+				return workspace.NewError(workspace.ErrorInvalidPath, "source path could not be made relative")
+			}
+			name := filepath.ToSlash(rel)
+			if seen[name] {
+				return nil
+			}
+			seen[name] = true
+
+			entry := archiveEntry{name: name, dir: info.IsDir(), regular: true, mode: info.Mode().Perm()}
+			if !entry.dir {
+				entry.size = info.Size()
+			}
+			result.Entries++
+			result.Bytes += entry.size
+			if err := s.checkArchiveLimits(result.Entries, result.Bytes); err != nil {
+				return err
+			}
+			members = append(members, member{entry: entry, path: current})
+			return nil
+		})
+		if err != nil {
+			return ArchiveResult{}, err
+		}
+	}
+
+	var buffer bytes.Buffer
+	add, finish := newArchiveWriter(&buffer, format)
+	for _, member := range members {
+		if err := checkContext(ctx); err != nil {
+			return ArchiveResult{}, err
+		}
+		if err := add(member.entry, member.path); err != nil {
+			return ArchiveResult{}, workspace.NormalizeIOError(err, "write archive failed")
+		}
+	}
+	if err := finish(); err != nil {
+		return ArchiveResult{}, workspace.NormalizeIOError(err, "write archive failed")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(resolvedArchive), 0o755); err != nil {
+		return ArchiveResult{}, workspace.NormalizeIOError(err, "create parent directory failed")
+	}
+	if err := s.guard.EnsureContained(resolvedArchive); err != nil {
+		return ArchiveResult{}, err
+	}
+	if err := s.checkQuota(ctx, quotaWrite{path: resolvedArchive, size: int64(buffer.Len())}); err != nil {
+		return ArchiveResult{}, err
+	}
+	if err := s.snapshot("create_archive", resolvedArchive); err != nil {
+		return ArchiveResult{}, err
+	}
+	s.forget(resolvedArchive)
+	if err := atomicWrite(resolvedArchive, buffer.Bytes(), 0o644); err != nil {
+		return ArchiveResult{}, workspace.NormalizeIOError(err, "write failed")
+	}
+	s.recordAudit(ctx, "create_archive", AuditEntry{Path: resolvedArchive, BytesBefore: sizeBefore, BytesAfter: int64(buffer.Len())})
+
+	return result, nil
+}
+
+// ExtractArchive unpacks a zip or tar.gz archive into a workspace directory.
+//
+// Every entry is checked before anything is written: names that are absolute
+// or climb out of the destination (zip slip), symlinks and other special
+// entries, existing files without overwrite, and archives over
+// max_archive_entries or max_archive_bytes are all refused. Entries that
+// decompress to more than their declared size stop the extraction.
+func (s *Service) ExtractArchive(ctx context.Context, archivePath string, destination string, overwrite bool) (ArchiveResult, error) {
+	ctx, cancel := s.withOperationContext(ctx)
+	defer cancel()
+
+	if err := checkContext(ctx); err != nil {
+		return ArchiveResult{}, err
+	}
+	format, err := archiveFormat(archivePath)
+	if err != nil {
+		return ArchiveResult{}, err
+	}
+	resolvedArchive, err := s.guard.ResolvePath(archivePath)
+	if err != nil {
+		return ArchiveResult{}, err
+	}
+	if strings.TrimSpace(destination) == "" {
+		destination = "."
+	}
+	resolvedDestination, err := s.guard.ResolvePath(destination)
+	if err != nil {
+		return ArchiveResult{}, err
+	}
+	if info, statErr := os.Stat(resolvedDestination); statErr == nil && !info.IsDir() {
+		return ArchiveResult{}, workspace.NewError(workspace.ErrorInvalidPath, "destination is not a directory")
+	}
+
+	result := ArchiveResult{Path: resolvedArchive, Format: format, Destination: resolvedDestination}
+	var (
+		writes  []quotaWrite
+		audited []AuditEntry
+	)
+	err = readArchive(resolvedArchive, format, func(entry archiveEntry, _ io.Reader) error {
+		target, err := s.archiveTarget(resolvedDestination, entry)
+		if err != nil || target == "" {
+			return err
+		}
+
+		result.Entries++
+		result.Bytes += entry.size
+		if err := s.checkArchiveLimits(result.Entries, result.Bytes); err != nil {
+			return err
+		}
+		info, statErr := os.Stat(target)
+		switch {
+		case statErr == nil && entry.dir && !info.IsDir():
+			return workspace.NewError(workspace.ErrorInvalidPath, fmt.Sprintf("%s exists and is not a directory", entry.name))
+		case statErr == nil && !entry.dir && info.IsDir():
+			return workspace.NewError(workspace.ErrorInvalidPath, fmt.Sprintf("%s exists and is a directory", entry.name))
+		case statErr == nil && !entry.dir && !overwrite:
+			return workspace.NewError(workspace.ErrorAlreadyExists, fmt.Sprintf("%s exists; set overwrite to replace existing files", entry.name))
+		case statErr == nil && !entry.dir:
+			result.Overwrote = true
+		case statErr != nil && !os.IsNotExist(statErr):
+			return workspace.NormalizeIOError(statErr, "stat destination failed")
+		}
+		if !entry.dir {
+			writes = append(writes, quotaWrite{path: target, size: entry.size})
+		}
+
+		return checkContext(ctx)
+	})
+	if err == nil {
+		err = s.checkQuota(ctx, writes...)
+	}
+	if err == nil {
+		targets := make([]string, 0, len(writes))
+		for _, write := range writes {
+			targets = append(targets, write.path)
+		}
+		err = s.snapshot("extract_archive", targets...)
+	}
+	if err != nil {
+		return ArchiveResult{}, err
+	}
+
+	err = readArchive(resolvedArchive, format, func(entry archiveEntry, content io.Reader) error {
+		if err := checkContext(ctx); err != nil {
+			return err
+		}
+		target, err := s.archiveTarget(resolvedDestination, entry)
+		if err != nil || target == "" {
+			return err
+		}
+		if entry.dir {
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return workspace.NormalizeIOError(err, "create directory failed")
+			}
+			return s.guard.EnsureContained(target)
+		}
+
+		data, err := io.ReadAll(io.LimitReader(content, entry.size+1))
+		if err != nil {
+			return workspace.NormalizeIOError(err, fmt.Sprintf("read %s failed", entry.name))
+		}
+		if int64(len(data)) != entry.size {
+			return workspace.NewError(workspace.ErrorIO, fmt.Sprintf("%s does not match its declared size; extraction stopped", entry.name))
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return workspace.NormalizeIOError(err, "create parent directory failed")
+		}
+		if err := s.guard.EnsureContained(target); err != nil {
+			return err
+		}
+		mode := os.FileMode(0o644)
+		if entry.mode&0o111 != 0 {
+			mode = 0o755
+		}
+		sizeBefore := fileSize(target)
+		s.forget(target)
+		if err := atomicWrite(target, data, mode); err != nil {
+			return workspace.NormalizeIOError(err, "write failed")
+		}
+		audited = append(audited, AuditEntry{Path: target, BytesBefore: sizeBefore, BytesAfter: int64(len(data))})
+
+		return nil
+	})
+	// Files written before a failed entry stay on disk, so they are audited either way.
+	s.recordAudit(ctx, "extract_archive", audited...)
+	if err != nil {
+		return ArchiveResult{}, err
+	}
+
+	return result, nil
+}
+
+// archiveTarget validates an entry and returns where it extracts to, or "" for
+// the destination itself. Names must be relative and stay inside destination
+// both lexically and after the Guard resolves existing symlinks.
+func (s *Service) archiveTarget(destination string, entry archiveEntry) (string, error) {
+	if !entry.regular {
+		return "", workspace.NewError(workspace.ErrorInvalidArgument, fmt.Sprintf("%s: only regular files and directories can be extracted", entry.name))
+	}
+	name := entry.name
+	if name == "" || strings.Contains(name, `\`) || strings.HasPrefix(name, "/") || filepath.VolumeName(name) != "" {
+		return "", workspace.NewError(workspace.ErrorOutsideWorkspace, fmt.Sprintf("%q: archive entry names must be relative paths", name))
+	}
+	if containsDotDot(strings.Split(name, "/")) {
+		return "", workspace.NewError(workspace.ErrorOutsideWorkspace, fmt.Sprintf("%q: archive entry escapes the destination", name))
+	}
+	clean := path.Clean(name)
+	if clean == "." {
+		return "", nil
+	}
+
+	target, err := s.guard.ResolvePath(filepath.Join(destination, filepath.FromSlash(clean)))
+	if err != nil {
+		return "", err
+	}
+	if rel, err := filepath.Rel(destination, target); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", workspace.NewError(workspace.ErrorOutsideWorkspace, fmt.Sprintf("%q: archive entry escapes the destination", name))
+	}
+
+	return target, nil
+}
+
+func (s *Service) checkArchiveLimits(entries int, bytes int64) error {
+	if entries > s.maxArchiveEntries {
+		return workspace.NewError(workspace.ErrorInvalidArgument, fmt.Sprintf("archive exceeds max_archive_entries (%d)", s.maxArchiveEntries))
+	}
+	if bytes > s.maxArchiveBytes {
+		return workspace.NewError(workspace.ErrorInvalidArgument, fmt.Sprintf("archive exceeds max_archive_bytes (%d)", s.maxArchiveBytes))
+	}
+
+	return nil
+}
+
+func containsDotDot(parts []string) bool {
+	for _, part := range parts {
+		if part == ".." {
+			return true
+		}
+	}
+
+	return false
+}
+
+// archiveFormat picks the archive format from the file extension.
+func archiveFormat(archivePath string) (string, error) {
+	lower := strings.ToLower(strings.TrimSpace(archivePath))
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return ArchiveZip, nil
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return ArchiveTarGz, nil
+	default:
+		return "", workspace.NewError(workspace.ErrorInvalidArgument, "archive path must end in .zip, .tar.gz, or .tgz")
+	}
+}
+
+// newArchiveWriter returns functions that add one entry from disk and close the archive.
+func newArchiveWriter(w io.Writer, format string) (func(entry archiveEntry, source string) error, func() error) {
+	copyFile := func(dst io.Writer, source string, size int64) error {
+		file, err := os.Open(source)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.CopyN(dst, file, size)
+		return err
+	}
+
+	if format == ArchiveZip {
+		zw := zip.NewWriter(w)
+		add := func(entry archiveEntry, source string) error {
+			header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate}
+			header.SetMode(entry.mode)
+			if entry.dir {
+				header.Name += "/"
+				header.Method = zip.Store
+				header.SetMode(entry.mode | os.ModeDir)
+			}
+			dst, err := zw.CreateHeader(header)
+			if err != nil || entry.dir {
+				return err
+			}
+			return copyFile(dst, source, entry.size)
+		}
+		return add, zw.Close
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	add := func(entry archiveEntry, source string) error {
+		header := &tar.Header{Name: entry.name, Mode: int64(entry.mode), Size: entry.size, Typeflag: tar.TypeReg, Format: tar.FormatPAX}
+		if entry.dir {
+			header.Name += "/"
+			header.Typeflag = tar.TypeDir
+		}
+		if err := tw.WriteHeader(header); err != nil || entry.dir {
+			return err
+		}
+		return copyFile(tw, source, entry.size)
+	}
+	finish := func() error {
+		return errors.Join(tw.Close(), gz.Close())
+	}
+	return add, finish
+}
+
+// readArchive calls visit for every entry in archive order. content is only
+// valid during the call.
+func readArchive(archivePath string, format string, visit func(entry archiveEntry, content io.Reader) error) error {
+	if format == ArchiveZip {
+		reader, err := zip.OpenReader(archivePath)
+		if err != nil {
+			return readArchiveError(err)
+		}
+		defer reader.Close()
+
+		for _, file := range reader.File {
+			mode := file.Mode()
+			entry := archiveEntry{
+				name:    strings.TrimSuffix(file.Name, "/"),
+				dir:     mode.IsDir(),
+				regular: mode.IsDir() || mode.IsRegular(),
+				size:    int64(file.UncompressedSize64),
+				mode:    mode.Perm(),
+			}
+			if entry.dir {
+				entry.size = 0
+			}
+			if err := visitZipEntry(file, entry, visit); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return workspace.NormalizeIOError(err, "open archive failed")
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return readArchiveError(err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return readArchiveError(err)
+		}
+		entry := archiveEntry{
+			name:    strings.TrimSuffix(header.Name, "/"),
+			dir:     header.Typeflag == tar.TypeDir,
+			regular: header.Typeflag == tar.TypeDir || header.Typeflag == tar.TypeReg,
+			size:    header.Size,
+			mode:    os.FileMode(header.Mode).Perm(),
+		}
+		if entry.dir {
+			entry.size = 0
+		}
+		if err := visit(entry, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func visitZipEntry(file *zip.File, entry archiveEntry, visit func(entry archiveEntry, content io.Reader) error) error {
+	if entry.dir || !entry.regular {
+		return visit(entry, bytes.NewReader(nil))
+	}
+
+	content, err := file.Open()
+	if err != nil {
+		return readArchiveError(err)
+	}
+	defer content.Close()
+
+	return visit(entry, content)
+}
+
+func readArchiveError(err error) error {
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, os.ErrPermission) {
+		return workspace.NormalizeIOError(err, "open archive failed")
+	}
+
+	return workspace.NewError(workspace.ErrorInvalidArgument, fmt.Sprintf("read archive: %v", err))
+}
@@ -0,0 +1,180 @@
+package fs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"miniclaw/pkg/workspace"
+)
+
+// AuditFile is the append-only mutation log, relative to the workspace root.
+const AuditFile = ".miniclaw/audit.jsonl"
+
+// AuditEntry is one successful file mutation by a tool.
+type AuditEntry struct {
+	At time.Time `json:"at"`
+	// Session is the gateway session key or provider session ID the tool ran for.
+	Session string `json:"session,omitempty"`
+	Tool    string `json:"tool"`
+	// Path is workspace-relative, or absolute for allowed paths outside the workspace.
+	Path string `json:"path"`
+	// BytesBefore and BytesAfter are the file size before and after the
+	// change; a removed directory reports the total size of its files.
+	BytesBefore int64 `json:"bytes_before"`
+	BytesAfter  int64 `json:"bytes_after"`
+	Deleted     bool  `json:"deleted,omitempty"`
+}
+
+// AuditLog appends mutation entries, one JSON object per line, to AuditFile.
+// Entries are only ever appended; the log is never rewritten or pruned.
+type AuditLog struct {
+	path string
+
+	mu sync.Mutex
+}
+
+type auditSessionKey struct{}
+
+// OpenAudit returns the audit log of the guard's workspace. Nothing is
+// written until the first entry is appended.
+func OpenAudit(guard *workspace.Guard) *AuditLog {
+	return &AuditLog{path: filepath.Join(guard.Root(), filepath.FromSlash(AuditFile))}
+}
+
+// Path returns the absolute path of the audit file.
+func (a *AuditLog) Path() string {
+	return a.path
+}
+
+// Append writes entries to the end of the audit file.
+func (a *AuditLog) Append(entries ...AuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	var lines []byte
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("encode audit entry: %w", err)
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(a.path), 0o755); err != nil {
+		return fmt.Errorf("create audit directory: %w", err)
+	}
+	file, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open audit file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(lines); err != nil {
+		return fmt.Errorf("write audit file: %w", err)
+	}
+
+	return nil
+}
+
+// Tail returns the last n entries, oldest first. A missing audit file has no
+// entries, and lines that do not decode are skipped.
+func (a *AuditLog) Tail(n int) ([]AuditEntry, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	file, err := os.Open(a.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open audit file: %w", err)
+	}
+	defer file.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+		if len(entries) > n {
+			entries = entries[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read audit file: %w", err)
+	}
+
+	return entries, nil
+}
+
+// WithAuditSession attaches the session key recorded with audit entries for tool calls made under ctx.
+func WithAuditSession(ctx context.Context, session string) context.Context {
+	return context.WithValue(ctx, auditSessionKey{}, strings.TrimSpace(session))
+}
+
+// AuditSessionFromContext returns the session key attached with WithAuditSession.
+func AuditSessionFromContext(ctx context.Context) (string, bool) {
+	session, ok := ctx.Value(auditSessionKey{}).(string)
+	return session, ok && session != ""
+}
+
+// EnableAudit records every successful write, append, edit, and delete made
+// through the service in AuditFile.
+func (s *Service) EnableAudit() {
+	s.auditLog = OpenAudit(s.guard)
+}
+
+// recordAudit stamps and appends entries; it is a no-op unless auditing is
+// enabled. The change has already happened, so a failed append is logged
+// rather than returned.
+func (s *Service) recordAudit(ctx context.Context, tool string, entries ...AuditEntry) {
+	if s.auditLog == nil || len(entries) == 0 {
+		return
+	}
+
+	at := time.Now().UTC()
+	session, _ := AuditSessionFromContext(ctx)
+	for index := range entries {
+		entries[index].At = at
+		entries[index].Session = session
+		entries[index].Tool = tool
+		entries[index].Path = filepath.ToSlash(s.guard.RelPath(entries[index].Path))
+	}
+	if err := s.auditLog.Append(entries...); err != nil {
+		slog.Default().With("component", "tools.fs").Warn("Audit log write failed",
+			"tool", tool,
+			"error", err,
+		)
+	}
+}
+
+// fileSize returns the size of a regular file, or zero when it does not exist.
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return 0
+	}
+
+	return info.Size()
+}
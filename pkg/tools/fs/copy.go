@@ -0,0 +1,114 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"miniclaw/pkg/workspace"
+)
+
+// CopyResult reports the outcome of CopyFile.
+type CopyResult struct {
+	Source      string
+	Destination string
+	BytesCopied int
+	Overwrote   bool
+}
+
+// CopyFile copies one regular file to a new location inside the workspace.
+//
+// An existing destination is only replaced when overwrite is true. The copy
+// uses 0644 permissions unless preserveMode is set, in which case the source
+// permission bits are kept. Files larger than max_write_bytes are refused.
+func (s *Service) CopyFile(ctx context.Context, source string, destination string, overwrite bool, preserveMode bool) (CopyResult, error) {
+	ctx, cancel := s.withOperationContext(ctx)
+	defer cancel()
+
+	if err := checkContext(ctx); err != nil {
+		return CopyResult{}, err
+	}
+
+	resolvedSource, err := s.guard.ResolvePath(source)
+	if err != nil {
+		return CopyResult{}, err
+	}
+	if err := s.checkIgnored(resolvedSource); err != nil {
+		return CopyResult{}, err
+	}
+	resolvedDestination, err := s.guard.ResolvePath(destination)
+	if err != nil {
+		return CopyResult{}, err
+	}
+	if resolvedSource == resolvedDestination {
+		return CopyResult{}, workspace.NewError(workspace.ErrorInvalidPath, "source and destination are the same file")
+	}
+
+	sourceInfo, err := os.Stat(resolvedSource)
+	if err != nil {
+		return CopyResult{}, workspace.NormalizeIOError(err, "stat source failed")
+	}
+	if !sourceInfo.Mode().IsRegular() {
+		return CopyResult{}, workspace.NewError(workspace.ErrorInvalidPath, "source is not a regular file")
+	}
+	if sourceInfo.Size() > int64(s.maxWriteBytes) {
+		return CopyResult{}, workspace.NewError(workspace.ErrorIO, fmt.Sprintf("source exceeds max_write_bytes (%d)", s.maxWriteBytes))
+	}
+
+	overwrote := false
+	var sizeBefore int64
+	if destinationInfo, statErr := os.Stat(resolvedDestination); statErr == nil {
+		if destinationInfo.IsDir() {
+			return CopyResult{}, workspace.NewError(workspace.ErrorInvalidPath, "destination is a directory")
+		}
+		if !overwrite {
+			return CopyResult{}, workspace.NewError(workspace.ErrorAlreadyExists, "destination exists; set overwrite to replace it")
+		}
+		overwrote = true
+		sizeBefore = destinationInfo.Size()
+	} else if !os.IsNotExist(statErr) {
+		return CopyResult{}, workspace.NormalizeIOError(statErr, "stat destination failed")
+	}
+
+	content, err := os.ReadFile(resolvedSource)
+	if err != nil {
+		return CopyResult{}, workspace.NormalizeIOError(err, "read source failed")
+	}
+	if len(content) > s.maxWriteBytes {
+		return CopyResult{}, workspace.NewError(workspace.ErrorIO, fmt.Sprintf("source exceeds max_write_bytes (%d)", s.maxWriteBytes))
+	}
+	if err := checkContext(ctx); err != nil {
+		return CopyResult{}, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(resolvedDestination), 0o755); err != nil {
+		return CopyResult{}, workspace.NormalizeIOError(err, "create parent directory failed")
+	}
+	if err := s.guard.EnsureContained(resolvedDestination); err != nil {
+		return CopyResult{}, err
+	}
+	if err := s.checkQuota(ctx, quotaWrite{path: resolvedDestination, size: int64(len(content))}); err != nil {
+		return CopyResult{}, err
+	}
+	if err := s.snapshot("copy_file", resolvedDestination); err != nil {
+		return CopyResult{}, err
+	}
+
+	mode := os.FileMode(0o644)
+	if preserveMode {
+		mode = sourceInfo.Mode().Perm()
+	}
+	s.forget(resolvedDestination)
+	if err := atomicWrite(resolvedDestination, content, mode); err != nil {
+		return CopyResult{}, workspace.NormalizeIOError(err, "write failed")
+	}
+	s.recordAudit(ctx, "copy_file", AuditEntry{Path: resolvedDestination, BytesBefore: sizeBefore, BytesAfter: int64(len(content))})
+
+	return CopyResult{
+		Source:      resolvedSource,
+		Destination: resolvedDestination,
+		BytesCopied: len(content),
+		Overwrote:   overwrote,
+	}, nil
+}
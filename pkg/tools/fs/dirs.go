@@ -0,0 +1,135 @@
+package fs
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"miniclaw/pkg/workspace"
+)
+
+// MakeDirResult reports the outcome of MakeDir.
+type MakeDirResult struct {
+	Path    string
+	Created bool
+}
+
+// RemoveDirResult reports the outcome of RemoveDir.
+type RemoveDirResult struct {
+	Path           string
+	EntriesRemoved int
+}
+
+// MakeDir creates a directory (and any missing parents) inside the workspace.
+//
+// Creating a directory that already exists is not an error, so the tool is
+// safe to call before writing several files into the same folder.
+func (s *Service) MakeDir(ctx context.Context, path string) (MakeDirResult, error) {
+	ctx, cancel := s.withOperationContext(ctx)
+	defer cancel()
+
+	if err := checkContext(ctx); err != nil {
+		return MakeDirResult{}, err
+	}
+
+	resolvedPath, err := s.guard.ResolvePath(path)
+	if err != nil {
+		return MakeDirResult{}, err
+	}
+
+	if info, statErr := os.Stat(resolvedPath); statErr == nil {
+		if !info.IsDir() {
+			return MakeDirResult{}, workspace.NewError(workspace.ErrorInvalidPath, "path exists and is not a directory")
+		}
+		return MakeDirResult{Path: resolvedPath}, nil
+	} else if !os.IsNotExist(statErr) {
+		return MakeDirResult{}, workspace.NormalizeIOError(statErr, "stat failed")
+	}
+
+	if err := os.MkdirAll(resolvedPath, 0o755); err != nil {
+		return MakeDirResult{}, workspace.NormalizeIOError(err, "create directory failed")
+	}
+
+	if err := s.guard.EnsureContained(resolvedPath); err != nil {
+		return MakeDirResult{}, err
+	}
+
+	return MakeDirResult{Path: resolvedPath, Created: true}, nil
+}
+
+// RemoveDir deletes a directory inside the workspace.
+//
+// Non-empty directories are only removed when recursive is true. The
+// workspace root itself can never be removed.
+func (s *Service) RemoveDir(ctx context.Context, path string, recursive bool) (RemoveDirResult, error) {
+	ctx, cancel := s.withOperationContext(ctx)
+	defer cancel()
+
+	if err := checkContext(ctx); err != nil {
+		return RemoveDirResult{}, err
+	}
+
+	resolvedPath, err := s.guard.ResolvePath(path)
+	if err != nil {
+		return RemoveDirResult{}, err
+	}
+	if resolvedPath == s.guard.Root() {
+		return RemoveDirResult{}, workspace.NewError(workspace.ErrorInvalidPath, "refusing to remove workspace root")
+	}
+
+	info, err := os.Lstat(resolvedPath)
+	if err != nil {
+		return RemoveDirResult{}, workspace.NormalizeIOError(err, "stat failed")
+	}
+	if !info.IsDir() {
+		return RemoveDirResult{}, workspace.NewError(workspace.ErrorInvalidPath, "path is not a directory")
+	}
+
+	entries, err := os.ReadDir(resolvedPath)
+	if err != nil {
+		return RemoveDirResult{}, workspace.NormalizeIOError(err, "read directory failed")
+	}
+	if len(entries) > 0 && !recursive {
+		return RemoveDirResult{}, workspace.NewError(workspace.ErrorDirNotEmpty, "directory is not empty; set recursive to remove its contents")
+	}
+
+	if err := s.guard.EnsureContained(resolvedPath); err != nil {
+		return RemoveDirResult{}, err
+	}
+
+	removed := 0
+	var (
+		files []string
+		bytes int64
+	)
+	if len(entries) > 0 {
+		walkErr := filepath.WalkDir(resolvedPath, func(current string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if current != resolvedPath {
+				removed++
+			}
+			if entry.Type().IsRegular() {
+				files = append(files, current)
+				bytes += fileSize(current)
+			}
+			return checkContext(ctx)
+		})
+		if walkErr != nil {
+			return RemoveDirResult{}, workspace.NormalizeIOError(walkErr, "scan directory failed")
+		}
+	}
+	if err := s.snapshot("remove_dir", files...); err != nil {
+		return RemoveDirResult{}, err
+	}
+
+	s.forget(resolvedPath)
+	if err := os.RemoveAll(resolvedPath); err != nil {
+		return RemoveDirResult{}, workspace.NormalizeIOError(err, "remove directory failed")
+	}
+	s.recordAudit(ctx, "remove_dir", AuditEntry{Path: resolvedPath, BytesBefore: bytes, Deleted: true})
+
+	return RemoveDirResult{Path: resolvedPath, EntriesRemoved: removed}, nil
+}
@@ -0,0 +1,104 @@
+package fs
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"miniclaw/pkg/workspace"
+)
+
+const (
+	HashSHA256 = "sha256"
+	HashMD5    = "md5"
+)
+
+// HashResult reports the digest of one file.
+type HashResult struct {
+	Path      string
+	Algorithm string
+	Digest    string
+	Size      int64
+}
+
+// HashFile returns the hex digest of a file using sha256 (default) or md5.
+//
+// The file is streamed, so max_read_bytes does not apply; large files are
+// bounded by the operation timeout instead.
+func (s *Service) HashFile(ctx context.Context, path string, algorithm string) (HashResult, error) {
+	ctx, cancel := s.withOperationContext(ctx)
+	defer cancel()
+
+	if err := checkContext(ctx); err != nil {
+		return HashResult{}, err
+	}
+
+	algorithm = strings.ToLower(strings.TrimSpace(algorithm))
+	var hasher hash.Hash
+	switch algorithm {
+	case "", HashSHA256:
+		algorithm = HashSHA256
+		hasher = sha256.New()
+	case HashMD5:
+		hasher = md5.New()
+	default:
+		return HashResult{}, workspace.NewError(workspace.ErrorInvalidArgument, fmt.Sprintf("unsupported hash algorithm %q (use sha256 or md5)", algorithm))
+	}
+
+	resolvedPath, err := s.guard.ResolvePath(path)
+	if err != nil {
+		return HashResult{}, err
+	}
+	if err := s.checkIgnored(resolvedPath); err != nil {
+		return HashResult{}, err
+	}
+
+	file, err := os.Open(resolvedPath)
+	if err != nil {
+		return HashResult{}, workspace.NormalizeIOError(err, "open failed")
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return HashResult{}, workspace.NormalizeIOError(err, "stat failed")
+	}
+	if !info.Mode().IsRegular() {
+		return HashResult{}, workspace.NewError(workspace.ErrorInvalidPath, "path is not a regular file")
+	}
+
+	size, err := io.Copy(hasher, contextReader{ctx: ctx, reader: file})
+	if err != nil {
+		if ctxErr := checkContext(ctx); ctxErr != nil {
+			return HashResult{}, ctxErr
+		}
+		return HashResult{}, workspace.NormalizeIOError(err, "read failed")
+	}
+
+	return HashResult{
+		Path:      resolvedPath,
+		Algorithm: algorithm,
+		Digest:    hex.EncodeToString(hasher.Sum(nil)),
+		Size:      size,
+	}, nil
+}
+
+// contextReader stops a streaming read once the operation context is done.
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.reader.Read(p)
+}
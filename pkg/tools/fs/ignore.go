@@ -0,0 +1,217 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"miniclaw/pkg/workspace"
+)
+
+// IgnoreFile is the gitignore-syntax file in the workspace root whose
+// patterns hide paths from the listing, search, and read tools.
+const IgnoreFile = ".miniclawignore"
+
+// ignoreRule is one compiled pattern line.
+type ignoreRule struct {
+	pattern *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Ignore matches workspace-relative paths against gitignore-style patterns.
+//
+// Supported syntax: blank lines and # comments, ! negation, a trailing / for
+// directories only, a leading or inner / to anchor to the workspace root,
+// and the *, ?, [...] and ** wildcards. As in git, a file inside an ignored
+// directory cannot be re-included by a later negation.
+type Ignore struct {
+	rules []ignoreRule
+}
+
+// ParseIgnore compiles ignore file content. Lines that do not form a valid
+// pattern are skipped.
+func ParseIgnore(content string) *Ignore {
+	ignore := &Ignore{}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if !strings.HasSuffix(line, `\ `) {
+			line = strings.TrimRight(line, " \t")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if line == "" {
+			continue
+		}
+
+		expression := globExpression(line)
+		if !anchored {
+			expression = "(?:.*/)?" + expression
+		}
+		compiled, err := regexp.Compile("^" + expression + "$")
+		if err != nil {
+			continue
+		}
+		rule.pattern = compiled
+		ignore.rules = append(ignore.rules, rule)
+	}
+
+	return ignore
+}
+
+// Match reports whether rel, a slash-separated path relative to the
+// workspace root, is ignored. isDir says whether rel itself is a directory.
+func (i *Ignore) Match(rel string, isDir bool) bool {
+	if i == nil || len(i.rules) == 0 {
+		return false
+	}
+	rel = strings.Trim(filepath.ToSlash(rel), "/")
+	if rel == "" || rel == "." {
+		return false
+	}
+
+	parts := strings.Split(rel, "/")
+	for index := range parts {
+		last := index == len(parts)-1
+		if i.matchOne(strings.Join(parts[:index+1], "/"), !last || isDir) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchOne applies every rule to one path; the last matching rule wins.
+func (i *Ignore) matchOne(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range i.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.pattern.MatchString(rel) {
+			ignored = !rule.negate
+		}
+	}
+
+	return ignored
+}
+
+// globExpression translates one gitignore glob into a regular expression.
+func globExpression(glob string) string {
+	var b strings.Builder
+	for index := 0; index < len(glob); index++ {
+		char := glob[index]
+		switch {
+		case char == '*' && strings.HasPrefix(glob[index:], "**/") && (index == 0 || glob[index-1] == '/'):
+			b.WriteString("(?:.*/)?")
+			index += 2
+		case char == '*' && strings.HasPrefix(glob[index:], "**") && index+2 == len(glob) && (index == 0 || glob[index-1] == '/'):
+			b.WriteString(".*")
+			index++
+		case char == '*':
+			b.WriteString("[^/]*")
+		case char == '?':
+			b.WriteString("[^/]")
+		case char == '\\' && index+1 < len(glob):
+			index++
+			b.WriteString(regexp.QuoteMeta(string(glob[index])))
+		case char == '[':
+			end := strings.IndexByte(glob[index+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[index+1 : index+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			index += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+
+	return b.String()
+}
+
+// ignoreCache reloads the ignore file when its size or modification time changes.
+type ignoreCache struct {
+	mu      sync.Mutex
+	size    int64
+	modTime time.Time
+	loaded  bool
+	ignore  *Ignore
+}
+
+// current returns the patterns of the ignore file at path, or nil when there is none.
+func (c *ignoreCache) current(path string) *Ignore {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		c.loaded, c.ignore = false, nil
+		return nil
+	}
+	if c.loaded && info.Size() == c.size && info.ModTime().Equal(c.modTime) {
+		return c.ignore
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		c.loaded, c.ignore = false, nil
+		return nil
+	}
+	c.loaded, c.size, c.modTime = true, info.Size(), info.ModTime()
+	c.ignore = ParseIgnore(string(content))
+	return c.ignore
+}
+
+// ignored reports whether a resolved path is hidden from the read-side tools:
+// matched by the workspace ignore file, inside the snapshot store, which
+// keeps copies of files that may themselves be ignored, or the audit log.
+// Paths outside the workspace are never ignored.
+func (s *Service) ignored(resolvedPath string, isDir bool) bool {
+	rel := s.guard.RelPath(resolvedPath)
+	if filepath.IsAbs(rel) {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == SnapshotDir || strings.HasPrefix(rel, SnapshotDir+"/") || rel == AuditFile {
+		return true
+	}
+
+	return s.ignores.current(filepath.Join(s.guard.Root(), IgnoreFile)).Match(rel, isDir)
+}
+
+// checkIgnored fails with permission_denied when a resolved path is hidden by the ignore file.
+func (s *Service) checkIgnored(resolvedPath string) error {
+	isDir := false
+	if info, err := os.Stat(resolvedPath); err == nil {
+		isDir = info.IsDir()
+	}
+	if s.ignored(resolvedPath, isDir) {
+		return workspace.NewError(workspace.ErrorPermissionDenied, "path is hidden by "+IgnoreFile)
+	}
+
+	return nil
+}
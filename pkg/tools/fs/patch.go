@@ -0,0 +1,505 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"miniclaw/pkg/workspace"
+)
+
+const devNull = "/dev/null"
+
+const (
+	PatchOperationModify = "modify"
+	PatchOperationCreate = "create"
+	PatchOperationDelete = "delete"
+)
+
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// PatchHunkRejection explains why one hunk could not be applied.
+type PatchHunkRejection struct {
+	// Hunk is the 1-based hunk index within its file.
+	Hunk     int
+	OldStart int
+	Reason   string
+}
+
+// PatchFileResult reports how a patch affected one file.
+type PatchFileResult struct {
+	Path         string
+	Operation    string
+	HunksApplied int
+	Rejected     []PatchHunkRejection
+}
+
+// PatchResult summarizes one ApplyPatch call.
+type PatchResult struct {
+	Files []PatchFileResult
+}
+
+type filePatch struct {
+	oldPath string
+	newPath string
+	hunks   []patchHunk
+}
+
+type patchHunk struct {
+	oldStart int
+	oldLines []string
+	newLines []string
+	// oldNoNewline/newNoNewline record "\ No newline at end of file" markers.
+	oldNoNewline bool
+	newNoNewline bool
+}
+
+// plannedWrite is one fully computed file change waiting to be committed.
+type plannedWrite struct {
+	path     string
+	content  []byte
+	mode     os.FileMode
+	remove   bool
+	original []byte
+	existed  bool
+}
+
+// ApplyPatch applies a unified diff to files inside the workspace.
+//
+// Every hunk of every file is checked before anything is written: if any hunk
+// is rejected, no file is changed and the error lists each rejected hunk.
+// Hunks are located at their stated line first and then by scanning outward,
+// so patches with slightly stale line numbers still apply when the context
+// matches exactly.
+func (s *Service) ApplyPatch(ctx context.Context, patch string) (PatchResult, error) {
+	ctx, cancel := s.withOperationContext(ctx)
+	defer cancel()
+
+	if len(patch) > s.maxWriteBytes {
+		return PatchResult{}, workspace.NewError(workspace.ErrorIO, fmt.Sprintf("patch exceeds max_write_bytes (%d)", s.maxWriteBytes))
+	}
+
+	files, err := parseUnifiedDiff(patch)
+	if err != nil {
+		return PatchResult{}, err
+	}
+
+	result := PatchResult{Files: make([]PatchFileResult, 0, len(files))}
+	planned := make([]plannedWrite, 0, len(files))
+	rejected := 0
+	for _, file := range files {
+		if err := checkContext(ctx); err != nil {
+			return PatchResult{}, err
+		}
+
+		fileResult, write, err := s.planFilePatch(file)
+		if err != nil {
+			return PatchResult{}, err
+		}
+		result.Files = append(result.Files, fileResult)
+		rejected += len(fileResult.Rejected)
+		planned = append(planned, write)
+	}
+
+	if rejected > 0 {
+		return result, workspace.NewError(workspace.ErrorPatchRejected, describeRejections(s.guard, result, rejected))
+	}
+
+	writes := make([]quotaWrite, 0, len(planned))
+	for _, write := range planned {
+		writes = append(writes, quotaWrite{path: write.path, size: int64(len(write.content)), remove: write.remove})
+	}
+	if err := s.checkQuota(ctx, writes...); err != nil {
+		return PatchResult{}, err
+	}
+	targets := make([]string, 0, len(planned))
+	for _, write := range planned {
+		targets = append(targets, write.path)
+	}
+	if err := s.snapshot("apply_patch", targets...); err != nil {
+		return PatchResult{}, err
+	}
+
+	if err := s.commitPatch(ctx, planned); err != nil {
+		return PatchResult{}, err
+	}
+	audited := make([]AuditEntry, 0, len(planned))
+	for _, write := range planned {
+		entry := AuditEntry{Path: write.path, BytesBefore: int64(len(write.original)), BytesAfter: int64(len(write.content)), Deleted: write.remove}
+		if write.remove {
+			entry.BytesAfter = 0
+		}
+		audited = append(audited, entry)
+	}
+	s.recordAudit(ctx, "apply_patch", audited...)
+
+	return result, nil
+}
+
+// planFilePatch applies hunks in memory and returns the write that would commit them.
+func (s *Service) planFilePatch(file filePatch) (PatchFileResult, plannedWrite, error) {
+	operation := PatchOperationModify
+	target := file.newPath
+	switch {
+	case file.oldPath == devNull:
+		operation = PatchOperationCreate
+	case file.newPath == devNull:
+		operation = PatchOperationDelete
+		target = file.oldPath
+	}
+
+	resolvedPath, err := s.guard.ResolvePath(target)
+	if err != nil {
+		return PatchFileResult{}, plannedWrite{}, err
+	}
+	fileResult := PatchFileResult{Path: resolvedPath, Operation: operation}
+	write := plannedWrite{path: resolvedPath, mode: 0o644, remove: operation == PatchOperationDelete}
+
+	original := []byte{}
+	info, statErr := os.Stat(resolvedPath)
+	switch {
+	case statErr == nil && operation == PatchOperationCreate:
+		fileResult.Rejected = append(fileResult.Rejected, PatchHunkRejection{Hunk: 1, Reason: "file already exists"})
+		return fileResult, write, nil
+	case statErr == nil:
+		if info.IsDir() {
+			return PatchFileResult{}, plannedWrite{}, workspace.NewError(workspace.ErrorInvalidPath, "patch target is a directory")
+		}
+		original, err = os.ReadFile(resolvedPath)
+		if err != nil {
+			return PatchFileResult{}, plannedWrite{}, workspace.NormalizeIOError(err, "read failed")
+		}
+		if err := ensureText(original); err != nil {
+			return PatchFileResult{}, plannedWrite{}, err
+		}
+		write.mode = info.Mode().Perm()
+		write.original = original
+		write.existed = true
+	case os.IsNotExist(statErr) && operation != PatchOperationCreate:
+		fileResult.Rejected = append(fileResult.Rejected, PatchHunkRejection{Hunk: 1, Reason: "file does not exist"})
+		return fileResult, write, nil
+	case !os.IsNotExist(statErr):
+		return PatchFileResult{}, plannedWrite{}, workspace.NormalizeIOError(statErr, "stat failed")
+	}
+
+	lines, trailingNewline := splitLines(string(original))
+	delta := 0
+	searchFrom := 0
+	for index, hunk := range file.hunks {
+		// A hunk without old lines (git diff -U0 insertions) inserts after
+		// line oldStart rather than replacing from it.
+		expected := max(hunk.oldStart-1, 0) + delta
+		if len(hunk.oldLines) == 0 {
+			expected = hunk.oldStart + delta
+		}
+		position, ok := locateHunk(lines, hunk.oldLines, expected, searchFrom)
+		if !ok {
+			fileResult.Rejected = append(fileResult.Rejected, PatchHunkRejection{
+				Hunk:     index + 1,
+				OldStart: hunk.oldStart,
+				Reason:   "context does not match file contents",
+			})
+			continue
+		}
+
+		updated := make([]string, 0, len(lines)-len(hunk.oldLines)+len(hunk.newLines))
+		updated = append(updated, lines[:position]...)
+		updated = append(updated, hunk.newLines...)
+		updated = append(updated, lines[position+len(hunk.oldLines):]...)
+		touchesEOF := position+len(hunk.oldLines) == len(lines)
+		lines = updated
+
+		if touchesEOF {
+			if hunk.newNoNewline {
+				trailingNewline = false
+			} else if hunk.oldNoNewline || len(original) == 0 {
+				trailingNewline = true
+			}
+		}
+
+		delta += len(hunk.newLines) - len(hunk.oldLines)
+		searchFrom = position + len(hunk.newLines)
+		fileResult.HunksApplied++
+	}
+
+	content := strings.Join(lines, "\n")
+	if trailingNewline && len(lines) > 0 {
+		content += "\n"
+	}
+	if operation == PatchOperationDelete && content != "" && len(fileResult.Rejected) == 0 {
+		fileResult.Rejected = append(fileResult.Rejected, PatchHunkRejection{Hunk: len(file.hunks), Reason: "file is not empty after removing patched lines"})
+	}
+	if len(content) > s.maxWriteBytes {
+		return PatchFileResult{}, plannedWrite{}, workspace.NewError(workspace.ErrorIO, fmt.Sprintf("content exceeds max_write_bytes (%d)", s.maxWriteBytes))
+	}
+
+	write.content = []byte(content)
+	return fileResult, write, nil
+}
+
+// commitPatch writes all planned changes, restoring earlier files if a later write fails.
+func (s *Service) commitPatch(ctx context.Context, planned []plannedWrite) error {
+	for index, write := range planned {
+		err := checkContext(ctx)
+		if err == nil {
+			err = s.commitWrite(write)
+		}
+		if err != nil {
+			for _, done := range planned[:index] {
+				rollbackWrite(done)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) commitWrite(write plannedWrite) error {
+	s.forget(write.path)
+	if write.remove {
+		if err := s.guard.EnsureContained(write.path); err != nil {
+			return err
+		}
+		if err := os.Remove(write.path); err != nil {
+			return workspace.NormalizeIOError(err, "delete failed")
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(write.path), 0o755); err != nil {
+		return workspace.NormalizeIOError(err, "create parent directory failed")
+	}
+	if err := s.guard.EnsureContained(write.path); err != nil {
+		return err
+	}
+	if err := atomicWrite(write.path, write.content, write.mode); err != nil {
+		return workspace.NormalizeIOError(err, "write failed")
+	}
+
+	return nil
+}
+
+// rollbackWrite restores a file to its pre-patch state on a best-effort basis.
+func rollbackWrite(write plannedWrite) {
+	if !write.existed {
+		_ = os.Remove(write.path)
+		return
+	}
+
+	_ = atomicWrite(write.path, write.original, write.mode)
+}
+
+// locateHunk finds where oldLines occur, preferring expected and scanning outward from it.
+func locateHunk(lines []string, oldLines []string, expected int, searchFrom int) (int, bool) {
+	lastStart := len(lines) - len(oldLines)
+	if lastStart < searchFrom {
+		return 0, false
+	}
+	expected = min(max(expected, searchFrom), lastStart)
+
+	for distance := 0; ; distance++ {
+		before := expected - distance
+		after := expected + distance
+		if before < searchFrom && after > lastStart {
+			return 0, false
+		}
+		if before >= searchFrom && linesEqual(lines[before:before+len(oldLines)], oldLines) {
+			return before, true
+		}
+		if distance > 0 && after <= lastStart && linesEqual(lines[after:after+len(oldLines)], oldLines) {
+			return after, true
+		}
+	}
+}
+
+func linesEqual(left []string, right []string) bool {
+	for index := range right {
+		if left[index] != right[index] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// PatchPaths returns the files a unified diff touches, in patch order, using
+// the old path for deletions. It does not read the workspace.
+func PatchPaths(patch string) ([]string, error) {
+	files, err := parseUnifiedDiff(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		if file.newPath == devNull {
+			paths = append(paths, file.oldPath)
+			continue
+		}
+		paths = append(paths, file.newPath)
+	}
+
+	return paths, nil
+}
+
+// splitLines splits content into lines and reports whether it ended with a newline.
+func splitLines(content string) ([]string, bool) {
+	if content == "" {
+		return nil, false
+	}
+
+	trailingNewline := strings.HasSuffix(content, "\n")
+	content = strings.TrimSuffix(content, "\n")
+	return strings.Split(content, "\n"), trailingNewline
+}
+
+// parseUnifiedDiff parses git-style or plain unified diffs into per-file hunks.
+func parseUnifiedDiff(patch string) ([]filePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+
+	var files []filePatch
+	for index := 0; index < len(lines); {
+		line := lines[index]
+		if strings.HasPrefix(line, "--- ") && index+1 < len(lines) && strings.HasPrefix(lines[index+1], "+++ ") {
+			files = append(files, filePatch{
+				oldPath: patchPath(strings.TrimPrefix(line, "--- "), "a/"),
+				newPath: patchPath(strings.TrimPrefix(lines[index+1], "+++ "), "b/"),
+			})
+			index += 2
+			continue
+		}
+
+		if strings.HasPrefix(line, "@@ ") {
+			if len(files) == 0 {
+				return nil, workspace.NewError(workspace.ErrorInvalidPatch, "hunk found before ---/+++ file header")
+			}
+			hunk, next, err := parseHunk(lines, index)
+			if err != nil {
+				return nil, err
+			}
+			current := &files[len(files)-1]
+			current.hunks = append(current.hunks, hunk)
+			index = next
+			continue
+		}
+
+		// Anything else (diff --git, index, mode lines, prose) is ignored.
+		index++
+	}
+
+	if len(files) == 0 {
+		return nil, workspace.NewError(workspace.ErrorInvalidPatch, "no file headers found; expected ---/+++ lines")
+	}
+	for _, file := range files {
+		if file.oldPath == devNull && file.newPath == devNull {
+			return nil, workspace.NewError(workspace.ErrorInvalidPatch, "file header has no path")
+		}
+		if len(file.hunks) == 0 {
+			return nil, workspace.NewError(workspace.ErrorInvalidPatch, fmt.Sprintf("no hunks for %s", file.newPath))
+		}
+	}
+
+	return files, nil
+}
+
+// parseHunk reads one hunk starting at its @@ header and returns the index after it.
+func parseHunk(lines []string, index int) (patchHunk, int, error) {
+	match := hunkHeaderPattern.FindStringSubmatch(lines[index])
+	if match == nil {
+		return patchHunk{}, 0, workspace.NewError(workspace.ErrorInvalidPatch, fmt.Sprintf("malformed hunk header %q", lines[index]))
+	}
+
+	oldStart, _ := strconv.Atoi(match[1])
+	oldCount := hunkCount(match[2])
+	newCount := hunkCount(match[4])
+	hunk := patchHunk{oldStart: oldStart}
+
+	index++
+	lastKind := byte(' ')
+	for ; index < len(lines); index++ {
+		line := lines[index]
+		if strings.HasPrefix(line, `\`) {
+			// "\ No newline at end of file" applies to the line right before it.
+			if lastKind != '+' {
+				hunk.oldNoNewline = true
+			}
+			if lastKind != '-' {
+				hunk.newNoNewline = true
+			}
+			continue
+		}
+		if len(hunk.oldLines) >= oldCount && len(hunk.newLines) >= newCount {
+			break
+		}
+
+		kind := byte(' ')
+		body := ""
+		if line != "" {
+			kind = line[0]
+			body = line[1:]
+		}
+		switch kind {
+		case ' ':
+			hunk.oldLines = append(hunk.oldLines, body)
+			hunk.newLines = append(hunk.newLines, body)
+		case '-':
+			hunk.oldLines = append(hunk.oldLines, body)
+		case '+':
+			hunk.newLines = append(hunk.newLines, body)
+		default:
+			return patchHunk{}, 0, workspace.NewError(workspace.ErrorInvalidPatch, fmt.Sprintf("unexpected line in hunk: %q", line))
+		}
+		lastKind = kind
+	}
+
+	if len(hunk.oldLines) != oldCount || len(hunk.newLines) != newCount {
+		return patchHunk{}, 0, workspace.NewError(workspace.ErrorInvalidPatch, fmt.Sprintf("hunk at -%d has %d/%d lines, header says %d/%d", oldStart, len(hunk.oldLines), len(hunk.newLines), oldCount, newCount))
+	}
+
+	return hunk, index, nil
+}
+
+func hunkCount(raw string) int {
+	if raw == "" {
+		return 1
+	}
+	count, _ := strconv.Atoi(raw)
+	return count
+}
+
+// patchPath strips diff prefixes and trailing timestamps from a header path.
+func patchPath(raw string, prefix string) string {
+	path, _, _ := strings.Cut(raw, "\t")
+	path = strings.TrimSpace(path)
+	if path == devNull {
+		return devNull
+	}
+
+	return strings.TrimPrefix(path, prefix)
+}
+
+func describeRejections(guard *workspace.Guard, result PatchResult, rejected int) string {
+	total := 0
+	for _, file := range result.Files {
+		total += file.HunksApplied + len(file.Rejected)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d of %d hunk(s) rejected; no files were changed", rejected, total)
+	for _, file := range result.Files {
+		for _, rejection := range file.Rejected {
+			fmt.Fprintf(&b, "\n- %s hunk %d", guard.RelPath(file.Path), rejection.Hunk)
+			if rejection.OldStart > 0 {
+				fmt.Fprintf(&b, " (@@ -%d)", rejection.OldStart)
+			}
+			fmt.Fprintf(&b, ": %s", rejection.Reason)
+		}
+	}
+
+	return b.String()
+}
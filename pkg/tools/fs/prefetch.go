@@ -0,0 +1,250 @@
+package fs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// PrefetchMaxFileBytes bounds which files are cached and prefetched.
+	PrefetchMaxFileBytes = 32 * 1024
+	DefaultPrefetchFiles = 4
+	readCacheEntries     = 64
+)
+
+// likelyReadNames are file names an agent usually opens right after listing a directory.
+var likelyReadNames = map[string]int{
+	"readme":         5,
+	"readme.md":      5,
+	"agents.md":      5,
+	"go.mod":         4,
+	"package.json":   4,
+	"cargo.toml":     4,
+	"pyproject.toml": 4,
+	"makefile":       4,
+	"dockerfile":     3,
+}
+
+// readCache holds small file contents keyed by resolved path.
+//
+// Entries are validated against size and modification time on every read, so
+// files changed outside the service (for example by run_command) are re-read.
+type readCache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	order   []string
+}
+
+type cacheEntry struct {
+	content []byte
+	size    int64
+	modTime time.Time
+}
+
+// EnablePrefetch turns on the read cache and background prefetch of up to
+// maxFiles likely reads after each list_dir or search_files call.
+func (s *Service) EnablePrefetch(maxFiles int) {
+	if maxFiles <= 0 {
+		maxFiles = DefaultPrefetchFiles
+	}
+
+	s.cache = &readCache{entries: make(map[string]cacheEntry)}
+	s.prefetchFiles = maxFiles
+}
+
+// readCached returns file content from cache when the entry still matches info.
+func (s *Service) readCached(path string, info os.FileInfo) ([]byte, bool) {
+	if s.cache == nil {
+		return nil, false
+	}
+
+	return s.cache.get(path, info)
+}
+
+// remember stores content read from disk when it is small enough to cache.
+func (s *Service) remember(path string, content []byte, info os.FileInfo) {
+	if s.cache == nil || info == nil || len(content) > PrefetchMaxFileBytes {
+		return
+	}
+
+	s.cache.put(path, content, info)
+}
+
+// forget drops cached entries for path and anything below it.
+func (s *Service) forget(path string) {
+	if s.cache == nil {
+		return
+	}
+
+	s.cache.invalidate(path)
+}
+
+// prefetchListing queues the most likely reads from a directory listing.
+func (s *Service) prefetchListing(dir string, entries []ListEntry) {
+	if s.cache == nil {
+		return
+	}
+
+	type candidate struct {
+		name  string
+		score int
+	}
+	candidates := make([]candidate, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir || entry.Size == 0 || entry.Size > PrefetchMaxFileBytes || strings.HasPrefix(entry.Name, ".") {
+			continue
+		}
+		candidates = append(candidates, candidate{name: entry.Name, score: likelyReadScore(entry.Name)})
+	}
+	sort.SliceStable(candidates, func(i int, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	paths := make([]string, 0, s.prefetchFiles)
+	for _, candidate := range candidates {
+		if len(paths) >= s.prefetchFiles {
+			break
+		}
+		paths = append(paths, filepath.Join(dir, candidate.name))
+	}
+	s.startPrefetch(paths)
+}
+
+// prefetchSearch queues the files with the most matches from a search.
+func (s *Service) prefetchSearch(matches []SearchMatch) {
+	if s.cache == nil {
+		return
+	}
+
+	counts := make(map[string]int)
+	order := make([]string, 0)
+	for _, match := range matches {
+		if counts[match.Path] == 0 {
+			order = append(order, match.Path)
+		}
+		counts[match.Path]++
+	}
+	sort.SliceStable(order, func(i int, j int) bool {
+		return counts[order[i]] > counts[order[j]]
+	})
+	s.startPrefetch(order[:min(len(order), s.prefetchFiles)])
+}
+
+// startPrefetch reads paths into the cache in the background.
+//
+// Failures are ignored: prefetching is only a hint, and the real read reports
+// any error to the model.
+func (s *Service) startPrefetch(paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+
+	s.prefetchWG.Add(1)
+	go func() {
+		defer s.prefetchWG.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), s.maxToolOperationDuration)
+		defer cancel()
+		for _, path := range paths {
+			if ctx.Err() != nil {
+				return
+			}
+			if err := s.guard.EnsureContained(path); err != nil {
+				continue
+			}
+			info, err := os.Stat(path)
+			if err != nil || !info.Mode().IsRegular() || info.Size() > PrefetchMaxFileBytes {
+				continue
+			}
+			if _, ok := s.readCached(path, info); ok {
+				continue
+			}
+			content, err := os.ReadFile(path)
+			if err != nil || ensureText(content) != nil {
+				continue
+			}
+			s.remember(path, content, info)
+		}
+	}()
+}
+
+// likelyReadScore ranks a file name by how often agents open it first.
+func likelyReadScore(name string) int {
+	lower := strings.ToLower(name)
+	if score, ok := likelyReadNames[lower]; ok {
+		return score
+	}
+
+	stem := strings.TrimSuffix(lower, filepath.Ext(lower))
+	switch {
+	case stem == "main" || stem == "index" || stem == "app":
+		return 3
+	case strings.HasSuffix(lower, ".md"):
+		return 2
+	case filepath.Ext(lower) != "":
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (c *readCache) get(path string, info os.FileInfo) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[path]
+	if !ok {
+		return nil, false
+	}
+	if entry.size != info.Size() || !entry.modTime.Equal(info.ModTime()) {
+		delete(c.entries, path)
+		return nil, false
+	}
+
+	return entry.content, true
+}
+
+func (c *readCache) put(path string, content []byte, info os.FileInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[path]; !ok {
+		c.order = append(c.order, path)
+	}
+	c.entries[path] = cacheEntry{content: content, size: info.Size(), modTime: info.ModTime()}
+
+	// Evict oldest insertions; order may hold paths already dropped by get or invalidate.
+	for len(c.entries) > readCacheEntries && len(c.order) > 0 {
+		oldest := c.order[0]
+		c.order = c.order[1:]
+		delete(c.entries, oldest)
+	}
+	if len(c.order) > 2*readCacheEntries {
+		live := c.order[:0]
+		seen := make(map[string]bool, len(c.entries))
+		for _, cached := range c.order {
+			if _, ok := c.entries[cached]; ok && !seen[cached] {
+				seen[cached] = true
+				live = append(live, cached)
+			}
+		}
+		c.order = live
+	}
+}
+
+func (c *readCache) invalidate(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prefix := path + string(filepath.Separator)
+	for cached := range c.entries {
+		if cached == path || strings.HasPrefix(cached, prefix) {
+			delete(c.entries, cached)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"miniclaw/pkg/workspace"
+)
+
+// quotaWrite is one file a pending operation leaves at size bytes, or deletes when remove is set.
+type quotaWrite struct {
+	path   string
+	size   int64
+	remove bool
+}
+
+// EnableDiskQuota caps the total bytes and number of regular files in the
+// workspace. Writes that would push usage past a limit fail with
+// quota_exceeded; writes that do not grow usage are always allowed, so an
+// agent can still shrink or delete files once the workspace is full. Zero
+// leaves a limit off.
+func (s *Service) EnableDiskQuota(maxBytes int64, maxFiles int) {
+	s.maxWorkspaceBytes = max(maxBytes, 0)
+	s.maxWorkspaceFiles = max(maxFiles, 0)
+}
+
+// checkQuota measures the workspace and rejects writes that would take it over a quota.
+func (s *Service) checkQuota(ctx context.Context, writes ...quotaWrite) error {
+	if s.maxWorkspaceBytes == 0 && s.maxWorkspaceFiles == 0 {
+		return nil
+	}
+
+	usedBytes, usedFiles, err := s.workspaceUsage(ctx)
+	if err != nil {
+		return err
+	}
+
+	bytes, files := usedBytes, usedFiles
+	for _, write := range writes {
+		// Writes to allowed paths outside the workspace do not count.
+		if filepath.IsAbs(s.guard.RelPath(write.path)) {
+			continue
+		}
+		if info, statErr := os.Lstat(write.path); statErr == nil && info.Mode().IsRegular() {
+			bytes -= info.Size()
+			files--
+		}
+		if !write.remove {
+			bytes += write.size
+			files++
+		}
+	}
+
+	if s.maxWorkspaceBytes > 0 && bytes > s.maxWorkspaceBytes && bytes > usedBytes {
+		return workspace.NewError(workspace.ErrorQuotaExceeded, fmt.Sprintf("workspace would hold %d bytes and the limit is %d; delete or shrink files first", bytes, s.maxWorkspaceBytes))
+	}
+	if s.maxWorkspaceFiles > 0 && files > s.maxWorkspaceFiles && files > usedFiles {
+		return workspace.NewError(workspace.ErrorQuotaExceeded, fmt.Sprintf("workspace would hold %d files and the limit is %d; delete files first", files, s.maxWorkspaceFiles))
+	}
+
+	return nil
+}
+
+// workspaceUsage sums the size and count of regular files under the workspace
+// root. Symlinks are not followed and unreadable directories are skipped.
+func (s *Service) workspaceUsage(ctx context.Context) (int64, int, error) {
+	var (
+		bytes int64
+		files int
+	)
+	err := filepath.WalkDir(s.guard.Root(), func(_ string, entry fs.DirEntry, err error) error {
+		if ctxErr := checkContext(ctx); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			if entry != nil && entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		bytes += info.Size()
+		files++
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return bytes, files, nil
+}
@@ -0,0 +1,142 @@
+package fs
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"miniclaw/pkg/workspace"
+)
+
+// ReadFileLines reads a line window from a text file inside the workspace.
+//
+// Unlike ReadFile, the whole file does not need to fit in max_read_bytes: the
+// file is streamed and only the requested window is returned. startLine is
+// 1-based; maxLines <= 0 reads until the byte budget is used. The window is
+// cut short (Truncated) when adding the next line would exceed max_read_bytes;
+// when the first line alone does, the window is the part of it that fits
+// (PartialLine) and the rest of that line is skipped.
+func (s *Service) ReadFileLines(ctx context.Context, path string, startLine int, maxLines int) (ReadResult, error) {
+	ctx, cancel := s.withOperationContext(ctx)
+	defer cancel()
+
+	if startLine < 1 {
+		startLine = 1
+	}
+
+	resolvedPath, err := s.guard.ResolvePath(path)
+	if err != nil {
+		return ReadResult{}, err
+	}
+	if err := s.checkIgnored(resolvedPath); err != nil {
+		return ReadResult{}, err
+	}
+
+	if err := checkContext(ctx); err != nil {
+		return ReadResult{}, err
+	}
+
+	file, err := os.Open(resolvedPath)
+	if err != nil {
+		return ReadResult{}, workspace.NormalizeIOError(err, "read failed")
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var content strings.Builder
+	lineNumber := 0
+	linesRead := 0
+	result := ReadResult{Path: resolvedPath, StartLine: startLine}
+
+	for {
+		if err := checkContext(ctx); err != nil {
+			return ReadResult{}, err
+		}
+
+		// Lines before the window are skipped without keeping any of them.
+		budget := 0
+		if lineNumber+1 >= startLine {
+			budget = s.maxReadBytes - content.Len()
+		}
+		line, size, readErr := readLine(reader, budget)
+		if size > 0 {
+			lineNumber++
+			if lineNumber >= startLine {
+				if maxLines > 0 && linesRead >= maxLines {
+					result.HasMore = true
+					break
+				}
+				if size > budget {
+					result.HasMore = true
+					result.Truncated = true
+					if linesRead > 0 {
+						break
+					}
+					// The first line alone exceeds max_read_bytes: return what
+					// fits of it rather than nothing.
+					line = validPrefix(line)
+					result.PartialLine = true
+					_, peekErr := reader.Peek(1)
+					result.HasMore = peekErr == nil
+					readErr = io.EOF
+				}
+				if err := ensureText(line); err != nil {
+					return ReadResult{}, err
+				}
+				content.Write(line)
+				linesRead++
+			}
+		}
+
+		if readErr != nil {
+			if errors.Is(readErr, io.EOF) {
+				break
+			}
+			return ReadResult{}, workspace.NormalizeIOError(readErr, "read failed")
+		}
+	}
+
+	if linesRead == 0 && startLine > 1 && lineNumber < startLine {
+		return ReadResult{}, workspace.NewError(workspace.ErrorInvalidRange, "offset is past the end of the file")
+	}
+
+	result.Content = content.String()
+	result.Bytes = content.Len()
+	result.EndLine = startLine + linesRead - 1
+	if linesRead == 0 {
+		result.EndLine = 0
+	}
+
+	return result, nil
+}
+
+// readLine reads the next line of reader, newline included, keeping at most
+// limit bytes of it and discarding the rest, so a long line or a file without
+// newlines is never held in memory whole. size is the full length of the
+// line; 0 means reader had nothing left.
+func readLine(reader *bufio.Reader, limit int) (line []byte, size int, err error) {
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if room := limit - len(line); room > 0 {
+			line = append(line, chunk[:min(len(chunk), room)]...)
+		}
+		size += len(chunk)
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return line, size, err
+		}
+	}
+}
+
+// validPrefix drops the bytes of a UTF-8 sequence a cut left incomplete at
+// the end of line.
+func validPrefix(line []byte) []byte {
+	for i := 0; i < utf8.UTFMax && len(line) > 0 && !utf8.Valid(line); i++ {
+		line = line[:len(line)-1]
+	}
+
+	return line
+}
@@ -0,0 +1,66 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"miniclaw/pkg/workspace"
+)
+
+// SaveFile creates a new file at path inside the workspace from content,
+// which must hold at most size bytes, and returns its resolved path. It
+// creates missing parent directories, refuses paths hidden by the ignore file
+// and existing files, and counts size against the disk quota before writing.
+//
+// Unlike the tool operations it has no time limit, so callers can stream
+// downloads into it; ctx still cancels it. A failed save leaves no file.
+func (s *Service) SaveFile(ctx context.Context, path string, content io.Reader, size int64) (string, error) {
+	if err := checkContext(ctx); err != nil {
+		return "", err
+	}
+
+	resolvedPath, err := s.guard.ResolvePath(path)
+	if err != nil {
+		return "", err
+	}
+	if err := s.checkIgnored(resolvedPath); err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(resolvedPath), 0o755); err != nil {
+		return "", workspace.NormalizeIOError(err, "create parent directory failed")
+	}
+	if err := s.guard.EnsureContained(resolvedPath); err != nil {
+		return "", err
+	}
+	if err := s.checkQuota(ctx, quotaWrite{path: resolvedPath, size: size}); err != nil {
+		return "", err
+	}
+
+	out, err := os.OpenFile(resolvedPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return "", workspace.NewError(workspace.ErrorAlreadyExists, "file exists")
+	}
+	if err != nil {
+		return "", workspace.NormalizeIOError(err, "create file failed")
+	}
+	written, err := io.Copy(out, contextReader{ctx: ctx, reader: io.LimitReader(content, size+1)})
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && written > size {
+		err = workspace.NewError(workspace.ErrorIO, fmt.Sprintf("content exceeds %d bytes", size))
+	}
+	if err != nil {
+		_ = os.Remove(resolvedPath)
+		return "", workspace.NormalizeIOError(err, "")
+	}
+	s.forget(resolvedPath)
+
+	return resolvedPath, nil
+}
@@ -0,0 +1,188 @@
+package fs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"miniclaw/pkg/workspace"
+)
+
+const (
+	MaxSearchMatches      = 100
+	MaxSearchBytesScanned = 8 * 1024 * 1024
+	MaxSearchSnippetChars = 200
+)
+
+// SearchMatch is one matching line found by SearchFiles.
+type SearchMatch struct {
+	Path    string
+	Line    int
+	Snippet string
+}
+
+// SearchResult summarizes one bounded content search.
+type SearchResult struct {
+	Path         string
+	Matches      []SearchMatch
+	FilesScanned int
+	BytesScanned int64
+	Truncated    bool
+}
+
+// SearchFiles scans text files below path for a regex or literal pattern.
+//
+// The walk is bounded by match count and total bytes scanned so a broad
+// search over a large workspace cannot exhaust memory or tool time. Binary
+// files, oversized files, symlinks, and paths hidden by the ignore file are
+// skipped rather than failing the whole search.
+func (s *Service) SearchFiles(ctx context.Context, path string, pattern string, literal bool) (SearchResult, error) {
+	ctx, cancel := s.withOperationContext(ctx)
+	defer cancel()
+
+	if pattern == "" {
+		return SearchResult{}, workspace.NewError(workspace.ErrorInvalidPattern, "pattern must not be empty")
+	}
+	expression := pattern
+	if literal {
+		expression = regexp.QuoteMeta(pattern)
+	}
+	matcher, err := regexp.Compile(expression)
+	if err != nil {
+		return SearchResult{}, workspace.NewError(workspace.ErrorInvalidPattern, err.Error())
+	}
+
+	if strings.TrimSpace(path) == "" {
+		path = "."
+	}
+	if err := checkContext(ctx); err != nil {
+		return SearchResult{}, err
+	}
+
+	resolvedPath, err := s.guard.ResolvePath(path)
+	if err != nil {
+		return SearchResult{}, err
+	}
+	if err := s.checkIgnored(resolvedPath); err != nil {
+		return SearchResult{}, err
+	}
+
+	result := SearchResult{Path: resolvedPath}
+	walkErr := filepath.WalkDir(resolvedPath, func(current string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if current == resolvedPath {
+				return err
+			}
+			// Unreadable subtrees are skipped so one bad directory does not hide other matches.
+			return nil
+		}
+		if err := checkContext(ctx); err != nil {
+			return err
+		}
+		if current != resolvedPath && s.ignored(current, entry.IsDir()) {
+			if entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || !entry.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil || info.Size() > int64(s.maxReadBytes) {
+			return nil
+		}
+		if result.BytesScanned+info.Size() > s.maxSearchBytes {
+			result.Truncated = true
+			return fs.SkipAll
+		}
+
+		content, err := os.ReadFile(current)
+		if err != nil {
+			return nil
+		}
+		result.FilesScanned++
+		result.BytesScanned += int64(len(content))
+		if ensureText(content) != nil {
+			return nil
+		}
+
+		if s.collectMatches(&result, current, content, matcher) {
+			result.Truncated = true
+			return fs.SkipAll
+		}
+
+		return nil
+	})
+	if walkErr != nil {
+		var categorized *workspace.Error
+		if errors.As(walkErr, &categorized) {
+			return SearchResult{}, walkErr
+		}
+		return SearchResult{}, workspace.NormalizeIOError(walkErr, "search failed")
+	}
+
+	s.prefetchSearch(result.Matches)
+	return result, nil
+}
+
+// collectMatches appends line matches for one file and reports whether the match limit was hit.
+func (s *Service) collectMatches(result *SearchResult, path string, content []byte, matcher *regexp.Regexp) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(content)+1)
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		location := matcher.FindStringIndex(line)
+		if location == nil {
+			continue
+		}
+		if len(result.Matches) >= s.maxSearchMatches {
+			return true
+		}
+
+		result.Matches = append(result.Matches, SearchMatch{
+			Path:    path,
+			Line:    lineNumber,
+			Snippet: snippetAround(line, location[0], location[1]),
+		})
+	}
+
+	return false
+}
+
+// snippetAround returns a bounded slice of line centered on the match.
+func snippetAround(line string, start int, end int) string {
+	trimmed := strings.TrimRight(line, "\r")
+	if utf8.RuneCountInString(trimmed) <= MaxSearchSnippetChars {
+		return strings.TrimSpace(trimmed)
+	}
+
+	// Center the window on the match and widen it until the rune budget is used.
+	runes := []rune(trimmed)
+	matchStart := utf8.RuneCountInString(trimmed[:min(start, len(trimmed))])
+	matchEnd := matchStart + utf8.RuneCountInString(trimmed[min(start, len(trimmed)):min(end, len(trimmed))])
+	padding := max(0, (MaxSearchSnippetChars-(matchEnd-matchStart))/2)
+	from := max(0, matchStart-padding)
+	to := min(len(runes), from+MaxSearchSnippetChars)
+
+	snippet := string(runes[from:to])
+	if from > 0 {
+		snippet = "..." + snippet
+	}
+	if to < len(runes) {
+		snippet += "..."
+	}
+
+	return strings.TrimSpace(snippet)
+}
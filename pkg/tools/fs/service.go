@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 	"unicode/utf8"
 
@@ -27,13 +29,39 @@ type Service struct {
 	maxReadBytes             int
 	maxWriteBytes            int
 	maxListEntries           int
+	maxSearchMatches         int
+	maxSearchBytes           int64
+	maxArchiveEntries        int
+	maxArchiveBytes          int64
 	maxToolOperationDuration time.Duration
+	// maxWorkspaceBytes and maxWorkspaceFiles are zero unless EnableDiskQuota is called.
+	maxWorkspaceBytes int64
+	maxWorkspaceFiles int
+	// snapshots is nil unless EnableSnapshots is called.
+	snapshots *Snapshots
+	// auditLog is nil unless EnableAudit is called.
+	auditLog *AuditLog
+	// ignores holds the parsed workspace ignore file.
+	ignores ignoreCache
+
+	// cache and prefetch are nil/zero unless EnablePrefetch is called.
+	cache         *readCache
+	prefetchFiles int
+	prefetchWG    sync.WaitGroup
 }
 
 type ReadResult struct {
 	Path    string
 	Content string
 	Bytes   int
+	// StartLine and EndLine are set for ranged reads (1-based, inclusive).
+	StartLine int
+	EndLine   int
+	HasMore   bool
+	Truncated bool
+	// PartialLine is set when the only line of a ranged read was longer than
+	// max_read_bytes and Content holds just its beginning.
+	PartialLine bool
 }
 
 type WriteResult struct {
@@ -75,6 +103,10 @@ func NewService(guard *workspace.Guard) *Service {
 		maxReadBytes:             MaxReadBytes,
 		maxWriteBytes:            MaxWriteBytes,
 		maxListEntries:           MaxListEntries,
+		maxSearchMatches:         MaxSearchMatches,
+		maxSearchBytes:           MaxSearchBytesScanned,
+		maxArchiveEntries:        MaxArchiveEntries,
+		maxArchiveBytes:          MaxArchiveBytes,
 		maxToolOperationDuration: MaxToolOperationDuration,
 	}
 }
@@ -87,23 +119,40 @@ func (s *Service) ReadFile(ctx context.Context, path string) (ReadResult, error)
 	if err != nil {
 		return ReadResult{}, err
 	}
+	if err := s.checkIgnored(resolvedPath); err != nil {
+		return ReadResult{}, err
+	}
 
 	if err := checkContext(ctx); err != nil {
 		return ReadResult{}, err
 	}
 
-	content, err := os.ReadFile(resolvedPath)
-	if err != nil {
-		return ReadResult{}, workspace.NormalizeIOError(err, "read failed")
+	var info os.FileInfo
+	if s.cache != nil {
+		info, err = os.Stat(resolvedPath)
+		if err != nil {
+			return ReadResult{}, workspace.NormalizeIOError(err, "read failed")
+		}
+	}
+	content, cached := s.readCached(resolvedPath, info)
+	if !cached {
+		content, err = os.ReadFile(resolvedPath)
+		if err != nil {
+			return ReadResult{}, workspace.NormalizeIOError(err, "read failed")
+		}
 	}
 
 	if len(content) > s.maxReadBytes {
-		return ReadResult{}, workspace.NewError(workspace.ErrorIO, fmt.Sprintf("file exceeds max_read_bytes (%d)", s.maxReadBytes))
+		return ReadResult{}, workspace.NewError(workspace.ErrorIO, fmt.Sprintf("file exceeds max_read_bytes (%d); use offset/limit to read a line range", s.maxReadBytes))
 	}
 	if err := ensureText(content); err != nil {
 		return ReadResult{}, err
 	}
 
+	if !cached {
+		s.remember(resolvedPath, content, info)
+	}
+
 	return ReadResult{
 		Path:    resolvedPath,
 		Content: string(content),
@@ -111,6 +160,30 @@ func (s *Service) ReadFile(ctx context.Context, path string) (ReadResult, error)
 	}, nil
 }
 
+// ResolveFile resolves path for handing a workspace file to the user, as
+// send_file does. Like the read-side tools it refuses paths hidden by the
+// ignore file, the snapshot store, and the audit log; the path must name a
+// regular file.
+func (s *Service) ResolveFile(path string) (string, error) {
+	resolvedPath, err := s.guard.ResolvePath(path)
+	if err != nil {
+		return "", err
+	}
+	if err := s.checkIgnored(resolvedPath); err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(resolvedPath)
+	if err != nil {
+		return "", workspace.NewError(workspace.ErrorPathNotFound, fmt.Sprintf("%s does not exist", path))
+	}
+	if !info.Mode().IsRegular() {
+		return "", workspace.NewError(workspace.ErrorInvalidArgument, fmt.Sprintf("%s is not a regular file", path))
+	}
+
+	return resolvedPath, nil
+}
+
 func (s *Service) WriteFile(ctx context.Context, path string, content string) (WriteResult, error) {
 	ctx, cancel := s.withOperationContext(ctx)
 	defer cancel()
@@ -128,8 +201,10 @@ func (s *Service) WriteFile(ctx context.Context, path string, content string) (W
 	}
 
 	mode := os.FileMode(0o644)
+	var sizeBefore int64
 	if info, statErr := os.Stat(resolvedPath); statErr == nil {
 		mode = info.Mode().Perm()
+		sizeBefore = info.Size()
 	} else if !os.IsNotExist(statErr) {
 		return WriteResult{}, workspace.NormalizeIOError(statErr, "stat failed")
 	}
@@ -141,10 +216,18 @@ func (s *Service) WriteFile(ctx context.Context, path string, content string) (W
 	if err := s.guard.EnsureContained(resolvedPath); err != nil {
 		return WriteResult{}, err
 	}
+	if err := s.checkQuota(ctx, quotaWrite{path: resolvedPath, size: int64(len(content))}); err != nil {
+		return WriteResult{}, err
+	}
+	if err := s.snapshot("write_file", resolvedPath); err != nil {
+		return WriteResult{}, err
+	}
 
+	s.forget(resolvedPath)
 	if err := atomicWrite(resolvedPath, []byte(content), mode); err != nil {
 		return WriteResult{}, workspace.NormalizeIOError(err, "write failed")
 	}
+	s.recordAudit(ctx, "write_file", AuditEntry{Path: resolvedPath, BytesBefore: sizeBefore, BytesAfter: int64(len(content))})
 
 	return WriteResult{Path: resolvedPath, BytesWritten: len(content)}, nil
 }
@@ -172,7 +255,18 @@ func (s *Service) AppendFile(ctx context.Context, path string, content string) (
 	if err := s.guard.EnsureContained(resolvedPath); err != nil {
 		return AppendResult{}, err
 	}
+	size := int64(len(content))
+	if info, statErr := os.Stat(resolvedPath); statErr == nil {
+		size += info.Size()
+	}
+	if err := s.checkQuota(ctx, quotaWrite{path: resolvedPath, size: size}); err != nil {
+		return AppendResult{}, err
+	}
+	if err := s.snapshot("append_file", resolvedPath); err != nil {
+		return AppendResult{}, err
+	}
 
+	s.forget(resolvedPath)
 	file, err := os.OpenFile(resolvedPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
 	if err != nil {
 		return AppendResult{}, workspace.NormalizeIOError(err, "open append target failed")
@@ -188,6 +282,7 @@ func (s *Service) AppendFile(ctx context.Context, path string, content string) (
 	if err != nil {
 		return AppendResult{}, workspace.NormalizeIOError(err, "stat append target failed")
 	}
+	s.recordAudit(ctx, "append_file", AuditEntry{Path: resolvedPath, BytesBefore: info.Size() - int64(bytesWritten), BytesAfter: info.Size()})
 
 	return AppendResult{Path: resolvedPath, BytesAppended: bytesWritten, Size: info.Size()}, nil
 }
@@ -207,11 +302,17 @@ func (s *Service) ListDir(ctx context.Context, path string) (ListResult, error)
 	if err != nil {
 		return ListResult{}, err
 	}
+	if err := s.checkIgnored(resolvedPath); err != nil {
+		return ListResult{}, err
+	}
 
 	entries, err := os.ReadDir(resolvedPath)
 	if err != nil {
 		return ListResult{}, workspace.NormalizeIOError(err, "list directory failed")
 	}
+	entries = slices.DeleteFunc(entries, func(entry os.DirEntry) bool {
+		return s.ignored(filepath.Join(resolvedPath, entry.Name()), entry.IsDir())
+	})
 
 	sort.Slice(entries, func(i int, j int) bool {
 		return entries[i].Name() < entries[j].Name()
@@ -244,6 +345,8 @@ func (s *Service) ListDir(ctx context.Context, path string) (ListResult, error)
 		})
 	}
 
+	s.prefetchListing(resolvedPath, resultEntries)
+
 	return ListResult{
 		Path:      resolvedPath,
 		Entries:   resultEntries,
@@ -267,6 +370,9 @@ func (s *Service) EditFile(ctx context.Context, path string, oldText string, new
 	if err != nil {
 		return EditResult{}, err
 	}
+	if err := s.checkIgnored(resolvedPath); err != nil {
+		return EditResult{}, err
+	}
 
 	raw, err := os.ReadFile(resolvedPath)
 	if err != nil {
@@ -301,15 +407,23 @@ func (s *Service) EditFile(ctx context.Context, path string, oldText string, new
 	if err := s.guard.EnsureContained(resolvedPath); err != nil {
 		return EditResult{}, err
 	}
+	if err := s.checkQuota(ctx, quotaWrite{path: resolvedPath, size: int64(len(updated))}); err != nil {
+		return EditResult{}, err
+	}
+	if err := s.snapshot("edit_file", resolvedPath); err != nil {
+		return EditResult{}, err
+	}
 
 	mode := os.FileMode(0o644)
 	if info, statErr := os.Stat(resolvedPath); statErr == nil {
 		mode = info.Mode().Perm()
 	}
 
+	s.forget(resolvedPath)
 	if err := atomicWrite(resolvedPath, []byte(updated), mode); err != nil {
 		return EditResult{}, workspace.NormalizeIOError(err, "write failed")
 	}
+	s.recordAudit(ctx, "edit_file", AuditEntry{Path: resolvedPath, BytesBefore: int64(len(raw)), BytesAfter: int64(len(updated))})
 
 	return EditResult{
 		Path:          resolvedPath,
@@ -1,11 +1,15 @@
 package fs
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"miniclaw/pkg/workspace"
 )
@@ -123,6 +127,313 @@ func TestWriteAndAppendEnforceSizeLimit(t *testing.T) {
 	}
 }
 
+func TestDiskQuotaLimitsWorkspaceGrowth(t *testing.T) {
+	service, guard := mustService(t)
+	service.EnableDiskQuota(10, 2)
+	ctx := context.Background()
+
+	if _, err := service.WriteFile(ctx, "a.txt", "12345"); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	if _, err := service.AppendFile(ctx, "a.txt", "123456"); workspace.CategoryFromError(err) != workspace.ErrorQuotaExceeded {
+		t.Fatalf("AppendFile past max bytes error = %v, want %s", err, workspace.ErrorQuotaExceeded)
+	}
+	if _, err := service.WriteFile(ctx, "b.txt", "1"); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	if _, err := service.WriteFile(ctx, "c.txt", "1"); workspace.CategoryFromError(err) != workspace.ErrorQuotaExceeded {
+		t.Fatalf("WriteFile past max files error = %v, want %s", err, workspace.ErrorQuotaExceeded)
+	}
+	if _, err := os.Stat(filepath.Join(guard.Root(), "c.txt")); !os.IsNotExist(err) {
+		t.Fatalf("rejected write created c.txt: %v", err)
+	}
+
+	// Rewriting an existing file within the byte budget does not add a file.
+	if _, err := service.WriteFile(ctx, "a.txt", "123456789"); err != nil {
+		t.Fatalf("WriteFile replace error: %v", err)
+	}
+
+	// A workspace already over quota can still shrink.
+	service.EnableDiskQuota(4, 0)
+	if _, err := service.WriteFile(ctx, "a.txt", "12"); err != nil {
+		t.Fatalf("WriteFile shrink error: %v", err)
+	}
+	patch := "--- /dev/null\n+++ b/d.txt\n@@ -0,0 +1 @@\n+more text\n"
+	if _, err := service.ApplyPatch(ctx, patch); workspace.CategoryFromError(err) != workspace.ErrorQuotaExceeded {
+		t.Fatalf("ApplyPatch past max bytes error = %v, want %s", err, workspace.ErrorQuotaExceeded)
+	}
+}
+
+func TestSaveFileKeepsToTheWorkspaceRules(t *testing.T) {
+	service, guard := mustService(t)
+	ctx := context.Background()
+	if err := os.WriteFile(filepath.Join(guard.Root(), IgnoreFile), []byte("secrets/\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	// The ignore file holds 9 of the 16 bytes.
+	service.EnableDiskQuota(16, 0)
+
+	path, err := service.SaveFile(ctx, "uploads/a.txt", strings.NewReader("hello"), 5)
+	if err != nil {
+		t.Fatalf("SaveFile error: %v", err)
+	}
+	if content, err := os.ReadFile(path); err != nil || string(content) != "hello" {
+		t.Fatalf("saved content = %q, %v; want hello", content, err)
+	}
+
+	for name, save := range map[string]struct {
+		path     string
+		content  string
+		size     int64
+		category string
+	}{
+		"existing file":  {path: "uploads/a.txt", content: "again", size: 5, category: workspace.ErrorAlreadyExists},
+		"ignored path":   {path: "secrets/key.txt", content: "key", size: 3, category: workspace.ErrorPermissionDenied},
+		"outside root":   {path: "../escape.txt", content: "out", size: 3, category: workspace.ErrorOutsideWorkspace},
+		"over the quota": {path: "uploads/b.txt", content: "123", size: 3, category: workspace.ErrorQuotaExceeded},
+		"over its size":  {path: "uploads/c.txt", content: "longer", size: 2, category: workspace.ErrorIO},
+	} {
+		if _, err := service.SaveFile(ctx, save.path, strings.NewReader(save.content), save.size); workspace.CategoryFromError(err) != save.category {
+			t.Fatalf("%s: SaveFile error = %v, want %s", name, err, save.category)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(guard.Root(), "uploads", "c.txt")); !os.IsNotExist(err) {
+		t.Fatalf("failed save left c.txt: %v", err)
+	}
+}
+
+func TestAuditLogRecordsSuccessfulMutations(t *testing.T) {
+	service, guard := mustService(t)
+	service.EnableAudit()
+	ctx := WithAuditSession(context.Background(), "telegram:42")
+
+	if _, err := service.WriteFile(ctx, "notes/a.txt", "hello"); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	if _, err := service.AppendFile(ctx, "notes/a.txt", " world"); err != nil {
+		t.Fatalf("AppendFile error: %v", err)
+	}
+	if _, err := service.EditFile(ctx, "notes/a.txt", "world", "there", false); err != nil {
+		t.Fatalf("EditFile error: %v", err)
+	}
+	if _, err := service.EditFile(ctx, "notes/a.txt", "missing", "x", false); err == nil {
+		t.Fatal("EditFile with missing text succeeded")
+	}
+	if _, err := service.RemoveDir(ctx, "notes", true); err != nil {
+		t.Fatalf("RemoveDir error: %v", err)
+	}
+
+	entries, err := OpenAudit(guard).Tail(10)
+	if err != nil {
+		t.Fatalf("Tail error: %v", err)
+	}
+	want := []AuditEntry{
+		{Tool: "write_file", Path: "notes/a.txt", BytesBefore: 0, BytesAfter: 5},
+		{Tool: "append_file", Path: "notes/a.txt", BytesBefore: 5, BytesAfter: 11},
+		{Tool: "edit_file", Path: "notes/a.txt", BytesBefore: 11, BytesAfter: 11},
+		{Tool: "remove_dir", Path: "notes", BytesBefore: 11, Deleted: true},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("audit entries = %+v, want %d", entries, len(want))
+	}
+	for index, entry := range entries {
+		if entry.At.IsZero() || entry.Session != "telegram:42" {
+			t.Fatalf("entry %d = %+v, want a timestamp and session telegram:42", index, entry)
+		}
+		entry.At, entry.Session = want[index].At, ""
+		if entry != want[index] {
+			t.Fatalf("entry %d = %+v, want %+v", index, entry, want[index])
+		}
+	}
+
+	last, err := OpenAudit(guard).Tail(1)
+	if err != nil || len(last) != 1 || last[0].Tool != "remove_dir" {
+		t.Fatalf("Tail(1) = %+v, %v; want the remove_dir entry", last, err)
+	}
+	if _, err := service.ReadFile(ctx, AuditFile); workspace.CategoryFromError(err) != workspace.ErrorPermissionDenied {
+		t.Fatalf("ReadFile audit log error = %v, want %s", err, workspace.ErrorPermissionDenied)
+	}
+}
+
+func TestIgnoreMatchesGitignoreSyntax(t *testing.T) {
+	ignore := ParseIgnore("# secrets\n.env\n*.key\n!public.key\nbuild/\n/root-only.txt\ndocs/**/draft.md\nlogs/**\n")
+
+	cases := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{path: ".env", want: true},
+		{path: "app/.env", want: true},
+		{path: "certs/server.key", want: true},
+		{path: "certs/public.key", want: false},
+		{path: "build", isDir: true, want: true},
+		{path: "build/out.bin", want: true},
+		{path: "build", want: false},
+		{path: "root-only.txt", want: true},
+		{path: "sub/root-only.txt", want: false},
+		{path: "docs/draft.md", want: true},
+		{path: "docs/a/b/draft.md", want: true},
+		{path: "logs/today.log", want: true},
+		{path: "main.go", want: false},
+	}
+	for _, tc := range cases {
+		if got := ignore.Match(tc.path, tc.isDir); got != tc.want {
+			t.Fatalf("Match(%q, %v) = %v, want %v", tc.path, tc.isDir, got, tc.want)
+		}
+	}
+}
+
+func TestIgnoreFileHidesPathsFromReadTools(t *testing.T) {
+	service, guard := mustService(t)
+	ctx := context.Background()
+	root := guard.Root()
+
+	files := map[string]string{
+		IgnoreFile:         "secrets/\n*.pem\n",
+		"notes.txt":        "token lives elsewhere",
+		"cert.pem":         "token=abc",
+		"secrets/prod.env": "token=xyz",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("MkdirAll error: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile error: %v", err)
+		}
+	}
+
+	listed, err := service.ListDir(ctx, ".")
+	if err != nil {
+		t.Fatalf("ListDir error: %v", err)
+	}
+	var names []string
+	for _, entry := range listed.Entries {
+		names = append(names, entry.Name)
+	}
+	if strings.Join(names, ",") != IgnoreFile+",notes.txt" {
+		t.Fatalf("ListDir names = %v, want only the ignore file and notes.txt", names)
+	}
+
+	searched, err := service.SearchFiles(ctx, ".", "token", true)
+	if err != nil {
+		t.Fatalf("SearchFiles error: %v", err)
+	}
+	if len(searched.Matches) != 1 || filepath.Base(searched.Matches[0].Path) != "notes.txt" {
+		t.Fatalf("SearchFiles matches = %+v, want only notes.txt", searched.Matches)
+	}
+
+	for name, call := range map[string]func() error{
+		"ReadFile":      func() error { _, err := service.ReadFile(ctx, "cert.pem"); return err },
+		"ReadFileLines": func() error { _, err := service.ReadFileLines(ctx, "secrets/prod.env", 1, 10); return err },
+		"FileInfo":      func() error { _, err := service.FileInfo(ctx, "secrets"); return err },
+		"HashFile":      func() error { _, err := service.HashFile(ctx, "cert.pem", ""); return err },
+		"ListDir":       func() error { _, err := service.ListDir(ctx, "secrets"); return err },
+		"CopyFile":      func() error { _, err := service.CopyFile(ctx, "cert.pem", "copy.txt", false, false); return err },
+	} {
+		if err := call(); workspace.CategoryFromError(err) != workspace.ErrorPermissionDenied {
+			t.Fatalf("%s on ignored path error = %v, want %s", name, err, workspace.ErrorPermissionDenied)
+		}
+	}
+
+	archived, err := service.CreateArchive(ctx, "all.zip", []string{"."}, false)
+	if err != nil {
+		t.Fatalf("CreateArchive error: %v", err)
+	}
+	if archived.Skipped != 2 {
+		t.Fatalf("CreateArchive skipped = %d, want 2 ignored paths", archived.Skipped)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, IgnoreFile), nil, 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	if _, err := service.ReadFile(ctx, "cert.pem"); err != nil {
+		t.Fatalf("ReadFile after clearing ignore file error: %v", err)
+	}
+}
+
+func TestSnapshotsUndoAndRestoreToolChanges(t *testing.T) {
+	service, guard := mustService(t)
+	service.EnableSnapshots()
+	ctx := context.Background()
+	read := func(name string) string {
+		t.Helper()
+		content, err := os.ReadFile(filepath.Join(guard.Root(), name))
+		if err != nil {
+			return "<missing>"
+		}
+		return string(content)
+	}
+
+	if _, err := service.UndoLastChange(ctx); workspace.CategoryFromError(err) != workspace.ErrorInvalidArgument {
+		t.Fatalf("UndoLastChange on empty journal error = %v, want invalid argument", err)
+	}
+	if _, err := service.WriteFile(ctx, "a.txt", "one"); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	if _, err := service.EditFile(ctx, "a.txt", "one", "two", false); err != nil {
+		t.Fatalf("EditFile error: %v", err)
+	}
+	if _, err := service.CopyFile(ctx, "a.txt", "b.txt", false, false); err != nil {
+		t.Fatalf("CopyFile error: %v", err)
+	}
+
+	result, err := service.UndoLastChange(ctx)
+	if err != nil {
+		t.Fatalf("UndoLastChange error: %v", err)
+	}
+	if result.Change.Tool != "copy_file" || len(result.Removed) != 1 || read("b.txt") != "<missing>" {
+		t.Fatalf("undo copy = %+v, b.txt = %q; want b.txt removed", result, read("b.txt"))
+	}
+
+	restored, err := service.RestoreFile(ctx, "a.txt")
+	if err != nil {
+		t.Fatalf("RestoreFile error: %v", err)
+	}
+	if read("a.txt") != "one" || restored.Change.Tool != "edit_file" {
+		t.Fatalf("after restore a.txt = %q (change %+v), want one from edit_file", read("a.txt"), restored.Change)
+	}
+	if _, err := service.UndoLastChange(ctx); err != nil || read("a.txt") != "two" {
+		t.Fatalf("undo restore: a.txt = %q, err = %v; want two", read("a.txt"), err)
+	}
+
+	if _, err := service.MakeDir(ctx, "dir"); err != nil {
+		t.Fatalf("MakeDir error: %v", err)
+	}
+	if _, err := service.WriteFile(ctx, "dir/c.txt", "keep me"); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	if _, err := service.RemoveDir(ctx, "dir", true); err != nil {
+		t.Fatalf("RemoveDir error: %v", err)
+	}
+	if _, err := service.UndoLastChange(ctx); err != nil || read("dir/c.txt") != "keep me" {
+		t.Fatalf("undo remove_dir: dir/c.txt = %q, err = %v", read("dir/c.txt"), err)
+	}
+
+	changes, err := service.snapshots.Changes()
+	if err != nil {
+		t.Fatalf("Changes error: %v", err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("changes = %d, want 3 (write, edit, write)", len(changes))
+	}
+	if _, err := service.RestoreFile(ctx, "never-touched.txt"); workspace.CategoryFromError(err) != workspace.ErrorPathNotFound {
+		t.Fatalf("RestoreFile untouched error = %v, want %s", err, workspace.ErrorPathNotFound)
+	}
+
+	if pruned, total, err := service.snapshots.Prune(changes[0].At); err != nil || pruned != 0 || total != 3 {
+		t.Fatalf("Prune before the first change = %d of %d, %v; want 0 of 3", pruned, total, err)
+	}
+	if pruned, total, err := service.snapshots.Prune(time.Now().Add(time.Minute)); err != nil || pruned != 3 || total != 3 {
+		t.Fatalf("Prune everything = %d of %d, %v; want 3 of 3", pruned, total, err)
+	}
+	if objects, _ := filepath.Glob(filepath.Join(guard.Root(), filepath.FromSlash(SnapshotDir), "objects", "*", "*")); len(objects) != 0 {
+		t.Fatalf("objects after prune = %v, want none", objects)
+	}
+}
+
 func TestListDirTruncatesDeterministically(t *testing.T) {
 	service, guard := mustService(t)
 	service.maxListEntries = 2
@@ -160,6 +471,578 @@ func TestServiceRespectsCancelledContext(t *testing.T) {
 	}
 }
 
+func TestReadFileLinesPagesThroughLargeFile(t *testing.T) {
+	service, guard := mustService(t)
+	service.maxReadBytes = 16
+
+	var b strings.Builder
+	for i := 1; i <= 10; i++ {
+		fmt.Fprintf(&b, "line %02d\n", i)
+	}
+	if err := os.WriteFile(filepath.Join(guard.Root(), "big.log"), []byte(b.String()), 0o644); err != nil {
+		t.Fatalf("seed file: %v", err)
+	}
+
+	if _, err := service.ReadFile(context.Background(), "big.log"); workspace.CategoryFromError(err) != workspace.ErrorIO {
+		t.Fatalf("full read category = %q, want %q", workspace.CategoryFromError(err), workspace.ErrorIO)
+	}
+
+	result, err := service.ReadFileLines(context.Background(), "big.log", 3, 2)
+	if err != nil {
+		t.Fatalf("ReadFileLines error: %v", err)
+	}
+	if result.Content != "line 03\nline 04\n" || result.StartLine != 3 || result.EndLine != 4 || !result.HasMore {
+		t.Fatalf("result = %+v, want lines 3-4 with more", result)
+	}
+
+	result, err = service.ReadFileLines(context.Background(), "big.log", 9, 0)
+	if err != nil {
+		t.Fatalf("ReadFileLines error: %v", err)
+	}
+	if result.Content != "line 09\nline 10\n" || result.HasMore {
+		t.Fatalf("result = %+v, want final two lines", result)
+	}
+
+	result, err = service.ReadFileLines(context.Background(), "big.log", 1, 0)
+	if err != nil {
+		t.Fatalf("ReadFileLines error: %v", err)
+	}
+	if !result.Truncated || result.EndLine != 2 {
+		t.Fatalf("result = %+v, want byte-budget truncation after line 2", result)
+	}
+
+	if _, err := service.ReadFileLines(context.Background(), "big.log", 50, 1); workspace.CategoryFromError(err) != workspace.ErrorInvalidRange {
+		t.Fatalf("error category = %q, want %q", workspace.CategoryFromError(err), workspace.ErrorInvalidRange)
+	}
+}
+
+func TestReadFileLinesCutsALineLongerThanTheBudget(t *testing.T) {
+	service, guard := mustService(t)
+	service.maxReadBytes = 16
+	ctx := context.Background()
+
+	long := strings.Repeat("x", 40)
+	if err := os.WriteFile(filepath.Join(guard.Root(), "wide.csv"), []byte("id\n"+long+"\nend\n"), 0o644); err != nil {
+		t.Fatalf("seed file: %v", err)
+	}
+	result, err := service.ReadFileLines(ctx, "wide.csv", 2, 0)
+	if err != nil {
+		t.Fatalf("ReadFileLines error: %v", err)
+	}
+	if result.Content != long[:16] || result.StartLine != 2 || result.EndLine != 2 || !result.PartialLine || !result.Truncated || !result.HasMore {
+		t.Fatalf("result = %+v, want the first 16 bytes of line 2 with more to follow", result)
+	}
+	result, err = service.ReadFileLines(ctx, "wide.csv", 3, 0)
+	if err != nil || result.Content != "end\n" || result.PartialLine {
+		t.Fatalf("next window = %+v, %v; want line 3", result, err)
+	}
+
+	// A large file without newlines is read no further than the budget
+	// needs, and a character the budget splits is left out.
+	service.maxReadBytes = 15
+	if err := os.WriteFile(filepath.Join(guard.Root(), "blob.txt"), bytes.Repeat([]byte("é"), 4<<20), 0o644); err != nil {
+		t.Fatalf("seed file: %v", err)
+	}
+	result, err = service.ReadFileLines(ctx, "blob.txt", 1, 0)
+	if err != nil {
+		t.Fatalf("ReadFileLines error: %v", err)
+	}
+	if result.Content != strings.Repeat("é", 7) || result.EndLine != 1 || !result.PartialLine || result.HasMore {
+		t.Fatalf("result = %+v, want the first 7 characters of the only line", result)
+	}
+}
+
+func TestSearchFilesFindsMatchesWithLineNumbers(t *testing.T) {
+	service, guard := mustService(t)
+	ctx := context.Background()
+
+	if _, err := service.WriteFile(ctx, "src/main.go", "package main\n\nfunc main() {\n\tprintln(\"TODO: wire\")\n}\n"); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	if _, err := service.WriteFile(ctx, "notes.txt", "nothing here\nTODO(later)\n"); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(guard.Root(), "blob.bin"), []byte{'T', 'O', 'D', 'O', 0x00}, 0o644); err != nil {
+		t.Fatalf("seed binary file: %v", err)
+	}
+
+	result, err := service.SearchFiles(ctx, ".", "TODO(", true)
+	if err != nil {
+		t.Fatalf("SearchFiles error: %v", err)
+	}
+	if len(result.Matches) != 1 {
+		t.Fatalf("literal matches = %+v, want 1", result.Matches)
+	}
+	if got := guard.RelPath(result.Matches[0].Path); got != "notes.txt" || result.Matches[0].Line != 2 {
+		t.Fatalf("match = %s:%d, want notes.txt:2", got, result.Matches[0].Line)
+	}
+
+	result, err = service.SearchFiles(ctx, "", `TODO[:(]`, false)
+	if err != nil {
+		t.Fatalf("SearchFiles error: %v", err)
+	}
+	if len(result.Matches) != 2 {
+		t.Fatalf("regex matches = %+v, want 2", result.Matches)
+	}
+	if result.Matches[1].Snippet != `println("TODO: wire")` {
+		t.Fatalf("snippet = %q, want trimmed line", result.Matches[1].Snippet)
+	}
+}
+
+func TestSearchFilesLimitsAndErrors(t *testing.T) {
+	service, _ := mustService(t)
+	ctx := context.Background()
+	service.maxSearchMatches = 2
+
+	if _, err := service.WriteFile(ctx, "many.txt", "hit\nhit\nhit\n"); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	result, err := service.SearchFiles(ctx, ".", "hit", true)
+	if err != nil {
+		t.Fatalf("SearchFiles error: %v", err)
+	}
+	if !result.Truncated || len(result.Matches) != 2 {
+		t.Fatalf("result = %+v, want 2 truncated matches", result)
+	}
+
+	if _, err := service.SearchFiles(ctx, ".", "(", false); workspace.CategoryFromError(err) != workspace.ErrorInvalidPattern {
+		t.Fatalf("error category = %q, want %q", workspace.CategoryFromError(err), workspace.ErrorInvalidPattern)
+	}
+	if _, err := service.SearchFiles(ctx, "missing", "hit", true); workspace.CategoryFromError(err) != workspace.ErrorPathNotFound {
+		t.Fatalf("error category = %q, want %q", workspace.CategoryFromError(err), workspace.ErrorPathNotFound)
+	}
+}
+
+func TestMakeDirAndRemoveDir(t *testing.T) {
+	service, guard := mustService(t)
+	ctx := context.Background()
+
+	made, err := service.MakeDir(ctx, "build/out")
+	if err != nil {
+		t.Fatalf("MakeDir error: %v", err)
+	}
+	if !made.Created {
+		t.Fatal("Created = false, want true")
+	}
+	again, err := service.MakeDir(ctx, "build/out")
+	if err != nil || again.Created {
+		t.Fatalf("MakeDir existing = %+v, %v; want Created=false and no error", again, err)
+	}
+
+	if _, err := service.WriteFile(ctx, "build/out/a.txt", "a"); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	if _, err := service.MakeDir(ctx, "build/out/a.txt"); workspace.CategoryFromError(err) != workspace.ErrorInvalidPath {
+		t.Fatalf("error category = %q, want %q", workspace.CategoryFromError(err), workspace.ErrorInvalidPath)
+	}
+
+	if _, err := service.RemoveDir(ctx, "build", false); workspace.CategoryFromError(err) != workspace.ErrorDirNotEmpty {
+		t.Fatalf("error category = %q, want %q", workspace.CategoryFromError(err), workspace.ErrorDirNotEmpty)
+	}
+	if _, err := service.RemoveDir(ctx, "build/out/a.txt", true); workspace.CategoryFromError(err) != workspace.ErrorInvalidPath {
+		t.Fatalf("error category = %q, want %q", workspace.CategoryFromError(err), workspace.ErrorInvalidPath)
+	}
+	if _, err := service.RemoveDir(ctx, ".", true); workspace.CategoryFromError(err) != workspace.ErrorInvalidPath {
+		t.Fatalf("error category = %q, want %q", workspace.CategoryFromError(err), workspace.ErrorInvalidPath)
+	}
+
+	removed, err := service.RemoveDir(ctx, "build", true)
+	if err != nil {
+		t.Fatalf("RemoveDir error: %v", err)
+	}
+	if removed.EntriesRemoved != 2 {
+		t.Fatalf("EntriesRemoved = %d, want 2", removed.EntriesRemoved)
+	}
+	if _, err := os.Stat(filepath.Join(guard.Root(), "build")); !os.IsNotExist(err) {
+		t.Fatalf("build still exists: %v", err)
+	}
+
+	if _, err := service.MakeDir(ctx, "empty"); err != nil {
+		t.Fatalf("MakeDir error: %v", err)
+	}
+	if _, err := service.RemoveDir(ctx, "empty", false); err != nil {
+		t.Fatalf("RemoveDir empty error: %v", err)
+	}
+}
+
+func TestApplyPatchModifiesCreatesAndDeletes(t *testing.T) {
+	service, guard := mustService(t)
+	ctx := context.Background()
+
+	if _, err := service.WriteFile(ctx, "main.go", "package main\n\nfunc a() {}\n\nfunc b() {}\n\nfunc c() {}\n"); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	if _, err := service.WriteFile(ctx, "old.txt", "bye\n"); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	// The second hunk's line number is stale by one; it must still apply by context.
+	patch := strings.Join([]string{
+		"diff --git a/main.go b/main.go",
+		"--- a/main.go",
+		"+++ b/main.go",
+		"@@ -3,1 +3,1 @@",
+		"-func a() {}",
+		"+func a() { println(\"a\") }",
+		"@@ -8,1 +8,2 @@",
+		" func c() {}",
+		"+// end",
+		"--- /dev/null",
+		"+++ b/docs/new.md",
+		"@@ -0,0 +1,2 @@",
+		"+# New",
+		"+text",
+		"--- a/old.txt",
+		"+++ /dev/null",
+		"@@ -1 +0,0 @@",
+		"-bye",
+		"",
+	}, "\n")
+
+	result, err := service.ApplyPatch(ctx, patch)
+	if err != nil {
+		t.Fatalf("ApplyPatch error: %v", err)
+	}
+	if len(result.Files) != 3 {
+		t.Fatalf("files = %d, want 3", len(result.Files))
+	}
+
+	got, _ := os.ReadFile(filepath.Join(guard.Root(), "main.go"))
+	want := "package main\n\nfunc a() { println(\"a\") }\n\nfunc b() {}\n\nfunc c() {}\n// end\n"
+	if string(got) != want {
+		t.Fatalf("main.go = %q, want %q", got, want)
+	}
+	created, _ := os.ReadFile(filepath.Join(guard.Root(), "docs", "new.md"))
+	if string(created) != "# New\ntext\n" {
+		t.Fatalf("new.md = %q", created)
+	}
+	if _, err := os.Stat(filepath.Join(guard.Root(), "old.txt")); !os.IsNotExist(err) {
+		t.Fatalf("old.txt still exists: %v", err)
+	}
+}
+
+func TestApplyPatchInsertsZeroContextHunksAfterTheirLine(t *testing.T) {
+	service, guard := mustService(t)
+	ctx := context.Background()
+
+	if _, err := service.WriteFile(ctx, "n.txt", "1\n2\n3\n4\n5\n6\n"); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	// As git diff -U0 writes them: old count 0 means "insert after line N".
+	patch := strings.Join([]string{
+		"--- a/n.txt",
+		"+++ b/n.txt",
+		"@@ -0,0 +1 @@",
+		"+TOP",
+		"@@ -5,0 +7 @@",
+		"+NEW",
+		"",
+	}, "\n")
+	if _, err := service.ApplyPatch(ctx, patch); err != nil {
+		t.Fatalf("ApplyPatch error: %v", err)
+	}
+
+	got, _ := os.ReadFile(filepath.Join(guard.Root(), "n.txt"))
+	if want := "TOP\n1\n2\n3\n4\n5\nNEW\n6\n"; string(got) != want {
+		t.Fatalf("n.txt = %q, want %q", got, want)
+	}
+}
+
+func TestApplyPatchRejectsWithoutWriting(t *testing.T) {
+	service, guard := mustService(t)
+	ctx := context.Background()
+
+	if _, err := service.WriteFile(ctx, "a.txt", "one\ntwo\n"); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	patch := strings.Join([]string{
+		"--- a/a.txt",
+		"+++ b/a.txt",
+		"@@ -1 +1 @@",
+		"-one",
+		"+ONE",
+		"@@ -2 +2 @@",
+		"-missing",
+		"+two",
+	}, "\n")
+
+	result, err := service.ApplyPatch(ctx, patch)
+	if workspace.CategoryFromError(err) != workspace.ErrorPatchRejected {
+		t.Fatalf("error category = %q, want %q", workspace.CategoryFromError(err), workspace.ErrorPatchRejected)
+	}
+	if !strings.Contains(err.Error(), "a.txt hunk 2") {
+		t.Fatalf("error = %q, want per-hunk detail", err.Error())
+	}
+	if len(result.Files) != 1 || result.Files[0].HunksApplied != 1 || len(result.Files[0].Rejected) != 1 {
+		t.Fatalf("result = %+v, want 1 applied and 1 rejected hunk", result)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(guard.Root(), "a.txt"))
+	if string(content) != "one\ntwo\n" {
+		t.Fatalf("a.txt = %q, want unchanged", content)
+	}
+
+	if _, err := service.ApplyPatch(ctx, "not a diff"); workspace.CategoryFromError(err) != workspace.ErrorInvalidPatch {
+		t.Fatalf("error category = %q, want %q", workspace.CategoryFromError(err), workspace.ErrorInvalidPatch)
+	}
+}
+
+func TestFileInfoReportsMetadata(t *testing.T) {
+	service, _ := mustService(t)
+	ctx := context.Background()
+	service.maxReadBytes = 4
+
+	if _, err := service.WriteFile(ctx, "dir/big.txt", "hello"); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	info, err := service.FileInfo(ctx, "dir/big.txt")
+	if err != nil {
+		t.Fatalf("FileInfo error: %v", err)
+	}
+	if info.Type != "file" || info.Size != 5 || info.Mode != 0o644 || info.Readable || info.ModTime.IsZero() {
+		t.Fatalf("info = %+v, want unreadable 5-byte 0644 file", info)
+	}
+
+	dirInfo, err := service.FileInfo(ctx, "dir")
+	if err != nil {
+		t.Fatalf("FileInfo dir error: %v", err)
+	}
+	if dirInfo.Type != "dir" {
+		t.Fatalf("Type = %q, want dir", dirInfo.Type)
+	}
+
+	if _, err := service.FileInfo(ctx, "missing"); workspace.CategoryFromError(err) != workspace.ErrorPathNotFound {
+		t.Fatalf("error category = %q, want %q", workspace.CategoryFromError(err), workspace.ErrorPathNotFound)
+	}
+}
+
+func TestCopyFileOverwritePolicyAndMode(t *testing.T) {
+	service, guard := mustService(t)
+	ctx := context.Background()
+
+	if _, err := service.WriteFile(ctx, "script.sh", "echo hi\n"); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	if err := os.Chmod(filepath.Join(guard.Root(), "script.sh"), 0o755); err != nil {
+		t.Fatalf("Chmod error: %v", err)
+	}
+
+	result, err := service.CopyFile(ctx, "script.sh", "backup/script.sh.bak", false, true)
+	if err != nil {
+		t.Fatalf("CopyFile error: %v", err)
+	}
+	if result.BytesCopied != 8 || result.Overwrote {
+		t.Fatalf("result = %+v, want 8 bytes without overwrite", result)
+	}
+	info, err := os.Stat(filepath.Join(guard.Root(), "backup", "script.sh.bak"))
+	if err != nil {
+		t.Fatalf("Stat error: %v", err)
+	}
+	if info.Mode().Perm() != 0o755 {
+		t.Fatalf("mode = %v, want 0755", info.Mode().Perm())
+	}
+
+	if _, err := service.CopyFile(ctx, "script.sh", "backup/script.sh.bak", false, false); workspace.CategoryFromError(err) != workspace.ErrorAlreadyExists {
+		t.Fatalf("error category = %q, want %q", workspace.CategoryFromError(err), workspace.ErrorAlreadyExists)
+	}
+	overwritten, err := service.CopyFile(ctx, "script.sh", "backup/script.sh.bak", true, false)
+	if err != nil || !overwritten.Overwrote {
+		t.Fatalf("CopyFile overwrite = %+v, %v; want Overwrote=true", overwritten, err)
+	}
+
+	service.maxWriteBytes = 4
+	if _, err := service.CopyFile(ctx, "script.sh", "big.sh", false, false); workspace.CategoryFromError(err) != workspace.ErrorIO {
+		t.Fatalf("error category = %q, want %q", workspace.CategoryFromError(err), workspace.ErrorIO)
+	}
+}
+
+func TestHashFileDigests(t *testing.T) {
+	service, _ := mustService(t)
+	ctx := context.Background()
+
+	if _, err := service.WriteFile(ctx, "hello.txt", "hello\n"); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	sum, err := service.HashFile(ctx, "hello.txt", "")
+	if err != nil {
+		t.Fatalf("HashFile sha256 error: %v", err)
+	}
+	if sum.Algorithm != HashSHA256 || sum.Digest != "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03" || sum.Size != 6 {
+		t.Fatalf("sha256 result = %+v", sum)
+	}
+
+	sum, err = service.HashFile(ctx, "hello.txt", "MD5")
+	if err != nil {
+		t.Fatalf("HashFile md5 error: %v", err)
+	}
+	if sum.Algorithm != HashMD5 || sum.Digest != "b1946ac92492d2347c6235b4d2611184" {
+		t.Fatalf("md5 result = %+v", sum)
+	}
+
+	if _, err := service.HashFile(ctx, "hello.txt", "crc32"); workspace.CategoryFromError(err) != workspace.ErrorInvalidArgument {
+		t.Fatalf("error category = %q, want %q", workspace.CategoryFromError(err), workspace.ErrorInvalidArgument)
+	}
+	if _, err := service.HashFile(ctx, ".", ""); workspace.CategoryFromError(err) != workspace.ErrorInvalidPath {
+		t.Fatalf("error category = %q, want %q", workspace.CategoryFromError(err), workspace.ErrorInvalidPath)
+	}
+}
+
+func TestPrefetchCachesLikelyReadsAndRevalidates(t *testing.T) {
+	service, guard := mustService(t)
+	service.EnablePrefetch(2)
+	ctx := context.Background()
+
+	for name, content := range map[string]string{"README.md": "# readme\n", "notes.txt": "notes\n", "z.bin": "zzz\n"} {
+		if err := os.WriteFile(filepath.Join(guard.Root(), name), []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile error: %v", err)
+		}
+	}
+
+	if _, err := service.ListDir(ctx, "."); err != nil {
+		t.Fatalf("ListDir error: %v", err)
+	}
+	service.prefetchWG.Wait()
+
+	readmePath := filepath.Join(guard.Root(), "README.md")
+	info, err := os.Stat(readmePath)
+	if err != nil {
+		t.Fatalf("Stat error: %v", err)
+	}
+	if _, ok := service.readCached(readmePath, info); !ok {
+		t.Fatal("README.md was not prefetched")
+	}
+	if len(service.cache.entries) != 2 {
+		t.Fatalf("cached entries = %d, want 2", len(service.cache.entries))
+	}
+
+	// Changes made outside the service are picked up through size/mtime validation.
+	if err := os.WriteFile(readmePath, []byte("# changed readme\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	result, err := service.ReadFile(ctx, "README.md")
+	if err != nil || result.Content != "# changed readme\n" {
+		t.Fatalf("ReadFile = %q, %v; want changed content", result.Content, err)
+	}
+
+	if _, err := service.WriteFile(ctx, "README.md", "# rewritten\n"); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	result, err = service.ReadFile(ctx, "README.md")
+	if err != nil || result.Content != "# rewritten\n" {
+		t.Fatalf("ReadFile = %q, %v; want rewritten content", result.Content, err)
+	}
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	for _, archive := range []string{"out/site.zip", "out/site.tar.gz"} {
+		t.Run(filepath.Base(archive), func(t *testing.T) {
+			service, guard := mustService(t)
+			ctx := context.Background()
+
+			if _, err := service.WriteFile(ctx, "site/index.html", "<h1>hi</h1>"); err != nil {
+				t.Fatalf("WriteFile error: %v", err)
+			}
+			if _, err := service.WriteFile(ctx, "site/css/main.css", "body{}"); err != nil {
+				t.Fatalf("WriteFile error: %v", err)
+			}
+			if err := os.Symlink("/etc/passwd", filepath.Join(guard.Root(), "site", "passwd")); err != nil {
+				t.Fatalf("Symlink error: %v", err)
+			}
+
+			created, err := service.CreateArchive(ctx, archive, []string{"site"}, false)
+			if err != nil {
+				t.Fatalf("CreateArchive error: %v", err)
+			}
+			if created.Entries != 4 || created.Bytes != 17 || created.Skipped != 1 {
+				t.Fatalf("created = %+v, want 4 entries, 17 bytes, 1 skipped symlink", created)
+			}
+			if _, err := service.CreateArchive(ctx, archive, []string{"site"}, false); workspace.CategoryFromError(err) != workspace.ErrorAlreadyExists {
+				t.Fatalf("CreateArchive again error = %v, want already_exists", err)
+			}
+
+			extracted, err := service.ExtractArchive(ctx, archive, "copy", false)
+			if err != nil {
+				t.Fatalf("ExtractArchive error: %v", err)
+			}
+			if extracted.Entries != 4 {
+				t.Fatalf("extracted = %+v, want 4 entries", extracted)
+			}
+			content, err := os.ReadFile(filepath.Join(guard.Root(), "copy", "site", "css", "main.css"))
+			if err != nil || string(content) != "body{}" {
+				t.Fatalf("extracted css = %q, %v", content, err)
+			}
+
+			if _, err := service.ExtractArchive(ctx, archive, "copy", false); workspace.CategoryFromError(err) != workspace.ErrorAlreadyExists {
+				t.Fatalf("ExtractArchive again error = %v, want already_exists", err)
+			}
+			if result, err := service.ExtractArchive(ctx, archive, "copy", true); err != nil || !result.Overwrote {
+				t.Fatalf("ExtractArchive overwrite = %+v, %v", result, err)
+			}
+		})
+	}
+}
+
+func TestExtractArchiveRejectsZipSlipAndLimits(t *testing.T) {
+	service, guard := mustService(t)
+	ctx := context.Background()
+
+	writeZip := func(name string, entries map[string]string) {
+		t.Helper()
+		var buffer bytes.Buffer
+		writer := zip.NewWriter(&buffer)
+		for entryName, content := range entries {
+			entry, err := writer.Create(entryName)
+			if err != nil {
+				t.Fatalf("zip Create error: %v", err)
+			}
+			if _, err := entry.Write([]byte(content)); err != nil {
+				t.Fatalf("zip Write error: %v", err)
+			}
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("zip Close error: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(guard.Root(), name), buffer.Bytes(), 0o644); err != nil {
+			t.Fatalf("WriteFile error: %v", err)
+		}
+	}
+
+	for name, entry := range map[string]string{
+		"parent.zip":   "../evil.txt",
+		"nested.zip":   "ok/../../evil.txt",
+		"absolute.zip": "/tmp/evil.txt",
+	} {
+		writeZip(name, map[string]string{"safe.txt": "fine", entry: "pwned"})
+		_, err := service.ExtractArchive(ctx, name, "out", false)
+		if workspace.CategoryFromError(err) != workspace.ErrorOutsideWorkspace {
+			t.Fatalf("%s: error = %v, want outside_workspace", name, err)
+		}
+		if _, statErr := os.Stat(filepath.Join(guard.Root(), "out", "safe.txt")); !os.IsNotExist(statErr) {
+			t.Fatalf("%s: safe.txt was written before the archive was rejected", name)
+		}
+	}
+
+	writeZip("many.zip", map[string]string{"a.txt": "aaaa", "b.txt": "bbbb", "c.txt": "cccc"})
+	service.maxArchiveEntries = 2
+	if _, err := service.ExtractArchive(ctx, "many.zip", "out", false); err == nil || !strings.Contains(err.Error(), "max_archive_entries") {
+		t.Fatalf("entry limit error = %v", err)
+	}
+	service.maxArchiveEntries = MaxArchiveEntries
+	service.maxArchiveBytes = 10
+	if _, err := service.ExtractArchive(ctx, "many.zip", "out", false); err == nil || !strings.Contains(err.Error(), "max_archive_bytes") {
+		t.Fatalf("size limit error = %v", err)
+	}
+
+	if _, err := service.ExtractArchive(ctx, "many.rar", "out", false); workspace.CategoryFromError(err) != workspace.ErrorInvalidArgument {
+		t.Fatalf("unsupported format error = %v", err)
+	}
+}
+
 func mustService(t *testing.T) (*Service, *workspace.Guard) {
 	t.Helper()
 
@@ -0,0 +1,449 @@
+package fs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"miniclaw/pkg/workspace"
+)
+
+// SnapshotDir holds file snapshots, relative to the workspace root.
+const SnapshotDir = ".miniclaw/snapshots"
+
+const (
+	// MaxSnapshotChanges bounds the recorded changes; the oldest are pruned first.
+	MaxSnapshotChanges = 100
+	// MaxSnapshotFileBytes bounds one saved file. Larger files are recorded
+	// but cannot be restored.
+	MaxSnapshotFileBytes = 8 * 1024 * 1024
+	// MaxSnapshotFiles bounds the files saved for one change, such as a recursive remove_dir.
+	MaxSnapshotFiles = 1000
+
+	snapshotObjectsDir = "objects"
+	snapshotJournal    = "changes.jsonl"
+	// toolRestoreFile is recorded for restore_file so a restore can itself be undone.
+	toolRestoreFile = "restore_file"
+)
+
+// SnapshotFile is the state of one file just before a change.
+type SnapshotFile struct {
+	// Path is workspace-relative, or absolute for allowed paths outside the workspace.
+	Path    string      `json:"path"`
+	Existed bool        `json:"existed"`
+	SHA256  string      `json:"sha256,omitempty"`
+	Mode    os.FileMode `json:"mode,omitempty"`
+	// TooLarge marks files over MaxSnapshotFileBytes whose content was not saved.
+	TooLarge bool `json:"too_large,omitempty"`
+}
+
+// Change is one mutating tool call and the files it was about to write.
+type Change struct {
+	ID    int            `json:"id"`
+	At    time.Time      `json:"at"`
+	Tool  string         `json:"tool"`
+	Files []SnapshotFile `json:"files"`
+}
+
+// RestoreResult reports what undoing a change or restoring a file did.
+type RestoreResult struct {
+	Change Change
+	// Restored files got their earlier content back; Removed files did not exist before the change.
+	Restored []string
+	Removed  []string
+	// Skipped files were too large to snapshot and were left as they are.
+	Skipped []string
+}
+
+// Snapshots saves file contents before tool writes so they can be rolled
+// back. Contents are stored once per SHA-256 under objects/ and changes are
+// journaled, newest last, in changes.jsonl.
+type Snapshots struct {
+	guard *workspace.Guard
+	dir   string
+
+	mu sync.Mutex
+}
+
+// OpenSnapshots returns the snapshot store of the guard's workspace. Nothing
+// is written until the first change is recorded.
+func OpenSnapshots(guard *workspace.Guard) *Snapshots {
+	return &Snapshots{guard: guard, dir: filepath.Join(guard.Root(), filepath.FromSlash(SnapshotDir))}
+}
+
+// Record saves the current state of paths as a change made by tool. Paths
+// inside the snapshot directory are ignored.
+func (s *Snapshots) Record(tool string, paths ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	change := Change{At: time.Now().UTC(), Tool: tool}
+	seen := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		if seen[path] || s.isInternal(path) {
+			continue
+		}
+		seen[path] = true
+		if len(change.Files) == MaxSnapshotFiles {
+			return workspace.NewError(workspace.ErrorInvalidArgument, fmt.Sprintf("change touches more than %d files; too many to snapshot", MaxSnapshotFiles))
+		}
+
+		file, err := s.saveFile(path)
+		if err != nil {
+			return err
+		}
+		change.Files = append(change.Files, file)
+	}
+	if len(change.Files) == 0 {
+		return nil
+	}
+
+	changes, err := s.load()
+	if err != nil {
+		return err
+	}
+	change.ID = 1
+	if len(changes) > 0 {
+		change.ID = changes[len(changes)-1].ID + 1
+	}
+	changes = append(changes, change)
+	if len(changes) > MaxSnapshotChanges {
+		changes = changes[len(changes)-MaxSnapshotChanges:]
+	}
+
+	return s.save(changes)
+}
+
+// Changes lists the recorded changes, oldest first.
+func (s *Snapshots) Changes() ([]Change, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.load()
+}
+
+// Prune forgets the changes recorded before cutoff, deleting the saved
+// contents no remaining change refers to, and returns how many it dropped
+// out of how many were recorded.
+func (s *Snapshots) Prune(cutoff time.Time) (int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changes, err := s.load()
+	if err != nil {
+		return 0, 0, err
+	}
+	kept := slices.DeleteFunc(slices.Clone(changes), func(change Change) bool { return change.At.Before(cutoff) })
+	if len(kept) == len(changes) {
+		return 0, len(changes), nil
+	}
+
+	return len(changes) - len(kept), len(changes), s.save(kept)
+}
+
+// UndoLast rolls back the most recent change and forgets it.
+func (s *Snapshots) UndoLast() (RestoreResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changes, err := s.load()
+	if err != nil {
+		return RestoreResult{}, err
+	}
+	if len(changes) == 0 {
+		return RestoreResult{}, workspace.NewError(workspace.ErrorInvalidArgument, "no recorded changes to undo")
+	}
+
+	last := changes[len(changes)-1]
+	result := RestoreResult{Change: last}
+	for _, file := range slices.Backward(last.Files) {
+		if err := s.restore(file, &result); err != nil {
+			return RestoreResult{}, err
+		}
+	}
+
+	return result, s.save(changes[:len(changes)-1])
+}
+
+// RestoreFile puts path back to how it was before the most recent tool
+// change to it. The restore is recorded as a change of its own, so
+// UndoLast can revert it, and repeating it gives the same result.
+func (s *Snapshots) RestoreFile(path string) (RestoreResult, error) {
+	resolved, err := s.guard.ResolvePath(path)
+	if err != nil {
+		return RestoreResult{}, err
+	}
+	rel := s.guard.RelPath(resolved)
+
+	s.mu.Lock()
+	changes, err := s.load()
+	s.mu.Unlock()
+	if err != nil {
+		return RestoreResult{}, err
+	}
+
+	for _, change := range slices.Backward(changes) {
+		if change.Tool == toolRestoreFile {
+			continue
+		}
+		index := slices.IndexFunc(change.Files, func(file SnapshotFile) bool { return file.Path == rel })
+		if index < 0 {
+			continue
+		}
+
+		if err := s.Record(toolRestoreFile, resolved); err != nil {
+			return RestoreResult{}, err
+		}
+		result := RestoreResult{Change: change}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return result, s.restore(change.Files[index], &result)
+	}
+
+	return RestoreResult{}, workspace.NewError(workspace.ErrorPathNotFound, fmt.Sprintf("no recorded changes to %s", rel))
+}
+
+// saveFile snapshots one path; the caller holds s.mu.
+func (s *Snapshots) saveFile(path string) (SnapshotFile, error) {
+	file := SnapshotFile{Path: s.guard.RelPath(path)}
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return file, nil
+	}
+	if err != nil {
+		return SnapshotFile{}, workspace.NormalizeIOError(err, "snapshot stat failed")
+	}
+	if !info.Mode().IsRegular() {
+		return file, nil
+	}
+
+	file.Existed = true
+	file.Mode = info.Mode().Perm()
+	if info.Size() > MaxSnapshotFileBytes {
+		file.TooLarge = true
+		return file, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return SnapshotFile{}, workspace.NormalizeIOError(err, "snapshot read failed")
+	}
+	sum := sha256.Sum256(content)
+	file.SHA256 = hex.EncodeToString(sum[:])
+
+	object := s.objectPath(file.SHA256)
+	if _, err := os.Stat(object); err == nil {
+		return file, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(object), 0o755); err != nil {
+		return SnapshotFile{}, workspace.NormalizeIOError(err, "create snapshot directory failed")
+	}
+	if err := atomicWrite(object, content, 0o644); err != nil {
+		return SnapshotFile{}, workspace.NormalizeIOError(err, "snapshot write failed")
+	}
+
+	return file, nil
+}
+
+// restore puts one file back to its snapshot state; the caller holds s.mu.
+func (s *Snapshots) restore(file SnapshotFile, result *RestoreResult) error {
+	target, err := s.guard.ResolvePath(file.Path)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case file.TooLarge:
+		result.Skipped = append(result.Skipped, file.Path)
+	case !file.Existed:
+		if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+			return workspace.NormalizeIOError(err, "remove failed")
+		}
+		result.Removed = append(result.Removed, file.Path)
+	default:
+		content, err := os.ReadFile(s.objectPath(file.SHA256))
+		if err != nil {
+			return workspace.NewError(workspace.ErrorIO, fmt.Sprintf("snapshot of %s is missing", file.Path))
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return workspace.NormalizeIOError(err, "create parent directory failed")
+		}
+		if err := s.guard.EnsureContained(target); err != nil {
+			return err
+		}
+		if err := atomicWrite(target, content, file.Mode); err != nil {
+			return workspace.NormalizeIOError(err, "restore failed")
+		}
+		result.Restored = append(result.Restored, file.Path)
+	}
+
+	return nil
+}
+
+// load reads the journal; the caller holds s.mu.
+func (s *Snapshots) load() ([]Change, error) {
+	raw, err := os.ReadFile(filepath.Join(s.dir, snapshotJournal))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, workspace.NormalizeIOError(err, "read snapshot journal failed")
+	}
+
+	var changes []Change
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var change Change
+		if err := json.Unmarshal(line, &change); err != nil {
+			return nil, workspace.NewError(workspace.ErrorIO, "snapshot journal is corrupt: "+err.Error())
+		}
+		changes = append(changes, change)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, workspace.NormalizeIOError(err, "read snapshot journal failed")
+	}
+
+	return changes, nil
+}
+
+// save rewrites the journal and deletes objects no change refers to; the caller holds s.mu.
+func (s *Snapshots) save(changes []Change) error {
+	var buffer bytes.Buffer
+	referenced := make(map[string]bool)
+	for _, change := range changes {
+		line, err := json.Marshal(change)
+		if err != nil {
+			return workspace.NewError(workspace.ErrorIO, "encode snapshot journal: "+err.Error())
+		}
+		buffer.Write(line)
+		buffer.WriteByte('\n')
+		for _, file := range change.Files {
+			if file.SHA256 != "" {
+				referenced[file.SHA256] = true
+			}
+		}
+	}
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return workspace.NormalizeIOError(err, "create snapshot directory failed")
+	}
+	if err := atomicWrite(filepath.Join(s.dir, snapshotJournal), buffer.Bytes(), 0o644); err != nil {
+		return workspace.NormalizeIOError(err, "write snapshot journal failed")
+	}
+
+	objects, err := filepath.Glob(filepath.Join(s.dir, snapshotObjectsDir, "*", "*"))
+	if err != nil {
+		return nil
+	}
+	for _, object := range objects {
+		if !referenced[filepath.Base(object)] {
+			if err := os.Remove(object); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return workspace.NormalizeIOError(err, "prune snapshot failed")
+			}
+		}
+	}
+
+	return nil
+}
+
+// objectPath fans objects out by the first two hex digits, like git.
+func (s *Snapshots) objectPath(sum string) string {
+	return filepath.Join(s.dir, snapshotObjectsDir, sum[:2], sum)
+}
+
+func (s *Snapshots) isInternal(path string) bool {
+	rel, err := filepath.Rel(s.dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// EnableSnapshots saves each file under SnapshotDir before a write, edit,
+// patch, copy, archive, or recursive remove changes it, and turns on
+// UndoLastChange and RestoreFile.
+func (s *Service) EnableSnapshots() {
+	s.snapshots = OpenSnapshots(s.guard)
+}
+
+// SnapshotsEnabled reports whether EnableSnapshots was called.
+func (s *Service) SnapshotsEnabled() bool {
+	return s.snapshots != nil
+}
+
+// UndoLastChange rolls back the most recent snapshotted tool change.
+func (s *Service) UndoLastChange(ctx context.Context) (RestoreResult, error) {
+	if err := s.checkSnapshots(ctx); err != nil {
+		return RestoreResult{}, err
+	}
+
+	result, err := s.snapshots.UndoLast()
+	s.forgetRestored(result)
+	return result, err
+}
+
+// RestoreFile puts path back to how it was before the most recent tool change to it.
+func (s *Service) RestoreFile(ctx context.Context, path string) (RestoreResult, error) {
+	if err := s.checkSnapshots(ctx); err != nil {
+		return RestoreResult{}, err
+	}
+
+	result, err := s.snapshots.RestoreFile(path)
+	s.forgetRestored(result)
+	return result, err
+}
+
+func (s *Service) checkSnapshots(ctx context.Context) error {
+	if s.snapshots == nil {
+		return workspace.NewError(workspace.ErrorInvalidArgument, "file snapshots are disabled; set tools.filesystem.snapshots")
+	}
+
+	return checkContext(ctx)
+}
+
+// snapshot records paths before tool changes them; it is a no-op unless snapshots are enabled.
+func (s *Service) snapshot(tool string, paths ...string) error {
+	if s.snapshots == nil {
+		return nil
+	}
+
+	return s.snapshots.Record(tool, paths...)
+}
+
+func (s *Service) forgetRestored(result RestoreResult) {
+	for _, path := range slices.Concat(result.Restored, result.Removed) {
+		if resolved, err := s.guard.ResolvePath(path); err == nil {
+			s.forget(resolved)
+		}
+	}
+}
+
+// DescribeRestore summarizes a restore in one line, for example
+// "change 3 (edit_file at 14:02:05 UTC): restored notes.txt; removed new.txt".
+func DescribeRestore(result RestoreResult) string {
+	var parts []string
+	if len(result.Restored) > 0 {
+		parts = append(parts, "restored "+strings.Join(result.Restored, ", "))
+	}
+	if len(result.Removed) > 0 {
+		parts = append(parts, "removed "+strings.Join(result.Removed, ", "))
+	}
+	if len(result.Skipped) > 0 {
+		parts = append(parts, fmt.Sprintf("left %s unchanged (over %d bytes, not snapshotted)", strings.Join(result.Skipped, ", "), MaxSnapshotFileBytes))
+	}
+
+	return fmt.Sprintf("change %d (%s at %s UTC): %s", result.Change.ID, result.Change.Tool, result.Change.At.UTC().Format("15:04:05"), strings.Join(parts, "; "))
+}
@@ -0,0 +1,63 @@
+package fs
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"miniclaw/pkg/workspace"
+)
+
+// FileInfoResult describes one workspace path without reading its content.
+type FileInfoResult struct {
+	Path    string
+	Type    string
+	Size    int64
+	Mode    os.FileMode
+	ModTime time.Time
+	// Readable reports whether read_file could return the whole file in one call.
+	Readable bool
+}
+
+// FileInfo returns size, type, mode, and modification time for a path.
+//
+// Symlinks inside the workspace are resolved by the guard, so the reported
+// metadata is for the target.
+func (s *Service) FileInfo(ctx context.Context, path string) (FileInfoResult, error) {
+	ctx, cancel := s.withOperationContext(ctx)
+	defer cancel()
+
+	if err := checkContext(ctx); err != nil {
+		return FileInfoResult{}, err
+	}
+
+	resolvedPath, err := s.guard.ResolvePath(path)
+	if err != nil {
+		return FileInfoResult{}, err
+	}
+	if err := s.checkIgnored(resolvedPath); err != nil {
+		return FileInfoResult{}, err
+	}
+
+	info, err := os.Stat(resolvedPath)
+	if err != nil {
+		return FileInfoResult{}, workspace.NormalizeIOError(err, "stat failed")
+	}
+
+	entryType := "other"
+	switch {
+	case info.IsDir():
+		entryType = "dir"
+	case info.Mode().IsRegular():
+		entryType = "file"
+	}
+
+	return FileInfoResult{
+		Path:     resolvedPath,
+		Type:     entryType,
+		Size:     info.Size(),
+		Mode:     info.Mode().Perm(),
+		ModTime:  info.ModTime().UTC(),
+		Readable: entryType == "file" && info.Size() <= int64(s.maxReadBytes),
+	}, nil
+}
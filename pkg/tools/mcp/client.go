@@ -0,0 +1,237 @@
+// Package mcp is a minimal Model Context Protocol implementation: a client for
+// calling tools on external MCP servers over stdio or streamable HTTP, and a
+// stdio server that exposes miniclaw's own tools.
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"miniclaw/pkg/config"
+)
+
+const (
+	// ProtocolVersion is the MCP revision this client speaks.
+	ProtocolVersion = "2025-06-18"
+	// DefaultTimeout bounds connecting and each request when timeout_seconds is unset.
+	DefaultTimeout = 30 * time.Second
+
+	implementationName    = "miniclaw"
+	implementationVersion = "0.1.0"
+)
+
+// Tool is one tool advertised by a server.
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"inputSchema,omitempty"`
+}
+
+// Content is one block of a tool result.
+type Content struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+// CallResult is a server's answer to tools/call.
+type CallResult struct {
+	Content []Content `json:"content"`
+	// IsError reports a tool-level failure the model should see, as opposed to a protocol error.
+	IsError bool `json:"isError,omitempty"`
+}
+
+// Text joins the text blocks and summarizes any binary blocks.
+func (r CallResult) Text() string {
+	parts := make([]string, 0, len(r.Content))
+	for _, content := range r.Content {
+		switch content.Type {
+		case "text":
+			parts = append(parts, content.Text)
+		default:
+			parts = append(parts, fmt.Sprintf("[%s content: %s, %d base64 bytes]", content.Type, content.MimeType, len(content.Data)))
+		}
+	}
+
+	return strings.Join(parts, "\n")
+}
+
+// RPCError is a JSON-RPC error returned by a server.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("mcp error %d: %s", e.Code, e.Message)
+}
+
+type request struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      *int64 `json:"id,omitempty"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// transport carries JSON-RPC messages to one server.
+type transport interface {
+	// roundTrip sends a request and waits for the response with the same ID.
+	roundTrip(ctx context.Context, req request) (response, error)
+	// notify sends a message that expects no response.
+	notify(ctx context.Context, req request) error
+	close() error
+}
+
+// Client is a connected, initialized MCP server session.
+type Client struct {
+	name      string
+	transport transport
+	timeout   time.Duration
+	nextID    atomic.Int64
+}
+
+// Connect starts or dials the server described by cfg and completes the initialize handshake.
+func Connect(ctx context.Context, cfg config.MCPServerConfig) (*Client, error) {
+	name := strings.TrimSpace(cfg.Name)
+	if name == "" {
+		return nil, errors.New("mcp server name is required")
+	}
+
+	timeout := DefaultTimeout
+	if cfg.TimeoutSeconds > 0 {
+		timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+
+	log := slog.Default().With("component", "tools.mcp", "server", name)
+	var t transport
+	var err error
+	switch command, url := strings.TrimSpace(cfg.Command), strings.TrimSpace(cfg.URL); {
+	case command != "" && url != "":
+		return nil, fmt.Errorf("mcp server %s: set either command or url, not both", name)
+	case command != "":
+		t, err = startStdio(command, cfg.Args, cfg.Env, log)
+	case url != "":
+		t, err = newHTTPTransport(url, cfg.Headers)
+	default:
+		return nil, fmt.Errorf("mcp server %s: command or url is required", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mcp server %s: %w", name, err)
+	}
+
+	client := &Client{name: name, transport: t, timeout: timeout}
+	if err := client.initialize(ctx); err != nil {
+		_ = t.close()
+		return nil, fmt.Errorf("mcp server %s: initialize: %w", name, err)
+	}
+
+	return client, nil
+}
+
+// Name returns the configured server name.
+func (c *Client) Name() string {
+	return c.name
+}
+
+// ListTools returns every tool the server advertises, following pagination cursors.
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	cursor := ""
+	for {
+		params := map[string]any{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+
+		var page struct {
+			Tools      []Tool `json:"tools"`
+			NextCursor string `json:"nextCursor,omitempty"`
+		}
+		if err := c.call(ctx, "tools/list", params, &page); err != nil {
+			return nil, fmt.Errorf("list tools: %w", err)
+		}
+		tools = append(tools, page.Tools...)
+		if page.NextCursor == "" || page.NextCursor == cursor {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool runs one tool with JSON-encoded arguments.
+func (c *Client) CallTool(ctx context.Context, name string, arguments json.RawMessage) (CallResult, error) {
+	if len(strings.TrimSpace(string(arguments))) == 0 {
+		arguments = json.RawMessage("{}")
+	}
+
+	var result CallResult
+	if err := c.call(ctx, "tools/call", map[string]any{"name": name, "arguments": arguments}, &result); err != nil {
+		return CallResult{}, fmt.Errorf("call %s: %w", name, err)
+	}
+
+	return result, nil
+}
+
+// Close ends the session and stops a stdio server.
+func (c *Client) Close() error {
+	return c.transport.close()
+}
+
+func (c *Client) initialize(ctx context.Context) error {
+	params := map[string]any{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]string{"name": implementationName, "version": implementationVersion},
+	}
+	var result struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if err := c.call(ctx, "initialize", params, &result); err != nil {
+		return err
+	}
+	if setter, ok := c.transport.(interface{ setProtocolVersion(string) }); ok {
+		setter.setProtocolVersion(result.ProtocolVersion)
+	}
+
+	notifyCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.transport.notify(notifyCtx, request{JSONRPC: "2.0", Method: "notifications/initialized"})
+}
+
+// call sends one request bounded by the client timeout and decodes its result into out.
+func (c *Client) call(ctx context.Context, method string, params any, out any) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	id := c.nextID.Add(1)
+	resp, err := c.transport.roundTrip(ctx, request{JSONRPC: "2.0", ID: &id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if out == nil || len(resp.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, out); err != nil {
+		return fmt.Errorf("decode %s result: %w", method, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"miniclaw/pkg/config"
+)
+
+// serverEnv makes the test binary act as a stdio MCP server.
+const serverEnv = "MINICLAW_MCP_TEST_SERVER"
+
+func TestMain(m *testing.M) {
+	switch os.Getenv(serverEnv) {
+	case "1":
+		serveStdio()
+		os.Exit(0)
+	case "server":
+		_ = NewServer(testServerTools()).Serve(context.Background(), os.Stdin, os.Stdout)
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// serveStdio answers newline-delimited requests until stdin closes.
+func serveStdio() {
+	scanner := bufio.NewScanner(os.Stdin)
+	encoder := json.NewEncoder(os.Stdout)
+	for scanner.Scan() {
+		var req serverRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil || req.ID == nil {
+			continue
+		}
+		// Exercise server-to-client requests before the first tool call.
+		if req.Method == "tools/call" {
+			_ = encoder.Encode(map[string]any{"jsonrpc": "2.0", "id": 9000, "method": "ping"})
+			_ = encoder.Encode(map[string]any{"jsonrpc": "2.0", "method": "notifications/message", "params": map[string]any{}})
+		}
+		_ = encoder.Encode(fakeReply(req))
+	}
+}
+
+// serverRequest is a request as the fake server sees it.
+type serverRequest struct {
+	ID     *int64          `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// fakeReply implements a two-page tools/list, an echo tool, and a failing tool.
+func fakeReply(req serverRequest) map[string]any {
+	reply := map[string]any{"jsonrpc": "2.0", "id": *req.ID}
+	var params struct {
+		Cursor    string          `json:"cursor"`
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if len(req.Params) > 0 {
+		_ = json.Unmarshal(req.Params, &params)
+	}
+
+	switch req.Method {
+	case "initialize":
+		reply["result"] = map[string]any{"protocolVersion": ProtocolVersion, "capabilities": map[string]any{"tools": map[string]any{}}}
+	case "tools/list":
+		if params.Cursor == "" {
+			reply["result"] = map[string]any{
+				"tools":      []map[string]any{{"name": "echo", "description": "Echo input.", "inputSchema": map[string]any{"type": "object", "properties": map[string]any{"text": map[string]any{"type": "string"}}, "required": []string{"text"}}}},
+				"nextCursor": "page-2",
+			}
+		} else {
+			reply["result"] = map[string]any{"tools": []map[string]any{{"name": "fail"}}}
+		}
+	case "tools/call":
+		if params.Name == "fail" {
+			reply["result"] = map[string]any{"content": []map[string]any{{"type": "text", "text": "boom"}}, "isError": true}
+		} else {
+			reply["result"] = map[string]any{"content": []map[string]any{{"type": "text", "text": "echo " + string(params.Arguments)}}}
+		}
+	default:
+		reply["error"] = map[string]any{"code": -32601, "message": "method not found"}
+	}
+
+	return reply
+}
+
+func TestStdioClientListsAndCallsTools(t *testing.T) {
+	t.Setenv(serverEnv, "1")
+
+	client, err := Connect(context.Background(), config.MCPServerConfig{Name: "fake", Command: os.Args[0]})
+	if err != nil {
+		t.Fatalf("Connect error: %v", err)
+	}
+	defer client.Close()
+
+	exerciseClient(t, client)
+}
+
+func TestHTTPClientHandlesJSONAndEventStreams(t *testing.T) {
+	var sawSession, sawAuth bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		sawAuth = sawAuth || r.Header.Get("Authorization") == "Bearer token"
+
+		var req serverRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.ID == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		if req.Method == "initialize" {
+			w.Header().Set(sessionIDHeader, "session-1")
+		} else {
+			sawSession = r.Header.Get(sessionIDHeader) == "session-1" && r.Header.Get(protocolVersionHeader) == ProtocolVersion
+		}
+
+		reply, _ := json.Marshal(fakeReply(req))
+		if req.Method == "tools/call" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\n")
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", reply)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(reply)
+	}))
+	defer server.Close()
+
+	client, err := Connect(context.Background(), config.MCPServerConfig{Name: "remote", URL: server.URL, Headers: map[string]string{"Authorization": "Bearer token"}})
+	if err != nil {
+		t.Fatalf("Connect error: %v", err)
+	}
+	defer client.Close()
+
+	exerciseClient(t, client)
+	if !sawSession || !sawAuth {
+		t.Fatalf("session header seen = %v, auth header seen = %v; want both", sawSession, sawAuth)
+	}
+}
+
+func TestConnectRejectsInvalidConfig(t *testing.T) {
+	for name, cfg := range map[string]config.MCPServerConfig{
+		"no name":      {Command: "true"},
+		"no transport": {Name: "x"},
+		"both":         {Name: "x", Command: "true", URL: "http://localhost"},
+		"bad url":      {Name: "x", URL: "ftp://localhost"},
+	} {
+		if _, err := Connect(context.Background(), cfg); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func exerciseClient(t *testing.T, client *Client) {
+	t.Helper()
+
+	tools, err := client.ListTools(context.Background())
+	if err != nil {
+		t.Fatalf("ListTools error: %v", err)
+	}
+	if len(tools) != 2 || tools[0].Name != "echo" || tools[1].Name != "fail" {
+		t.Fatalf("tools = %+v, want echo and fail across two pages", tools)
+	}
+
+	result, err := client.CallTool(context.Background(), "echo", json.RawMessage(`{"text":"hi"}`))
+	if err != nil {
+		t.Fatalf("CallTool error: %v", err)
+	}
+	if result.IsError || !strings.Contains(result.Text(), `"text":"hi"`) {
+		t.Fatalf("echo result = %+v, want echoed arguments", result)
+	}
+
+	result, err = client.CallTool(context.Background(), "fail", nil)
+	if err != nil {
+		t.Fatalf("CallTool error: %v", err)
+	}
+	if !result.IsError || result.Text() != "boom" {
+		t.Fatalf("fail result = %+v, want tool error", result)
+	}
+
+	var rpcErr *RPCError
+	if err := client.call(context.Background(), "resources/list", nil, nil); !errors.As(err, &rpcErr) || rpcErr.Code != -32601 {
+		t.Fatalf("unsupported method error = %v, want RPCError -32601", err)
+	}
+}
@@ -0,0 +1,177 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	sessionIDHeader       = "Mcp-Session-Id"
+	protocolVersionHeader = "MCP-Protocol-Version"
+
+	// maxErrorBody bounds how much of a failed HTTP response is quoted in errors.
+	maxErrorBody = 512
+)
+
+// httpTransport speaks the MCP streamable HTTP transport: one POST per message,
+// answered with JSON or a server-sent event stream.
+type httpTransport struct {
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+
+	mu              sync.Mutex
+	sessionID       string
+	protocolVersion string
+}
+
+func newHTTPTransport(endpoint string, headers map[string]string) (*httpTransport, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid url %q", endpoint)
+	}
+
+	return &httpTransport{endpoint: endpoint, headers: headers, client: &http.Client{}}, nil
+}
+
+func (t *httpTransport) setProtocolVersion(version string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.protocolVersion = version
+}
+
+func (t *httpTransport) roundTrip(ctx context.Context, req request) (response, error) {
+	resp, err := t.post(ctx, req)
+	if err != nil {
+		return response{}, err
+	}
+	defer resp.Body.Close()
+
+	if id := resp.Header.Get(sessionIDHeader); id != "" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "text/event-stream" {
+		return readEventStream(resp.Body, *req.ID)
+	}
+
+	var message response
+	if err := json.NewDecoder(resp.Body).Decode(&message); err != nil {
+		return response{}, fmt.Errorf("decode response: %w", err)
+	}
+
+	return message, nil
+}
+
+func (t *httpTransport) notify(ctx context.Context, req request) error {
+	resp, err := t.post(ctx, req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+
+	return nil
+}
+
+// post sends one message and returns the response when its status is 2xx.
+func (t *httpTransport) post(ctx context.Context, message request) (*http.Response, error) {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("encode message: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json, text/event-stream")
+	for key, value := range t.headers {
+		httpReq.Header.Set(key, value)
+	}
+	t.mu.Lock()
+	if t.sessionID != "" {
+		httpReq.Header.Set(sessionIDHeader, t.sessionID)
+	}
+	if t.protocolVersion != "" {
+		httpReq.Header.Set(protocolVersionHeader, t.protocolVersion)
+	}
+	t.mu.Unlock()
+
+	resp, err := t.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return nil, fmt.Errorf("http %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return resp, nil
+}
+
+// close ends the server-side session when the server issued one.
+func (t *httpTransport) close() error {
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if sessionID == "" {
+		return nil
+	}
+
+	httpReq, err := http.NewRequest(http.MethodDelete, t.endpoint, nil)
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set(sessionIDHeader, sessionID)
+	resp, err := t.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+// readEventStream returns the first event carrying the response to id.
+func readEventStream(body io.Reader, id int64) (response, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if value, ok := strings.CutPrefix(line, "data:"); ok {
+			data.WriteString(strings.TrimPrefix(value, " "))
+			continue
+		}
+		if line != "" || data.Len() == 0 {
+			continue
+		}
+
+		var message response
+		if err := json.Unmarshal([]byte(data.String()), &message); err == nil && message.Method == "" && message.ID != nil && *message.ID == id {
+			return message, nil
+		}
+		data.Reset()
+	}
+	if err := scanner.Err(); err != nil {
+		return response{}, fmt.Errorf("read event stream: %w", err)
+	}
+
+	return response{}, errors.New("event stream ended without a response")
+}
@@ -0,0 +1,198 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// JSON-RPC error codes the server returns.
+const (
+	codeParseError     = -32700
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// supportedVersions are the protocol revisions the server accepts; the tools
+// subset it implements is the same in all of them.
+var supportedVersions = []string{ProtocolVersion, "2025-03-26", "2024-11-05"}
+
+// ServerTool is a tool a Server exposes to MCP clients.
+type ServerTool struct {
+	Tool
+	// Call runs the tool. Returned errors are reported as tool errors so the
+	// calling model sees them, like any other failed call.
+	Call func(ctx context.Context, arguments json.RawMessage) (CallResult, error)
+}
+
+// Server answers MCP requests for a fixed set of tools over newline-delimited JSON-RPC.
+type Server struct {
+	tools  []ServerTool
+	byName map[string]ServerTool
+	log    *slog.Logger
+}
+
+// NewServer builds a server for tools.
+func NewServer(tools []ServerTool) *Server {
+	byName := make(map[string]ServerTool, len(tools))
+	for _, tool := range tools {
+		byName[tool.Name] = tool
+	}
+
+	return &Server{
+		tools:  tools,
+		byName: byName,
+		log:    slog.Default().With("component", "tools.mcp"),
+	}
+}
+
+// serverMessage is an incoming request or notification; IDs may be numbers or strings.
+type serverMessage struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+type serverReply struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// Serve reads requests from in and writes replies to out until in closes.
+//
+// Tool calls run concurrently and are cancelled by notifications/cancelled or
+// when ctx ends; Serve waits for calls in flight before returning.
+func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	var writeMu sync.Mutex
+	encoder := json.NewEncoder(out)
+	reply := func(message serverReply) {
+		message.JSONRPC = "2.0"
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if err := encoder.Encode(message); err != nil {
+			s.log.Debug("Failed to write MCP reply", "error", err)
+		}
+	}
+
+	var (
+		wg        sync.WaitGroup
+		cancelsMu sync.Mutex
+		cancels   = make(map[string]context.CancelFunc)
+	)
+	defer wg.Wait()
+
+	reader := bufio.NewReader(in)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var message serverMessage
+			if err := json.Unmarshal(line, &message); err != nil {
+				reply(serverReply{ID: json.RawMessage("null"), Error: &RPCError{Code: codeParseError, Message: "parse error"}})
+			} else if len(message.ID) == 0 || string(message.ID) == "null" {
+				if message.Method == "notifications/cancelled" {
+					var params struct {
+						RequestID json.RawMessage `json:"requestId"`
+					}
+					_ = json.Unmarshal(message.Params, &params)
+					cancelsMu.Lock()
+					if cancel, ok := cancels[string(params.RequestID)]; ok {
+						cancel()
+					}
+					cancelsMu.Unlock()
+				}
+			} else if message.Method == "tools/call" {
+				callCtx, cancel := context.WithCancel(ctx)
+				key := string(message.ID)
+				cancelsMu.Lock()
+				cancels[key] = cancel
+				cancelsMu.Unlock()
+
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					result, rpcErr := s.callTool(callCtx, message.Params)
+					cancelsMu.Lock()
+					delete(cancels, key)
+					cancelsMu.Unlock()
+					cancel()
+					reply(serverReply{ID: message.ID, Result: result, Error: rpcErr})
+				}()
+			} else {
+				result, rpcErr := s.handle(message)
+				reply(serverReply{ID: message.ID, Result: result, Error: rpcErr})
+			}
+		}
+		if readErr != nil {
+			if errors.Is(readErr, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("read request: %w", readErr)
+		}
+	}
+}
+
+// handle answers every request except tools/call.
+func (s *Server) handle(message serverMessage) (any, *RPCError) {
+	switch message.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		_ = json.Unmarshal(message.Params, &params)
+		version := ProtocolVersion
+		if slices.Contains(supportedVersions, params.ProtocolVersion) {
+			version = params.ProtocolVersion
+		}
+
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{"listChanged": false}},
+			"serverInfo":      map[string]string{"name": implementationName, "version": implementationVersion},
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		tools := make([]Tool, 0, len(s.tools))
+		for _, tool := range s.tools {
+			tools = append(tools, tool.Tool)
+		}
+		return map[string]any{"tools": tools}, nil
+	default:
+		return nil, &RPCError{Code: codeMethodNotFound, Message: "method not found: " + message.Method}
+	}
+}
+
+func (s *Server) callTool(ctx context.Context, raw json.RawMessage) (any, *RPCError) {
+	var params struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, &RPCError{Code: codeInvalidParams, Message: "invalid tools/call params"}
+	}
+	tool, ok := s.byName[params.Name]
+	if !ok {
+		return nil, &RPCError{Code: codeInvalidParams, Message: "unknown tool: " + params.Name}
+	}
+	if len(params.Arguments) == 0 || string(params.Arguments) == "null" {
+		params.Arguments = json.RawMessage("{}")
+	}
+
+	result, err := tool.Call(ctx, params.Arguments)
+	if err != nil {
+		result = CallResult{Content: []Content{{Type: "text", Text: err.Error()}}, IsError: true}
+	}
+	if result.Content == nil {
+		result.Content = []Content{}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,112 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"miniclaw/pkg/config"
+)
+
+// testServerTools are served by the test binary when serverEnv is "server".
+func testServerTools() []ServerTool {
+	return []ServerTool{
+		{
+			Tool: Tool{Name: "echo", Description: "Echo input.", InputSchema: json.RawMessage(`{"type":"object","properties":{},"required":[]}`)},
+			Call: func(_ context.Context, arguments json.RawMessage) (CallResult, error) {
+				return CallResult{Content: []Content{{Type: "text", Text: "echo " + string(arguments)}}}, nil
+			},
+		},
+		{
+			Tool: Tool{Name: "fail", InputSchema: json.RawMessage(`{"type":"object"}`)},
+			Call: func(context.Context, json.RawMessage) (CallResult, error) {
+				return CallResult{}, errors.New("boom")
+			},
+		},
+	}
+}
+
+func TestServerInteroperatesWithClient(t *testing.T) {
+	t.Setenv(serverEnv, "server")
+
+	client, err := Connect(context.Background(), config.MCPServerConfig{Name: "self", Command: os.Args[0]})
+	if err != nil {
+		t.Fatalf("Connect error: %v", err)
+	}
+	defer client.Close()
+
+	tools, err := client.ListTools(context.Background())
+	if err != nil {
+		t.Fatalf("ListTools error: %v", err)
+	}
+	if len(tools) != 2 || tools[0].Name != "echo" {
+		t.Fatalf("tools = %+v, want echo and fail", tools)
+	}
+
+	result, err := client.CallTool(context.Background(), "echo", json.RawMessage(`{"text":"hi"}`))
+	if err != nil || result.IsError || result.Text() != `echo {"text":"hi"}` {
+		t.Fatalf("echo result = %+v, err = %v", result, err)
+	}
+
+	result, err = client.CallTool(context.Background(), "fail", nil)
+	if err != nil || !result.IsError || result.Text() != "boom" {
+		t.Fatalf("fail result = %+v, err = %v; want tool error", result, err)
+	}
+
+	var rpcErr *RPCError
+	if _, err := client.CallTool(context.Background(), "missing", nil); !errors.As(err, &rpcErr) || rpcErr.Code != codeInvalidParams {
+		t.Fatalf("unknown tool error = %v, want RPCError %d", err, codeInvalidParams)
+	}
+}
+
+func TestServerRepliesToMalformedAndUnknownRequests(t *testing.T) {
+	in := strings.NewReader(strings.Join([]string{
+		`not json`,
+		`{"jsonrpc":"2.0","id":"a","method":"initialize","params":{"protocolVersion":"2024-11-05"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":"b","method":"resources/list"}`,
+	}, "\n"))
+	reader, writer := io.Pipe()
+	go func() {
+		_ = writer.CloseWithError(NewServer(nil).Serve(context.Background(), in, writer))
+	}()
+
+	var replies []serverReply
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		var reply serverReply
+		if err := json.Unmarshal(scanner.Bytes(), &reply); err != nil {
+			t.Fatalf("decode reply %q: %v", scanner.Text(), err)
+		}
+		replies = append(replies, reply)
+	}
+
+	if len(replies) != 3 {
+		t.Fatalf("replies = %d, want 3 (notifications get none)", len(replies))
+	}
+	if replies[0].Error == nil || replies[0].Error.Code != codeParseError {
+		t.Fatalf("first reply = %+v, want parse error", replies[0])
+	}
+	if string(replies[1].ID) != `"a"` || !strings.Contains(string(mustJSON(t, replies[1].Result)), `"protocolVersion":"2024-11-05"`) {
+		t.Fatalf("initialize reply = %+v, want string id and negotiated version", replies[1])
+	}
+	if string(replies[2].ID) != `"b"` || replies[2].Error == nil || replies[2].Error.Code != codeMethodNotFound {
+		t.Fatalf("unknown method reply = %+v, want method not found", replies[2])
+	}
+}
+
+func mustJSON(t *testing.T, value any) []byte {
+	t.Helper()
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	return encoded
+}
@@ -0,0 +1,195 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	osexec "os/exec"
+	"sync"
+	"time"
+)
+
+// stopGrace is how long close waits for a server to exit after its stdin closes.
+const stopGrace = 2 * time.Second
+
+// stdioTransport exchanges newline-delimited JSON-RPC messages with a child process.
+type stdioTransport struct {
+	cmd   *osexec.Cmd
+	stdin io.WriteCloser
+	log   *slog.Logger
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[int64]chan response
+	// readErr is set once stdout closes; later requests fail with it.
+	readErr error
+	done    chan struct{}
+}
+
+// startStdio launches command with the host environment plus env.
+func startStdio(command string, args []string, env map[string]string, log *slog.Logger) (*stdioTransport, error) {
+	cmd := osexec.Command(command, args...)
+	cmd.Env = os.Environ()
+	for key, value := range env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("open stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("open stdout: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("open stderr: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start %s: %w", command, err)
+	}
+
+	t := &stdioTransport{
+		cmd:     cmd,
+		stdin:   stdin,
+		log:     log,
+		pending: make(map[int64]chan response),
+		done:    make(chan struct{}),
+	}
+	go t.readLoop(stdout)
+	go t.logStderr(stderr)
+
+	return t, nil
+}
+
+func (t *stdioTransport) roundTrip(ctx context.Context, req request) (response, error) {
+	reply := make(chan response, 1)
+	t.mu.Lock()
+	if t.readErr != nil {
+		t.mu.Unlock()
+		return response{}, t.readErr
+	}
+	t.pending[*req.ID] = reply
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, *req.ID)
+		t.mu.Unlock()
+	}()
+
+	if err := t.write(req); err != nil {
+		return response{}, err
+	}
+
+	select {
+	case resp := <-reply:
+		return resp, nil
+	case <-t.done:
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		return response{}, t.readErr
+	case <-ctx.Done():
+		return response{}, ctx.Err()
+	}
+}
+
+func (t *stdioTransport) notify(_ context.Context, req request) error {
+	return t.write(req)
+}
+
+func (t *stdioTransport) write(message any) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("encode message: %w", err)
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if _, err := t.stdin.Write(append(payload, '\n')); err != nil {
+		return fmt.Errorf("write to server: %w", err)
+	}
+
+	return nil
+}
+
+// readLoop routes responses to waiting requests and answers server pings.
+func (t *stdioTransport) readLoop(stdout io.Reader) {
+	reader := bufio.NewReader(stdout)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			t.dispatch(line)
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = errors.New("server closed its output")
+			}
+			t.mu.Lock()
+			t.readErr = fmt.Errorf("mcp stdio: %w", err)
+			t.mu.Unlock()
+			close(t.done)
+			return
+		}
+	}
+}
+
+func (t *stdioTransport) dispatch(line []byte) {
+	var message response
+	if err := json.Unmarshal(line, &message); err != nil {
+		t.log.Debug("Ignoring non-JSON MCP output", "error", err)
+		return
+	}
+
+	switch {
+	case message.Method != "" && message.ID != nil:
+		// Servers may ping the client; any other request is unsupported.
+		reply := map[string]any{"jsonrpc": "2.0", "id": *message.ID, "result": map[string]any{}}
+		if message.Method != "ping" {
+			reply = map[string]any{"jsonrpc": "2.0", "id": *message.ID, "error": RPCError{Code: -32601, Message: "method not found"}}
+		}
+		if err := t.write(reply); err != nil {
+			t.log.Debug("Failed to answer MCP server request", "method", message.Method, "error", err)
+		}
+	case message.Method != "":
+		t.log.Debug("MCP notification", "method", message.Method)
+	case message.ID != nil:
+		t.mu.Lock()
+		reply, ok := t.pending[*message.ID]
+		t.mu.Unlock()
+		if ok {
+			reply <- message
+		}
+	}
+}
+
+func (t *stdioTransport) logStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		t.log.Debug("MCP server stderr", "line", scanner.Text())
+	}
+}
+
+// close shuts stdin, which asks the server to exit, and kills it after stopGrace.
+func (t *stdioTransport) close() error {
+	_ = t.stdin.Close()
+
+	var err error
+	select {
+	case <-t.done:
+	case <-time.After(stopGrace):
+		if killErr := t.cmd.Process.Kill(); killErr != nil {
+			err = fmt.Errorf("kill server: %w", killErr)
+		}
+	}
+	// Wait also closes stdout, which unblocks readLoop after a kill.
+	_ = t.cmd.Wait()
+
+	return err
+}
@@ -0,0 +1,223 @@
+// Package memory keeps agent notes in a Markdown file in the workspace so
+// facts survive across sessions and can be read or edited by hand.
+package memory
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"miniclaw/pkg/config"
+	"miniclaw/pkg/workspace"
+)
+
+const (
+	DefaultFile          = "MEMORY.md"
+	DefaultMaxBytes      = 64 * 1024
+	DefaultMaxEntryChars = 500
+	// DefaultRecallLimit bounds how many entries one recall returns.
+	DefaultRecallLimit = 20
+
+	header     = "# MiniClaw memory\n\nFacts saved by the agent with the remember tool, one per line.\n\n"
+	dateLayout = "2006-01-02"
+)
+
+// Entry is one remembered fact.
+type Entry struct {
+	// At is the day the fact was saved; zero for lines written by hand without a date.
+	At   time.Time
+	Text string
+}
+
+// String renders the entry as it is stored, without the list marker.
+func (e Entry) String() string {
+	if e.At.IsZero() {
+		return e.Text
+	}
+
+	return e.At.Format(dateLayout) + ": " + e.Text
+}
+
+// Store reads and appends entries in the workspace memory file.
+type Store struct {
+	path          string
+	maxBytes      int64
+	maxEntryChars int
+	now           func() time.Time
+
+	mu sync.Mutex
+}
+
+// NewStore resolves the memory file from tools.memory config inside the workspace.
+func NewStore(guard *workspace.Guard, cfg config.MemoryToolsConfig) (*Store, error) {
+	if guard == nil {
+		return nil, errors.New("workspace guard is required")
+	}
+	if cfg.MaxBytes < 0 || cfg.MaxEntryChars < 0 {
+		return nil, errors.New("tools.memory limits must not be negative")
+	}
+
+	file := strings.TrimSpace(cfg.Path)
+	if file == "" {
+		file = DefaultFile
+	}
+	path, err := guard.ResolvePath(file)
+	if err != nil {
+		return nil, fmt.Errorf("tools.memory.path: %w", err)
+	}
+
+	store := &Store{
+		path:          path,
+		maxBytes:      DefaultMaxBytes,
+		maxEntryChars: DefaultMaxEntryChars,
+		now:           time.Now,
+	}
+	if cfg.MaxBytes > 0 {
+		store.maxBytes = cfg.MaxBytes
+	}
+	if cfg.MaxEntryChars > 0 {
+		store.maxEntryChars = cfg.MaxEntryChars
+	}
+
+	return store, nil
+}
+
+// Path returns the absolute path of the memory file.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Remember appends text as a new entry dated today.
+//
+// Line breaks are folded into spaces so each fact stays on one line. Facts
+// longer than the entry limit, and appends that would grow the file past the
+// size limit, are rejected rather than trimmed.
+func (s *Store) Remember(text string) (Entry, error) {
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return Entry{}, workspace.NewError(workspace.ErrorInvalidArgument, "fact must not be empty")
+	}
+	if chars := len([]rune(text)); chars > s.maxEntryChars {
+		return Entry{}, workspace.NewError(workspace.ErrorInvalidArgument, fmt.Sprintf("fact is %d characters; the limit is %d", chars, s.maxEntryChars))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	size := int64(0)
+	if info, err := os.Stat(s.path); err == nil {
+		size = info.Size()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return Entry{}, workspace.NormalizeIOError(err, "stat memory file")
+	}
+
+	entry := Entry{At: s.now(), Text: text}
+	line := "- " + entry.String() + "\n"
+	if size == 0 {
+		line = header + line
+	}
+	if size+int64(len(line)) > s.maxBytes {
+		return Entry{}, workspace.NewError(workspace.ErrorMemoryFull, fmt.Sprintf("memory file is %d bytes and the limit is %d; ask the user to prune %s", size, s.maxBytes, filepath.Base(s.path)))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return Entry{}, workspace.NormalizeIOError(err, "create memory directory")
+	}
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return Entry{}, workspace.NormalizeIOError(err, "open memory file")
+	}
+	if _, err := file.WriteString(line); err != nil {
+		_ = file.Close()
+		return Entry{}, workspace.NormalizeIOError(err, "write memory file")
+	}
+	if err := file.Close(); err != nil {
+		return Entry{}, workspace.NormalizeIOError(err, "close memory file")
+	}
+
+	return entry, nil
+}
+
+// Entries returns every entry in file order; a missing file has none.
+func (s *Store) Entries() ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, workspace.NormalizeIOError(err, "open memory file")
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), int(max(s.maxBytes, 64*1024)))
+	for scanner.Scan() {
+		if entry, ok := parseEntry(scanner.Text()); ok {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, workspace.NormalizeIOError(err, "read memory file")
+	}
+
+	return entries, nil
+}
+
+// Recall returns up to limit entries containing every word of query
+// (case-insensitive), newest first. An empty query matches every entry.
+func (s *Store) Recall(query string, limit int) ([]Entry, error) {
+	entries, err := s.Entries()
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultRecallLimit
+	}
+
+	words := strings.Fields(strings.ToLower(query))
+	matches := make([]Entry, 0, min(limit, len(entries)))
+	for i := len(entries) - 1; i >= 0 && len(matches) < limit; i-- {
+		text := strings.ToLower(entries[i].Text)
+		matched := true
+		for _, word := range words {
+			if !strings.Contains(text, word) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			matches = append(matches, entries[i])
+		}
+	}
+
+	return matches, nil
+}
+
+// parseEntry reads one "- [YYYY-MM-DD: ]text" list item.
+func parseEntry(line string) (Entry, bool) {
+	text, ok := strings.CutPrefix(strings.TrimSpace(line), "- ")
+	if !ok {
+		return Entry{}, false
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Entry{}, false
+	}
+
+	if date, rest, found := strings.Cut(text, ": "); found {
+		if at, err := time.Parse(dateLayout, date); err == nil {
+			return Entry{At: at, Text: strings.TrimSpace(rest)}, true
+		}
+	}
+
+	return Entry{Text: text}, true
+}
@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"miniclaw/pkg/config"
+	"miniclaw/pkg/workspace"
+)
+
+func newTestStore(t *testing.T, cfg config.MemoryToolsConfig) *Store {
+	t.Helper()
+
+	guard, err := workspace.NewGuard(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewGuard error: %v", err)
+	}
+	store, err := NewStore(guard, cfg)
+	if err != nil {
+		t.Fatalf("NewStore error: %v", err)
+	}
+	store.now = func() time.Time { return time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC) }
+
+	return store
+}
+
+func TestRememberAndRecall(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t, config.MemoryToolsConfig{})
+	for _, fact := range []string{"User prefers tabs", "Deploys go out on\nFridays", "User's editor is Helix"} {
+		if _, err := store.Remember(fact); err != nil {
+			t.Fatalf("Remember(%q) error: %v", fact, err)
+		}
+	}
+
+	content, err := os.ReadFile(store.Path())
+	if err != nil {
+		t.Fatalf("ReadFile error: %v", err)
+	}
+	if !strings.HasPrefix(string(content), "# MiniClaw memory") || !strings.Contains(string(content), "- 2026-03-04: Deploys go out on Fridays\n") {
+		t.Fatalf("memory file = %q, want header and one dated line per fact", content)
+	}
+
+	matches, err := store.Recall("USER", 0)
+	if err != nil {
+		t.Fatalf("Recall error: %v", err)
+	}
+	if len(matches) != 2 || matches[0].Text != "User's editor is Helix" || matches[1].Text != "User prefers tabs" {
+		t.Fatalf("matches = %+v, want user facts newest first", matches)
+	}
+	if matches, _ := store.Recall("", 1); len(matches) != 1 || matches[0].Text != "User's editor is Helix" {
+		t.Fatalf("limited matches = %+v, want newest fact only", matches)
+	}
+
+	// Hand-written lines without a date are still entries.
+	if err := os.WriteFile(store.Path(), append(content, []byte("- hand written note\nnot an entry\n")...), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	entries, err := store.Entries()
+	if err != nil {
+		t.Fatalf("Entries error: %v", err)
+	}
+	if len(entries) != 4 || !entries[3].At.IsZero() || entries[3].String() != "hand written note" {
+		t.Fatalf("entries = %+v, want hand-written entry last", entries)
+	}
+}
+
+func TestRememberEnforcesLimits(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t, config.MemoryToolsConfig{MaxBytes: 200, MaxEntryChars: 40})
+	if _, err := store.Remember("   "); workspace.CategoryFromError(err) != workspace.ErrorInvalidArgument {
+		t.Fatalf("empty fact error = %v, want invalid_argument", err)
+	}
+	if _, err := store.Remember(strings.Repeat("x", 41)); workspace.CategoryFromError(err) != workspace.ErrorInvalidArgument {
+		t.Fatalf("long fact error = %v, want invalid_argument", err)
+	}
+
+	var err error
+	for range 10 {
+		if _, err = store.Remember("a fact that takes some room"); err != nil {
+			break
+		}
+	}
+	if workspace.CategoryFromError(err) != workspace.ErrorMemoryFull {
+		t.Fatalf("error = %v, want memory_full", err)
+	}
+	if info, statErr := os.Stat(store.Path()); statErr != nil || info.Size() > 200 {
+		t.Fatalf("memory file size = %v (%v), want at most 200 bytes", info.Size(), statErr)
+	}
+}
+
+func TestNewStoreRejectsPathsOutsideWorkspace(t *testing.T) {
+	t.Parallel()
+
+	guard, err := workspace.NewGuard(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewGuard error: %v", err)
+	}
+	if _, err := NewStore(guard, config.MemoryToolsConfig{Path: "../outside.md"}); err == nil {
+		t.Fatal("expected path outside workspace to be rejected")
+	}
+}
@@ -0,0 +1,192 @@
+// Package plan keeps the agent's task list for one session so it can lay out
+// multi-step work and report progress as it goes.
+package plan
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"miniclaw/pkg/workspace"
+)
+
+const (
+	StatusPending    = "pending"
+	StatusInProgress = "in_progress"
+	StatusDone       = "done"
+
+	// MaxItems bounds how many items one plan holds.
+	MaxItems = 50
+	// MaxItemChars bounds the text of one item.
+	MaxItemChars = 200
+
+	// Header starts every rendered plan; the chat UI uses it to tell plans from tool errors.
+	Header = "Plan "
+)
+
+// Tool names the plan tools are registered under.
+const (
+	ToolAdd      = "plan_add"
+	ToolUpdate   = "plan_update"
+	ToolComplete = "plan_complete"
+)
+
+// IsTool reports whether name is one of the plan tools.
+func IsTool(name string) bool {
+	switch name {
+	case ToolAdd, ToolUpdate, ToolComplete:
+		return true
+	default:
+		return false
+	}
+}
+
+// Item is one step of the plan.
+type Item struct {
+	ID     int
+	Text   string
+	Status string
+}
+
+// Plan is an ordered task list. The zero value is an empty plan ready to use.
+type Plan struct {
+	mu     sync.Mutex
+	items  []Item
+	nextID int
+}
+
+// Add appends texts as pending items and returns their IDs.
+func (p *Plan) Add(texts []string) ([]int, error) {
+	cleaned := make([]string, 0, len(texts))
+	for _, text := range texts {
+		text, err := cleanText(text)
+		if err != nil {
+			return nil, err
+		}
+		cleaned = append(cleaned, text)
+	}
+	if len(cleaned) == 0 {
+		return nil, workspace.NewError(workspace.ErrorInvalidArgument, "items must not be empty")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.items)+len(cleaned) > MaxItems {
+		return nil, workspace.NewError(workspace.ErrorInvalidArgument, fmt.Sprintf("plan would have %d items; the limit is %d", len(p.items)+len(cleaned), MaxItems))
+	}
+	ids := make([]int, 0, len(cleaned))
+	for _, text := range cleaned {
+		p.nextID++
+		p.items = append(p.items, Item{ID: p.nextID, Text: text, Status: StatusPending})
+		ids = append(ids, p.nextID)
+	}
+
+	return ids, nil
+}
+
+// Update changes an item's text, status, or both; empty arguments keep the current value.
+func (p *Plan) Update(id int, text string, status string) error {
+	if text = strings.TrimSpace(text); text != "" {
+		cleaned, err := cleanText(text)
+		if err != nil {
+			return err
+		}
+		text = cleaned
+	}
+	status = strings.ToLower(strings.TrimSpace(status))
+	switch status {
+	case "", StatusPending, StatusInProgress, StatusDone:
+	default:
+		return workspace.NewError(workspace.ErrorInvalidArgument, fmt.Sprintf("status %q must be %s, %s, or %s", status, StatusPending, StatusInProgress, StatusDone))
+	}
+	if text == "" && status == "" {
+		return workspace.NewError(workspace.ErrorInvalidArgument, "set text or status")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	item, err := p.find(id)
+	if err != nil {
+		return err
+	}
+	if text != "" {
+		item.Text = text
+	}
+	if status != "" {
+		item.Status = status
+	}
+
+	return nil
+}
+
+// Complete marks an item done.
+func (p *Plan) Complete(id int) error {
+	return p.Update(id, "", StatusDone)
+}
+
+// Items returns a copy of the items in plan order.
+func (p *Plan) Items() []Item {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	items := make([]Item, len(p.items))
+	copy(items, p.items)
+	return items
+}
+
+// Render formats the plan as a checklist, for example:
+//
+//	Plan (1/3 done)
+//	[x] 1. Read the config loader
+//	[~] 2. Add the new field
+//	[ ] 3. Run the tests
+func (p *Plan) Render() string {
+	items := p.Items()
+	done := 0
+	for _, item := range items {
+		if item.Status == StatusDone {
+			done++
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s(%d/%d done)", Header, done, len(items))
+	for _, item := range items {
+		marker := "[ ]"
+		switch item.Status {
+		case StatusInProgress:
+			marker = "[~]"
+		case StatusDone:
+			marker = "[x]"
+		}
+		fmt.Fprintf(&b, "\n%s %d. %s", marker, item.ID, item.Text)
+	}
+
+	return b.String()
+}
+
+// find returns the item with id; the caller holds p.mu.
+func (p *Plan) find(id int) (*Item, error) {
+	for index := range p.items {
+		if p.items[index].ID == id {
+			return &p.items[index], nil
+		}
+	}
+
+	return nil, workspace.NewError(workspace.ErrorInvalidArgument, fmt.Sprintf("no plan item with id %d", id))
+}
+
+// cleanText folds whitespace so each item stays on one line and enforces the length limit.
+func cleanText(text string) (string, error) {
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return "", workspace.NewError(workspace.ErrorInvalidArgument, "item text must not be empty")
+	}
+	if chars := len([]rune(text)); chars > MaxItemChars {
+		return "", workspace.NewError(workspace.ErrorInvalidArgument, fmt.Sprintf("item is %d characters; the limit is %d", chars, MaxItemChars))
+	}
+
+	return text, nil
+}
@@ -0,0 +1,68 @@
+package plan
+
+import (
+	"strings"
+	"testing"
+
+	"miniclaw/pkg/workspace"
+)
+
+func TestPlanTracksItemProgress(t *testing.T) {
+	t.Parallel()
+
+	var p Plan
+	ids, err := p.Add([]string{"Read the config\nloader", "Add the field", "Run the tests"})
+	if err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[2] != 3 {
+		t.Fatalf("ids = %v, want [1 2 3]", ids)
+	}
+	if err := p.Complete(1); err != nil {
+		t.Fatalf("Complete error: %v", err)
+	}
+	if err := p.Update(2, "", " In_Progress "); err != nil {
+		t.Fatalf("Update error: %v", err)
+	}
+	if err := p.Update(3, "Run go test ./...", ""); err != nil {
+		t.Fatalf("Update error: %v", err)
+	}
+
+	want := "Plan (1/3 done)\n[x] 1. Read the config loader\n[~] 2. Add the field\n[ ] 3. Run go test ./..."
+	if got := p.Render(); got != want {
+		t.Fatalf("Render =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPlanRejectsInvalidChanges(t *testing.T) {
+	t.Parallel()
+
+	var p Plan
+	if _, err := p.Add([]string{"one"}); err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+
+	for name, err := range map[string]error{
+		"empty add":      func() error { _, err := p.Add(nil); return err }(),
+		"blank item":     func() error { _, err := p.Add([]string{"ok", "  "}); return err }(),
+		"long item":      func() error { _, err := p.Add([]string{strings.Repeat("x", MaxItemChars+1)}); return err }(),
+		"unknown id":     p.Complete(7),
+		"bad status":     p.Update(1, "", "blocked"),
+		"nothing to set": p.Update(1, " ", ""),
+	} {
+		if workspace.CategoryFromError(err) != workspace.ErrorInvalidArgument {
+			t.Fatalf("%s: error = %v, want invalid_argument", name, err)
+		}
+	}
+	if items := p.Items(); len(items) != 1 || items[0].Status != StatusPending {
+		t.Fatalf("items = %+v, want the original pending item", items)
+	}
+
+	many := make([]string, MaxItems)
+	for i := range many {
+		many[i] = "step"
+	}
+	if _, err := p.Add(many); err == nil {
+		t.Fatal("expected error past MaxItems")
+	}
+}
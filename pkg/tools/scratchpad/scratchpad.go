@@ -0,0 +1,139 @@
+// Package scratchpad keeps named text snippets in memory for one session so
+// the agent can stash intermediate results without writing files into the workspace.
+package scratchpad
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"miniclaw/pkg/workspace"
+)
+
+const (
+	// MaxEntries bounds how many snippets one scratchpad holds.
+	MaxEntries = 50
+	// MaxValueBytes bounds one snippet.
+	MaxValueBytes = 64 * 1024
+	// MaxTotalBytes bounds all snippets of one scratchpad together.
+	MaxTotalBytes = 1024 * 1024
+	// MaxNameChars bounds a snippet name.
+	MaxNameChars = 64
+)
+
+// Tool names the scratchpad tools are registered under.
+const (
+	ToolSet = "scratch_set"
+	ToolGet = "scratch_get"
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Entry describes one stored snippet.
+type Entry struct {
+	Name  string
+	Bytes int
+}
+
+// Pad holds named snippets. The zero value is an empty pad ready to use.
+type Pad struct {
+	mu      sync.Mutex
+	entries map[string]string
+	total   int
+}
+
+// Set stores content under name, replacing any earlier value. Empty content
+// removes the snippet. It reports whether a snippet was replaced or removed.
+func (p *Pad) Set(name string, content string) (bool, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return false, err
+	}
+	if len(content) > MaxValueBytes {
+		return false, workspace.NewError(workspace.ErrorInvalidArgument, fmt.Sprintf("snippet is %d bytes; the limit is %d", len(content), MaxValueBytes))
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	previous, existed := p.entries[name]
+	if content == "" {
+		if existed {
+			delete(p.entries, name)
+			p.total -= len(previous)
+		}
+		return existed, nil
+	}
+	if !existed && len(p.entries) >= MaxEntries {
+		return false, workspace.NewError(workspace.ErrorInvalidArgument, fmt.Sprintf("scratchpad holds %d snippets; the limit is %d (clear one by setting it to empty content)", len(p.entries), MaxEntries))
+	}
+	if total := p.total - len(previous) + len(content); total > MaxTotalBytes {
+		return false, workspace.NewError(workspace.ErrorInvalidArgument, fmt.Sprintf("scratchpad would hold %d bytes; the limit is %d", total, MaxTotalBytes))
+	}
+
+	if p.entries == nil {
+		p.entries = make(map[string]string)
+	}
+	p.entries[name] = content
+	p.total += len(content) - len(previous)
+	return existed, nil
+}
+
+// Get returns the snippet stored under name.
+func (p *Pad) Get(name string) (string, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	content, ok := p.entries[name]
+	p.mu.Unlock()
+	if !ok {
+		return "", workspace.NewError(workspace.ErrorInvalidArgument, fmt.Sprintf("no snippet named %q; %s", name, p.Render()))
+	}
+
+	return content, nil
+}
+
+// Entries lists the stored snippets sorted by name.
+func (p *Pad) Entries() []Entry {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entries := make([]Entry, 0, len(p.entries))
+	for name, content := range p.entries {
+		entries = append(entries, Entry{Name: name, Bytes: len(content)})
+	}
+	slices.SortFunc(entries, func(a, b Entry) int { return strings.Compare(a.Name, b.Name) })
+	return entries
+}
+
+// Render lists the snippet names and sizes on one line, for example
+// "scratchpad: notes (120 bytes), todo (40 bytes)".
+func (p *Pad) Render() string {
+	entries := p.Entries()
+	if len(entries) == 0 {
+		return "scratchpad is empty"
+	}
+
+	parts := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		parts = append(parts, fmt.Sprintf("%s (%d bytes)", entry.Name, entry.Bytes))
+	}
+	return "scratchpad: " + strings.Join(parts, ", ")
+}
+
+func cleanName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", workspace.NewError(workspace.ErrorInvalidArgument, "name must not be empty")
+	}
+	if len(name) > MaxNameChars || !namePattern.MatchString(name) {
+		return "", workspace.NewError(workspace.ErrorInvalidArgument, fmt.Sprintf("name %q must be up to %d letters, digits, '.', '_', or '-'", name, MaxNameChars))
+	}
+
+	return name, nil
+}
@@ -0,0 +1,65 @@
+package scratchpad
+
+import (
+	"strings"
+	"testing"
+
+	"miniclaw/pkg/workspace"
+)
+
+func TestPadStoresAndRemovesSnippets(t *testing.T) {
+	t.Parallel()
+
+	var p Pad
+	if replaced, err := p.Set("notes", "first draft"); err != nil || replaced {
+		t.Fatalf("Set = %v, %v; want false, nil", replaced, err)
+	}
+	if replaced, err := p.Set(" notes ", "second draft"); err != nil || !replaced {
+		t.Fatalf("Set = %v, %v; want true, nil", replaced, err)
+	}
+	if _, err := p.Set("todo.v2", "ship it"); err != nil {
+		t.Fatalf("Set error: %v", err)
+	}
+
+	got, err := p.Get("notes")
+	if err != nil || got != "second draft" {
+		t.Fatalf("Get = %q, %v; want second draft", got, err)
+	}
+	if want := "scratchpad: notes (12 bytes), todo.v2 (7 bytes)"; p.Render() != want {
+		t.Fatalf("Render = %q, want %q", p.Render(), want)
+	}
+
+	if removed, err := p.Set("notes", ""); err != nil || !removed {
+		t.Fatalf("Set empty = %v, %v; want true, nil", removed, err)
+	}
+	_, err = p.Get("notes")
+	if workspace.CategoryFromError(err) != workspace.ErrorInvalidArgument || !strings.Contains(err.Error(), "todo.v2") {
+		t.Fatalf("Get removed error = %v, want invalid argument listing todo.v2", err)
+	}
+}
+
+func TestPadEnforcesLimits(t *testing.T) {
+	t.Parallel()
+
+	var p Pad
+	for _, name := range []string{"", "../etc", "has space", strings.Repeat("a", MaxNameChars+1)} {
+		if _, err := p.Set(name, "x"); workspace.CategoryFromError(err) != workspace.ErrorInvalidArgument {
+			t.Fatalf("Set(%q) error = %v, want invalid argument", name, err)
+		}
+	}
+	if _, err := p.Set("big", strings.Repeat("x", MaxValueBytes+1)); err == nil {
+		t.Fatal("Set oversized snippet succeeded")
+	}
+
+	for i := range MaxTotalBytes / MaxValueBytes {
+		if _, err := p.Set("chunk"+strings.Repeat("x", i), strings.Repeat("x", MaxValueBytes)); err != nil {
+			t.Fatalf("Set chunk %d error: %v", i, err)
+		}
+	}
+	if _, err := p.Set("overflow", "x"); err == nil || !strings.Contains(err.Error(), "limit") {
+		t.Fatalf("Set past total = %v, want limit error", err)
+	}
+	if _, err := p.Set("chunk", strings.Repeat("y", MaxValueBytes)); err != nil {
+		t.Fatalf("Replacing within the total failed: %v", err)
+	}
+}
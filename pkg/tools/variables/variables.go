@@ -0,0 +1,184 @@
+// Package variables keeps named values for one session that prompts and the
+// system prompt reference as {{name}}, so a workflow can be reused with
+// different parameters.
+package variables
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"miniclaw/pkg/workspace"
+)
+
+const (
+	// MaxEntries bounds how many variables one session holds.
+	MaxEntries = 100
+	// MaxValueBytes bounds one value.
+	MaxValueBytes = 4 * 1024
+	// MaxNameChars bounds a variable name.
+	MaxNameChars = 64
+	// maxRenderedValueChars cuts long values in Render.
+	maxRenderedValueChars = 40
+)
+
+// Tool names the variable tools are registered under.
+const (
+	ToolSet = "var_set"
+	ToolGet = "var_get"
+)
+
+var (
+	namePattern      = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+	referencePattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9][A-Za-z0-9._-]*)\s*\}\}`)
+)
+
+// Entry is one variable.
+type Entry struct {
+	Name  string
+	Value string
+}
+
+// Store holds the variables of one session. The zero value is an empty store
+// ready to use.
+type Store struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+// NewStore returns a store holding a copy of defaults.
+func NewStore(defaults map[string]string) *Store {
+	return &Store{values: maps.Clone(defaults)}
+}
+
+// Validate checks names and values the way Set does, for configured defaults.
+func Validate(values map[string]string) error {
+	if len(values) > MaxEntries {
+		return fmt.Errorf("%d variables; the limit is %d", len(values), MaxEntries)
+	}
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		if _, err := cleanName(name); err != nil {
+			return err
+		}
+		if len(values[name]) > MaxValueBytes {
+			return fmt.Errorf("variable %s is %d bytes; the limit is %d", name, len(values[name]), MaxValueBytes)
+		}
+	}
+
+	return nil
+}
+
+// Set stores value under name, replacing any earlier value. An empty value
+// removes the variable. It reports whether a variable was replaced or removed.
+func (s *Store) Set(name string, value string) (bool, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return false, err
+	}
+	if len(value) > MaxValueBytes {
+		return false, workspace.NewError(workspace.ErrorInvalidArgument, fmt.Sprintf("value is %d bytes; the limit is %d", len(value), MaxValueBytes))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, existed := s.values[name]
+	if value == "" {
+		delete(s.values, name)
+		return existed, nil
+	}
+	if !existed && len(s.values) >= MaxEntries {
+		return false, workspace.NewError(workspace.ErrorInvalidArgument, fmt.Sprintf("session holds %d variables; the limit is %d (remove one by setting it to an empty value)", len(s.values), MaxEntries))
+	}
+	if s.values == nil {
+		s.values = make(map[string]string)
+	}
+	s.values[name] = value
+
+	return existed, nil
+}
+
+// Get returns the value of name.
+func (s *Store) Get(name string) (string, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	value, ok := s.values[name]
+	s.mu.Unlock()
+	if !ok {
+		return "", workspace.NewError(workspace.ErrorInvalidArgument, fmt.Sprintf("no variable named %q; %s", name, s.Render()))
+	}
+
+	return value, nil
+}
+
+// Entries lists the variables sorted by name.
+func (s *Store) Entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]Entry, 0, len(s.values))
+	for _, name := range slices.Sorted(maps.Keys(s.values)) {
+		entries = append(entries, Entry{Name: name, Value: s.values[name]})
+	}
+
+	return entries
+}
+
+// Render lists the variables on one line with long values cut, for example
+// "variables: client=Acme, tone=formal".
+func (s *Store) Render() string {
+	entries := s.Entries()
+	if len(entries) == 0 {
+		return "no variables are set"
+	}
+
+	parts := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		value := strings.ReplaceAll(entry.Value, "\n", " ")
+		if runes := []rune(value); len(runes) > maxRenderedValueChars {
+			value = string(runes[:maxRenderedValueChars]) + "..."
+		}
+		parts = append(parts, entry.Name+"="+value)
+	}
+
+	return "variables: " + strings.Join(parts, ", ")
+}
+
+// Expand replaces each {{name}} in text (spaces inside the braces are
+// allowed) with the variable's value. References to unset variables are left
+// as written, so braces in code or templates survive. A nil store expands nothing.
+func (s *Store) Expand(text string) string {
+	if s == nil || !strings.Contains(text, "{{") {
+		return text
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return referencePattern.ReplaceAllStringFunc(text, func(reference string) string {
+		name := referencePattern.FindStringSubmatch(reference)[1]
+		if value, ok := s.values[name]; ok {
+			return value
+		}
+		return reference
+	})
+}
+
+func cleanName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", workspace.NewError(workspace.ErrorInvalidArgument, "name must not be empty")
+	}
+	if len(name) > MaxNameChars || !namePattern.MatchString(name) {
+		return "", workspace.NewError(workspace.ErrorInvalidArgument, fmt.Sprintf("name %q must be up to %d letters, digits, '.', '_', or '-'", name, MaxNameChars))
+	}
+
+	return name, nil
+}
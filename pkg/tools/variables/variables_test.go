@@ -0,0 +1,62 @@
+package variables
+
+import (
+	"strings"
+	"testing"
+
+	"miniclaw/pkg/workspace"
+)
+
+func TestStoreSetsAndExpands(t *testing.T) {
+	t.Parallel()
+
+	defaults := map[string]string{"client": "Acme"}
+	s := NewStore(defaults)
+	if replaced, err := s.Set("tone", "formal"); err != nil || replaced {
+		t.Fatalf("Set = %v, %v; want false, nil", replaced, err)
+	}
+	if replaced, err := s.Set(" client ", "Globex"); err != nil || !replaced {
+		t.Fatalf("Set = %v, %v; want true, nil", replaced, err)
+	}
+	if defaults["client"] != "Acme" {
+		t.Fatal("Set changed the defaults map")
+	}
+
+	got := s.Expand("Write to {{client}} in a {{ tone }} tone; keep {{missing}} and {x}.")
+	if want := "Write to Globex in a formal tone; keep {{missing}} and {x}."; got != want {
+		t.Fatalf("Expand = %q, want %q", got, want)
+	}
+	if want := "variables: client=Globex, tone=formal"; s.Render() != want {
+		t.Fatalf("Render = %q, want %q", s.Render(), want)
+	}
+
+	if removed, err := s.Set("tone", ""); err != nil || !removed {
+		t.Fatalf("Set empty = %v, %v; want true, nil", removed, err)
+	}
+	_, err := s.Get("tone")
+	if workspace.CategoryFromError(err) != workspace.ErrorInvalidArgument || !strings.Contains(err.Error(), "client=Globex") {
+		t.Fatalf("Get removed error = %v, want invalid argument listing client", err)
+	}
+
+	var nilStore *Store
+	if nilStore.Expand("{{client}}") != "{{client}}" {
+		t.Fatal("nil store expanded a reference")
+	}
+}
+
+func TestStoreEnforcesLimits(t *testing.T) {
+	t.Parallel()
+
+	var s Store
+	for _, name := range []string{"", "has space", "{{x}}", strings.Repeat("a", MaxNameChars+1)} {
+		if _, err := s.Set(name, "x"); workspace.CategoryFromError(err) != workspace.ErrorInvalidArgument {
+			t.Fatalf("Set(%q) error = %v, want invalid argument", name, err)
+		}
+	}
+	if _, err := s.Set("big", strings.Repeat("x", MaxValueBytes+1)); err == nil {
+		t.Fatal("Set oversized value succeeded")
+	}
+	if err := Validate(map[string]string{"bad name": "x"}); err == nil {
+		t.Fatal("Validate accepted an invalid name")
+	}
+}
@@ -0,0 +1,399 @@
+// Package transcript copies stored sessions in and out of the session store
+// as a portable JSON document, and renders them as Markdown for reading.
+//
+// An export keeps every stored turn, including the provider payloads that
+// fantasy-agent replays, so importing it into a fresh session continues the
+// conversation exactly where it left off.
+package transcript
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"miniclaw/pkg/store"
+
+	core "charm.land/fantasy"
+)
+
+// Version is the export format version written by Export and accepted by Decode.
+const Version = 1
+
+const (
+	// UsageRole marks a stored turn whose payload is the Usage of the prompt
+	// before it; it is folded into that prompt's last message on export.
+	UsageRole = "usage"
+	// CompactionRole marks a stored turn whose payload, a JSON message list,
+	// replaces the history before it (see agents.defaults.compaction).
+	CompactionRole = "compaction"
+	// RollbackRole marks a stored turn whose payload, a Rollback, drops the
+	// last messages of the history before it (see /undo).
+	RollbackRole = "rollback"
+)
+
+// Rollback is the payload of a RollbackRole turn.
+type Rollback struct {
+	// Messages is how many messages at the end of the history are dropped.
+	Messages int `json:"messages"`
+}
+
+const (
+	FormatJSON     = "json"
+	FormatMarkdown = "md"
+)
+
+// maxMarkdownToolBytes bounds each tool input or result in Markdown; JSON keeps them whole.
+const maxMarkdownToolBytes = 2000
+
+// Transcript is one exported session.
+type Transcript struct {
+	Version   int    `json:"version"`
+	SessionID string `json:"session_id"`
+	Title     string `json:"title,omitempty"`
+	// Name is the session's display name, such as one generated from its first prompt.
+	Name       string    `json:"name,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	ExportedAt time.Time `json:"exported_at"`
+	// Usage totals the token usage recorded for the session's prompts.
+	Usage    Usage     `json:"usage"`
+	Messages []Message `json:"messages"`
+}
+
+// Message is one stored turn.
+type Message struct {
+	Role       string      `json:"role"`
+	Text       string      `json:"text,omitempty"`
+	At         time.Time   `json:"at"`
+	ToolEvents []ToolEvent `json:"tool_events,omitempty"`
+	// Usage is set on the last message of a prompt that recorded token usage.
+	Usage *Usage `json:"usage,omitempty"`
+	// Payload is the provider's encoded message, replayed after an import.
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// ToolEvent is one tool call or result within a message.
+type ToolEvent struct {
+	Kind    string `json:"kind"`
+	Tool    string `json:"tool,omitempty"`
+	Payload string `json:"payload,omitempty"`
+}
+
+// Usage is token usage as recorded in the store.
+type Usage struct {
+	InputTokens         int64 `json:"input_tokens"`
+	OutputTokens        int64 `json:"output_tokens"`
+	TotalTokens         int64 `json:"total_tokens"`
+	ReasoningTokens     int64 `json:"reasoning_tokens,omitempty"`
+	CacheCreationTokens int64 `json:"cache_creation_tokens,omitempty"`
+	CacheReadTokens     int64 `json:"cache_read_tokens,omitempty"`
+}
+
+func (u *Usage) add(other Usage) {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.TotalTokens += other.TotalTokens
+	u.ReasoningTokens += other.ReasoningTokens
+	u.CacheCreationTokens += other.CacheCreationTokens
+	u.CacheReadTokens += other.CacheReadTokens
+}
+
+// ParseFormat normalizes an export format name; empty selects Markdown.
+func ParseFormat(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", FormatMarkdown, "markdown":
+		return FormatMarkdown, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("unsupported export format %q (want json or md)", value)
+	}
+}
+
+// Export reads session id from sessions.
+func Export(ctx context.Context, sessions store.SessionStore, id string) (Transcript, error) {
+	session, err := sessions.Load(ctx, id)
+	if err != nil {
+		return Transcript{}, err
+	}
+
+	transcript := Transcript{
+		Version:    Version,
+		SessionID:  session.ID,
+		Title:      session.Title,
+		Name:       session.Name,
+		CreatedAt:  session.CreatedAt,
+		UpdatedAt:  session.UpdatedAt,
+		ExportedAt: time.Now().UTC(),
+		Messages:   make([]Message, 0, len(session.Turns)),
+	}
+	toolByCallID := make(map[string]string)
+	for _, turn := range session.Turns {
+		if turn.Role == UsageRole {
+			var usage Usage
+			if err := json.Unmarshal(turn.Payload, &usage); err != nil {
+				return Transcript{}, fmt.Errorf("decode usage turn: %w", err)
+			}
+			transcript.Usage.add(usage)
+			if count := len(transcript.Messages); count > 0 {
+				last := &transcript.Messages[count-1]
+				if last.Usage == nil {
+					last.Usage = &Usage{}
+				}
+				last.Usage.add(usage)
+			}
+			continue
+		}
+
+		message := Message{Role: turn.Role, Text: turn.Content, At: turn.At, Payload: turn.Payload}
+		if len(turn.Payload) > 0 && turn.Role != CompactionRole && turn.Role != RollbackRole {
+			message.ToolEvents = toolEvents(turn.Payload, toolByCallID)
+		}
+		transcript.Messages = append(transcript.Messages, message)
+	}
+
+	return transcript, nil
+}
+
+// toolEvents lists the tool calls and results in an encoded provider message.
+// Results are named through toolByCallID, which collects calls as they appear.
+func toolEvents(payload json.RawMessage, toolByCallID map[string]string) []ToolEvent {
+	var message core.Message
+	if err := json.Unmarshal(payload, &message); err != nil {
+		return nil
+	}
+
+	var events []ToolEvent
+	for _, part := range message.Content {
+		switch typed := part.(type) {
+		case core.ToolCallPart:
+			toolByCallID[typed.ToolCallID] = typed.ToolName
+			events = append(events, ToolEvent{Kind: "call", Tool: typed.ToolName, Payload: strings.TrimSpace(typed.Input)})
+		case core.ToolResultPart:
+			output := ""
+			switch result := typed.Output.(type) {
+			case core.ToolResultOutputContentText:
+				output = result.Text
+			case core.ToolResultOutputContentError:
+				if result.Error != nil {
+					output = result.Error.Error()
+				}
+			}
+			events = append(events, ToolEvent{Kind: "result", Tool: toolByCallID[typed.ToolCallID], Payload: strings.TrimSpace(output)})
+		}
+	}
+
+	return events
+}
+
+// Import stores transcript as a new session titled title and returns its ID.
+// The session keeps the transcript's Name.
+//
+// Messages without a payload (for example from a hand-written file) get one
+// built from their role and text, so fantasy-agent can replay them.
+func Import(ctx context.Context, sessions store.SessionStore, transcript Transcript, title string) (string, error) {
+	if transcript.Version != Version {
+		return "", fmt.Errorf("unsupported transcript version %d (want %d)", transcript.Version, Version)
+	}
+	if len(transcript.Messages) == 0 {
+		return "", errors.New("transcript has no messages")
+	}
+
+	turns := make([]store.Turn, 0, len(transcript.Messages))
+	for index, message := range transcript.Messages {
+		turn, err := importTurn(message)
+		if err != nil {
+			return "", fmt.Errorf("message %d: %w", index, err)
+		}
+		turns = append(turns, turn)
+		if message.Usage != nil {
+			payload, err := json.Marshal(message.Usage)
+			if err != nil {
+				return "", fmt.Errorf("message %d: encode usage: %w", index, err)
+			}
+			turns = append(turns, store.Turn{Role: UsageRole, At: message.At, Payload: payload})
+		}
+	}
+
+	id := ""
+	for {
+		id = "import-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+		_, err := sessions.Create(ctx, id, title)
+		if errors.Is(err, store.ErrExists) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("create session: %w", err)
+		}
+		break
+	}
+	if err := sessions.AppendTurn(ctx, id, turns...); err != nil {
+		return "", fmt.Errorf("append turns: %w", err)
+	}
+	if name := strings.TrimSpace(transcript.Name); name != "" {
+		if err := sessions.Rename(ctx, id, name); err != nil {
+			return "", fmt.Errorf("rename session: %w", err)
+		}
+	}
+
+	return id, nil
+}
+
+// importTurn validates message and converts it back into a stored turn.
+func importTurn(message Message) (store.Turn, error) {
+	turn := store.Turn{Role: strings.TrimSpace(message.Role), Content: message.Text, At: message.At}
+	if len(message.Payload) > 0 {
+		// Encode indents payloads; store them compact as the provider wrote them.
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, message.Payload); err != nil {
+			return store.Turn{}, fmt.Errorf("decode payload: %w", err)
+		}
+		turn.Payload = compact.Bytes()
+	}
+	switch turn.Role {
+	case "":
+		return store.Turn{}, errors.New("role is required")
+	case UsageRole:
+		return store.Turn{}, errors.New("usage belongs in the usage field of the message it follows")
+	case CompactionRole:
+		var compacted []core.Message
+		if err := json.Unmarshal(turn.Payload, &compacted); err != nil {
+			return store.Turn{}, fmt.Errorf("decode compaction payload: %w", err)
+		}
+		return turn, nil
+	case RollbackRole:
+		var rollback Rollback
+		if err := json.Unmarshal(turn.Payload, &rollback); err != nil || rollback.Messages < 1 {
+			return store.Turn{}, errors.New("rollback payload must drop at least one message")
+		}
+		return turn, nil
+	}
+
+	if len(turn.Payload) > 0 {
+		var decoded core.Message
+		if err := json.Unmarshal(turn.Payload, &decoded); err != nil {
+			return store.Turn{}, fmt.Errorf("decode payload: %w", err)
+		}
+		return turn, nil
+	}
+
+	var built core.Message
+	switch core.MessageRole(turn.Role) {
+	case core.MessageRoleSystem:
+		built = core.NewSystemMessage(message.Text)
+	case core.MessageRoleUser:
+		built = core.NewUserMessage(message.Text)
+	case core.MessageRoleAssistant:
+		built = core.Message{Role: core.MessageRoleAssistant, Content: []core.MessagePart{core.TextPart{Text: message.Text}}}
+	default:
+		return store.Turn{}, fmt.Errorf("role %q needs a payload", turn.Role)
+	}
+	payload, err := json.Marshal(built)
+	if err != nil {
+		return store.Turn{}, fmt.Errorf("encode payload: %w", err)
+	}
+	turn.Payload = payload
+
+	return turn, nil
+}
+
+// Decode reads a JSON export written by Encode.
+func Decode(r io.Reader) (Transcript, error) {
+	var transcript Transcript
+	if err := json.NewDecoder(r).Decode(&transcript); err != nil {
+		return Transcript{}, fmt.Errorf("decode transcript: %w", err)
+	}
+	if transcript.Version != Version {
+		return Transcript{}, fmt.Errorf("unsupported transcript version %d (want %d)", transcript.Version, Version)
+	}
+
+	return transcript, nil
+}
+
+// Encode writes transcript as indented JSON or as Markdown.
+func Encode(w io.Writer, transcript Transcript, format string) error {
+	format, err := ParseFormat(format)
+	if err != nil {
+		return err
+	}
+	if format == FormatMarkdown {
+		_, err := io.WriteString(w, transcript.Markdown())
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(transcript)
+}
+
+// Markdown renders transcript for reading. Tool inputs and results are cut
+// to maxMarkdownToolBytes; it cannot be imported back.
+func (t Transcript) Markdown() string {
+	var b strings.Builder
+	title := t.Name
+	if title == "" {
+		title = t.Title
+	}
+	if title == "" {
+		title = t.SessionID
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	fmt.Fprintf(&b, "- Session: `%s`\n", t.SessionID)
+	fmt.Fprintf(&b, "- Created: %s\n", formatTime(t.CreatedAt))
+	fmt.Fprintf(&b, "- Exported: %s\n", formatTime(t.ExportedAt))
+	fmt.Fprintf(&b, "- Tokens in/out/total: %d/%d/%d\n", t.Usage.InputTokens, t.Usage.OutputTokens, t.Usage.TotalTokens)
+
+	for _, message := range t.Messages {
+		heading := message.Role
+		switch heading {
+		case "":
+			heading = "message"
+		case CompactionRole:
+			heading = "summary of earlier messages"
+		case RollbackRole:
+			heading = "undo"
+		}
+		fmt.Fprintf(&b, "\n## %s (%s)\n\n", strings.ToUpper(heading[:1])+heading[1:], formatTime(message.At))
+		if text := strings.TrimSpace(message.Text); text != "" {
+			b.WriteString(text + "\n")
+		}
+		for _, event := range message.ToolEvents {
+			tool := event.Tool
+			if tool == "" {
+				tool = "tool"
+			}
+			verb := "called with"
+			if event.Kind == "result" {
+				verb = "returned"
+			}
+			fmt.Fprintf(&b, "\n`%s` %s:\n\n```\n%s\n```\n", tool, verb, clip(event.Payload))
+		}
+		if message.Usage != nil {
+			fmt.Fprintf(&b, "\n_tokens in/out/total: %d/%d/%d_\n", message.Usage.InputTokens, message.Usage.OutputTokens, message.Usage.TotalTokens)
+		}
+	}
+
+	return b.String()
+}
+
+func formatTime(at time.Time) string {
+	if at.IsZero() {
+		return "unknown"
+	}
+
+	return at.UTC().Format("2006-01-02 15:04:05") + " UTC"
+}
+
+func clip(text string) string {
+	if len(text) <= maxMarkdownToolBytes {
+		return text
+	}
+
+	return strings.ToValidUTF8(text[:maxMarkdownToolBytes], "") + " [truncated]"
+}
@@ -0,0 +1,135 @@
+package transcript
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"miniclaw/pkg/store"
+
+	core "charm.land/fantasy"
+)
+
+func providerTurn(t *testing.T, message core.Message, content string, at time.Time) store.Turn {
+	t.Helper()
+
+	payload, err := json.Marshal(message)
+	if err != nil {
+		t.Fatalf("marshal message: %v", err)
+	}
+	return store.Turn{Role: string(message.Role), Content: content, At: at, Payload: payload}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	at := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	sessions := store.NewMemoryStore()
+	if _, err := sessions.Create(ctx, "s1", "miniclaw"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	usage, _ := json.Marshal(Usage{InputTokens: 10, OutputTokens: 4, TotalTokens: 14})
+	turns := []store.Turn{
+		providerTurn(t, core.NewUserMessage("read notes.txt"), "read notes.txt", at),
+		providerTurn(t, core.Message{Role: core.MessageRoleAssistant, Content: []core.MessagePart{
+			core.ToolCallPart{ToolCallID: "c1", ToolName: "read_file", Input: `{"path":"notes.txt"}`},
+		}}, "", at),
+		providerTurn(t, core.Message{Role: core.MessageRoleTool, Content: []core.MessagePart{
+			core.ToolResultPart{ToolCallID: "c1", Output: core.ToolResultOutputContentText{Text: "buy milk"}},
+		}}, "", at),
+		providerTurn(t, core.Message{Role: core.MessageRoleAssistant, Content: []core.MessagePart{core.TextPart{Text: "It says buy milk."}}}, "It says buy milk.", at),
+		{Role: UsageRole, At: at, Payload: usage},
+	}
+	if err := sessions.AppendTurn(ctx, "s1", turns...); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if err := sessions.Rename(ctx, "s1", "Shopping notes"); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+
+	exported, err := Export(ctx, sessions, "s1")
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if len(exported.Messages) != 4 || exported.Usage.TotalTokens != 14 {
+		t.Fatalf("exported %d messages with %d tokens, want 4 and 14", len(exported.Messages), exported.Usage.TotalTokens)
+	}
+	if last := exported.Messages[3]; last.Usage == nil || last.Usage.TotalTokens != 14 {
+		t.Fatalf("last message usage = %+v, want 14 total tokens", last.Usage)
+	}
+	call, result := exported.Messages[1].ToolEvents, exported.Messages[2].ToolEvents
+	if len(call) != 1 || call[0].Kind != "call" || call[0].Tool != "read_file" || len(result) != 1 || result[0].Tool != "read_file" || result[0].Payload != "buy milk" {
+		t.Fatalf("tool events = %+v / %+v, want read_file call and result", call, result)
+	}
+
+	var encoded bytes.Buffer
+	if err := Encode(&encoded, exported, FormatJSON); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	decoded, err := Decode(&encoded)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	id, err := Import(ctx, sessions, decoded, "miniclaw")
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	imported, err := sessions.Load(ctx, id)
+	if err != nil {
+		t.Fatalf("load import: %v", err)
+	}
+	if imported.Title != "miniclaw" || imported.Name != "Shopping notes" || len(imported.Turns) != len(turns) {
+		t.Fatalf("imported %q (%q) with %d turns, want miniclaw (Shopping notes) with %d", imported.Title, imported.Name, len(imported.Turns), len(turns))
+	}
+	for index, turn := range imported.Turns {
+		if turn.Role != turns[index].Role || !bytes.Equal(turn.Payload, turns[index].Payload) {
+			t.Fatalf("turn %d = %s %s, want %s %s", index, turn.Role, turn.Payload, turns[index].Role, turns[index].Payload)
+		}
+	}
+
+	markdown := exported.Markdown()
+	for _, want := range []string{"# Shopping notes", "`read_file` called with", "`read_file` returned", "buy milk", "tokens in/out/total: 10/4/14"} {
+		if !strings.Contains(markdown, want) {
+			t.Fatalf("markdown missing %q:\n%s", want, markdown)
+		}
+	}
+}
+
+func TestImportBuildsPayloadsAndRejectsInvalid(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	sessions := store.NewMemoryStore()
+
+	id, err := Import(ctx, sessions, Transcript{Version: Version, Messages: []Message{
+		{Role: "user", Text: "hello"},
+		{Role: "assistant", Text: "hi"},
+	}}, "miniclaw")
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	imported, err := sessions.Load(ctx, id)
+	if err != nil {
+		t.Fatalf("load import: %v", err)
+	}
+	var reply core.Message
+	if err := json.Unmarshal(imported.Turns[1].Payload, &reply); err != nil || reply.Role != core.MessageRoleAssistant {
+		t.Fatalf("built payload = %s (%v), want an assistant message", imported.Turns[1].Payload, err)
+	}
+
+	for name, bad := range map[string]Transcript{
+		"version": {Version: 2, Messages: []Message{{Role: "user", Text: "hello"}}},
+		"empty":   {Version: Version},
+		"tool":    {Version: Version, Messages: []Message{{Role: "tool", Text: "orphan"}}},
+		"payload": {Version: Version, Messages: []Message{{Role: "user", Payload: json.RawMessage(`"nope"`)}}},
+		"usage":   {Version: Version, Messages: []Message{{Role: UsageRole}}},
+	} {
+		if _, err := Import(ctx, sessions, bad, "miniclaw"); err == nil {
+			t.Fatalf("%s: Import succeeded, want an error", name)
+		}
+	}
+}
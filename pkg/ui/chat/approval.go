@@ -0,0 +1,123 @@
+package chat
+
+import (
+	"context"
+	"strings"
+
+	providertypes "miniclaw/pkg/provider/types"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxApprovalInputPreview bounds the tool arguments shown on an approval card.
+const maxApprovalInputPreview = 600
+
+// approvalRequest is one tool call waiting for a y/n answer in the UI.
+type approvalRequest struct {
+	request providertypes.ToolApprovalRequest
+	reply   chan bool
+	// messageIndex is the transcript card showing this request.
+	messageIndex int
+}
+
+type approvalRequestMsg struct {
+	approval *approvalRequest
+	stream   <-chan *approvalRequest
+}
+
+type approvalStreamClosedMsg struct{}
+
+// newApprover forwards approval requests from the prompt goroutine to the UI and waits for the answer.
+func newApprover(stream chan<- *approvalRequest) providertypes.ToolApprover {
+	return func(ctx context.Context, request providertypes.ToolApprovalRequest) (bool, error) {
+		approval := &approvalRequest{request: request, reply: make(chan bool, 1)}
+		select {
+		case stream <- approval:
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+
+		select {
+		case approved := <-approval.reply:
+			return approved, nil
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+}
+
+func waitApprovalCmd(stream <-chan *approvalRequest) tea.Cmd {
+	return func() tea.Msg {
+		approval, ok := <-stream
+		if !ok {
+			return approvalStreamClosedMsg{}
+		}
+
+		return approvalRequestMsg{approval: approval, stream: stream}
+	}
+}
+
+// queueApproval shows a new approval card; requests are answered in arrival order.
+func (m *model) queueApproval(approval *approvalRequest) {
+	m.messages = append(m.messages, chatMessage{role: "approval", content: m.approvalCardBody(approval.request, "")})
+	approval.messageIndex = len(m.messages) - 1
+	m.pendingApprovals = append(m.pendingApprovals, approval)
+	m.followLog = true
+}
+
+// handleApprovalKey answers the oldest pending approval with y or n (Esc also denies).
+//
+// Other keys are swallowed so typing cannot accidentally approve a call.
+func (m *model) handleApprovalKey(msg tea.KeyMsg) {
+	var approved bool
+	switch strings.ToLower(msg.String()) {
+	case "y":
+		approved = true
+	case "n", "esc":
+		approved = false
+	default:
+		return
+	}
+
+	approval := m.pendingApprovals[0]
+	m.pendingApprovals = m.pendingApprovals[1:]
+	approval.reply <- approved
+
+	outcome := m.sym.denied + "denied"
+	if approved {
+		outcome = m.sym.done + "approved"
+	}
+	if approval.messageIndex >= 0 && approval.messageIndex < len(m.messages) {
+		m.messages[approval.messageIndex].content = m.approvalCardBody(approval.request, outcome)
+	}
+	m.refreshViewport(false)
+}
+
+// clearApprovals drops requests whose prompt already finished.
+func (m *model) clearApprovals() {
+	for _, approval := range m.pendingApprovals {
+		if approval.messageIndex >= 0 && approval.messageIndex < len(m.messages) {
+			m.messages[approval.messageIndex].content = m.approvalCardBody(approval.request, m.sym.expired+"expired")
+		}
+	}
+	m.pendingApprovals = nil
+}
+
+func (m *model) approvalCardBody(request providertypes.ToolApprovalRequest, outcome string) string {
+	input := strings.TrimSpace(request.Input)
+	if runes := []rune(input); len(runes) > maxApprovalInputPreview {
+		input = string(runes[:maxApprovalInputPreview]) + "..."
+	}
+
+	lines := []string{"Allow " + request.Tool + "?"}
+	if input != "" {
+		lines = append(lines, input)
+	}
+	if outcome == "" {
+		lines = append(lines, "[y] approve  "+m.sym.sep+"  [n] deny")
+	} else {
+		lines = append(lines, outcome)
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,66 @@
+package chat
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	providertypes "miniclaw/pkg/provider/types"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestApprovalRequestCapturesKeysUntilAnswered(t *testing.T) {
+	t.Parallel()
+
+	m := newModel(context.Background(), nil, modeInteractive, "", RuntimeInfo{})
+	m.booting = false
+	m.isLoading = true
+
+	stream := make(chan *approvalRequest)
+	approval := &approvalRequest{
+		request: providertypes.ToolApprovalRequest{Tool: "run_command", Input: `{"command":"rm -rf build"}`},
+		reply:   make(chan bool, 1),
+	}
+	m.Update(approvalRequestMsg{approval: approval, stream: stream})
+
+	if !strings.Contains(m.View(), "allow run_command?") {
+		t.Fatalf("status line missing approval prompt:\n%s", m.View())
+	}
+	if !strings.Contains(m.viewport.View(), "rm -rf build") {
+		t.Fatalf("approval card missing tool input:\n%s", m.viewport.View())
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	if len(m.pendingApprovals) != 1 || m.input.Value() != "" {
+		t.Fatalf("unrelated key should be swallowed while approval is pending (pending=%d input=%q)", len(m.pendingApprovals), m.input.Value())
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	select {
+	case approved := <-approval.reply:
+		if approved {
+			t.Fatal("expected n to deny")
+		}
+	default:
+		t.Fatal("expected an answer after pressing n")
+	}
+	if len(m.pendingApprovals) != 0 || !strings.Contains(m.viewport.View(), "denied") {
+		t.Fatalf("expected denied card and no pending approvals:\n%s", m.viewport.View())
+	}
+}
+
+func TestNewApproverReturnsUIAnswer(t *testing.T) {
+	t.Parallel()
+
+	stream := make(chan *approvalRequest)
+	go func() {
+		approval := <-stream
+		approval.reply <- true
+	}()
+
+	approved, err := newApprover(stream)(context.Background(), providertypes.ToolApprovalRequest{Tool: "write_file"})
+	if err != nil || !approved {
+		t.Fatalf("approver = %v, %v; want true, nil", approved, err)
+	}
+}
@@ -0,0 +1,307 @@
+// Package chattest drives the interactive chat UI in a virtual terminal for tests.
+//
+// It wraps teatest with scripted keypresses and golden-frame assertions so
+// forks that customize pkg/ui/chat can test their changes the same way the
+// core package does:
+//
+//	h := chattest.New(t, chattest.Options{Prompt: chattest.Replies("hi there")})
+//	h.Submit("hello")
+//	h.WaitFor("hi there")
+//	h.RequireGolden(t)
+//	h.Quit()
+//
+// Golden files live in testdata/<test name>.golden and are rewritten with
+// `go test -update`.
+package chattest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	providertypes "miniclaw/pkg/provider/types"
+	"miniclaw/pkg/ui/chat"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/exp/golden"
+	"github.com/charmbracelet/x/exp/teatest"
+	"github.com/muesli/termenv"
+)
+
+const (
+	// DefaultWidth and DefaultHeight size the virtual terminal when Options leaves them unset.
+	DefaultWidth  = 100
+	DefaultHeight = 28
+	// DefaultTimeout bounds WaitFor and Quit when Options.Timeout is unset.
+	DefaultTimeout = 5 * time.Second
+
+	pollInterval = 10 * time.Millisecond
+)
+
+// asciiOnce pins lipgloss to plain text so frames do not depend on the host terminal.
+var asciiOnce sync.Once
+
+// Options configures a Harness.
+type Options struct {
+	// Prompt answers submitted prompts; nil fails the test if a prompt is sent.
+	Prompt  chat.PromptFunc
+	Runtime chat.RuntimeInfo
+	// Model replaces the default chat model, for forks that wrap or extend it.
+	Model tea.Model
+	// Boot plays the startup animation instead of starting with input enabled.
+	Boot bool
+	// ASCII renders the plain-ASCII fallback UI; the default is the unicode UI
+	// regardless of the host terminal, so frames stay stable across machines.
+	ASCII         bool
+	Width, Height int
+	Timeout       time.Duration
+}
+
+// Harness runs one chat program and records the frames it renders.
+type Harness struct {
+	tb      testing.TB
+	tm      *teatest.TestModel
+	frames  *frameRecorder
+	timeout time.Duration
+	// seen counts recorded frames already consumed by WaitUntil.
+	seen int
+}
+
+// New starts the chat UI in a virtual terminal and stops it when the test ends.
+func New(tb testing.TB, opts Options) *Harness {
+	tb.Helper()
+
+	asciiOnce.Do(func() {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	})
+
+	width, height := opts.Width, opts.Height
+	if width <= 0 {
+		width = DefaultWidth
+	}
+	if height <= 0 {
+		height = DefaultHeight
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	model := opts.Model
+	if model == nil {
+		promptFn := opts.Prompt
+		if promptFn == nil {
+			promptFn = func(context.Context, string) (providertypes.PromptResult, error) {
+				tb.Errorf("chattest: prompt submitted but Options.Prompt is nil")
+				return providertypes.PromptResult{}, fmt.Errorf("no prompt function configured")
+			}
+		}
+		model = chat.NewModel(context.Background(), promptFn, chat.ModelOptions{Runtime: opts.Runtime, SkipBoot: !opts.Boot, Terminal: &chat.TerminalCaps{ASCII: opts.ASCII}})
+	}
+
+	frames := &frameRecorder{inner: model}
+	h := &Harness{
+		tb:      tb,
+		tm:      teatest.NewTestModel(tb, frames, teatest.WithInitialTermSize(width, height)),
+		frames:  frames,
+		timeout: timeout,
+	}
+	tb.Cleanup(h.stop)
+
+	return h
+}
+
+// Type sends text as individual key presses, as if typed by the user.
+func (h *Harness) Type(text string) {
+	for _, r := range text {
+		h.tm.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+}
+
+// Press sends named keys such as "enter", "esc", "ctrl+t", "pgup", or "y".
+func (h *Harness) Press(keys ...string) {
+	h.tb.Helper()
+
+	for _, name := range keys {
+		key, err := ParseKey(name)
+		if err != nil {
+			h.tb.Fatalf("chattest: %v", err)
+		}
+		h.tm.Send(key)
+	}
+}
+
+// Submit types prompt and presses enter.
+func (h *Harness) Submit(prompt string) {
+	h.tb.Helper()
+
+	h.Type(prompt)
+	h.Press("enter")
+}
+
+// Send delivers an arbitrary message to the program, for example a tea.WindowSizeMsg.
+func (h *Harness) Send(msg tea.Msg) {
+	h.tm.Send(msg)
+}
+
+// WaitFor blocks until a frame rendered since the last wait contains text, failing the test on timeout.
+func (h *Harness) WaitFor(text string) string {
+	h.tb.Helper()
+
+	return h.WaitUntil(func(frame string) bool {
+		return strings.Contains(frame, text)
+	}, fmt.Sprintf("frame to contain %q", text))
+}
+
+// WaitUntil blocks until condition holds for a frame rendered since the last wait and returns that frame.
+//
+// Every distinct frame is checked, so short-lived states such as the boot
+// animation are not missed between polls.
+func (h *Harness) WaitUntil(condition func(frame string) bool, description string) string {
+	h.tb.Helper()
+
+	deadline := time.Now().Add(h.timeout)
+	for {
+		frames := h.frames.since(h.seen)
+		for index, frame := range frames {
+			if condition(frame) {
+				h.seen += index + 1
+				return frame
+			}
+		}
+		h.seen += len(frames)
+		if time.Now().After(deadline) {
+			h.tb.Fatalf("chattest: timed out after %s waiting for %s; last frame:\n%s", h.timeout, description, h.Frame())
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// Frame returns the most recently rendered view.
+func (h *Harness) Frame() string {
+	return h.frames.last()
+}
+
+// RequireGolden compares the current frame with testdata/<tb.Name()>.golden.
+//
+// Pass a subtest's t to keep several golden frames from one script apart.
+func (h *Harness) RequireGolden(tb testing.TB) {
+	tb.Helper()
+
+	golden.RequireEqual(tb, []byte(h.Frame()))
+}
+
+// Quit stops the program and returns the final frame.
+func (h *Harness) Quit() string {
+	h.tb.Helper()
+
+	h.stop()
+	return h.Frame()
+}
+
+func (h *Harness) stop() {
+	_ = h.tm.Quit()
+	h.tm.WaitFinished(h.tb, teatest.WithFinalTimeout(h.timeout))
+}
+
+// ParseKey converts a key name as printed by tea.KeyMsg.String into a key message.
+//
+// Single characters become rune keys; an "alt+" prefix sets Alt.
+func ParseKey(name string) (tea.KeyMsg, error) {
+	alt := false
+	if rest, ok := strings.CutPrefix(name, "alt+"); ok && rest != "" {
+		alt = true
+		name = rest
+	}
+
+	if keyType, ok := keyTypes()[name]; ok {
+		key := tea.KeyMsg{Type: keyType, Alt: alt}
+		if keyType == tea.KeySpace {
+			key.Runes = []rune{' '}
+		}
+		return key, nil
+	}
+	if runes := []rune(name); len(runes) == 1 {
+		return tea.KeyMsg{Type: tea.KeyRunes, Runes: runes, Alt: alt}, nil
+	}
+
+	return tea.KeyMsg{}, fmt.Errorf("unknown key %q", name)
+}
+
+var (
+	keyTypesOnce sync.Once
+	keyTypeNames map[string]tea.KeyType
+)
+
+// keyTypes indexes bubbletea's named keys by the names KeyType.String reports.
+func keyTypes() map[string]tea.KeyType {
+	keyTypesOnce.Do(func() {
+		keyTypeNames = make(map[string]tea.KeyType)
+		for keyType := tea.KeyType(-128); keyType <= tea.KeyBackspace; keyType++ {
+			if keyType == tea.KeyRunes {
+				continue
+			}
+			if name := keyType.String(); name != "" {
+				if _, exists := keyTypeNames[name]; !exists {
+					keyTypeNames[name] = keyType
+				}
+			}
+		}
+	})
+
+	return keyTypeNames
+}
+
+// frameRecorder wraps a model and remembers each distinct view the program rendered.
+type frameRecorder struct {
+	inner tea.Model
+
+	mu     sync.Mutex
+	frames []string
+}
+
+func (r *frameRecorder) Init() tea.Cmd {
+	return r.inner.Init()
+}
+
+func (r *frameRecorder) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	r.inner, cmd = r.inner.Update(msg)
+	return r, cmd
+}
+
+func (r *frameRecorder) View() string {
+	view := r.inner.View()
+	r.mu.Lock()
+	if len(r.frames) == 0 || r.frames[len(r.frames)-1] != view {
+		r.frames = append(r.frames, view)
+	}
+	r.mu.Unlock()
+
+	return view
+}
+
+func (r *frameRecorder) last() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.frames) == 0 {
+		return ""
+	}
+	return r.frames[len(r.frames)-1]
+}
+
+// since returns the frames recorded after the first n.
+func (r *frameRecorder) since(n int) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if n >= len(r.frames) {
+		return nil
+	}
+	return append([]string(nil), r.frames[n:]...)
+}
@@ -0,0 +1,74 @@
+package chattest
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestParseKey(t *testing.T) {
+	t.Parallel()
+
+	for name, want := range map[string]tea.KeyMsg{
+		"enter":  {Type: tea.KeyEnter},
+		"esc":    {Type: tea.KeyEsc},
+		"ctrl+t": {Type: tea.KeyCtrlT},
+		"pgup":   {Type: tea.KeyPgUp},
+		"y":      {Type: tea.KeyRunes, Runes: []rune("y")},
+		"alt+up": {Type: tea.KeyUp, Alt: true},
+		" ":      {Type: tea.KeySpace, Runes: []rune(" ")},
+	} {
+		got, err := ParseKey(name)
+		if err != nil {
+			t.Fatalf("ParseKey(%q) error: %v", name, err)
+		}
+		if got.String() != want.String() || got.Type != want.Type {
+			t.Fatalf("ParseKey(%q) = %q (type %d), want %q (type %d)", name, got.String(), got.Type, want.String(), want.Type)
+		}
+	}
+
+	if _, err := ParseKey("hyper+q"); err == nil {
+		t.Fatal("expected unknown key error")
+	}
+}
+
+func TestHarnessScriptsConversation(t *testing.T) {
+	t.Parallel()
+
+	h := New(t, Options{Prompt: Replies("first answer", "second answer")})
+	h.WaitFor("Enter send")
+
+	h.Submit("hello")
+	h.WaitFor("first answer")
+	h.Submit("again")
+	frame := h.WaitFor("second answer")
+	if !strings.Contains(frame, "turns:2") {
+		t.Fatalf("expected two turns in header:\n%s", frame)
+	}
+
+	h.Submit("one more")
+	h.WaitFor("no scripted reply left")
+}
+
+func TestHarnessBootAnimation(t *testing.T) {
+	t.Parallel()
+
+	h := New(t, Options{Boot: true})
+	h.WaitFor("[BOOT] syncing lobster core")
+	h.WaitFor("Enter send")
+}
+
+func TestHarnessASCIIFallback(t *testing.T) {
+	t.Parallel()
+
+	h := New(t, Options{Prompt: Replies("plain answer"), ASCII: true})
+	h.WaitFor("Enter send")
+	h.Submit("hello")
+	frame := h.WaitFor("plain answer")
+	for _, r := range frame {
+		if r > 0x7f {
+			t.Fatalf("expected only ASCII in frame, found %q:\n%s", r, frame)
+		}
+	}
+}
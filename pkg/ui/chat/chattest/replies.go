@@ -0,0 +1,31 @@
+package chattest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	providertypes "miniclaw/pkg/provider/types"
+	"miniclaw/pkg/ui/chat"
+)
+
+// Replies returns a prompt function that answers successive prompts with texts in order.
+//
+// Prompts past the end of texts fail with an error, which the UI renders as an error card.
+func Replies(texts ...string) chat.PromptFunc {
+	var mu sync.Mutex
+	next := 0
+
+	return func(context.Context, string) (providertypes.PromptResult, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if next >= len(texts) {
+			return providertypes.PromptResult{}, fmt.Errorf("chattest: no scripted reply left (%d used)", len(texts))
+		}
+		text := texts[next]
+		next++
+
+		return providertypes.PromptResult{Text: text}, nil
+	}
+}
@@ -0,0 +1,65 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+// maxErrorRecords bounds the /errors history kept in memory.
+const maxErrorRecords = 20
+
+// errorRecord is one failed prompt shown as an error card and in the /errors overlay.
+type errorRecord struct {
+	at      time.Time
+	summary providertypes.ErrorSummary
+}
+
+// recordError summarizes err, keeps it in the bounded failure history, and returns the record.
+func (m *model) recordError(err error) *errorRecord {
+	record := &errorRecord{at: time.Now(), summary: providertypes.Summarize(err)}
+	m.errorLog = append(m.errorLog, record)
+	if len(m.errorLog) > maxErrorRecords {
+		m.errorLog = m.errorLog[len(m.errorLog)-maxErrorRecords:]
+	}
+
+	return record
+}
+
+// renderErrorBody formats an error card: title, suggested fix, then request/category detail.
+func (m *model) renderErrorBody(record *errorRecord) string {
+	lines := []string{strings.TrimSpace(record.summary.Title)}
+	if hint := strings.TrimSpace(record.summary.Hint); hint != "" {
+		lines = append(lines, m.sym.hint+hint)
+	}
+
+	meta := fmt.Sprintf("request %s %s %s", displayOrNA(record.summary.RequestID), m.sym.sep, record.summary.Category)
+	if detail := strings.TrimSpace(record.summary.Detail); detail != "" && detail != strings.TrimSpace(record.summary.Title) {
+		meta += " " + m.sym.sep + " " + detail
+	}
+	lines = append(lines, m.theme.hint.Render(meta))
+
+	return strings.Join(lines, "\n")
+}
+
+// errorsOverlayView lists recent failures, newest first, in place of the transcript.
+func (m *model) errorsOverlayView() string {
+	if len(m.errorLog) == 0 {
+		return m.theme.hint.Render("No failed requests this session.")
+	}
+
+	entries := make([]string, 0, len(m.errorLog))
+	for index := len(m.errorLog) - 1; index >= 0; index-- {
+		record := m.errorLog[index]
+		header := fmt.Sprintf("%s  request %s  [%s]", record.at.Format("15:04:05"), displayOrNA(record.summary.RequestID), record.summary.Category)
+		entries = append(entries, m.theme.errorTitle.Render(header)+"\n"+m.renderErrorBody(record))
+	}
+
+	return strings.Join(entries, "\n\n")
+}
+
+func isErrorsCommand(input string) bool {
+	return strings.EqualFold(strings.TrimSpace(input), "/errors")
+}
@@ -0,0 +1,103 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	providertypes "miniclaw/pkg/provider/types"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestPromptFailureRendersErrorCardAndOverlay(t *testing.T) {
+	t.Parallel()
+
+	m := newModel(context.Background(), nil, modeInteractive, "", RuntimeInfo{})
+	m.booting = false
+	m.isLoading = true
+
+	failure := &providertypes.PromptError{
+		Category:  providertypes.ErrorAuth,
+		Provider:  "openai",
+		RequestID: "12",
+		Err:       errors.New("prompt failed: 401 Unauthorized"),
+	}
+	m.Update(promptResultMsg{err: failure})
+
+	if len(m.errorLog) != 1 {
+		t.Fatalf("error log len = %d, want 1", len(m.errorLog))
+	}
+	card := m.viewport.View()
+	for _, want := range []string{"API key invalid", "Set OPENAI_API_KEY", "request 12"} {
+		if !strings.Contains(card, want) {
+			t.Fatalf("error card missing %q:\n%s", want, card)
+		}
+	}
+
+	m.input.SetValue("/errors")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !m.showErrors {
+		t.Fatal("expected /errors to open the failure overlay")
+	}
+	if view := m.View(); !strings.Contains(view, "request 12") || !strings.Contains(view, "[auth]") {
+		t.Fatalf("overlay missing failure entry:\n%s", view)
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.showErrors {
+		t.Fatal("expected Esc to close the overlay")
+	}
+}
+
+func TestRecordErrorKeepsBoundedHistory(t *testing.T) {
+	t.Parallel()
+
+	m := newModel(context.Background(), nil, modeInteractive, "", RuntimeInfo{})
+	for range maxErrorRecords + 5 {
+		m.recordError(errors.New("boom"))
+	}
+	if len(m.errorLog) != maxErrorRecords {
+		t.Fatalf("error log len = %d, want %d", len(m.errorLog), maxErrorRecords)
+	}
+}
+
+func TestStatsOverlayShowsTurnTiming(t *testing.T) {
+	t.Parallel()
+
+	m := newModel(context.Background(), nil, modeInteractive, "", RuntimeInfo{})
+	m.booting = false
+	m.isLoading = true
+
+	m.Update(promptResultMsg{result: providertypes.PromptResult{
+		Text: "done",
+		Metadata: providertypes.PromptMetadata{Timing: &providertypes.TurnTiming{
+			QueueWait: 4 * time.Millisecond,
+			Provider:  1500 * time.Millisecond,
+			Tools:     250 * time.Millisecond,
+			Total:     1754 * time.Millisecond,
+		}},
+	}})
+	if len(m.turnStats) != 1 {
+		t.Fatalf("turn stats len = %d, want 1", len(m.turnStats))
+	}
+
+	m.input.SetValue("/stats")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !m.showStats {
+		t.Fatal("expected /stats to open the timing overlay")
+	}
+	view := m.View()
+	for _, want := range []string{"provider", "1.50s", "250ms", "4ms", "average"} {
+		if !strings.Contains(view, want) {
+			t.Fatalf("stats overlay missing %q:\n%s", want, view)
+		}
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.showStats {
+		t.Fatal("expected Esc to close the overlay")
+	}
+}
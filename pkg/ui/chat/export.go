@@ -0,0 +1,32 @@
+package chat
+
+import "strings"
+
+// exportConversation runs the /export command and shows its outcome as an EXPORT card.
+func (m *model) exportConversation(format string) {
+	content := "Export is not available for this agent type; use fantasy-agent."
+	if m.runtime.Export != nil {
+		summary, err := m.runtime.Export(m.ctx, format)
+		if err != nil {
+			content = "Export failed: " + err.Error()
+		} else {
+			content = summary
+		}
+	}
+
+	m.messages = append(m.messages, chatMessage{role: "export", content: content})
+	m.refreshViewport(true)
+}
+
+// parseExportCommand matches "/export" with an optional format argument.
+func parseExportCommand(input string) (string, bool) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || len(fields) > 2 || !strings.EqualFold(fields[0], "/export") {
+		return "", false
+	}
+	if len(fields) == 1 {
+		return "", true
+	}
+
+	return fields[1], true
+}
@@ -0,0 +1,53 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestExportCommandShowsOutcome(t *testing.T) {
+	t.Parallel()
+
+	var formats []string
+	info := RuntimeInfo{Export: func(_ context.Context, format string) (string, error) {
+		formats = append(formats, format)
+		if format == "xml" {
+			return "", errors.New(`unsupported export format "xml" (want json or md)`)
+		}
+		return "Exported 4 messages to miniclaw-s1.md", nil
+	}}
+	m := newModel(context.Background(), nil, modeInteractive, "", info)
+	m.booting = false
+
+	for _, tc := range []struct {
+		input string
+		want  string
+	}{
+		{input: "/export", want: "Exported 4 messages"},
+		{input: "/export xml", want: "Export failed: unsupported export format"},
+	} {
+		m.input.SetValue(tc.input)
+		m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		if last := m.messages[len(m.messages)-1]; last.role != "export" || !strings.Contains(last.content, tc.want) {
+			t.Fatalf("%s: last message = %+v, want export card containing %q", tc.input, last, tc.want)
+		}
+	}
+	if strings.Join(formats, ",") != ",xml" {
+		t.Fatalf("formats = %q, want [\"\" \"xml\"]", formats)
+	}
+	if m.isLoading {
+		t.Fatal("/export must not start a prompt")
+	}
+
+	disabled := newModel(context.Background(), nil, modeInteractive, "", RuntimeInfo{})
+	disabled.booting = false
+	disabled.input.SetValue("/export json")
+	disabled.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if last := disabled.messages[len(disabled.messages)-1]; !strings.Contains(last.content, "fantasy-agent") {
+		t.Fatalf("disabled /export message = %q, want fantasy-agent hint", last.content)
+	}
+}
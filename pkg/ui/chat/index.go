@@ -0,0 +1,49 @@
+package chat
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// indexResultMsg carries the outcome of /index to the card it started.
+type indexResultMsg struct {
+	messageIndex int
+	content      string
+}
+
+// reindexWorkspace runs the /index command in the background, since embedding
+// a large workspace takes a while, and shows its outcome on an INDEX card.
+func (m *model) reindexWorkspace() tea.Cmd {
+	if m.runtime.Reindex == nil {
+		m.messages = append(m.messages, chatMessage{role: "index", content: "Workspace retrieval is off; set agents.defaults.retrieval.enabled to index the workspace."})
+		m.refreshViewport(true)
+		return nil
+	}
+
+	m.messages = append(m.messages, chatMessage{role: "index", content: "Indexing the workspace..."})
+	m.refreshViewport(true)
+	messageIndex := len(m.messages) - 1
+	ctx, reindex := m.ctx, m.runtime.Reindex
+	return func() tea.Msg {
+		summary, err := reindex(ctx)
+		if err != nil {
+			summary = "Indexing failed: " + err.Error()
+		}
+		return indexResultMsg{messageIndex: messageIndex, content: summary}
+	}
+}
+
+// finishIndex replaces the INDEX card's progress note with the outcome.
+func (m *model) finishIndex(msg indexResultMsg) {
+	if msg.messageIndex < 0 || msg.messageIndex >= len(m.messages) || m.messages[msg.messageIndex].role != "index" {
+		return
+	}
+
+	m.messages[msg.messageIndex].content = msg.content
+	m.refreshViewport(false)
+}
+
+func isIndexCommand(input string) bool {
+	return strings.EqualFold(strings.TrimSpace(input), "/index")
+}
@@ -0,0 +1,44 @@
+package chat
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestIndexCommandShowsOutcome(t *testing.T) {
+	t.Parallel()
+
+	info := RuntimeInfo{Reindex: func(context.Context) (string, error) {
+		return "Indexed 3 files (5 snippets); 3 updated, 0 removed", nil
+	}}
+	m := newModel(context.Background(), nil, modeInteractive, "", info)
+	m.booting = false
+
+	m.input.SetValue("/index")
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("expected /index to run in the background")
+	}
+	if last := m.messages[len(m.messages)-1]; last.role != "index" || !strings.Contains(last.content, "Indexing") {
+		t.Fatalf("last message = %+v, want an index card in progress", last)
+	}
+	if m.isLoading {
+		t.Fatal("/index must not start a prompt")
+	}
+
+	m.Update(cmd())
+	if last := m.messages[len(m.messages)-1]; !strings.Contains(last.content, "Indexed 3 files") {
+		t.Fatalf("last message = %+v, want the index summary", last)
+	}
+
+	disabled := newModel(context.Background(), nil, modeInteractive, "", RuntimeInfo{})
+	disabled.booting = false
+	disabled.input.SetValue("/index")
+	disabled.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if last := disabled.messages[len(disabled.messages)-1]; !strings.Contains(last.content, "agents.defaults.retrieval") {
+		t.Fatalf("disabled /index message = %q, want retrieval config hint", last.content)
+	}
+}
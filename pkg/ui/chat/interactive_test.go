@@ -0,0 +1,119 @@
+package chat_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	providertypes "miniclaw/pkg/provider/types"
+	"miniclaw/pkg/ui/chat"
+	"miniclaw/pkg/ui/chat/chattest"
+)
+
+func TestInteractiveConversationFrame(t *testing.T) {
+	t.Parallel()
+
+	h := chattest.New(t, chattest.Options{
+		// Tool events ride on the result rather than the live stream so card order is deterministic.
+		Prompt: func(context.Context, string) (providertypes.PromptResult, error) {
+			return providertypes.PromptResult{
+				Text: "You have one file: notes.md",
+				Metadata: providertypes.PromptMetadata{
+					Usage: &providertypes.TokenUsage{InputTokens: 12, OutputTokens: 7, TotalTokens: 19},
+					ToolEvents: []providertypes.ToolEvent{
+						{Kind: "call", Tool: "list_dir", Payload: `{"path":"."}`},
+						{Kind: "result", Tool: "list_dir", Payload: "notes.md", DurationMs: 3},
+					},
+				},
+			}, nil
+		},
+		Runtime: chat.RuntimeInfo{AgentType: "fantasy-agent", Provider: "mock", Model: "test-model"},
+		Width:   90,
+		Height:  30,
+	})
+
+	h.Submit("what is in my workspace?")
+	h.WaitFor("tokens(in/out/total):12/7/19")
+	h.RequireGolden(t)
+
+	h.Press("ctrl+t")
+	frame := h.WaitFor("tools:hidden")
+	if strings.Contains(frame, "list_dir") {
+		t.Fatalf("expected tool card to be hidden:\n%s", frame)
+	}
+}
+
+func TestInteractiveApprovalFlow(t *testing.T) {
+	t.Parallel()
+
+	h := chattest.New(t, chattest.Options{
+		Prompt: func(ctx context.Context, prompt string) (providertypes.PromptResult, error) {
+			approver, ok := providertypes.ToolApproverFromContext(ctx)
+			if !ok {
+				return providertypes.PromptResult{Text: "no approver"}, nil
+			}
+			approved, err := approver(ctx, providertypes.ToolApprovalRequest{Tool: "remove_dir", Input: `{"path":"build"}`})
+			if err != nil {
+				return providertypes.PromptResult{}, err
+			}
+			if !approved {
+				return providertypes.PromptResult{Text: "Left build/ in place."}, nil
+			}
+			return providertypes.PromptResult{Text: "Removed build/."}, nil
+		},
+	})
+
+	h.Submit("clean up")
+	h.WaitFor("allow remove_dir?")
+	h.Press("n")
+	frame := h.WaitFor("Left build/ in place.")
+	if !strings.Contains(frame, "denied") {
+		t.Fatalf("expected denied approval card:\n%s", frame)
+	}
+}
+
+func TestInteractiveStopCancelsPrompt(t *testing.T) {
+	t.Parallel()
+
+	h := chattest.New(t, chattest.Options{
+		Prompt: func(ctx context.Context, prompt string) (providertypes.PromptResult, error) {
+			<-ctx.Done()
+			return providertypes.PromptResult{}, ctx.Err()
+		},
+	})
+
+	h.Submit("long task")
+	h.WaitFor("Esc/Ctrl+X stop")
+	h.Press("ctrl+x")
+	frame := h.WaitFor("Prompt stopped before it finished.")
+	if strings.Contains(frame, "last request failed") {
+		t.Fatalf("expected a stopped prompt not to count as a failure:\n%s", frame)
+	}
+}
+
+func TestInteractiveContextUsageWarns(t *testing.T) {
+	t.Parallel()
+
+	used := int64(0)
+	h := chattest.New(t, chattest.Options{
+		Prompt: func(context.Context, string) (providertypes.PromptResult, error) {
+			used += 90_000
+			usage := &providertypes.ContextUsage{UsedTokens: used, WindowTokens: 200_000}
+			usage.NearlyFull = usage.Percent() >= 80
+			return providertypes.PromptResult{Text: "ok", Metadata: providertypes.PromptMetadata{Context: usage}}, nil
+		},
+		Width:  140,
+		Height: 30,
+	})
+
+	h.Submit("first")
+	frame := h.WaitFor("context:45%")
+	if strings.Contains(frame, "outgrow") {
+		t.Fatalf("expected no warning at 45%%:\n%s", frame)
+	}
+	h.Submit("second")
+	frame = h.WaitFor("context:90%")
+	if !strings.Contains(frame, "context 90% full") {
+		t.Fatalf("expected a nearly-full warning at 90%%:\n%s", frame)
+	}
+}
@@ -26,6 +26,7 @@ type chatMessage struct {
 	role    string
 	content string
 	usage   *providertypes.TokenUsage
+	failure *errorRecord
 }
 
 type promptResultMsg struct {
@@ -50,6 +51,7 @@ type model struct {
 	oneShotInput string
 
 	theme                   theme
+	sym                     symbols
 	spinner                 spinner.Model
 	input                   textinput.Model
 	viewport                viewport.Model
@@ -58,17 +60,27 @@ type model struct {
 	height                  int
 	isReady                 bool
 	isLoading               bool
+	cancelPrompt            context.CancelFunc
 	lastErr                 string
+	errorLog                []*errorRecord
+	showErrors              bool
+	turnStats               []turnStat
+	showStats               bool
+	promptStartedAt         time.Time
 	booting                 bool
 	bootStep                int
 	followLog               bool
 	showTools               bool
 	pendingToolMessageIndex int
+	planMessageIndex        int
 	receivedLiveToolEvents  bool
+	pendingApprovals        []*approvalRequest
 	runtime                 RuntimeInfo
 	usageIn                 int64
 	usageOut                int64
 	usageTotal              int64
+	// contextUsage is the context window fill reported by the last turn.
+	contextUsage *providertypes.ContextUsage
 }
 
 // newModel initializes chat UI state for interactive or one-shot mode.
@@ -91,6 +103,7 @@ func newModel(ctx context.Context, promptFn PromptFunc, runMode mode, prompt str
 		mode:                    runMode,
 		oneShotInput:            strings.TrimSpace(prompt),
 		theme:                   defaultTheme(),
+		sym:                     unicodeSymbols,
 		spinner:                 spin,
 		input:                   in,
 		viewport:                vp,
@@ -100,6 +113,7 @@ func newModel(ctx context.Context, promptFn PromptFunc, runMode mode, prompt str
 		followLog:               true,
 		showTools:               true,
 		pendingToolMessageIndex: -1,
+		planMessageIndex:        -1,
 		runtime:                 info,
 	}
 }
@@ -109,13 +123,18 @@ func (m *model) Init() tea.Cmd {
 		m.messages = append(m.messages, chatMessage{role: "user", content: m.oneShotInput})
 		m.isLoading = true
 		m.refreshViewport(false)
-		toolStream := make(chan providertypes.ToolEvent, 16)
 		m.pendingToolMessageIndex = -1
 		m.receivedLiveToolEvents = false
-		return tea.Batch(m.spinner.Tick, sendPromptCmd(m.ctx, m.promptFn, m.oneShotInput, toolStream), waitToolEventCmd(toolStream))
+		return m.startPrompt(m.oneShotInput)
+	}
+	if m.booting {
+		return bootTickCmd()
+	}
+	if m.mode == modeInteractive {
+		return textinput.Blink
 	}
 
-	return bootTickCmd()
+	return nil
 }
 
 func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -154,10 +173,9 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.messages = append(m.messages, chatMessage{role: "user", content: m.oneShotInput})
 			m.isLoading = true
 			m.refreshViewport(false)
-			toolStream := make(chan providertypes.ToolEvent, 16)
 			m.pendingToolMessageIndex = -1
 			m.receivedLiveToolEvents = false
-			return m, tea.Batch(m.spinner.Tick, sendPromptCmd(m.ctx, m.promptFn, m.oneShotInput, toolStream), waitToolEventCmd(toolStream))
+			return m, m.startPrompt(m.oneShotInput)
 		}
 
 		if m.mode == modeInteractive {
@@ -166,8 +184,28 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		return m, nil
 	case tea.KeyMsg:
+		if typed.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		// A pending tool approval captures the keyboard until it is answered.
+		if len(m.pendingApprovals) > 0 {
+			m.handleApprovalKey(typed)
+			return m, nil
+		}
+
+		// Esc or Ctrl+X stops a running prompt instead of quitting.
+		if m.isLoading && (typed.String() == "esc" || typed.String() == "ctrl+x") {
+			m.stopPrompt()
+			return m, nil
+		}
+
 		switch typed.String() {
-		case "ctrl+c", "esc":
+		case "esc":
+			if m.showErrors || m.showStats {
+				m.showErrors = false
+				m.showStats = false
+				return m, nil
+			}
 			return m, tea.Quit
 		case "ctrl+t":
 			if m.mode == modeInteractive && !m.booting {
@@ -203,6 +241,54 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if isExitCommand(prompt) {
 				return m, tea.Quit
 			}
+			if isErrorsCommand(prompt) {
+				m.input.SetValue("")
+				m.showErrors = !m.showErrors
+				m.showStats = false
+				return m, nil
+			}
+			if isStatsCommand(prompt) {
+				m.input.SetValue("")
+				m.showStats = !m.showStats
+				m.showErrors = false
+				return m, nil
+			}
+			if isUndoCommand(prompt) {
+				m.input.SetValue("")
+				m.showErrors = false
+				m.showStats = false
+				m.undoTurn()
+				return m, nil
+			}
+			if isUndoFilesCommand(prompt) {
+				m.input.SetValue("")
+				m.showErrors = false
+				m.showStats = false
+				m.undoFiles()
+				return m, nil
+			}
+			if format, ok := parseExportCommand(prompt); ok {
+				m.input.SetValue("")
+				m.showErrors = false
+				m.showStats = false
+				m.exportConversation(format)
+				return m, nil
+			}
+			if command, ok := parseVariableCommand(prompt); ok {
+				m.input.SetValue("")
+				m.showErrors = false
+				m.showStats = false
+				m.runVariableCommand(command)
+				return m, nil
+			}
+			if isIndexCommand(prompt) {
+				m.input.SetValue("")
+				m.showErrors = false
+				m.showStats = false
+				return m, m.reindexWorkspace()
+			}
+			m.showErrors = false
+			m.showStats = false
 
 			m.lastErr = ""
 			m.messages = append(m.messages, chatMessage{role: "user", content: prompt})
@@ -212,8 +298,7 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.pendingToolMessageIndex = -1
 			m.receivedLiveToolEvents = false
 			m.refreshViewport(true)
-			toolStream := make(chan providertypes.ToolEvent, 16)
-			return m, tea.Batch(m.spinner.Tick, sendPromptCmd(m.ctx, m.promptFn, prompt, toolStream), waitToolEventCmd(toolStream))
+			return m, m.startPrompt(prompt)
 		}
 	}
 
@@ -228,15 +313,29 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		m.spinner, cmd = m.spinner.Update(typed)
 		return m, cmd
+	case indexResultMsg:
+		m.finishIndex(typed)
+		return m, nil
 	case promptResultMsg:
 		m.isLoading = false
-		if typed.err != nil {
-			m.lastErr = typed.err.Error()
-			m.messages = append(m.messages, chatMessage{role: "error", content: typed.err.Error()})
+		if m.cancelPrompt != nil {
+			m.cancelPrompt()
+			m.cancelPrompt = nil
+		}
+		m.clearApprovals()
+		renderStartedAt := time.Now()
+		if typed.err != nil && m.mode == modeInteractive && providertypes.ErrorCategoryOf(typed.err) == providertypes.ErrorCanceled {
+			m.lastErr = ""
+			m.pendingToolMessageIndex = -1
+			m.messages = append(m.messages, chatMessage{role: "stopped", content: "Prompt stopped before it finished."})
+		} else if typed.err != nil {
+			record := m.recordError(typed.err)
+			m.lastErr = providertypes.UserMessage(typed.err)
+			m.messages = append(m.messages, chatMessage{role: "error", content: m.lastErr, failure: record})
 		} else {
 			m.lastErr = ""
 			if !m.receivedLiveToolEvents && len(typed.result.Metadata.ToolEvents) > 0 {
-				for _, block := range mergeToolEvents(typed.result.Metadata.ToolEvents) {
+				for _, block := range mergeToolEvents(m.splitPlanEvents(typed.result.Metadata.ToolEvents)) {
 					m.messages = append(m.messages, chatMessage{role: "tool", content: block})
 				}
 			}
@@ -247,8 +346,14 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.usageOut += typed.result.Metadata.Usage.OutputTokens
 				m.usageTotal += typed.result.Metadata.Usage.TotalTokens
 			}
+			if typed.result.Metadata.Context != nil {
+				m.contextUsage = typed.result.Metadata.Context
+			}
 		}
 		m.refreshViewport(false)
+		if typed.err == nil {
+			m.recordTurnStat(m.turnTiming(typed.result, time.Since(renderStartedAt)))
+		}
 		if m.mode == modeOneShot {
 			return m, tea.Quit
 		}
@@ -259,6 +364,12 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, waitToolEventCmd(typed.stream)
 	case toolEventStreamClosedMsg:
 		return m, nil
+	case approvalRequestMsg:
+		m.queueApproval(typed.approval)
+		m.refreshViewport(false)
+		return m, waitApprovalCmd(typed.stream)
+	case approvalStreamClosedMsg:
+		return m, nil
 	}
 
 	return m, cmd
@@ -276,36 +387,54 @@ func (m *model) View() string {
 		return m.bootView()
 	}
 
-	header := m.theme.header.Width(m.width - 2).Render("📟 MiniClaw Command Center")
-	meta := m.theme.headerMeta.Render(fmt.Sprintf(
-		"agent:%s · provider:%s · model:%s · turns:%d · tokens(in/out/total):%d/%d/%d",
-		displayOrNA(m.runtime.AgentType),
-		displayOrNA(m.runtime.Provider),
-		displayOrNA(m.runtime.Model),
-		conversationTurns(m.messages),
-		m.usageIn,
-		m.usageOut,
-		m.usageTotal,
-	))
-	line := m.theme.divider.Width(m.width - 2).Render(strings.Repeat("═", max(8, m.width-2)))
+	header := m.theme.header.Width(m.width - 2).Render(m.sym.logo + "MiniClaw Command Center")
+	metaFields := []string{
+		"agent:" + displayOrNA(m.runtime.AgentType),
+		"provider:" + displayOrNA(m.runtime.Provider),
+		"model:" + displayOrNA(m.runtime.Model),
+		fmt.Sprintf("turns:%d", conversationTurns(m.messages)),
+		fmt.Sprintf("tokens(in/out/total):%d/%d/%d", m.usageIn, m.usageOut, m.usageTotal),
+	}
+	if m.contextUsage != nil {
+		metaFields = append(metaFields, fmt.Sprintf("context:%d%%", m.contextUsage.Percent()))
+	}
+	meta := m.theme.headerMeta.Render(strings.Join(metaFields, " "+m.sym.sep+" "))
+	line := m.theme.divider.Width(m.width - 2).Render(strings.Repeat(m.sym.rule, max(8, m.width-2)))
 
 	toolToggleLabel := "showing"
 	if !m.showTools {
 		toolToggleLabel = "hidden"
 	}
-	status := m.theme.status.Render(fmt.Sprintf("💡 Enter send  ·  PgUp/PgDn scroll  ·  End jump latest  ·  Ctrl+T tools:%s  ·  🛑 Ctrl+C/Esc quit", toolToggleLabel))
+	sep := "  " + m.sym.sep + "  "
+	status := m.theme.status.Render(m.sym.hint + strings.Join([]string{"Enter send", "PgUp/PgDn scroll", "End jump latest", "Ctrl+T tools:" + toolToggleLabel, m.sym.stop + "Ctrl+C/Esc quit"}, sep))
+	if m.contextUsage != nil && m.contextUsage.NearlyFull {
+		status = m.theme.statusErr.Render(fmt.Sprintf("%scontext %d%% full - the conversation will soon outgrow the model window", m.sym.alert, m.contextUsage.Percent()))
+	}
 	if m.isLoading {
-		status = m.theme.statusBusy.Render(fmt.Sprintf("%s ⚡ generating response...", m.spinner.View()))
+		status = m.theme.statusBusy.Render(fmt.Sprintf("%s %sgenerating response...%sEsc/Ctrl+X stop", m.spinner.View(), m.sym.busy, sep))
 	}
 	if m.lastErr != "" {
-		status = m.theme.statusErr.Render("🚨 last request failed - try again")
+		status = m.theme.statusErr.Render(m.sym.alert + "last request failed - /errors for details")
+	}
+	if len(m.pendingApprovals) > 0 {
+		status = m.theme.statusErr.Render(fmt.Sprintf("%sallow %s?%sy approve%sn/Esc deny", m.sym.lock, m.pendingApprovals[0].request.Tool, sep, sep))
 	}
 
-	parts := []string{header, meta, line, m.theme.viewport.Width(m.width - 2).Render(m.viewport.View()), status}
+	body := m.viewport.View()
+	if m.showErrors {
+		body = m.errorsOverlayView()
+		status = m.theme.status.Render(fmt.Sprintf("%srecent failures (%d)%sEsc or /errors close", m.sym.failures, len(m.errorLog), sep))
+	}
+	if m.showStats {
+		body = m.statsOverlayView()
+		status = m.theme.status.Render(fmt.Sprintf("%sturn timing (%d)%sEsc or /stats close", m.sym.timing, len(m.turnStats), sep))
+	}
+
+	parts := []string{header, meta, line, m.theme.viewport.Width(m.width - 2).Render(body), status}
 
 	if m.mode == modeInteractive {
 		parts = append(parts,
-			m.theme.inputLabel.Render("👨🏻 You")+" "+m.theme.hint.Render("(type /exit, quit, or :q)"),
+			m.theme.inputLabel.Render(m.sym.you+"You")+" "+m.theme.hint.Render("(type /errors, /stats, /exit, quit, or :q)"),
 			m.theme.input.Width(m.width-2).Render(m.input.View()),
 		)
 	}
@@ -343,7 +472,7 @@ func (m *model) refreshViewport(forceBottom bool) {
 		switch item.role {
 		case "user":
 			sections = append(sections, m.renderCard(
-				m.theme.userTitle.Render("▛▚ [ 👨🏻 ] ▞▜"),
+				m.theme.userTitle.Render(m.sym.title(m.sym.userTitle)),
 				m.theme.userBox.Width(m.viewport.Width).Render(strings.TrimSpace(item.content)),
 			))
 		case "assistant":
@@ -352,17 +481,56 @@ func (m *model) refreshViewport(forceBottom bool) {
 				assistantBody = strings.TrimSpace(assistantBody + "\n\n" + m.theme.hint.Render(formatUsageLine(*item.usage)))
 			}
 			sections = append(sections, m.renderCard(
-				m.theme.assistantTitle.Render("▛▚ [ 🦞 ] ▞▜"),
+				m.theme.assistantTitle.Render(m.sym.title(m.sym.assistantTitle)),
 				m.theme.assistantBox.Width(m.viewport.Width).Render(assistantBody),
 			))
 		case "error":
+			errorBody := strings.TrimSpace(item.content)
+			if item.failure != nil {
+				errorBody = m.renderErrorBody(item.failure)
+			}
 			sections = append(sections, m.renderCard(
-				m.theme.errorTitle.Render("▛▚ [ERROR] ▞▜"),
-				m.theme.errorBox.Width(m.viewport.Width).Render(strings.TrimSpace(item.content)),
+				m.theme.errorTitle.Render(m.sym.title("ERROR")),
+				m.theme.errorBox.Width(m.viewport.Width).Render(errorBody),
 			))
 		case "tool":
 			sections = append(sections, m.renderCard(
-				m.theme.toolTitle.Render("▛▚ [ 🔧 TOOL ] ▞▜"),
+				m.theme.toolTitle.Render(m.sym.title(m.sym.toolTitle)),
+				m.theme.toolBox.Width(m.viewport.Width).Render(strings.TrimSpace(item.content)),
+			))
+		case "approval":
+			sections = append(sections, m.renderCard(
+				m.theme.toolTitle.Render(m.sym.title(m.sym.approvalTitle)),
+				m.theme.toolBox.Width(m.viewport.Width).Render(strings.TrimSpace(item.content)),
+			))
+		case "plan":
+			sections = append(sections, m.renderCard(
+				m.theme.toolTitle.Render(m.sym.title(m.sym.planTitle)),
+				m.theme.toolBox.Width(m.viewport.Width).Render(strings.TrimSpace(item.content)),
+			))
+		case "undo":
+			sections = append(sections, m.renderCard(
+				m.theme.toolTitle.Render(m.sym.title("UNDO")),
+				m.theme.toolBox.Width(m.viewport.Width).Render(strings.TrimSpace(item.content)),
+			))
+		case "stopped":
+			sections = append(sections, m.renderCard(
+				m.theme.toolTitle.Render(m.sym.title("STOPPED")),
+				m.theme.toolBox.Width(m.viewport.Width).Render(strings.TrimSpace(item.content)),
+			))
+		case "export":
+			sections = append(sections, m.renderCard(
+				m.theme.toolTitle.Render(m.sym.title("EXPORT")),
+				m.theme.toolBox.Width(m.viewport.Width).Render(strings.TrimSpace(item.content)),
+			))
+		case "vars":
+			sections = append(sections, m.renderCard(
+				m.theme.toolTitle.Render(m.sym.title("VARS")),
+				m.theme.toolBox.Width(m.viewport.Width).Render(strings.TrimSpace(item.content)),
+			))
+		case "index":
+			sections = append(sections, m.renderCard(
+				m.theme.toolTitle.Render(m.sym.title("INDEX")),
 				m.theme.toolBox.Width(m.viewport.Width).Render(strings.TrimSpace(item.content)),
 			))
 		}
@@ -392,20 +560,33 @@ func (m *model) renderCard(title string, body string) string {
 func (m *model) oneShotView() string {
 	contentWidth := max(40, m.width-6)
 	parts := []string{m.renderCard(
-		m.theme.userTitle.Render("▛▚ [SENT] ▞▜"),
+		m.theme.userTitle.Render(m.sym.title("SENT")),
 		m.theme.userBox.Width(contentWidth).Render(strings.TrimSpace(m.oneShotInput)),
 	)}
 
+	if len(m.pendingApprovals) > 0 {
+		approval := m.pendingApprovals[0]
+		parts = append(parts, m.renderCard(
+			m.theme.toolTitle.Render(m.sym.title(m.sym.approvalTitle)),
+			m.theme.toolBox.Width(contentWidth).Render(m.approvalCardBody(approval.request, "")),
+		))
+		return lipgloss.JoinVertical(lipgloss.Left, parts...) + "\n"
+	}
+
 	if m.isLoading {
-		parts = append(parts, m.theme.statusBusy.Render(fmt.Sprintf("%s ⚡ sending prompt and waiting for answer...", m.spinner.View())))
+		parts = append(parts, m.theme.statusBusy.Render(fmt.Sprintf("%s %ssending prompt and waiting for answer...", m.spinner.View(), m.sym.busy)))
 		return lipgloss.JoinVertical(lipgloss.Left, parts...) + "\n"
 	}
 
 	if m.lastErr != "" {
+		errorBody := strings.TrimSpace(m.lastErr)
+		if len(m.errorLog) > 0 {
+			errorBody = m.renderErrorBody(m.errorLog[len(m.errorLog)-1])
+		}
 		parts = append(parts,
 			m.renderCard(
-				m.theme.errorTitle.Render("▛▚ [ERROR] ▞▜"),
-				m.theme.errorBox.Width(contentWidth).Render(strings.TrimSpace(m.lastErr)),
+				m.theme.errorTitle.Render(m.sym.title("ERROR")),
+				m.theme.errorBox.Width(contentWidth).Render(errorBody),
 			),
 		)
 		return lipgloss.JoinVertical(lipgloss.Left, parts...) + "\n\n"
@@ -421,7 +602,7 @@ func (m *model) oneShotView() string {
 
 	parts = append(parts,
 		m.renderCard(
-			m.theme.assistantTitle.Render("▛▚ [ANSWER] ▞▜"),
+			m.theme.assistantTitle.Render(m.sym.title("ANSWER")),
 			m.theme.assistantBox.Width(contentWidth).Render(strings.TrimSpace(answer)),
 		),
 	)
@@ -431,9 +612,9 @@ func (m *model) oneShotView() string {
 
 // bootView renders the startup animation before interactive input is enabled.
 func (m *model) bootView() string {
-	header := m.theme.header.Width(m.width - 2).Render("📟 MiniClaw Command Center")
+	header := m.theme.header.Width(m.width - 2).Render(m.sym.logo + "MiniClaw Command Center")
 	meta := m.theme.headerMeta.Render("boot sequence")
-	line := m.theme.divider.Width(m.width - 2).Render(strings.Repeat("═", max(8, m.width-2)))
+	line := m.theme.divider.Width(m.width - 2).Render(strings.Repeat(m.sym.rule, max(8, m.width-2)))
 
 	script := bootScriptLines()
 	count := min(m.bootStep, len(script))
@@ -442,7 +623,7 @@ func (m *model) bootView() string {
 		visible = append(visible, m.theme.bootLine.Render(script[i]))
 	}
 	if m.bootStep > len(script) {
-		visible = append(visible, m.theme.bootDone.Render("✅ command center online"))
+		visible = append(visible, m.theme.bootDone.Render(m.sym.done+"command center online"))
 	}
 
 	body := m.theme.viewport.Width(m.width - 2).Render(strings.Join(visible, "\n"))
@@ -532,23 +713,69 @@ func bootScriptLines() []string {
 	}
 }
 
+// turnTiming completes a result's timing with the render time and, when the
+// runtime did not measure it, the wall-clock time seen by the UI.
+func (m *model) turnTiming(result providertypes.PromptResult, render time.Duration) providertypes.TurnTiming {
+	var timing providertypes.TurnTiming
+	if result.Metadata.Timing != nil {
+		timing = *result.Metadata.Timing
+	}
+	if timing.Total == 0 && !m.promptStartedAt.IsZero() {
+		timing.Total = time.Since(m.promptStartedAt) - render
+	}
+	timing.Render = render
+	timing.Total += render
+
+	return timing
+}
+
+// startPrompt runs prompt in the background with live tool events and approval requests wired to the UI.
+func (m *model) startPrompt(prompt string) tea.Cmd {
+	toolStream := make(chan providertypes.ToolEvent, 16)
+	approvalStream := make(chan *approvalRequest)
+	m.promptStartedAt = time.Now()
+	m.planMessageIndex = -1
+	promptCtx, cancel := context.WithCancel(m.ctx)
+	m.cancelPrompt = cancel
+
+	return tea.Batch(
+		m.spinner.Tick,
+		sendPromptCmd(promptCtx, m.promptFn, prompt, toolStream, approvalStream),
+		waitToolEventCmd(toolStream),
+		waitApprovalCmd(approvalStream),
+	)
+}
+
+// stopPrompt cancels the running prompt; its result arrives as a canceled error.
+func (m *model) stopPrompt() {
+	if m.cancelPrompt != nil {
+		m.cancelPrompt()
+	}
+}
+
 // sendPromptCmd wraps prompt execution as an async Bubble Tea command.
-func sendPromptCmd(ctx context.Context, promptFn PromptFunc, prompt string, toolStream chan providertypes.ToolEvent) tea.Cmd {
+func sendPromptCmd(ctx context.Context, promptFn PromptFunc, prompt string, toolStream chan providertypes.ToolEvent, approvalStream chan *approvalRequest) tea.Cmd {
 	return func() tea.Msg {
 		callCtx := ctx
 		if toolStream != nil {
-			callCtx = providertypes.WithToolEventHandler(ctx, func(event providertypes.ToolEvent) {
+			callCtx = providertypes.WithToolEventHandler(callCtx, func(event providertypes.ToolEvent) {
 				select {
 				case toolStream <- event:
 				default:
 				}
 			})
 		}
+		if approvalStream != nil {
+			callCtx = providertypes.WithToolApprover(callCtx, newApprover(approvalStream))
+		}
 
 		result, err := promptFn(callCtx, prompt)
 		if toolStream != nil {
 			close(toolStream)
 		}
+		if approvalStream != nil {
+			close(approvalStream)
+		}
 		return promptResultMsg{result: result, err: err}
 	}
 }
@@ -574,7 +801,12 @@ func conversationTurns(messages []chatMessage) int {
 }
 
 func formatUsageLine(usage providertypes.TokenUsage) string {
-	return fmt.Sprintf("tokens in/out/total: %d/%d/%d", usage.InputTokens, usage.OutputTokens, usage.TotalTokens)
+	line := fmt.Sprintf("tokens in/out/total: %d/%d/%d", usage.InputTokens, usage.OutputTokens, usage.TotalTokens)
+	if usage.CacheReadTokens > 0 || usage.CacheCreationTokens > 0 {
+		line += fmt.Sprintf(" (cache read/write: %d/%d)", usage.CacheReadTokens, usage.CacheCreationTokens)
+	}
+
+	return line
 }
 
 func isExitCommand(input string) bool {
@@ -608,6 +840,10 @@ func formatToolEvent(event providertypes.ToolEvent) string {
 }
 
 func (m *model) appendOrMergeToolEvent(event providertypes.ToolEvent) {
+	if m.handlePlanEvent(event) {
+		return
+	}
+
 	kind := strings.TrimSpace(strings.ToLower(event.Kind))
 	formatted := formatToolEvent(event)
 
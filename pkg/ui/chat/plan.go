@@ -0,0 +1,54 @@
+package chat
+
+import (
+	"strings"
+
+	providertypes "miniclaw/pkg/provider/types"
+	"miniclaw/pkg/tools/plan"
+)
+
+// handlePlanEvent routes plan tool events to the turn's plan card and reports
+// whether the event was consumed. Plan calls are not shown on their own; a
+// failed plan call falls through to an ordinary tool card.
+func (m *model) handlePlanEvent(event providertypes.ToolEvent) bool {
+	if !plan.IsTool(strings.TrimSpace(event.Tool)) {
+		return false
+	}
+
+	switch strings.TrimSpace(strings.ToLower(event.Kind)) {
+	case "call":
+		return true
+	case "result":
+		payload := strings.TrimSpace(event.Payload)
+		if !strings.HasPrefix(payload, plan.Header) {
+			return false
+		}
+		m.showPlan(payload)
+		return true
+	default:
+		return false
+	}
+}
+
+// showPlan updates this turn's plan card, adding it on the first plan change.
+func (m *model) showPlan(rendered string) {
+	if m.planMessageIndex >= 0 && m.planMessageIndex < len(m.messages) && m.messages[m.planMessageIndex].role == "plan" {
+		m.messages[m.planMessageIndex].content = rendered
+		return
+	}
+
+	m.messages = append(m.messages, chatMessage{role: "plan", content: rendered})
+	m.planMessageIndex = len(m.messages) - 1
+}
+
+// splitPlanEvents applies plan events from a finished turn and returns the rest.
+func (m *model) splitPlanEvents(events []providertypes.ToolEvent) []providertypes.ToolEvent {
+	rest := make([]providertypes.ToolEvent, 0, len(events))
+	for _, event := range events {
+		if !m.handlePlanEvent(event) {
+			rest = append(rest, event)
+		}
+	}
+
+	return rest
+}
@@ -0,0 +1,65 @@
+package chat
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+func TestPlanEventsUpdateOnePlanCard(t *testing.T) {
+	t.Parallel()
+
+	m := newModel(context.Background(), nil, modeInteractive, "", RuntimeInfo{})
+	m.booting = false
+	m.showTools = false
+	stream := make(chan providertypes.ToolEvent)
+
+	events := []providertypes.ToolEvent{
+		{Kind: "call", Tool: "plan_add", Payload: `{"items":["Read","Write"]}`},
+		{Kind: "result", Tool: "plan_add", Payload: "Plan (0/2 done)\n[ ] 1. Read\n[ ] 2. Write"},
+		{Kind: "call", Tool: "plan_complete", Payload: `{"id":1}`},
+		{Kind: "result", Tool: "plan_complete", Payload: "Plan (1/2 done)\n[x] 1. Read\n[ ] 2. Write"},
+	}
+	for _, event := range events {
+		m.Update(toolEventMsg{event: event, stream: stream})
+	}
+
+	if len(m.messages) != 1 || m.messages[0].role != "plan" {
+		t.Fatalf("messages = %+v, want a single plan card", m.messages)
+	}
+	view := m.viewport.View()
+	if !strings.Contains(view, "PLAN") || !strings.Contains(view, "[x] 1. Read") || strings.Contains(view, "0/2 done") {
+		t.Fatalf("plan card should show the latest plan even with tools hidden:\n%s", view)
+	}
+
+	m.Update(toolEventMsg{event: providertypes.ToolEvent{Kind: "result", Tool: "plan_update", Payload: "invalid_argument: no plan item with id 9"}, stream: stream})
+	if len(m.messages) != 2 || m.messages[1].role != "tool" {
+		t.Fatalf("failed plan call should fall back to a tool card, got %+v", m.messages)
+	}
+}
+
+func TestPlanCardFromFinishedTurnEvents(t *testing.T) {
+	t.Parallel()
+
+	m := newModel(context.Background(), nil, modeInteractive, "", RuntimeInfo{})
+	m.booting = false
+	m.Update(promptResultMsg{result: providertypes.PromptResult{
+		Text: "done",
+		Metadata: providertypes.PromptMetadata{ToolEvents: []providertypes.ToolEvent{
+			{Kind: "call", Tool: "plan_add", Payload: `{"items":["Read"]}`},
+			{Kind: "result", Tool: "plan_add", Payload: "Plan (0/1 done)\n[ ] 1. Read"},
+			{Kind: "call", Tool: "read_file", Payload: `{"path":"a.txt"}`},
+			{Kind: "result", Tool: "read_file", Payload: "hello"},
+		}},
+	}})
+
+	roles := make([]string, 0, len(m.messages))
+	for _, message := range m.messages {
+		roles = append(roles, message.role)
+	}
+	if strings.Join(roles, ",") != "plan,tool,assistant" {
+		t.Fatalf("roles = %v, want plan, tool, assistant", roles)
+	}
+}
@@ -17,11 +17,58 @@ type RuntimeInfo struct {
 	AgentType string
 	Provider  string
 	Model     string
+	// UndoTurn, when set, backs the /undo command: it removes the last prompt
+	// and reply from the session history.
+	UndoTurn func(ctx context.Context) error
+	// UndoFiles, when set, backs the /undo-files command: it rolls back the
+	// last tool file change and describes what it did.
+	UndoFiles func(ctx context.Context) (string, error)
+	// Export, when set, backs the /export command: it writes the conversation
+	// in format ("json" or "md") and describes where it went.
+	Export func(ctx context.Context, format string) (string, error)
+	// Reindex, when set, backs the /index command: it brings the workspace
+	// retrieval index up to date and describes it.
+	Reindex func(ctx context.Context) (string, error)
+	// SetVariable, when set, backs /set name=value: it sets a session
+	// variable, or removes it for an empty value, and lists the variables.
+	SetVariable func(name string, value string) (string, error)
+	// Variables, when set, backs /vars: it lists the session variables.
+	Variables func() string
+}
+
+// ModelOptions configures a model built with NewModel.
+type ModelOptions struct {
+	Runtime RuntimeInfo
+	// SkipBoot starts with input enabled instead of playing the boot animation.
+	SkipBoot bool
+	// Terminal overrides terminal detection; nil uses DetectTerminal.
+	Terminal *TerminalCaps
+}
+
+// NewModel returns the interactive chat model without starting a program.
+//
+// It lets callers embed the UI in their own tea.Program or drive it from
+// tests (see pkg/ui/chat/chattest); RunInteractive uses the same model.
+func NewModel(ctx context.Context, promptFn PromptFunc, opts ModelOptions) tea.Model {
+	model := newModel(ctx, promptFn, modeInteractive, "", opts.Runtime)
+	caps := opts.Terminal
+	if caps == nil {
+		detected := DetectTerminal()
+		caps = &detected
+	}
+	model.useTerminal(*caps)
+	if opts.SkipBoot {
+		model.booting = false
+	}
+
+	return model
 }
 
 // RunInteractive starts the full-screen interactive chat UI.
 func RunInteractive(ctx context.Context, promptFn PromptFunc, info RuntimeInfo) error {
+	caps := DetectTerminal()
 	model := newModel(ctx, promptFn, modeInteractive, "", info)
+	model.useTerminal(caps)
 	program := tea.NewProgram(model, tea.WithMouseCellMotion())
 	_, err := program.Run()
 	if err != nil {
@@ -29,25 +76,26 @@ func RunInteractive(ctx context.Context, promptFn PromptFunc, info RuntimeInfo)
 	}
 
 	fmt.Print("\033[H\033[2J")
-	fmt.Println(renderGoodbyeBanner())
+	fmt.Println(renderGoodbyeBanner(symbolsFor(caps)))
 	return nil
 }
 
 // RunOneShot sends one prompt and exits after rendering the response.
 func RunOneShot(ctx context.Context, promptFn PromptFunc, prompt string) error {
 	model := newModel(ctx, promptFn, modeOneShot, prompt, RuntimeInfo{})
+	model.useTerminal(DetectTerminal())
 	program := tea.NewProgram(model)
 	_, err := program.Run()
 	return err
 }
 
 // renderGoodbyeBanner returns the final banner printed after interactive exit.
-func renderGoodbyeBanner() string {
+func renderGoodbyeBanner(sym symbols) string {
 	style := lipgloss.NewStyle().
 		Bold(true).
 		Foreground(lipgloss.Color("230")).
 		Background(lipgloss.Color("88")).
 		Padding(1, 2)
 
-	return style.Render("🦞 Thanks for using MiniClaw")
+	return style.Render(sym.claw + "Thanks for using MiniClaw")
 }
@@ -0,0 +1,83 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+// maxTurnStats bounds the /stats history kept in memory.
+const maxTurnStats = 20
+
+// turnStat is the timing breakdown of one completed turn.
+type turnStat struct {
+	at     time.Time
+	timing providertypes.TurnTiming
+}
+
+// recordTurnStat keeps timing in the bounded /stats history.
+func (m *model) recordTurnStat(timing providertypes.TurnTiming) {
+	m.turnStats = append(m.turnStats, turnStat{at: time.Now(), timing: timing})
+	if len(m.turnStats) > maxTurnStats {
+		m.turnStats = m.turnStats[len(m.turnStats)-maxTurnStats:]
+	}
+}
+
+// statsOverlayView lists per-turn timing, newest first, with averages, in place of the transcript.
+func (m *model) statsOverlayView() string {
+	if len(m.turnStats) == 0 {
+		return m.theme.hint.Render("No completed turns this session.")
+	}
+
+	header := fmt.Sprintf("%-8s  %9s  %9s  %9s  %9s  %9s", "time", "total", "queue", "provider", "tools", "render")
+	lines := []string{m.theme.errorTitle.Render(header)}
+
+	var sum providertypes.TurnTiming
+	for index := len(m.turnStats) - 1; index >= 0; index-- {
+		stat := m.turnStats[index]
+		lines = append(lines, formatTimingRow(stat.at.Format("15:04:05"), stat.timing))
+		sum.QueueWait += stat.timing.QueueWait
+		sum.Provider += stat.timing.Provider
+		sum.Tools += stat.timing.Tools
+		sum.Render += stat.timing.Render
+		sum.Total += stat.timing.Total
+	}
+
+	count := time.Duration(len(m.turnStats))
+	average := providertypes.TurnTiming{
+		QueueWait: sum.QueueWait / count,
+		Provider:  sum.Provider / count,
+		Tools:     sum.Tools / count,
+		Render:    sum.Render / count,
+		Total:     sum.Total / count,
+	}
+	lines = append(lines, "", m.theme.hint.Render(formatTimingRow("average", average)))
+
+	return strings.Join(lines, "\n")
+}
+
+func formatTimingRow(label string, timing providertypes.TurnTiming) string {
+	return fmt.Sprintf("%-8s  %9s  %9s  %9s  %9s  %9s",
+		label,
+		formatStageDuration(timing.Total),
+		formatStageDuration(timing.QueueWait),
+		formatStageDuration(timing.Provider),
+		formatStageDuration(timing.Tools),
+		formatStageDuration(timing.Render),
+	)
+}
+
+// formatStageDuration prints milliseconds below one second and seconds above it.
+func formatStageDuration(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+
+	return fmt.Sprintf("%.2fs", d.Seconds())
+}
+
+func isStatsCommand(input string) bool {
+	return strings.EqualFold(strings.TrimSpace(input), "/stats")
+}
@@ -111,3 +111,13 @@ func defaultTheme() theme {
 			Padding(0, 1),
 	}
 }
+
+// asciiTheme is defaultTheme with borders drawn from plain ASCII characters.
+func asciiTheme() theme {
+	t := defaultTheme()
+	for _, style := range []*lipgloss.Style{&t.userBox, &t.assistantBox, &t.toolBox, &t.errorBox, &t.input, &t.viewport} {
+		*style = style.Border(lipgloss.ASCIIBorder())
+	}
+
+	return t
+}
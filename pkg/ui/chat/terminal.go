@@ -0,0 +1,126 @@
+package chat
+
+import (
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/spinner"
+)
+
+// asciiEnv forces (1) or disables (0) the ASCII fallback regardless of detection.
+const asciiEnv = "MINICLAW_ASCII"
+
+// TerminalCaps describes what the user's terminal can display.
+type TerminalCaps struct {
+	// ASCII swaps emoji, box-drawing borders, and block glyphs for plain ASCII.
+	ASCII bool
+}
+
+// DetectTerminal reports the terminal's capabilities from the environment.
+//
+// TERM=dumb or an explicitly non-UTF-8 locale (the first of LC_ALL, LC_CTYPE,
+// and LANG that is set) selects ASCII; MINICLAW_ASCII=1 or 0 overrides the
+// guess. Colors need no handling here: lipgloss already drops them for
+// TERM=dumb and NO_COLOR.
+func DetectTerminal() TerminalCaps {
+	return detectTerminal(os.Getenv)
+}
+
+func detectTerminal(getenv func(string) string) TerminalCaps {
+	switch strings.ToLower(strings.TrimSpace(getenv(asciiEnv))) {
+	case "1", "true", "yes":
+		return TerminalCaps{ASCII: true}
+	case "0", "false", "no":
+		return TerminalCaps{}
+	}
+
+	if strings.TrimSpace(getenv("TERM")) == "dumb" {
+		return TerminalCaps{ASCII: true}
+	}
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		locale := strings.ToLower(strings.TrimSpace(getenv(name)))
+		if locale == "" {
+			continue
+		}
+		return TerminalCaps{ASCII: !strings.Contains(locale, "utf-8") && !strings.Contains(locale, "utf8")}
+	}
+
+	return TerminalCaps{}
+}
+
+// symbols are the decorative glyphs the UI renders. Prefix fields carry their
+// own trailing space so the ASCII set can drop them entirely.
+type symbols struct {
+	frameLeft, frameRight string
+	rule                  string
+	sep                   string
+
+	userTitle, assistantTitle, toolTitle, approvalTitle, planTitle string
+
+	logo, claw, you, hint, stop, busy, alert, lock, failures, timing, done string
+	denied, expired                                                        string
+}
+
+var unicodeSymbols = symbols{
+	frameLeft:      "▛▚",
+	frameRight:     "▞▜",
+	rule:           "═",
+	sep:            "·",
+	userTitle:      " 👨🏻 ",
+	assistantTitle: " 🦞 ",
+	toolTitle:      " 🔧 TOOL ",
+	approvalTitle:  " 🔐 APPROVAL ",
+	planTitle:      " 📋 PLAN ",
+	logo:           "📟 ",
+	claw:           "🦞 ",
+	you:            "👨🏻 ",
+	hint:           "💡 ",
+	stop:           "🛑 ",
+	busy:           "⚡ ",
+	alert:          "🚨 ",
+	lock:           "🔐 ",
+	failures:       "🧾 ",
+	timing:         "⏱️ ",
+	done:           "✅ ",
+	denied:         "🚫 ",
+	expired:        "⌛ ",
+}
+
+var asciiSymbols = symbols{
+	frameLeft:      "==",
+	frameRight:     "==",
+	rule:           "=",
+	sep:            "|",
+	userTitle:      " YOU ",
+	assistantTitle: " MINICLAW ",
+	toolTitle:      " TOOL ",
+	approvalTitle:  " APPROVAL ",
+	planTitle:      " PLAN ",
+	alert:          "! ",
+	done:           "[ok] ",
+	denied:         "[x] ",
+	expired:        "[-] ",
+}
+
+// symbolsFor picks the glyph set for caps.
+func symbolsFor(caps TerminalCaps) symbols {
+	if caps.ASCII {
+		return asciiSymbols
+	}
+
+	return unicodeSymbols
+}
+
+// title frames a card label, for example "▛▚ [ERROR] ▞▜".
+func (s symbols) title(label string) string {
+	return s.frameLeft + " [" + label + "] " + s.frameRight
+}
+
+// useTerminal adapts glyphs, borders, and the spinner to caps.
+func (m *model) useTerminal(caps TerminalCaps) {
+	m.sym = symbolsFor(caps)
+	if caps.ASCII {
+		m.theme = asciiTheme()
+		m.spinner.Spinner = spinner.Line
+	}
+}
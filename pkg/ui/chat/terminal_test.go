@@ -0,0 +1,26 @@
+package chat
+
+import "testing"
+
+func TestDetectTerminal(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		env  map[string]string
+		want bool
+	}{
+		"unset locale":        {env: map[string]string{"TERM": "xterm-256color"}, want: false},
+		"utf-8 lang":          {env: map[string]string{"LANG": "en_US.UTF-8"}, want: false},
+		"utf8 lc_all":         {env: map[string]string{"LC_ALL": "C.utf8", "LANG": "C"}, want: false},
+		"posix locale":        {env: map[string]string{"LANG": "C"}, want: true},
+		"lc_ctype beats lang": {env: map[string]string{"LC_CTYPE": "POSIX", "LANG": "en_US.UTF-8"}, want: true},
+		"dumb terminal":       {env: map[string]string{"TERM": "dumb", "LANG": "en_US.UTF-8"}, want: true},
+		"forced ascii":        {env: map[string]string{asciiEnv: "1", "LANG": "en_US.UTF-8"}, want: true},
+		"forced unicode":      {env: map[string]string{asciiEnv: "0", "TERM": "dumb"}, want: false},
+	} {
+		got := detectTerminal(func(key string) string { return tc.env[key] })
+		if got.ASCII != tc.want {
+			t.Fatalf("%s: ASCII = %v, want %v", name, got.ASCII, tc.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package chat
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	providertypes "miniclaw/pkg/provider/types"
+)
+
+// undoTurn runs the /undo command. It drops the last answered prompt with
+// its tool cards and reply from the session and the transcript, and puts the
+// prompt back in the input so it can be edited and retried. Failed prompts
+// never reached the history, so their cards stay.
+func (m *model) undoTurn() {
+	content := "Undo is not available for this session."
+	if m.runtime.UndoTurn != nil {
+		err := m.runtime.UndoTurn(m.ctx)
+		switch {
+		case errors.Is(err, providertypes.ErrNoTurnToUndo):
+			content = "Nothing undone: there is no earlier prompt in this session."
+		case err != nil:
+			content = "Nothing undone: " + err.Error()
+		default:
+			content = "Removed the last prompt and its reply."
+			if start, end, ok := m.lastTurn(); ok {
+				m.input.SetValue(m.messages[start].content)
+				m.input.CursorEnd()
+				m.messages = slices.Delete(m.messages, start, end+1)
+				content += " The prompt is back in the input to edit and resend."
+			}
+		}
+	}
+
+	m.messages = append(m.messages, chatMessage{role: "undo", content: content})
+	m.refreshViewport(true)
+}
+
+// lastTurn returns the transcript range from the last answered prompt to its
+// reply.
+func (m *model) lastTurn() (int, int, bool) {
+	for end := len(m.messages) - 1; end >= 0; end-- {
+		if m.messages[end].role != "assistant" {
+			continue
+		}
+		for start := end - 1; start >= 0; start-- {
+			if m.messages[start].role == "user" {
+				return start, end, true
+			}
+		}
+		break
+	}
+
+	return 0, 0, false
+}
+
+// undoFiles runs the /undo-files command and shows its outcome as an UNDO card.
+func (m *model) undoFiles() {
+	content := "File snapshots are off; set tools.filesystem.snapshots to true to undo agent edits."
+	if m.runtime.UndoFiles != nil {
+		summary, err := m.runtime.UndoFiles(m.ctx)
+		if err != nil {
+			content = "Nothing undone: " + err.Error()
+		} else {
+			content = summary
+		}
+	}
+
+	m.messages = append(m.messages, chatMessage{role: "undo", content: content})
+	m.refreshViewport(true)
+}
+
+func isUndoCommand(input string) bool {
+	return strings.EqualFold(strings.TrimSpace(input), "/undo")
+}
+
+func isUndoFilesCommand(input string) bool {
+	return strings.EqualFold(strings.TrimSpace(input), "/undo-files")
+}
@@ -0,0 +1,92 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	providertypes "miniclaw/pkg/provider/types"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestUndoFilesCommandShowsOutcome(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	info := RuntimeInfo{UndoFiles: func(context.Context) (string, error) {
+		calls++
+		if calls > 1 {
+			return "", errors.New("invalid_argument: no recorded changes to undo")
+		}
+		return "Undid change 2 (edit_file at 10:00:00 UTC): restored notes.txt", nil
+	}}
+	m := newModel(context.Background(), nil, modeInteractive, "", info)
+	m.booting = false
+
+	for _, want := range []string{"restored notes.txt", "Nothing undone: invalid_argument: no recorded changes to undo"} {
+		m.input.SetValue("/undo-files")
+		m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		if last := m.messages[len(m.messages)-1]; last.role != "undo" || !strings.Contains(last.content, want) {
+			t.Fatalf("last message = %+v, want undo card containing %q", last, want)
+		}
+	}
+	if m.isLoading {
+		t.Fatal("/undo-files must not start a prompt")
+	}
+
+	disabled := newModel(context.Background(), nil, modeInteractive, "", RuntimeInfo{})
+	disabled.booting = false
+	disabled.input.SetValue("/undo-files")
+	disabled.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if last := disabled.messages[len(disabled.messages)-1]; !strings.Contains(last.content, "tools.filesystem.snapshots") {
+		t.Fatalf("disabled /undo-files message = %q, want snapshot hint", last.content)
+	}
+}
+
+func TestUndoCommandRemovesLastTurn(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	info := RuntimeInfo{UndoTurn: func(context.Context) error {
+		calls++
+		if calls > 1 {
+			return providertypes.ErrNoTurnToUndo
+		}
+		return nil
+	}}
+	m := newModel(context.Background(), nil, modeInteractive, "", info)
+	m.booting = false
+	m.messages = append(m.messages,
+		chatMessage{role: "user", content: "first"},
+		chatMessage{role: "assistant", content: "one"},
+		chatMessage{role: "user", content: "second"},
+		chatMessage{role: "tool", content: "read_file notes.txt"},
+		chatMessage{role: "assistant", content: "two"},
+		chatMessage{role: "user", content: "failed"},
+		chatMessage{role: "error", content: "Rate limited"},
+	)
+
+	m.input.SetValue("/undo")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if got := m.input.Value(); got != "second" {
+		t.Fatalf("input = %q, want the undone prompt", got)
+	}
+	var roles []string
+	for _, message := range m.messages {
+		roles = append(roles, message.role)
+	}
+	if got := strings.Join(roles, ","); !strings.HasSuffix(got, "user,assistant,user,error,undo") || strings.Contains(got, "tool") {
+		t.Fatalf("roles after /undo = %s, want the second turn removed and the failed prompt kept", got)
+	}
+
+	m.input.SetValue("/undo")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if last := m.messages[len(m.messages)-1]; last.role != "undo" || !strings.Contains(last.content, "no earlier prompt") {
+		t.Fatalf("last message = %+v, want a nothing-undone card", last)
+	}
+	if m.isLoading {
+		t.Fatal("/undo must not start a prompt")
+	}
+}
@@ -0,0 +1,49 @@
+package chat
+
+import "strings"
+
+// variableCommand is a parsed /set or /vars command; list is true for /vars.
+type variableCommand struct {
+	list  bool
+	name  string
+	value string
+	valid bool
+}
+
+// runVariableCommand runs /set or /vars and shows the outcome as a VARS card.
+func (m *model) runVariableCommand(command variableCommand) {
+	content := "Session variables are off; set tools.variables.enabled to use them with fantasy-agent."
+	switch {
+	case m.runtime.SetVariable == nil || m.runtime.Variables == nil:
+	case command.list:
+		content = m.runtime.Variables()
+	case !command.valid:
+		content = "Usage: /set name=value (an empty value removes the variable)"
+	default:
+		summary, err := m.runtime.SetVariable(command.name, command.value)
+		if err != nil {
+			content = "Variable not set: " + err.Error()
+		} else {
+			content = summary
+		}
+	}
+
+	m.messages = append(m.messages, chatMessage{role: "vars", content: content})
+	m.refreshViewport(true)
+}
+
+// parseVariableCommand matches "/vars" and "/set name=value".
+func parseVariableCommand(input string) (variableCommand, bool) {
+	input = strings.TrimSpace(input)
+	if strings.EqualFold(input, "/vars") {
+		return variableCommand{list: true}, true
+	}
+	command, rest, _ := strings.Cut(input, " ")
+	if !strings.EqualFold(command, "/set") {
+		return variableCommand{}, false
+	}
+
+	name, value, ok := strings.Cut(rest, "=")
+	name = strings.TrimSpace(name)
+	return variableCommand{name: name, value: strings.TrimSpace(value), valid: ok && name != ""}, true
+}
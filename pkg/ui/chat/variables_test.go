@@ -0,0 +1,58 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestVariableCommandsShowOutcome(t *testing.T) {
+	t.Parallel()
+
+	values := map[string]string{}
+	info := RuntimeInfo{
+		SetVariable: func(name string, value string) (string, error) {
+			if strings.Contains(name, " ") {
+				return "", errors.New("invalid name")
+			}
+			values[name] = value
+			return "variables: " + name + "=" + value, nil
+		},
+		Variables: func() string { return "variables: client=Acme" },
+	}
+	m := newModel(context.Background(), nil, modeInteractive, "", info)
+	m.booting = false
+
+	for _, tc := range []struct {
+		input string
+		want  string
+	}{
+		{input: "/set client = Acme Corp", want: "variables: client=Acme Corp"},
+		{input: "/vars", want: "variables: client=Acme"},
+		{input: "/set client", want: "Usage: /set name=value"},
+		{input: "/set bad name=x", want: "Variable not set: invalid name"},
+	} {
+		m.input.SetValue(tc.input)
+		m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		if last := m.messages[len(m.messages)-1]; last.role != "vars" || !strings.Contains(last.content, tc.want) {
+			t.Fatalf("%s: last message = %+v, want vars card containing %q", tc.input, last, tc.want)
+		}
+	}
+	if values["client"] != "Acme Corp" {
+		t.Fatalf("client = %q, want Acme Corp", values["client"])
+	}
+	if m.isLoading {
+		t.Fatal("variable commands must not start a prompt")
+	}
+
+	disabled := newModel(context.Background(), nil, modeInteractive, "", RuntimeInfo{})
+	disabled.booting = false
+	disabled.input.SetValue("/vars")
+	disabled.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if last := disabled.messages[len(disabled.messages)-1]; !strings.Contains(last.content, "tools.variables.enabled") {
+		t.Fatalf("disabled /vars message = %q, want config hint", last.content)
+	}
+}
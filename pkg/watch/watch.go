@@ -0,0 +1,282 @@
+// Package watch reports files created, modified, or removed in the workspace
+// as events on the message bus, so an agent can react to files a user drops in.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"miniclaw/pkg/bus"
+	fstools "miniclaw/pkg/tools/fs"
+)
+
+const (
+	// DefaultDebounce is how long the watcher waits for a burst of changes to settle.
+	DefaultDebounce = 500 * time.Millisecond
+	// MaxWatchedDirs bounds the directories watched; deeper trees are only partly observed.
+	MaxWatchedDirs = 4096
+	// maxPromptChanges bounds the changes listed in one Prompt.
+	maxPromptChanges = 50
+
+	// OpCreated, OpModified, and OpRemoved are the change kinds in event payloads.
+	OpCreated  = "created"
+	OpModified = "modified"
+	OpRemoved  = "removed"
+)
+
+// skippedDirs are never watched: version control and miniclaw's own state.
+var skippedDirs = []string{".git", ".miniclaw"}
+
+// Change is one coalesced file change, relative to the workspace root.
+type Change struct {
+	Path string
+	Op   string
+}
+
+// Watcher watches a workspace tree and publishes a bus.EventWorkspaceChanged
+// per changed file once changes have been quiet for the debounce interval.
+// Paths matched by the workspace .miniclawignore when the watcher starts are
+// skipped.
+type Watcher struct {
+	root     string
+	debounce time.Duration
+	ignore   *fstools.Ignore
+	notify   *fsnotify.Watcher
+	watched  int
+	log      *slog.Logger
+}
+
+// New starts watching every directory under root. A debounce of zero uses
+// DefaultDebounce.
+func New(root string, debounce time.Duration) (*Watcher, error) {
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+	notify, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("create file watcher: %w", err)
+	}
+
+	w := &Watcher{
+		root:     root,
+		debounce: debounce,
+		notify:   notify,
+		log:      slog.Default().With("component", "watch"),
+	}
+	if content, err := os.ReadFile(filepath.Join(root, fstools.IgnoreFile)); err == nil {
+		w.ignore = fstools.ParseIgnore(string(content))
+	}
+	if _, err := w.addTree(root); err != nil {
+		_ = notify.Close()
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Run publishes changes to messageBus until ctx is done, then stops watching.
+func (w *Watcher) Run(ctx context.Context, messageBus *bus.MessageBus) {
+	defer w.notify.Close()
+
+	pending := make(map[string]string)
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.notify.Events:
+			if !ok {
+				return
+			}
+			for _, change := range w.changes(event) {
+				pending[change.Path] = coalesce(pending[change.Path], change.Op)
+				if pending[change.Path] == "" {
+					delete(pending, change.Path)
+				}
+			}
+			timer.Reset(w.debounce)
+		case err, ok := <-w.notify.Errors:
+			if !ok {
+				return
+			}
+			w.log.Warn("Workspace watch error", "error", err)
+		case <-timer.C:
+			for _, path := range slices.Sorted(maps.Keys(pending)) {
+				_ = messageBus.PublishEvent(ctx, bus.Event{
+					Type:    bus.EventWorkspaceChanged,
+					Payload: bus.WorkspaceChangedPayload{Path: path, Op: pending[path]},
+				})
+			}
+			clear(pending)
+		}
+	}
+}
+
+// changes converts one notification into workspace changes, watching new
+// directories and reporting the files already inside them.
+func (w *Watcher) changes(event fsnotify.Event) []Change {
+	rel, ok := w.rel(event.Name)
+	if !ok {
+		return nil
+	}
+
+	switch {
+	case event.Has(fsnotify.Create):
+		info, err := os.Lstat(event.Name)
+		if err != nil {
+			return nil
+		}
+		if !info.IsDir() {
+			if w.skipped(rel, false) {
+				return nil
+			}
+			return []Change{{Path: rel, Op: OpCreated}}
+		}
+		if w.skipped(rel, true) {
+			return nil
+		}
+		files, err := w.addTree(event.Name)
+		if err != nil {
+			w.log.Warn("Watch new directory failed", "path", rel, "error", err)
+		}
+		changes := make([]Change, 0, len(files))
+		for _, file := range files {
+			changes = append(changes, Change{Path: file, Op: OpCreated})
+		}
+		return changes
+	case event.Has(fsnotify.Write):
+		if w.skipped(rel, false) {
+			return nil
+		}
+		return []Change{{Path: rel, Op: OpModified}}
+	case event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename):
+		if w.skipped(rel, false) {
+			return nil
+		}
+		return []Change{{Path: rel, Op: OpRemoved}}
+	}
+
+	return nil
+}
+
+// addTree watches dir and its subdirectories and returns the regular files
+// found in them, relative to the root.
+func (w *Watcher) addTree(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			return nil
+		}
+		rel, ok := w.rel(path)
+		if !ok && path != w.root {
+			return nil
+		}
+		if !entry.IsDir() {
+			if entry.Type().IsRegular() && !w.skipped(rel, false) && dir != w.root {
+				files = append(files, rel)
+			}
+			return nil
+		}
+		if path != w.root && w.skipped(rel, true) {
+			return filepath.SkipDir
+		}
+		if w.watched >= MaxWatchedDirs {
+			w.log.Warn("Workspace watch limit reached; deeper directories are not observed", "max_dirs", MaxWatchedDirs)
+			return fs.SkipAll
+		}
+		if err := w.notify.Add(path); err != nil {
+			return fmt.Errorf("watch %s: %w", path, err)
+		}
+		w.watched++
+		return nil
+	})
+
+	return files, err
+}
+
+func (w *Watcher) rel(path string) (string, bool) {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+
+	return filepath.ToSlash(rel), true
+}
+
+func (w *Watcher) skipped(rel string, isDir bool) bool {
+	top, _, _ := strings.Cut(rel, "/")
+	if slices.Contains(skippedDirs, top) {
+		return true
+	}
+
+	return w.ignore.Match(rel, isDir)
+}
+
+// coalesce folds a new change into the pending one for the same path: a file
+// created and then written stays created, and one created and removed
+// within the window is dropped.
+func coalesce(pending string, next string) string {
+	switch {
+	case pending == OpCreated && next == OpModified:
+		return OpCreated
+	case pending == OpCreated && next == OpRemoved:
+		return ""
+	case pending == OpRemoved && next == OpCreated:
+		return OpModified
+	default:
+		return next
+	}
+}
+
+// Describe renders changes as one prompt line, for example
+// "created notes/todo.md; modified data.csv".
+func Describe(changes []Change) string {
+	parts := make([]string, 0, len(changes))
+	for _, change := range changes {
+		parts = append(parts, change.Op+" "+change.Path)
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// ChangeFromEvent extracts the change carried by a workspace event.
+func ChangeFromEvent(event bus.Event) (Change, bool) {
+	payload, ok := event.Payload.(bus.WorkspaceChangedPayload)
+	if event.Type != bus.EventWorkspaceChanged || !ok || payload.Path == "" {
+		return Change{}, false
+	}
+
+	return Change{Path: payload.Path, Op: payload.Op}, true
+}
+
+// Prompt asks the agent to look at changes it did not make. At most
+// maxPromptChanges are listed.
+func Prompt(changes []Change) string {
+	listed := changes
+	more := ""
+	if len(changes) > maxPromptChanges {
+		listed = changes[:maxPromptChanges]
+		more = fmt.Sprintf("; and %d more", len(changes)-maxPromptChanges)
+	}
+	count := "1 file"
+	if len(changes) != 1 {
+		count = strconv.Itoa(len(changes)) + " files"
+	}
+
+	return fmt.Sprintf("%s changed in the workspace outside this conversation: %s%s. Check whether they need any action.", count, Describe(listed), more)
+}
@@ -0,0 +1,95 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"miniclaw/pkg/bus"
+	fstools "miniclaw/pkg/tools/fs"
+)
+
+func TestWatcherPublishesCoalescedChanges(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, fstools.IgnoreFile), []byte("*.secret\n"), 0o644); err != nil {
+		t.Fatalf("write ignore file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "old.txt"), []byte("old"), 0o644); err != nil {
+		t.Fatalf("write old.txt: %v", err)
+	}
+
+	watcher, err := New(root, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	messageBus := bus.NewMessageBus()
+	defer messageBus.Close()
+	events, unsubscribe := messageBus.SubscribeEvents(ctx, 32)
+	defer unsubscribe()
+	go watcher.Run(ctx, messageBus)
+
+	writes := map[string]string{
+		"new.txt":         "hello",
+		"token.secret":    "hidden",
+		"inbox/drop.csv":  "a,b",
+		".miniclaw/x.txt": "state",
+	}
+	for name, content := range writes {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("MkdirAll error: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	if err := os.Remove(filepath.Join(root, "old.txt")); err != nil {
+		t.Fatalf("remove old.txt: %v", err)
+	}
+
+	want := map[string]string{"new.txt": OpCreated, "inbox/drop.csv": OpCreated, "old.txt": OpRemoved}
+	got := make(map[string]string)
+	deadline := time.After(5 * time.Second)
+	for len(got) < len(want) {
+		select {
+		case event := <-events:
+			if change, ok := ChangeFromEvent(event); ok {
+				got[change.Path] = change.Op
+			}
+		case <-deadline:
+			t.Fatalf("changes = %v, want %v", got, want)
+		}
+	}
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected extra event %+v", event)
+	case <-time.After(200 * time.Millisecond):
+	}
+	for path, op := range want {
+		if got[path] != op {
+			t.Fatalf("changes = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestCoalesceAndPrompt(t *testing.T) {
+	if got := coalesce(OpCreated, OpModified); got != OpCreated {
+		t.Fatalf("created then modified = %q, want %q", got, OpCreated)
+	}
+	if got := coalesce(OpCreated, OpRemoved); got != "" {
+		t.Fatalf("created then removed = %q, want dropped", got)
+	}
+	if got := coalesce(OpRemoved, OpCreated); got != OpModified {
+		t.Fatalf("removed then created = %q, want %q", got, OpModified)
+	}
+
+	prompt := Prompt([]Change{{Path: "a.txt", Op: OpCreated}, {Path: "b.txt", Op: OpRemoved}})
+	if !strings.HasPrefix(prompt, "2 files changed") || !strings.Contains(prompt, "created a.txt; removed b.txt") {
+		t.Fatalf("Prompt = %q", prompt)
+	}
+}
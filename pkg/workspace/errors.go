@@ -15,6 +15,17 @@ const (
 	ErrorIO               = "io_error"
 	ErrorAmbiguousEdit    = "ambiguous_edit"
 	ErrorEditNotFound     = "edit_not_found"
+	ErrorInvalidPattern   = "invalid_pattern"
+	ErrorInvalidRange     = "invalid_range"
+	ErrorDirNotEmpty      = "dir_not_empty"
+	ErrorInvalidPatch     = "invalid_patch"
+	ErrorPatchRejected    = "patch_rejected"
+	ErrorAlreadyExists    = "already_exists"
+	ErrorInvalidArgument  = "invalid_argument"
+	ErrorCommandDenied    = "command_denied"
+	ErrorMemoryFull       = "memory_full"
+	ErrorQuotaExceeded    = "quota_exceeded"
+	ErrorSymlinkDenied    = "symlink_denied"
 )
 
 // Error represents a stable, categorized workspace/tooling failure.
@@ -1,6 +1,7 @@
 package workspace
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,10 +10,48 @@ import (
 
 const defaultWorkspaceDirName = ".miniclaw/workspace"
 
+// SymlinkPolicy controls how the guard treats symlinks inside the workspace.
+type SymlinkPolicy string
+
+const (
+	// SymlinkFollowWithinWorkspace follows symlinks and requires the resolved
+	// target to stay inside the workspace (or an allowed path). It is the default.
+	SymlinkFollowWithinWorkspace SymlinkPolicy = "follow_within_workspace"
+	// SymlinkDeny rejects any path that goes through a symlink below the workspace root.
+	SymlinkDeny SymlinkPolicy = "deny"
+	// SymlinkFollowAll follows symlinks wherever they point; only the path as
+	// written must stay inside the workspace.
+	SymlinkFollowAll SymlinkPolicy = "follow_all"
+)
+
+// ParseSymlinkPolicy validates a configured policy; empty selects SymlinkFollowWithinWorkspace.
+func ParseSymlinkPolicy(value string) (SymlinkPolicy, error) {
+	switch policy := SymlinkPolicy(strings.ToLower(strings.TrimSpace(value))); policy {
+	case "":
+		return SymlinkFollowWithinWorkspace, nil
+	case SymlinkFollowWithinWorkspace, SymlinkDeny, SymlinkFollowAll:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown symlink policy %q (use deny, follow_within_workspace, or follow_all)", value)
+	}
+}
+
+// GuardOptions configures NewGuardWithOptions.
+type GuardOptions struct {
+	RestrictToWorkspace bool
+	// AllowedPaths are extra absolute roots admitted when RestrictToWorkspace is false.
+	AllowedPaths []string
+	// SymlinkPolicy is a ParseSymlinkPolicy value; empty follows symlinks within the workspace.
+	SymlinkPolicy string
+}
+
 // Guard resolves and validates tool paths against a workspace root.
 type Guard struct {
 	rootPath            string
 	restrictToWorkspace bool
+	// allowedRoots are extra roots reachable when restrictToWorkspace is false.
+	allowedRoots []string
+	symlinks     SymlinkPolicy
 }
 
 // NewGuard resolves a workspace path and ensures the directory exists.
@@ -22,12 +61,65 @@ func NewGuard(workspacePath string) (*Guard, error) {
 
 // NewGuardWithPolicy resolves a workspace path and applies containment policy.
 func NewGuardWithPolicy(workspacePath string, restrictToWorkspace bool) (*Guard, error) {
+	return NewGuardWithAllowedPaths(workspacePath, restrictToWorkspace, nil)
+}
+
+// NewGuardWithAllowedPaths resolves a workspace path and, when
+// restrictToWorkspace is false, also admits paths inside allowedPaths.
+// Everything else stays blocked. Allowed paths must already exist; they are
+// ignored while restriction is on.
+func NewGuardWithAllowedPaths(workspacePath string, restrictToWorkspace bool, allowedPaths []string) (*Guard, error) {
+	return NewGuardWithOptions(workspacePath, GuardOptions{RestrictToWorkspace: restrictToWorkspace, AllowedPaths: allowedPaths})
+}
+
+// NewGuardWithOptions resolves a workspace path and applies the containment,
+// allowed path, and symlink policies in opts.
+func NewGuardWithOptions(workspacePath string, opts GuardOptions) (*Guard, error) {
+	symlinks, err := ParseSymlinkPolicy(opts.SymlinkPolicy)
+	if err != nil {
+		return nil, err
+	}
 	resolved, err := ResolveRoot(workspacePath)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Guard{rootPath: resolved, restrictToWorkspace: restrictToWorkspace}, nil
+	guard := &Guard{rootPath: resolved, restrictToWorkspace: opts.RestrictToWorkspace, symlinks: symlinks}
+	if opts.RestrictToWorkspace {
+		return guard, nil
+	}
+	for _, allowed := range opts.AllowedPaths {
+		root, err := resolveAllowedRoot(allowed)
+		if err != nil {
+			return nil, err
+		}
+		guard.allowedRoots = append(guard.allowedRoots, root)
+	}
+
+	return guard, nil
+}
+
+// resolveAllowedRoot normalizes one allowed path without creating it.
+func resolveAllowedRoot(path string) (string, error) {
+	trimmed := strings.TrimSpace(path)
+	if trimmed == "" {
+		return "", errors.New("allowed path must not be empty")
+	}
+
+	expanded, err := ExpandHome(trimmed)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(expanded) {
+		return "", fmt.Errorf("allowed path %q must be absolute", path)
+	}
+
+	resolved, err := filepath.EvalSymlinks(filepath.Clean(expanded))
+	if err != nil {
+		return "", fmt.Errorf("resolve allowed path %q: %w", path, err)
+	}
+
+	return filepath.Clean(resolved), nil
 }
 
 // ResolveRoot normalizes workspace path input and creates it when missing.
@@ -41,7 +133,7 @@ func ResolveRoot(workspacePath string) (string, error) {
 		trimmed = filepath.Join(homeDir, defaultWorkspaceDirName)
 	}
 
-	expanded, err := expandHome(trimmed)
+	expanded, err := ExpandHome(trimmed)
 	if err != nil {
 		return "", err
 	}
@@ -94,33 +186,95 @@ func (g *Guard) ResolvePath(inputPath string) (string, error) {
 		return "", NewError(ErrorInvalidPath, "path could not be resolved")
 	}
 
-	cleanPath := filepath.Clean(absPath)
+	return g.effectivePath(filepath.Clean(absPath))
+}
+
+// EnsureContained re-checks containment right before mutating operations.
+func (g *Guard) EnsureContained(path string) error {
+	_, err := g.effectivePath(filepath.Clean(path))
+	return err
+}
+
+// SymlinkPolicy returns the policy the guard applies to symlinks.
+func (g *Guard) SymlinkPolicy() SymlinkPolicy {
+	if g == nil || g.symlinks == "" {
+		return SymlinkFollowWithinWorkspace
+	}
+
+	return g.symlinks
+}
+
+// effectivePath applies the symlink policy to a clean absolute path and
+// checks that the result is contained.
+func (g *Guard) effectivePath(cleanPath string) (string, error) {
+	switch g.SymlinkPolicy() {
+	case SymlinkFollowAll:
+		// Only the path as written is checked; the OS follows links when it is used.
+		if err := g.checkContained(cleanPath); err != nil {
+			return "", err
+		}
+		return cleanPath, nil
+	case SymlinkDeny:
+		if err := g.rejectSymlinks(cleanPath); err != nil {
+			return "", err
+		}
+	}
+
 	effectivePath, err := canonicalPath(cleanPath)
 	if err != nil {
 		return "", err
 	}
-
-	if g.shouldEnforceContainment() && !isWithin(g.rootPath, effectivePath) {
-		return "", NewError(ErrorOutsideWorkspace, "resolved path escapes workspace")
+	if err := g.checkContained(effectivePath); err != nil {
+		return "", err
 	}
 
 	return effectivePath, nil
 }
 
-// EnsureContained re-checks containment right before mutating operations.
-func (g *Guard) EnsureContained(path string) error {
-	effectivePath, err := canonicalPath(path)
-	if err != nil {
-		return err
+// rejectSymlinks fails with symlink_denied when any existing component of
+// path below the workspace root or allowed root containing it is a symlink.
+// Paths outside every root are left to the containment check.
+func (g *Guard) rejectSymlinks(path string) error {
+	base := ""
+	for _, root := range append([]string{g.rootPath}, g.allowedRoots...) {
+		if isWithin(root, path) {
+			base = root
+			break
+		}
+	}
+	if base == "" {
+		return nil
 	}
 
-	if g.shouldEnforceContainment() && !isWithin(g.rootPath, effectivePath) {
-		return NewError(ErrorOutsideWorkspace, "resolved path escapes workspace")
+	rel, err := filepath.Rel(base, path)
+	if err != nil || rel == "." {
+		return nil
+	}
+	current := base
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if err != nil {
+			// Missing components cannot be symlinks yet.
+			return nil
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return NewError(ErrorSymlinkDenied, fmt.Sprintf("%s is a symlink and symlink_policy is deny", g.RelPath(current)))
+		}
 	}
 
 	return nil
 }
 
+// AllowedRoots returns the extra roots admitted outside the workspace.
+func (g *Guard) AllowedRoots() []string {
+	if g == nil {
+		return nil
+	}
+
+	return append([]string(nil), g.allowedRoots...)
+}
+
 // RelPath returns a workspace-relative path when representable.
 func (g *Guard) RelPath(path string) string {
 	if g == nil {
@@ -189,7 +343,9 @@ func nearestExistingParent(path string) (string, string, error) {
 	return "", "", NewError(ErrorInvalidPath, "path could not be resolved")
 }
 
-func expandHome(path string) (string, error) {
+// ExpandHome resolves a leading "~" or "~/" against the user's home
+// directory and returns other paths unchanged.
+func ExpandHome(path string) (string, error) {
 	if path == "~" {
 		home, err := os.UserHomeDir()
 		if err != nil {
@@ -228,8 +384,22 @@ func isWithin(root string, target string) bool {
 	return !filepath.IsAbs(rel)
 }
 
-func (g *Guard) shouldEnforceContainment() bool {
-	// Phase 1 keeps workspace containment mandatory even when restriction is disabled.
-	_ = g.restrictToWorkspace
-	return true
+// checkContained admits canonical paths inside the workspace root or, with
+// restriction off, inside one of the allowed roots.
+func (g *Guard) checkContained(effectivePath string) error {
+	if isWithin(g.rootPath, effectivePath) {
+		return nil
+	}
+	if !g.restrictToWorkspace {
+		for _, root := range g.allowedRoots {
+			if isWithin(root, effectivePath) {
+				return nil
+			}
+		}
+		if len(g.allowedRoots) > 0 {
+			return NewError(ErrorOutsideWorkspace, "resolved path is outside the workspace and allowed_paths")
+		}
+	}
+
+	return NewError(ErrorOutsideWorkspace, "resolved path escapes workspace")
 }
@@ -99,7 +99,7 @@ func TestEnsureContainedRejectsOutsidePath(t *testing.T) {
 	}
 }
 
-func TestNewGuardWithPolicyStillEnforcesContainmentInPhaseOne(t *testing.T) {
+func TestNewGuardWithPolicyWithoutAllowedPathsEnforcesContainment(t *testing.T) {
 	guard, err := NewGuardWithPolicy(t.TempDir(), false)
 	if err != nil {
 		t.Fatalf("NewGuardWithPolicy error: %v", err)
@@ -112,6 +112,43 @@ func TestNewGuardWithPolicyStillEnforcesContainmentInPhaseOne(t *testing.T) {
 	}
 }
 
+func TestGuardAllowedPathsWhenUnrestricted(t *testing.T) {
+	allowedDir := t.TempDir()
+	otherDir := t.TempDir()
+
+	guard, err := NewGuardWithAllowedPaths(t.TempDir(), false, []string{allowedDir})
+	if err != nil {
+		t.Fatalf("NewGuardWithAllowedPaths error: %v", err)
+	}
+	resolved, err := guard.ResolvePath(filepath.Join(allowedDir, "notes", "new.txt"))
+	if err != nil {
+		t.Fatalf("ResolvePath in allowed path error: %v", err)
+	}
+	if !strings.HasPrefix(resolved, guard.AllowedRoots()[0]) {
+		t.Fatalf("resolved = %q, want under %q", resolved, guard.AllowedRoots()[0])
+	}
+	if _, err := guard.ResolvePath(filepath.Join(otherDir, "outside.txt")); CategoryFromError(err) != ErrorOutsideWorkspace {
+		t.Fatalf("outside error category = %q, want %q", CategoryFromError(err), ErrorOutsideWorkspace)
+	}
+	if _, err := guard.ResolvePath(filepath.Join(allowedDir, "..", filepath.Base(otherDir), "x.txt")); CategoryFromError(err) != ErrorOutsideWorkspace {
+		t.Fatalf("traversal error category = %q, want %q", CategoryFromError(err), ErrorOutsideWorkspace)
+	}
+
+	restricted, err := NewGuardWithAllowedPaths(t.TempDir(), true, []string{allowedDir})
+	if err != nil {
+		t.Fatalf("NewGuardWithAllowedPaths restricted error: %v", err)
+	}
+	if _, err := restricted.ResolvePath(filepath.Join(allowedDir, "x.txt")); CategoryFromError(err) != ErrorOutsideWorkspace {
+		t.Fatalf("restricted error category = %q, want %q", CategoryFromError(err), ErrorOutsideWorkspace)
+	}
+
+	for _, bad := range []string{"relative/dir", filepath.Join(otherDir, "missing")} {
+		if _, err := NewGuardWithAllowedPaths(t.TempDir(), false, []string{bad}); err == nil {
+			t.Fatalf("NewGuardWithAllowedPaths(%q) succeeded, want error", bad)
+		}
+	}
+}
+
 func mustGuard(t *testing.T) *Guard {
 	t.Helper()
 
@@ -122,3 +159,49 @@ func mustGuard(t *testing.T) *Guard {
 
 	return guard
 }
+
+func TestGuardSymlinkPolicies(t *testing.T) {
+	root := t.TempDir()
+	outsideDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "docs"), 0o755); err != nil {
+		t.Fatalf("create docs: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(root, "docs"), filepath.Join(root, "in-link")); err != nil {
+		t.Fatalf("create inside symlink: %v", err)
+	}
+	if err := os.Symlink(outsideDir, filepath.Join(root, "out-link")); err != nil {
+		t.Fatalf("create outside symlink: %v", err)
+	}
+
+	deny, err := NewGuardWithOptions(root, GuardOptions{RestrictToWorkspace: true, SymlinkPolicy: "deny"})
+	if err != nil {
+		t.Fatalf("NewGuardWithOptions deny error: %v", err)
+	}
+	for _, path := range []string{"in-link/a.txt", "out-link/a.txt"} {
+		if _, err := deny.ResolvePath(path); CategoryFromError(err) != ErrorSymlinkDenied {
+			t.Fatalf("deny %s error category = %q, want %q", path, CategoryFromError(err), ErrorSymlinkDenied)
+		}
+	}
+	if _, err := deny.ResolvePath("docs/new/a.txt"); err != nil {
+		t.Fatalf("deny plain path error: %v", err)
+	}
+
+	followAll, err := NewGuardWithOptions(root, GuardOptions{RestrictToWorkspace: true, SymlinkPolicy: "follow_all"})
+	if err != nil {
+		t.Fatalf("NewGuardWithOptions follow_all error: %v", err)
+	}
+	resolved, err := followAll.ResolvePath("out-link/a.txt")
+	if err != nil {
+		t.Fatalf("follow_all error: %v", err)
+	}
+	if resolved != filepath.Join(followAll.Root(), "out-link", "a.txt") {
+		t.Fatalf("follow_all resolved = %q, want the unresolved workspace path", resolved)
+	}
+	if _, err := followAll.ResolvePath("../escape.txt"); CategoryFromError(err) != ErrorOutsideWorkspace {
+		t.Fatalf("follow_all traversal error category = %q, want %q", CategoryFromError(err), ErrorOutsideWorkspace)
+	}
+
+	if _, err := NewGuardWithOptions(root, GuardOptions{SymlinkPolicy: "sometimes"}); err == nil {
+		t.Fatal("NewGuardWithOptions with unknown policy succeeded, want error")
+	}
+}